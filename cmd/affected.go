@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// affectedPackages returns the list of packages which have tests, and which
+// contain a file that changed since opts.affectedBy, or import (directly or
+// transitively, including test imports) a package that changed.
+func affectedPackages(opts *options) ([]string, error) {
+	changed, err := gitChangedFiles(opts.affectedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find files changed since %v: %w", opts.affectedBy, err)
+	}
+	pkgs, err := goListTestPackages(cmdArgPackageList(opts, rerunOpts{}, "./..."), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	affected := filterAffectedPackages(pkgs, changed)
+	log.Debugf("packages affected by changes since %v: %v", opts.affectedBy, affected)
+	return affected, nil
+}
+
+// gitChangedFiles returns the absolute path of every file that is different
+// in the working tree from the git ref, including untracked files that are
+// not ignored.
+func gitChangedFiles(ref string) ([]string, error) {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	changed, err := gitOutput("diff", "--name-only", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitOutput("-C", root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	var files []string // nolint: prealloc
+	for _, name := range strings.Split(changed+untracked, "\n") {
+		if name == "" {
+			continue
+		}
+		files = append(files, filepath.Join(root, filepath.FromSlash(name)))
+	}
+	return files, nil
+}
+
+func gitOutput(args ...string) (string, error) {
+	log.Debugf("exec: git %v", args)
+	cmd := exec.Command("git", args...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %v: %w: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return string(out), nil
+}
+
+// listedPackage is a package, or a test variant of a package, as reported by
+// 'go list -test -json'.
+type listedPackage struct {
+	ImportPath      string
+	ForTest         string
+	Name            string
+	Dir             string
+	Standard        bool
+	Module          *listedModule
+	Deps            []string
+	GoFiles         []string
+	CgoFiles        []string
+	TestGoFiles     []string
+	XTestGoFiles    []string
+	EmbedFiles      []string
+	TestEmbedFiles  []string
+	XTestEmbedFiles []string
+	SFiles          []string
+	CFiles          []string
+	CXXFiles        []string
+	HFiles          []string
+	SysoFiles       []string
+}
+
+type listedModule struct {
+	Path    string
+	Version string
+	Dir     string
+}
+
+// isTestMain returns true if the package is the generated test binary for
+// another package (ex: example.com/pkg.test).
+func (p listedPackage) isTestMain() bool {
+	return p.Name == "main" && p.ForTest == "" && strings.HasSuffix(p.ImportPath, ".test")
+}
+
+// goListTestPackages runs 'go list -test' for the patterns. When deps is true
+// the dependencies of every package are also listed.
+func goListTestPackages(patterns []string, deps bool) ([]listedPackage, error) {
+	return goListTestPackagesInDir("", patterns, deps)
+}
+
+// goListTestPackagesInDir is goListTestPackages with the working directory
+// set to dir.
+func goListTestPackagesInDir(dir string, patterns []string, deps bool) ([]listedPackage, error) {
+	args := []string{"list", "-test", "-json"}
+	if deps {
+		args = append(args, "-deps")
+	}
+	args = append(args, patterns...)
+	log.Debugf("exec: go %v", args)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w: %s", err, stderr.String())
+	}
+
+	var pkgs []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg listedPackage
+		switch err := dec.Decode(&pkg); {
+		case err == io.EOF:
+			return pkgs, nil
+		case err != nil:
+			return nil, fmt.Errorf("failed to decode go list output: %w", err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+}
+
+// filterAffectedPackages returns the sorted import paths of all the packages
+// with tests that are affected by the changed files. A changed file belongs to
+// the package in the nearest parent directory, so a change to a file in a
+// testdata directory affects the package. A change to a go.mod or go.sum file
+// affects every package.
+func filterAffectedPackages(pkgs []listedPackage, changedFiles []string) []string {
+	pkgDirs := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		pkgDirs[pkg.Dir] = true
+	}
+
+	changedDirs := make(map[string]bool)
+	var modChanged bool
+	for _, file := range changedFiles {
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum":
+			modChanged = true
+		}
+		if dir, ok := nearestPackageDir(pkgDirs, file); ok {
+			changedDirs[dir] = true
+		}
+	}
+	return packagesAffectedByDirs(pkgs, changedDirs, modChanged)
+}
+
+// nearestPackageDir returns the closest parent directory of file that is one
+// of the pkgDirs.
+func nearestPackageDir(pkgDirs map[string]bool, file string) (string, bool) {
+	dir := filepath.Dir(file)
+	for {
+		if pkgDirs[dir] {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// packagesAffectedByDirs returns the sorted import paths of all the packages
+// with tests that are in one of the changedDirs, or that import a package in
+// one of the changedDirs. When modChanged is true every package is affected.
+func packagesAffectedByDirs(pkgs []listedPackage, changedDirs map[string]bool, modChanged bool) []string {
+	changedPkgs := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.ForTest == "" && !pkg.isTestMain() && changedDirs[pkg.Dir] {
+			changedPkgs[pkg.ImportPath] = true
+		}
+	}
+
+	var result []string
+	for _, pkg := range pkgs {
+		if !pkg.isTestMain() {
+			continue
+		}
+		name := strings.TrimSuffix(pkg.ImportPath, ".test")
+		if modChanged || changedPkgs[name] || anyDepChanged(pkg.Deps, changedPkgs) {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func anyDepChanged(deps []string, changed map[string]bool) bool {
+	for _, dep := range deps {
+		// strip the test variant suffix, ex: "example.com/pkg [example.com/pkg.test]"
+		if i := strings.Index(dep, " ["); i > 0 {
+			dep = dep[:i]
+		}
+		if changed[dep] {
+			return true
+		}
+	}
+	return false
+}
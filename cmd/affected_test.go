@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/skip"
+)
+
+func TestFilterAffectedPackages(t *testing.T) {
+	pkgs := []listedPackage{
+		{ImportPath: "example.com/a", Name: "a", Dir: "/src/a"},
+		{ImportPath: "example.com/b", Name: "b", Dir: "/src/b", Deps: []string{"example.com/a"}},
+		{ImportPath: "example.com/c", Name: "c", Dir: "/src/c"},
+		{ImportPath: "example.com/d", Name: "d", Dir: "/src/d"},
+		{
+			ImportPath: "example.com/a.test",
+			Name:       "main",
+			Dir:        "/src/a",
+			Deps:       []string{"example.com/a [example.com/a.test]"},
+		},
+		{
+			ImportPath: "example.com/b.test",
+			Name:       "main",
+			Dir:        "/src/b",
+			Deps:       []string{"example.com/a", "example.com/b [example.com/b.test]"},
+		},
+		{
+			ImportPath: "example.com/c.test",
+			Name:       "main",
+			Dir:        "/src/c",
+			Deps:       []string{"example.com/c [example.com/c.test]", "example.com/d"},
+		},
+		{
+			ImportPath: "example.com/c [example.com/c.test]",
+			ForTest:    "example.com/c",
+			Name:       "c",
+			Dir:        "/src/c",
+		},
+	}
+
+	t.Run("no changes", func(t *testing.T) {
+		assert.Assert(t, len(filterAffectedPackages(pkgs, nil)) == 0)
+	})
+	t.Run("change to imported package", func(t *testing.T) {
+		actual := filterAffectedPackages(pkgs, []string{"/src/a/file.go"})
+		assert.DeepEqual(t, actual, []string{"example.com/a", "example.com/b"})
+	})
+	t.Run("change to test import", func(t *testing.T) {
+		actual := filterAffectedPackages(pkgs, []string{"/src/d/file.go"})
+		assert.DeepEqual(t, actual, []string{"example.com/c"})
+	})
+	t.Run("change to package with no dependents", func(t *testing.T) {
+		actual := filterAffectedPackages(pkgs, []string{"/src/c/file_test.go"})
+		assert.DeepEqual(t, actual, []string{"example.com/c"})
+	})
+	t.Run("change to testdata of a package", func(t *testing.T) {
+		actual := filterAffectedPackages(pkgs, []string{"/src/c/testdata/golden/out.txt"})
+		assert.DeepEqual(t, actual, []string{"example.com/c"})
+	})
+	t.Run("change outside of any package", func(t *testing.T) {
+		actual := filterAffectedPackages(pkgs, []string{"/src/README.md", "/other/file.go"})
+		assert.Assert(t, len(actual) == 0)
+	})
+	t.Run("change to go.mod", func(t *testing.T) {
+		actual := filterAffectedPackages(pkgs, []string{"/src/go.mod"})
+		assert.DeepEqual(t, actual, []string{"example.com/a", "example.com/b", "example.com/c"})
+	})
+}
+
+func TestGitChangedFiles(t *testing.T) {
+	_, err := exec.LookPath("git")
+	skip.If(t, err != nil, "git is not installed")
+
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile(".gitignore", "ignored.txt\n"),
+		fs.WithDir("a", fs.WithFile("a.go", "package a\n"), fs.WithFile("b.go", "package a\n")))
+	defer env.ChangeWorkingDir(t, dir.Path())()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		_, err := gitOutput(args...)
+		assert.NilError(t, err)
+	}
+	writeFile(t, dir.Join("a", "a.go"), "package a // changed\n")
+	writeFile(t, dir.Join("a", "new.go"), "package a\n")
+	writeFile(t, dir.Join("ignored.txt"), "ignored")
+
+	// the git root may be a different path to the same directory, ex: on macOS
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	assert.NilError(t, err)
+	root = strings.TrimSpace(root)
+
+	files, err := gitChangedFiles("HEAD")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, files, []string{
+		filepath.Join(root, "a", "a.go"),
+		filepath.Join(root, "a", "new.go"),
+	})
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	osexec "os/exec"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// runAnalyzers runs go vet when --with-vet is set, and the --analyzer-command,
+// after the tests. Diagnostics are added to exec as errors, so that they are
+// included in the summary and reports. exitErr is returned unmodified unless
+// it is nil and an analyzer reported a problem.
+func runAnalyzers(opts *options, exec *testjson.Execution, exitErr error) error {
+	if exec == nil {
+		return exitErr
+	}
+	var commands [][]string
+	if opts.withVet {
+		commands = append(commands, goVetCmdArgs(opts))
+	}
+	if cmd := opts.analyzerCmd.Value(); len(cmd) > 0 {
+		commands = append(commands, cmd)
+	}
+
+	var failed []string
+	for _, args := range commands {
+		ok, err := runAnalyzer(opts, exec, args)
+		if err != nil {
+			log.Errorf("Failed to run %v: %v", strings.Join(args, " "), err)
+		}
+		if !ok {
+			failed = append(failed, strings.Join(args, " "))
+		}
+	}
+	if exitErr != nil || len(failed) == 0 {
+		return exitErr
+	}
+	return categoryError{
+		category: exitBuildError,
+		err:      fmt.Errorf("static analysis reported problems: %v", strings.Join(failed, ", ")),
+	}
+}
+
+// goVetCmdArgs returns the go vet command for the packages being tested. The
+// -tags flag is copied from the go test args.
+func goVetCmdArgs(opts *options) []string {
+	args := []string{"go", "vet"}
+	if start, end := argIndex("tags", opts.args); start >= 0 && end < len(opts.args) {
+		args = append(args, opts.args[start:end+1]...)
+	}
+	return append(args, cmdArgPackageList(opts, rerunOpts{}, "./...")...)
+}
+
+// runAnalyzer runs the command, and adds every diagnostic to exec. It returns
+// false if the command exited non-zero or reported any diagnostics.
+func runAnalyzer(opts *options, execution *testjson.Execution, args []string) (bool, error) {
+	log.Debugf("exec: %s", args)
+	buf := new(bytes.Buffer)
+	cmd := osexec.Command(args[0], args[1:]...)
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	runErr := cmd.Run()
+
+	var exitErr *osexec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return false, runErr
+	}
+
+	before := len(execution.Errors())
+	_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    strings.NewReader(""),
+		Stderr:    diagnosticLines(buf),
+		Execution: execution,
+		Handler:   stderrHandler{out: opts.stderr},
+	})
+	if err != nil {
+		return false, err
+	}
+	return runErr == nil && len(execution.Errors()) == before, nil
+}
+
+// diagnosticLines returns the output of an analyzer with any lines in the JSON
+// format used by staticcheck (-f json) converted to the compiler format.
+func diagnosticLines(out io.Reader) io.Reader {
+	result := new(bytes.Buffer)
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if diag, ok := parseJSONDiagnostic(line); ok {
+			line = diag
+		}
+		result.WriteString(line + "\n")
+	}
+	return result
+}
+
+type jsonDiagnostic struct {
+	Code     string `json:"code"`
+	Location struct {
+		File   string `json:"file"`
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
+	} `json:"location"`
+	Message string `json:"message"`
+}
+
+func parseJSONDiagnostic(line string) (string, bool) {
+	if !strings.HasPrefix(line, "{") {
+		return "", false
+	}
+	var diag jsonDiagnostic
+	if err := json.Unmarshal([]byte(line), &diag); err != nil || diag.Location.File == "" {
+		return "", false
+	}
+	buildErr := testjson.BuildError{
+		File:    diag.Location.File,
+		Line:    diag.Location.Line,
+		Column:  diag.Location.Column,
+		Message: diag.Message,
+	}
+	if diag.Code != "" {
+		buildErr.Message += " (" + diag.Code + ")"
+	}
+	return buildErr.String(), true
+}
+
+// stderrHandler is a testjson.EventHandler which writes stderr lines to out.
+type stderrHandler struct {
+	out io.Writer
+}
+
+func (h stderrHandler) Event(testjson.TestEvent, *testjson.Execution) error {
+	return nil
+}
+
+func (h stderrHandler) Err(text string) error {
+	_, _ = fmt.Fprintln(h.out, text)
+	return nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestGoVetCmdArgs(t *testing.T) {
+	type testCase struct {
+		opts     *options
+		expected []string
+	}
+	fn := func(t *testing.T, tc testCase) {
+		assert.DeepEqual(t, goVetCmdArgs(tc.opts), tc.expected)
+	}
+	testCases := map[string]testCase{
+		"no args": {
+			opts:     &options{},
+			expected: []string{"go", "vet", "./..."},
+		},
+		"packages": {
+			opts:     &options{packages: []string{"./cmd", "./testjson"}},
+			expected: []string{"go", "vet", "./cmd", "./testjson"},
+		},
+		"tags flag": {
+			opts: &options{
+				args:     []string{"-tags", "integration"},
+				packages: []string{"./pkg"},
+			},
+			expected: []string{"go", "vet", "-tags", "integration", "./pkg"},
+		},
+		"tags flag with equal": {
+			opts:     &options{args: []string{"-count=1", "-tags=integration"}},
+			expected: []string{"go", "vet", "-tags=integration", "./..."},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fn(t, tc)
+		})
+	}
+}
+
+func TestDiagnosticLines(t *testing.T) {
+	in := strings.Join([]string{
+		`{"code":"SA4006","severity":"error","location":{"file":"/src/a/a.go","line":12,"column":3},"message":"this value of x is never used"}`,
+		`{"code":"S1000","severity":"error","location":{"file":"b.go","line":4,"column":0},"message":"should use a simple channel send"}`,
+		`./c.go:1:2: unreachable code`,
+		`{"not":"a diagnostic"}`,
+	}, "\n")
+
+	out, err := ioutil.ReadAll(diagnosticLines(strings.NewReader(in)))
+	assert.NilError(t, err)
+	expected := `/src/a/a.go:12:3: this value of x is never used (SA4006)
+b.go:4: should use a simple channel send (S1000)
+./c.go:1:2: unreachable code
+{"not":"a diagnostic"}
+`
+	assert.Equal(t, string(out), expected)
+}
+
+func TestRunAnalyzers(t *testing.T) {
+	t.Run("no problems", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		opts := &options{stderr: buf, analyzerCmd: &commandValue{}}
+		assert.NilError(t, opts.analyzerCmd.Set("true"))
+
+		exec := newExecFromTestData(t)
+		assert.NilError(t, runAnalyzers(opts, exec, nil))
+		assert.Equal(t, buf.String(), "")
+	})
+
+	t.Run("diagnostics are added as errors", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		opts := &options{stderr: buf, analyzerCmd: &commandValue{}}
+		assert.NilError(t, opts.analyzerCmd.Set(`echo "./c.go:1:2: unreachable code"`))
+
+		exec := newExecFromTestData(t)
+		err := runAnalyzers(opts, exec, nil)
+		var catErr categoryError
+		assert.Assert(t, errors.As(err, &catErr))
+		assert.Equal(t, catErr.category, exitBuildError)
+		assert.ErrorContains(t, err, "static analysis reported problems")
+		assert.Equal(t, buf.String(), "./c.go:1:2: unreachable code\n")
+		assert.DeepEqual(t, exec.Errors(), []string{"./c.go:1:2: unreachable code"})
+		assert.Equal(t, len(exec.BuildErrors()), 1)
+	})
+
+	t.Run("exit error is preserved", func(t *testing.T) {
+		opts := &options{stderr: ioutil.Discard, analyzerCmd: &commandValue{}}
+		assert.NilError(t, opts.analyzerCmd.Set("false"))
+
+		exitErr := exitError{num: 1}
+		exec := newExecFromTestData(t)
+		assert.Equal(t, runAnalyzers(opts, exec, exitErr), error(exitErr))
+	})
+}
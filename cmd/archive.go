@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// archiveManifestName is the name of the manifest file in the --archive.
+const archiveManifestName = "manifest.json"
+
+// archiveFile is a file added to the --archive.
+type archiveFile struct {
+	// name of the file in the archive.
+	name string
+	// path of the file on disk.
+	path string
+}
+
+// archiveManifest is the manifest.json in the --archive. It lists every other
+// file in the archive, with its size and SHA-256 digest.
+type archiveManifest struct {
+	Files []archiveManifestFile `json:"files"`
+}
+
+type archiveManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// archiveFiles returns the files written by the run: the report files, their
+// signatures and digests, the coverage profile, the --profile-dir profiles, and the files
+// in the --attachment-dir. Files that do not exist are skipped.
+func archiveFiles(opts *options) []archiveFile {
+	var files []archiveFile
+	seen := make(map[string]bool)
+	add := func(name, path string) {
+		if path == "" || seen[name] {
+			return
+		}
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			return
+		}
+		seen[name] = true
+		files = append(files, archiveFile{name: name, path: path})
+	}
+	addReport := func(path string) {
+		if path == "" {
+			return
+		}
+		name := archiveName(path)
+		add(name, path)
+		add(name+signatureExt, path+signatureExt)
+		add(name+digestExt, path+digestExt)
+	}
+
+	for _, path := range []string{
+		opts.jsonFile,
+		opts.junitFile,
+		opts.summaryJSONFile,
+		opts.reportOutput,
+		opts.csvFile,
+		opts.sarifFile,
+		opts.cucumberFile,
+		opts.nunitFile,
+		opts.baselineOutput,
+		opts.rerunFailsReportFile,
+		opts.reproScript,
+		opts.testManifest,
+		goTestFlagValue("coverprofile", opts.args),
+	} {
+		addReport(path)
+	}
+
+	pkgs := make([]string, 0, len(opts.profiles))
+	for pkg := range opts.profiles {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		// the test binaries are not included, because they are large and
+		// can be rebuilt from the source.
+		for _, path := range []string{opts.profiles[pkg].CPU, opts.profiles[pkg].Memory} {
+			if path != "" {
+				add(filepath.ToSlash(filepath.Join("profiles", pkg, filepath.Base(path))), path)
+			}
+		}
+	}
+
+	if opts.attachmentDir != "" {
+		_ = filepath.Walk(opts.attachmentDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(opts.attachmentDir, path)
+			if err != nil {
+				return nil
+			}
+			add(filepath.ToSlash(filepath.Join(attachmentsDirName, rel)), path)
+			return nil
+		})
+	}
+	return files
+}
+
+// archiveName returns the name of a report file in the archive. Files in the
+// working directory keep their relative path, other files use the base name.
+func archiveName(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			rel, err := filepath.Rel(wd, abs)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return filepath.Base(path)
+}
+
+// goTestFlagValue returns the value of a go test flag in args, or an empty
+// string if the flag is not set.
+func goTestFlagValue(flag string, args []string) string {
+	start, end := argIndex(flag, args)
+	switch {
+	case start < 0:
+		return ""
+	case start == end:
+		return args[start][strings.Index(args[start], "=")+1:]
+	case end < len(args):
+		return args[end]
+	}
+	return ""
+}
+
+// writeArchive writes the files written by the run, and a manifest of
+// the files, to the --archive gzipped tar file.
+func writeArchive(opts *options) error {
+	if opts.archive == "" {
+		return nil
+	}
+	_ = os.MkdirAll(filepath.Dir(opts.archive), 0o755)
+	fh, err := os.Create(opts.archive)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	if err := writeArchiveTo(fh, archiveFiles(opts), archiveModTime(opts)); err != nil {
+		_ = fh.Close()
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return fh.Close()
+}
+
+// archiveModTime returns the modification time of the files in the archive,
+// or the zero time to use the modification time of each file. With
+// --deterministic every file has the same time, so that the archive is the
+// same for every run.
+func archiveModTime(opts *options) time.Time {
+	switch {
+	case !opts.deterministic:
+		return time.Time{}
+	case !opts.reportTimestamp.IsZero():
+		return opts.reportTimestamp
+	}
+	return time.Unix(0, 0).UTC()
+}
+
+func writeArchiveTo(out io.Writer, files []archiveFile, modTime time.Time) error {
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	manifest := archiveManifest{Files: []archiveManifestFile{}}
+	for _, file := range files {
+		entry, err := addToArchive(tw, file, modTime)
+		if err != nil {
+			return fmt.Errorf("failed to add %v: %w", file.path, err)
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	raw = append(raw, '\n')
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	header := &tar.Header{
+		Name:    archiveManifestName,
+		Mode:    0o644,
+		Size:    int64(len(raw)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.Write(raw); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addToArchive(tw *tar.Writer, file archiveFile, modTime time.Time) (archiveManifestFile, error) {
+	fh, err := os.Open(file.path)
+	if err != nil {
+		return archiveManifestFile{}, err
+	}
+	defer fh.Close() // nolint: errcheck
+
+	info, err := fh.Stat()
+	if err != nil {
+		return archiveManifestFile{}, err
+	}
+	if modTime.IsZero() {
+		modTime = info.ModTime()
+	}
+	header := &tar.Header{
+		Name:    file.name,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return archiveManifestFile{}, err
+	}
+	digest := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, digest), io.LimitReader(fh, info.Size())); err != nil {
+		return archiveManifestFile{}, err
+	}
+	log.Debugf("added %v to archive as %v", file.path, file.name)
+	return archiveManifestFile{
+		Name:   file.name,
+		Size:   info.Size(),
+		SHA256: hex.EncodeToString(digest.Sum(nil)),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestWriteArchive(t *testing.T) {
+	dir := fs.NewDir(t, "archive",
+		fs.WithFile("go-test.json", `{"Action":"pass"}`+"\n"),
+		fs.WithFile("junit.xml", "<testsuites></testsuites>\n"),
+		fs.WithFile("junit.xml.sig", "signature"),
+		fs.WithFile("cover.out", "mode: set\n"),
+		fs.WithDir("profiles",
+			fs.WithFile("cpu.prof", "cpu"),
+			fs.WithFile("pkg.test", "binary")),
+		fs.WithDir("attach",
+			fs.WithDir("TestLogin", fs.WithFile("screenshot.png", "png"))))
+
+	opts := &options{
+		jsonFile:      dir.Join("go-test.json"),
+		junitFile:     dir.Join("junit.xml"),
+		csvFile:       dir.Join("missing.csv"),
+		attachmentDir: dir.Join("attach"),
+		archive:       dir.Join("out", "run.tar.gz"),
+		args:          []string{"-coverprofile=" + dir.Join("cover.out"), "./..."},
+		profiles: map[string]*jsonsummary.Profiles{
+			"example.com/pkg": {
+				CPU:    dir.Join("profiles", "cpu.prof"),
+				Binary: dir.Join("profiles", "pkg.test"),
+			},
+		},
+		deterministic:   true,
+		reportTimestamp: time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC),
+	}
+	assert.NilError(t, writeArchive(opts))
+
+	contents := readArchive(t, opts.archive, opts.reportTimestamp)
+	expected := map[string]string{
+		"go-test.json":                         `{"Action":"pass"}` + "\n",
+		"junit.xml":                            "<testsuites></testsuites>\n",
+		"junit.xml.sig":                        "signature",
+		"cover.out":                            "mode: set\n",
+		"profiles/example.com/pkg/cpu.prof":    "cpu",
+		"attachments/TestLogin/screenshot.png": "png",
+	}
+	manifest := contents[archiveManifestName]
+	delete(contents, archiveManifestName)
+	assert.DeepEqual(t, contents, expected)
+
+	var m archiveManifest
+	assert.NilError(t, json.Unmarshal([]byte(manifest), &m))
+	assert.Equal(t, len(m.Files), len(expected))
+	for _, file := range m.Files {
+		digest := sha256.Sum256([]byte(expected[file.Name]))
+		assert.Equal(t, file.SHA256, hex.EncodeToString(digest[:]), file.Name)
+		assert.Equal(t, file.Size, int64(len(expected[file.Name])), file.Name)
+	}
+}
+
+func readArchive(t *testing.T, path string, modTime time.Time) map[string]string {
+	t.Helper()
+	fh, err := os.Open(path)
+	assert.NilError(t, err)
+	defer fh.Close()
+	gz, err := gzip.NewReader(fh)
+	assert.NilError(t, err)
+	tr := tar.NewReader(gz)
+	contents := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return contents
+		}
+		assert.NilError(t, err)
+		assert.Assert(t, header.ModTime.Equal(modTime), header.Name)
+		raw, err := ioutil.ReadAll(tr)
+		assert.NilError(t, err)
+		contents[header.Name] = string(raw)
+	}
+}
+
+func TestWriteArchive_NotSet(t *testing.T) {
+	assert.NilError(t, writeArchive(&options{}))
+}
+
+func TestGoTestFlagValue(t *testing.T) {
+	type testCase struct {
+		args     []string
+		expected string
+	}
+	testCases := []testCase{
+		{args: []string{"-coverprofile=c.out", "./..."}, expected: "c.out"},
+		{args: []string{"-count=1", "--coverprofile", "c.out"}, expected: "c.out"},
+		{args: []string{"-coverprofile"}, expected: ""},
+		{args: []string{"-cover", "./..."}, expected: ""},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, goTestFlagValue("coverprofile", tc.args), tc.expected, tc.args)
+	}
+}
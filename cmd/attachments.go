@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// attachmentDirEnv is the environment variable set for 'go test' to the
+// directory where tests should write files to attach them to the report.
+const attachmentDirEnv = "GOTESTSUM_ATTACHMENT_DIR"
+
+// attachmentsDirName is the name of the directory, next to the JUnit XML file,
+// where attachments are copied.
+const attachmentsDirName = "attachments"
+
+type attachmentKey struct {
+	pkg  string
+	test string
+}
+
+// attachments are the files attached to each test case.
+type attachments struct {
+	files map[attachmentKey][]string
+}
+
+func newAttachments() *attachments {
+	return &attachments{files: make(map[attachmentKey][]string)}
+}
+
+func (a *attachments) add(pkg, test, path string) {
+	key := attachmentKey{pkg: pkg, test: test}
+	for _, existing := range a.files[key] {
+		if existing == path {
+			return
+		}
+	}
+	a.files[key] = append(a.files[key], path)
+}
+
+// attachMarker is the prefix of a line of test output which attaches the
+// file at the path that follows the marker to the test.
+const attachMarker = "::attach::"
+
+// addFromOutput attaches a file to the test if the output of event contains
+// an attachMarker. It is safe to call on a nil attachments.
+func (a *attachments) addFromOutput(event testjson.TestEvent) {
+	if a == nil || event.Test == "" || event.Action != testjson.ActionOutput {
+		return
+	}
+	idx := strings.Index(event.Output, attachMarker)
+	if idx < 0 {
+		return
+	}
+	path := strings.TrimSpace(event.Output[idx+len(attachMarker):])
+	if path == "" {
+		return
+	}
+	a.add(event.Package, event.Test, path)
+}
+
+// get returns the files attached to the test case. It is safe to call on a
+// nil attachments.
+func (a *attachments) get(pkg, test string) []string {
+	if a == nil {
+		return nil
+	}
+	return a.files[attachmentKey{pkg: pkg, test: test}]
+}
+
+// testCaseAttachments returns a function used by the reports to lookup the
+// attachments of a test case, or nil if there are no attachments.
+func (a *attachments) testCaseAttachments() func(tc testjson.TestCase) []string {
+	if a == nil || len(a.files) == 0 {
+		return nil
+	}
+	return func(tc testjson.TestCase) []string {
+		return a.get(tc.Package, tc.Test.Name())
+	}
+}
+
+// setupAttachmentDir creates the --attachment-dir, and sets the environment
+// variable used by tests to find the directory.
+func setupAttachmentDir(opts *options) error {
+	if opts.attachmentDir == "" {
+		return nil
+	}
+	dir, err := filepath.Abs(opts.attachmentDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+	opts.attachmentDir = dir
+	opts.attachments = newAttachments()
+	return os.Setenv(attachmentDirEnv, dir)
+}
+
+// collectAttachments finds the files written by tests to the --attachment-dir
+// and associates them with a test case. Files are expected to be written to
+// a directory named after the test (the value of t.Name()), optionally in a
+// directory named after the import path of the package. For example:
+//
+//	$GOTESTSUM_ATTACHMENT_DIR/example.com/pkg/TestLogin/screenshot.png
+//	$GOTESTSUM_ATTACHMENT_DIR/TestLogin/screenshot.png
+//
+// When --junitfile is set the files are copied to a directory next to the
+// JUnit XML file, so that they can be archived with the report.
+func collectAttachments(opts *options, exec *testjson.Execution) error {
+	if opts.attachmentDir == "" || opts.attachments == nil || exec == nil {
+		return nil
+	}
+	tests := testNamesByPackage(exec)
+	return filepath.Walk(opts.attachmentDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(opts.attachmentDir, path)
+		if err != nil {
+			return err
+		}
+		keys := matchAttachment(tests, filepath.ToSlash(rel))
+		switch len(keys) {
+		case 0:
+			log.Warnf("Attachment %v does not match any test", rel)
+			return nil
+		case 1:
+		default:
+			log.Warnf("Attachment %v matches a test in more than one package, "+
+				"use a directory named after the package import path", rel)
+		}
+		if opts.junitFile != "" {
+			dest, err := filepath.Abs(filepath.Join(filepath.Dir(opts.junitFile), attachmentsDirName, rel))
+			if err != nil {
+				return err
+			}
+			if dest != path {
+				if err := copyFile(path, dest); err != nil {
+					return fmt.Errorf("failed to copy attachment: %w", err)
+				}
+			}
+			path = dest
+		}
+		for _, key := range keys {
+			opts.attachments.add(key.pkg, key.test, path)
+		}
+		return nil
+	})
+}
+
+// testNamesByPackage returns the set of test names in each package.
+func testNamesByPackage(exec *testjson.Execution) map[string]map[string]bool {
+	result := make(map[string]map[string]bool)
+	for _, pkgname := range exec.Packages() {
+		names := make(map[string]bool)
+		for _, tc := range exec.Package(pkgname).TestCases() {
+			names[tc.Test.Name()] = true
+		}
+		result[pkgname] = names
+	}
+	return result
+}
+
+// matchAttachment returns the test cases for the attachment at rel, a slash
+// separated path relative to the attachment directory. If rel starts with the
+// import path of a package, only tests in the longest matching package are
+// considered. Otherwise every package with a matching test name is returned.
+// When more than one test name matches, the longest test name is used.
+func matchAttachment(tests map[string]map[string]bool, rel string) []attachmentKey {
+	pkgs := make([]string, 0, len(tests))
+	for pkg := range tests {
+		pkgs = append(pkgs, pkg)
+	}
+	// longest first, so that the most specific package matches
+	sort.Slice(pkgs, func(i, j int) bool {
+		if len(pkgs[i]) != len(pkgs[j]) {
+			return len(pkgs[i]) > len(pkgs[j])
+		}
+		return pkgs[i] < pkgs[j]
+	})
+	for _, pkg := range pkgs {
+		if strings.HasPrefix(rel, pkg+"/") {
+			if test := matchTestName(tests[pkg], strings.TrimPrefix(rel, pkg+"/")); test != "" {
+				return []attachmentKey{{pkg: pkg, test: test}}
+			}
+		}
+	}
+
+	var keys []attachmentKey
+	for _, pkg := range pkgs {
+		if test := matchTestName(tests[pkg], rel); test != "" {
+			keys = append(keys, attachmentKey{pkg: pkg, test: test})
+		}
+	}
+	return keys
+}
+
+// matchTestName returns the longest leading directories of rel which are the
+// name of a test in names.
+func matchTestName(names map[string]bool, rel string) string {
+	parts := strings.Split(rel, "/")
+	// the last part is the file name
+	for i := len(parts) - 1; i > 0; i-- {
+		if name := strings.Join(parts[:i], "/"); names[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint: errcheck
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/testjson"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestMatchAttachment(t *testing.T) {
+	tests := map[string]map[string]bool{
+		"example.com/a":     {"TestLogin": true, "TestLogin/sub": true, "TestShared": true},
+		"example.com/a/sub": {"TestOther": true, "TestShared": true},
+	}
+	type testCase struct {
+		rel      string
+		expected []attachmentKey
+	}
+	testCases := []testCase{
+		{
+			rel:      "example.com/a/TestLogin/shot.png",
+			expected: []attachmentKey{{pkg: "example.com/a", test: "TestLogin"}},
+		},
+		{
+			rel:      "example.com/a/TestLogin/sub/shot.png",
+			expected: []attachmentKey{{pkg: "example.com/a", test: "TestLogin/sub"}},
+		},
+		{
+			rel:      "example.com/a/sub/TestOther/log.txt",
+			expected: []attachmentKey{{pkg: "example.com/a/sub", test: "TestOther"}},
+		},
+		{
+			rel:      "TestOther/nested/log.txt",
+			expected: []attachmentKey{{pkg: "example.com/a/sub", test: "TestOther"}},
+		},
+		{
+			rel: "TestShared/log.txt",
+			expected: []attachmentKey{
+				{pkg: "example.com/a/sub", test: "TestShared"},
+				{pkg: "example.com/a", test: "TestShared"},
+			},
+		},
+		{rel: "TestMissing/log.txt"},
+		{rel: "TestLogin"},
+	}
+	for _, tc := range testCases {
+		keys := matchAttachment(tests, tc.rel)
+		assert.DeepEqual(t, keys, tc.expected, cmpAttachmentKey)
+	}
+}
+
+var cmpAttachmentKey = gocmp.AllowUnexported(attachmentKey{})
+
+func TestCollectAttachments(t *testing.T) {
+	attachDir := fs.NewDir(t, "attachments",
+		fs.WithDir("gotest.tools",
+			fs.WithDir("gotestsum",
+				fs.WithDir("testjson",
+					fs.WithDir("internal",
+						fs.WithDir("good",
+							fs.WithDir("TestPassed",
+								fs.WithFile("log.txt", "passed log"))))))),
+		fs.WithDir("TestNestedSuccess",
+			fs.WithDir("a", fs.WithFile("shot.png", "png"))))
+	reportDir := fs.NewDir(t, "report")
+	env.Patch(t, attachmentDirEnv, "")
+
+	opts := &options{
+		attachmentDir: attachDir.Path(),
+		junitFile:     reportDir.Join("junit.xml"),
+	}
+	assert.NilError(t, setupAttachmentDir(opts))
+	assert.Equal(t, os.Getenv(attachmentDirEnv), attachDir.Path())
+
+	exec := newExecFromTestData(t)
+	assert.NilError(t, collectAttachments(opts, exec))
+
+	pkg := "gotest.tools/gotestsum/testjson/internal/good"
+	expected := reportDir.Join(attachmentsDirName, "gotest.tools/gotestsum/testjson/internal/good/TestPassed/log.txt")
+	assert.DeepEqual(t, opts.attachments.get(pkg, "TestPassed"), []string{expected})
+	raw, err := ioutil.ReadFile(expected)
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), "passed log")
+
+	// TestNestedSuccess is in more than one package
+	nested := opts.attachments.get(pkg, "TestNestedSuccess/a")
+	assert.DeepEqual(t, nested, []string{
+		filepath.Join(reportDir.Path(), attachmentsDirName, "TestNestedSuccess", "a", "shot.png"),
+	})
+}
+
+func TestAttachments_AddFromOutput(t *testing.T) {
+	a := newAttachments()
+	events := []testjson.TestEvent{
+		{Action: testjson.ActionOutput, Package: "pkg", Test: "TestA", Output: "    a_test.go:12: ::attach::/tmp/shot.png\n"},
+		{Action: testjson.ActionOutput, Package: "pkg", Test: "TestA", Output: "::attach:: /tmp/log.txt\n"},
+		{Action: testjson.ActionOutput, Package: "pkg", Test: "TestA", Output: "::attach::/tmp/shot.png\n"},
+		{Action: testjson.ActionOutput, Package: "pkg", Test: "TestA", Output: "::attach::\n"},
+		{Action: testjson.ActionOutput, Package: "pkg", Output: "::attach::/tmp/package.txt\n"},
+		{Action: testjson.ActionPass, Package: "pkg", Test: "TestB", Output: "::attach::/tmp/b.txt\n"},
+	}
+	for _, event := range events {
+		a.addFromOutput(event)
+	}
+	assert.DeepEqual(t, a.get("pkg", "TestA"), []string{"/tmp/shot.png", "/tmp/log.txt"})
+	assert.Equal(t, len(a.files), 1)
+
+	var nilAttachments *attachments
+	nilAttachments.addFromOutput(events[0])
+	assert.Assert(t, nilAttachments.testCaseAttachments() == nil)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// warnUnattributedOutput prints a warning for each test that received output
+// printed by a different test, so that the tests which write to os.Stdout
+// directly can be fixed to use t.Log. The warnings are only printed with
+// --strict-output-attribution.
+func warnUnattributedOutput(opts *options, execution *testjson.Execution) {
+	if !opts.strictOutputAttribution {
+		return
+	}
+	type key struct {
+		pkg, test, movedTo string
+	}
+	var order []key
+	counts := make(map[key]int)
+	for _, out := range execution.UnattributedOutput() {
+		k := key{pkg: out.Package, test: out.Test, movedTo: out.AttributedTo}
+		if counts[k] == 0 {
+			order = append(order, k)
+		}
+		counts[k]++
+	}
+	for _, k := range order {
+		name := opts.packageName(k.pkg) + "." + k.test
+		if k.movedTo == "" {
+			log.Warnf("%d lines of output attributed to %v were printed by an unknown test, "+
+				"and were moved to the package output", counts[k], name)
+			continue
+		}
+		log.Warnf("%d lines of output attributed to %v were printed by %v", counts[k], name, k.movedTo)
+	}
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// baselineValue is a flag.Value for the --baseline file of known failures.
+// Each line of the file is the name of a test, prefixed by the import path of
+// its package, like example.com/pkg.TestName. Blank lines and lines that
+// start with # are ignored. The lines of a --rerun-fails-report file are also
+// accepted.
+type baselineValue struct {
+	filename string
+	// tests is the set of names in the file.
+	tests map[string]bool
+}
+
+// rerunReportSuffix matches the counts at the end of a line in the
+// --rerun-fails-report file.
+var rerunReportSuffix = regexp.MustCompile(`: \d+ runs, \d+ failures$`)
+
+func (v *baselineValue) Set(filename string) error {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read baseline file: %w", err)
+	}
+	tests := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tests[rerunReportSuffix.ReplaceAllString(line, "")] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read baseline file: %w", err)
+	}
+	v.filename = filename
+	v.tests = tests
+	return nil
+}
+
+func (v *baselineValue) String() string {
+	return v.filename
+}
+
+func (v *baselineValue) Type() string {
+	return "filename"
+}
+
+// contains returns true if the test, or one of its parents, is in the
+// baseline.
+func (v *baselineValue) contains(name string) bool {
+	for {
+		if v.tests[name] {
+			return true
+		}
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			return false
+		}
+		name = name[:i]
+	}
+}
+
+// baselineName returns the name of a test in the baseline file. A failure of
+// TestMain, or of a package without tests, uses the name of the package.
+func baselineName(tc testjson.TestCase) string {
+	if tc.Test == "" {
+		return tc.Package
+	}
+	return tc.Package + "." + tc.Test.Name()
+}
+
+// baselineResult compares the tests that failed in a run to the baseline.
+type baselineResult struct {
+	// stillFailing are the failed tests that are in the baseline.
+	stillFailing []string
+	// newFailures are the failed tests that are not in the baseline.
+	newFailures []string
+	// nowPassing are the tests in the baseline that ran, and passed.
+	nowPassing []string
+}
+
+// failingTests returns the names of the tests that failed in the last run of
+// the test, sorted by name. A test that failed, and then passed when it was
+// re-run is not included.
+func failingTests(exec *testjson.Execution) []string {
+	var names []string
+	for _, tc := range failingTestCases(exec) {
+		names = append(names, baselineName(tc))
+	}
+	return names
+}
+
+// failingTestCases returns the last failed run of each test that failed in
+// the last run of the test, sorted by baselineName.
+func failingTestCases(exec *testjson.Execution) []testjson.TestCase {
+	last := lastRunFailed(exec)
+	byName := make(map[string]testjson.TestCase)
+	var names []string
+	for _, tc := range exec.Failed() {
+		name := baselineName(tc)
+		if failed, ok := last[name]; ok && !failed {
+			continue
+		}
+		prev, seen := byName[name]
+		if !seen {
+			names = append(names, name)
+		}
+		if !seen || tc.ID > prev.ID {
+			byName[name] = tc
+		}
+	}
+	sort.Strings(names)
+	result := make([]testjson.TestCase, 0, len(names))
+	for _, name := range names {
+		result = append(result, byName[name])
+	}
+	return result
+}
+
+// lastRunFailed returns true for each test that failed in the last run of the
+// test, and false for each test that passed in the last run.
+func lastRunFailed(exec *testjson.Execution) map[string]bool {
+	type run struct {
+		id     int
+		failed bool
+	}
+	last := make(map[string]run)
+	record := func(tc testjson.TestCase, failed bool) {
+		name := baselineName(tc)
+		if prev, ok := last[name]; !ok || tc.ID >= prev.id {
+			last[name] = run{id: tc.ID, failed: failed}
+		}
+	}
+	for _, pkgName := range exec.Packages() {
+		pkg := exec.Package(pkgName)
+		for _, tc := range pkg.Passed {
+			record(tc, false)
+		}
+		for _, tc := range pkg.Failed {
+			record(tc, true)
+		}
+	}
+	result := make(map[string]bool, len(last))
+	for name, r := range last {
+		result[name] = r.failed
+	}
+	return result
+}
+
+func (v *baselineValue) compare(exec *testjson.Execution) *baselineResult {
+	if v.tests == nil || exec == nil {
+		return nil
+	}
+	result := &baselineResult{}
+	for _, name := range failingTests(exec) {
+		if v.contains(name) {
+			result.stillFailing = append(result.stillFailing, name)
+			continue
+		}
+		result.newFailures = append(result.newFailures, name)
+	}
+	last := lastRunFailed(exec)
+	for name := range v.tests {
+		if failed, ok := last[name]; ok && !failed {
+			result.nowPassing = append(result.nowPassing, name)
+		}
+	}
+	sort.Strings(result.nowPassing)
+	return result
+}
+
+// ignoreKnownFailures returns nil when the only reason the run failed is
+// tests that are known to fail, like the tests in the --baseline.
+func ignoreKnownFailures(exec *testjson.Execution, exitErr error, known func(name string) bool) error {
+	if !canIgnoreFailures(exec, exitErr) {
+		return exitErr
+	}
+	names := failingTests(exec)
+	if len(names) == 0 {
+		return exitErr
+	}
+	for _, name := range names {
+		if !known(name) {
+			return exitErr
+		}
+	}
+	return nil
+}
+
+// canIgnoreFailures returns true if the run failed only because tests failed.
+// Build errors, timeouts, and panics are never ignored, because they may hide
+// the result of other tests.
+func canIgnoreFailures(exec *testjson.Execution, exitErr error) bool {
+	switch {
+	case exitErr == nil || exec == nil:
+		return false
+	case len(exec.Errors()) > 0 || exec.HasTimeout() || exec.HasPanic():
+		return false
+	}
+	return isTestFailure(exitErr)
+}
+
+// isTestFailure returns true if exitErr is the result of go test failing
+// because of test failures.
+func isTestFailure(exitErr error) bool {
+	var catErr categoryError
+	if errors.As(exitErr, &catErr) {
+		return catErr.category == exitRerunExhausted
+	}
+	return IsExitCoder(exitErr) && ExitCodeWithDefault(exitErr) == 1
+}
+
+func (r *baselineResult) print(out io.Writer) {
+	if r == nil {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Baseline: %d still failing, %d new failures, %d now passing\n",
+		len(r.stillFailing), len(r.newFailures), len(r.nowPassing))
+	for _, name := range r.newFailures {
+		fmt.Fprintf(out, "=== NEW FAILURE: %s\n", name)
+	}
+	for _, name := range r.stillFailing {
+		fmt.Fprintf(out, "=== STILL FAILING: %s\n", name)
+	}
+	for _, name := range r.nowPassing {
+		fmt.Fprintf(out, "=== NOW PASSING: %s (remove it from the baseline)\n", name)
+	}
+}
+
+// writeBaseline writes the tests that failed to the --baseline-output file,
+// in the format read by --baseline.
+func writeBaseline(opts *options, exec *testjson.Execution) error {
+	if opts.baselineOutput == "" {
+		return nil
+	}
+	_ = os.MkdirAll(filepath.Dir(opts.baselineOutput), 0o755)
+	fh, err := os.Create(opts.baselineOutput)
+	if err != nil {
+		return fmt.Errorf("failed to open baseline file: %v", err)
+	}
+	defer func() {
+		if err := fh.Close(); err != nil {
+			log.Errorf("Failed to close baseline file: %v", err)
+		}
+	}()
+	for _, name := range failingTests(exec) {
+		if _, err := fmt.Fprintln(fh, name); err != nil {
+			return fmt.Errorf("failed to write baseline file: %w", err)
+		}
+	}
+	return nil
+}
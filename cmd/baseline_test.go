@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestBaselineValue_Set(t *testing.T) {
+	file := fs.NewFile(t, "baseline.txt", fs.WithContent(`# known failures
+example.com/pkg.TestOne
+
+example.com/pkg.TestTwo: 3 runs, 3 failures
+example.com/other
+`))
+	var v baselineValue
+	assert.NilError(t, v.Set(file.Path()))
+	assert.Equal(t, v.String(), file.Path())
+	assert.DeepEqual(t, v.tests, map[string]bool{
+		"example.com/pkg.TestOne": true,
+		"example.com/pkg.TestTwo": true,
+		"example.com/other":       true,
+	})
+	assert.Assert(t, v.contains("example.com/pkg.TestOne/sub/case"))
+	assert.Assert(t, !v.contains("example.com/pkg.TestOneMore"))
+
+	err := v.Set(file.Path() + "-missing")
+	assert.ErrorContains(t, err, "failed to read baseline file")
+}
+
+func scanBaselineRun(t *testing.T) *testjson.Execution {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestKnown"}
+{"Action":"run","Package":"pkg","Test":"TestKnown/sub"}
+{"Action":"fail","Package":"pkg","Test":"TestKnown/sub"}
+{"Action":"fail","Package":"pkg","Test":"TestKnown"}
+{"Action":"run","Package":"pkg","Test":"TestFixed"}
+{"Action":"pass","Package":"pkg","Test":"TestFixed"}
+{"Action":"run","Package":"pkg","Test":"TestFlaky"}
+{"Action":"fail","Package":"pkg","Test":"TestFlaky"}
+{"Action":"run","Package":"pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"pkg","Test":"TestFlaky"}
+{"Action":"fail","Package":"pkg"}
+`),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestBaselineValue_Compare(t *testing.T) {
+	exec := scanBaselineRun(t)
+	v := baselineValue{tests: map[string]bool{
+		"pkg.TestKnown":   true,
+		"pkg.TestFixed":   true,
+		"pkg.TestMissing": true,
+	}}
+
+	result := v.compare(exec)
+	expected := &baselineResult{
+		stillFailing: []string{"pkg.TestKnown", "pkg.TestKnown/sub"},
+		nowPassing:   []string{"pkg.TestFixed"},
+	}
+	assert.DeepEqual(t, result, expected, cmpBaselineResult)
+
+	exitErr := exitError{num: 1}
+	assert.NilError(t, ignoreKnownFailures(exec, exitErr, v.contains))
+	rerunErr := categoryError{category: exitRerunExhausted, err: exitErr, reported: true}
+	assert.NilError(t, ignoreKnownFailures(exec, rerunErr, v.contains))
+	assert.Equal(t, ignoreKnownFailures(exec, exitError{num: 2}, v.contains), exitError{num: 2})
+	internalErr := errors.New("failed")
+	assert.Equal(t, ignoreKnownFailures(exec, internalErr, v.contains), internalErr)
+
+	out := new(bytes.Buffer)
+	result.print(out)
+	assert.Equal(t, out.String(), `
+=== Baseline: 2 still failing, 0 new failures, 1 now passing
+=== STILL FAILING: pkg.TestKnown
+=== STILL FAILING: pkg.TestKnown/sub
+=== NOW PASSING: pkg.TestFixed (remove it from the baseline)
+`)
+}
+
+func TestBaselineValue_Compare_NewFailure(t *testing.T) {
+	exec := scanBaselineRun(t)
+	v := baselineValue{tests: map[string]bool{"pkg.TestOther": true}}
+
+	result := v.compare(exec)
+	expected := &baselineResult{newFailures: []string{"pkg.TestKnown", "pkg.TestKnown/sub"}}
+	assert.DeepEqual(t, result, expected, cmpBaselineResult)
+
+	exitErr := exitError{num: 1}
+	assert.Equal(t, ignoreKnownFailures(exec, exitErr, v.contains), exitErr)
+
+	var empty baselineValue
+	assert.Assert(t, empty.compare(exec) == nil)
+}
+
+var cmpBaselineResult = gocmp.AllowUnexported(baselineResult{})
+
+func TestWriteBaseline(t *testing.T) {
+	dir := fs.NewDir(t, "baseline")
+	opts := &options{baselineOutput: dir.Join("out", "baseline.txt")}
+	assert.NilError(t, writeBaseline(opts, scanBaselineRun(t)))
+
+	raw, err := ioutil.ReadFile(opts.baselineOutput)
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), "pkg.TestKnown\npkg.TestKnown/sub\n")
+
+	var v baselineValue
+	assert.NilError(t, v.Set(opts.baselineOutput))
+	assert.Equal(t, len(v.compare(scanBaselineRun(t)).newFailures), 0)
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// transientBuildErrors are substrings of errors from the go command that are
+// caused by a temporary problem with the network or the module proxy, and
+// not by the code being tested.
+var transientBuildErrors = []string{
+	"connection reset by peer",
+	"connection refused",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"unexpected EOF",
+	"temporary failure in name resolution",
+	"server misbehaving",
+	"502 Bad Gateway",
+	"503 Service Unavailable",
+	"504 Gateway Timeout",
+	"429 Too Many Requests",
+}
+
+func isTransientBuildError(line string) bool {
+	for _, msg := range transientBuildErrors {
+		if strings.Contains(line, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// allTransientBuildErrors returns true if there is at least one error, and all
+// the errors are transient.
+func allTransientBuildErrors(errs []string) bool {
+	var transient bool
+	for _, line := range errs {
+		switch {
+		case isTransientBuildError(line):
+			transient = true
+		case strings.HasPrefix(line, "\t"), strings.HasPrefix(line, "FAIL"):
+			// continuation of a previous error, or the result of a package
+		default:
+			return false
+		}
+	}
+	return transient
+}
+
+// buildFailedPackages returns the packages that failed without running any
+// tests.
+func buildFailedPackages(exec *testjson.Execution) []string {
+	var pkgs []string
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if pkg.Result() == testjson.ActionFail && pkg.Total == 0 {
+			pkgs = append(pkgs, name)
+		}
+	}
+	return pkgs
+}
+
+// retryTransientBuildErrors runs 'go test' again for the packages that failed
+// to build when all the errors from the previous run were transient. Each
+// retry waits twice as long as the previous one. The errors from the last
+// attempt remain in exec, so that persistent failures are reported as build
+// errors. The returned error is the exitErr from the last attempt, or nil
+// when the retry succeeded and no tests failed.
+func retryTransientBuildErrors(
+	ctx context.Context,
+	opts *options,
+	cfg testjson.ScanConfig,
+	exitErr error,
+) (error, error) {
+	exec := cfg.Execution
+	delay := opts.buildRetryDelay
+	for attempt := 1; attempt <= opts.buildRetries; attempt++ {
+		if exitErr == nil || !allTransientBuildErrors(exec.Errors()) {
+			return exitErr, nil
+		}
+		pkgs := buildFailedPackages(exec)
+		log.Warnf("Retrying %v after transient build errors in %v (attempt %d of %d)",
+			retryTarget(pkgs), delay, attempt, opts.buildRetries)
+		select {
+		case <-ctx.Done():
+			return exitErr, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+
+		testFailed := hasFailuresOutside(exec, pkgs)
+		exec.RemoveErrors()
+		retryErr, err := runBuildRetry(ctx, opts, cfg, pkgs)
+		if err != nil {
+			return exitErr, err
+		}
+		switch {
+		case retryErr != nil:
+			exitErr = retryErr
+		case !testFailed:
+			exitErr = nil
+		}
+	}
+	return exitErr, nil
+}
+
+// hasFailuresOutside returns true if a package that is not in pkgs failed.
+// When pkgs is empty all the packages are run again, so the previous failures
+// are ignored.
+func hasFailuresOutside(exec *testjson.Execution, pkgs []string) bool {
+	if len(pkgs) == 0 {
+		return false
+	}
+	retried := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		retried[pkg] = true
+	}
+	for _, tc := range exec.Failed() {
+		if !retried[tc.Package] {
+			return true
+		}
+	}
+	return false
+}
+
+func retryTarget(pkgs []string) string {
+	switch len(pkgs) {
+	case 0:
+		return "all packages"
+	case 1:
+		return pkgs[0]
+	default:
+		return strings.Join(pkgs, ", ")
+	}
+}
+
+// runBuildRetry runs 'go test' for pkgs, once for each different
+// --package-timeout, --package-args, and --env-group of the packages. When pkgs is empty, the packages from
+// the original command are run.
+func runBuildRetry(
+	ctx context.Context,
+	opts *options,
+	cfg testjson.ScanConfig,
+	pkgs []string,
+) (error, error) {
+	var exitErr error
+	for _, rOpts := range buildRetryRerunOpts(opts, pkgs) {
+		// the packages of rOpts are all in the same --env-group
+		goTestProc, err := startGoTestWithEnv(ctx, "", goTestCmdArgs(opts, rOpts), groupEnv(opts, rOpts))
+		if err != nil {
+			return nil, err
+		}
+		opts.stuck.watch(goTestProc, cfg.Stop)
+		cfg.Stdout = goTestProc.stdout
+		cfg.Stderr = goTestProc.stderr
+		if _, err := testjson.ScanTestOutput(cfg); err != nil {
+			return nil, err
+		}
+		if err := goTestProc.cmd.Wait(); err != nil {
+			exitErr = err
+		}
+		recordUsage(opts, goTestProc, singleItem(rOpts.packages))
+		if err := opts.stuck.abortErr(); err != nil {
+			return nil, err
+		}
+		if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
+			return exitError{num: signalExitCode + int(signum)}, nil
+		}
+	}
+	return exitErr, nil
+}
+
+// groupEnv returns the environment variables of the --env-group of the
+// packages in rOpts.
+func groupEnv(opts *options, rOpts rerunOpts) []string {
+	if len(rOpts.packages) == 0 {
+		return nil
+	}
+	return opts.envGroups.envFor(rOpts.packages[0])
+}
+
+func buildRetryRerunOpts(opts *options, pkgs []string) []rerunOpts {
+	if len(pkgs) == 0 {
+		return []rerunOpts{{}}
+	}
+	type groupKey struct {
+		timeout string
+		args    string
+		env     string
+	}
+	groups := make(map[groupKey]*rerunOpts)
+	for _, pkg := range pkgs {
+		args := opts.packageArgs.argsFor(pkg)
+		envGroup, _ := opts.envGroups.forPackage(pkg)
+		key := groupKey{
+			timeout: opts.packageTimeouts.timeoutArg(pkg),
+			args:    strings.Join(args, " "),
+			env:     envGroup.name,
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &rerunOpts{timeoutFlag: key.timeout, packageArgs: args}
+			groups[key] = group
+		}
+		group.packages = append(group.packages, pkg)
+	}
+	result := make([]rerunOpts, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].timeoutFlag != result[j].timeoutFlag {
+			return result[i].timeoutFlag < result[j].timeoutFlag
+		}
+		if a, b := strings.Join(result[i].packageArgs, " "), strings.Join(result[j].packageArgs, " "); a != b {
+			return a < b
+		}
+		return result[i].packages[0] < result[j].packages[0]
+	})
+	return result
+}
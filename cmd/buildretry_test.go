@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestAllTransientBuildErrors(t *testing.T) {
+	type testCase struct {
+		name     string
+		errs     []string
+		expected bool
+	}
+	run := func(t *testing.T, tc testCase) {
+		assert.Equal(t, allTransientBuildErrors(tc.errs), tc.expected)
+	}
+	testCases := []testCase{
+		{name: "no errors"},
+		{
+			name: "module proxy timeout",
+			errs: []string{
+				`go: example.com/dep@v1.2.0: Get "https://proxy.golang.org/example.com/dep/@v/v1.2.0.zip": dial tcp: i/o timeout`,
+			},
+			expected: true,
+		},
+		{
+			name: "connection reset with continuation",
+			errs: []string{
+				`a/a.go:3:2: example.com/dep@v1.2.0: read tcp 10.0.0.2:5000->10.0.0.1:443: read: connection reset by peer`,
+				"\tmore detail",
+			},
+			expected: true,
+		},
+		{
+			name: "compile error",
+			errs: []string{
+				`go: example.com/dep@v1.2.0: 503 Service Unavailable`,
+				"a/a.go:5:21: undefined: somepackage",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestRun_BuildRetries(t *testing.T) {
+	setupFailed := `{"Package": "example.com/a", "Action": "output", "Output": "FAIL\texample.com/a [setup failed]\n"}
+{"Package": "example.com/a", "Action": "fail"}
+{"Package": "example.com/b", "Action": "run", "Test": "TestOne"}
+{"Package": "example.com/b", "Action": "pass", "Test": "TestOne"}
+{"Package": "example.com/b", "Action": "pass"}
+`
+	passed := `{"Package": "example.com/a", "Action": "run", "Test": "TestOne"}
+{"Package": "example.com/a", "Action": "pass", "Test": "TestOne"}
+{"Package": "example.com/a", "Action": "pass"}
+`
+	var calls [][]string
+	fn := func(args []string) *proc {
+		calls = append(calls, args)
+		if len(calls) == 1 {
+			return &proc{
+				cmd:    fakeWaiter{result: newExitCode("failed", 1)},
+				stdout: strings.NewReader(setupFailed),
+				stderr: strings.NewReader("# example.com/a\n" +
+					"a/a.go:3:2: example.com/dep@v1.2.0: read: connection reset by peer\n"),
+			}
+		}
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(passed),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:       "testname",
+		packages:     []string{"./..."},
+		buildRetries: 2,
+		stdout:       out,
+		stderr:       os.Stderr,
+		hideSummary:  newHideSummaryValue(),
+	}
+	err := run(opts)
+	assert.NilError(t, err, out.String())
+	expected := [][]string{
+		{"go", "test", "-json", "./..."},
+		{"go", "test", "-json", "example.com/a"},
+	}
+	assert.DeepEqual(t, calls, expected)
+}
+
+func TestRun_BuildRetries_PersistentFailure(t *testing.T) {
+	setupFailed := `{"Package": "example.com/a", "Action": "output", "Output": "FAIL\texample.com/a [setup failed]\n"}
+{"Package": "example.com/a", "Action": "fail"}
+`
+	var calls int
+	fn := func(args []string) *proc {
+		calls++
+		return &proc{
+			cmd:    fakeWaiter{result: newExitCode("failed", 1)},
+			stdout: strings.NewReader(setupFailed),
+			stderr: strings.NewReader("go: example.com/dep@v1.2.0: 502 Bad Gateway\n"),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:       "testname",
+		buildRetries: 2,
+		stdout:       out,
+		stderr:       os.Stderr,
+		hideSummary:  newHideSummaryValue(),
+	}
+	err := run(opts)
+	assert.Equal(t, calls, 3)
+	var catErr categoryError
+	assert.Assert(t, errors.As(err, &catErr))
+	assert.Equal(t, catErr.category, exitBuildError)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+// Placeholders in the --command args. Each placeholder must be a separate arg,
+// and is replaced by zero or more args.
+const (
+	commandPackages = "{packages}"
+	commandFlags    = "{flags}"
+)
+
+// validateCommandTemplate returns an error if the --command does not have the
+// placeholders. Both are required, because gotestsum changes the packages and
+// the flags to re-run tests, and to run the packages with different flags.
+func validateCommandTemplate(command []string) error {
+	if len(command) == 0 {
+		return nil
+	}
+	var hasPackages, hasFlags bool
+	for _, arg := range command {
+		switch arg {
+		case commandPackages:
+			hasPackages = true
+		case commandFlags:
+			hasFlags = true
+		}
+	}
+	if !hasPackages || !hasFlags {
+		return fmt.Errorf("--command must include the %v and %v placeholders as separate args",
+			commandPackages, commandFlags)
+	}
+	return nil
+}
+
+// commandTemplateArgs returns the --command args with the placeholders
+// replaced by the packages and the go test flags. The -json flag is not added
+// when the command already has it. The -args flag, and the args for the test
+// binary, are added to the end of the command.
+func commandTemplateArgs(command, flags, pkgs, binaryArgs []string) []string {
+	if boolArgIndex("json", command) >= 0 {
+		if i := boolArgIndex("json", flags); i >= 0 {
+			flags = append(append([]string{}, flags[:i]...), flags[i+1:]...)
+		}
+	}
+	result := make([]string, 0, len(command)+len(flags)+len(pkgs)+len(binaryArgs))
+	for _, arg := range command {
+		switch arg {
+		case commandPackages:
+			result = append(result, pkgs...)
+		case commandFlags:
+			result = append(result, flags...)
+		default:
+			result = append(result, arg)
+		}
+	}
+	return append(result, binaryArgs...)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestValidateCommandTemplate(t *testing.T) {
+	assert.NilError(t, validateCommandTemplate(nil))
+	assert.NilError(t, validateCommandTemplate([]string{"richgo", "test", "{packages}", "{flags}"}))
+
+	err := validateCommandTemplate([]string{"richgo", "test", "{packages}"})
+	assert.Error(t, err, "--command must include the {packages} and {flags} placeholders as separate args")
+	err = validateCommandTemplate([]string{"richgo", "test", "{packages}", "-run={flags}"})
+	assert.ErrorContains(t, err, "as separate args")
+}
+
+func TestGoTestCmdArgs_Command(t *testing.T) {
+	type testCase struct {
+		name      string
+		command   string
+		args      []string
+		packages  []string
+		rerunOpts rerunOpts
+		expected  []string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		opts := &options{command: &commandValue{}, args: tc.args, packages: tc.packages}
+		assert.NilError(t, opts.command.Set(tc.command))
+		assert.DeepEqual(t, goTestCmdArgs(opts, tc.rerunOpts), tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name:     "no args",
+			command:  "richgo test {packages} {flags}",
+			expected: []string{"richgo", "test", "./...", "-json"},
+		},
+		{
+			name:     "command has -json",
+			command:  "bazel run //tools:gotest -- {flags} -json {packages}",
+			args:     []string{"-count=1"},
+			packages: []string{"./cmd", "./internal/..."},
+			expected: []string{"bazel", "run", "//tools:gotest", "--", "-count=1", "-json", "./cmd", "./internal/..."},
+		},
+		{
+			name:    "rerun with binary args",
+			command: "richgo test {packages} {flags}",
+			args:    []string{"-run=TestAll", "-count=1", "-args", "-update"},
+			rerunOpts: rerunOpts{
+				runFlag: "-test.run=^TestOne$",
+				pkg:     "./fails",
+			},
+			expected: []string{"richgo", "test", "./fails", "-json", "-test.run=^TestOne$", "-count=1", "-args", "-update"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
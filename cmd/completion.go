@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// completionWordEnv is the environment variable used by the completion
+// scripts to send the word being completed. An environment variable is used
+// because some shells do not pass empty arguments to commands.
+const completionWordEnv = "GOTESTSUM_COMPLETE_WORD"
+
+// RunCompletion prints a completion script for the shell in args.
+func RunCompletion(name string, args []string) error {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stdout, `Usage:
+    %[1]s bash|zsh|fish|powershell
+
+Print a script that completes the flags, --format values, and package paths
+of the gotestsum command in the shell.
+
+Example: load completions in the current bash shell
+    source <(%[1]s bash)
+`, name)
+	}
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		flags.Usage()
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected one argument, the name of a shell")
+	}
+	command := filepath.Base(strings.Fields(name)[0])
+	script, err := completionScript(flags.Arg(0), command)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(os.Stdout, script)
+	return err
+}
+
+func completionScript(shell string, command string) (string, error) {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	case "powershell", "pwsh":
+		script = powershellCompletion
+	default:
+		return "", fmt.Errorf("unsupported shell %q, must be one of: bash, zsh, fish, powershell", shell)
+	}
+	fn := strings.NewReplacer("-", "_", ".", "_").Replace(command)
+	return strings.NewReplacer("{{command}}", command, "{{func}}", fn).Replace(script), nil
+}
+
+const bashCompletion = `# bash completion for {{command}}
+_{{func}}_complete() {
+    local IFS=$'\n'
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    COMPREPLY=($(GOTESTSUM_COMPLETE_WORD="$cur" {{command}} __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" 2>/dev/null))
+    if [ ${#COMPREPLY[@]} -eq 0 ]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+    fi
+}
+complete -o filenames -F _{{func}}_complete {{command}}
+`
+
+const zshCompletion = `#compdef {{command}}
+_{{func}}_complete() {
+    local -a completions
+    completions=("${(@f)$(GOTESTSUM_COMPLETE_WORD="${words[CURRENT]}" {{command}} __complete "${(@)words[2,CURRENT-1]}" 2>/dev/null)}")
+    if [[ -n "${completions[1]}" ]]; then
+        compadd -- "${completions[@]}"
+    else
+        _files
+    fi
+}
+compdef _{{func}}_complete {{command}}
+`
+
+const fishCompletion = `# fish completion for {{command}}
+function __{{func}}_complete
+    set -l words (commandline -opc)
+    GOTESTSUM_COMPLETE_WORD=(commandline -ct) {{command}} __complete $words[2..-1] 2>/dev/null
+end
+complete -c {{command}} -f -a '(__{{func}}_complete)'
+`
+
+const powershellCompletion = `# powershell completion for {{command}}
+Register-ArgumentCompleter -Native -CommandName '{{command}}' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 |
+        Where-Object { $_.Extent.EndOffset -lt $cursorPosition } |
+        ForEach-Object { $_.ToString() })
+    $env:GOTESTSUM_COMPLETE_WORD = $wordToComplete
+    try {
+        & '{{command}}' __complete @words 2>$null | ForEach-Object {
+            [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+        }
+    } finally {
+        Remove-Item Env:\GOTESTSUM_COMPLETE_WORD
+    }
+}
+`
+
+// RunComplete prints the completions for a partial command line. It is used
+// by the scripts from RunCompletion. args are the words before the word being
+// completed, and the word being completed is read from completionWordEnv.
+func RunComplete(name string, args []string) error {
+	flags, _ := setupFlags(name)
+	for _, c := range completeArgs(flags, args, os.Getenv(completionWordEnv)) {
+		fmt.Fprintln(os.Stdout, c)
+	}
+	return nil
+}
+
+// completionCommands are the commands that may be used as the first argument.
+var completionCommands = []string{"completion", "help", "init", "serve-ui", "tool"}
+
+// completionShells are the arguments of the completion command.
+var completionShells = []string{"bash", "fish", "powershell", "zsh"}
+
+// completeFormat returns the values of the --format flag that start with cur.
+// The values are the formats and the format plugin prefix, and, once the name
+// of a format is complete, the format with each modifier that it supports
+// and has not already been added, like testname+tree.
+func completeFormat(cur string) []string {
+	values := append(testjson.Formats(), formatPluginPrefix)
+	base, used := cur, []string(nil)
+	if i := strings.LastIndex(cur, "+"); i >= 0 {
+		base = cur[:i]
+		used = strings.Split(base, "+")[1:]
+	}
+	name := strings.Split(base, "+")[0]
+	if contains(testjson.Formats(), name) {
+		for _, mod := range testjson.SupportedFormatModifiers(name) {
+			if !contains(used, mod) {
+				values = append(values, base+"+"+mod)
+			}
+		}
+	}
+	return matchPrefix(values, cur)
+}
+
+// completionPackages returns the package paths that start with prefix. It is
+// a variable so that it can be replaced in tests.
+var completionPackages = listCompletionPackages
+
+// completeArgs returns the completions for cur, given the words that precede
+// it on the command line.
+func completeArgs(flags *pflag.FlagSet, words []string, cur string) []string {
+	// bash splits --flag=value into three words
+	if n := len(words); n > 1 && words[n-1] == "=" {
+		words = words[:n-1]
+	}
+	if cur == "=" {
+		cur = ""
+	}
+
+	if len(words) > 0 && words[0] == "completion" {
+		if len(words) == 1 {
+			return matchPrefix(completionShells, cur)
+		}
+		return nil
+	}
+
+	var prev string
+	if len(words) > 0 {
+		prev = words[len(words)-1]
+	}
+	if afterArgsSeparator(flags, words) {
+		return completePositional(words, cur)
+	}
+
+	if parts := strings.SplitN(cur, "=", 2); len(parts) == 2 && strings.HasPrefix(parts[0], "-") {
+		var result []string
+		for _, v := range completeFlagValue(flags, parts[0], parts[1]) {
+			result = append(result, parts[0]+"="+v)
+		}
+		return result
+	}
+	if strings.HasPrefix(prev, "-") && !strings.Contains(prev, "=") && flagTakesValue(flags, prev) {
+		return completeFlagValue(flags, prev, cur)
+	}
+	if strings.HasPrefix(cur, "-") {
+		return completeFlagNames(flags, cur)
+	}
+	return completePositional(words, cur)
+}
+
+// afterArgsSeparator returns true if words contains the -- separator, or a
+// positional argument, after which all the arguments are passed to go test.
+func afterArgsSeparator(flags *pflag.FlagSet, words []string) bool {
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case word == "--":
+			return true
+		case !strings.HasPrefix(word, "-"):
+			return true
+		case !strings.Contains(word, "=") && flagTakesValue(flags, word):
+			i++
+		}
+	}
+	return false
+}
+
+func completePositional(words []string, cur string) []string {
+	var result []string
+	if len(words) == 0 && !strings.HasPrefix(cur, ".") {
+		result = matchPrefix(completionCommands, cur)
+	}
+	if strings.HasPrefix(cur, "-") {
+		return result
+	}
+	return append(result, completionPackages(cur)...)
+}
+
+func completeFlagValue(flags *pflag.FlagSet, name string, cur string) []string {
+	flag := lookupFlag(flags, name)
+	if flag == nil {
+		return nil
+	}
+	switch flag.Name {
+	case "format":
+		return completeFormat(cur)
+	case "packages":
+		// the value is a space separated list, complete the last package
+		i := strings.LastIndex(cur, " ")
+		var result []string
+		for _, pkg := range completionPackages(cur[i+1:]) {
+			result = append(result, cur[:i+1]+pkg)
+		}
+		return result
+	}
+	return nil
+}
+
+func completeFlagNames(flags *pflag.FlagSet, cur string) []string {
+	var result []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Deprecated != "" {
+			return
+		}
+		if name := "--" + flag.Name; strings.HasPrefix(name, cur) {
+			result = append(result, name)
+		}
+	})
+	sort.Strings(result)
+	return result
+}
+
+func lookupFlag(flags *pflag.FlagSet, arg string) *pflag.Flag {
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		return flags.Lookup(strings.TrimPrefix(arg, "--"))
+	case len(arg) == 2 && arg[0] == '-':
+		return flags.ShorthandLookup(arg[1:])
+	}
+	return nil
+}
+
+func flagTakesValue(flags *pflag.FlagSet, arg string) bool {
+	flag := lookupFlag(flags, arg)
+	return flag != nil && flag.NoOptDefVal == ""
+}
+
+func matchPrefix(values []string, prefix string) []string {
+	var result []string
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// listCompletionPackages uses 'go list' to find the packages in the current
+// module. Relative paths, like ./pkg and ./pkg/..., are returned unless the
+// prefix looks like an import path.
+func listCompletionPackages(prefix string) []string {
+	args := []string{"list", "-e", "-f", "{{.ImportPath}}\t{{.Dir}}", "./..."}
+	log.Debugf("exec: go %v", args)
+	out, err := exec.Command("go", args...).Output()
+	if err != nil {
+		log.Debugf("failed to list packages: %v", err)
+		return nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+
+	relative := prefix == "" || strings.HasPrefix(prefix, ".")
+	candidates := []string{"./..."}
+	if !relative {
+		candidates = nil
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		importPath, dir := fields[0], fields[1]
+		if !relative {
+			candidates = append(candidates, importPath)
+			continue
+		}
+		rel, err := filepath.Rel(cwd, dir)
+		if err != nil || rel == "." {
+			continue
+		}
+		rel = "./" + filepath.ToSlash(rel)
+		candidates = append(candidates, rel, rel+"/...")
+	}
+	return matchPrefix(candidates, prefix)
+}
+
+func contains(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestCompleteArgs(t *testing.T) {
+	orig := completionPackages
+	completionPackages = func(prefix string) []string {
+		return matchPrefix([]string{"./...", "./cmd", "./cmd/...", "./testjson", "./testjson/..."}, prefix)
+	}
+	t.Cleanup(func() {
+		completionPackages = orig
+	})
+
+	var testcases = []struct {
+		name     string
+		words    []string
+		cur      string
+		expected []string
+	}{
+		{
+			name:     "flag names",
+			cur:      "--format-h",
+			expected: []string{"--format-hide-empty-pkg", "--format-hivis"},
+		},
+		{
+			name:     "format values",
+			words:    []string{"--format"},
+			cur:      "pkg",
+			expected: []string{"pkgname", "pkgname-and-test-fails"},
+		},
+		{
+			name:     "format values with shorthand",
+			words:    []string{"--no-color", "-f"},
+			cur:      "standard-",
+			expected: []string{"standard-quiet", "standard-verbose"},
+		},
+		{
+			name:     "format values in the same word",
+			cur:      "--format=dot",
+			expected: []string{"--format=dots", "--format=dots-v1", "--format=dots-v2"},
+		},
+		{
+			name:     "format values include aliases",
+			words:    []string{"--format"},
+			cur:      "short-",
+			expected: []string{"short-verbose", "short-with-failures"},
+		},
+		{
+			name:  "format modifiers of a complete format",
+			words: []string{"--format"},
+			cur:   "testname",
+			expected: []string{
+				"testname", "testname+align", "testname+coverage",
+				"testname+timestamps", "testname+tree", "testname+wall-clock",
+			},
+		},
+		{
+			name:  "format modifiers after a modifier",
+			words: []string{"--format"},
+			cur:   "testname+tree+",
+			expected: []string{
+				"testname+tree+align", "testname+tree+coverage",
+				"testname+tree+timestamps", "testname+tree+wall-clock",
+			},
+		},
+		{
+			name:     "format modifiers by prefix",
+			cur:      "--format=pkgname+h",
+			expected: []string{"--format=pkgname+hide-empty", "--format=pkgname+hivis"},
+		},
+		{
+			name:     "format plugin prefix",
+			words:    []string{"--format"},
+			cur:      "ex",
+			expected: []string{"exec:"},
+		},
+		{
+			name:     "format values split by bash",
+			words:    []string{"--format", "="},
+			cur:      "test",
+			expected: []string{"testname"},
+		},
+		{
+			name:     "packages flag",
+			words:    []string{"--packages"},
+			cur:      "./cmd ./test",
+			expected: []string{"./cmd ./testjson", "./cmd ./testjson/..."},
+		},
+		{
+			name:     "positional packages after separator",
+			words:    []string{"--format", "dots", "--"},
+			cur:      "./c",
+			expected: []string{"./cmd", "./cmd/..."},
+		},
+		{
+			name:  "go test flags are not completed",
+			words: []string{"--"},
+			cur:   "-ru",
+		},
+		{
+			name:     "commands and packages",
+			cur:      "",
+			expected: []string{"completion", "help", "init", "serve-ui", "tool", "./...", "./cmd", "./cmd/...", "./testjson", "./testjson/..."},
+		},
+		{
+			name:     "completion shells",
+			words:    []string{"completion"},
+			cur:      "",
+			expected: []string{"bash", "fish", "powershell", "zsh"},
+		},
+		{
+			name:  "values of other flags are not completed",
+			words: []string{"--jsonfile"},
+			cur:   "out",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			flags, _ := setupFlags("gotestsum")
+			actual := completeArgs(flags, tc.words, tc.cur)
+			assert.DeepEqual(t, actual, tc.expected)
+		})
+	}
+}
+
+func TestCompletionScript(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			script, err := completionScript(shell, "gotestsum")
+			assert.NilError(t, err)
+			assert.Assert(t, cmp.Contains(script, "__complete"))
+			assert.Assert(t, cmp.Contains(script, completionWordEnv))
+		})
+	}
+
+	_, err := completionScript("tcsh", "gotestsum")
+	assert.ErrorContains(t, err, `unsupported shell "tcsh"`)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/configfile"
+)
+
+// configFilename is the name of the file, in the current directory, that
+// sets the default value of flags.
+const configFilename = ".gotestsum.yaml"
+
+// envVarAnnotation is the annotation of a flag that names the environment
+// variable which sets the default value of the flag. The config file does not
+// change a flag that was set by its environment variable.
+const envVarAnnotation = "gotestsum_env_var"
+
+// flagEnvVars records the environment variable of each flag while the flags
+// are registered, so that it can be added to the flags by annotate.
+type flagEnvVars map[string]string
+
+// lookup records key as the environment variable of the flag, and returns
+// the value of key, or defValue if it is not set.
+func (e flagEnvVars) lookup(flag, key, defValue string) string {
+	e[flag] = key
+	return lookEnvWithDefault(key, defValue)
+}
+
+// annotate sets the envVarAnnotation of every flag that was recorded by
+// lookup. It panics if a flag was not registered.
+func (e flagEnvVars) annotate(flags *pflag.FlagSet) {
+	for name, key := range e {
+		if err := flags.SetAnnotation(name, envVarAnnotation, []string{key}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// setFromEnv returns true if the default value of the flag was set by its
+// environment variable.
+func setFromEnv(flag *pflag.Flag) bool {
+	for _, key := range flag.Annotations[envVarAnnotation] {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// loadConfigFile sets the value of every flag in the config file that was not
+// set on the command line, or by its environment variable. The config file is a YAML mapping of flag names to
+// values. A value may be a list, which sets the flag once for each item, like
+// a flag that is used more than once. A value may also be a mapping, which
+// sets the flag to key=value for each key, or for each item in the list of a
+// key. It is not an error if the file does not exist.
+func loadConfigFile(flags *pflag.FlagSet, filename string) error {
+	raw, err := ioutil.ReadFile(filename)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	}
+	entries, err := configfile.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %w", filename, err)
+	}
+	for _, entry := range entries {
+		flag := flags.Lookup(entry.Name)
+		if flag == nil {
+			return fmt.Errorf("%v:%d: unknown flag %q", filename, entry.Line, entry.Name)
+		}
+		if flag.Changed || setFromEnv(flag) {
+			continue
+		}
+		for _, value := range entry.Values {
+			if err := flag.Value.Set(value); err != nil {
+				return fmt.Errorf("%v:%d: invalid value %q for %v: %w",
+					filename, entry.Line, value, entry.Name, err)
+			}
+		}
+	}
+	return nil
+}
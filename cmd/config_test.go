@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := fs.NewDir(t, "config", fs.WithFile(configFilename, `
+format: testname
+jsonfile: out.json
+packages:
+  - ./cmd/...
+  - ./testjson
+rerun-fails: 3
+`))
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{"--format", "dots"}))
+	assert.NilError(t, loadConfigFile(flags, dir.Join(configFilename)))
+
+	assert.Equal(t, opts.format, "dots", "flags on the command line take precedence")
+	assert.Equal(t, opts.jsonFile, "out.json")
+	assert.DeepEqual(t, opts.packages, []string{"./cmd/...", "./testjson"})
+	assert.Equal(t, opts.rerunFailsMaxAttempts, 3)
+}
+
+func TestLoadConfigFile_EnvPrecedence(t *testing.T) {
+	env.Patch(t, "GOTESTSUM_FORMAT", "dots")
+	env.Patch(t, "GOTESTSUM_JUNITFILE", "")
+	dir := fs.NewDir(t, "config", fs.WithFile(configFilename, `
+format: testname
+junitfile: junit.xml
+`))
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse(nil))
+	assert.NilError(t, loadConfigFile(flags, dir.Join(configFilename)))
+
+	assert.Equal(t, opts.format, "dots", "environment variables take precedence")
+	assert.Equal(t, opts.junitFile, "junit.xml")
+}
+
+func TestSetFromEnv(t *testing.T) {
+	env.Patch(t, "GOTESTSUM_MIN_CAPTURED_LOG_LEVEL", "warn")
+	flags, _ := setupFlags("gotestsum")
+	assert.Assert(t, setFromEnv(flags.Lookup("min-captured-log-level")))
+	assert.Assert(t, !setFromEnv(flags.Lookup("format")))
+	assert.Assert(t, !setFromEnv(flags.Lookup("rerun-fails")))
+}
+
+func TestLoadConfigFile_Errors(t *testing.T) {
+	flags, _ := setupFlags("gotestsum")
+	assert.NilError(t, loadConfigFile(flags, "does-not-exist.yaml"))
+
+	dir := fs.NewDir(t, "config", fs.WithFile(configFilename, "no-such-flag: true\n"))
+	err := loadConfigFile(flags, dir.Join(configFilename))
+	assert.Error(t, err, dir.Join(configFilename)+`:1: unknown flag "no-such-flag"`)
+
+	dir = fs.NewDir(t, "config", fs.WithFile(configFilename, "\nrerun-fails: many\n"))
+	err = loadConfigFile(flags, dir.Join(configFilename))
+	assert.ErrorContains(t, err, dir.Join(configFilename)+`:2: invalid value "many" for rerun-fails`)
+}
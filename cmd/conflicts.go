@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// conflictSignature matches the output of a test that failed because a
+// resource, like a port or a file, was used by another test binary at the same
+// time. The first group of the expression is the resource.
+type conflictSignature struct {
+	kind string
+	expr *regexp.Regexp
+}
+
+var conflictSignatures = []conflictSignature{
+	{
+		kind: "address",
+		expr: regexp.MustCompile(`listen (?:tcp|udp)[46]? (\S*:\d+): bind: ` +
+			`(?:address already in use|Only one usage of each socket address)`),
+	},
+	{kind: "address", expr: regexp.MustCompile(`()address already in use`)},
+	{kind: "file", expr: regexp.MustCompile(`(?:open|fork/exec|remove|rename) (\S+): text file busy`)},
+	{kind: "file", expr: regexp.MustCompile(`(?:open|remove|rename|unlinkat) (\S+): device or resource busy`)},
+	{
+		kind: "file",
+		expr: regexp.MustCompile(`(?:open|remove|rename) (\S+): ` +
+			`The process cannot access the file because it is being used by another process`),
+	},
+	{kind: "database", expr: regexp.MustCompile(`()database is locked`)},
+}
+
+// resourceConflict is a failed test, and the tests in other packages that may
+// have been using the same resource.
+type resourceConflict struct {
+	test testjson.TestCase
+	kind string
+	// resource is the port or file from the failure, or empty if the output
+	// does not include it.
+	resource string
+	// others are the tests in other packages that mention the resource in
+	// their output, or when no test mentions the resource, the tests in other
+	// packages that were running at the same time as the failed test. Only
+	// the output of tests that failed or were skipped is kept, so the output
+	// of a test that passed never mentions the resource.
+	others []testjson.TestCase
+}
+
+// matchConflictSignature returns the kind of resource, and the resource, when
+// the output matches a conflictSignature.
+func matchConflictSignature(output string) (kind string, resource string, ok bool) {
+	for _, sig := range conflictSignatures {
+		if m := sig.expr.FindStringSubmatch(output); m != nil {
+			return sig.kind, m[1], true
+		}
+	}
+	return "", "", false
+}
+
+// findResourceConflicts returns the failed tests with output that matches a
+// conflictSignature, sorted by package and test name. A test that passed when
+// it was re-run is included, because the conflict may happen again in the next
+// run. A test that failed more than once is only included once.
+func findResourceConflicts(exec *testjson.Execution) []resourceConflict {
+	var conflicts []resourceConflict
+	seen := make(map[string]bool)
+	for _, tc := range exec.Failed() {
+		kind, resource, ok := matchConflictSignature(failureOutput(exec, tc))
+		if !ok || seen[baselineName(tc)] {
+			continue
+		}
+		seen[baselineName(tc)] = true
+		conflicts = append(conflicts, resourceConflict{
+			test:     tc,
+			kind:     kind,
+			resource: resource,
+			others:   conflictingTests(exec, tc, resource),
+		})
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		a, b := conflicts[i].test, conflicts[j].test
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Test < b.Test
+	})
+	return conflicts
+}
+
+// conflictingTests returns the top-level tests in other packages that may
+// have used the resource at the same time as tc.
+func conflictingTests(exec *testjson.Execution, tc testjson.TestCase, resource string) []testjson.TestCase {
+	start, end := tc.Span()
+	mentions := resourceMatcher(resource)
+	var mention, overlap []testjson.TestCase
+	for _, name := range exec.Packages() {
+		if name == tc.Package {
+			continue
+		}
+		pkg := exec.Package(name)
+		for _, other := range pkg.TestCases() {
+			if other.Test.IsSubTest() {
+				continue
+			}
+			if mentions != nil && mentions(strings.Join(pkg.OutputLines(other), "")) {
+				mention = append(mention, other)
+				continue
+			}
+			if otherStart, otherEnd := other.Span(); !start.IsZero() && !otherStart.IsZero() &&
+				overlaps(start, end, otherStart, otherEnd) {
+				overlap = append(overlap, other)
+			}
+		}
+	}
+	if len(mention) > 0 {
+		return mention
+	}
+	return overlap
+}
+
+// resourceMatcher returns a function that returns true when the output
+// includes the resource, or nil when the resource is empty. For an address,
+// only the port is used, because a test may log the address as
+// localhost:8080 and fail with 127.0.0.1:8080.
+func resourceMatcher(resource string) func(output string) bool {
+	switch i := strings.LastIndex(resource, ":"); {
+	case resource == "":
+		return nil
+	case i >= 0:
+		expr := regexp.MustCompile(regexp.QuoteMeta(resource[i:]) + `\b`)
+		return expr.MatchString
+	}
+	return func(output string) bool {
+		return strings.Contains(output, resource)
+	}
+}
+
+func overlaps(start, end, otherStart, otherEnd time.Time) bool {
+	return start.Before(otherEnd) && otherStart.Before(end)
+}
+
+// maxConflictingTests is the number of conflicting tests printed for each
+// failed test. A failure that does not include the port or file may have run
+// at the same time as every test in other packages.
+const maxConflictingTests = 3
+
+// printResourceConflicts prints the failed tests that conflicted with tests
+// in other packages.
+func printResourceConflicts(out io.Writer, exec *testjson.Execution) {
+	conflicts := findResourceConflicts(exec)
+	if len(conflicts) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Resource conflicts: %v failed because a resource was in use\n",
+		pluralize(len(conflicts), "test"))
+	for _, c := range conflicts {
+		what := c.kind + " in use"
+		if c.resource != "" {
+			what = c.kind + " " + c.resource + " in use"
+		}
+		fmt.Fprintf(out, "=== %v: %v", conflictTestName(c.test), what)
+		if len(c.others) > 0 {
+			names := make([]string, 0, maxConflictingTests+1)
+			for i, other := range c.others {
+				if i == maxConflictingTests {
+					names = append(names, fmt.Sprintf("and %d more", len(c.others)-i))
+					break
+				}
+				names = append(names, conflictTestName(other))
+			}
+			fmt.Fprintf(out, ", conflicts with %v", strings.Join(names, ", "))
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+func conflictTestName(tc testjson.TestCase) string {
+	name := testjson.RelativePackagePath(tc.Package)
+	if tc.Test != "" {
+		name += "." + tc.Test.Name()
+	}
+	return name
+}
+
+// retryResourceConflicts returns true when the failed tests should be re-run
+// once, because --rerun-conflicts is set, --rerun-fails is not set, and every
+// failure is a resource conflict. Reruns run one package at a time, so the
+// packages do not conflict again.
+func retryResourceConflicts(opts *options, exec *testjson.Execution) bool {
+	switch {
+	case !opts.rerunConflicts || opts.rerunFailsMaxAttempts > 0:
+		return false
+	case len(opts.args) > 0 && !opts.rawCommand && len(opts.packages) == 0:
+		return false
+	case boolArgIndex("failfast", opts.args) > -1:
+		return false
+	}
+	failures := failingTestCases(exec)
+	for _, tc := range failures {
+		if _, _, ok := matchConflictSignature(failureOutput(exec, tc)); !ok {
+			return false
+		}
+	}
+	return len(failures) > 0
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestMatchConflictSignature(t *testing.T) {
+	type testCase struct {
+		output   string
+		kind     string
+		resource string
+		ok       bool
+	}
+	run := func(t *testing.T, tc testCase) {
+		kind, resource, ok := matchConflictSignature(tc.output)
+		assert.Equal(t, ok, tc.ok)
+		assert.Equal(t, kind, tc.kind)
+		assert.Equal(t, resource, tc.resource)
+	}
+	testCases := map[string]testCase{
+		"listen tcp": {
+			output:   "server_test.go:12: listen tcp 127.0.0.1:8080: bind: address already in use\n",
+			kind:     "address",
+			resource: "127.0.0.1:8080",
+			ok:       true,
+		},
+		"listen tcp without host": {
+			output:   "listen tcp :9090: bind: address already in use",
+			kind:     "address",
+			resource: ":9090",
+			ok:       true,
+		},
+		"listen on windows": {
+			output: "listen tcp :8080: bind: Only one usage of each socket address " +
+				"(protocol/network address/port) is normally permitted.",
+			kind:     "address",
+			resource: ":8080",
+			ok:       true,
+		},
+		"address without listen": {
+			output: "failed to start: address already in use",
+			kind:   "address",
+			ok:     true,
+		},
+		"text file busy": {
+			output:   "fork/exec /tmp/testbin/server: text file busy",
+			kind:     "file",
+			resource: "/tmp/testbin/server",
+			ok:       true,
+		},
+		"database locked": {
+			output: "store_test.go:40: database is locked",
+			kind:   "database",
+			ok:     true,
+		},
+		"other failure": {
+			output: "expected 1, got 2",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// conflictRun is a run where TestServer in the api package failed to listen
+// on a port that was used by TestServer in the web package. TestOther in the
+// db package was running at the same time.
+const conflictRun = `{"Time":"2024-06-01T10:00:00Z","Action":"run","Package":"example.com/web","Test":"TestServer"}
+{"Time":"2024-06-01T10:00:00Z","Action":"output","Package":"example.com/web","Test":"TestServer","Output":"server_test.go:10: listening on localhost:8080\n"}
+{"Time":"2024-06-01T10:00:00Z","Action":"run","Package":"example.com/db","Test":"TestOther"}
+{"Time":"2024-06-01T10:00:01Z","Action":"run","Package":"example.com/api","Test":"TestServer"}
+{"Time":"2024-06-01T10:00:01Z","Action":"output","Package":"example.com/api","Test":"TestServer","Output":"server_test.go:12: listen tcp 127.0.0.1:8080: bind: address already in use\n"}
+{"Time":"2024-06-01T10:00:01.5Z","Action":"fail","Package":"example.com/api","Test":"TestServer","Elapsed":0.5}
+{"Time":"2024-06-01T10:00:01.5Z","Action":"fail","Package":"example.com/api","Elapsed":1.5}
+{"Time":"2024-06-01T10:00:02Z","Action":"pass","Package":"example.com/db","Test":"TestOther","Elapsed":2}
+{"Time":"2024-06-01T10:00:02Z","Action":"pass","Package":"example.com/db","Elapsed":2}
+{"Time":"2024-06-01T10:00:03Z","Action":"pass","Package":"example.com/web","Test":"TestServer","Elapsed":3}
+{"Time":"2024-06-01T10:00:03Z","Action":"pass","Package":"example.com/web","Elapsed":3}
+`
+
+func scanConflictRun(t *testing.T, input string) *testjson.Execution {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestFindResourceConflicts(t *testing.T) {
+	exec := scanConflictRun(t, conflictRun)
+	conflicts := findResourceConflicts(exec)
+	assert.Equal(t, len(conflicts), 1)
+	c := conflicts[0]
+	assert.Equal(t, c.test.Package, "example.com/api")
+	assert.Equal(t, c.kind, "address")
+	assert.Equal(t, c.resource, "127.0.0.1:8080")
+
+	out := new(bytes.Buffer)
+	printResourceConflicts(out, exec)
+	expected := `
+=== Resource conflicts: 1 test failed because a resource was in use
+=== example.com/api.TestServer: address 127.0.0.1:8080 in use, conflicts with example.com/db.TestOther, example.com/web.TestServer
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestFindResourceConflicts_MentionsResource(t *testing.T) {
+	input := strings.Replace(conflictRun,
+		`"Action":"pass","Package":"example.com/web","Test":"TestServer"`,
+		`"Action":"fail","Package":"example.com/web","Test":"TestServer"`, 1)
+	exec := scanConflictRun(t, input)
+	conflicts := findResourceConflicts(exec)
+	assert.Equal(t, len(conflicts), 1)
+	var others []string
+	for _, other := range conflicts[0].others {
+		others = append(others, conflictTestName(other))
+	}
+	assert.DeepEqual(t, others, []string{"example.com/web.TestServer"})
+}
+
+func TestPrintResourceConflicts_ManyOverlappingTests(t *testing.T) {
+	lines := []string{
+		`{"Time":"2024-06-01T10:00:00Z","Action":"run","Package":"example.com/store","Test":"TestSave"}`,
+	}
+	for i := 0; i < 5; i++ {
+		lines = append(lines,
+			fmt.Sprintf(`{"Time":"2024-06-01T10:00:00Z","Action":"run","Package":"example.com/p%d","Test":"TestOne"}`, i),
+			fmt.Sprintf(`{"Time":"2024-06-01T10:00:02Z","Action":"pass","Package":"example.com/p%d","Test":"TestOne","Elapsed":2}`, i))
+	}
+	lines = append(lines,
+		`{"Time":"2024-06-01T10:00:01Z","Action":"output","Package":"example.com/store","Test":"TestSave","Output":"store_test.go:40: database is locked\n"}`,
+		`{"Time":"2024-06-01T10:00:01Z","Action":"fail","Package":"example.com/store","Test":"TestSave","Elapsed":1}`,
+		`{"Time":"2024-06-01T10:00:01Z","Action":"fail","Package":"example.com/store"}`,
+		"")
+	exec := scanConflictRun(t, strings.Join(lines, "\n"))
+
+	out := new(bytes.Buffer)
+	printResourceConflicts(out, exec)
+	expected := `
+=== Resource conflicts: 1 test failed because a resource was in use
+=== example.com/store.TestSave: database in use, conflicts with example.com/p0.TestOne, example.com/p1.TestOne, example.com/p2.TestOne, and 2 more
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRetryResourceConflicts(t *testing.T) {
+	exec := scanConflictRun(t, conflictRun)
+	opts := &options{}
+	assert.Assert(t, !retryResourceConflicts(opts, exec))
+
+	opts.rerunConflicts = true
+	assert.Assert(t, retryResourceConflicts(opts, exec))
+
+	opts.rerunFailsMaxAttempts = 2
+	assert.Assert(t, !retryResourceConflicts(opts, exec), "--rerun-fails reruns all failures")
+
+	opts.rerunFailsMaxAttempts = 0
+	other := scanConflictRun(t, conflictRun+
+		`{"Action":"run","Package":"example.com/cli","Test":"TestFlags"}
+{"Action":"fail","Package":"example.com/cli","Test":"TestFlags"}
+{"Action":"fail","Package":"example.com/cli"}
+`)
+	assert.Assert(t, !retryResourceConflicts(opts, other), "not every failure is a conflict")
+}
+
+func TestRun_RerunConflicts(t *testing.T) {
+	var commands [][]string
+	fn := func(args []string) *proc {
+		commands = append(commands, args)
+		if len(commands) == 1 {
+			return &proc{
+				cmd:    fakeWaiter{result: newExitCode("failed", 1)},
+				stdout: strings.NewReader(conflictRun),
+				stderr: bytes.NewReader(nil),
+			}
+		}
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(`{"Action":"run","Package":"example.com/api","Test":"TestServer"}
+{"Action":"pass","Package":"example.com/api","Test":"TestServer"}
+{"Action":"pass","Package":"example.com/api"}
+`),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		rawCommand:     true,
+		args:           []string{"./test-all"},
+		format:         "testname",
+		rerunConflicts: true,
+		stdout:         out,
+		// the default value of the flag
+		rerunFailsMaxInitialFailures: 10,
+		stderr:                       os.Stderr,
+		hideSummary:                  newHideSummaryValue(),
+	}
+	assert.NilError(t, run(opts))
+	assert.Equal(t, len(commands), 2)
+	assert.Assert(t, strings.Contains(out.String(), "=== Resource conflicts: 1 test failed"), out.String())
+}
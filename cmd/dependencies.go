@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// dependencies are the services used by the tests, like a database, which
+// are started before go test runs, and stopped after the last go test.
+type dependencies struct {
+	teardown [][]string
+	timeout  time.Duration
+	// setup is the time from the start of the first setup command until the
+	// dependencies were ready.
+	setup   time.Duration
+	stopped time.Duration
+	done    bool
+	// probes are the results of the --wait-for probes.
+	probes []jsonsummary.Probe
+	// err is the reason the dependencies were not ready.
+	err error
+}
+
+// setupProbeInterval is the time between the runs of the --setup-ready
+// command. It is a var for testing.
+var setupProbeInterval = time.Second
+
+// runSetupCommandFn is a shim for testing
+var runSetupCommandFn = runSetupCommand
+
+func runSetupCommand(ctx context.Context, args []string) ([]byte, error) {
+	log.Debugf("exec: %s", args)
+	return exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+}
+
+func (o options) setupEnabled() bool {
+	return o.composeFile != "" || o.setupCommand != "" || o.setupReady != "" ||
+		o.teardownCommand != "" || len(o.waitFor.probes) > 0
+}
+
+// startDependencies runs the commands that start the dependencies, waits
+// until the --setup-ready command succeeds, and then waits for each of the
+// --wait-for probes. The dependencies are stopped if they do not become ready
+// before --setup-timeout, or if a probe is not ready before --wait-timeout.
+// When the dependencies are not ready the dependencies are returned with the
+// error, so that the results of the probes are in the JSON summary.
+// startDependencies returns nil when no setup flags are set.
+func startDependencies(ctx context.Context, opts *options) (*dependencies, error) {
+	if !opts.setupEnabled() {
+		return nil, nil
+	}
+	setup, teardown, ready, err := dependencyCommands(opts)
+	if err != nil {
+		return nil, misuseError(err)
+	}
+	d := &dependencies{teardown: teardown, timeout: opts.setupTimeout}
+
+	start := time.Now()
+	setupCtx, cancel := context.WithTimeout(ctx, opts.setupTimeout)
+	defer cancel()
+	for _, args := range setup {
+		if out, err := runSetupCommandFn(setupCtx, args); err != nil {
+			return d, d.fail(start, fmt.Errorf("setup command %v failed: %w%s",
+				strings.Join(args, " "), timeoutCause(setupCtx, err, opts.setupTimeout), formatCommandOutput(out)))
+		}
+	}
+	if ready != nil {
+		if err := waitUntilReady(setupCtx, ready); err != nil {
+			return d, d.fail(start, err)
+		}
+	}
+	if len(opts.waitFor.probes) > 0 {
+		var err error
+		d.probes, err = waitForProbes(ctx, opts.waitFor.probes, opts.waitTimeout)
+		if err != nil {
+			return d, d.fail(start, err)
+		}
+	}
+	d.setup = time.Since(start)
+	log.Debugf("dependencies were ready after %v", d.setup)
+	return d, nil
+}
+
+// dependencyCommands returns the commands that start and stop the
+// dependencies, and the readiness probe, from the flags.
+func dependencyCommands(opts *options) (setup, teardown [][]string, ready []string, err error) {
+	if opts.composeFile != "" {
+		setup = append(setup, []string{"docker", "compose", "-f", opts.composeFile, "up", "--detach", "--wait"})
+	}
+	if opts.setupCommand != "" {
+		args, err := splitCommand("--setup-command", opts.setupCommand)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		setup = append(setup, args)
+	}
+	if opts.teardownCommand != "" {
+		args, err := splitCommand("--teardown-command", opts.teardownCommand)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		teardown = append(teardown, args)
+	}
+	if opts.composeFile != "" {
+		teardown = append(teardown, []string{"docker", "compose", "-f", opts.composeFile, "down"})
+	}
+	if opts.setupReady != "" {
+		if ready, err = splitCommand("--setup-ready", opts.setupReady); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return setup, teardown, ready, nil
+}
+
+func splitCommand(flag, value string) ([]string, error) {
+	args, err := shlex.Split(value)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("invalid %v %q: %w", flag, value, err)
+	case len(args) == 0:
+		return nil, fmt.Errorf("%v must not be empty", flag)
+	}
+	return args, nil
+}
+
+// waitUntilReady runs the readiness probe until it succeeds, or until ctx is
+// done.
+func waitUntilReady(ctx context.Context, probe []string) error {
+	for {
+		out, err := runSetupCommandFn(ctx, probe)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(setupProbeInterval):
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("dependencies were not ready: the last run of %v failed: %w%s",
+				strings.Join(probe, " "), err, formatCommandOutput(out))
+		}
+	}
+}
+
+// timeoutCause returns an error that explains that the command was stopped
+// by --setup-timeout, or err when the command failed for another reason.
+func timeoutCause(ctx context.Context, err error, timeout time.Duration) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("--setup-timeout of %v was exceeded: %w", timeout, err)
+	}
+	return err
+}
+
+func formatCommandOutput(out []byte) string {
+	text := strings.TrimSpace(string(out))
+	if text == "" {
+		return ""
+	}
+	return "\n" + text
+}
+
+func setupError(err error) error {
+	return categoryError{category: exitSetupFailure, err: err}
+}
+
+// fail records that the dependencies were not ready because of err, stops
+// them, and returns the setup error.
+func (d *dependencies) fail(start time.Time, err error) error {
+	d.setup = time.Since(start)
+	d.err = err
+	d.stop()
+	return setupError(err)
+}
+
+// writeSetupFailureSummary writes the JSON summary of a run that stopped
+// because the dependencies were not ready, so that the summary includes the
+// results of the --wait-for probes. It returns err. exec may be nil.
+func writeSetupFailureSummary(opts *options, exec *testjson.Execution, err error) error {
+	if exec == nil {
+		exec = emptyExecution()
+	}
+	if writeErr := writeJSONSummary(opts, exec); writeErr != nil {
+		log.Errorf("Failed to write JSON summary: %v", writeErr)
+	}
+	return err
+}
+
+// stop runs the teardown commands. It is safe to call more than once, only
+// the first call runs the commands. An error from a teardown command is
+// logged, because the tests have already finished.
+func (d *dependencies) stop() {
+	if d == nil || d.done {
+		return
+	}
+	d.done = true
+	start := time.Now()
+	for _, args := range d.teardown {
+		ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+		out, err := runSetupCommandFn(ctx, args)
+		cancel()
+		if err != nil {
+			log.Warnf("teardown command %v failed: %v%s", strings.Join(args, " "), err, formatCommandOutput(out))
+		}
+	}
+	d.stopped = time.Since(start)
+}
+
+// printSummary prints the time used to start and stop the dependencies, which
+// is not included in the elapsed time of the packages.
+func (d *dependencies) printSummary(out io.Writer) {
+	if d == nil {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Setup: dependencies were ready in %v", testjson.FormatDurationAsSeconds(d.setup, 2))
+	if len(d.teardown) > 0 {
+		fmt.Fprintf(out, ", and stopped in %v", testjson.FormatDurationAsSeconds(d.stopped, 2))
+	}
+	fmt.Fprintln(out)
+	for _, probe := range d.probes {
+		fmt.Fprintf(out, "=== Wait for: %v was ready after %v in %v\n", probe.Target,
+			pluralize(probe.Attempts, "attempt"), testjson.FormatDurationAsSeconds(seconds(probe.Elapsed), 2))
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// jsonSummary returns the setup times for the JSON summary, or nil when no
+// setup flags are set.
+func (d *dependencies) jsonSummary() *jsonsummary.Setup {
+	if d == nil {
+		return nil
+	}
+	setup := &jsonsummary.Setup{Elapsed: d.setup.Seconds(), Teardown: d.stopped.Seconds(), Probes: d.probes}
+	if d.err != nil {
+		setup.Error = d.err.Error()
+	}
+	return setup
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+// patchRunSetupCommandFn records the commands that are run, and returns the
+// result from f.
+func patchRunSetupCommandFn(f func(args []string) ([]byte, error)) (*[]string, func()) {
+	var commands []string
+	orig := runSetupCommandFn
+	runSetupCommandFn = func(ctx context.Context, args []string) ([]byte, error) {
+		commands = append(commands, strings.Join(args, " "))
+		return f(args)
+	}
+	return &commands, func() {
+		runSetupCommandFn = orig
+	}
+}
+
+func TestRun_Dependencies(t *testing.T) {
+	probes := 0
+	commands, reset := patchRunSetupCommandFn(func(args []string) ([]byte, error) {
+		if args[0] == "pg_isready" {
+			probes++
+			if probes < 3 {
+				return []byte("no response"), errors.New("exit status 2")
+			}
+		}
+		return nil, nil
+	})
+	defer reset()
+	defer patchSetupProbeInterval(time.Millisecond)()
+
+	var goTestCommands []string
+	resetGoTest := patchStartGoTestFn(func(args []string) *proc {
+		goTestCommands = append(goTestCommands, strings.Join(*commands, ";"))
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "pkg", "Action": "pass"}` + "\n"),
+			stderr: bytes.NewReader(nil),
+		}
+	})
+	defer resetGoTest()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		rawCommand:      true,
+		args:            []string{"./test.test"},
+		format:          "testname",
+		composeFile:     "compose.yaml",
+		setupCommand:    "./scripts/seed 'test db'",
+		setupReady:      "pg_isready -h localhost",
+		teardownCommand: "./scripts/dump-logs",
+		setupTimeout:    time.Minute,
+		stdout:          out,
+		stderr:          os.Stderr,
+		hideSummary:     newHideSummaryValue(),
+	}
+	assert.NilError(t, run(opts))
+
+	expected := []string{
+		"docker compose -f compose.yaml up --detach --wait",
+		"./scripts/seed test db",
+		"pg_isready -h localhost",
+		"pg_isready -h localhost",
+		"pg_isready -h localhost",
+		"./scripts/dump-logs",
+		"docker compose -f compose.yaml down",
+	}
+	assert.DeepEqual(t, *commands, expected)
+	assert.DeepEqual(t, goTestCommands, []string{strings.Join(expected[:5], ";")})
+	assert.Assert(t, strings.Contains(out.String(), "=== Setup: dependencies were ready in "), out.String())
+	assert.Assert(t, opts.dependencies.jsonSummary() != nil)
+}
+
+func patchSetupProbeInterval(d time.Duration) func() {
+	orig := setupProbeInterval
+	setupProbeInterval = d
+	return func() {
+		setupProbeInterval = orig
+	}
+}
+
+func TestStartDependencies_NotReady(t *testing.T) {
+	commands, reset := patchRunSetupCommandFn(func(args []string) ([]byte, error) {
+		if args[0] == "pg_isready" {
+			return []byte("localhost:5432 - no response\n"), errors.New("exit status 2")
+		}
+		return nil, nil
+	})
+	defer reset()
+	defer patchSetupProbeInterval(time.Millisecond)()
+
+	opts := &options{
+		composeFile:  "compose.yaml",
+		setupReady:   "pg_isready",
+		setupTimeout: 20 * time.Millisecond,
+	}
+	d, err := startDependencies(context.Background(), opts)
+	assert.Assert(t, d != nil && d.done, "dependencies are returned with the error")
+	assert.Equal(t, d.jsonSummary().Error, "dependencies were not ready: the last run of pg_isready failed: "+
+		"exit status 2\nlocalhost:5432 - no response")
+	assert.Error(t, err, "dependencies were not ready: the last run of pg_isready failed: "+
+		"exit status 2\nlocalhost:5432 - no response")
+	assert.Equal(t, ExitCodeWithDefault(opts.exitCodes.resolve(err)), 7)
+	last := (*commands)[len(*commands)-1]
+	assert.Equal(t, last, "docker compose -f compose.yaml down", "dependencies are stopped")
+}
+
+func TestRun_WaitForNotReady_JSONSummary(t *testing.T) {
+	_, reset := patchRunSetupCommandFn(func(args []string) ([]byte, error) {
+		return []byte("no response"), errors.New("exit status 2")
+	})
+	defer reset()
+	defer patchSetupProbeInterval(time.Millisecond)()
+
+	dir := fs.NewDir(t, t.Name())
+	opts := &options{
+		rawCommand:      true,
+		args:            []string{"./test.test"},
+		format:          "testname",
+		waitFor:         waitForValue{probes: parseProbes(t, "cmd:pg_isready")},
+		waitTimeout:     20 * time.Millisecond,
+		setupTimeout:    time.Minute,
+		summaryJSONFile: dir.Join("summary.json"),
+		stdout:          new(bytes.Buffer),
+		stderr:          new(bytes.Buffer),
+		hideSummary:     newHideSummaryValue(),
+	}
+	err := run(opts)
+	assert.ErrorContains(t, err, "--wait-for cmd:pg_isready was not ready")
+
+	raw, err := ioutil.ReadFile(opts.summaryJSONFile)
+	assert.NilError(t, err)
+	var summary jsonsummary.Summary
+	assert.NilError(t, json.Unmarshal(raw, &summary))
+	assert.Assert(t, summary.Setup != nil)
+	assert.Equal(t, len(summary.Setup.Probes), 1)
+	probe := summary.Setup.Probes[0]
+	assert.Equal(t, probe.Target, "cmd:pg_isready")
+	assert.Assert(t, !probe.Ready)
+	assert.Equal(t, probe.Error, "exit status 2\nno response")
+	assert.Assert(t, strings.Contains(summary.Setup.Error, "--wait-for cmd:pg_isready was not ready"))
+}
+
+func TestStartDependencies_SetupCommandFailed(t *testing.T) {
+	commands, reset := patchRunSetupCommandFn(func(args []string) ([]byte, error) {
+		if args[0] == "./start-db" {
+			return []byte("port 5432 is in use\n"), errors.New("exit status 1")
+		}
+		return nil, nil
+	})
+	defer reset()
+
+	opts := &options{
+		setupCommand:    "./start-db",
+		setupReady:      "pg_isready",
+		teardownCommand: "./stop-db",
+		setupTimeout:    time.Minute,
+	}
+	_, err := startDependencies(context.Background(), opts)
+	assert.Error(t, err, "setup command ./start-db failed: exit status 1\nport 5432 is in use")
+	assert.DeepEqual(t, *commands, []string{"./start-db", "./stop-db"})
+}
+
+func TestStartDependencies_NotEnabled(t *testing.T) {
+	d, err := startDependencies(context.Background(), &options{})
+	assert.NilError(t, err)
+	assert.Assert(t, d == nil)
+	// methods are safe to call on a nil dependencies
+	d.stop()
+	d.printSummary(new(bytes.Buffer))
+	assert.Assert(t, d.jsonSummary() == nil)
+}
+
+func TestDependencies_PrintSummary(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := &dependencies{
+		teardown: [][]string{{"./stop-db"}},
+		setup:    4210 * time.Millisecond,
+		stopped:  1020 * time.Millisecond,
+	}
+	d.printSummary(out)
+	assert.Equal(t, out.String(), "\n=== Setup: dependencies were ready in 4.21s, and stopped in 1.02s\n")
+}
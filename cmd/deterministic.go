@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// sourceDateEpochEnv is the environment variable used by reproducible builds
+// for the time of the build. See https://reproducible-builds.org/specs/source-date-epoch/.
+const sourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// reportTimestamp returns the time from SOURCE_DATE_EPOCH, or the zero time
+// when it is not set.
+func reportTimestamp(getenv func(string) string) (time.Time, error) {
+	value := getenv(sourceDateEpochEnv)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %v %q: must be a number of seconds", sourceDateEpochEnv, value)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// deterministicGoEnvironment returns the environment of the go command from
+// environment variables, instead of running the go command, so that the
+// reports do not change when a different go command is in PATH.
+func deterministicGoEnvironment(getenv func(string) string) goEnvironment {
+	version := getenv("GOVERSION")
+	if version == "" {
+		version = "unknown"
+	}
+	return goEnvironment{
+		Version: version,
+		GOOS:    getenv("GOOS"),
+		GOARCH:  getenv("GOARCH"),
+		GOFLAGS: getenv("GOFLAGS"),
+	}
+}
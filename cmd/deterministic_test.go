@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestReportTimestamp(t *testing.T) {
+	getenv := func(value string) func(string) string {
+		return func(name string) string {
+			if name == "SOURCE_DATE_EPOCH" {
+				return value
+			}
+			return ""
+		}
+	}
+
+	ts, err := reportTimestamp(getenv(""))
+	assert.NilError(t, err)
+	assert.Assert(t, ts.IsZero())
+
+	ts, err = reportTimestamp(getenv("1646370367"))
+	assert.NilError(t, err)
+	assert.Equal(t, ts, time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC))
+
+	_, err = reportTimestamp(getenv("yesterday"))
+	assert.Error(t, err, `invalid SOURCE_DATE_EPOCH "yesterday": must be a number of seconds`)
+}
+
+func TestGoEnv_Deterministic(t *testing.T) {
+	calls := patchGoEnvironment(t, goEnvironment{Version: "go1.1.1"})
+	env.PatchAll(t, map[string]string{
+		"GOVERSION": "go1.99.1",
+		"GOOS":      "plan9",
+		"GOFLAGS":   "-mod=vendor",
+	})
+
+	opts := &options{deterministic: true}
+	expected := goEnvironment{Version: "go1.99.1", GOOS: "plan9", GOFLAGS: "-mod=vendor"}
+	assert.Equal(t, goEnv(opts), expected)
+	assert.Equal(t, *calls, 0)
+}
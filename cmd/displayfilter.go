@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// displayFilter is a flag.Value that selects the packages and tests printed
+// by the formatter. The value is a list of patterns separated by |. A pattern
+// that starts with ./ or ends with /... selects a package, or all the
+// packages under a directory. Any other pattern is a regular expression that
+// selects tests by name, like the go test -run flag.
+type displayFilter struct {
+	raw      string
+	packages []string
+	tests    []*regexp.Regexp
+}
+
+func (f *displayFilter) String() string {
+	return f.raw
+}
+
+func (f *displayFilter) Set(raw string) error {
+	var packages []string
+	var tests []*regexp.Regexp
+	for _, pattern := range strings.Split(raw, "|") {
+		pattern = strings.TrimSpace(pattern)
+		switch {
+		case pattern == "":
+			return fmt.Errorf("display filter %q contains an empty pattern", raw)
+		case strings.HasPrefix(pattern, "./") || strings.HasSuffix(pattern, "/..."):
+			packages = append(packages, strings.TrimPrefix(pattern, "./"))
+		default:
+			expr, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid test name pattern %q: %w", pattern, err)
+			}
+			tests = append(tests, expr)
+		}
+	}
+	f.raw, f.packages, f.tests = raw, packages, tests
+	return nil
+}
+
+func (f *displayFilter) Type() string {
+	return "pattern"
+}
+
+func (f *displayFilter) enabled() bool {
+	return f.raw != ""
+}
+
+func (f *displayFilter) matchPackage(pkg string) bool {
+	rel := testjson.RelativePackagePath(pkg)
+	for _, pattern := range f.packages {
+		if matchPackagePattern(pattern, pkg) || matchPackagePattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPackagePattern returns true if pkg is the package of the pattern, or
+// if the pattern ends with /... and pkg is in the directory of the pattern.
+func matchPackagePattern(pattern, pkg string) bool {
+	if pattern == "..." {
+		return true
+	}
+	if dir := strings.TrimSuffix(pattern, "/..."); dir != pattern {
+		return pkg == dir || strings.HasPrefix(pkg, dir+"/")
+	}
+	return pkg == pattern
+}
+
+func (f *displayFilter) matchTest(name string) bool {
+	for _, expr := range f.tests {
+		if expr.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// displayFilterFormatter is an EventFormatter that only formats the events
+// selected by the filter. Package events are formatted when the package is
+// selected, or when at least one test in the package was selected.
+type displayFilterFormatter struct {
+	formatter testjson.EventFormatter
+	filter    *displayFilter
+	// testMatched is the set of packages with at least one selected test.
+	testMatched map[string]bool
+}
+
+func newDisplayFilterFormatter(formatter testjson.EventFormatter, filter *displayFilter) testjson.EventFormatter {
+	if !filter.enabled() {
+		return formatter
+	}
+	return &displayFilterFormatter{
+		formatter:   formatter,
+		filter:      filter,
+		testMatched: make(map[string]bool),
+	}
+}
+
+func (f *displayFilterFormatter) Format(event testjson.TestEvent, exec *testjson.Execution) error {
+	if !f.selected(event) {
+		return nil
+	}
+	return f.formatter.Format(event, exec)
+}
+
+func (f *displayFilterFormatter) selected(event testjson.TestEvent) bool {
+	if f.filter.matchPackage(event.Package) {
+		return true
+	}
+	if event.PackageEvent() {
+		return f.testMatched[event.Package]
+	}
+	if f.filter.matchTest(event.Test) {
+		f.testMatched[event.Package] = true
+		return true
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestDisplayFilter_Set(t *testing.T) {
+	var f displayFilter
+	assert.Assert(t, !f.enabled())
+	assert.NilError(t, f.Set("./api/...|example.com/db|TestFoo.*"))
+	assert.Assert(t, f.enabled())
+	assert.Equal(t, f.String(), "./api/...|example.com/db|TestFoo.*")
+	assert.DeepEqual(t, f.packages, []string{"api/..."})
+	assert.Equal(t, len(f.tests), 2)
+
+	assert.ErrorContains(t, f.Set("TestOne||TestTwo"), "contains an empty pattern")
+	assert.ErrorContains(t, f.Set("Test(One"), `invalid test name pattern "Test(One"`)
+}
+
+func TestMatchPackagePattern(t *testing.T) {
+	assert.Assert(t, matchPackagePattern("example.com/api/...", "example.com/api"))
+	assert.Assert(t, matchPackagePattern("example.com/api/...", "example.com/api/v1"))
+	assert.Assert(t, !matchPackagePattern("example.com/api/...", "example.com/apis"))
+	assert.Assert(t, matchPackagePattern("example.com/api", "example.com/api"))
+	assert.Assert(t, !matchPackagePattern("example.com/api", "example.com/api/v1"))
+	assert.Assert(t, matchPackagePattern("...", "example.com/other"))
+}
+
+func TestDisplayFilterFormatter(t *testing.T) {
+	var filter displayFilter
+	assert.NilError(t, filter.Set("example.com/api/...|TestFoo"))
+
+	out := new(bytes.Buffer)
+	formatter := newDisplayFilterFormatter(
+		testjson.NewEventFormatter(out, "standard-verbose", testjson.FormatOptions{}), &filter)
+
+	events := []testjson.TestEvent{
+		{Package: "example.com/api/v1", Test: "TestBar", Action: testjson.ActionOutput, Output: "api TestBar\n"},
+		{Package: "example.com/api/v1", Action: testjson.ActionOutput, Output: "api package\n"},
+		{Package: "example.com/db", Test: "TestBar", Action: testjson.ActionOutput, Output: "db TestBar\n"},
+		{Package: "example.com/db", Test: "TestFooBar/sub", Action: testjson.ActionOutput, Output: "db TestFooBar/sub\n"},
+		{Package: "example.com/db", Action: testjson.ActionOutput, Output: "db package\n"},
+		{Package: "example.com/other", Action: testjson.ActionOutput, Output: "other package\n"},
+	}
+	for _, event := range events {
+		assert.NilError(t, formatter.Format(event, nil))
+	}
+	expected := "api TestBar\napi package\ndb TestFooBar/sub\ndb package\n"
+	assert.Equal(t, out.String(), expected)
+}
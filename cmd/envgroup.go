@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/google/shlex"
+	"gotest.tools/gotestsum/internal/junitxml"
+)
+
+// envGroup is a name for a group of packages that are run with the same
+// environment variables, like the packages that use one database.
+type envGroup struct {
+	name     string
+	patterns []string
+	env      []string
+}
+
+// envGroupsValue is a flag.Value that maps the name of a group to the package
+// patterns and environment variables of the group. A value is a space
+// separated list, where each item that contains an = is an environment
+// variable, and every other item is a package pattern. A value may be set
+// more than once for the same name, which adds to the group.
+type envGroupsValue struct {
+	raw    []string
+	groups []envGroup
+	// byPackage is set by runWithPackageGroups to the index of the group of
+	// each package that matched one of the patterns.
+	byPackage map[string]int
+}
+
+func (v *envGroupsValue) Set(raw string) error {
+	parts := strings.SplitN(raw, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("invalid env group %q, must be name=patterns and KEY=VALUE", raw)
+	}
+	items, err := shlex.Split(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid env group %q: %w", raw, err)
+	}
+	name := strings.TrimSpace(parts[0])
+	var patterns, env []string
+	for _, item := range items {
+		switch i := strings.Index(item, "="); {
+		case i == 0:
+			return fmt.Errorf("env group %v contains an environment variable without a name: %q", name, item)
+		case i > 0:
+			env = append(env, item)
+		default:
+			patterns = append(patterns, item)
+		}
+	}
+	var g *envGroup
+	for i := range v.groups {
+		if v.groups[i].name == name {
+			g = &v.groups[i]
+		}
+	}
+	if g == nil {
+		v.groups = append(v.groups, envGroup{name: name})
+		g = &v.groups[len(v.groups)-1]
+	}
+	g.patterns = append(g.patterns, patterns...)
+	g.env = append(g.env, env...)
+	v.raw = append(v.raw, raw)
+	return nil
+}
+
+func (v *envGroupsValue) String() string {
+	return strings.Join(v.raw, ",")
+}
+
+func (v *envGroupsValue) Type() string {
+	return "name=values"
+}
+
+func (v *envGroupsValue) enabled() bool {
+	return len(v.groups) > 0
+}
+
+// validate returns an error if a group does not have any package patterns.
+func (v *envGroupsValue) validate() error {
+	for _, g := range v.groups {
+		if len(g.patterns) == 0 {
+			return fmt.Errorf("--env-group %v must have at least one package pattern", g.name)
+		}
+	}
+	return nil
+}
+
+// forPackage returns the group of the package, and false if the package did
+// not match any group.
+func (v *envGroupsValue) forPackage(pkg string) (envGroup, bool) {
+	i, ok := v.byPackage[pkg]
+	if !ok {
+		return envGroup{}, false
+	}
+	return v.groups[i], true
+}
+
+// envFor returns the environment variables of the group of the package.
+func (v *envGroupsValue) envFor(pkg string) []string {
+	g, _ := v.forPackage(pkg)
+	return g.env
+}
+
+// junitProperties returns the env property for the testsuite of the package.
+func (v *envGroupsValue) junitProperties(pkg string) []junitxml.JUnitProperty {
+	g, ok := v.forPackage(pkg)
+	if !ok {
+		return nil
+	}
+	return []junitxml.JUnitProperty{{Name: "env", Value: g.name}}
+}
+
+// splitGroupsByEnv splits each group into groups of packages that are in the
+// same env group, so that each go test command is run with the environment
+// of one group. The order of the groups, and of the packages in a group, is
+// preserved.
+func splitGroupsByEnv(groups []packageGroup, envGroups *envGroupsValue) []packageGroup {
+	var result []packageGroup
+	for _, group := range groups {
+		index := make(map[string]int)
+		for _, pkg := range group.packages {
+			g, _ := envGroups.forPackage(pkg)
+			i, ok := index[g.name]
+			if !ok {
+				i = len(result)
+				index[g.name] = i
+				result = append(result, packageGroup{
+					timeout: group.timeout,
+					args:    group.args,
+					env:     g.env,
+				})
+			}
+			result[i].packages = append(result[i].packages, pkg)
+		}
+	}
+	return result
+}
+
+// startGoTestWithEnv starts go test with the environment variables added to
+// the environment of gotestsum.
+func startGoTestWithEnv(ctx context.Context, dir string, args []string, env []string) (*proc, error) {
+	if len(env) == 0 {
+		return startGoTestFn(ctx, dir, args)
+	}
+	return startGoTestEnvFn(ctx, dir, args, env)
+}
+
+// startGoTestEnvFn is a shim for testing
+var startGoTestEnvFn = startGoTestEnv
+
+// mergeLines returns a reader that yields the lines read from all of the
+// readers. Lines are not split, so each line in the result is a line from one
+// of the readers. The reader returns io.EOF once all of the readers are done.
+func mergeLines(readers []io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, r := range readers {
+		wg.Add(1)
+		go func(r io.Reader) {
+			defer wg.Done()
+			reader := bufio.NewReader(r)
+			for {
+				line, err := reader.ReadBytes('\n')
+				if len(line) > 0 {
+					if line[len(line)-1] != '\n' {
+						line = append(line, '\n')
+					}
+					mu.Lock()
+					_, werr := pw.Write(line)
+					mu.Unlock()
+					if werr != nil {
+						// drain the reader so that the process is not blocked
+						_, _ = io.Copy(ioutil.Discard, reader)
+						return
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		}(r)
+	}
+	go func() {
+		wg.Wait()
+		_ = pw.Close()
+	}()
+	return pr
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestEnvGroupsValue(t *testing.T) {
+	var v envGroupsValue
+	assert.Assert(t, !v.enabled())
+	assert.NilError(t, v.Set("postgres=./store/... DB_URL=postgres://localhost/test"))
+	assert.NilError(t, v.Set("mysql=./store/mysql"))
+	assert.NilError(t, v.Set(`mysql=DB_URL="mysql://localhost/test?a=b c"`))
+	expected := []envGroup{
+		{
+			name:     "postgres",
+			patterns: []string{"./store/..."},
+			env:      []string{"DB_URL=postgres://localhost/test"},
+		},
+		{
+			name:     "mysql",
+			patterns: []string{"./store/mysql"},
+			env:      []string{"DB_URL=mysql://localhost/test?a=b c"},
+		},
+	}
+	assert.DeepEqual(t, v.groups, expected, gocmp.AllowUnexported(envGroup{}))
+	assert.Assert(t, v.enabled())
+	assert.NilError(t, v.validate())
+
+	assert.ErrorContains(t, v.Set("./store"), `invalid env group "./store"`)
+	assert.ErrorContains(t, v.Set("=./store"), `invalid env group "=./store"`)
+	assert.ErrorContains(t, v.Set("sqlite==memory"), `without a name: "=memory"`)
+
+	assert.NilError(t, v.Set("only-env=DB_URL=sqlite://"))
+	assert.Error(t, v.validate(), "--env-group only-env must have at least one package pattern")
+}
+
+func TestEnvGroupsValue_ForPackage(t *testing.T) {
+	v := envGroupsValue{
+		groups: []envGroup{
+			{name: "postgres", env: []string{"DB=pg"}},
+			{name: "mysql", env: []string{"DB=mysql"}},
+		},
+		byPackage: map[string]int{"ex.com/store/pg": 0, "ex.com/store/mysql": 1},
+	}
+	assert.DeepEqual(t, v.envFor("ex.com/store/mysql"), []string{"DB=mysql"})
+	assert.Assert(t, v.envFor("ex.com/api") == nil)
+	assert.DeepEqual(t, v.junitProperties("ex.com/store/pg"),
+		[]junitxml.JUnitProperty{{Name: "env", Value: "postgres"}})
+	assert.Assert(t, v.junitProperties("ex.com/api") == nil)
+}
+
+func TestSplitGroupsByEnv(t *testing.T) {
+	envGroups := &envGroupsValue{
+		groups: []envGroup{
+			{name: "postgres", env: []string{"DB=pg"}},
+			{name: "mysql", env: []string{"DB=mysql"}},
+		},
+		byPackage: map[string]int{
+			"ex.com/store/pg":    0,
+			"ex.com/store/mysql": 1,
+			"ex.com/store/pg/tx": 0,
+		},
+	}
+	groups := []packageGroup{
+		{args: []string{"-tags=db"}, packages: []string{"ex.com/store/pg", "ex.com/store/mysql", "ex.com/store/pg/tx"}},
+		{packages: []string{"ex.com/api", "ex.com/cli"}},
+	}
+
+	expected := []packageGroup{
+		{args: []string{"-tags=db"}, env: []string{"DB=pg"}, packages: []string{"ex.com/store/pg", "ex.com/store/pg/tx"}},
+		{args: []string{"-tags=db"}, env: []string{"DB=mysql"}, packages: []string{"ex.com/store/mysql"}},
+		{packages: []string{"ex.com/api", "ex.com/cli"}},
+	}
+	assert.DeepEqual(t, splitGroupsByEnv(groups, envGroups), expected, gocmp.AllowUnexported(packageGroup{}))
+}
+
+func TestMergeLines(t *testing.T) {
+	readers := []io.Reader{
+		strings.NewReader("one 1\none 2\none 3"),
+		strings.NewReader(""),
+		strings.NewReader("two 1\ntwo 2\n"),
+	}
+	raw, err := ioutil.ReadAll(mergeLines(readers))
+	assert.NilError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+	var one, two []string
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "one"):
+			one = append(one, line)
+		case strings.HasPrefix(line, "two"):
+			two = append(two, line)
+		}
+	}
+	assert.DeepEqual(t, one, []string{"one 1", "one 2", "one 3"})
+	assert.DeepEqual(t, two, []string{"two 1", "two 2"})
+	sort.Strings(lines)
+	assert.DeepEqual(t, lines, []string{"one 1", "one 2", "one 3", "two 1", "two 2"})
+}
+
+func TestLoadConfigFile_EnvGroups(t *testing.T) {
+	dir := fs.NewDir(t, "config", fs.WithFile(configFilename, `
+env-group:
+  postgres: ./store/... DB_URL=postgres://localhost/test
+  mysql:
+    - ./store/...
+    - DB_URL=mysql://localhost/test
+env-parallel: true
+`))
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, loadConfigFile(flags, dir.Join(configFilename)))
+	expected := []envGroup{
+		{
+			name:     "postgres",
+			patterns: []string{"./store/..."},
+			env:      []string{"DB_URL=postgres://localhost/test"},
+		},
+		{
+			name:     "mysql",
+			patterns: []string{"./store/..."},
+			env:      []string{"DB_URL=mysql://localhost/test"},
+		},
+	}
+	assert.DeepEqual(t, opts.envGroups.groups, expected, gocmp.AllowUnexported(envGroup{}))
+	assert.Assert(t, opts.envParallel)
+}
+
+func TestRun_EnvGroup_BadPattern(t *testing.T) {
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{"--env-group", "x=./nope/... A=1"}))
+	out := new(bytes.Buffer)
+	opts.stdout = out
+	opts.stderr = new(bytes.Buffer)
+
+	err := run(opts)
+	assert.ErrorContains(t, err, "./nope/...")
+	assert.Assert(t, strings.Contains(out.String(), "DONE 0 tests"), out.String())
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// goEnvironment is the environment of the go command that runs the tests.
+type goEnvironment struct {
+	// Version is the output of go version, without the "go version " prefix.
+	Version string
+	GOOS    string
+	GOARCH  string
+	GOFLAGS string
+	// Module is the path of the module in the current directory.
+	Module string
+	// Revision is the VCS revision of the gotestsum binary, from its build
+	// info.
+	Revision string
+}
+
+// goEnv returns the environment of the go command. The environment is probed
+// once, and stored on opts, so that every report uses the same values. With
+// --deterministic the environment is read from environment variables.
+func goEnv(opts *options) goEnvironment {
+	if opts.goEnv == nil {
+		var env goEnvironment
+		if opts.deterministic {
+			env = deterministicGoEnvironment(os.Getenv)
+		} else {
+			env = probeGoEnvironment()
+		}
+		opts.goEnv = &env
+	}
+	return *opts.goEnv
+}
+
+// probeGoEnvironment is a variable so that it can be replaced in tests.
+var probeGoEnvironment = func() goEnvironment {
+	env := goEnvironment{Version: goVersion()}
+
+	log.Debugf("exec: go env -json GOOS GOARCH GOFLAGS GOMOD")
+	out, err := exec.Command("go", "env", "-json", "GOOS", "GOARCH", "GOFLAGS", "GOMOD").Output()
+	vars := map[string]string{}
+	if err == nil {
+		err = json.Unmarshal(out, &vars)
+	}
+	if err != nil {
+		log.Warnf("Failed to lookup go env: %v", err)
+		vars["GOFLAGS"] = os.Getenv("GOFLAGS")
+	}
+	env.GOOS = vars["GOOS"]
+	env.GOARCH = vars["GOARCH"]
+	env.GOFLAGS = vars["GOFLAGS"]
+	if gomod := vars["GOMOD"]; gomod != "" && gomod != os.DevNull {
+		env.Module, _ = readModulePath(filepath.Clean(gomod))
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				env.Revision = setting.Value
+			}
+		}
+	}
+	return env
+}
+
+// goVersion returns the version as reported by the go binary in PATH. This
+// version will not be the same as runtime.Version, which is always the version
+// of go used to build the gotestsum binary.
+//
+// To skip the os/exec call set the GOVERSION environment variable to the
+// desired value.
+func goVersion() string {
+	if version, ok := os.LookupEnv("GOVERSION"); ok {
+		return version
+	}
+	log.Debugf("exec: go version")
+	out, err := exec.Command("go", "version").Output()
+	if err != nil {
+		log.Warnf("Failed to lookup go version: %v", err)
+		return "unknown"
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "go version ")
+}
+
+// runEnvironment returns the settings of the test run which are necessary to
+// reproduce a failure: the go version, GOOS and GOARCH, the effective GOFLAGS,
+// whether the race detector was enabled, and the value of every environment
+// variable in opts.captureEnv. The values of variables that match a
+// --redact-env pattern are replaced. The value is computed once, and stored
+// on opts.
+func runEnvironment(opts *options) map[string]string {
+	if opts.environment != nil {
+		return opts.environment
+	}
+	env := make(map[string]string)
+	goenv := goEnv(opts)
+	goflags := goenv.GOFLAGS
+	if goflags != "" {
+		env["go.flags"] = goflags
+	}
+	env["go.version"] = goenv.Version
+	setIfNotEmpty(env, "go.os", goenv.GOOS)
+	setIfNotEmpty(env, "go.arch", goenv.GOARCH)
+	setIfNotEmpty(env, "go.main.module", goenv.Module)
+	setIfNotEmpty(env, "gotestsum.revision", goenv.Revision)
+	env["go.race"] = strconv.FormatBool(raceEnabled(opts.args, goflags))
+	for name, value := range capturedEnv(opts.captureEnv, os.Environ()) {
+		if redactEnv(opts, name) {
+			value = redactedValue
+		}
+		env["env."+name] = value
+	}
+	opts.environment = env
+	return env
+}
+
+// capturedEnv returns the variables in environ that match the names, or glob
+// patterns, in captureEnv.
+func capturedEnv(captureEnv []string, environ []string) map[string]string {
+	result := make(map[string]string)
+	if len(captureEnv) == 0 {
+		return result
+	}
+	values := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 {
+			values[kv[:i]] = kv[i+1:]
+		}
+	}
+	for _, name := range captureEnv {
+		if !isGlob(name) {
+			if value, ok := values[name]; ok {
+				result[name] = value
+			}
+			continue
+		}
+		for key, value := range values {
+			if ok, _ := path.Match(name, key); ok {
+				result[key] = value
+			}
+		}
+	}
+	return result
+}
+
+func setIfNotEmpty(env map[string]string, name, value string) {
+	if value != "" {
+		env[name] = value
+	}
+}
+
+func raceEnabled(args []string, goflags string) bool {
+	for _, arg := range append(strings.Fields(goflags), args...) {
+		switch arg {
+		case "-race", "--race", "-race=true", "--race=true":
+			return true
+		}
+	}
+	return false
+}
+
+// environmentProperties returns the run environment as JUnit properties,
+// sorted by name. The go.version property is excluded, because it is added by
+// junitxml from Config.GoVersion.
+func environmentProperties(opts *options) []junitxml.JUnitProperty {
+	env := runEnvironment(opts)
+	props := make([]junitxml.JUnitProperty, 0, len(env))
+	for _, name := range sortedStringKeys(env) {
+		if name == "go.version" {
+			continue
+		}
+		props = append(props, junitxml.JUnitProperty{Name: name, Value: env[name]})
+	}
+	return props
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestRaceEnabled(t *testing.T) {
+	assert.Assert(t, !raceEnabled(nil, ""))
+	assert.Assert(t, raceEnabled([]string{"-count=1", "-race"}, ""))
+	assert.Assert(t, raceEnabled(nil, "-mod=mod -race"))
+	assert.Assert(t, !raceEnabled([]string{"-race=false"}, ""))
+}
+
+func patchGoEnvironment(t *testing.T, env goEnvironment) *int {
+	t.Helper()
+	calls := new(int)
+	orig := probeGoEnvironment
+	probeGoEnvironment = func() goEnvironment {
+		*calls++
+		return env
+	}
+	t.Cleanup(func() { probeGoEnvironment = orig })
+	return calls
+}
+
+func TestRunEnvironment(t *testing.T) {
+	calls := patchGoEnvironment(t, goEnvironment{
+		Version: "go7.7.7 linux/amd64",
+		GOOS:    "linux",
+		GOARCH:  "arm64",
+		GOFLAGS: "-tags=integration",
+		Module:  "example.com/repo",
+	})
+	env.Patch(t, "GOTESTSUM_EXAMPLE", "value")
+	opts := &options{
+		args:       []string{"-race"},
+		captureEnv: []string{"GOTESTSUM_EXAMPLE", "GOTESTSUM_UNSET_EXAMPLE"},
+	}
+	expected := map[string]string{
+		"go.flags":              "-tags=integration",
+		"go.race":               "true",
+		"go.version":            "go7.7.7 linux/amd64",
+		"go.os":                 "linux",
+		"go.arch":               "arm64",
+		"go.main.module":        "example.com/repo",
+		"env.GOTESTSUM_EXAMPLE": "value",
+	}
+	assert.DeepEqual(t, runEnvironment(opts), expected)
+	assert.DeepEqual(t, opts.environment, expected)
+
+	props := environmentProperties(opts)
+	for _, prop := range props {
+		assert.Assert(t, prop.Name != "go.version")
+	}
+	opts.junitTestSuiteNameFormat = &junitFieldFormatValue{}
+	opts.junitTestCaseClassnameFormat = &junitFieldFormatValue{}
+	assert.Equal(t, junitConfig(opts).GoVersion, "go7.7.7 linux/amd64")
+	assert.Equal(t, *calls, 1, "go environment is probed once")
+}
+
+func TestRunEnvironment_CaptureAndRedact(t *testing.T) {
+	patchGoEnvironment(t, goEnvironment{Version: "go7.7.7 linux/amd64"})
+	env.PatchAll(t, map[string]string{
+		"GOTESTSUM_CI_NAME":  "build",
+		"GOTESTSUM_CI_TOKEN": "abc123",
+		"GOTESTSUM_DB_URL":   "postgres://user:pass@db",
+		"GOTESTSUM_OTHER":    "other",
+	})
+	opts := &options{
+		captureEnv: []string{"GOTESTSUM_CI_*", "GOTESTSUM_DB_URL"},
+		redactEnv:  envPatternsValue{"*_DB_URL"},
+	}
+	expected := map[string]string{
+		"go.race":                "false",
+		"go.version":             "go7.7.7 linux/amd64",
+		"env.GOTESTSUM_CI_NAME":  "build",
+		"env.GOTESTSUM_CI_TOKEN": redactedValue,
+		"env.GOTESTSUM_DB_URL":   redactedValue,
+	}
+	assert.DeepEqual(t, runEnvironment(opts), expected)
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// exitCategory identifies the reason a test run failed. Each category has a
+// distinct exit code, which can be changed with --exit-code-map.
+type exitCategory string
+
+const (
+	exitTestFailure    exitCategory = "test-failure"
+	exitBuildError     exitCategory = "build-error"
+	exitInternalError  exitCategory = "internal-error"
+	exitMisuse         exitCategory = "misuse"
+	exitRerunExhausted exitCategory = "rerun-exhausted"
+	exitTimeout        exitCategory = "timeout"
+	exitSetupFailure   exitCategory = "setup-failure"
+)
+
+var defaultExitCodes = map[exitCategory]int{
+	exitTestFailure:    1,
+	exitBuildError:     2,
+	exitInternalError:  3,
+	exitMisuse:         4,
+	exitRerunExhausted: 5,
+	exitTimeout:        6,
+	exitSetupFailure:   7,
+}
+
+// categoryError is an error with the exitCategory that identifies the reason
+// for the failure.
+type categoryError struct {
+	category exitCategory
+	err      error
+	// reported is true when the error was already reported by go test, and
+	// does not need to be printed.
+	reported bool
+}
+
+func (e categoryError) Error() string {
+	return e.err.Error()
+}
+
+func (e categoryError) Unwrap() error {
+	return e.err
+}
+
+// isTestRunFailure returns true if err is the result of go test exiting
+// non-zero, and not an error from gotestsum.
+func isTestRunFailure(err error) bool {
+	var catErr categoryError
+	if errors.As(err, &catErr) {
+		return catErr.reported
+	}
+	return IsExitCoder(err)
+}
+
+func misuseError(err error) error {
+	return categoryError{category: exitMisuse, err: err}
+}
+
+// categorize returns exitErr with the exitCategory for the reason the test
+// run failed. Exit codes from signals, and exit codes from go test other than
+// 1 are returned unchanged.
+func categorize(exec *testjson.Execution, exitErr error) error {
+	var catErr categoryError
+	switch {
+	case exitErr == nil:
+		return nil
+	case errors.As(exitErr, &catErr):
+		return exitErr
+	case isSignalExitError(exitErr):
+		return exitErr
+	}
+
+	reported := IsExitCoder(exitErr)
+	switch {
+	case exec != nil && exec.HasTimeout():
+		return categoryError{category: exitTimeout, err: exitErr, reported: reported}
+	case exec != nil && len(exec.Errors()) > 0:
+		return categoryError{category: exitBuildError, err: exitErr, reported: reported}
+	case !reported:
+		return categoryError{category: exitInternalError, err: exitErr}
+	case ExitCodeWithDefault(exitErr) == 1:
+		return categoryError{category: exitTestFailure, err: exitErr, reported: true}
+	default:
+		return exitErr
+	}
+}
+
+// withCheckErrors returns the first of checkErrs that is not nil when the test
+// run did not fail. When the run failed, err keeps the exit code for the
+// reason the run failed, and the errors from the checks are printed.
+func withCheckErrors(err error, checkErrs ...error) error {
+	for _, checkErr := range checkErrs {
+		switch {
+		case checkErr == nil:
+		case err == nil:
+			err = checkErr
+		default:
+			log.Error(checkErr.Error())
+		}
+	}
+	return err
+}
+
+func isSignalExitError(err error) bool {
+	var exitErr exitError
+	return errors.As(err, &exitErr) && exitErr.num > signalExitCode
+}
+
+// mappedExitError is returned by Run. It uses the exit code from the
+// exitCodeMap for the category of the error.
+type mappedExitError struct {
+	code     int
+	err      error
+	reported bool
+}
+
+func (e mappedExitError) Error() string {
+	return e.err.Error()
+}
+
+func (e mappedExitError) Unwrap() error {
+	return e.err
+}
+
+func (e mappedExitError) ExitCode() int {
+	return e.code
+}
+
+// ErrorMessage returns the message to print for an error returned by Run. An
+// empty string is returned when the error has already been reported by go test.
+func ErrorMessage(err error) string {
+	var codeErr mappedExitError
+	switch {
+	case errors.As(err, &codeErr):
+		if codeErr.reported {
+			return ""
+		}
+		return codeErr.Error()
+	case IsExitCoder(err):
+		return ""
+	}
+	return err.Error()
+}
+
+// exitCodeMap is a flag.Value that maps an exitCategory to an exit code.
+type exitCodeMap struct {
+	raw   []string
+	codes map[exitCategory]int
+}
+
+func (m *exitCodeMap) String() string {
+	return strings.Join(m.raw, ",")
+}
+
+func (m *exitCodeMap) Set(raw string) error {
+	items, err := readAsCSV(raw)
+	if err != nil {
+		return err
+	}
+	if m.codes == nil {
+		m.codes = make(map[exitCategory]int)
+	}
+	for _, item := range items {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		category := exitCategory(parts[0])
+		if _, ok := defaultExitCodes[category]; !ok || len(parts) != 2 {
+			return fmt.Errorf("invalid exit code mapping %q, must be name=code, where name is one of: %v",
+				item, exitCategoryNames())
+		}
+		code, err := strconv.Atoi(parts[1])
+		if err != nil || code < 0 || code > 255 {
+			return fmt.Errorf("invalid exit code in %q, must be a number from 0 to 255", item)
+		}
+		m.codes[category] = code
+	}
+	m.raw = append(m.raw, raw)
+	return nil
+}
+
+func (m *exitCodeMap) Type() string {
+	return "mapping"
+}
+
+func (m *exitCodeMap) code(category exitCategory) int {
+	if code, ok := m.codes[category]; ok {
+		return code
+	}
+	return defaultExitCodes[category]
+}
+
+// resolve returns an mappedExitError with the exit code for the category of
+// err. Errors without a category are internal errors.
+func (m *exitCodeMap) resolve(err error) error {
+	var catErr categoryError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &catErr):
+		return mappedExitError{code: m.code(catErr.category), err: err, reported: catErr.reported}
+	case IsExitCoder(err):
+		return err
+	default:
+		return mappedExitError{code: m.code(exitInternalError), err: err}
+	}
+}
+
+func exitCategoryNames() string {
+	names := make([]string, 0, len(defaultExitCodes))
+	for category := range defaultExitCodes {
+		names = append(names, string(category))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
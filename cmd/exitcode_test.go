@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestCategorize(t *testing.T) {
+	scan := func(t *testing.T, stdout string, stderr string) *testjson.Execution {
+		exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+			Stdout: strings.NewReader(stdout),
+			Stderr: strings.NewReader(stderr),
+		})
+		assert.NilError(t, err)
+		return exec
+	}
+	failed := `{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"fail","Package":"pkg","Test":"TestOne"}
+{"Action":"fail","Package":"pkg"}
+`
+	timedOut := `{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"panic: test timed out after 1s\n"}
+{"Action":"fail","Package":"pkg"}
+`
+
+	type testCase struct {
+		name     string
+		exec     *testjson.Execution
+		err      error
+		expected exitCategory
+		reported bool
+	}
+	testCases := []testCase{
+		{
+			name:     "test failure",
+			exec:     scan(t, failed, ""),
+			err:      newExitCode("exit status 1", 1),
+			expected: exitTestFailure,
+			reported: true,
+		},
+		{
+			name:     "build error",
+			exec:     scan(t, failed, "pkg/file.go:1:1: syntax error\n"),
+			err:      newExitCode("exit status 1", 1),
+			expected: exitBuildError,
+			reported: true,
+		},
+		{
+			name:     "timeout",
+			exec:     scan(t, timedOut, ""),
+			err:      newExitCode("exit status 1", 1),
+			expected: exitTimeout,
+			reported: true,
+		},
+		{
+			name:     "internal error",
+			exec:     scan(t, failed, ""),
+			err:      errors.New("failed to write junit file"),
+			expected: exitInternalError,
+		},
+		{
+			name:     "already categorized",
+			exec:     scan(t, failed, ""),
+			err:      misuseError(errors.New("bad flag")),
+			expected: exitMisuse,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var catErr categoryError
+			assert.Assert(t, errors.As(categorize(tc.exec, tc.err), &catErr))
+			assert.Equal(t, catErr.category, tc.expected)
+			assert.Equal(t, catErr.reported, tc.reported)
+		})
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		assert.NilError(t, categorize(nil, nil))
+		signal := exitError{num: signalExitCode + 2}
+		assert.Equal(t, categorize(nil, signal), error(signal))
+		other := newExitCode("exit status 2", 2)
+		assert.Equal(t, categorize(scan(t, failed, ""), other), other)
+	})
+}
+
+func TestWithCheckErrors(t *testing.T) {
+	checkErr := errors.New("2 JUnit testcase names are used by more than one test")
+
+	t.Run("run passed", func(t *testing.T) {
+		assert.Equal(t, withCheckErrors(nil, nil, checkErr), checkErr)
+		assert.NilError(t, withCheckErrors(nil, nil, nil))
+	})
+	t.Run("run failed", func(t *testing.T) {
+		runErr := categoryError{category: exitTestFailure, err: newExitCode("exit status 1", 1), reported: true}
+		err := withCheckErrors(runErr, checkErr)
+		var catErr categoryError
+		assert.Assert(t, errors.As(err, &catErr))
+		assert.Equal(t, catErr.category, exitTestFailure)
+	})
+}
+
+func TestExitCodeMap(t *testing.T) {
+	var codes exitCodeMap
+	assert.NilError(t, codes.Set("build-error=10,timeout=20"))
+	assert.NilError(t, codes.Set("test-failure=0"))
+	assert.Equal(t, codes.String(), "build-error=10,timeout=20,test-failure=0")
+	assert.Equal(t, codes.code(exitBuildError), 10)
+	assert.Equal(t, codes.code(exitTimeout), 20)
+	assert.Equal(t, codes.code(exitTestFailure), 0)
+	assert.Equal(t, codes.code(exitMisuse), 4)
+
+	assert.ErrorContains(t, codes.Set("flaky=3"), `invalid exit code mapping "flaky=3"`)
+	assert.ErrorContains(t, codes.Set("timeout"), `invalid exit code mapping "timeout"`)
+	assert.ErrorContains(t, codes.Set("timeout=300"), "must be a number from 0 to 255")
+}
+
+func TestExitCodeMap_Resolve(t *testing.T) {
+	var codes exitCodeMap
+	assert.NilError(t, codes.Set("misuse=64"))
+
+	err := codes.resolve(misuseError(errors.New("bad flag")))
+	assert.Equal(t, ExitCodeWithDefault(err), 64)
+	assert.Equal(t, ErrorMessage(err), "bad flag")
+
+	err = codes.resolve(categoryError{category: exitTestFailure, err: newExitCode("exit status 1", 1), reported: true})
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Equal(t, ErrorMessage(err), "")
+
+	err = codes.resolve(errors.New("something broke"))
+	assert.Equal(t, ExitCodeWithDefault(err), 3)
+	assert.Equal(t, ErrorMessage(err), "something broke")
+
+	signal := exitError{num: signalExitCode + 2}
+	assert.Equal(t, codes.resolve(signal), error(signal))
+	assert.NilError(t, codes.resolve(nil))
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// failureMatcher matches a test failure by package, test name, or output. It
+// is used by the rules of the --severity-rules and --flake-rules files.
+type failureMatcher struct {
+	// pkg is a package pattern, like ./api or ./api/...
+	pkg string
+	// test and output are regular expressions that match the name and the
+	// output of the test.
+	test   *regexp.Regexp
+	output *regexp.Regexp
+}
+
+// parseFailureMatcher parses a matcher in the form package=pattern,
+// test=regexp, or output=regexp.
+func parseFailureMatcher(text string) (failureMatcher, error) {
+	var m failureMatcher
+	kv := strings.SplitN(text, "=", 2)
+	if len(kv) != 2 || kv[1] == "" {
+		return m, fmt.Errorf("invalid rule %q, must be package=, test=, or output=", text)
+	}
+	switch kv[0] {
+	case "package":
+		m.pkg = strings.TrimPrefix(kv[1], "./")
+		if kv[1] == "./" {
+			m.pkg = "."
+		}
+	case "test", "output":
+		expr, err := regexp.Compile(kv[1])
+		if err != nil {
+			return m, fmt.Errorf("invalid %v pattern %q: %w", kv[0], kv[1], err)
+		}
+		if kv[0] == "test" {
+			m.test = expr
+		} else {
+			m.output = expr
+		}
+	default:
+		return m, fmt.Errorf("invalid rule %q, must be package=, test=, or output=", text)
+	}
+	return m, nil
+}
+
+func (m failureMatcher) match(tc testjson.TestCase, output string) bool {
+	switch {
+	case m.pkg != "":
+		return matchPackagePattern(m.pkg, tc.Package) ||
+			matchPackagePattern(m.pkg, testjson.RelativePackagePath(tc.Package))
+	case m.test != nil:
+		return m.test.MatchString(tc.Test.Name())
+	case m.output != nil:
+		return m.output.MatchString(output)
+	}
+	return false
+}
+
+// failureOutput returns the output of a failed test in exec. The output of a
+// failure of TestMain is the output of the package.
+func failureOutput(exec *testjson.Execution, tc testjson.TestCase) string {
+	pkg := exec.Package(tc.Package)
+	switch {
+	case pkg == nil:
+		return ""
+	case tc.Test == "" || tc.Test == "TestMain":
+		return pkg.Output(0)
+	}
+	return strings.Join(pkg.OutputLines(tc), "")
+}
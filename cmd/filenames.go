@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// filenameVarPattern matches a variable, like {shard}, in the name of a
+// report file.
+var filenameVarPattern = regexp.MustCompile(`{([^{}]*)}`)
+
+// shardEnvVars are the environment variables that are used as the {shard}
+// variable when --shard-index is not set. The first one that is set is used.
+var shardEnvVars = []string{
+	"GOTESTSUM_SHARD_INDEX",
+	"CI_NODE_INDEX",          // GitLab CI, CircleCI
+	"BUILDKITE_PARALLEL_JOB", // Buildkite
+}
+
+// filenameVars returns the value of each variable that can be used in the
+// name of a report file. The values are the same for every file in the run,
+// so that the files from one run can be matched.
+func filenameVars(opts *options) map[string]string {
+	return map[string]string{
+		"shard":     shardIndex(opts, os.Getenv),
+		"pid":       strconv.Itoa(os.Getpid()),
+		"timestamp": filenameNow().UTC().Format("20060102T150405Z"),
+		"random":    randomSuffix(),
+	}
+}
+
+// filenameNow is a shim for testing.
+var filenameNow = time.Now
+
+func shardIndex(opts *options, getenv func(string) string) string {
+	if opts.shardIndex != "" {
+		return opts.shardIndex
+	}
+	for _, name := range shardEnvVars {
+		if v := getenv(name); v != "" {
+			return v
+		}
+	}
+	return "0"
+}
+
+func randomSuffix() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// expandFilename replaces the variables in the name of a report file with
+// their values. A variable that is not in vars is an error.
+func expandFilename(name string, vars map[string]string) (string, error) {
+	var err error
+	result := filenameVarPattern.ReplaceAllStringFunc(name, func(match string) string {
+		key := strings.Trim(match, "{}")
+		value, ok := vars[key]
+		if !ok && err == nil {
+			err = fmt.Errorf("unknown variable %v in file name %q, must be one of: %v",
+				match, name, filenameVarNames(vars))
+		}
+		return value
+	})
+	return result, err
+}
+
+func filenameVarNames(vars map[string]string) string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, "{"+name+"}")
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// expandFilenames replaces the variables in the names of all the files
+// written by the run.
+func expandFilenames(opts *options) error {
+	files := []struct {
+		flag  string
+		value *string
+	}{
+		{"--jsonfile", &opts.jsonFile},
+		{"--junitfile", &opts.junitFile},
+		{"--summary-jsonfile", &opts.summaryJSONFile},
+		{"--report-output", &opts.reportOutput},
+		{"--csvfile", &opts.csvFile},
+		{"--sariffile", &opts.sarifFile},
+		{"--cucumberfile", &opts.cucumberFile},
+		{"--nunitfile", &opts.nunitFile},
+		{"--baseline-output", &opts.baselineOutput},
+		{"--rerun-fails-report", &opts.rerunFailsReportFile},
+		{"--write-repro-script", &opts.reproScript},
+		{"--test-manifest", &opts.testManifest},
+		{"--archive", &opts.archive},
+	}
+	var vars map[string]string
+	for _, file := range files {
+		if !strings.Contains(*file.value, "{") {
+			continue
+		}
+		if vars == nil {
+			vars = filenameVars(opts)
+		}
+		name, err := expandFilename(*file.value, vars)
+		if err != nil {
+			return fmt.Errorf("%v: %w", file.flag, err)
+		}
+		log.Debugf("%v %v", file.flag, name)
+		*file.value = name
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestExpandFilename(t *testing.T) {
+	vars := map[string]string{"shard": "2", "pid": "1234"}
+	type testCase struct {
+		name     string
+		input    string
+		expected string
+		err      string
+	}
+	run := func(t *testing.T, tc testCase) {
+		actual, err := expandFilename(tc.input, vars)
+		if tc.err != "" {
+			assert.Error(t, err, tc.err)
+			return
+		}
+		assert.NilError(t, err)
+		assert.Equal(t, actual, tc.expected)
+	}
+	testCases := []testCase{
+		{name: "no variables", input: "report.xml", expected: "report.xml"},
+		{name: "variables", input: "out/report-{shard}-{pid}.xml", expected: "out/report-2-1234.xml"},
+		{name: "repeated", input: "{shard}/{shard}.json", expected: "2/2.json"},
+		{
+			name:  "unknown variable",
+			input: "report-{node}.xml",
+			err:   `unknown variable {node} in file name "report-{node}.xml", must be one of: {pid}, {shard}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestExpandFilenames(t *testing.T) {
+	env.Patch(t, "CI_NODE_INDEX", "3")
+	orig := filenameNow
+	filenameNow = func() time.Time {
+		return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	}
+	t.Cleanup(func() {
+		filenameNow = orig
+	})
+
+	opts := &options{
+		jsonFile:  "events-{shard}-{timestamp}.json",
+		junitFile: "junit-{shard}-{pid}-{random}.xml",
+		csvFile:   "results.csv",
+	}
+	assert.NilError(t, expandFilenames(opts))
+	assert.Equal(t, opts.jsonFile, "events-3-20240506T070809Z.json")
+	prefix := "junit-3-" + strconv.Itoa(os.Getpid()) + "-"
+	assert.Assert(t, len(opts.junitFile) == len(prefix)+8+len(".xml"), opts.junitFile)
+	assert.Equal(t, opts.junitFile[:len(prefix)], prefix)
+	assert.Equal(t, opts.csvFile, "results.csv")
+
+	opts = &options{shardIndex: "1", sarifFile: "{shard}-{bogus}.sarif"}
+	assert.ErrorContains(t, expandFilenames(opts), "--sariffile: unknown variable {bogus}")
+}
+
+func TestShardIndex(t *testing.T) {
+	getenv := func(values map[string]string) func(string) string {
+		return func(key string) string {
+			return values[key]
+		}
+	}
+	assert.Equal(t, shardIndex(&options{}, getenv(nil)), "0")
+	assert.Equal(t, shardIndex(&options{}, getenv(map[string]string{"BUILDKITE_PARALLEL_JOB": "4"})), "4")
+	assert.Equal(t, shardIndex(&options{}, getenv(map[string]string{
+		"GOTESTSUM_SHARD_INDEX": "1",
+		"CI_NODE_INDEX":         "2",
+	})), "1")
+	assert.Equal(t, shardIndex(&options{shardIndex: "5"}, getenv(map[string]string{"CI_NODE_INDEX": "2"})), "5")
+}
@@ -56,17 +56,21 @@ var junitFieldFormatValues = "full, relative, short"
 
 type junitFieldFormatValue struct {
 	value junitxml.FormatFunc
+	// set is true when the flag was set, so that the value is not replaced by
+	// the format from --short-package-names.
+	set bool
 }
 
 func (f *junitFieldFormatValue) Set(val string) error {
 	switch val {
 	case "full":
+		f.value, f.set = nil, true
 		return nil
 	case "relative":
-		f.value = testjson.RelativePackagePath
+		f.value, f.set = testjson.RelativePackagePath, true
 		return nil
 	case "short":
-		f.value = path.Base
+		f.value, f.set = path.Base, true
 		return nil
 	}
 	return fmt.Errorf("invalid value: %v, must be one of: "+junitFieldFormatValues, val)
@@ -87,6 +91,56 @@ func (f *junitFieldFormatValue) Value() junitxml.FormatFunc {
 	return f.value
 }
 
+type junitSchemaValue struct {
+	value junitxml.Schema
+}
+
+func (s *junitSchemaValue) Set(val string) error {
+	schema, err := junitxml.ParseSchema(val)
+	s.value = schema
+	return err
+}
+
+func (s *junitSchemaValue) Type() string {
+	return "schema"
+}
+
+func (s *junitSchemaValue) String() string {
+	if s.value == "" {
+		return string(junitxml.SchemaJenkins)
+	}
+	return string(s.value)
+}
+
+func junitSchemaNames() string {
+	names := make([]string, 0, len(junitxml.Schemas))
+	for _, schema := range junitxml.Schemas {
+		names = append(names, string(schema))
+	}
+	return strings.Join(names, ", ")
+}
+
+type junitSortValue struct {
+	value junitxml.Sort
+}
+
+func (s *junitSortValue) Set(val string) error {
+	order, err := junitxml.ParseSort(val)
+	s.value = order
+	return err
+}
+
+func (s *junitSortValue) Type() string {
+	return "order"
+}
+
+func (s *junitSortValue) String() string {
+	if s.value == "" {
+		return string(junitxml.SortRunOrder)
+	}
+	return string(s.value)
+}
+
 type commandValue struct {
 	original string
 	command  []string
@@ -114,6 +168,38 @@ func (c *commandValue) Value() []string {
 	return c.command
 }
 
+// commandListValue is a flag.Value which accumulates a list of commands,
+// one for each time the flag is set.
+type commandListValue struct {
+	original []string
+	commands [][]string
+}
+
+func (c *commandListValue) String() string {
+	return strings.Join(c.original, ", ")
+}
+
+func (c *commandListValue) Set(raw string) error {
+	command, err := shlex.Split(raw)
+	if err != nil {
+		return err
+	}
+	c.original = append(c.original, raw)
+	c.commands = append(c.commands, command)
+	return nil
+}
+
+func (c *commandListValue) Type() string {
+	return "command"
+}
+
+func (c *commandListValue) Value() [][]string {
+	if c == nil {
+		return nil
+	}
+	return c.commands
+}
+
 var _ pflag.Value = (*stringSlice)(nil)
 
 // stringSlice is a flag.Value which populates the string slice by splitting
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// flakeRulesNow is a shim for testing.
+var flakeRulesNow = time.Now
+
+// flakeRule is a known flaky failure from the --flake-rules file.
+type flakeRule struct {
+	line  int
+	owner string
+	// expires is the last day the rule is active.
+	expires time.Time
+	failureMatcher
+}
+
+func (r flakeRule) expired(now time.Time) bool {
+	return !now.Before(r.expires.AddDate(0, 0, 1))
+}
+
+// flakeRulesValue is a flag.Value for the --flake-rules file. Each line of
+// the file is the date a rule expires, the owner of the rule, and a rule in
+// the format of the --severity-rules file, like:
+//
+//	2024-06-30 @team-payments test=^TestCharge$
+//	2024-05-01 alice output=connection reset by peer
+//
+// Blank lines and lines that start with # are ignored.
+type flakeRulesValue struct {
+	filename string
+	rules    []flakeRule
+}
+
+func (v *flakeRulesValue) Set(filename string) error {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read flake rules: %w", err)
+	}
+	var rules []flakeRule
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseFlakeRule(line)
+		if err != nil {
+			return fmt.Errorf("%v:%d: %w", filename, lineNum, err)
+		}
+		rule.line = lineNum
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read flake rules: %w", err)
+	}
+	v.filename = filename
+	v.rules = rules
+	return nil
+}
+
+func parseFlakeRule(line string) (flakeRule, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return flakeRule{}, fmt.Errorf("expected an expiry date, an owner, and a rule, like: 2024-06-30 alice test=^TestName$")
+	}
+	expires, err := time.ParseInLocation("2006-01-02", fields[0], time.Local)
+	if err != nil {
+		return flakeRule{}, fmt.Errorf("invalid expiry date %q, must be YYYY-MM-DD", fields[0])
+	}
+	// the rule is the rest of the line, which may contain spaces.
+	rest := strings.TrimSpace(line[len(fields[0]):])
+	matcher, err := parseFailureMatcher(strings.TrimSpace(rest[len(fields[1]):]))
+	if err != nil {
+		return flakeRule{}, err
+	}
+	return flakeRule{owner: fields[1], expires: expires, failureMatcher: matcher}, nil
+}
+
+func (v *flakeRulesValue) String() string {
+	return v.filename
+}
+
+func (v *flakeRulesValue) Type() string {
+	return "filename"
+}
+
+func (v *flakeRulesValue) enabled() bool {
+	return len(v.rules) > 0
+}
+
+// forFailure returns the first rule that matches the failure, or nil if no
+// rule matches.
+func (v *flakeRulesValue) forFailure(exec *testjson.Execution, tc testjson.TestCase) *flakeRule {
+	output := failureOutput(exec, tc)
+	for i, rule := range v.rules {
+		if rule.match(tc, output) {
+			return &v.rules[i]
+		}
+	}
+	return nil
+}
+
+// allKnown returns true if every test that failed in its last run matches a
+// rule that has not expired.
+func (v *flakeRulesValue) allKnown(exec *testjson.Execution) bool {
+	failures := failingTestCases(exec)
+	if !v.enabled() || len(failures) == 0 {
+		return false
+	}
+	now := flakeRulesNow()
+	for _, tc := range failures {
+		rule := v.forFailure(exec, tc)
+		if rule == nil || rule.expired(now) {
+			return false
+		}
+	}
+	return true
+}
+
+// retryKnownFlakes returns true when the failed tests should be re-run once,
+// because --rerun-fails is not set and every failure is a known flake.
+func retryKnownFlakes(opts *options, exec *testjson.Execution) bool {
+	switch {
+	case opts.rerunFailsMaxAttempts > 0 || !opts.flakeRules.enabled():
+		return false
+	case len(opts.args) > 0 && !opts.rawCommand && len(opts.packages) == 0:
+		return false
+	case boolArgIndex("failfast", opts.args) > -1:
+		return false
+	}
+	return opts.flakeRules.allKnown(exec)
+}
+
+// flakeMatch is a failed test that matched a rule in the --flake-rules file.
+type flakeMatch struct {
+	name string
+	rule *flakeRule
+}
+
+// flakeResult is the result of matching the failed tests to the rules.
+type flakeResult struct {
+	filename string
+	now      time.Time
+	// known are the failures that matched a rule that has not expired.
+	known []flakeMatch
+	// expired are the failures that matched a rule that expired.
+	expired []flakeMatch
+	// unusedExpired are the expired rules that did not match a failure.
+	unusedExpired []*flakeRule
+}
+
+func (v *flakeRulesValue) match(exec *testjson.Execution) *flakeResult {
+	if !v.enabled() || exec == nil {
+		return nil
+	}
+	result := &flakeResult{filename: v.filename, now: flakeRulesNow()}
+	used := make(map[*flakeRule]bool)
+	for _, tc := range failingTestCases(exec) {
+		rule := v.forFailure(exec, tc)
+		if rule == nil {
+			continue
+		}
+		used[rule] = true
+		m := flakeMatch{name: baselineName(tc), rule: rule}
+		if rule.expired(result.now) {
+			result.expired = append(result.expired, m)
+			continue
+		}
+		result.known = append(result.known, m)
+	}
+	for i := range v.rules {
+		if rule := &v.rules[i]; !used[rule] && rule.expired(result.now) {
+			result.unusedExpired = append(result.unusedExpired, rule)
+		}
+	}
+	return result
+}
+
+// isKnown returns true if the failed test matched a rule that has not
+// expired.
+func (r *flakeResult) isKnown(name string) bool {
+	if r == nil {
+		return false
+	}
+	for _, m := range r.known {
+		if m.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *flakeResult) print(out io.Writer) {
+	if r == nil {
+		return
+	}
+	for _, rule := range r.unusedExpired {
+		log.Warnf("flake rule %v:%d owned by %v expired on %v, remove or renew it",
+			r.filename, rule.line, rule.owner, rule.expires.Format("2006-01-02"))
+	}
+	if len(r.known) == 0 && len(r.expired) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Known flakes: %d suppressed, %d expired\n", len(r.known), len(r.expired))
+	for _, m := range r.expired {
+		fmt.Fprintf(out, "=== EXPIRED FLAKE: %s (owner %s, expired %s, %s:%d)\n",
+			m.name, m.rule.owner, m.rule.expires.Format("2006-01-02"), r.filename, m.rule.line)
+	}
+	for _, m := range r.known {
+		fmt.Fprintf(out, "=== KNOWN FLAKE: %s (owner %s, expires %s)\n",
+			m.name, m.rule.owner, m.rule.expires.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestFlakeRulesValue_Set(t *testing.T) {
+	type testCase struct {
+		name     string
+		content  string
+		expected string
+	}
+	run := func(t *testing.T, tc testCase) {
+		file := fs.NewFile(t, "flakes.txt", fs.WithContent(tc.content))
+		var v flakeRulesValue
+		err := v.Set(file.Path())
+		if tc.expected == "" {
+			assert.NilError(t, err)
+			return
+		}
+		assert.ErrorContains(t, err, tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name: "valid",
+			content: `# known flakes
+2024-06-30 @team-payments test=^TestCharge$
+
+2024-05-01	alice	output=connection reset by peer
+`,
+		},
+		{
+			name:     "invalid date",
+			content:  "06/30/2024 alice test=^TestCharge$",
+			expected: `:1: invalid expiry date "06/30/2024", must be YYYY-MM-DD`,
+		},
+		{
+			name:     "missing owner",
+			content:  "\n2024-06-30 test=^TestCharge$",
+			expected: ":2: expected an expiry date, an owner, and a rule",
+		},
+		{
+			name:     "unknown rule",
+			content:  "2024-06-30 alice file=api.go",
+			expected: `:1: invalid rule "file=api.go", must be package=, test=, or output=`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func patchFlakeRulesNow(t *testing.T, now time.Time) {
+	orig := flakeRulesNow
+	flakeRulesNow = func() time.Time { return now }
+	t.Cleanup(func() {
+		flakeRulesNow = orig
+	})
+}
+
+func flakeRulesForTest(t *testing.T) (flakeRulesValue, string) {
+	t.Helper()
+	file := fs.NewFile(t, "flakes.txt", fs.WithContent(`# known flakes
+2024-06-30 alice output=connection refused
+2024-06-30 bob test=^TestIntegration
+2024-05-01 carol package=example.com/other
+`))
+	var v flakeRulesValue
+	assert.NilError(t, v.Set(file.Path()))
+	return v, file.Path()
+}
+
+func TestFlakeRulesValue_Match(t *testing.T) {
+	patchFlakeRulesNow(t, time.Date(2024, 6, 30, 23, 0, 0, 0, time.Local))
+	v, filename := flakeRulesForTest(t)
+	exec := scanSeverityRun(t)
+
+	result := v.match(exec)
+	assert.Equal(t, len(result.known), 2)
+	assert.Equal(t, len(result.expired), 0)
+	assert.Equal(t, len(result.unusedExpired), 1)
+	assert.Equal(t, result.unusedExpired[0].owner, "carol")
+	assert.Assert(t, result.isKnown("example.com/api.TestClient"))
+	assert.Assert(t, result.isKnown("example.com/api.TestIntegrationDB"))
+	assert.Assert(t, !result.isKnown("example.com/payments.TestCharge"))
+	assert.Assert(t, !v.allKnown(exec))
+
+	out := new(bytes.Buffer)
+	result.print(out)
+	assert.Equal(t, out.String(), `
+=== Known flakes: 2 suppressed, 0 expired
+=== KNOWN FLAKE: example.com/api.TestClient (owner alice, expires 2024-06-30)
+=== KNOWN FLAKE: example.com/api.TestIntegrationDB (owner bob, expires 2024-06-30)
+`)
+
+	patchFlakeRulesNow(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local))
+	result = v.match(exec)
+	assert.Equal(t, len(result.known), 0)
+	assert.Equal(t, len(result.expired), 2)
+	assert.Assert(t, !result.isKnown("example.com/api.TestClient"))
+
+	out.Reset()
+	result.print(out)
+	assert.Equal(t, out.String(), `
+=== Known flakes: 0 suppressed, 2 expired
+=== EXPIRED FLAKE: example.com/api.TestClient (owner alice, expired 2024-06-30, `+filename+`:2)
+=== EXPIRED FLAKE: example.com/api.TestIntegrationDB (owner bob, expired 2024-06-30, `+filename+`:3)
+`)
+
+	var empty flakeRulesValue
+	assert.Assert(t, empty.match(exec) == nil)
+}
+
+func TestIgnoreKnownFailures_Flakes(t *testing.T) {
+	patchFlakeRulesNow(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local))
+	v, _ := flakeRulesForTest(t)
+	exec := scanBaselineRun(t)
+
+	result := v.match(exec)
+	exitErr := exitError{num: 1}
+	assert.Equal(t, ignoreKnownFailures(exec, exitErr, result.isKnown), exitErr)
+
+	file := fs.NewFile(t, "flakes.txt", fs.WithContent("2024-06-30 alice test=^TestKnown\n"))
+	assert.NilError(t, v.Set(file.Path()))
+	assert.Assert(t, v.allKnown(exec))
+	assert.NilError(t, ignoreKnownFailures(exec, exitErr, v.match(exec).isKnown))
+
+	patchFlakeRulesNow(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local))
+	assert.Assert(t, !v.allKnown(exec))
+	assert.Equal(t, ignoreKnownFailures(exec, exitErr, v.match(exec).isKnown), exitErr)
+}
+
+func TestRetryKnownFlakes(t *testing.T) {
+	patchFlakeRulesNow(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local))
+	file := fs.NewFile(t, "flakes.txt", fs.WithContent("2024-06-30 alice test=^TestKnown\n"))
+	exec := scanBaselineRun(t)
+
+	opts := &options{}
+	assert.Assert(t, !retryKnownFlakes(opts, exec))
+
+	assert.NilError(t, opts.flakeRules.Set(file.Path()))
+	assert.Assert(t, retryKnownFlakes(opts, exec))
+
+	opts.args = []string{"-failfast"}
+	opts.packages = []string{"./..."}
+	assert.Assert(t, !retryKnownFlakes(opts, exec))
+
+	opts.args = []string{"./pkg"}
+	opts.packages = nil
+	assert.Assert(t, !retryKnownFlakes(opts, exec))
+
+	opts.args = nil
+	opts.rerunFailsMaxAttempts = 2
+	assert.Assert(t, !retryKnownFlakes(opts, exec))
+}
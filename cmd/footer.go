@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+	"gotest.tools/gotestsum/internal/dotwriter"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// footerInterval is the time between updates of the running footer, when
+// nothing else is printed.
+const footerInterval = time.Second
+
+// runningFooter prints the tests that have been running for the longest time,
+// and the elapsed time of the run, in the last lines of the terminal. The
+// footer is removed before any other output is printed, and printed again
+// below the output, so that it stays at the bottom of the terminal.
+type runningFooter struct {
+	max     int
+	width   int
+	started time.Time
+
+	// mu is held while writing to stdout or stderr.
+	mu     sync.Mutex
+	writer *dotwriter.Writer
+	// exec is the Execution from the most recent event, which is used to
+	// find the tests that are running.
+	exec *testjson.Execution
+	// packageName formats the package of the running tests.
+	packageName func(pkgpath string) string
+	// partial is true when the last output did not end with a newline. The
+	// footer is not printed until the line is complete.
+	partial bool
+	closed  bool
+	// logOut is the writer used by the logger before the footer was created.
+	logOut io.Writer
+
+	stop chan struct{}
+	once sync.Once
+	done sync.WaitGroup
+}
+
+// newRunningFooter returns a runningFooter which prints to opts.stdout, and
+// replaces opts.stdout, opts.stderr, and the output of the logger, with
+// writers that keep the footer below the output. It must be called before
+// anything else captures opts.stdout. It returns nil when --running-footer is not set, when
+// stdout is not a terminal, or when the format updates the terminal itself.
+func newRunningFooter(opts *options) *runningFooter {
+	if opts.runningFooter <= 0 || opts.formatOptions.NoTerminal || !footerFormat(opts.format) {
+		return nil
+	}
+	f := &runningFooter{
+		max:         opts.runningFooter,
+		width:       footerWidth(opts),
+		started:     time.Now(),
+		writer:      dotwriter.New(opts.stdout),
+		packageName: opts.packageName,
+		stop:        make(chan struct{}),
+	}
+	opts.stdout = footerWriter{f: f, out: opts.stdout}
+	opts.stderr = footerWriter{f: f, out: opts.stderr}
+	f.logOut = log.Output()
+	log.SetOutput(footerWriter{f: f, out: f.logOut})
+	f.done.Add(1)
+	go f.loop()
+	return f
+}
+
+// footerFormat returns true when the footer can be printed below the output
+// of the format. The dots formats do not print complete lines, and the
+// formats of plugins are printed by another process.
+func footerFormat(format string) bool {
+	if strings.HasPrefix(format, formatPluginPrefix) {
+		return false
+	}
+	name, _, err := testjson.ParseFormat(format, testjson.FormatOptions{})
+	if err != nil {
+		return false
+	}
+	switch name {
+	case "dots", "dots-v1", "dots-v2":
+		return false
+	}
+	return true
+}
+
+func footerWidth(opts *options) int {
+	if opts.formatOptions.TerminalWidth > 0 {
+		return opts.formatOptions.TerminalWidth
+	}
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	return 80
+}
+
+type footerWriter struct {
+	f   *runningFooter
+	out io.Writer
+}
+
+func (w footerWriter) Write(p []byte) (int, error) {
+	w.f.mu.Lock()
+	defer w.f.mu.Unlock()
+	if w.f.closed {
+		return w.out.Write(p)
+	}
+	w.f.writer.Clear()
+	n, err := w.out.Write(p)
+	if len(p) > 0 {
+		w.f.partial = p[len(p)-1] != '\n'
+	}
+	w.f.draw(time.Now())
+	return n, err
+}
+
+// received is called by the EventHandler for every event. It is safe to call
+// on a nil runningFooter.
+func (f *runningFooter) received(_ testjson.TestEvent, exec *testjson.Execution) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.exec = exec
+}
+
+func (f *runningFooter) loop() {
+	defer f.done.Done()
+	ticker := time.NewTicker(footerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+		}
+		f.mu.Lock()
+		if !f.closed {
+			f.draw(time.Now())
+		}
+		f.mu.Unlock()
+	}
+}
+
+// draw prints the footer, replacing the previous footer. Must be called while
+// holding f.mu.
+func (f *runningFooter) draw(now time.Time) {
+	if f.partial {
+		return
+	}
+	_, _ = io.WriteString(f.writer, f.lines(now))
+	_ = f.writer.Flush()
+}
+
+// lines returns the lines of the footer. Must be called while holding f.mu.
+func (f *runningFooter) lines(now time.Time) string {
+	tests := longestRunningTests(f.exec)
+
+	var buf strings.Builder
+	header := fmt.Sprintf("=== RUNNING %v elapsed, %d tests running",
+		now.Sub(f.started).Round(time.Second), len(tests))
+	buf.WriteString(color.CyanString(truncateLine(header, f.width)) + "\n")
+	for i, tc := range tests {
+		if i == f.max {
+			break
+		}
+		line := "    " + joinPkgToTestName(f.packageName(tc.Package), tc.Test.Name())
+		if !tc.Time.IsZero() {
+			line += fmt.Sprintf(" (%v)", now.Sub(tc.Time).Round(time.Second))
+		}
+		buf.WriteString(truncateLine(line, f.width) + "\n")
+	}
+	return buf.String()
+}
+
+// longestRunningTests returns the running tests, in the order they started,
+// which is the longest running test first. A test that is waiting for a
+// running subtest is not included, because the subtest is the one that is
+// slow.
+func longestRunningTests(exec *testjson.Execution) []testjson.TestCase {
+	running := exec.Running()
+	parents := make(map[string]bool)
+	for _, tc := range running {
+		name := tc.Test.Name()
+		for i := strings.LastIndex(name, "/"); i > 0; i = strings.LastIndex(name, "/") {
+			name = name[:i]
+			parents[tc.Package+"."+name] = true
+		}
+	}
+	var result []testjson.TestCase
+	for _, tc := range running {
+		if !parents[tc.Package+"."+tc.Test.Name()] {
+			result = append(result, tc)
+		}
+	}
+	return result
+}
+
+// truncateLine returns line, cut to fit in one less than width columns, so
+// that it does not wrap in the terminal.
+func truncateLine(line string, width int) string {
+	runes := []rune(line)
+	if width < 2 || len(runes) < width {
+		return line
+	}
+	return string(runes[:width-2]) + "…"
+}
+
+// close removes the footer, and stops updating it. It is safe to call on a
+// nil runningFooter, and to call more than once.
+func (f *runningFooter) close() {
+	if f == nil {
+		return
+	}
+	f.once.Do(func() {
+		close(f.stop)
+	})
+	f.done.Wait()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.closed {
+		f.writer.Clear()
+		f.closed = true
+		if f.logOut != nil {
+			log.SetOutput(f.logOut)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"gotest.tools/gotestsum/internal/dotwriter"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/skip"
+)
+
+func TestRunningFooter_Lines(t *testing.T) {
+	orig := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = orig })
+
+	start := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	events := []string{
+		`{"Time":"2022-01-02T03:04:05Z","Action":"run","Package":"example.com/a","Test":"TestSlow"}`,
+		`{"Time":"2022-01-02T03:04:06Z","Action":"run","Package":"example.com/a","Test":"TestSlow/sub"}`,
+		`{"Time":"2022-01-02T03:04:10Z","Action":"run","Package":"example.com/b","Test":"TestOne"}`,
+		`{"Time":"2022-01-02T03:04:20Z","Action":"run","Package":"example.com/b","Test":"TestTwo"}`,
+		`{"Time":"2022-01-02T03:04:21Z","Action":"run","Package":"example.com/b","Test":"TestDone"}`,
+		`{"Time":"2022-01-02T03:04:22Z","Action":"pass","Package":"example.com/b","Test":"TestDone"}`,
+	}
+	f := &runningFooter{max: 2, width: 80, started: start, packageName: testjson.RelativePackagePath}
+	handler := &footerLinesHandler{f: f, at: len(events), now: start.Add(time.Minute)}
+	_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(strings.Join(events, "\n") + "\n"),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	expected := `=== RUNNING 1m0s elapsed, 3 tests running
+    example.com/a.TestSlow/sub (59s)
+    example.com/b.TestOne (55s)
+`
+	assert.Equal(t, handler.lines[0], expected)
+	expected = `=== RUNNING 1m0s elapsed, 3 …
+    example.com/a.TestSlow/s…
+    example.com/b.TestOne (5…
+`
+	assert.Equal(t, handler.lines[1], expected)
+}
+
+// footerLinesHandler sends the events to the footer, and saves the lines of
+// the footer after event number at, with a width of 80 and 30.
+type footerLinesHandler struct {
+	f     *runningFooter
+	at    int
+	now   time.Time
+	count int
+	lines []string
+}
+
+func (s *footerLinesHandler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	s.f.received(event, exec)
+	s.count++
+	if s.count == s.at {
+		s.lines = append(s.lines, s.f.lines(s.now))
+		s.f.width = 30
+		s.lines = append(s.lines, s.f.lines(s.now))
+	}
+	return nil
+}
+
+func (s *footerLinesHandler) Err(string) error {
+	return nil
+}
+
+func TestFooterWriter(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "clear sequence is different on windows")
+	orig := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = orig })
+
+	out := new(bytes.Buffer)
+	f := &runningFooter{max: 1, width: 80, started: time.Now(), writer: dotwriter.New(out),
+		packageName: testjson.RelativePackagePath, stop: make(chan struct{})}
+	w := footerWriter{f: f, out: out}
+
+	_, err := w.Write([]byte("PASS pkg.TestOne\n"))
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "PASS pkg.TestOne\n=== RUNNING 0s elapsed, 0 tests running\n")
+
+	out.Reset()
+	_, err = w.Write([]byte("partial"))
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "\x1b[1A\x1b[2Kpartial", "footer is not printed after a partial line")
+
+	out.Reset()
+	_, err = w.Write([]byte(" line\n"))
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), " line\n=== RUNNING 0s elapsed, 0 tests running\n")
+
+	out.Reset()
+	f.close()
+	assert.Equal(t, out.String(), "\x1b[1A\x1b[2K", "footer is removed")
+
+	out.Reset()
+	_, err = w.Write([]byte("=== Summary\n"))
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "=== Summary\n")
+}
+
+func TestNewRunningFooter_WrapsOtherWriters(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "clear sequence is different on windows")
+	orig := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = orig })
+
+	stdout := new(bytes.Buffer)
+	opts := &options{
+		format:            "testname",
+		runningFooter:     1,
+		heartbeatInterval: time.Hour,
+		stdout:            stdout,
+		stderr:            new(bytes.Buffer),
+		formatOptions:     testjson.FormatOptions{TerminalWidth: 80},
+	}
+	origLog := log.Output()
+	t.Cleanup(func() { log.SetOutput(origLog) })
+	logOut := new(bytes.Buffer)
+	log.SetOutput(logOut)
+
+	f := newRunningFooter(opts)
+	assert.Assert(t, f != nil)
+	h := newHeartbeat(opts)
+	_, ok := h.out.(footerWriter)
+	assert.Assert(t, ok, "heartbeat writes through the footer")
+
+	log.Warnf("a warning")
+	assert.Equal(t, logOut.String(), "WARN a warning\n")
+	assert.Equal(t, stdout.String(), "=== RUNNING 0s elapsed, 0 tests running\n",
+		"footer is printed again after the warning")
+
+	h.close()
+	f.close()
+	assert.Equal(t, log.Output(), io.Writer(logOut))
+}
+
+func TestFooterFormat(t *testing.T) {
+	assert.Assert(t, footerFormat("pkgname"))
+	assert.Assert(t, footerFormat("testname+align"))
+	assert.Assert(t, !footerFormat("dots"))
+	assert.Assert(t, !footerFormat("dots-v2"))
+	assert.Assert(t, !footerFormat("exec:./formatter"))
+	assert.Assert(t, !footerFormat("not-a-format"))
+}
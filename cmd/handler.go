@@ -6,9 +6,15 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"gotest.tools/gotestsum/internal/csvreport"
+	"gotest.tools/gotestsum/internal/cucumber"
+	"gotest.tools/gotestsum/internal/jsonsummary"
 	"gotest.tools/gotestsum/internal/junitxml"
 	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/nunitxml"
+	"gotest.tools/gotestsum/internal/sarif"
 	"gotest.tools/gotestsum/testjson"
 )
 
@@ -17,6 +23,15 @@ type eventHandler struct {
 	err       io.Writer
 	jsonFile  io.WriteCloser
 	maxFails  int
+	stuck     *stuckDetector
+	heartbeat *heartbeat
+	footer    *runningFooter
+	dashboard *dashboard
+	// attachments are added from the ::attach:: markers in test output.
+	attachments *attachments
+	plugins     []*pluginProcess
+	// pluginFormatter is the plugin used as the formatter. It may be nil.
+	pluginFormatter *pluginProcess
 }
 
 func (h *eventHandler) Err(text string) error {
@@ -26,6 +41,10 @@ func (h *eventHandler) Err(text string) error {
 }
 
 func (h *eventHandler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	h.stuck.received(event, execution)
+	h.heartbeat.received(event, execution)
+	h.footer.received(event, execution)
+
 	// ignore artificial events with no raw Bytes()
 	if h.jsonFile != nil && len(event.Bytes()) > 0 {
 		_, err := h.jsonFile.Write(append(event.Bytes(), '\n'))
@@ -38,6 +57,9 @@ func (h *eventHandler) Event(event testjson.TestEvent, execution *testjson.Execu
 	if err != nil {
 		return fmt.Errorf("failed to format event: %w", err)
 	}
+	sendToPlugins(h.plugins, event, h.pluginFormatter)
+	h.attachments.addFromOutput(event)
+	h.dashboard.update(event, execution)
 
 	if h.maxFails > 0 && len(execution.Failed()) >= h.maxFails {
 		return fmt.Errorf("ending test run because max failures was reached")
@@ -51,22 +73,51 @@ func (h *eventHandler) Close() error {
 			log.Errorf("Failed to close JSON file: %v", err)
 		}
 	}
+	// plugins are normally closed by finishRun, this closes them if the run
+	// ended early.
+	_ = closePlugins(h.plugins)
 	return nil
 }
 
 var _ testjson.EventHandler = &eventHandler{}
 
 func newEventHandler(opts *options) (*eventHandler, error) {
-	formatter := testjson.NewEventFormatter(opts.stdout, opts.format, opts.formatOptions)
-	if formatter == nil {
-		return nil, fmt.Errorf("unknown format %s", opts.format)
+	var formatter testjson.EventFormatter
+	if !strings.HasPrefix(opts.format, formatPluginPrefix) {
+		formatOpts := opts.formatOptions
+		formatOpts.Verbosity = opts.verbosity()
+		formatOpts.Truncate = testjson.Truncate(opts.formatTruncate)
+		if _, _, err := testjson.ParseFormat(opts.format, formatOpts); err != nil {
+			return nil, misuseError(err)
+		}
+		formatter = testjson.NewEventFormatter(opts.stdout, opts.format, formatOpts)
+		if formatter == nil {
+			return nil, misuseError(fmt.Errorf("unknown format %s", opts.format))
+		}
 	}
 	handler := &eventHandler{
 		formatter: formatter,
 		err:       opts.stderr,
 		maxFails:  opts.maxFails,
+		stuck:     opts.stuck,
+		heartbeat: opts.heartbeat,
+		footer:    opts.footer,
+		dashboard: opts.dashboard,
+	}
+	if opts.attachments == nil {
+		opts.attachments = newAttachments()
 	}
-	var err error
+	handler.attachments = opts.attachments
+	pluginFormatter, err := startPlugins(opts)
+	handler.plugins = opts.plugins
+	if err != nil {
+		return handler, err
+	}
+	if pluginFormatter != nil {
+		handler.formatter = pluginFormatter
+		handler.pluginFormatter = pluginFormatter
+	}
+	handler.formatter = newDisplayFilterFormatter(handler.formatter, &opts.displayFilter)
 	if opts.jsonFile != "" {
 		_ = os.MkdirAll(filepath.Dir(opts.jsonFile), 0o755)
 		handler.jsonFile, err = os.Create(opts.jsonFile)
@@ -92,14 +143,182 @@ func writeJUnitFile(opts *options, execution *testjson.Execution) error {
 		}
 	}()
 
-	return junitxml.Write(junitFile, execution, junitxml.Config{
+	cfg := junitConfig(opts)
+	cfg.TestCaseProperties = joinTestCaseProperties(
+		opts.severityRules.junitProperties(execution),
+		skipCategoryJUnitProperties(opts, execution))
+	return junitxml.Write(junitFile, execution, cfg)
+}
+
+// joinTestCaseProperties returns a function that returns the properties from
+// every non-nil function in funcs.
+func joinTestCaseProperties(funcs ...func(tc testjson.TestCase) []junitxml.JUnitProperty) func(tc testjson.TestCase) []junitxml.JUnitProperty {
+	return func(tc testjson.TestCase) []junitxml.JUnitProperty {
+		var props []junitxml.JUnitProperty
+		for _, f := range funcs {
+			if f != nil {
+				props = append(props, f(tc)...)
+			}
+		}
+		return props
+	}
+}
+
+func junitConfig(opts *options) junitxml.Config {
+	cfg := junitxml.Config{
 		ProjectName:             opts.junitProjectName,
 		FormatTestSuiteName:     opts.junitTestSuiteNameFormat.Value(),
 		FormatTestCaseClassname: opts.junitTestCaseClassnameFormat.Value(),
 		HideEmptyPackages:       opts.junitHideEmptyPackages,
+		HideExamples:            opts.hideExamples,
+		Sort:                    opts.junitSort.value,
+		Schema:                  opts.junitSchema.value,
+		Validate:                opts.junitValidate,
+		PackageProperties:       junitPackageProperties(opts),
+		GoVersion:               goEnv(opts).Version,
+		Attachments:             opts.attachments.testCaseAttachments(),
+		DisambiguateNames:       opts.junitDisambiguateNames,
+		Properties:              opts.labels.junitProperties(),
+		Timestamp:               opts.reportTimestamp,
+		Deterministic:           opts.deterministic,
+		RequirementProperties:   opts.requirementProperties.mapping,
+		RequirementURLTemplate:  opts.requirementURLTemplate,
+	}
+	applyPolarionConfig(opts, &cfg)
+	return cfg
+}
+
+// checkJUnitNames warns about test cases in the junit.xml file that have the
+// same classname and name as a different test, because most tools that read
+// the file report them as the same test. With --strict-names the collisions
+// are an error.
+func checkJUnitNames(opts *options, execution *testjson.Execution) error {
+	if (opts.junitFile == "" && !opts.strictNames) || opts.junitDisambiguateNames {
+		return nil
+	}
+	collisions := junitxml.Collisions(execution, junitConfig(opts))
+	for _, c := range collisions {
+		tests := make([]string, 0, len(c.Tests))
+		for _, tc := range c.Tests {
+			tests = append(tests, opts.packageName(tc.Package)+"."+tc.Test)
+		}
+		log.Warnf("JUnit testcase %v %v is used by more than one test: %v",
+			c.Classname, c.Name, strings.Join(tests, ", "))
+	}
+	if opts.strictNames && len(collisions) > 0 {
+		return fmt.Errorf("%d JUnit testcase names are used by more than one test, "+
+			"use --junitfile-disambiguate-names to make them unique", len(collisions))
+	}
+	return nil
+}
+
+// junitPackageProperties returns the properties added to the testsuite of
+// every package, in addition to the properties added by junitxml.
+func junitPackageProperties(opts *options) func(pkgname string) []junitxml.JUnitProperty {
+	env := append(environmentProperties(opts), vcs(opts).junitProperties()...)
+	return func(pkgname string) []junitxml.JUnitProperty {
+		props := append([]junitxml.JUnitProperty{}, env...)
+		props = append(props, opts.modules.junitProperties(pkgname)...)
+		props = append(props, opts.projects.junitProperties(pkgname)...)
+		props = append(props, opts.envGroups.junitProperties(pkgname)...)
+		return append(props, opts.resultCache.junitProperties(pkgname)...)
+	}
+}
+
+func writeJSONSummary(opts *options, execution *testjson.Execution) error {
+	if opts.summaryJSONFile == "" {
+		return nil
+	}
+	_ = os.MkdirAll(filepath.Dir(opts.summaryJSONFile), 0o755)
+	fh, err := os.Create(opts.summaryJSONFile)
+	if err != nil {
+		return fmt.Errorf("failed to open JSON summary file: %v", err)
+	}
+	defer func() {
+		if err := fh.Close(); err != nil {
+			log.Errorf("Failed to close JSON summary file: %v", err)
+		}
+	}()
+
+	return jsonsummary.Write(fh, execution, jsonSummaryConfig(opts))
+}
+
+func jsonSummaryConfig(opts *options) jsonsummary.Config {
+	return jsonsummary.Config{
+		Profiles:      opts.profiles,
+		ResourceUsage: opts.resourceUsage,
+		Environment:   runEnvironment(opts),
+		Attachments:   opts.attachments.testCaseAttachments(),
+		HideExamples:  opts.hideExamples,
+		Module:        opts.modules.modulePath(),
+		VCS:           vcs(opts).summary(),
+		Labels:        opts.labels.value(),
+		SkipCategory:  opts.skipCategories.categorize,
+		Reruns:        opts.reruns,
+		Setup:         opts.dependencies.jsonSummary(),
+	}
+}
+
+func writeCSVFile(opts *options, execution *testjson.Execution) error {
+	if opts.csvFile == "" {
+		return nil
+	}
+	return writeReportFile(opts.csvFile, func(out io.Writer) error {
+		return csvreport.Write(out, execution)
+	})
+}
+
+func writeSARIFFile(opts *options, execution *testjson.Execution) error {
+	if opts.sarifFile == "" {
+		return nil
+	}
+	return writeReportFile(opts.sarifFile, func(out io.Writer) error {
+		return sarif.Write(out, execution, sarif.Config{
+			Version:     version,
+			PackageName: opts.formatOptions.PackageName,
+		})
+	})
+}
+
+func writeCucumberFile(opts *options, execution *testjson.Execution) error {
+	if opts.cucumberFile == "" {
+		return nil
+	}
+	return writeReportFile(opts.cucumberFile, func(out io.Writer) error {
+		return cucumber.Write(out, execution)
+	})
+}
+
+func writeNUnitFile(opts *options, execution *testjson.Execution) error {
+	if opts.nunitFile == "" {
+		return nil
+	}
+	return writeReportFile(opts.nunitFile, func(out io.Writer) error {
+		return nunitxml.Write(out, execution, nunitxml.Config{
+			Version:               version,
+			PackageProperties:     junitPackageProperties(opts),
+			Attachments:           opts.attachments.testCaseAttachments(),
+			RequirementProperties: opts.requirementProperties.mapping,
+		})
 	})
 }
 
+// writeReportFile creates the file at path, and its parent directory, and
+// calls write to write the report to the file.
+func writeReportFile(path string, write func(io.Writer) error) error {
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	fh, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to open report file: %v", err)
+	}
+	defer func() {
+		if err := fh.Close(); err != nil {
+			log.Errorf("Failed to close report file %v: %v", path, err)
+		}
+	}()
+	return write(fh)
+}
+
 func postRunHook(opts *options, execution *testjson.Execution) error {
 	command := opts.postRunHookCmd.Value()
 	if len(command) == 0 {
@@ -118,6 +337,8 @@ func postRunHook(opts *options, execution *testjson.Execution) error {
 		fmt.Sprintf("TESTS_SKIPPED=%d", len(execution.Skipped())),
 		fmt.Sprintf("TESTS_ERRORS=%d", len(execution.Errors())),
 	)
+	cmd.Env = append(cmd.Env, vcs(opts).environ()...)
+	cmd.Env = append(cmd.Env, opts.labels.environ()...)
 	// TODO: send a more detailed report to stdin?
 	return cmd.Run()
 }
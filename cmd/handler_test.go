@@ -29,6 +29,7 @@ func TestPostRunHook(t *testing.T) {
 	}
 
 	env.Patch(t, "GOTESTSUM_FORMAT", "short")
+	patchVCS(t, &vcsInfo{Commit: "abc123", Branch: "main"})
 
 	exec := newExecFromTestData(t)
 	err = postRunHook(opts, exec)
@@ -104,6 +105,16 @@ func TestNewEventHandler_CreatesDirectory(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func TestNewEventHandler_FormatModifiers(t *testing.T) {
+	opts := &options{stdout: new(bytes.Buffer), format: "testname+tree+timestamps"}
+	_, err := newEventHandler(opts)
+	assert.NilError(t, err)
+
+	opts.format = "dots+tree"
+	_, err = newEventHandler(opts)
+	assert.Error(t, err, `format modifier "tree" can not be used with the dots format`)
+}
+
 func TestWriteJunitFile_CreatesDirectory(t *testing.T) {
 	dir := fs.NewDir(t, t.Name())
 	junitFile := filepath.Join(dir.Path(), "new-path", "junit.xml")
@@ -120,3 +131,41 @@ func TestWriteJunitFile_CreatesDirectory(t *testing.T) {
 	_, err = os.Stat(junitFile)
 	assert.NilError(t, err)
 }
+
+func TestCheckJUnitNames(t *testing.T) {
+	events := []string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"pass","Package":"example.com/a"}`,
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(strings.Join(events, "\n")),
+	})
+	assert.NilError(t, err)
+
+	newOpts := func() *options {
+		return &options{
+			junitFile:                    "junit.xml",
+			junitTestCaseClassnameFormat: &junitFieldFormatValue{},
+			junitTestSuiteNameFormat:     &junitFieldFormatValue{},
+		}
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		assert.NilError(t, checkJUnitNames(newOpts(), exec))
+	})
+	t.Run("strict", func(t *testing.T) {
+		opts := newOpts()
+		opts.strictNames = true
+		err := checkJUnitNames(opts, exec)
+		assert.ErrorContains(t, err, "1 JUnit testcase names are used by more than one test")
+	})
+	t.Run("strict with disambiguated names", func(t *testing.T) {
+		opts := newOpts()
+		opts.strictNames = true
+		opts.junitDisambiguateNames = true
+		assert.NilError(t, checkJUnitNames(opts, exec))
+	})
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// heartbeatMaxTests is the maximum number of running tests printed by a
+// heartbeat line.
+const heartbeatMaxTests = 5
+
+// heartbeat prints a progress line when nothing has been written to stdout
+// for longer than interval, so that CI systems which end jobs that have not
+// produced output for some time do not end a test run that is still making
+// progress.
+type heartbeat struct {
+	interval time.Duration
+	out      io.Writer
+	started  time.Time
+
+	// mu is held while writing to out.
+	mu         sync.Mutex
+	lastOutput time.Time
+	// packages is the set of packages that have received an event. The value
+	// is true when the package is done.
+	packages map[string]bool
+	// exec is the Execution from the most recent event, which is used to
+	// find the tests that are running.
+	exec *testjson.Execution
+	// packageName formats the package of the running tests.
+	packageName func(pkgpath string) string
+
+	stop chan struct{}
+	once sync.Once
+	done sync.WaitGroup
+}
+
+// newHeartbeat returns a heartbeat which prints to opts.stdout, and replaces
+// opts.stdout with a writer that records the time of each write. It returns
+// nil when --heartbeat is not set.
+func newHeartbeat(opts *options) *heartbeat {
+	if opts.heartbeatInterval <= 0 {
+		return nil
+	}
+	now := time.Now()
+	h := &heartbeat{
+		interval:    opts.heartbeatInterval,
+		out:         opts.stdout,
+		started:     now,
+		lastOutput:  now,
+		packages:    make(map[string]bool),
+		packageName: opts.packageName,
+		stop:        make(chan struct{}),
+	}
+	opts.stdout = heartbeatWriter{h: h}
+	h.done.Add(1)
+	go h.loop()
+	return h
+}
+
+type heartbeatWriter struct {
+	h *heartbeat
+}
+
+func (w heartbeatWriter) Write(p []byte) (int, error) {
+	w.h.mu.Lock()
+	defer w.h.mu.Unlock()
+	w.h.lastOutput = time.Now()
+	return w.h.out.Write(p)
+}
+
+// received is called by the EventHandler for every event. It is safe to call
+// on a nil heartbeat.
+func (h *heartbeat) received(event testjson.TestEvent, exec *testjson.Execution) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.exec = exec
+	if event.PackageEvent() {
+		h.packages[event.Package] = h.packages[event.Package] || event.Action.IsTerminal()
+		return
+	}
+	if _, ok := h.packages[event.Package]; !ok {
+		h.packages[event.Package] = false
+	}
+}
+
+func (h *heartbeat) loop() {
+	defer h.done.Done()
+	ticker := time.NewTicker(stuckCheckInterval(h.interval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+		h.mu.Lock()
+		now := time.Now()
+		if now.Sub(h.lastOutput) >= h.interval {
+			_, _ = io.WriteString(h.out, h.line(now))
+			h.lastOutput = now
+		}
+		h.mu.Unlock()
+	}
+}
+
+// line returns the progress line. Must be called while holding h.mu.
+func (h *heartbeat) line(now time.Time) string {
+	var done int
+	for _, pkgDone := range h.packages {
+		if pkgDone {
+			done++
+		}
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "=== HEARTBEAT %v elapsed, %d/%d packages done",
+		now.Sub(h.started).Round(time.Second), done, len(h.packages))
+	if running := formatRunningTests(h.exec, now, h.packageName); running != "" {
+		buf.WriteString(", running: " + running)
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// formatRunningTests returns a list of the top-level tests that are running,
+// with the time since each test started, or an empty string if no tests are
+// running. At most heartbeatMaxTests tests are included in the list.
+func formatRunningTests(exec *testjson.Execution, now time.Time, packageName func(string) string) string {
+	var tests []testjson.TestCase
+	for _, tc := range exec.Running() {
+		if !tc.Test.IsSubTest() {
+			tests = append(tests, tc)
+		}
+	}
+
+	var buf strings.Builder
+	for i, tc := range tests {
+		if i == heartbeatMaxTests {
+			fmt.Fprintf(&buf, ", and %d more", len(tests)-heartbeatMaxTests)
+			break
+		}
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(joinPkgToTestName(packageName(tc.Package), tc.Test.Name()))
+		if !tc.Time.IsZero() {
+			fmt.Fprintf(&buf, " (%v)", now.Sub(tc.Time).Round(time.Second))
+		}
+	}
+	return buf.String()
+}
+
+// close stops the heartbeat. It is safe to call on a nil heartbeat, and to
+// call more than once.
+func (h *heartbeat) close() {
+	if h == nil {
+		return
+	}
+	h.once.Do(func() {
+		close(h.stop)
+	})
+	h.done.Wait()
+}
+
+func joinPkgToTestName(pkg string, test string) string {
+	if pkg == "." {
+		return test
+	}
+	return pkg + "." + test
+}
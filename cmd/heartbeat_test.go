@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/poll"
+)
+
+func TestHeartbeat_Line(t *testing.T) {
+	start := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := &heartbeat{started: start, packages: make(map[string]bool), packageName: testjson.RelativePackagePath}
+	events := []string{
+		`{"Action":"start","Package":"example.com/a"}`,
+		`{"Time":"2022-01-02T03:04:05Z","Action":"run","Package":"example.com/a","Test":"TestSlow"}`,
+		`{"Time":"2022-01-02T03:04:05Z","Action":"run","Package":"example.com/a","Test":"TestSlow/sub"}`,
+		`{"Time":"2022-01-02T03:04:05Z","Action":"run","Package":"example.com/a","Test":"TestDone"}`,
+		`{"Time":"2022-01-02T03:04:05Z","Action":"pass","Package":"example.com/a","Test":"TestDone"}`,
+		`{"Time":"2022-01-02T03:05:05Z","Action":"run","Package":"example.com/b","Test":"TestOne"}`,
+		`{"Time":"2022-01-02T03:05:05Z","Action":"pass","Package":"example.com/b","Test":"TestOne"}`,
+		`{"Action":"pass","Package":"example.com/b"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestSlow/sub"}`,
+		`{"Action":"fail","Package":"example.com/a","Test":"TestSlow"}`,
+	}
+	lines := scanHeartbeatLines(t, h, events, map[int]time.Time{
+		8:  start.Add(2 * time.Minute),
+		10: start.Add(3 * time.Minute),
+	})
+	expected := []string{
+		"=== HEARTBEAT 2m0s elapsed, 1/2 packages done, running: example.com/a.TestSlow (2m0s)\n",
+		"=== HEARTBEAT 3m0s elapsed, 1/2 packages done\n",
+	}
+	assert.DeepEqual(t, lines, expected)
+}
+
+func TestHeartbeat_Line_MaxTests(t *testing.T) {
+	start := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := &heartbeat{started: start, packages: make(map[string]bool), packageName: testjson.RelativePackagePath}
+	var events []string
+	for _, name := range []string{"TestA", "TestB", "TestC", "TestD", "TestE", "TestF", "TestG"} {
+		events = append(events, `{"Action":"run","Package":"example.com/a","Test":"`+name+`"}`)
+	}
+	lines := scanHeartbeatLines(t, h, events, map[int]time.Time{7: start.Add(time.Second)})
+	expected := "=== HEARTBEAT 1s elapsed, 0/1 packages done, running: " +
+		"example.com/a.TestA, example.com/a.TestB, example.com/a.TestC, example.com/a.TestD, " +
+		"example.com/a.TestE, and 2 more\n"
+	assert.DeepEqual(t, lines, []string{expected})
+}
+
+// scanHeartbeatLines sends the events to the heartbeat, and returns the
+// heartbeat line after each event number in at, using the time from at.
+func scanHeartbeatLines(t *testing.T, h *heartbeat, events []string, at map[int]time.Time) []string {
+	t.Helper()
+	handler := &heartbeatLineHandler{h: h, at: at}
+	_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(strings.Join(events, "\n") + "\n"),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	return handler.lines
+}
+
+type heartbeatLineHandler struct {
+	h     *heartbeat
+	at    map[int]time.Time
+	count int
+	lines []string
+}
+
+func (s *heartbeatLineHandler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	s.h.received(event, exec)
+	s.count++
+	if now, ok := s.at[s.count]; ok {
+		s.lines = append(s.lines, s.h.line(now))
+	}
+	return nil
+}
+
+func (s *heartbeatLineHandler) Err(string) error {
+	return nil
+}
+
+func TestHeartbeat_PrintsWhenThereIsNoOutput(t *testing.T) {
+	out := &syncBuffer{}
+	opts := &options{heartbeatInterval: 50 * time.Millisecond, stdout: out}
+	h := newHeartbeat(opts)
+	defer h.close()
+
+	_, err := io.WriteString(opts.stdout, "first\n")
+	assert.NilError(t, err)
+
+	poll.WaitOn(t, func(t poll.LogT) poll.Result {
+		if strings.Contains(out.String(), "=== HEARTBEAT") {
+			return poll.Success()
+		}
+		return poll.Continue("no heartbeat yet: %q", out.String())
+	}, poll.WithTimeout(5*time.Second), poll.WithDelay(10*time.Millisecond))
+	h.close()
+	assert.Assert(t, cmp.Contains(out.String(), "first\n=== HEARTBEAT"))
+}
+
+func TestHeartbeat_NilIsDisabled(t *testing.T) {
+	opts := &options{stdout: new(bytes.Buffer)}
+	h := newHeartbeat(opts)
+	assert.Assert(t, h == nil)
+	h.received(testjson.TestEvent{Action: testjson.ActionRun}, nil)
+	h.close()
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
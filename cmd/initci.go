@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/pflag"
+)
+
+// RunInit writes a config file and a CI pipeline that runs gotestsum.
+func RunInit(name string, args []string) error {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	var ci string
+	var force bool
+	flags.StringVar(&ci, "ci", "", "CI system of the pipeline, one of: "+strings.Join(ciSystemNames(), ", "))
+	flags.BoolVar(&force, "force", false, "overwrite files that already exist")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stdout, `Usage:
+    %[1]s --ci SYSTEM [flags]
+
+Write a %[2]s config file with recommended flags, and a pipeline for the CI
+system that runs gotestsum, stores the JUnit XML file, and uploads the test
+results as artifacts.
+
+Flags:
+`, name, configFilename)
+		flags.SetOutput(os.Stdout)
+		flags.PrintDefaults()
+	}
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		flags.Usage()
+		return err
+	}
+	system, ok := ciSystems[ci]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("--ci must be one of: %v", strings.Join(ciSystemNames(), ", "))
+	}
+	return writeInitFiles(os.Stdout, system, force)
+}
+
+// ciSystem is the pipeline written by init for a CI system.
+type ciSystem struct {
+	// filename of the pipeline, relative to the root of the repository.
+	filename string
+	pipeline string
+}
+
+var ciSystems = map[string]ciSystem{
+	"github":   {filename: ".github/workflows/gotestsum.yml", pipeline: githubPipeline},
+	"gitlab":   {filename: ".gitlab-ci.yml", pipeline: gitlabPipeline},
+	"circleci": {filename: ".circleci/config.yml", pipeline: circleciPipeline},
+}
+
+func ciSystemNames() []string {
+	return []string{"github", "gitlab", "circleci"}
+}
+
+// initResultsDir is the directory used for the JUnit XML and JSON files in
+// the config and pipelines written by init.
+const initResultsDir = "test-results"
+
+const initConfig = `# Default flags for gotestsum. Flags on the command line override these values.
+# See https://github.com/gotestyourself/gotestsum#documentation
+format: pkgname-and-test-fails
+packages: ./...
+junitfile: test-results/junit.xml
+jsonfile: test-results/test-output.json
+# Run failed tests again, and fail the run if they fail every time, or if the
+# first run has too many failures to be flaky.
+rerun-fails: 2
+rerun-fails-max-failures: 10
+rerun-fails-report: test-results/rerun-fails.txt
+`
+
+const githubPipeline = `name: Test
+on:
+  push:
+  pull_request:
+
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@v5
+        with:
+          go-version-file: go.mod
+      - run: go install gotest.tools/gotestsum@latest
+      - run: gotestsum
+      - uses: actions/upload-artifact@v4
+        if: always()
+        with:
+          name: test-results
+          path: test-results/
+`
+
+const gitlabPipeline = `test:
+  image: golang:latest
+  script:
+    - go install gotest.tools/gotestsum@latest
+    - gotestsum
+  artifacts:
+    when: always
+    paths:
+      - test-results/
+    reports:
+      junit: test-results/junit.xml
+`
+
+const circleciPipeline = `version: 2.1
+
+jobs:
+  test:
+    docker:
+      - image: cimg/go:1.22
+    steps:
+      - checkout
+      - run: go install gotest.tools/gotestsum@latest
+      - run: gotestsum
+      - store_test_results:
+          path: test-results
+      - store_artifacts:
+          path: test-results
+
+workflows:
+  test:
+    jobs:
+      - test
+`
+
+// writeInitFiles writes the config file and the pipeline. A file that already
+// exists is only replaced when force is true, otherwise the content that would
+// have been written is printed to out so that it can be merged by hand.
+func writeInitFiles(out io.Writer, system ciSystem, force bool) error {
+	files := []struct {
+		filename string
+		content  string
+	}{
+		{filename: configFilename, content: initConfig},
+		{filename: filepath.FromSlash(system.filename), content: system.pipeline},
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file.filename); err == nil && !force {
+			fmt.Fprintf(out, "%v already exists, add the following to it, or use --force to replace it:\n\n%v\n",
+				file.filename, file.content)
+			continue
+		}
+		if dir := filepath.Dir(file.filename); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+		if err := ioutil.WriteFile(file.filename, []byte(file.content), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Wrote %v\n", file.filename)
+	}
+	fmt.Fprintf(out, "Add %v/ to .gitignore to ignore the test results.\n", initResultsDir)
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestWriteInitFiles(t *testing.T) {
+	for _, name := range ciSystemNames() {
+		t.Run(name, func(t *testing.T) {
+			dir := fs.NewDir(t, "init")
+			defer env.ChangeWorkingDir(t, dir.Path())()
+
+			system := ciSystems[name]
+			out := new(bytes.Buffer)
+			assert.NilError(t, writeInitFiles(out, system, false))
+			assert.Assert(t, cmp.Contains(out.String(), "Wrote "+configFilename))
+
+			raw, err := ioutil.ReadFile(filepath.FromSlash(system.filename))
+			assert.NilError(t, err)
+			assert.Equal(t, string(raw), system.pipeline)
+
+			flags, opts := setupFlags("gotestsum")
+			assert.NilError(t, flags.Parse(nil))
+			assert.NilError(t, loadConfigFile(flags, configFilename))
+			assert.NilError(t, opts.Validate())
+		})
+	}
+}
+
+func TestWriteInitFiles_FileExists(t *testing.T) {
+	dir := fs.NewDir(t, "init", fs.WithFile(".gitlab-ci.yml", "stages: [build]\n"))
+	defer env.ChangeWorkingDir(t, dir.Path())()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, writeInitFiles(out, ciSystems["gitlab"], false))
+	assert.Assert(t, cmp.Contains(out.String(), ".gitlab-ci.yml already exists"))
+	assert.Assert(t, cmp.Contains(out.String(), gitlabPipeline))
+
+	raw, err := ioutil.ReadFile(".gitlab-ci.yml")
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), "stages: [build]\n")
+
+	assert.NilError(t, writeInitFiles(new(bytes.Buffer), ciSystems["gitlab"], true))
+	raw, err = ioutil.ReadFile(".gitlab-ci.yml")
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), gitlabPipeline)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+)
+
+// label is a key=value pair attached to the test run with --label.
+type label struct {
+	key   string
+	value string
+}
+
+// labelsValue is a flag.Value for the labels of the test run. The labels are
+// kept in the order of the flags. A label with the same key as an earlier
+// label replaces its value.
+type labelsValue struct {
+	labels []label
+}
+
+func (v *labelsValue) Set(raw string) error {
+	i := strings.Index(raw, "=")
+	if i < 1 {
+		return fmt.Errorf("label %q must be in the form key=value", raw)
+	}
+	key, value := raw[:i], raw[i+1:]
+	for i, l := range v.labels {
+		if l.key == key {
+			v.labels[i].value = value
+			return nil
+		}
+	}
+	v.labels = append(v.labels, label{key: key, value: value})
+	return nil
+}
+
+func (v *labelsValue) String() string {
+	parts := make([]string, 0, len(v.labels))
+	for _, l := range v.labels {
+		parts = append(parts, l.key+"="+l.value)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (v *labelsValue) Type() string {
+	return "key=value"
+}
+
+// value returns the labels indexed by key, or nil when there are no labels.
+func (v *labelsValue) value() map[string]string {
+	if len(v.labels) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(v.labels))
+	for _, l := range v.labels {
+		result[l.key] = l.value
+	}
+	return result
+}
+
+// junitProperties returns the labels as properties of the testsuites element.
+func (v *labelsValue) junitProperties() []junitxml.JUnitProperty {
+	props := make([]junitxml.JUnitProperty, 0, len(v.labels))
+	for _, l := range v.labels {
+		props = append(props, junitxml.JUnitProperty{Name: "label." + l.key, Value: l.value})
+	}
+	return props
+}
+
+// environ returns the labels as an environment variable for publishers and
+// the --post-run-command.
+func (v *labelsValue) environ() []string {
+	if len(v.labels) == 0 {
+		return nil
+	}
+	return []string{"GOTESTSUM_LABELS=" + v.String()}
+}
+
+// printHeader prints the labels before the summary of the run.
+func (v *labelsValue) printHeader(out io.Writer) {
+	if len(v.labels) == 0 {
+		return
+	}
+	parts := make([]string, 0, len(v.labels))
+	for _, l := range v.labels {
+		parts = append(parts, l.key+"="+l.value)
+	}
+	fmt.Fprintf(out, "\n=== Labels: %s\n", strings.Join(parts, ", "))
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/v3/assert"
+)
+
+func TestLabelsValue(t *testing.T) {
+	var v labelsValue
+	assert.NilError(t, v.Set("env=staging"))
+	assert.NilError(t, v.Set("cluster=us-east=1"))
+	assert.NilError(t, v.Set("dataset="))
+	assert.NilError(t, v.Set("env=prod"))
+	assert.Equal(t, v.String(), "env=prod,cluster=us-east=1,dataset=")
+	assert.DeepEqual(t, v.value(), map[string]string{
+		"env": "prod", "cluster": "us-east=1", "dataset": "",
+	})
+	assert.DeepEqual(t, v.junitProperties(), []junitxml.JUnitProperty{
+		{Name: "label.env", Value: "prod"},
+		{Name: "label.cluster", Value: "us-east=1"},
+		{Name: "label.dataset", Value: ""},
+	})
+	assert.DeepEqual(t, v.environ(), []string{"GOTESTSUM_LABELS=env=prod,cluster=us-east=1,dataset="})
+
+	out := new(bytes.Buffer)
+	v.printHeader(out)
+	assert.Equal(t, out.String(), "\n=== Labels: env=prod, cluster=us-east=1, dataset=\n")
+
+	assert.ErrorContains(t, v.Set("staging"), `label "staging" must be in the form key=value`)
+	assert.ErrorContains(t, v.Set("=staging"), `label "=staging" must be in the form key=value`)
+}
+
+func TestLabelsValue_Empty(t *testing.T) {
+	var v labelsValue
+	assert.Assert(t, v.value() == nil)
+	assert.Equal(t, len(v.junitProperties()), 0)
+	assert.Assert(t, v.environ() == nil)
+
+	out := new(bytes.Buffer)
+	v.printHeader(out)
+	assert.Equal(t, out.String(), "")
+}
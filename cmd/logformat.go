@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"gotest.tools/gotestsum/internal/structlog"
+)
+
+// summaryFormatLine returns the function that formats the structured log lines
+// in the output of failed tests in the summary, or nil when --log-format is
+// raw and --min-captured-log-level is not set. The other reports are written
+// with the output unchanged.
+func summaryFormatLine(opts *options) func(line string) string {
+	format := structlog.FormatLine
+	if opts.logFormat == "raw" {
+		format = nil
+	}
+	if opts.minCapturedLogLevel == "" {
+		return format
+	}
+
+	minRank := structlog.Rank(opts.minCapturedLogLevel)
+	return func(line string) string {
+		// lines without a known level, including lines that are not
+		// structured logs, are always printed.
+		if r, ok := structlog.Parse(line); ok && structlog.IsLevel(r.Level) && structlog.Rank(r.Level) < minRank {
+			return ""
+		}
+		if format == nil {
+			return line
+		}
+		return format(line)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"gotest.tools/v3/assert"
+)
+
+func TestSummaryFormatLine(t *testing.T) {
+	orig := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = orig })
+
+	debug := `    a_test.go:1: {"level":"DEBUG","msg":"connecting"}` + "\n"
+	warn := `    a_test.go:2: {"level":"WARN","msg":"retrying","n":2}` + "\n"
+	plain := "    a_test.go:3: plain text\n"
+
+	type testCase struct {
+		name     string
+		opts     *options
+		expected []string
+	}
+	run := func(t *testing.T, tc testCase) {
+		format := summaryFormatLine(tc.opts)
+		var actual []string
+		for _, line := range []string{debug, warn, plain} {
+			if format != nil {
+				line = format(line)
+			}
+			actual = append(actual, line)
+		}
+		assert.DeepEqual(t, actual, tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name: "pretty",
+			opts: &options{logFormat: "pretty"},
+			expected: []string{
+				"    a_test.go:1: DEBUG connecting\n",
+				"    a_test.go:2: WARN retrying n=2\n",
+				plain,
+			},
+		},
+		{
+			name:     "raw",
+			opts:     &options{logFormat: "raw"},
+			expected: []string{debug, warn, plain},
+		},
+		{
+			name:     "pretty with min level",
+			opts:     &options{logFormat: "pretty", minCapturedLogLevel: "warn"},
+			expected: []string{"", "    a_test.go:2: WARN retrying n=2\n", plain},
+		},
+		{
+			name:     "raw with min level",
+			opts:     &options{logFormat: "raw", minCapturedLogLevel: "info"},
+			expected: []string{"", warn, plain},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestSummaryFormatLine_OtherLevels(t *testing.T) {
+	format := summaryFormatLine(&options{logFormat: "raw", minCapturedLogLevel: "warn"})
+
+	numericInfo := `    a_test.go:1: {"level":30,"msg":"connected"}` + "\n"
+	assert.Equal(t, format(numericInfo), "")
+	numericError := `    a_test.go:2: {"level":50,"msg":"failed"}` + "\n"
+	assert.Equal(t, format(numericError), numericError)
+	custom := `    a_test.go:3: {"level":"notice","msg":"maintenance"}` + "\n"
+	assert.Equal(t, format(custom), custom)
+}
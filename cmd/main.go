@@ -11,25 +11,43 @@ import (
 	"strings"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/dnephin/pflag"
 	"github.com/fatih/color"
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/junitxml"
 	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/structlog"
 	"gotest.tools/gotestsum/testjson"
 )
 
 var version = "dev"
 
 func Run(name string, args []string) error {
+	return runWithFlags(name, args, nil)
+}
+
+// runWithFlags parses args and runs the command. addFlags may add flags
+// which are specific to a command. It may be nil.
+func runWithFlags(name string, args []string, addFlags func(*pflag.FlagSet, *options)) error {
 	flags, opts := setupFlags(name)
+	if addFlags != nil {
+		addFlags(flags, opts)
+	}
 	switch err := flags.Parse(args); {
 	case err == pflag.ErrHelp:
 		return nil
 	case err != nil:
 		usage(os.Stderr, name, flags)
-		return err
+		return opts.exitCodes.resolve(misuseError(err))
+	}
+	if err := loadConfigFile(flags, configFilename); err != nil {
+		return opts.exitCodes.resolve(misuseError(err))
 	}
 	opts.args = flags.Args()
+	restoreTerminal := setupTerminal(flags, opts)
+	defer restoreTerminal()
 	setupLogging(opts)
 
 	switch {
@@ -37,9 +55,9 @@ func Run(name string, args []string) error {
 		fmt.Fprintf(os.Stdout, "gotestsum version %s\n", version)
 		return nil
 	case opts.watch:
-		return runWatcher(opts)
+		return opts.exitCodes.resolve(runWatcher(opts))
 	}
-	return run(opts)
+	return opts.exitCodes.resolve(run(opts))
 }
 
 func setupFlags(name string) (*pflag.FlagSet, *options) {
@@ -48,6 +66,8 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 		junitTestCaseClassnameFormat: &junitFieldFormatValue{},
 		junitTestSuiteNameFormat:     &junitFieldFormatValue{},
 		postRunHookCmd:               &commandValue{},
+		command:                      &commandValue{},
+		analyzerCmd:                  &commandValue{},
 		stdout:                       color.Output,
 		stderr:                       color.Error,
 	}
@@ -56,30 +76,80 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 	flags.Usage = func() {
 		usage(os.Stdout, name, flags)
 	}
+	envVars := flagEnvVars{}
 	flags.StringVarP(&opts.format, "format", "f",
-		lookEnvWithDefault("GOTESTSUM_FORMAT", "short"),
+		envVars.lookup("format", "GOTESTSUM_FORMAT", "short"),
 		"print format of test input")
+	flags.CountVarP(&opts.verbose, "verbose", "v",
+		"print a line for each test with -v, and all test output with -vv, in any format")
+	flags.BoolVarP(&opts.quiet, "quiet", "q", false,
+		"do not print passing tests, and packages that passed or have no tests, in any format")
+	flags.Var(&opts.displayFilter, "display-filter",
+		"only print the packages and tests that match one of these | separated patterns, a pattern is a package like ./pkg/... or a test name regex")
 	flags.BoolVar(&opts.formatOptions.HideEmptyPackages, "format-hide-empty-pkg",
 		false, "do not print empty packages in compact formats")
 	flags.BoolVar(&opts.formatOptions.UseHiVisibilityIcons, "format-hivis",
 		false, "use high visibility characters in some formats")
+	flags.StringVar(&opts.formatTruncate, "format-truncate", "middle",
+		"replace the middle, start, or end of names that do not fit in the terminal with an ellipsis, or none, in formats that align lines")
+	flags.StringVar(&opts.logFormat, "log-format",
+		envVars.lookup("log-format", "GOTESTSUM_LOG_FORMAT", "pretty"),
+		"print JSON log lines in the output of failed tests in the summary as: pretty (level, message, and key=value), or raw")
+	flags.StringVar(&opts.minCapturedLogLevel, "min-captured-log-level",
+		envVars.lookup("min-captured-log-level", "GOTESTSUM_MIN_CAPTURED_LOG_LEVEL", ""),
+		"omit JSON log lines below this level (ex: warn) from the output of failed tests in the summary")
 	flags.BoolVar(&opts.rawCommand, "raw-command", false,
 		"don't prepend 'go test -json' to the 'go test' command")
+	flags.Var(opts.command, "command",
+		"command used instead of 'go test', with {packages} and {flags} placeholders (ex: 'richgo test {packages} {flags}')")
 	flags.BoolVar(&opts.ignoreNonJSONOutputLines, "ignore-non-json-output-lines", false,
 		"write non-JSON 'go test' output lines to stderr instead of failing")
 	flags.Lookup("ignore-non-json-output-lines").Hidden = true
+	flags.StringVar(&opts.strictJSON, "strict-json", "",
+		"warn about lines of go test output that are not test2json events, or fail the run (warn|fail)")
+	flags.Lookup("strict-json").NoOptDefVal = "warn"
+	flags.BoolVar(&opts.strictOutputAttribution, "strict-output-attribution", false,
+		"move output printed directly to stdout while parallel tests run to the package output, and report it")
+	flags.BoolVar(&opts.captureDiagnostics, "capture-diagnostics", false,
+		"save the warnings from the stderr of go test in the JSON summary and junit.xml file")
 	flags.StringVar(&opts.jsonFile, "jsonfile",
-		lookEnvWithDefault("GOTESTSUM_JSONFILE", ""),
+		envVars.lookup("jsonfile", "GOTESTSUM_JSONFILE", ""),
 		"write all TestEvents to file")
 	flags.BoolVar(&opts.noColor, "no-color", defaultNoColor, "disable color output")
+	flags.BoolVar(&opts.forceTTY, "force-tty", false,
+		"format the output as if stdout is a terminal, even when stdout is redirected")
+	flags.Var(&opts.packageNameFormat, "short-package-names",
+		"format package names in the output and reports as: "+packageNameFormatValues)
 
 	flags.Var(opts.hideSummary, "no-summary",
 		"do not print summary of: "+testjson.SummarizeAll.String())
 	flags.Lookup("no-summary").Hidden = true
 	flags.Var(opts.hideSummary, "hide-summary",
 		"hide sections of the summary: "+testjson.SummarizeAll.String())
+	flags.Var(&opts.pluginCommands, "plugin",
+		"command that receives every test event as a line of JSON on stdin. May be used more than once")
+	flags.Var(&opts.labels, "label",
+		"label the run with key=value in the summary, reports, and publishers, may be repeated")
 	flags.Var(opts.postRunHookCmd, "post-run-command",
 		"command to run after the tests have completed")
+	flags.Var(&opts.publisherCommands, "publisher",
+		"command to run after the tests, with the JSON summary on stdin, may be repeated")
+	flags.IntVar(&opts.publisherRetries, "publisher-retries", 2,
+		"number of times to retry a --publisher, --webhook-url, or --teams-webhook-url that fails")
+	flags.StringVar(&opts.webhookURL, "webhook-url",
+		envVars.lookup("webhook-url", "GOTESTSUM_WEBHOOK_URL", ""),
+		"send a POST request to this URL on the --webhook-on events")
+	flags.Var(&opts.webhookTemplate, "webhook-template",
+		"go text/template file used to create the body of the --webhook-url request")
+	flags.Var(&opts.webhookOn, "webhook-on",
+		"comma separated list of events that send the webhook: "+strings.Join(webhookEventNames(), ", "))
+	flags.StringVar(&opts.teamsWebhookURL, "teams-webhook-url",
+		envVars.lookup("teams-webhook-url", "GOTESTSUM_TEAMS_WEBHOOK_URL", ""),
+		"send an Adaptive Card with the results to this Microsoft Teams webhook URL")
+	flags.BoolVar(&opts.withVet, "with-vet", false,
+		"run go vet on the packages after the tests, and report any problems as errors")
+	flags.Var(opts.analyzerCmd, "analyzer-command",
+		"command to run after the tests, and report any problems as errors (ex: 'staticcheck -f json ./...')")
 	flags.BoolVar(&opts.watch, "watch", false,
 		"watch go files, and run tests when a file is modified")
 	flags.BoolVar(&opts.watchChdir, "watch-chdir", false,
@@ -88,18 +158,101 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 		"end the test run after this number of failures")
 
 	flags.StringVar(&opts.junitFile, "junitfile",
-		lookEnvWithDefault("GOTESTSUM_JUNITFILE", ""),
+		envVars.lookup("junitfile", "GOTESTSUM_JUNITFILE", ""),
 		"write a JUnit XML file")
 	flags.Var(opts.junitTestSuiteNameFormat, "junitfile-testsuite-name",
 		"format the testsuite name field as: "+junitFieldFormatValues)
 	flags.Var(opts.junitTestCaseClassnameFormat, "junitfile-testcase-classname",
 		"format the testcase classname field as: "+junitFieldFormatValues)
+	flags.Var(&opts.requirementProperties, "requirement-property",
+		"write the requirements in test names as a property with this name and format, instead of Requirement, may be repeated")
+	flags.StringVar(&opts.requirementURLTemplate, "requirement-url-template", "",
+		"URL of a requirement, with {id} or {number}, used to link requirements in the junit.xml file, reports, and webhooks")
+	flags.BoolVar(&opts.requireRequirements, "require-requirements", false,
+		"fail if a test that was run does not have a requirement in its name, like TestLogin[REQ-1]")
+	flags.Var(&opts.requirementExemptions, "require-requirements-exempt",
+		"package=pattern or test=regexp of tests that do not need a requirement, may be repeated")
+	flags.Var(&opts.skipCategories, "skip-category",
+		"name=regexp, put skipped tests with output that matches regexp in a category in the summary and reports, may be repeated")
+	flags.Var(&opts.projects, "projects",
+		"name=pattern[,pattern], group the packages that match the patterns into a project in the summary and junit.xml file")
 	flags.StringVar(&opts.junitProjectName, "junitfile-project-name",
-		lookEnvWithDefault("GOTESTSUM_JUNITFILE_PROJECT_NAME", ""),
+		envVars.lookup("junitfile-project-name", "GOTESTSUM_JUNITFILE_PROJECT_NAME", ""),
 		"name of the project used in the junit.xml file")
+	flags.StringVar(&opts.attachmentDir, "attachment-dir", "",
+		"directory where tests write files to attach to the junit.xml file. Set as "+attachmentDirEnv+" for go test")
+	flags.Var(&opts.junitSort, "junitfile-sort",
+		"order of test cases in the junit.xml file: runorder, name, or outcome")
+	flags.Var(&opts.junitSchema, "junitfile-schema",
+		"write the junit.xml file using the dialect of: "+junitSchemaNames())
+	flags.StringVar(&opts.polarion.projectID, "polarion-project-id", "",
+		"ID of the Polarion project, required by --junitfile-schema polarion")
+	flags.StringVar(&opts.polarion.testRunID, "polarion-testrun-id", "",
+		"ID of the Polarion test run created by --junitfile-schema polarion")
+	flags.StringVar(&opts.polarion.testRunTitle, "polarion-testrun-title", "",
+		"title of the Polarion test run created by --junitfile-schema polarion. Defaults to --junitfile-project-name")
+	flags.BoolVar(&opts.junitValidate, "junitfile-validate", false,
+		"fail if the junit.xml file does not conform to the --junitfile-schema")
+	flags.BoolVar(&opts.junitDisambiguateNames, "junitfile-disambiguate-names", false,
+		"add a suffix to test cases in the junit.xml file that have the same classname and name as a different test")
+	flags.BoolVar(&opts.strictNames, "strict-names", false,
+		"fail if test cases in the junit.xml file have the same classname and name as a different test")
+	flags.StringVar(&opts.summaryJSONFile, "summary-jsonfile",
+		envVars.lookup("summary-jsonfile", "GOTESTSUM_SUMMARY_JSONFILE", ""),
+		"write a JSON summary of the test run to file")
+	flags.Var(&opts.reportTemplate, "report-template",
+		"write a report of the test run using the go text/template in this file")
+	flags.StringVar(&opts.reportOutput, "report-output", "",
+		"write the --report-template report to file instead of stdout")
+	flags.StringVar(&opts.csvFile, "csvfile",
+		envVars.lookup("csvfile", "GOTESTSUM_CSVFILE", ""),
+		"write a CSV file with one row for each test")
+	flags.StringVar(&opts.sarifFile, "sariffile",
+		envVars.lookup("sariffile", "GOTESTSUM_SARIFFILE", ""),
+		"write a SARIF file with the test failures and build errors")
+	flags.StringVar(&opts.cucumberFile, "cucumberfile",
+		envVars.lookup("cucumberfile", "GOTESTSUM_CUCUMBERFILE", ""),
+		"write a Cucumber JSON file with a feature for each package")
+	flags.StringVar(&opts.nunitFile, "nunitfile",
+		envVars.lookup("nunitfile", "GOTESTSUM_NUNITFILE", ""),
+		"write an NUnit 3 XML file")
+	flags.StringVar(&opts.shardIndex, "shard-index", "",
+		"index of this shard of a sharded run, used as {shard} in the name of report files")
+	flags.Var((*stringSlice)(&opts.captureEnv), "capture-env",
+		"space separated list of environment variables, or patterns like 'CI_*', to record in the junit.xml and JSON summary")
+	flags.Var(&opts.redactEnv, "redact-env",
+		"comma separated list of environment variables, or patterns like 'SECRET*', to redact from reports and webhooks")
+	flags.Var(&opts.scrubPatterns, "scrub-pattern",
+		"regular expression for secrets to remove from the test output, may be repeated")
+	flags.BoolVar(&opts.noDefaultScrub, "no-default-scrub", false,
+		"do not remove common token formats, like bearer tokens, from the test output")
+	flags.Var(&opts.signReport, "sign-report",
+		"private key (PEM) used to write a detached signature of the --jsonfile and --junitfile to FILE.sig")
+	flags.StringVar(&opts.testManifest, "test-manifest", "",
+		"write a JSON manifest of every test that was run, with its result and the digest of its test binary")
+	flags.StringVar(&opts.archive, "archive", "",
+		"write the report files, coverage profile, profiles, and attachments, with a manifest of their digests, to this .tar.gz file")
+	flags.BoolVar(&opts.deterministic, "deterministic", false,
+		"write the same junit.xml for every run of the same tests, without durations. "+
+			"The timestamp is from "+sourceDateEpochEnv)
+	flags.BoolVar(&opts.noVCS, "no-vcs", false,
+		"do not record the git commit, branch, and dirty state in the junit.xml and JSON summary")
 	flags.BoolVar(&opts.junitHideEmptyPackages, "junitfile-hide-empty-pkg",
-		truthyFlag(lookEnvWithDefault("GOTESTSUM_JUNIT_HIDE_EMPTY_PKG", "")),
+		truthyFlag(envVars.lookup("junitfile-hide-empty-pkg", "GOTESTSUM_JUNIT_HIDE_EMPTY_PKG", "")),
 		"omit packages with no tests from the junit.xml file")
+	flags.BoolVar(&opts.hideExamples, "hide-examples", false,
+		"omit Example functions from the junit.xml file and JSON summary")
+
+	flags.Var(&opts.baseline, "baseline",
+		"file of known test failures, which are reported but do not fail the run")
+	flags.StringVar(&opts.baselineOutput, "baseline-output", "",
+		"write the tests that failed to this file, in the format of --baseline")
+	flags.Var(&opts.flakeRules, "flake-rules",
+		"file of known flaky failures, with an owner and expiry date, that are re-run and do not fail the run")
+	flags.Var(&opts.severityRules, "severity-rules",
+		"file of rules that set the severity (blocker, major, minor) of test failures")
+	flags.Var(&opts.failOnSeverity, "fail-on-severity",
+		"only fail the run for test failures with at least this --severity-rules severity")
 
 	flags.IntVar(&opts.rerunFailsMaxAttempts, "rerun-fails", 0,
 		"rerun failed tests until they all pass, or attempts exceeds maximum. Defaults to max 2 reruns when enabled")
@@ -110,11 +263,92 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 		"space separated list of package to test")
 	flags.StringVar(&opts.rerunFailsReportFile, "rerun-fails-report", "",
 		"write a report to the file, of the tests that were rerun")
+	flags.DurationVar(&opts.rerunTimeBudget, "rerun-time-budget", 0,
+		"stop re-running failed tests when --rerun-fails has spent this much time, and fail the run")
+	flags.BoolVar(&opts.rerunConflicts, "rerun-conflicts", false,
+		"rerun failed tests once, one package at a time, when every failure is caused by a port or file that was in use by another package")
 	flags.BoolVar(&opts.rerunFailsRunRootCases, "rerun-fails-run-root-test", false,
 		"rerun the entire root testcase when any of its subtests fail, instead of only the failed subtest")
+	flags.StringVar(&opts.shuffle, "shuffle", "",
+		"run go test with -shuffle. When on, a seed is chosen so every package uses the same seed (on, off, or a seed)")
+	flags.BoolVar(&opts.reproCommand, "repro-command", false,
+		"print a go test command to reproduce each failure in the summary. Enabled by --shuffle")
+	flags.StringVar(&opts.reproScript, "write-repro-script", "",
+		"write a shell script to this file that runs all the failed tests again")
+	flags.StringVar(&opts.affectedBy, "affected-by", "",
+		"only test packages affected by the files changed since this git ref")
+	flags.StringVar(&opts.resultCacheDir, "result-cache",
+		envVars.lookup("result-cache", "GOTESTSUM_RESULT_CACHE", ""),
+		"directory used to cache the results of packages that passed, and skip them when their inputs are unchanged")
+	flags.BoolVar(&opts.noResultCache, "no-cache", false,
+		"do not read or write the --result-cache")
+	flags.Var((*stringSlice)(&opts.resultCacheInvalidate), "result-cache-invalidate",
+		"space separated list of packages to remove from the --result-cache before the run")
+	flags.StringVar(&opts.profileDir, "profile-dir", "",
+		"run go test once for each package, and write CPU and memory profiles for each package to this directory")
+	flags.IntVar(&opts.profileTop, "profile-top", 0,
+		"print this number of functions from the CPU profile of the slowest packages when --profile-dir is set")
+	flags.Var(&opts.packageTimeouts, "package-timeout",
+		"comma separated list of pattern=duration, run the packages matching each pattern with this go test -timeout")
+	flags.Var(&opts.packageArgs, "package-args",
+		"pattern=args, run the packages matching the pattern with these extra go test args, may be used more than once")
+	flags.Var(&opts.envGroups, "env-group",
+		"name=patterns and KEY=VALUE, run the packages matching the patterns with these environment variables, may be used more than once")
+	flags.BoolVar(&opts.envParallel, "env-parallel", false,
+		"run the go test commands of each --env-group at the same time, instead of one after the other")
+	flags.StringVar(&opts.composeFile, "compose-file", "",
+		"start the services in this docker compose file before the run, and stop them after the run")
+	flags.StringVar(&opts.setupCommand, "setup-command", "",
+		"command to run before the run to start the dependencies of the tests")
+	flags.StringVar(&opts.setupReady, "setup-ready", "",
+		"command to run until it succeeds, to wait for the dependencies to be ready before the run")
+	flags.StringVar(&opts.teardownCommand, "teardown-command", "",
+		"command to run after the run to stop the dependencies of the tests")
+	flags.DurationVar(&opts.setupTimeout, "setup-timeout", 2*time.Minute,
+		"fail the run if the dependencies are not ready after this duration")
+	flags.Var(&opts.waitFor, "wait-for",
+		"wait until tcp://HOST:PORT accepts a connection, an http:// or https:// URL returns a 2xx or 3xx status, or cmd:COMMAND succeeds, before the run, may be used more than once")
+	flags.DurationVar(&opts.waitTimeout, "wait-timeout", time.Minute,
+		"fail the run if the --wait-for probes are not ready after this duration")
+	flags.BoolVar(&opts.allModules, "all-modules", false,
+		"run go test in the directory of each module in go.work, or of each go.mod file in the current directory and its subdirectories")
+	flags.IntVar(&opts.buildRetries, "build-retries", 0,
+		"run go test again this many times for packages that fail to build because of transient network or module proxy errors")
+	flags.DurationVar(&opts.buildRetryDelay, "build-retry-delay", 5*time.Second,
+		"time to wait before the first --build-retries attempt, doubled for each attempt")
+	flags.DurationVar(&opts.setupThreshold, "setup-threshold", 0,
+		"print the setup and teardown time of packages in the summary when TestMain takes longer than this duration before the first test or after the last test")
+	flags.StringVar(&opts.statusAddr, "status-addr", "",
+		"address for an HTTP server that serves the status of the run at /status (JSON) and /metrics (Prometheus) while the tests run")
+	flags.BoolVar(&opts.summaryLine, "summary-line", false,
+		"print a GOTESTSUM_RESULT line with the totals of the run after the summary, in a format that is easy for tools to parse")
+	flags.BoolVar(&opts.utilizationChart, "utilization-chart", false,
+		"print a chart of the number of tests running in each package over the time of the run")
+	flags.BoolVar(&opts.tune, "tune", false,
+		"print the recommended go test -p and -parallel values after the summary, based on the times of the tests")
+	flags.IntVar(&opts.goTestP, "go-test-p", 0,
+		"run go test with -p, the number of packages that are tested at the same time")
+	flags.IntVar(&opts.goTestParallel, "go-test-parallel", 0,
+		"run go test with -parallel, the number of parallel tests that run at the same time in a package")
+	flags.BoolVar(&opts.resourceUsageSummary, "resource-usage", false,
+		"print the CPU time and memory used by each go test process in the summary")
+	flags.Var(&opts.maxRSS, "max-rss",
+		"warn when a go test process uses more than this amount of memory (ex: 2GiB)")
+	flags.DurationVar(&opts.heartbeatInterval, "heartbeat", 0,
+		"print a progress line when nothing has been printed for this duration, to prevent CI inactivity timeouts")
+	flags.IntVar(&opts.runningFooter, "running-footer", 0,
+		"print this number of the longest running tests, and the elapsed time, below the output when stdout is a terminal")
+	flags.DurationVar(&opts.stuckThreshold, "stuck-threshold", 0,
+		"send SIGQUIT to the test binaries to print goroutine stack traces when no test events are received for this duration")
+	flags.BoolVar(&opts.stuckAbort, "stuck-abort", false,
+		"end the test run after the test binaries are sent SIGQUIT by --stuck-threshold")
+
+	flags.Var(&opts.exitCodes, "exit-code-map",
+		"comma separated list of name=code to change the exit code for: "+exitCategoryNames())
 
 	flags.BoolVar(&opts.debug, "debug", false, "enabled debug logging")
 	flags.BoolVar(&opts.version, "version", false, "show version and exit")
+	envVars.annotate(flags)
 	return flags, opts
 }
 
@@ -138,8 +372,21 @@ Formats:
     testname                 print a line for each test and package
     standard-quiet           standard go test format
     standard-verbose         standard go test -v format
+    exec:COMMAND             run COMMAND as a formatter plugin
+
+Format modifiers are added to the name of a format with +, like testname+tree:
+    align                    print elapsed times in a column on the right, and truncate names that do not fit
+    coverage                 print the coverage of each package in a column before the name
+    hide-empty               do not print packages that have no tests
+    hivis                    use high visibility characters in pkgname formats
+    timestamps               print the time of each event at the start of the line
+    tree                     print subtests below their test, in the testname format
+    wall-clock               print the time since the start of the run when each test or package ends
 
 Commands:
+    %[1]s serve-ui       run tests and serve a live dashboard of the test run
+    %[1]s init           write a config file and a CI pipeline that runs gotestsum
+    %[1]s completion     print a shell completion script for bash, zsh, fish, or powershell
     %[1]s tool slowest   find or skip the slowest tests
     %[1]s help           print this help next
 `, name)
@@ -155,29 +402,164 @@ func lookEnvWithDefault(key, defValue string) string {
 type options struct {
 	args                         []string
 	format                       string
+	displayFilter                displayFilter
 	formatOptions                testjson.FormatOptions
+	formatTruncate               string
+	verbose                      int
+	quiet                        bool
 	debug                        bool
 	rawCommand                   bool
+	command                      *commandValue
 	ignoreNonJSONOutputLines     bool
+	captureDiagnostics           bool
+	strictJSON                   string
+	strictOutputAttribution      bool
 	jsonFile                     string
 	junitFile                    string
+	shardIndex                   string
 	postRunHookCmd               *commandValue
+	withVet                      bool
+	analyzerCmd                  *commandValue
+	pluginCommands               commandListValue
+	labels                       labelsValue
+	publisherCommands            commandListValue
+	publisherRetries             int
+	publishResults               []publishResult
+	webhookURL                   string
+	webhookTemplate              templateFileValue
+	webhookOn                    webhookEventsValue
+	teamsWebhookURL              string
 	noColor                      bool
+	forceTTY                     bool
+	packageNameFormat            packageNameFormatValue
+	projects                     projectsValue
+	requirementProperties        requirementPropertiesValue
+	requirementURLTemplate       string
+	skipCategories               skipCategoriesValue
+	requireRequirements          bool
+	requirementExemptions        requirementExemptionsValue
 	hideSummary                  *hideSummaryValue
 	junitTestSuiteNameFormat     *junitFieldFormatValue
 	junitTestCaseClassnameFormat *junitFieldFormatValue
 	junitProjectName             string
 	junitHideEmptyPackages       bool
+	hideExamples                 bool
+	setupThreshold               time.Duration
+	utilizationChart             bool
+	tune                         bool
+	goTestP                      int
+	goTestParallel               int
+	summaryLine                  bool
+	junitSort                    junitSortValue
+	attachmentDir                string
+	junitSchema                  junitSchemaValue
+	polarion                     polarionOptions
+	junitValidate                bool
+	junitDisambiguateNames       bool
+	strictNames                  bool
 	rerunFailsMaxAttempts        int
 	rerunFailsMaxInitialFailures int
 	rerunFailsReportFile         string
+	rerunTimeBudget              time.Duration
+	baseline                     baselineValue
+	baselineOutput               string
+	flakeRules                   flakeRulesValue
+	severityRules                severityRulesValue
+	failOnSeverity               severityValue
 	rerunFailsRunRootCases       bool
+	shuffle                      string
+	reproCommand                 bool
+	reproScript                  string
+	affectedBy                   string
+	resultCacheDir               string
+	noResultCache                bool
+	resultCacheInvalidate        []string
+	profileDir                   string
+	profileTop                   int
+	packageTimeouts              packageTimeoutMap
+	packageArgs                  packageArgsMap
+	envGroups                    envGroupsValue
+	envParallel                  bool
+	composeFile                  string
+	setupCommand                 string
+	setupReady                   string
+	teardownCommand              string
+	setupTimeout                 time.Duration
+	waitFor                      waitForValue
+	waitTimeout                  time.Duration
+	rerunConflicts               bool
+	logFormat                    string
+	minCapturedLogLevel          string
+	buildRetries                 int
+	allModules                   bool
+	buildRetryDelay              time.Duration
+	summaryJSONFile              string
+	reportTemplate               templateFileValue
+	reportOutput                 string
+	csvFile                      string
+	sarifFile                    string
+	cucumberFile                 string
+	nunitFile                    string
+	captureEnv                   []string
+	redactEnv                    envPatternsValue
+	scrubPatterns                scrubPatternsValue
+	noDefaultScrub               bool
+	scrubOutput                  func(string) string
+	signReport                   signingKeyValue
+	testManifest                 string
+	archive                      string
+	deterministic                bool
+	reportTimestamp              time.Time
+	noVCS                        bool
+	resourceUsageSummary         bool
+	maxRSS                       byteSizeValue
+	stuckThreshold               time.Duration
+	stuckAbort                   bool
+	heartbeatInterval            time.Duration
+	runningFooter                int
+	serveUIAddr                  string
+	serveUILinger                time.Duration
+	statusAddr                   string
+	exitCodes                    exitCodeMap
 	packages                     []string
 	watch                        bool
 	watchChdir                   bool
 	maxFails                     int
 	version                      bool
 
+	// resultCache is set by run when the result cache is enabled.
+	resultCache *resultCache
+	// modules is set by run when allModules is set.
+	modules goModules
+	// profiles is set by run to the profile files of each package when
+	// profileDir is set.
+	profiles map[string]*jsonsummary.Profiles
+	// resourceUsage is appended to by run each time a go test process exits.
+	resourceUsage []jsonsummary.ResourceUsage
+	// reruns is set by rerunFailed to the cost of re-running failed tests.
+	reruns *jsonsummary.Reruns
+	// heartbeat is set by run when heartbeatInterval is set.
+	heartbeat *heartbeat
+	// footer is set by run when runningFooter is set.
+	footer *runningFooter
+	// stuck is set by run when stuckThreshold is set.
+	stuck *stuckDetector
+	// environment is set by runEnvironment.
+	environment map[string]string
+	// goEnv is set by goEnv.
+	goEnv *goEnvironment
+	// vcs and vcsProbed are set by vcs.
+	vcs       *vcsInfo
+	vcsProbed bool
+	// plugins are the plugin processes started by newEventHandler.
+	plugins []*pluginProcess
+	// dashboard is set by run when serveUIAddr or statusAddr is set.
+	dashboard *dashboard
+	// attachments is set by run when attachmentDir is set.
+	attachments *attachments
+	// dependencies is set by run when setupEnabled.
+	dependencies *dependencies
+
 	// shims for testing
 	stdout io.Writer
 	stderr io.Writer
@@ -193,9 +575,196 @@ func (o options) Validate() error {
 		return fmt.Errorf("-failfast can not be used with --rerun-fails " +
 			"because not all test cases will run")
 	}
+	if o.junitSchema.value == junitxml.SchemaPolarion && o.polarion.projectID == "" {
+		return fmt.Errorf("--polarion-project-id is required by --junitfile-schema polarion")
+	}
+	if o.affectedBy != "" && o.rawCommand {
+		return fmt.Errorf("--affected-by can not be used with --raw-command")
+	}
+	if o.affectedBy != "" && len(o.args) > 0 && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when go test args are used with --affected-by " +
+				"the list of packages to test must be specified by the --packages flag")
+	}
+	if o.useResultCache() && o.rawCommand {
+		return fmt.Errorf("--result-cache can not be used with --raw-command")
+	}
+	if o.useResultCache() && len(o.args) > 0 && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when go test args are used with --result-cache " +
+				"the list of packages to test must be specified by the --packages flag")
+	}
+	if o.profileDir != "" && o.rawCommand {
+		return fmt.Errorf("--profile-dir can not be used with --raw-command")
+	}
+	if o.profileDir != "" && len(o.args) > 0 && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when go test args are used with --profile-dir " +
+				"the list of packages to test must be specified by the --packages flag")
+	}
+	if o.packageTimeouts.enabled() && o.rawCommand {
+		return fmt.Errorf("--package-timeout can not be used with --raw-command")
+	}
+	if o.packageTimeouts.enabled() && o.profileDir != "" {
+		return fmt.Errorf("--package-timeout can not be used with --profile-dir")
+	}
+	if o.packageTimeouts.enabled() && len(o.args) > 0 && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when go test args are used with --package-timeout " +
+				"the list of packages to test must be specified by the --packages flag")
+	}
+	if o.packageArgs.enabled() && (o.rawCommand || o.profileDir != "") {
+		return fmt.Errorf("--package-args can not be used with --raw-command or --profile-dir")
+	}
+	if o.packageArgs.enabled() && len(o.args) > 0 && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when go test args are used with --package-args " +
+				"the list of packages to test must be specified by the --packages flag")
+	}
+	if err := o.envGroups.validate(); err != nil {
+		return err
+	}
+	if o.envGroups.enabled() && (o.rawCommand || o.profileDir != "") {
+		return fmt.Errorf("--env-group can not be used with --raw-command or --profile-dir")
+	}
+	if o.envGroups.enabled() && len(o.args) > 0 && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when go test args are used with --env-group " +
+				"the list of packages to test must be specified by the --packages flag")
+	}
+	if o.envParallel && !o.envGroups.enabled() {
+		return fmt.Errorf("--env-parallel requires --env-group")
+	}
+	switch testjson.Truncate(o.formatTruncate) {
+	case "", testjson.TruncateMiddle, testjson.TruncateStart, testjson.TruncateEnd, testjson.TruncateNone:
+	default:
+		return fmt.Errorf("invalid --format-truncate %q, must be one of: middle, start, end, none", o.formatTruncate)
+	}
+	if o.runningFooter < 0 {
+		return fmt.Errorf("--running-footer must not be negative")
+	}
+	if o.quiet && o.verbose > 0 {
+		return fmt.Errorf("--quiet can not be used with --verbose")
+	}
+	switch o.logFormat {
+	case "", "pretty", "raw":
+	default:
+		return fmt.Errorf("invalid --log-format %q, must be one of: pretty, raw", o.logFormat)
+	}
+	if o.minCapturedLogLevel != "" && !structlog.IsLevel(o.minCapturedLogLevel) {
+		return fmt.Errorf("invalid --min-captured-log-level %q, must be a level like debug, info, warn, or error",
+			o.minCapturedLogLevel)
+	}
+	if o.setupEnabled() && o.setupTimeout <= 0 {
+		return fmt.Errorf("--setup-timeout must be greater than 0")
+	}
+	if len(o.waitFor.probes) > 0 && o.waitTimeout <= 0 {
+		return fmt.Errorf("--wait-timeout must be greater than 0")
+	}
+	if o.setupEnabled() && o.watch {
+		return fmt.Errorf("--compose-file, --setup-command, and --wait-for can not be used with --watch")
+	}
+	if o.allModules && (o.rawCommand || o.profileDir != "" ||
+		o.packageTimeouts.enabled() || o.packageArgs.enabled() || o.envGroups.enabled()) {
+		return fmt.Errorf("--all-modules can not be used with --raw-command, --profile-dir, " +
+			"--package-timeout, --package-args, or --env-group")
+	}
+	if o.allModules && (o.buildRetries > 0 || o.affectedBy != "" || o.useResultCache()) {
+		return fmt.Errorf("--all-modules can not be used with --build-retries, --affected-by, or --result-cache")
+	}
+	if o.allModules && len(o.args) > 0 && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when go test args are used with --all-modules " +
+				"the list of packages to test in each module must be specified by the --packages flag")
+	}
+	if o.buildRetries > 0 && (o.rawCommand || o.profileDir != "") {
+		return fmt.Errorf("--build-retries can not be used with --raw-command or --profile-dir")
+	}
+	if o.buildRetries > 0 && len(o.args) > 0 && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when go test args are used with --build-retries " +
+				"the list of packages to test must be specified by the --packages flag")
+	}
+	if err := validateShuffle(o.shuffle); err != nil {
+		return err
+	}
+	if err := validateStrictJSON(o.strictJSON); err != nil {
+		return err
+	}
+	if err := validateRequirementURLTemplate(o.requirementURLTemplate); err != nil {
+		return err
+	}
+	if o.reportOutput != "" && o.reportTemplate.tmpl == nil {
+		return fmt.Errorf("--report-output requires --report-template")
+	}
+	if o.failOnSeverity.value != 0 && !o.severityRules.enabled() {
+		return fmt.Errorf("--fail-on-severity requires --severity-rules")
+	}
+	if err := validateCommandTemplate(o.command.Value()); err != nil {
+		return err
+	}
+	if len(o.command.Value()) > 0 && o.rawCommand {
+		return fmt.Errorf("--command can not be used with --raw-command")
+	}
+	if o.rerunTimeBudget < 0 {
+		return fmt.Errorf("--rerun-time-budget must not be negative")
+	}
+	if o.publisherRetries < 0 {
+		return fmt.Errorf("--publisher-retries must not be negative")
+	}
+	if o.goTestP < 0 || o.goTestParallel < 0 {
+		return fmt.Errorf("--go-test-p and --go-test-parallel must not be negative")
+	}
+	if o.webhookURL == "" && (o.webhookTemplate.tmpl != nil || o.webhookOn.events != nil) {
+		return fmt.Errorf("--webhook-template and --webhook-on require --webhook-url")
+	}
+	if o.shuffle != "" && (o.rawCommand || hasShuffleArg(o.args)) {
+		return fmt.Errorf("--shuffle can not be used with --raw-command, or with a -shuffle go test flag")
+	}
+	if o.reproScript != "" && o.rawCommand {
+		return fmt.Errorf("--write-repro-script can not be used with --raw-command")
+	}
+	if o.withVet && o.rawCommand && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when --with-vet is used with --raw-command " +
+				"the list of packages to vet must be specified by the --packages flag")
+	}
+	if o.withVet && len(o.args) > 0 && len(o.packages) == 0 {
+		return fmt.Errorf(
+			"when go test args are used with --with-vet " +
+				"the list of packages to vet must be specified by the --packages flag")
+	}
+	if o.stuckAbort && o.stuckThreshold <= 0 {
+		return fmt.Errorf("--stuck-abort requires --stuck-threshold")
+	}
+	if o.statusAddr != "" && o.watch {
+		return fmt.Errorf("--status-addr can not be used with --watch")
+	}
+	if o.statusAddr != "" && o.serveUIAddr != "" {
+		return fmt.Errorf("--status-addr can not be used with serve-ui, which serves /status and /metrics at --addr")
+	}
+	if o.serveUIAddr != "" && o.watch {
+		return fmt.Errorf("serve-ui can not be used with --watch")
+	}
 	return nil
 }
 
+func (o options) useResultCache() bool {
+	return o.resultCacheDir != "" && !o.noResultCache
+}
+
+// verbosity returns the verbosity of the format from the --quiet and
+// --verbose flags. More than -vv is the same as -vv.
+func (o options) verbosity() testjson.Verbosity {
+	switch {
+	case o.quiet:
+		return testjson.VerbosityQuiet
+	case o.verbose >= int(testjson.VerbosityOutput):
+		return testjson.VerbosityOutput
+	}
+	return testjson.Verbosity(o.verbose)
+}
+
 var defaultNoColor = func() bool {
 	if os.Getenv("GITHUB_ACTIONS") == "true" {
 		return false
@@ -215,35 +784,140 @@ func run(opts *options) error {
 	defer cancel()
 
 	if err := opts.Validate(); err != nil {
+		return misuseError(err)
+	}
+	if err := expandFilenames(opts); err != nil {
+		return misuseError(err)
+	}
+	opts.scrubOutput = newScrubber(opts, os.Environ())
+	if opts.deterministic {
+		var err error
+		if opts.reportTimestamp, err = reportTimestamp(os.Getenv); err != nil {
+			return misuseError(err)
+		}
+	}
+	if err := setupPackageNames(opts); err != nil {
 		return err
 	}
 
-	goTestProc, err := startGoTestFn(ctx, "", goTestCmdArgs(opts, rerunOpts{}))
+	if opts.affectedBy != "" {
+		pkgs, err := affectedPackages(opts)
+		if err != nil {
+			return err
+		}
+		if len(pkgs) == 0 {
+			fmt.Fprintf(opts.stdout, "No packages affected by changes since %v\n", opts.affectedBy)
+			return nil
+		}
+		opts.packages = pkgs
+	}
+
+	resolveShuffleSeed(opts)
+	opts.stuck = newStuckDetector(opts)
+	if err := setupAttachmentDir(opts); err != nil {
+		return err
+	}
+	// the footer is created first, so that the heartbeat writes through it.
+	opts.footer = newRunningFooter(opts)
+	defer opts.footer.close()
+	opts.heartbeat = newHeartbeat(opts)
+	defer opts.heartbeat.close()
+	var err error
+	opts.dashboard, err = startDashboard(opts)
 	if err != nil {
 		return err
 	}
+	defer opts.dashboard.close()
 
 	handler, err := newEventHandler(opts)
 	if err != nil {
 		return err
 	}
 	defer handler.Close() // nolint: errcheck
+
+	var scanHandler testjson.EventHandler = handler
+	var cachedExec *testjson.Execution
+	if opts.useResultCache() {
+		opts.resultCache, err = newResultCache(opts)
+		if err != nil {
+			return err
+		}
+		cachedExec, opts.packages, err = opts.resultCache.replay(handler)
+		if err != nil {
+			return fmt.Errorf("failed to read result cache: %w", err)
+		}
+		if len(opts.packages) == 0 {
+			return finishRun(opts, cachedExec, nil)
+		}
+		scanHandler = opts.resultCache.recorder(handler)
+	}
+
+	opts.dependencies, err = startDependencies(ctx, opts)
+	if err != nil {
+		return writeSetupFailureSummary(opts, cachedExec, err)
+	}
+	defer opts.dependencies.stop()
+
+	webhookStart(opts)
 	cfg := testjson.ScanConfig{
-		Stdout:                   goTestProc.stdout,
-		Stderr:                   goTestProc.stderr,
-		Handler:                  handler,
+		Handler:                  scanHandler,
+		Execution:                cachedExec,
 		Stop:                     cancel,
 		IgnoreNonJSONOutputLines: opts.ignoreNonJSONOutputLines,
+		CaptureDiagnostics:       opts.captureDiagnostics,
+		StrictJSON:               opts.strictJSON != "",
+		StrictOutputAttribution:  opts.strictOutputAttribution,
+		ScrubOutput:              opts.scrubOutput,
 	}
-	exec, err := testjson.ScanTestOutput(cfg)
-	if err != nil {
-		return finishRun(opts, exec, err)
+	var exec *testjson.Execution
+	var exitErr error
+	switch {
+	case opts.profileDir != "":
+		exec, exitErr, err = runWithProfiles(ctx, opts, cfg)
+		if err != nil {
+			return finishRun(opts, exec, err)
+		}
+	case opts.allModules:
+		exec, exitErr, err = runWithModules(ctx, opts, cfg)
+		if err != nil {
+			return finishRun(opts, exec, err)
+		}
+	case opts.packageTimeouts.enabled() || opts.packageArgs.enabled() || opts.envGroups.enabled():
+		exec, exitErr, err = runWithPackageGroups(ctx, opts, cfg)
+		if err != nil {
+			return finishRun(opts, exec, err)
+		}
+	default:
+		goTestProc, err := startGoTestFn(ctx, "", goTestCmdArgs(opts, rerunOpts{}))
+		if err != nil {
+			return err
+		}
+		opts.stuck.watch(goTestProc, cancel)
+
+		cfg.Stdout = goTestProc.stdout
+		cfg.Stderr = goTestProc.stderr
+		exec, err = testjson.ScanTestOutput(cfg)
+		if err != nil {
+			return finishRun(opts, exec, err)
+		}
+		exitErr = goTestProc.cmd.Wait()
+		recordUsage(opts, goTestProc, singlePackage(opts))
+		if err := opts.stuck.abortErr(); err != nil {
+			return finishRun(opts, exec, err)
+		}
+		if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
+			return finishRun(opts, exec, exitError{num: signalExitCode + int(signum)})
+		}
 	}
-	exitErr := goTestProc.cmd.Wait()
-	if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
-		return finishRun(opts, exec, exitError{num: signalExitCode + int(signum)})
+	if opts.buildRetries > 0 {
+		cfg.Execution = exec
+		exitErr, err = retryTransientBuildErrors(ctx, opts, cfg, exitErr)
+		if err != nil {
+			return finishRun(opts, exec, err)
+		}
 	}
-	if exitErr == nil || opts.rerunFailsMaxAttempts == 0 {
+	if exitErr == nil || (opts.rerunFailsMaxAttempts == 0 &&
+		!retryKnownFlakes(opts, exec) && !retryResourceConflicts(opts, exec)) {
 		return finishRun(opts, exec, exitErr)
 	}
 	if err := hasErrors(exitErr, exec); err != nil {
@@ -255,11 +929,21 @@ func run(opts *options) error {
 		err := fmt.Errorf(
 			"number of test failures (%d) exceeds maximum (%d) set by --rerun-fails-max-failures",
 			failed, opts.rerunFailsMaxInitialFailures)
-		return finishRun(opts, exec, err)
+		return finishRun(opts, exec, categoryError{category: exitRerunExhausted, err: err})
 	}
 
-	cfg = testjson.ScanConfig{Execution: exec, Handler: handler}
+	cfg = testjson.ScanConfig{
+		Execution:               exec,
+		Handler:                 handler,
+		CaptureDiagnostics:      opts.captureDiagnostics,
+		StrictJSON:              opts.strictJSON != "",
+		StrictOutputAttribution: opts.strictOutputAttribution,
+		ScrubOutput:             opts.scrubOutput,
+	}
 	exitErr = rerunFailed(ctx, opts, cfg)
+	if IsExitCoder(exitErr) && ExitCodeWithDefault(exitErr) == 1 {
+		exitErr = categoryError{category: exitRerunExhausted, err: exitErr, reported: true}
+	}
 	if err := writeRerunFailsReport(opts, exec); err != nil {
 		return err
 	}
@@ -267,38 +951,147 @@ func run(opts *options) error {
 }
 
 func finishRun(opts *options, exec *testjson.Execution, exitErr error) error {
-	testjson.PrintSummary(opts.stdout, exec, opts.hideSummary.value)
+	if exec == nil {
+		// the run stopped before go test, ex: go list failed, or the
+		// package patterns did not match any packages
+		exec = emptyExecution()
+	}
+	opts.heartbeat.close()
+	opts.footer.close()
+	opts.dependencies.stop()
+	baseline := opts.baseline.compare(exec)
+	flakes := opts.flakeRules.match(exec)
+	known := func(name string) bool {
+		return opts.baseline.contains(name) || flakes.isKnown(name)
+	}
+	exitErr = ignoreKnownFailures(exec, exitErr, known)
+	failures := opts.severityRules.classify(exec)
+	exitErr = severityExitErr(exec, exitErr, failures, opts.failOnSeverity.value, known)
+	exitErr = runAnalyzers(opts, exec, exitErr)
+	// plugins may write to stdout, so they must exit before the summary
+	if err := closePlugins(opts.plugins); err != nil && exitErr == nil {
+		exitErr = err
+	}
+	printProfileTop(opts, exec)
+	if opts.resourceUsageSummary {
+		printResourceUsage(opts.stdout, opts.resourceUsage, opts.packageName)
+	}
+	opts.resultCache.printSummary(opts.stdout, opts.packageName)
+	opts.dashboard.finish(exec)
+	if opts.utilizationChart {
+		printUtilizationChart(opts.stdout, exec, utilizationChartWidth, opts.packageName)
+	}
+	opts.labels.printHeader(opts.stdout)
+	testjson.PrintSummaryWithOptions(opts.stdout, exec, opts.hideSummary.value, summaryOptions(opts, exec))
+	if opts.summaryLine {
+		printSummaryLine(opts.stdout, exec)
+	}
+	baseline.print(opts.stdout)
+	flakes.print(opts.stdout)
+	opts.severityRules.printSummary(opts.stdout, failures)
+	printRequirementsSummary(opts.stdout, exec, opts)
+	printSkipCategories(opts.stdout, exec, opts)
+	printResourceConflicts(opts.stdout, exec)
+	printRerunSummary(opts.stdout, opts.reruns)
+	printTuning(opts.stdout, exec, opts)
+	opts.dependencies.printSummary(opts.stdout)
 
+	if err := collectAttachments(opts, exec); err != nil {
+		return err
+	}
 	if err := writeJUnitFile(opts, exec); err != nil {
 		return fmt.Errorf("failed to write junit file: %w", err)
 	}
+	if err := writeJSONSummary(opts, exec); err != nil {
+		return fmt.Errorf("failed to write JSON summary: %w", err)
+	}
+	if err := writeCSVFile(opts, exec); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+	if err := writeSARIFFile(opts, exec); err != nil {
+		return fmt.Errorf("failed to write SARIF file: %w", err)
+	}
+	if err := writeCucumberFile(opts, exec); err != nil {
+		return fmt.Errorf("failed to write Cucumber JSON file: %w", err)
+	}
+	if err := writeNUnitFile(opts, exec); err != nil {
+		return fmt.Errorf("failed to write NUnit file: %w", err)
+	}
+	if err := writeTemplateReport(opts, exec); err != nil {
+		return err
+	}
+	if err := writeReproScript(opts, exec); err != nil {
+		return err
+	}
+	if err := writeBaseline(opts, exec); err != nil {
+		return err
+	}
+	if err := writeTestManifest(opts, exec); err != nil {
+		return err
+	}
+	if err := signReports(opts); err != nil {
+		return err
+	}
+	if err := writeArchive(opts); err != nil {
+		return err
+	}
 	if err := postRunHook(opts, exec); err != nil {
 		return fmt.Errorf("post run command failed: %w", err)
 	}
-	return exitErr
+	if err := runPublishers(opts, exec, exitErr); err != nil {
+		return fmt.Errorf("failed to publish results: %w", err)
+	}
+	if opts.resultCache != nil {
+		if err := opts.resultCache.save(exec); err != nil {
+			return err
+		}
+	}
+	checkErrs := []error{
+		checkJUnitNames(opts, exec),
+		checkRequirements(opts, exec),
+	}
+	warnUnattributedOutput(opts, exec)
+	checkErrs = append(checkErrs, checkStrictJSON(opts, exec))
+	return withCheckErrors(categorize(exec, exitErr), checkErrs...)
 }
 
 func goTestCmdArgs(opts *options, rerunOpts rerunOpts) []string {
 	if opts.rawCommand {
-		var result []string
-		result = append(result, opts.args...)
-		result = append(result, rerunOpts.Args()...)
-		return result
+		return rawCommandArgs(opts.args, rerunOpts)
 	}
 
-	args := opts.args
+	flags, pkgs, binaryArgs := goTestArgs(opts, rerunOpts)
+	if command := opts.command.Value(); len(command) > 0 {
+		return commandTemplateArgs(command, flags, pkgs, binaryArgs)
+	}
 	result := []string{"go", "test"}
+	result = append(result, flags...)
+	result = append(result, pkgs...)
+	return append(result, binaryArgs...)
+}
+
+// goTestArgs returns the flags, the list of packages, and the -args flag with
+// the args for the test binary, of the go test command for the run.
+func goTestArgs(opts *options, rerunOpts rerunOpts) (flags, pkgs, binaryArgs []string) {
+	args := opts.args
 
 	if len(args) == 0 {
-		result = append(result, "-json")
+		flags = append(flags, "-json")
 		if rerunOpts.runFlag != "" {
-			result = append(result, rerunOpts.runFlag)
+			flags = append(flags, rerunOpts.runFlag)
 		}
-		return append(result, cmdArgPackageList(opts, rerunOpts, "./...")...)
+		if rerunOpts.timeoutFlag != "" {
+			flags = append(flags, rerunOpts.timeoutFlag)
+		}
+		flags = append(flags, rerunOpts.extraArgs...)
+		flags = append(flags, shuffleArgs(opts)...)
+		flags = append(flags, tuneArgs(opts)...)
+		flags = append(flags, rerunOpts.packageArgs...)
+		return flags, cmdArgPackageList(opts, rerunOpts, "./..."), nil
 	}
 
 	if boolArgIndex("json", args) < 0 {
-		result = append(result, "-json")
+		flags = append(flags, "-json")
 	}
 
 	if rerunOpts.runFlag != "" {
@@ -308,20 +1101,49 @@ func goTestCmdArgs(opts *options, rerunOpts rerunOpts) []string {
 		if runIndex >= 0 && runIndexEnd < len(args) {
 			args = append(args[:runIndex], args[runIndexEnd+1:]...)
 		}
-		result = append(result, rerunOpts.runFlag)
+		flags = append(flags, rerunOpts.runFlag)
 	}
+	if rerunOpts.timeoutFlag != "" {
+		// Remove any existing timeout arg, it is replaced by the timeout for
+		// the package.
+		timeoutIndex, timeoutIndexEnd := argIndex("timeout", args)
+		if timeoutIndex >= 0 && timeoutIndexEnd < len(args) {
+			args = append(append([]string{}, args[:timeoutIndex]...), args[timeoutIndexEnd+1:]...)
+		}
+		flags = append(flags, rerunOpts.timeoutFlag)
+	}
+	flags = append(flags, rerunOpts.extraArgs...)
+	flags = append(flags, shuffleArgs(opts)...)
+	flags = append(flags, tuneArgs(opts)...)
 
 	pkgArgIndex := findPkgArgPosition(args)
-	result = append(result, args[:pkgArgIndex]...)
-	result = append(result, cmdArgPackageList(opts, rerunOpts)...)
-	result = append(result, args[pkgArgIndex:]...)
-	return result
+	flags = append(flags, args[:pkgArgIndex]...)
+	flags = append(flags, rerunOpts.packageArgs...)
+	return flags, cmdArgPackageList(opts, rerunOpts), args[pkgArgIndex:]
+}
+
+// emptyExecution returns an Execution without any events.
+func emptyExecution() *testjson.Execution {
+	exec, _ := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader("")})
+	return exec
+}
+
+// singlePackage returns the name of the package when only a single package is
+// being tested, otherwise returns an empty string.
+func singlePackage(opts *options) string {
+	pkgs := cmdArgPackageList(opts, rerunOpts{})
+	if len(pkgs) != 1 || strings.Contains(pkgs[0], "...") {
+		return ""
+	}
+	return pkgs[0]
 }
 
 func cmdArgPackageList(opts *options, rerunOpts rerunOpts, defPkgList ...string) []string {
 	switch {
 	case rerunOpts.pkg != "":
 		return []string{rerunOpts.pkg}
+	case len(rerunOpts.packages) > 0:
+		return rerunOpts.packages
 	case len(opts.packages) > 0:
 		return opts.packages
 	case os.Getenv("TEST_DIRECTORY") != "":
@@ -372,6 +1194,15 @@ type proc struct {
 	// signal is atomically set to the signal value when a signal is received
 	// by newSignalHandler.
 	signal int32
+	// started is the time the process was started.
+	started time.Time
+	// processState returns the state of the process after it exits. It may
+	// be nil.
+	processState func() *os.ProcessState
+	// pid of the process.
+	pid int
+	// done is closed when the process exits.
+	done <-chan struct{}
 }
 
 type waiter interface {
@@ -379,6 +1210,12 @@ type waiter interface {
 }
 
 func startGoTest(ctx context.Context, dir string, args []string) (*proc, error) {
+	return startGoTestEnv(ctx, dir, args, nil)
+}
+
+// startGoTestEnv starts go test with env added to the environment of the
+// process.
+func startGoTestEnv(ctx context.Context, dir string, args []string, env []string) (*proc, error) {
 	if len(args) == 0 {
 		return nil, errors.New("missing command to run")
 	}
@@ -386,8 +1223,14 @@ func startGoTest(ctx context.Context, dir string, args []string) (*proc, error)
 	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	cmd.Stdin = os.Stdin
 	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
-	p := proc{cmd: cmd}
+	p := proc{
+		cmd:          cmd,
+		processState: func() *os.ProcessState { return cmd.ProcessState },
+	}
 	log.Debugf("exec: %s", cmd.Args)
 	var err error
 	p.stdout, err = cmd.StdoutPipe()
@@ -398,12 +1241,15 @@ func startGoTest(ctx context.Context, dir string, args []string) (*proc, error)
 	if err != nil {
 		return nil, err
 	}
+	p.started = time.Now()
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to run %s: %w", strings.Join(cmd.Args, " "), err)
 	}
 	log.Debugf("go test pid: %d", cmd.Process.Pid)
 
+	p.pid = cmd.Process.Pid
 	ctx, cancel := context.WithCancel(ctx)
+	p.done = ctx.Done()
 	newSignalHandler(ctx, cmd.Process.Pid, &p)
 	p.cmd = &cancelWaiter{cancel: cancel, wrapped: p.cmd}
 	return &p, nil
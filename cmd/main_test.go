@@ -75,6 +75,78 @@ func TestOptions_Validate_FromFlags(t *testing.T) {
 			args:     []string{"--rerun-fails", "--packages=./...", "--", "-failfast"},
 			expected: "-failfast can not be used with --rerun-fails",
 		},
+		{
+			name:     "affected-by with raw command",
+			args:     []string{"--affected-by=main", "--raw-command", "--", "./test-all"},
+			expected: "--affected-by can not be used with --raw-command",
+		},
+		{
+			name:     "affected-by, go-test args, no packages flag",
+			args:     []string{"--affected-by=main", "--", "-count=1"},
+			expected: "the list of packages to test must be specified by the --packages flag",
+		},
+		{
+			name: "affected-by, go-test args, with packages flag",
+			args: []string{"--affected-by=main", "--packages=./...", "--", "-count=1"},
+		},
+		{
+			name:     "package-timeout with raw command",
+			args:     []string{"--package-timeout=./e2e/...=30m", "--raw-command", "--", "./test-all"},
+			expected: "--package-timeout can not be used with --raw-command",
+		},
+		{
+			name:     "package-timeout, go-test args, no packages flag",
+			args:     []string{"--package-timeout=./e2e/...=30m", "--", "-count=1"},
+			expected: "the list of packages to test must be specified by the --packages flag",
+		},
+		{
+			name:     "package-args with raw command",
+			args:     []string{"--package-args=./e2e/...=-tags=e2e", "--raw-command", "--", "./test-all"},
+			expected: "--package-args can not be used with --raw-command or --profile-dir",
+		},
+		{
+			name:     "package-args, go-test args, no packages flag",
+			args:     []string{"--package-args=./e2e/...=-tags=e2e", "--", "-count=1"},
+			expected: "the list of packages to test must be specified by the --packages flag",
+		},
+		{
+			name:     "env-group without patterns",
+			args:     []string{"--env-group=pg=DB=postgres"},
+			expected: "--env-group pg must have at least one package pattern",
+		},
+		{
+			name:     "env-parallel without env-group",
+			args:     []string{"--env-parallel"},
+			expected: "--env-parallel requires --env-group",
+		},
+		{
+			name:     "unknown format-truncate",
+			args:     []string{"--format-truncate", "left"},
+			expected: "invalid --format-truncate",
+		},
+		{
+			name:     "negative running-footer",
+			args:     []string{"--running-footer=-1"},
+			expected: "--running-footer must not be negative",
+		},
+		{
+			name:     "quiet and verbose",
+			args:     []string{"-q", "-v"},
+			expected: "--quiet can not be used with --verbose",
+		},
+		{
+			name: "double verbose",
+			args: []string{"-vv", "--format", "pkgname"},
+		},
+		{
+			name:     "unknown min-captured-log-level",
+			args:     []string{"--min-captured-log-level", "loud"},
+			expected: "invalid --min-captured-log-level",
+		},
+		{
+			name: "min-captured-log-level",
+			args: []string{"--min-captured-log-level", "WARN"},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -152,6 +224,24 @@ func TestGoTestCmdArgs(t *testing.T) {
 		},
 		expected: []string{"./script", "-test.timeout=20m", "-run=TestOne|TestTwo", "./fails"},
 	})
+	run(t, "raw command with placeholders, first run", testCase{
+		opts: &options{
+			rawCommand: true,
+			args:       []string{"make", "test", "RUN={run}", "{runFlag}", "{pkg}"},
+		},
+		expected: []string{"make", "test", "RUN="},
+	})
+	run(t, "raw command with placeholders, with rerunOpts", testCase{
+		opts: &options{
+			rawCommand: true,
+			args:       []string{"make", "test", "RUN={run}", "PKG={pkg}", "{runFlag}"},
+		},
+		rerunOpts: rerunOpts{
+			runFlag: "-test.run=^TestOne$",
+			pkg:     "./fails",
+		},
+		expected: []string{"make", "test", "RUN=^TestOne$", "PKG=./fails", "-test.run=^TestOne$"},
+	})
 	run(t, "no args, with rerunOpts", testCase{
 		opts: &options{},
 		rerunOpts: rerunOpts{
@@ -293,6 +383,60 @@ func TestGoTestCmdArgs(t *testing.T) {
 		},
 		expected: []string{"go", "test", "-json", "-run=TestOne|TestTwo", "-count", "1", "-run", "./fails"},
 	})
+	run(t, "no args, with extra args", testCase{
+		opts: &options{},
+		rerunOpts: rerunOpts{
+			pkg:       "./pkg",
+			extraArgs: []string{"-cpuprofile=cpu.pprof"},
+		},
+		expected: []string{"go", "test", "-json", "-cpuprofile=cpu.pprof", "./pkg"},
+	})
+	run(t, "with args, with extra args", testCase{
+		opts: &options{
+			args:     []string{"-count", "1", "-args", "-update"},
+			packages: []string{"./..."},
+		},
+		rerunOpts: rerunOpts{
+			pkg:       "./pkg",
+			extraArgs: []string{"-cpuprofile=cpu.pprof"},
+		},
+		expected: []string{
+			"go", "test", "-json", "-cpuprofile=cpu.pprof", "-count", "1", "./pkg", "-args", "-update",
+		},
+	})
+	run(t, "no args, with timeout flag and packages", testCase{
+		opts: &options{},
+		rerunOpts: rerunOpts{
+			packages:    []string{"./e2e", "./e2e/api"},
+			timeoutFlag: "-timeout=30m0s",
+		},
+		expected: []string{"go", "test", "-json", "-timeout=30m0s", "./e2e", "./e2e/api"},
+	})
+	run(t, "with args, with timeout flag", testCase{
+		opts: &options{
+			args:     []string{"-count", "1", "-timeout", "5m", "-v"},
+			packages: []string{"./..."},
+		},
+		rerunOpts: rerunOpts{
+			pkg:         "./e2e",
+			timeoutFlag: "-timeout=30m0s",
+		},
+		expected: []string{"go", "test", "-json", "-timeout=30m0s", "-count", "1", "-v", "./e2e"},
+	})
+	run(t, "with args, with package args", testCase{
+		opts: &options{
+			args:     []string{"-count", "1", "-timeout", "5m"},
+			packages: []string{"./..."},
+		},
+		rerunOpts: rerunOpts{
+			packages:    []string{"./integration/db"},
+			packageArgs: []string{"-tags=integration", "-timeout=20m"},
+		},
+		expected: []string{
+			"go", "test", "-json", "-count", "1", "-timeout", "5m",
+			"-tags=integration", "-timeout=20m", "./integration/db",
+		},
+	})
 }
 
 func runCase(t *testing.T, name string, fn func(t *testing.T)) {
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// goModule is a Go module in a multi-module repository.
+type goModule struct {
+	// Path is the module path from the module directive in go.mod.
+	Path string
+	// Dir is the directory that contains the go.mod file.
+	Dir string
+}
+
+type goModules []goModule
+
+// forPackage returns the module that contains the package, using the module
+// with the longest path that is a prefix of the package import path.
+func (m goModules) forPackage(pkg string) (goModule, bool) {
+	var result goModule
+	var found bool
+	for _, mod := range m {
+		if pkg != mod.Path && !strings.HasPrefix(pkg, mod.Path+"/") {
+			continue
+		}
+		if !found || len(mod.Path) > len(result.Path) {
+			result, found = mod, true
+		}
+	}
+	return result, found
+}
+
+// forDir returns the module with the longest directory that contains dir.
+func (m goModules) forDir(dir string) (goModule, bool) {
+	if len(m) == 0 {
+		return goModule{}, false
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return goModule{}, false
+	}
+	var result goModule
+	var found bool
+	for _, mod := range m {
+		if dir != mod.Dir && !strings.HasPrefix(dir, mod.Dir+string(filepath.Separator)) {
+			continue
+		}
+		if !found || len(mod.Dir) > len(result.Dir) {
+			result, found = mod, true
+		}
+	}
+	return result, found
+}
+
+// dir returns the directory of the module that contains the package, or an
+// empty string if the package is not in any of the modules.
+func (m goModules) dir(pkg string) string {
+	mod, _ := m.forPackage(pkg)
+	return mod.Dir
+}
+
+// junitProperties returns the go.module property for the testsuite of the
+// package.
+func (m goModules) junitProperties(pkg string) []junitxml.JUnitProperty {
+	mod, ok := m.forPackage(pkg)
+	if !ok {
+		return nil
+	}
+	return []junitxml.JUnitProperty{{Name: "go.module", Value: mod.Path}}
+}
+
+// modulePath returns the module path of the module that contains the package.
+// It is nil when there are no modules, so that the module is omitted from the
+// JSON summary.
+func (m goModules) modulePath() func(pkg string) string {
+	if len(m) == 0 {
+		return nil
+	}
+	return func(pkg string) string {
+		mod, _ := m.forPackage(pkg)
+		return mod.Path
+	}
+}
+
+// findModules returns the modules listed by the use directives in the go.work
+// file in root. If there is no go.work file, findModules returns the modules
+// of every go.mod file in root and its subdirectories, excluding directories
+// that are ignored by the go command.
+func findModules(root string) (goModules, error) {
+	dirs, err := readGoWorkUse(filepath.Join(root, "go.work"))
+	switch {
+	case os.IsNotExist(err):
+		dirs, err = findGoModDirs(root)
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	modules := make(goModules, 0, len(dirs))
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		path, err := readModulePath(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, goModule{Path: path, Dir: dir})
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Dir < modules[j].Dir
+	})
+	return modules, nil
+}
+
+func findGoModDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != root && (name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() == "go.mod" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// readGoWorkUse returns the directories from the use directives in a go.work
+// file.
+func readGoWorkUse(filename string) ([]string, error) {
+	var dirs []string
+	var inBlock bool
+	err := scanModFile(filename, func(fields []string) error {
+		switch {
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock:
+			dirs = append(dirs, fields[0])
+		case fields[0] == "use" && len(fields) > 1 && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "use" && len(fields) > 1:
+			dirs = append(dirs, fields[1])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, dir := range dirs {
+		if unquoted, err := strconv.Unquote(dir); err == nil {
+			dirs[i] = unquoted
+		}
+	}
+	return dirs, nil
+}
+
+// readModulePath returns the path from the module directive in a go.mod file.
+func readModulePath(filename string) (string, error) {
+	var path string
+	err := scanModFile(filename, func(fields []string) error {
+		if path == "" && fields[0] == "module" && len(fields) > 1 {
+			path = fields[1]
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		path = unquoted
+	}
+	if path == "" {
+		return "", fmt.Errorf("%v: missing module directive", filename)
+	}
+	return path, nil
+}
+
+// scanModFile calls fn with the fields of each line in a go.mod or go.work
+// file, excluding comments and empty lines.
+func scanModFile(filename string, fn func(fields []string) error) error {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := fn(fields); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// runWithModules runs 'go test' once in the directory of each module, because
+// a pattern like ./... does not match the packages in nested modules. The
+// returned exitErr is the error from the last 'go test' that did not exit
+// successfully.
+func runWithModules(
+	ctx context.Context,
+	opts *options,
+	cfg testjson.ScanConfig,
+) (exec *testjson.Execution, exitErr error, err error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, nil, err
+	}
+	opts.modules, err = findModules(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find modules: %w", err)
+	}
+	if len(opts.modules) == 0 {
+		return nil, nil, fmt.Errorf("no go.work or go.mod files found in %v", root)
+	}
+
+	exec = cfg.Execution
+	for _, mod := range opts.modules {
+		log.Debugf("testing module %v in %v", mod.Path, mod.Dir)
+		goTestProc, err := startGoTestFn(ctx, mod.Dir, goTestCmdArgs(opts, rerunOpts{}))
+		if err != nil {
+			return exec, nil, err
+		}
+		opts.stuck.watch(goTestProc, cfg.Stop)
+		cfg.Stdout = goTestProc.stdout
+		cfg.Stderr = goTestProc.stderr
+		cfg.Execution = exec
+		exec, err = testjson.ScanTestOutput(cfg)
+		if err != nil {
+			return exec, nil, err
+		}
+		if err := goTestProc.cmd.Wait(); err != nil {
+			exitErr = err
+		}
+		recordUsage(opts, goTestProc, "")
+		if err := opts.stuck.abortErr(); err != nil {
+			return exec, nil, err
+		}
+		if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
+			return exec, exitError{num: signalExitCode + int(signum)}, nil
+		}
+	}
+	return exec, exitErr, nil
+}
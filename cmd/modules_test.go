@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestFindModules_GoWork(t *testing.T) {
+	dir := fs.NewDir(t, "modules",
+		fs.WithFile("go.work", `go 1.18
+
+use ./tools // build tools
+use (
+	.
+	"./api"
+)
+`),
+		fs.WithFile("go.mod", "module example.com/root\n"),
+		fs.WithDir("api", fs.WithFile("go.mod", "// API\nmodule \"example.com/root/api\"\n\ngo 1.18\n")),
+		fs.WithDir("tools", fs.WithFile("go.mod", "module example.com/tools\n")),
+		fs.WithDir("unused", fs.WithFile("go.mod", "module example.com/unused\n")))
+
+	modules, err := findModules(dir.Path())
+	assert.NilError(t, err)
+	expected := goModules{
+		{Path: "example.com/root", Dir: dir.Path()},
+		{Path: "example.com/root/api", Dir: dir.Join("api")},
+		{Path: "example.com/tools", Dir: dir.Join("tools")},
+	}
+	assert.DeepEqual(t, modules, expected)
+}
+
+func TestFindModules_NestedGoMod(t *testing.T) {
+	dir := fs.NewDir(t, "modules",
+		fs.WithFile("go.mod", "module example.com/root\n"),
+		fs.WithDir("api", fs.WithFile("go.mod", "module example.com/root/api\n")),
+		fs.WithDir("testdata", fs.WithFile("go.mod", "module example.com/fixture\n")),
+		fs.WithDir("vendor", fs.WithFile("go.mod", "module example.com/vendored\n")),
+		fs.WithDir(".cache", fs.WithFile("go.mod", "module example.com/cache\n")))
+
+	modules, err := findModules(dir.Path())
+	assert.NilError(t, err)
+	expected := goModules{
+		{Path: "example.com/root", Dir: dir.Path()},
+		{Path: "example.com/root/api", Dir: dir.Join("api")},
+	}
+	assert.DeepEqual(t, modules, expected)
+}
+
+func TestFindModules_MissingModuleDirective(t *testing.T) {
+	dir := fs.NewDir(t, "modules", fs.WithFile("go.mod", "go 1.18\n"))
+	_, err := findModules(dir.Path())
+	assert.ErrorContains(t, err, "missing module directive")
+}
+
+func TestGoModules_ForPackage(t *testing.T) {
+	modules := goModules{
+		{Path: "example.com/root", Dir: "/src"},
+		{Path: "example.com/root/api", Dir: "/src/api"},
+	}
+	assert.Equal(t, modules.dir("example.com/root/api/v1"), "/src/api")
+	assert.Equal(t, modules.dir("example.com/root/apis"), "/src")
+	assert.Equal(t, modules.dir("example.com/root"), "/src")
+	assert.Equal(t, modules.dir("example.com/other"), "")
+	assert.Equal(t, modules.modulePath()("example.com/root/api"), "example.com/root/api")
+	assert.Assert(t, goModules(nil).modulePath() == nil)
+}
+
+func TestRun_AllModules(t *testing.T) {
+	dir := fs.NewDir(t, "modules",
+		fs.WithFile("go.mod", "module example.com/root\n"),
+		fs.WithDir("api", fs.WithFile("go.mod", "module example.com/root/api\n")))
+	defer env.ChangeWorkingDir(t, dir.Path())()
+
+	output := map[string]string{
+		dir.Path(): `{"Package": "example.com/root", "Action": "run", "Test": "TestRoot"}
+{"Package": "example.com/root", "Action": "pass", "Test": "TestRoot"}
+{"Package": "example.com/root", "Action": "pass"}
+`,
+		dir.Join("api"): `{"Package": "example.com/root/api", "Action": "run", "Test": "TestAPI"}
+{"Package": "example.com/root/api", "Action": "pass", "Test": "TestAPI"}
+{"Package": "example.com/root/api", "Action": "pass"}
+`,
+	}
+	var dirs []string
+	orig := startGoTestFn
+	startGoTestFn = func(ctx context.Context, dir string, args []string) (*proc, error) {
+		dirs = append(dirs, dir)
+		assert.DeepEqual(t, args, []string{"go", "test", "-json", "./..."})
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(output[dir]),
+			stderr: bytes.NewReader(nil),
+		}, nil
+	}
+	defer func() {
+		startGoTestFn = orig
+	}()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:          "testname",
+		allModules:      true,
+		summaryJSONFile: dir.Join("summary.json"),
+		stdout:          out,
+		stderr:          os.Stderr,
+		hideSummary:     newHideSummaryValue(),
+	}
+	assert.NilError(t, run(opts))
+	assert.DeepEqual(t, dirs, []string{dir.Path(), dir.Join("api")})
+	assert.Assert(t, strings.Contains(out.String(), "DONE 2 tests"), out.String())
+
+	summary, err := ioutil.ReadFile(dir.Join("summary.json"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(summary), `"module": "example.com/root/api"`), string(summary))
+}
+
+func TestGoModules_ForDir(t *testing.T) {
+	dir := fs.NewDir(t, "modules", fs.WithDir("api", fs.WithDir("v1")), fs.WithDir("apis"))
+	modules := goModules{
+		{Path: "example.com/root", Dir: dir.Path()},
+		{Path: "example.com/root/api", Dir: dir.Join("api")},
+	}
+	mod, ok := modules.forDir(dir.Join("api", "v1"))
+	assert.Assert(t, ok)
+	assert.Equal(t, mod.Path, "example.com/root/api")
+
+	mod, ok = modules.forDir(dir.Join("apis"))
+	assert.Assert(t, ok)
+	assert.Equal(t, mod.Path, "example.com/root")
+
+	_, ok = modules.forDir(os.TempDir())
+	assert.Assert(t, !ok)
+}
+
+func TestModuleAffectedPackages(t *testing.T) {
+	dir := fs.NewDir(t, "module",
+		fs.WithFile("go.mod", "module example.com/ws/api\n\ngo 1.13\n"),
+		fs.WithDir("a",
+			fs.WithFile("a.go", "package a\n\nconst A = 1\n"),
+			fs.WithFile("a_test.go", "package a\n")),
+		fs.WithDir("b",
+			fs.WithFile("b.go", "package b\n\nimport \"example.com/ws/api/a\"\n\nconst B = a.A\n"),
+			fs.WithFile("b_test.go", "package b\n")),
+		fs.WithDir("c",
+			fs.WithFile("c.go", "package c\n"),
+			fs.WithFile("c_test.go", "package c\n")),
+		fs.WithDir("notests", fs.WithFile("notests.go", "package notests\n")))
+	defer env.Patch(t, "GOWORK", "off")()
+	defer env.Patch(t, "GOFLAGS", "-mod=mod")()
+	mod := goModule{Path: "example.com/ws/api", Dir: dir.Path()}
+
+	pkgs, err := moduleAffectedPackages(mod, dir.Join("a"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pkgs, []string{"example.com/ws/api/a", "example.com/ws/api/b"})
+
+	pkgs, err = moduleAffectedPackages(mod, dir.Join("notests"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pkgs, []string{"./notests"})
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/shlex"
+)
+
+// packageArgs are the extra go test args used for the packages which match
+// pattern.
+type packageArgs struct {
+	pattern string
+	args    []string
+}
+
+// packageArgsMap is a flag.Value that maps a package pattern to the extra go
+// test args used for the packages which match the pattern. Unlike
+// --package-timeout, each value is a single mapping, because the args may
+// contain commas.
+type packageArgsMap struct {
+	raw   []string
+	items []packageArgs
+	// byPackage is set by runWithPackageGroups to the args of each package
+	// that matched one of the patterns.
+	byPackage map[string][]string
+}
+
+func (m *packageArgsMap) String() string {
+	return strings.Join(m.raw, ",")
+}
+
+func (m *packageArgsMap) Set(raw string) error {
+	parts := strings.SplitN(strings.TrimSpace(raw), "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("invalid package args %q, must be pattern=args", raw)
+	}
+	args, err := shlex.Split(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid args in %q: %w", raw, err)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("invalid package args %q, args must not be empty", raw)
+	}
+	m.items = append(m.items, packageArgs{pattern: parts[0], args: args})
+	m.raw = append(m.raw, raw)
+	return nil
+}
+
+func (m *packageArgsMap) Type() string {
+	return "pattern=args"
+}
+
+func (m *packageArgsMap) enabled() bool {
+	return len(m.items) > 0
+}
+
+// argsFor returns the extra go test args of the package, or nil if the
+// package did not match any pattern.
+func (m *packageArgsMap) argsFor(pkg string) []string {
+	return m.byPackage[pkg]
+}
+
+// listFlags returns the go list flags for the extra args, so that go list
+// finds the packages that are only built with the build tags in args.
+func listFlags(args []string) []string {
+	if v, ok := argValue(args, "tags"); ok {
+		return []string{"-tags=" + v}
+	}
+	return nil
+}
+
+// splitGroupsByArgs splits each group into groups of packages that have the
+// same extra args, so that each group can be run by a single go test command.
+// The order of the groups, and of the packages in a group, is preserved.
+func splitGroupsByArgs(groups []packageGroup, argsFor func(pkg string) []string) []packageGroup {
+	var result []packageGroup
+	for _, group := range groups {
+		index := make(map[string]int)
+		for _, pkg := range group.packages {
+			args := argsFor(pkg)
+			key := strings.Join(args, "\x00")
+			i, ok := index[key]
+			if !ok {
+				i = len(result)
+				index[key] = i
+				result = append(result, packageGroup{timeout: group.timeout, args: args})
+			}
+			result[i].packages = append(result[i].packages, pkg)
+		}
+	}
+	return result
+}
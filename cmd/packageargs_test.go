@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestPackageArgsMap(t *testing.T) {
+	var m packageArgsMap
+	assert.Assert(t, !m.enabled())
+	assert.NilError(t, m.Set("./integration/...=-tags=integration -timeout=20m"))
+	assert.NilError(t, m.Set(`./e2e=-run "TestE2E,TestSmoke"`))
+	assert.Equal(t, m.String(), `./integration/...=-tags=integration -timeout=20m,./e2e=-run "TestE2E,TestSmoke"`)
+	expected := []packageArgs{
+		{pattern: "./integration/...", args: []string{"-tags=integration", "-timeout=20m"}},
+		{pattern: "./e2e", args: []string{"-run", "TestE2E,TestSmoke"}},
+	}
+	assert.DeepEqual(t, m.items, expected, gocmp.AllowUnexported(packageArgs{}))
+	assert.Assert(t, m.enabled())
+
+	assert.ErrorContains(t, m.Set("./e2e"), `invalid package args "./e2e"`)
+	assert.ErrorContains(t, m.Set("=-v"), `invalid package args "=-v"`)
+	assert.ErrorContains(t, m.Set("./e2e= "), `args must not be empty`)
+	assert.ErrorContains(t, m.Set(`./e2e=-run "Test`), `invalid args in`)
+}
+
+func TestListFlags(t *testing.T) {
+	assert.DeepEqual(t, listFlags([]string{"-v", "-tags", "integration"}), []string{"-tags=integration"})
+	assert.DeepEqual(t, listFlags([]string{"-tags=a,b", "-v"}), []string{"-tags=a,b"})
+	assert.Assert(t, listFlags([]string{"-timeout=20m"}) == nil)
+}
+
+func TestSplitGroupsByArgs(t *testing.T) {
+	groups := []packageGroup{
+		{timeout: time.Hour, packages: []string{"ex.com/e2e/api", "ex.com/e2e/web"}},
+		{packages: []string{"ex.com/a", "ex.com/it/db", "ex.com/b", "ex.com/it/queue", "ex.com/e2e"}},
+	}
+	byPackage := map[string][]string{
+		"ex.com/e2e/api":  {"-tags=e2e"},
+		"ex.com/it/db":    {"-tags=integration"},
+		"ex.com/it/queue": {"-tags=integration"},
+		"ex.com/e2e":      {"-tags=e2e"},
+	}
+	argsFor := func(pkg string) []string {
+		return byPackage[pkg]
+	}
+
+	expected := []packageGroup{
+		{timeout: time.Hour, args: []string{"-tags=e2e"}, packages: []string{"ex.com/e2e/api"}},
+		{timeout: time.Hour, packages: []string{"ex.com/e2e/web"}},
+		{packages: []string{"ex.com/a", "ex.com/b"}},
+		{args: []string{"-tags=integration"}, packages: []string{"ex.com/it/db", "ex.com/it/queue"}},
+		{args: []string{"-tags=e2e"}, packages: []string{"ex.com/e2e"}},
+	}
+	assert.DeepEqual(t, splitGroupsByArgs(groups, argsFor), expected, gocmp.AllowUnexported(packageGroup{}))
+}
+
+func TestLoadConfigFile_PackageArgs(t *testing.T) {
+	dir := fs.NewDir(t, "config", fs.WithFile(configFilename, `
+package-args:
+  ./integration/...: -tags=integration -timeout=20m
+`))
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, loadConfigFile(flags, dir.Join(configFilename)))
+	expected := []packageArgs{
+		{pattern: "./integration/...", args: []string{"-tags=integration", "-timeout=20m"}},
+	}
+	assert.DeepEqual(t, opts.packageArgs.items, expected, gocmp.AllowUnexported(packageArgs{}))
+}
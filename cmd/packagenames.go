@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+var packageNameFormatValues = "relative, last-N, module-trim"
+
+// packageNameFormatValue is the value of --short-package-names. The zero value
+// uses the default package names.
+type packageNameFormatValue struct {
+	name string
+	// lastN is the number of path elements used by the last-N format.
+	lastN int
+}
+
+func (f *packageNameFormatValue) Set(val string) error {
+	switch {
+	case val == "relative" || val == "module-trim":
+		*f = packageNameFormatValue{name: val}
+		return nil
+	case strings.HasPrefix(val, "last-"):
+		n, err := strconv.Atoi(strings.TrimPrefix(val, "last-"))
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid value: %v, N in last-N must be a positive number", val)
+		}
+		*f = packageNameFormatValue{name: val, lastN: n}
+		return nil
+	}
+	return fmt.Errorf("invalid value: %v, must be one of: "+packageNameFormatValues, val)
+}
+
+func (f *packageNameFormatValue) Type() string {
+	return "format"
+}
+
+func (f *packageNameFormatValue) String() string {
+	return f.name
+}
+
+// setupPackageNames sets the format of the package names printed by the
+// formatters, the summary, and the other output of gotestsum. When
+// --short-package-names is set it is also used for the testsuite name and
+// testcase classname in the JUnit XML file, unless those fields have their
+// own flag.
+func setupPackageNames(opts *options) error {
+	fn, err := packageNameFormatFunc(opts.packageNameFormat)
+	if err != nil {
+		return err
+	}
+	if fn == nil {
+		return nil
+	}
+	for _, field := range []*junitFieldFormatValue{
+		opts.junitTestSuiteNameFormat,
+		opts.junitTestCaseClassnameFormat,
+	} {
+		if field != nil && !field.set {
+			field.value = fn
+		}
+	}
+	opts.formatOptions.PackageName = fn
+	return nil
+}
+
+// packageName returns the name of pkgpath printed by gotestsum, using the
+// format from --short-package-names.
+func (o *options) packageName(pkgpath string) string {
+	if fn := o.formatOptions.PackageName; fn != nil {
+		return fn(pkgpath)
+	}
+	return testjson.RelativePackagePath(pkgpath)
+}
+
+// packageNameFormatFunc returns the function that formats a package path, or
+// nil for the default format.
+func packageNameFormatFunc(f packageNameFormatValue) (junitxml.FormatFunc, error) {
+	switch {
+	case f.name == "relative":
+		return testjson.RelativePackagePath, nil
+	case f.lastN > 0:
+		return lastPathElements(f.lastN), nil
+	case f.name == "module-trim":
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		modules, err := findModules(cwd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find modules for --short-package-names: %w", err)
+		}
+		return moduleTrim(modules), nil
+	}
+	return nil, nil
+}
+
+// lastPathElements returns a function that returns the last n elements of a
+// package path.
+func lastPathElements(n int) junitxml.FormatFunc {
+	return func(pkg string) string {
+		parts := strings.Split(pkg, "/")
+		if len(parts) <= n {
+			return pkg
+		}
+		return strings.Join(parts[len(parts)-n:], "/")
+	}
+}
+
+// moduleTrim returns a function that removes the path of the module that
+// contains the package from the package path. The package at the root of a
+// module is named by the last element of the module path, so that the root
+// packages of different modules have different names.
+func moduleTrim(modules goModules) junitxml.FormatFunc {
+	return func(pkg string) string {
+		mod, ok := modules.forPackage(pkg)
+		switch {
+		case !ok:
+			return pkg
+		case pkg == mod.Path:
+			return path.Base(pkg)
+		}
+		return strings.TrimPrefix(pkg, mod.Path+"/")
+	}
+}
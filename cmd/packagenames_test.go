@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPackageNameFormatValue_Set(t *testing.T) {
+	var v packageNameFormatValue
+	assert.NilError(t, v.Set("last-2"))
+	assert.Equal(t, v.lastN, 2)
+	assert.Equal(t, v.String(), "last-2")
+
+	assert.NilError(t, v.Set("module-trim"))
+	assert.Equal(t, v.lastN, 0)
+
+	assert.ErrorContains(t, v.Set("last-0"), "must be a positive number")
+	assert.ErrorContains(t, v.Set("last-two"), "must be a positive number")
+	assert.ErrorContains(t, v.Set("short"), "must be one of: relative, last-N, module-trim")
+}
+
+func TestLastPathElements(t *testing.T) {
+	fn := lastPathElements(2)
+	assert.Equal(t, fn("example.com/org/repo/pkg/sub"), "pkg/sub")
+	assert.Equal(t, fn("example.com/repo"), "example.com/repo")
+	assert.Equal(t, fn("fmt"), "fmt")
+}
+
+func TestModuleTrim(t *testing.T) {
+	modules := goModules{
+		{Path: "example.com/repo", Dir: filepath.FromSlash("/src/repo")},
+		{Path: "example.com/repo/tools", Dir: filepath.FromSlash("/src/repo/tools")},
+	}
+	fn := moduleTrim(modules)
+	assert.Equal(t, fn("example.com/repo/pkg/sub"), "pkg/sub")
+	assert.Equal(t, fn("example.com/repo/tools/lint"), "lint")
+	assert.Equal(t, fn("example.com/repo/tools"), "tools")
+	assert.Equal(t, fn("example.com/repo"), "repo")
+	assert.Equal(t, fn("example.com/other/pkg"), "example.com/other/pkg")
+}
+
+func TestSetupPackageNames(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		_, opts := setupFlags("gotestsum")
+		assert.NilError(t, setupPackageNames(opts))
+		assert.Assert(t, opts.formatOptions.PackageName == nil)
+		assert.Equal(t, opts.packageName("fmt"), "fmt")
+		assert.Assert(t, opts.junitTestSuiteNameFormat.Value() == nil)
+		assert.Assert(t, opts.junitTestCaseClassnameFormat.Value() == nil)
+	})
+
+	t.Run("used by junit fields without a flag", func(t *testing.T) {
+		flags, opts := setupFlags("gotestsum")
+		assert.NilError(t, flags.Parse([]string{
+			"--short-package-names=last-1",
+			"--junitfile-testcase-classname=full",
+		}))
+		assert.NilError(t, setupPackageNames(opts))
+
+		assert.Equal(t, opts.packageName("example.com/repo/pkg"), "pkg")
+		assert.Equal(t, opts.formatOptions.PackageName("example.com/repo/pkg"), "pkg")
+		assert.Equal(t, opts.junitTestSuiteNameFormat.Value()("example.com/repo/pkg"), "pkg")
+		assert.Assert(t, opts.junitTestCaseClassnameFormat.Value() == nil)
+	})
+}
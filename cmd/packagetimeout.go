@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// packageTimeout is the go test -timeout used for the packages which match
+// pattern.
+type packageTimeout struct {
+	pattern string
+	timeout time.Duration
+}
+
+// packageTimeoutMap is a flag.Value that maps a package pattern to the
+// go test -timeout used for the packages which match the pattern.
+type packageTimeoutMap struct {
+	raw      []string
+	timeouts []packageTimeout
+	// byPackage is set by runWithPackageGroups to the timeout of each
+	// package that matched one of the patterns.
+	byPackage map[string]time.Duration
+}
+
+func (m *packageTimeoutMap) String() string {
+	return strings.Join(m.raw, ",")
+}
+
+func (m *packageTimeoutMap) Set(raw string) error {
+	items, err := readAsCSV(raw)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("invalid package timeout %q, must be pattern=duration", item)
+		}
+		timeout, err := time.ParseDuration(parts[1])
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid duration in %q, must be a positive duration (ex: 10m)", item)
+		}
+		m.timeouts = append(m.timeouts, packageTimeout{pattern: parts[0], timeout: timeout})
+	}
+	m.raw = append(m.raw, raw)
+	return nil
+}
+
+func (m *packageTimeoutMap) Type() string {
+	return "mapping"
+}
+
+func (m *packageTimeoutMap) enabled() bool {
+	return len(m.timeouts) > 0
+}
+
+// timeoutArg returns the go test -timeout flag for the package, or an empty
+// string if the package did not match any pattern.
+func (m *packageTimeoutMap) timeoutArg(pkg string) string {
+	timeout, ok := m.byPackage[pkg]
+	if !ok {
+		return ""
+	}
+	return "-timeout=" + timeout.String()
+}
+
+// packageGroup is a list of packages that are run by a single go test
+// command.
+type packageGroup struct {
+	timeout time.Duration
+	// args are the extra go test args of the packages from --package-args.
+	args []string
+	// env are the environment variables of the packages from --env-group.
+	env      []string
+	packages []string
+}
+
+// groupPackagesByTimeout places each package in pkgs into the group of the
+// first pattern that matched the package. matches contains the packages
+// matched by each pattern in timeouts. Packages which do not match any pattern
+// are returned in the last group, which has no timeout. Empty groups are
+// omitted.
+func groupPackagesByTimeout(
+	pkgs []string,
+	timeouts []packageTimeout,
+	matches [][]string,
+) []packageGroup {
+	groups := make([]packageGroup, len(timeouts)+1)
+	for i, pt := range timeouts {
+		groups[i].timeout = pt.timeout
+	}
+
+	index := make(map[string]int)
+	for i := len(matches) - 1; i >= 0; i-- {
+		for _, pkg := range matches[i] {
+			index[pkg] = i
+		}
+	}
+	for _, pkg := range pkgs {
+		i, ok := index[pkg]
+		if !ok {
+			i = len(timeouts)
+		}
+		groups[i].packages = append(groups[i].packages, pkg)
+	}
+
+	result := groups[:0]
+	for _, group := range groups {
+		if len(group.packages) > 0 {
+			result = append(result, group)
+		}
+	}
+	return result
+}
+
+// runWithPackageGroups runs 'go test' once for each group of packages that
+// have the same timeout, the same --package-args, and the same --env-group,
+// so that a slow package does not require a long timeout for every package,
+// and the flags and environment of one package are not used for every
+// package. The returned exitErr is the error from the last 'go test' that did
+// not exit successfully.
+func runWithPackageGroups(
+	ctx context.Context,
+	opts *options,
+	cfg testjson.ScanConfig,
+) (exec *testjson.Execution, exitErr error, err error) {
+	groups, err := listPackageGroups(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.envParallel {
+		return runPackageGroupsParallel(ctx, opts, cfg, groups)
+	}
+
+	exec = cfg.Execution
+	for _, group := range groups {
+		goTestProc, err := startGoTestWithEnv(ctx, "", goTestCmdArgs(opts, group.rerunOpts()), group.env)
+		if err != nil {
+			return exec, nil, err
+		}
+		opts.stuck.watch(goTestProc, cfg.Stop)
+		cfg.Stdout = goTestProc.stdout
+		cfg.Stderr = goTestProc.stderr
+		cfg.Execution = exec
+		exec, err = testjson.ScanTestOutput(cfg)
+		if err != nil {
+			return exec, nil, err
+		}
+		if err := goTestProc.cmd.Wait(); err != nil {
+			exitErr = err
+		}
+		recordUsage(opts, goTestProc, singleItem(group.packages))
+		if err := opts.stuck.abortErr(); err != nil {
+			return exec, nil, err
+		}
+		if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
+			return exec, exitError{num: signalExitCode + int(signum)}, nil
+		}
+	}
+	return exec, exitErr, nil
+}
+
+// runPackageGroupsParallel runs 'go test' for all of the groups at the same
+// time. The output of every 'go test' is read as a single stream, like the
+// output of the packages in one 'go test'.
+func runPackageGroupsParallel(
+	ctx context.Context,
+	opts *options,
+	cfg testjson.ScanConfig,
+	groups []packageGroup,
+) (exec *testjson.Execution, exitErr error, err error) {
+	procs := make([]*proc, 0, len(groups))
+	var stdouts, stderrs []io.Reader
+	for _, group := range groups {
+		goTestProc, err := startGoTestWithEnv(ctx, "", goTestCmdArgs(opts, group.rerunOpts()), group.env)
+		if err != nil {
+			return cfg.Execution, nil, err
+		}
+		opts.stuck.watch(goTestProc, cfg.Stop)
+		procs = append(procs, goTestProc)
+		stdouts = append(stdouts, goTestProc.stdout)
+		stderrs = append(stderrs, goTestProc.stderr)
+	}
+	cfg.Stdout = mergeLines(stdouts)
+	cfg.Stderr = mergeLines(stderrs)
+	exec, err = testjson.ScanTestOutput(cfg)
+	if err != nil {
+		return exec, nil, err
+	}
+	var signum int32
+	for i, goTestProc := range procs {
+		if err := goTestProc.cmd.Wait(); err != nil {
+			exitErr = err
+		}
+		recordUsage(opts, goTestProc, singleItem(groups[i].packages))
+		if n := atomic.LoadInt32(&goTestProc.signal); n != 0 {
+			signum = n
+		}
+	}
+	if err := opts.stuck.abortErr(); err != nil {
+		return exec, nil, err
+	}
+	if signum != 0 {
+		return exec, exitError{num: signalExitCode + int(signum)}, nil
+	}
+	return exec, exitErr, nil
+}
+
+// listPackageGroups returns the groups of packages to test, using go list to
+// find the packages that match the patterns of --package-timeout,
+// --package-args, and --env-group.
+func listPackageGroups(opts *options) ([]packageGroup, error) {
+	pkgs, err := goListPackages(cmdArgPackageList(opts, rerunOpts{}, "./..."))
+	if err != nil {
+		return nil, err
+	}
+	timeouts := opts.packageTimeouts.timeouts
+	matches := make([][]string, len(timeouts))
+	for i, pt := range timeouts {
+		if matches[i], err = goListPackages([]string{pt.pattern}); err != nil {
+			return nil, fmt.Errorf("--package-timeout pattern %v: %w", pt.pattern, err)
+		}
+	}
+	opts.packageArgs.byPackage = make(map[string][]string)
+	for i := len(opts.packageArgs.items) - 1; i >= 0; i-- {
+		item := opts.packageArgs.items[i]
+		matched, err := goListPackages(append(listFlags(item.args), item.pattern))
+		if err != nil {
+			return nil, fmt.Errorf("--package-args pattern %v: %w", item.pattern, err)
+		}
+		for _, pkg := range matched {
+			opts.packageArgs.byPackage[pkg] = item.args
+		}
+		// packages that are only built with the build tags in args are not
+		// in the list of all packages
+		pkgs = appendMissing(pkgs, matched)
+	}
+	opts.envGroups.byPackage = make(map[string]int)
+	for i := len(opts.envGroups.groups) - 1; i >= 0; i-- {
+		g := opts.envGroups.groups[i]
+		matched, err := goListPackages(g.patterns)
+		if err != nil {
+			return nil, fmt.Errorf("--env-group %v: %w", g.name, err)
+		}
+		for _, pkg := range matched {
+			opts.envGroups.byPackage[pkg] = i
+		}
+	}
+
+	groups := groupPackagesByTimeout(pkgs, timeouts, matches)
+	groups = splitGroupsByArgs(groups, opts.packageArgs.argsFor)
+	groups = splitGroupsByEnv(groups, &opts.envGroups)
+	opts.packageTimeouts.byPackage = make(map[string]time.Duration)
+	for _, group := range groups {
+		for _, pkg := range group.packages {
+			if group.timeout > 0 {
+				opts.packageTimeouts.byPackage[pkg] = group.timeout
+			}
+		}
+	}
+	return groups, nil
+}
+
+func (g packageGroup) rerunOpts() rerunOpts {
+	rOpts := rerunOpts{packages: g.packages, packageArgs: g.args}
+	if g.timeout > 0 {
+		rOpts.timeoutFlag = "-timeout=" + g.timeout.String()
+	}
+	return rOpts
+}
+
+// appendMissing returns items with each of add that is not already in items.
+func appendMissing(items, add []string) []string {
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		seen[item] = true
+	}
+	for _, item := range add {
+		if !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// singleItem returns the only item in items, or an empty string if items does
+// not have exactly one item.
+func singleItem(items []string) string {
+	if len(items) != 1 {
+		return ""
+	}
+	return items[0]
+}
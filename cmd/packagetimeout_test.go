@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+)
+
+func TestPackageTimeoutMap(t *testing.T) {
+	var m packageTimeoutMap
+	assert.Assert(t, !m.enabled())
+	assert.NilError(t, m.Set("./e2e/...=30m,example.com/slow=2m"))
+	assert.NilError(t, m.Set("./db=5m"))
+	assert.Equal(t, m.String(), "./e2e/...=30m,example.com/slow=2m,./db=5m")
+	expected := []packageTimeout{
+		{pattern: "./e2e/...", timeout: 30 * time.Minute},
+		{pattern: "example.com/slow", timeout: 2 * time.Minute},
+		{pattern: "./db", timeout: 5 * time.Minute},
+	}
+	assert.DeepEqual(t, m.timeouts, expected, cmpPackageTimeout)
+	assert.Assert(t, m.enabled())
+
+	assert.ErrorContains(t, m.Set("./e2e"), `invalid package timeout "./e2e"`)
+	assert.ErrorContains(t, m.Set("=10m"), `invalid package timeout "=10m"`)
+	assert.ErrorContains(t, m.Set("./e2e=soon"), `invalid duration in "./e2e=soon"`)
+	assert.ErrorContains(t, m.Set("./e2e=0s"), `invalid duration in "./e2e=0s"`)
+}
+
+var cmpPackageTimeout = gocmp.AllowUnexported(packageTimeout{})
+
+func TestPackageTimeoutMap_TimeoutArg(t *testing.T) {
+	m := packageTimeoutMap{
+		byPackage: map[string]time.Duration{"example.com/e2e": 30 * time.Minute},
+	}
+	assert.Equal(t, m.timeoutArg("example.com/e2e"), "-timeout=30m0s")
+	assert.Equal(t, m.timeoutArg("example.com/unit"), "")
+}
+
+func TestGroupPackagesByTimeout(t *testing.T) {
+	pkgs := []string{"ex.com/a", "ex.com/e2e", "ex.com/e2e/api", "ex.com/b", "ex.com/db"}
+	timeouts := []packageTimeout{
+		{pattern: "./e2e/api", timeout: time.Hour},
+		{pattern: "./e2e/...", timeout: 30 * time.Minute},
+		{pattern: "./other/...", timeout: time.Minute},
+		{pattern: "./db", timeout: 5 * time.Minute},
+	}
+	matches := [][]string{
+		{"ex.com/e2e/api"},
+		{"ex.com/e2e", "ex.com/e2e/api"},
+		nil,
+		{"ex.com/db"},
+	}
+
+	groups := groupPackagesByTimeout(pkgs, timeouts, matches)
+	expected := []packageGroup{
+		{timeout: time.Hour, packages: []string{"ex.com/e2e/api"}},
+		{timeout: 30 * time.Minute, packages: []string{"ex.com/e2e"}},
+		{timeout: 5 * time.Minute, packages: []string{"ex.com/db"}},
+		{packages: []string{"ex.com/a", "ex.com/b"}},
+	}
+	assert.DeepEqual(t, groups, expected, gocmp.AllowUnexported(packageGroup{}))
+}
+
+func TestRun_PackageTimeout_BadPattern(t *testing.T) {
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{"--package-timeout", "./nope/...=1m"}))
+	out := new(bytes.Buffer)
+	opts.stdout = out
+	opts.stderr = new(bytes.Buffer)
+
+	err := run(opts)
+	assert.ErrorContains(t, err, "./nope/...")
+	assert.Assert(t, strings.Contains(out.String(), "DONE 0 tests"), out.String())
+}
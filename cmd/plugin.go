@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/shlex"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// pluginProtocolVersion is incremented when there is a change to the data
+// sent to plugins that is not backwards compatible.
+const pluginProtocolVersion = "1"
+
+// formatPluginPrefix is the prefix of a --format value that runs a plugin
+// as the formatter.
+const formatPluginPrefix = "exec:"
+
+// pluginProcess is a subprocess that receives every TestEvent as a line of
+// JSON on stdin. The stdin of the process is closed when the test run ends.
+//
+// Plugins are used to add formatters and report writers without changing
+// gotestsum.
+type pluginProcess struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	closed bool
+	// writeErr is the first error from writing to stdin. No more events are
+	// sent after a write fails.
+	writeErr error
+}
+
+func startPlugin(opts *options, command []string) (*pluginProcess, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("missing plugin command")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	// hide any io.ReaderFrom implementation, so that output is copied with
+	// Write, and does not conflict with other writes to stdout.
+	cmd.Stdout = struct{ io.Writer }{opts.stdout}
+	cmd.Stderr = struct{ io.Writer }{opts.stderr}
+	cmd.Env = append(os.Environ(),
+		"GOTESTSUM_PLUGIN_PROTOCOL="+pluginProtocolVersion,
+		"GOTESTSUM_FORMAT="+opts.format,
+		"GOTESTSUM_JSONFILE="+opts.jsonFile,
+		"GOTESTSUM_JUNITFILE="+opts.junitFile,
+	)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("exec: %s", cmd.Args)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", command[0], err)
+	}
+	return &pluginProcess{name: command[0], cmd: cmd, stdin: stdin}, nil
+}
+
+// Format sends the event to the plugin. Format implements
+// testjson.EventFormatter so that a plugin can be used as the formatter.
+func (p *pluginProcess) Format(event testjson.TestEvent, _ *testjson.Execution) error {
+	if p.writeErr != nil {
+		return p.writeErr
+	}
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := p.stdin.Write(append(raw, '\n')); err != nil {
+		p.writeErr = fmt.Errorf("failed to send event to plugin %s: %w", p.name, err)
+		return p.writeErr
+	}
+	return nil
+}
+
+// close stdin, and wait for the process to exit.
+func (p *pluginProcess) close() error {
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	_ = p.stdin.Close()
+	if err := p.cmd.Wait(); err != nil {
+		return fmt.Errorf("plugin %s failed: %w", p.name, err)
+	}
+	return nil
+}
+
+// startPlugins starts the formatter plugin, when the --format is a plugin, and
+// every --plugin. The plugins are stored on opts so that finishRun can wait
+// for them to exit before printing the summary.
+func startPlugins(opts *options) (formatter *pluginProcess, err error) {
+	if strings.HasPrefix(opts.format, formatPluginPrefix) {
+		command, err := shlex.Split(strings.TrimPrefix(opts.format, formatPluginPrefix))
+		if err != nil {
+			return nil, misuseError(fmt.Errorf("invalid format plugin command: %w", err))
+		}
+		formatter, err = startPlugin(opts, command)
+		if err != nil {
+			return nil, err
+		}
+		opts.plugins = append(opts.plugins, formatter)
+	}
+	for _, command := range opts.pluginCommands.Value() {
+		plugin, err := startPlugin(opts, command)
+		if err != nil {
+			return formatter, err
+		}
+		opts.plugins = append(opts.plugins, plugin)
+	}
+	return formatter, nil
+}
+
+// sendToPlugins sends the event to every plugin that is not the formatter. A
+// plugin that fails to receive an event is logged once, and does not stop
+// the test run.
+func sendToPlugins(plugins []*pluginProcess, event testjson.TestEvent, formatter *pluginProcess) {
+	for _, plugin := range plugins {
+		if plugin == formatter || plugin.writeErr != nil {
+			continue
+		}
+		if err := plugin.Format(event, nil); err != nil {
+			log.Warnf("%v", err)
+		}
+	}
+}
+
+// closePlugins closes every plugin, and returns the first error.
+func closePlugins(plugins []*pluginProcess) error {
+	var firstErr error
+	for _, plugin := range plugins {
+		if err := plugin.close(); err != nil {
+			log.Errorf("%v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
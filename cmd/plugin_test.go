@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/skip"
+)
+
+func TestPlugin_ReceivesEvents(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "plugin command requires sh")
+	dir := fs.NewDir(t, "plugin")
+	out := new(bytes.Buffer)
+	opts := &options{
+		format: "testname",
+		stdout: out,
+		stderr: out,
+	}
+	assert.NilError(t, opts.pluginCommands.Set("sh -c 'cat > "+dir.Join("events")+"'"))
+
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"pass","Package":"pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"pass","Package":"pkg","Elapsed":0.2}
+`),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, closePlugins(opts.plugins))
+	assert.NilError(t, handler.Close())
+	assert.Equal(t, exec.Total(), 1)
+
+	raw, err := ioutil.ReadFile(dir.Join("events"))
+	assert.NilError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	assert.Equal(t, len(lines), 3)
+	var event testjson.TestEvent
+	assert.NilError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, event.Action, testjson.ActionPass)
+	assert.Equal(t, event.Test, "TestOne")
+
+	// the built-in formatter still prints the events
+	assert.Assert(t, strings.Contains(out.String(), "PASS pkg.TestOne"), out.String())
+}
+
+func TestPlugin_AsFormatter(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "plugin command requires sh")
+	out := new(bytes.Buffer)
+	opts := &options{
+		format: `exec:sh -c 'echo "plugin protocol $GOTESTSUM_PLUGIN_PROTOCOL"; wc -l'`,
+		stdout: out,
+		stderr: out,
+	}
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"fail","Package":"pkg","Test":"TestOne","Elapsed":0.1}
+`),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, closePlugins(opts.plugins))
+	assert.Equal(t, strings.Join(strings.Fields(out.String()), " "), "plugin protocol 1 2")
+}
+
+func TestPlugin_Failed(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "plugin command requires sh")
+	opts := &options{stdout: new(bytes.Buffer), stderr: new(bytes.Buffer)}
+	assert.NilError(t, opts.pluginCommands.Set("sh -c 'exit 3'"))
+	_, err := startPlugins(opts)
+	assert.NilError(t, err)
+	assert.ErrorContains(t, closePlugins(opts.plugins), "plugin sh failed: exit status 3")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"gotest.tools/gotestsum/internal/junitxml"
+)
+
+// polarionOptions are the flags used by --junitfile-schema polarion.
+type polarionOptions struct {
+	projectID    string
+	testRunID    string
+	testRunTitle string
+}
+
+// polarionTestCaseID is the mapping used for the requirements of a test when
+// --junitfile-schema is polarion and --requirement-property is not set. The
+// requirements are expected to be the IDs of Polarion test cases.
+var polarionTestCaseID = junitxml.RequirementProperty{Name: "polarion-testcase-id", Format: "{id}"}
+
+// polarionProperties returns the properties of the testsuites element read by
+// the Polarion xUnit importer. The labels of the run are custom fields of
+// the test run.
+func polarionProperties(opts *options) []junitxml.JUnitProperty {
+	title := opts.polarion.testRunTitle
+	if title == "" {
+		title = opts.junitProjectName
+	}
+	props := []junitxml.JUnitProperty{
+		{Name: "polarion-project-id", Value: opts.polarion.projectID},
+		{Name: "polarion-lookup-method", Value: "id"},
+	}
+	if opts.polarion.testRunID != "" {
+		props = append(props, junitxml.JUnitProperty{Name: "polarion-testrun-id", Value: opts.polarion.testRunID})
+	}
+	if title != "" {
+		props = append(props, junitxml.JUnitProperty{Name: "polarion-testrun-title", Value: title})
+	}
+	for _, l := range opts.labels.labels {
+		props = append(props, junitxml.JUnitProperty{Name: "polarion-custom-" + l.key, Value: l.value})
+	}
+	return props
+}
+
+// applyPolarionConfig changes cfg to include the properties used by the
+// Polarion xUnit importer.
+func applyPolarionConfig(opts *options, cfg *junitxml.Config) {
+	if cfg.Schema != junitxml.SchemaPolarion {
+		return
+	}
+	cfg.Properties = polarionProperties(opts)
+	if len(cfg.RequirementProperties) == 0 {
+		cfg.RequirementProperties = []junitxml.RequirementProperty{polarionTestCaseID}
+	}
+}
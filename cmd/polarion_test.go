@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/v3/assert"
+)
+
+func TestJUnitConfig_Polarion(t *testing.T) {
+	_, opts := setupFlags("gotestsum")
+	opts.goEnv = &goEnvironment{Version: "go7.7.7"}
+	assert.NilError(t, opts.junitSchema.Set("polarion"))
+	assert.NilError(t, opts.labels.Set("build=1234"))
+	opts.junitProjectName = "nightly"
+	opts.polarion.projectID = "PROJ"
+
+	cfg := junitConfig(opts)
+	assert.DeepEqual(t, cfg.Properties, []junitxml.JUnitProperty{
+		{Name: "polarion-project-id", Value: "PROJ"},
+		{Name: "polarion-lookup-method", Value: "id"},
+		{Name: "polarion-testrun-title", Value: "nightly"},
+		{Name: "polarion-custom-build", Value: "1234"},
+	})
+	assert.DeepEqual(t, cfg.RequirementProperties, []junitxml.RequirementProperty{
+		{Name: "polarion-testcase-id", Format: "{id}"},
+	})
+
+	opts.polarion.testRunID = "run-1"
+	opts.polarion.testRunTitle = "Release 1.2"
+	assert.NilError(t, opts.requirementProperties.Set("polarion-testcase-id=PROJ-{number}"))
+	cfg = junitConfig(opts)
+	assert.DeepEqual(t, cfg.Properties[2:4], []junitxml.JUnitProperty{
+		{Name: "polarion-testrun-id", Value: "run-1"},
+		{Name: "polarion-testrun-title", Value: "Release 1.2"},
+	})
+	assert.DeepEqual(t, cfg.RequirementProperties, []junitxml.RequirementProperty{
+		{Name: "polarion-testcase-id", Format: "PROJ-{number}"},
+	})
+}
+
+func TestOptionsValidate_PolarionProjectID(t *testing.T) {
+	_, opts := setupFlags("gotestsum")
+	assert.NilError(t, opts.junitSchema.Set("polarion"))
+	assert.Error(t, opts.Validate(), "--polarion-project-id is required by --junitfile-schema polarion")
+
+	opts.polarion.projectID = "PROJ"
+	assert.NilError(t, opts.Validate())
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// profileSlowestPackages is the number of packages printed by --profile-top.
+const profileSlowestPackages = 3
+
+// runWithProfiles runs 'go test' once for each package, because the profile
+// flags can only be used with a single package. The profile files for each
+// package are written to opts.profileDir. The returned exitErr is the error
+// from the last 'go test' that did not exit successfully.
+func runWithProfiles(
+	ctx context.Context,
+	opts *options,
+	cfg testjson.ScanConfig,
+) (exec *testjson.Execution, exitErr error, err error) {
+	if err := os.MkdirAll(opts.profileDir, 0o755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	dir, err := filepath.Abs(opts.profileDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkgs, err := goListPackages(cmdArgPackageList(opts, rerunOpts{}, "./..."))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts.profiles = make(map[string]*jsonsummary.Profiles)
+	exec = cfg.Execution
+	for _, pkg := range pkgs {
+		profiles := profileFiles(dir, pkg)
+		opts.profiles[pkg] = profiles
+
+		rOpts := rerunOpts{
+			pkg: pkg,
+			extraArgs: []string{
+				"-cpuprofile=" + profiles.CPU,
+				"-memprofile=" + profiles.Memory,
+				"-o=" + profiles.Binary,
+			},
+		}
+		goTestProc, err := startGoTestFn(ctx, "", goTestCmdArgs(opts, rOpts))
+		if err != nil {
+			return exec, nil, err
+		}
+		opts.stuck.watch(goTestProc, cfg.Stop)
+		cfg.Stdout = goTestProc.stdout
+		cfg.Stderr = goTestProc.stderr
+		cfg.Execution = exec
+		exec, err = testjson.ScanTestOutput(cfg)
+		if err != nil {
+			return exec, nil, err
+		}
+		if err := goTestProc.cmd.Wait(); err != nil {
+			exitErr = err
+		}
+		recordUsage(opts, goTestProc, pkg)
+		if err := opts.stuck.abortErr(); err != nil {
+			return exec, nil, err
+		}
+		if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
+			return exec, exitError{num: signalExitCode + int(signum)}, nil
+		}
+	}
+	removeEmptyProfiles(opts.profiles)
+	return exec, exitErr, nil
+}
+
+func profileFiles(dir string, pkg string) *jsonsummary.Profiles {
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(pkg)
+	return &jsonsummary.Profiles{
+		CPU:    filepath.Join(dir, name+".cpu.pprof"),
+		Memory: filepath.Join(dir, name+".mem.pprof"),
+		Binary: filepath.Join(dir, name+".test"),
+	}
+}
+
+// removeEmptyProfiles removes the packages which did not write a profile,
+// because they had no test files.
+func removeEmptyProfiles(profiles map[string]*jsonsummary.Profiles) {
+	for pkg, p := range profiles {
+		if _, err := os.Stat(p.CPU); err != nil {
+			delete(profiles, pkg)
+		}
+	}
+}
+
+func goListPackages(patterns []string) ([]string, error) {
+	args := append([]string{"list"}, patterns...)
+	log.Debugf("exec: go %v", args)
+	out, err := exec.Command("go", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to list packages: %w%s", err, formatCommandOutput(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// printProfileTop prints the functions with the most CPU time in the slowest
+// packages.
+func printProfileTop(opts *options, execution *testjson.Execution) {
+	if opts.profileTop <= 0 || len(opts.profiles) == 0 || execution == nil {
+		return
+	}
+	var pkgs []string
+	for pkg := range opts.profiles {
+		if execution.Package(pkg) != nil {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return execution.Package(pkgs[i]).Elapsed() > execution.Package(pkgs[j]).Elapsed()
+	})
+	if len(pkgs) > profileSlowestPackages {
+		pkgs = pkgs[:profileSlowestPackages]
+	}
+
+	for _, pkg := range pkgs {
+		profiles := opts.profiles[pkg]
+		fmt.Fprintf(opts.stdout, "\n=== CPU profile: %s (%s)\n",
+			opts.packageName(pkg), execution.Package(pkg).Elapsed())
+		args := []string{"tool", "pprof", "-top", fmt.Sprintf("-nodecount=%d", opts.profileTop),
+			profiles.Binary, profiles.CPU}
+		log.Debugf("exec: go %v", args)
+		cmd := exec.Command("go", args...)
+		cmd.Stdout = opts.stdout
+		if err := cmd.Run(); err != nil {
+			log.Warnf("Failed to print CPU profile for %v: %v", pkg, err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestProfileFiles(t *testing.T) {
+	dir := filepath.FromSlash("/out/profiles")
+	actual := profileFiles(dir, "example.com/project/pkg")
+	expected := &jsonsummary.Profiles{
+		CPU:    filepath.Join(dir, "example.com_project_pkg.cpu.pprof"),
+		Memory: filepath.Join(dir, "example.com_project_pkg.mem.pprof"),
+		Binary: filepath.Join(dir, "example.com_project_pkg.test"),
+	}
+	assert.DeepEqual(t, actual, expected)
+}
+
+func TestRun_Profiles_BadPattern(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{"--profile-dir", dir.Path(), "--packages", "./nope/..."}))
+	out := new(bytes.Buffer)
+	opts.stdout = out
+	opts.stderr = new(bytes.Buffer)
+
+	err := run(opts)
+	assert.ErrorContains(t, err, "./nope/...")
+	assert.Assert(t, strings.Contains(out.String(), "DONE 0 tests"), out.String())
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// project is a name for a group of packages, like the packages of one service
+// in a repository with many services.
+type project struct {
+	name     string
+	patterns []string
+}
+
+// projectsValue is a flag.Value that maps the name of a project to the
+// package patterns of the packages in the project. A pattern is a package
+// path, relative to the module in the current directory, like ./api, or all
+// the packages in a directory, like ./api/....
+type projectsValue struct {
+	raw      []string
+	projects []project
+}
+
+func (v *projectsValue) Set(raw string) error {
+	parts := strings.SplitN(raw, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("invalid project %q, must be name=pattern[,pattern]", raw)
+	}
+	patterns, err := readAsCSV(parts[1])
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSpace(parts[0])
+	var p *project
+	for i := range v.projects {
+		if v.projects[i].name == name {
+			p = &v.projects[i]
+		}
+	}
+	if p == nil {
+		v.projects = append(v.projects, project{name: name})
+		p = &v.projects[len(v.projects)-1]
+	}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return fmt.Errorf("project %v contains an empty pattern", name)
+		}
+		if pattern == "./" {
+			pattern = "."
+		}
+		p.patterns = append(p.patterns, strings.TrimPrefix(pattern, "./"))
+	}
+	v.raw = append(v.raw, raw)
+	return nil
+}
+
+func (v *projectsValue) String() string {
+	return strings.Join(v.raw, " ")
+}
+
+func (v *projectsValue) Type() string {
+	return "name=patterns"
+}
+
+// forPackage returns the name of the first project with a pattern that
+// matches the package, or an empty string if no project matches.
+func (v *projectsValue) forPackage(pkg string) string {
+	rel := testjson.RelativePackagePath(pkg)
+	for _, p := range v.projects {
+		for _, pattern := range p.patterns {
+			if matchPackagePattern(pattern, pkg) || matchPackagePattern(pattern, rel) {
+				return p.name
+			}
+		}
+	}
+	return ""
+}
+
+// summaryProject returns the function used to group packages by project in
+// the summary, or nil when no projects are defined.
+func (v *projectsValue) summaryProject() func(pkg string) string {
+	if len(v.projects) == 0 {
+		return nil
+	}
+	return v.forPackage
+}
+
+// junitProperties returns the project property for the testsuite of the
+// package.
+func (v *projectsValue) junitProperties(pkg string) []junitxml.JUnitProperty {
+	name := v.forPackage(pkg)
+	if name == "" {
+		return nil
+	}
+	return []junitxml.JUnitProperty{{Name: "project", Value: name}}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestProjectsValue(t *testing.T) {
+	var v projectsValue
+	assert.NilError(t, v.Set("api=./services/api/...,./lib/api"))
+	assert.NilError(t, v.Set("web=./web/..."))
+	assert.NilError(t, v.Set("api=./tools/apigen"))
+	assert.NilError(t, v.Set("root=./"))
+
+	pkgPrefix := "gotest.tools/gotestsum"
+	assert.Equal(t, v.forPackage(pkgPrefix+"/services/api/users"), "api")
+	assert.Equal(t, v.forPackage(pkgPrefix+"/lib/api"), "api")
+	assert.Equal(t, v.forPackage(pkgPrefix+"/lib/api/sub"), "")
+	assert.Equal(t, v.forPackage(pkgPrefix+"/tools/apigen"), "api")
+	assert.Equal(t, v.forPackage(pkgPrefix+"/web"), "web")
+	assert.Equal(t, v.forPackage(pkgPrefix), "root")
+	assert.Equal(t, v.forPackage("example.com/other"), "")
+
+	assert.DeepEqual(t, v.junitProperties(pkgPrefix+"/web"),
+		[]junitxml.JUnitProperty{{Name: "project", Value: "web"}})
+	assert.Assert(t, v.junitProperties("example.com/other") == nil)
+
+	assert.ErrorContains(t, v.Set("./api/..."), "must be name=pattern")
+	assert.ErrorContains(t, v.Set("api=./a,,./b"), "contains an empty pattern")
+}
+
+func TestProjectsValue_SummaryProject(t *testing.T) {
+	var v projectsValue
+	assert.Assert(t, v.summaryProject() == nil)
+	assert.NilError(t, v.Set("api=./api/..."))
+	assert.Assert(t, v.summaryProject() != nil)
+}
+
+func TestLoadConfigFile_Projects(t *testing.T) {
+	dir := fs.NewDir(t, "config", fs.WithFile(configFilename, `
+projects:
+  api:
+    - ./services/api/...
+  web: ./web/...
+`))
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse(nil))
+	assert.NilError(t, loadConfigFile(flags, dir.Join(configFilename)))
+
+	expected := []project{
+		{name: "api", patterns: []string{"services/api/..."}},
+		{name: "web", patterns: []string{"web/..."}},
+	}
+	assert.DeepEqual(t, opts.projects.projects, expected, cmpProject)
+}
+
+var cmpProject = gocmp.AllowUnexported(project{})
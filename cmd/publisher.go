@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// publisher sends the result of a test run to some other system, like a test
+// management tool, after the run. summary is the JSON summary of the run, in
+// the format written by --summary-jsonfile.
+type publisher interface {
+	name() string
+	publish(summary []byte, attempt int) error
+}
+
+// publishResult is the outcome of running a publisher, which is printed in
+// the summary at the end of the run.
+type publishResult struct {
+	name     string
+	attempts int
+	err      error
+}
+
+// publisherRetryDelay is multiplied by the number of the attempt to get the
+// time to wait before a publisher is retried.
+var publisherRetryDelay = time.Second
+
+// commandPublisher is a publisher that runs a command, created with
+// --publisher. The JSON summary of the run is sent to the stdin of the command.
+type commandPublisher struct {
+	opts    *options
+	exec    *testjson.Execution
+	command []string
+}
+
+func (p commandPublisher) name() string {
+	return strings.Join(p.command, " ")
+}
+
+func (p commandPublisher) publish(summary []byte, attempt int) error {
+	cmd := exec.Command(p.command[0], p.command[1:]...)
+	cmd.Stdin = bytes.NewReader(summary)
+	cmd.Stdout = p.opts.stdout
+	cmd.Stderr = p.opts.stderr
+	cmd.Env = append(os.Environ(),
+		"GOTESTSUM_PLUGIN_PROTOCOL="+pluginProtocolVersion,
+		"GOTESTSUM_PUBLISH_ATTEMPT="+strconv.Itoa(attempt),
+		"GOTESTSUM_JSONFILE="+p.opts.jsonFile,
+		"GOTESTSUM_JUNITFILE="+p.opts.junitFile,
+		fmt.Sprintf("TESTS_TOTAL=%d", p.exec.Total()),
+		fmt.Sprintf("TESTS_FAILED=%d", len(p.exec.Failed())),
+	)
+	cmd.Env = append(cmd.Env, vcs(p.opts).environ()...)
+	cmd.Env = append(cmd.Env, p.opts.labels.environ()...)
+	log.Debugf("exec: %s", cmd.Args)
+	return cmd.Run()
+}
+
+func publishers(opts *options, exec *testjson.Execution, exitErr error) []publisher {
+	var result []publisher
+	for _, command := range opts.publisherCommands.Value() {
+		if len(command) == 0 {
+			continue
+		}
+		result = append(result, commandPublisher{opts: opts, exec: exec, command: command})
+	}
+	result = append(result, webhookPublishers(opts, exec, exitErr)...)
+	return append(result, teamsPublishers(opts, exec)...)
+}
+
+// runPublishers sends the JSON summary of the run to every publisher. A
+// publisher that fails is retried up to --publisher-retries times. The
+// results, including the results of publishers that ran when the tests
+// started, are printed to stdout. A failed publisher does not change the exit
+// status of the run.
+func runPublishers(opts *options, exec *testjson.Execution, exitErr error) error {
+	pubs := publishers(opts, exec, exitErr)
+	results := opts.publishResults
+	if len(pubs) == 0 && len(results) == 0 {
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	if err := jsonsummary.Write(buf, exec, jsonSummaryConfig(opts)); err != nil {
+		return err
+	}
+
+	for _, pub := range pubs {
+		results = append(results, runPublisher(pub, buf.Bytes(), opts.publisherRetries))
+	}
+	printPublishResults(opts.stdout, results)
+	return nil
+}
+
+func runPublisher(pub publisher, summary []byte, retries int) publishResult {
+	result := publishResult{name: pub.name()}
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * publisherRetryDelay)
+		}
+		result.attempts = attempt
+		result.err = pub.publish(summary, attempt)
+		if result.err == nil {
+			return result
+		}
+		log.Warnf("publisher %v failed (attempt %d of %d): %v",
+			result.name, attempt, retries+1, result.err)
+	}
+	return result
+}
+
+func printPublishResults(out io.Writer, results []publishResult) {
+	var failed int
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+		}
+	}
+	fmt.Fprintf(out, "\n=== Publish: %d publishers, %d failed\n", len(results), failed)
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(out, "=== PUBLISH FAILED: %s (%s): %v\n", r.name, attempts(r.attempts), r.err)
+			continue
+		}
+		fmt.Fprintf(out, "=== PUBLISHED: %s (%s)\n", r.name, attempts(r.attempts))
+	}
+}
+
+func attempts(n int) string {
+	if n == 1 {
+		return "1 attempt"
+	}
+	return fmt.Sprintf("%d attempts", n)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/skip"
+)
+
+func TestRunPublishers(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "publisher command requires sh")
+	patchPublisherRetryDelay(t)
+	patchVCS(t, nil)
+	dir := fs.NewDir(t, "publisher")
+
+	out := new(bytes.Buffer)
+	opts := &options{stdout: out, stderr: out, publisherRetries: 2}
+	patchGoEnvironment(t, goEnvironment{Version: "go7.7.7"})
+	assert.NilError(t, opts.publisherCommands.Set(
+		"sh -c 'cat > "+dir.Join("summary.json")+"'"))
+	assert.NilError(t, opts.publisherCommands.Set(
+		"sh -c 'echo attempt $GOTESTSUM_PUBLISH_ATTEMPT; exit 3'"))
+
+	exec := scanForPublisher(t)
+	assert.NilError(t, runPublishers(opts, exec, nil))
+
+	raw, err := ioutil.ReadFile(dir.Join("summary.json"))
+	assert.NilError(t, err)
+	var summary jsonsummary.Summary
+	assert.NilError(t, json.Unmarshal(raw, &summary))
+	assert.Equal(t, summary.Total, 1)
+
+	expected := `attempt 1
+attempt 2
+attempt 3
+
+=== Publish: 2 publishers, 1 failed
+=== PUBLISHED: sh -c cat > ` + dir.Join("summary.json") + ` (1 attempt)
+=== PUBLISH FAILED: sh -c echo attempt $GOTESTSUM_PUBLISH_ATTEMPT; exit 3 (3 attempts): exit status 3
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRunPublisher_SucceedsAfterRetry(t *testing.T) {
+	patchPublisherRetryDelay(t)
+	pub := &fakePublisher{failures: 1}
+	result := runPublisher(pub, []byte("{}"), 3)
+	assert.NilError(t, result.err)
+	assert.Equal(t, result.attempts, 2)
+	assert.DeepEqual(t, pub.attempts, []int{1, 2})
+
+	pub = &fakePublisher{failures: 5}
+	result = runPublisher(pub, []byte("{}"), 0)
+	assert.Error(t, result.err, "upload failed")
+	assert.Equal(t, result.attempts, 1)
+}
+
+func TestRunPublishers_NoPublishers(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &options{stdout: out}
+	assert.NilError(t, runPublishers(opts, scanForPublisher(t), nil))
+	assert.Equal(t, out.String(), "")
+}
+
+type fakePublisher struct {
+	failures int
+	attempts []int
+}
+
+func (p *fakePublisher) name() string {
+	return "fake"
+}
+
+func (p *fakePublisher) publish(_ []byte, attempt int) error {
+	p.attempts = append(p.attempts, attempt)
+	if len(p.attempts) <= p.failures {
+		return errors.New("upload failed")
+	}
+	return nil
+}
+
+func scanForPublisher(t *testing.T) *testjson.Execution {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"pass","Package":"pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"pass","Package":"pkg","Elapsed":0.2}
+`),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func patchPublisherRetryDelay(t *testing.T) {
+	orig := publisherRetryDelay
+	publisherRetryDelay = 0
+	t.Cleanup(func() {
+		publisherRetryDelay = orig
+	})
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces the value of an environment variable that matches
+// a --redact-env pattern.
+const redactedValue = "[REDACTED]"
+
+// defaultRedactEnv are the patterns of environment variable names that are
+// always redacted, because they are commonly used for secrets.
+var defaultRedactEnv = []string{
+	"*TOKEN*",
+	"*SECRET*",
+	"*PASSWORD*",
+	"*PASSWD*",
+	"*CREDENTIAL*",
+	"*API_KEY*",
+	"*PRIVATE_KEY*",
+}
+
+// envPatternsValue is a flag.Value for a comma separated list of environment
+// variable names, or glob patterns like SECRET_*.
+type envPatternsValue []string
+
+func (v *envPatternsValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *envPatternsValue) Set(raw string) error {
+	for _, pattern := range strings.Split(raw, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			*v = append(*v, pattern)
+		}
+	}
+	return nil
+}
+
+func (v *envPatternsValue) Type() string {
+	return "list"
+}
+
+// isGlob returns true if the name of an environment variable is a pattern.
+func isGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// matchEnvName returns true if name matches any of the patterns. Names are
+// compared without case.
+func matchEnvName(patterns []string, name string) bool {
+	name = strings.ToUpper(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactEnv returns true if the value of the environment variable must not
+// be written to reports.
+func redactEnv(opts *options, name string) bool {
+	return matchEnvName(defaultRedactEnv, name) || matchEnvName(opts.redactEnv, name)
+}
+
+// scrubRule replaces the text that matches pattern in the output of tests.
+type scrubRule struct {
+	pattern *regexp.Regexp
+	// replacement may refer to the groups in pattern, like ${1}.
+	replacement string
+}
+
+// defaultScrubRules match the formats of common tokens and credentials.
+var defaultScrubRules = []scrubRule{
+	// Authorization: Bearer <token>
+	{regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9\-._~+/]{8,}=*`), "${1}" + redactedValue},
+	// Authorization: Basic <credentials>
+	{regexp.MustCompile(`(?i)(\bbasic\s+)[A-Za-z0-9+/]{8,}=*`), "${1}" + redactedValue},
+	// user:password@ in a URL
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`), "${1}" + redactedValue + "@"},
+	// GitHub tokens
+	{regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`), redactedValue},
+	// GitLab personal access tokens
+	{regexp.MustCompile(`\bglpat-[A-Za-z0-9\-_]{20,}\b`), redactedValue},
+	// Slack tokens
+	{regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`), redactedValue},
+	// AWS access key IDs
+	{regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), redactedValue},
+	// JSON Web Tokens
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`), redactedValue},
+}
+
+// scrubPatternsValue is a flag.Value for the --scrub-pattern regular
+// expressions. It may be set more than once.
+type scrubPatternsValue struct {
+	raw   []string
+	rules []scrubRule
+}
+
+func (v *scrubPatternsValue) String() string {
+	return strings.Join(v.raw, " ")
+}
+
+func (v *scrubPatternsValue) Set(raw string) error {
+	expr, err := regexp.Compile(raw)
+	if err != nil {
+		return fmt.Errorf("invalid scrub pattern %q: %w", raw, err)
+	}
+	v.raw = append(v.raw, raw)
+	v.rules = append(v.rules, scrubRule{pattern: expr, replacement: redactedValue})
+	return nil
+}
+
+func (v *scrubPatternsValue) Type() string {
+	return "regexp"
+}
+
+// minScrubValueLength is the shortest value of a redacted environment
+// variable that is removed from the output. Shorter values, like 1 or true,
+// would remove too much of the output.
+const minScrubValueLength = 6
+
+// newScrubber returns the function that removes secrets from the output of
+// the tests, or nil when there is nothing to remove. The secrets are the
+// text that matches the default rules, unless --no-default-scrub is set,
+// the --scrub-pattern rules, and the values of the environment variables
+// that are redacted by --redact-env.
+func newScrubber(opts *options, environ []string) func(string) string {
+	var rules []scrubRule
+	if !opts.noDefaultScrub {
+		rules = append(rules, defaultScrubRules...)
+	}
+	rules = append(rules, opts.scrubPatterns.rules...)
+
+	var values []string
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i <= 0 || len(kv)-i-1 < minScrubValueLength || !redactEnv(opts, kv[:i]) {
+			continue
+		}
+		values = append(values, kv[i+1:])
+	}
+	// replace the longest values first, in case one value contains another.
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	if len(rules) == 0 && len(values) == 0 {
+		return nil
+	}
+	return func(text string) string {
+		for _, value := range values {
+			text = strings.Replace(text, value, redactedValue, -1)
+		}
+		for _, rule := range rules {
+			text = rule.pattern.ReplaceAllString(text, rule.replacement)
+		}
+		return text
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/textreport"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// templateFileValue is a flag.Value that reads and parses a text/template
+// from a file, so that an invalid template is reported before the tests run.
+type templateFileValue struct {
+	filename string
+	tmpl     *template.Template
+}
+
+func (v *templateFileValue) Set(filename string) error {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read report template: %w", err)
+	}
+	tmpl, err := textreport.Parse(filepath.Base(filename), string(raw))
+	if err != nil {
+		return fmt.Errorf("failed to parse report template: %w", err)
+	}
+	v.filename = filename
+	v.tmpl = tmpl
+	return nil
+}
+
+func (v *templateFileValue) String() string {
+	return v.filename
+}
+
+func (v *templateFileValue) Type() string {
+	return "filename"
+}
+
+// writeTemplateReport writes the report from --report-template to the
+// --report-output file, or to stdout when no file is set.
+func writeTemplateReport(opts *options, execution *testjson.Execution) error {
+	if opts.reportTemplate.tmpl == nil {
+		return nil
+	}
+	var out io.Writer = opts.stdout
+	if opts.reportOutput != "" {
+		_ = os.MkdirAll(filepath.Dir(opts.reportOutput), 0o755)
+		fh, err := os.Create(opts.reportOutput)
+		if err != nil {
+			return fmt.Errorf("failed to open report file: %v", err)
+		}
+		defer func() {
+			if err := fh.Close(); err != nil {
+				log.Errorf("Failed to close report file: %v", err)
+			}
+		}()
+		out = fh
+	}
+	return textreport.Write(out, opts.reportTemplate.tmpl, execution, textreport.Config{
+		Environment:            runEnvironment(opts),
+		Labels:                 opts.labels.value(),
+		RequirementURLTemplate: opts.requirementURLTemplate,
+		VCS:                    vcs(opts).report(),
+		PackageName:            opts.formatOptions.PackageName,
+	})
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestTemplateFileValue(t *testing.T) {
+	dir := fs.NewDir(t, "report",
+		fs.WithFile("good.tmpl", "{{ .Total }} tests"),
+		fs.WithFile("bad.tmpl", "{{ .Total "))
+
+	var v templateFileValue
+	assert.ErrorContains(t, v.Set(dir.Join("missing.tmpl")), "failed to read report template")
+	assert.ErrorContains(t, v.Set(dir.Join("bad.tmpl")), "failed to parse report template")
+	assert.NilError(t, v.Set(dir.Join("good.tmpl")))
+	assert.Equal(t, v.String(), dir.Join("good.tmpl"))
+}
+
+func TestWriteTemplateReport(t *testing.T) {
+	patchGoEnvironment(t, goEnvironment{Version: "go7.7.7"})
+	dir := fs.NewDir(t, "report", fs.WithFile("report.tmpl",
+		`{{ .Total }} tests, {{ len .Failures }} failures, go {{ index .Environment "go.version" }}`))
+	exec := newExecFromTestData(t)
+
+	t.Run("stdout", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		opts := &options{stdout: buf}
+		assert.NilError(t, opts.reportTemplate.Set(dir.Join("report.tmpl")))
+		assert.NilError(t, writeTemplateReport(opts, exec))
+		assert.Equal(t, buf.String(), "59 tests, 12 failures, go go7.7.7")
+	})
+
+	t.Run("file", func(t *testing.T) {
+		opts := &options{reportOutput: dir.Join("out", "report.txt")}
+		assert.NilError(t, opts.reportTemplate.Set(dir.Join("report.tmpl")))
+		assert.NilError(t, writeTemplateReport(opts, exec))
+		raw, err := ioutil.ReadFile(opts.reportOutput)
+		assert.NilError(t, err)
+		assert.Equal(t, string(raw), "59 tests, 12 failures, go go7.7.7")
+	})
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/repro"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// resolveShuffleSeed replaces a --shuffle value of "on" with a seed, so that
+// every go test process uses the same seed, and the seed is known before the
+// tests are run.
+func resolveShuffleSeed(opts *options) {
+	if opts.shuffle == "on" {
+		opts.shuffle = strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+}
+
+func validateShuffle(value string) error {
+	switch value {
+	case "", "on", "off":
+		return nil
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+		return fmt.Errorf("--shuffle must be on, off, or an integer seed, not %q", value)
+	}
+	return nil
+}
+
+func hasShuffleArg(args []string) bool {
+	start, _ := argIndex("shuffle", args)
+	testStart, _ := argIndex("test.shuffle", args)
+	return start >= 0 || testStart >= 0
+}
+
+// shuffleEnabled returns true when --shuffle runs the tests in a random order.
+// The go test default, off, is the same as an empty value.
+func shuffleEnabled(opts *options) bool {
+	return opts.shuffle != "" && opts.shuffle != "off"
+}
+
+// shuffleArgs returns the go test flag for the --shuffle value.
+func shuffleArgs(opts *options) []string {
+	if !shuffleEnabled(opts) {
+		return nil
+	}
+	return []string{"-shuffle=" + opts.shuffle}
+}
+
+// summaryOptions returns the options used to print the summary. A command
+// to reproduce each failure is printed when --repro-command or --shuffle are
+// set.
+func summaryOptions(opts *options, exec *testjson.Execution) testjson.SummaryOptions {
+	summaryOpts := testjson.SummaryOptions{
+		Attachments:        opts.attachments.testCaseAttachments(),
+		SlowSetupThreshold: opts.setupThreshold,
+		Project:            opts.projects.summaryProject(),
+		FormatLine:         summaryFormatLine(opts),
+		PackageName:        opts.formatOptions.PackageName,
+	}
+	if opts.rawCommand || (!opts.reproCommand && !shuffleEnabled(opts)) {
+		return summaryOpts
+	}
+	args := reproArgs(opts)
+	summaryOpts.ReproCommand = func(tc testjson.TestCase) string {
+		seed := packageShuffleSeed(exec, tc.Package)
+		return repro.ShellQuote(repro.Command(tc.Package, tc.Test, seed, args))
+	}
+	return summaryOpts
+}
+
+func packageShuffleSeed(exec *testjson.Execution, name string) string {
+	if pkg := exec.Package(name); pkg != nil {
+		return pkg.ShuffleSeed()
+	}
+	return ""
+}
+
+// writeReproScript writes a shell script to opts.reproScript that runs every
+// test that failed again, with the same go test flags, GOFLAGS, and the
+// environment variables from --capture-env.
+func writeReproScript(opts *options, exec *testjson.Execution) error {
+	if opts.reproScript == "" {
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString("#!/bin/sh\n# Run the tests that failed in a previous gotestsum run.\n\n")
+
+	env := runEnvironment(opts)
+	if goflags := env["go.flags"]; goflags != "" {
+		fmt.Fprintf(buf, "export GOFLAGS=%s\n", repro.ShellQuote([]string{goflags}))
+	}
+	for _, name := range sortedStringKeys(env) {
+		if !strings.HasPrefix(name, "env.") {
+			continue
+		}
+		key := strings.TrimPrefix(name, "env.")
+		if redactEnv(opts, key) {
+			fmt.Fprintf(buf, "# %s is redacted, set it before running the script\n", key)
+			continue
+		}
+		fmt.Fprintf(buf, "export %s=%s\n", key, repro.ShellQuote([]string{env[name]}))
+	}
+
+	buf.WriteString("\nstatus=0\n")
+	args := reproArgs(opts)
+	seen := make(map[string]bool)
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		key := tc.Package + " " + tc.Test.Name()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		cmd := repro.Command(tc.Package, tc.Test, packageShuffleSeed(exec, tc.Package), args)
+		fmt.Fprintf(buf, "%s || status=1\n", repro.ShellQuote(cmd))
+	}
+	buf.WriteString("exit $status\n")
+
+	_ = os.MkdirAll(filepath.Dir(opts.reproScript), 0o755)
+	if err := ioutil.WriteFile(opts.reproScript, buf.Bytes(), 0o755); err != nil {
+		return fmt.Errorf("failed to write repro script: %w", err)
+	}
+	return nil
+}
+
+// goTestValueFlags are the go test flags, and the flags of the test binary,
+// that have a value which may be the next argument, ex: -run TestOne.
+var goTestValueFlags = []string{
+	"C", "asmflags", "bench", "benchtime", "blockprofile", "blockprofilerate",
+	"buildmode", "compiler", "count", "coverpkg", "covermode", "coverprofile",
+	"cpu", "cpuprofile", "exec", "fuzz", "fuzzcachedir", "fuzzminimizetime",
+	"fuzztime", "gccgoflags", "gcflags", "installsuffix", "ldflags", "list",
+	"memprofile", "memprofilerate", "mod", "modfile", "mutexprofile",
+	"mutexprofilefraction", "o", "outputdir", "overlay", "p", "parallel", "pgo",
+	"pkgdir", "run", "shuffle", "skip", "tags", "timeout", "toolexec", "trace",
+	"vet",
+}
+
+// reproArgs returns the go test flags from opts.args which are required to
+// reproduce a failure. Flags which are replaced by repro.Command, and the
+// list of packages, are removed.
+func reproArgs(opts *options) []string {
+	args := opts.args
+	var testBinaryArgs []string
+	if i := findPkgArgPosition(args); i < len(args) {
+		args, testBinaryArgs = args[:i], args[i:]
+	}
+
+	result := make([]string, 0, len(args)+len(testBinaryArgs))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			continue // a package
+		}
+		flag := []string{arg}
+		name := strings.TrimPrefix(strings.TrimLeft(strings.SplitN(arg, "=", 2)[0], "-"), "test.")
+		if !strings.Contains(arg, "=") && contains(goTestValueFlags, name) && i+1 < len(args) {
+			i++
+			flag = append(flag, args[i])
+		}
+		switch name {
+		case "json", "count", "run", "shuffle":
+			continue
+		}
+		result = append(result, flag...)
+	}
+	return append(result, testBinaryArgs...)
+}
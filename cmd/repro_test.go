@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestReproArgs(t *testing.T) {
+	type testCase struct {
+		name     string
+		opts     *options
+		expected []string
+	}
+	run := func(t *testing.T, tc testCase) {
+		assert.DeepEqual(t, reproArgs(tc.opts), tc.expected)
+	}
+	testCases := []testCase{
+		{
+			name:     "no args",
+			opts:     &options{},
+			expected: []string{},
+		},
+		{
+			name: "removes packages and replaced flags",
+			opts: &options{args: []string{
+				"-json", "-tags=integration", "-run", "TestOne", "-count=3", "-shuffle=on", "./...",
+			}},
+			expected: []string{"-tags=integration"},
+		},
+		{
+			name: "keeps test binary args",
+			opts: &options{
+				args:     []string{"-race", "-args", "-update"},
+				packages: []string{"./pkg"},
+			},
+			expected: []string{"-race", "-args", "-update"},
+		},
+		{
+			name: "removes test prefixed flags",
+			opts: &options{
+				args:     []string{"-test.run=TestOne", "-test.v"},
+				packages: []string{"./pkg"},
+			},
+			expected: []string{"-test.v"},
+		},
+		{
+			name: "flags with a value in the next argument",
+			opts: &options{args: []string{
+				"-run", "TestOne", "-tags", "integration", "-test.count", "2",
+				"-timeout", "5m", "-v", "./pkg", "./other",
+			}},
+			expected: []string{"-tags", "integration", "-timeout", "5m", "-v"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestOptions_Validate_Shuffle(t *testing.T) {
+	assert.NilError(t, options{shuffle: "on"}.Validate())
+	assert.NilError(t, options{shuffle: "1234"}.Validate())
+	assert.ErrorContains(t, options{shuffle: "yes"}.Validate(), "--shuffle must be on, off")
+	assert.ErrorContains(t, options{shuffle: "on", args: []string{"-shuffle=off"}}.Validate(),
+		"--shuffle can not be used")
+}
+
+func TestShuffleArgs(t *testing.T) {
+	assert.Assert(t, shuffleArgs(&options{}) == nil)
+	assert.Assert(t, shuffleArgs(&options{shuffle: "off"}) == nil)
+	assert.DeepEqual(t, shuffleArgs(&options{shuffle: "1234"}), []string{"-shuffle=1234"})
+}
+
+func TestSummaryOptions_ShuffleOff(t *testing.T) {
+	opts := &options{shuffle: "off"}
+	assert.Assert(t, summaryOptions(opts, nil).ReproCommand == nil)
+
+	opts.shuffle = "1234"
+	assert.Assert(t, summaryOptions(opts, nil).ReproCommand != nil)
+}
+
+func TestResolveShuffleSeed(t *testing.T) {
+	opts := &options{shuffle: "on"}
+	resolveShuffleSeed(opts)
+	assert.Assert(t, opts.shuffle != "on")
+	assert.NilError(t, validateShuffle(opts.shuffle))
+	assert.DeepEqual(t, goTestCmdArgs(opts, rerunOpts{}),
+		[]string{"go", "test", "-json", "-shuffle=" + opts.shuffle, "./..."})
+}
+
+func TestWriteReproScript(t *testing.T) {
+	raw, err := ioutil.ReadFile("../testjson/testdata/input/go-test-json-with-shuffle.out")
+	assert.NilError(t, err)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: bytes.NewReader(raw)})
+	assert.NilError(t, err)
+
+	dir := fs.NewDir(t, "repro-script")
+	opts := &options{
+		reproScript: dir.Join("fails.sh"),
+		args:        []string{"-tags=integration", "./..."},
+		environment: map[string]string{
+			"go.flags":     "-mod=vendor",
+			"go.race":      "false",
+			"env.TZ":       "America/New_York",
+			"env.DB_QUERY": "select 'x'",
+			"env.DB_TOKEN": redactedValue,
+		},
+	}
+	assert.NilError(t, writeReproScript(opts, exec))
+
+	script, err := ioutil.ReadFile(opts.reproScript)
+	assert.NilError(t, err)
+	golden.Assert(t, string(script), "repro-script.golden")
+
+	info, err := os.Stat(opts.reproScript)
+	assert.NilError(t, err)
+	assert.Assert(t, info.Mode()&0o100 != 0, "script is not executable")
+}
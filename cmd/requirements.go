@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// requirementPropertiesValue is a flag.Value for the --requirement-property
+// mappings. It may be set more than once.
+type requirementPropertiesValue struct {
+	mapping []junitxml.RequirementProperty
+}
+
+func (v *requirementPropertiesValue) Set(raw string) error {
+	i := strings.Index(raw, "=")
+	if i < 1 {
+		return fmt.Errorf("requirement property %q must be in the form name=format", raw)
+	}
+	name, format := raw[:i], raw[i+1:]
+	if !strings.Contains(format, "{id}") && !strings.Contains(format, "{ids}") &&
+		!strings.Contains(format, "{number}") {
+		return fmt.Errorf("requirement property %q: format must contain {id}, {ids}, or {number}", raw)
+	}
+	v.mapping = append(v.mapping, junitxml.RequirementProperty{Name: name, Format: format})
+	return nil
+}
+
+func (v *requirementPropertiesValue) String() string {
+	parts := make([]string, 0, len(v.mapping))
+	for _, m := range v.mapping {
+		parts = append(parts, m.Name+"="+m.Format)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (v *requirementPropertiesValue) Type() string {
+	return "name=format"
+}
+
+// printRequirementsSummary prints the result of the tests of each requirement
+// in the names of the tests. A requirement is verified when its tests passed,
+// failing when any of its tests failed, and not verified when all of its
+// tests were skipped.
+func printRequirementsSummary(out io.Writer, exec *testjson.Execution, opts *options) {
+	if !opts.hideSummary.value.Includes(testjson.SummarizeRequirements) {
+		return
+	}
+	results := junitxml.RequirementResults(exec, opts.hideExamples)
+	if len(results) == 0 {
+		return
+	}
+	var verified, failing, notVerified []junitxml.RequirementResult
+	for _, r := range results {
+		switch {
+		case r.Failed > 0:
+			failing = append(failing, r)
+		case r.Passed > 0:
+			verified = append(verified, r)
+		default:
+			notVerified = append(notVerified, r)
+		}
+	}
+	fmt.Fprintf(out, "\n=== Requirements: %d verified, %d failing, %d not verified\n",
+		len(verified), len(failing), len(notVerified))
+	for _, group := range []struct {
+		label   string
+		results []junitxml.RequirementResult
+	}{
+		{"FAILING", failing},
+		{"NOT VERIFIED", notVerified},
+		{"VERIFIED", verified},
+	} {
+		for _, r := range group.results {
+			fmt.Fprintf(out, "=== %s: %s (%s)\n", group.label, r.ID, formatRequirementCounts(r))
+		}
+	}
+}
+
+func formatRequirementCounts(r junitxml.RequirementResult) string {
+	var parts []string
+	if r.Passed > 0 {
+		parts = append(parts, fmt.Sprintf("%d passed", r.Passed))
+	}
+	if r.Failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", r.Failed))
+	}
+	if r.Skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d skipped", r.Skipped))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// requirementExemptionsValue is a flag.Value for the
+// --require-requirements-exempt rules. It may be set more than once.
+type requirementExemptionsValue struct {
+	raw      []string
+	matchers []failureMatcher
+}
+
+func (v *requirementExemptionsValue) Set(raw string) error {
+	if strings.HasPrefix(raw, "output=") {
+		return fmt.Errorf("invalid rule %q, must be package= or test=", raw)
+	}
+	m, err := parseFailureMatcher(raw)
+	if err != nil {
+		return err
+	}
+	v.raw = append(v.raw, raw)
+	v.matchers = append(v.matchers, m)
+	return nil
+}
+
+func (v *requirementExemptionsValue) String() string {
+	return strings.Join(v.raw, ",")
+}
+
+func (v *requirementExemptionsValue) Type() string {
+	return "rule"
+}
+
+func (v *requirementExemptionsValue) exempt(tc testjson.TestCase) bool {
+	for _, m := range v.matchers {
+		if m.match(tc, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRequirements returns an error when --require-requirements is set and
+// a test that was run does not have a requirement in its name. Example
+// functions are always exempt, because their names can not include a
+// requirement.
+func checkRequirements(opts *options, exec *testjson.Execution) error {
+	if !opts.requireRequirements {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var missing []string
+	for _, pkgname := range exec.Packages() {
+		for _, tc := range exec.Package(pkgname).TestCases() {
+			if tc.Test.IsExample() || opts.requirementExemptions.exempt(tc) {
+				continue
+			}
+			if reqs, _ := junitxml.SplitRequirements(tc.Test.Name()); len(reqs) > 0 {
+				continue
+			}
+			name := opts.packageName(tc.Package) + "." + tc.Test.Name()
+			if !seen[name] {
+				seen[name] = true
+				missing = append(missing, name)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		log.Warnf("Test %v does not have a requirement, like TestName[REQ-1]", name)
+	}
+	return fmt.Errorf("%d tests do not have a requirement, "+
+		"add one to the name of each test or exempt them with --require-requirements-exempt", len(missing))
+}
+
+// validateRequirementURLTemplate returns an error when the
+// --requirement-url-template does not include the requirement.
+func validateRequirementURLTemplate(template string) error {
+	if template == "" || strings.Contains(template, "{id}") || strings.Contains(template, "{number}") {
+		return nil
+	}
+	return fmt.Errorf("--requirement-url-template must contain {id} or {number}")
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestRequirementPropertiesValue(t *testing.T) {
+	var v requirementPropertiesValue
+	assert.NilError(t, v.Set("polarion-testcase-id={id}"))
+	assert.NilError(t, v.Set("tracker=TRK-{number}"))
+	assert.DeepEqual(t, v.mapping, []junitxml.RequirementProperty{
+		{Name: "polarion-testcase-id", Format: "{id}"},
+		{Name: "tracker", Format: "TRK-{number}"},
+	})
+	assert.Equal(t, v.String(), "polarion-testcase-id={id},tracker=TRK-{number}")
+
+	assert.ErrorContains(t, v.Set("={id}"), "must be in the form name=format")
+	assert.ErrorContains(t, v.Set("tracker=TRK"), "format must contain {id}, {ids}, or {number}")
+}
+
+func TestPrintRequirementsSummary(t *testing.T) {
+	events := []string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogout[REQ-1,REQ-2]"}`,
+		`{"Action":"fail","Package":"example.com/a","Test":"TestLogout[REQ-1,REQ-2]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestSave[REQ-3]"}`,
+		`{"Action":"skip","Package":"example.com/a","Test":"TestSave[REQ-3]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestLoad[REQ-4]"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestLoad[REQ-4]"}`,
+		`{"Action":"fail","Package":"example.com/a"}`,
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(strings.Join(events, "\n")),
+	})
+	assert.NilError(t, err)
+
+	_, opts := setupFlags("gotestsum")
+	out := new(bytes.Buffer)
+	printRequirementsSummary(out, exec, opts)
+	expected := `
+=== Requirements: 1 verified, 2 failing, 1 not verified
+=== FAILING: REQ-1 (1 passed, 1 failed)
+=== FAILING: REQ-2 (1 failed)
+=== NOT VERIFIED: REQ-3 (1 skipped)
+=== VERIFIED: REQ-4 (1 passed)
+`
+	assert.Equal(t, out.String(), expected)
+
+	assert.NilError(t, opts.hideSummary.Set("requirements"))
+	out.Reset()
+	printRequirementsSummary(out, exec, opts)
+	assert.Equal(t, out.String(), "")
+}
+
+func TestCheckRequirements(t *testing.T) {
+	events := []string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogin[REQ-1]/admin"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestLogin[REQ-1]/admin"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogout"}`,
+		`{"Action":"fail","Package":"example.com/a","Test":"TestLogout"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogout"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestLogout"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"ExampleLogin"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"ExampleLogin"}`,
+		`{"Action":"pass","Package":"example.com/a"}`,
+		`{"Action":"run","Package":"example.com/internal/b","Test":"TestHelper"}`,
+		`{"Action":"pass","Package":"example.com/internal/b","Test":"TestHelper"}`,
+		`{"Action":"pass","Package":"example.com/internal/b"}`,
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(strings.Join(events, "\n")),
+	})
+	assert.NilError(t, err)
+
+	t.Run("not enabled", func(t *testing.T) {
+		assert.NilError(t, checkRequirements(&options{}, exec))
+	})
+
+	t.Run("untagged tests", func(t *testing.T) {
+		opts := &options{requireRequirements: true}
+		err := checkRequirements(opts, exec)
+		assert.ErrorContains(t, err, "2 tests do not have a requirement")
+	})
+
+	t.Run("exempt", func(t *testing.T) {
+		opts := &options{requireRequirements: true}
+		assert.NilError(t, opts.requirementExemptions.Set("package=example.com/internal/..."))
+		assert.NilError(t, opts.requirementExemptions.Set("test=^TestLogout$"))
+		assert.NilError(t, checkRequirements(opts, exec))
+	})
+}
+
+func TestRequirementExemptionsValue(t *testing.T) {
+	var v requirementExemptionsValue
+	assert.NilError(t, v.Set("package=./internal/..."))
+	assert.NilError(t, v.Set("test=^TestHelper"))
+	assert.Equal(t, v.String(), "package=./internal/...,test=^TestHelper")
+
+	assert.ErrorContains(t, v.Set("output=panic"), "must be package= or test=")
+	assert.Assert(t, v.Set("test=[") != nil)
+}
+
+func TestOptions_Validate_RequirementURLTemplate(t *testing.T) {
+	assert.NilError(t, options{requirementURLTemplate: "https://jira.example.com/browse/{id}"}.Validate())
+	assert.NilError(t, options{requirementURLTemplate: "https://tracker.example.com/{number}"}.Validate())
+	err := options{requirementURLTemplate: "https://jira.example.com/browse/"}.Validate()
+	assert.ErrorContains(t, err, "--requirement-url-template must contain {id} or {number}")
+}
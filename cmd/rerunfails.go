@@ -3,15 +3,29 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"sort"
+	"strings"
+	"time"
 
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/repro"
 	"gotest.tools/gotestsum/testjson"
 )
 
 type rerunOpts struct {
 	runFlag string
 	pkg     string
+	// packages is the list of packages to test, used when pkg is empty.
+	packages []string
+	// timeoutFlag replaces any -timeout flag in the go test args.
+	timeoutFlag string
+	// extraArgs are additional go test flags, added before the list of packages.
+	extraArgs []string
+	// packageArgs are the go test flags of the packages from --package-args.
+	// They are added after the go test args so that they override them.
+	packageArgs []string
 }
 
 func (o rerunOpts) Args() []string {
@@ -25,6 +39,81 @@ func (o rerunOpts) Args() []string {
 	return result
 }
 
+// Placeholders in the --raw-command args that are replaced by the test and
+// package to re-run. When the args have none of the placeholders, the
+// -test.run flag and the package are added to the end of the args instead.
+const (
+	rawCommandRunFlag = "{runFlag}"
+	rawCommandRun     = "{run}"
+	rawCommandPkg     = "{pkg}"
+)
+
+// Environment variables set for the --raw-command when it re-runs failed
+// tests, for commands that can not use the placeholders, like a make target.
+const (
+	rerunRunEnv     = "GOTESTSUM_RERUN_RUN"
+	rerunPackageEnv = "GOTESTSUM_RERUN_PACKAGE"
+)
+
+// run returns the regular expression of the runFlag.
+func (o rerunOpts) run() string {
+	return strings.TrimPrefix(o.runFlag, "-test.run=")
+}
+
+// rawCommandArgs returns the --raw-command args for a run. The placeholders
+// are replaced with the test and package to re-run. An arg that is only a
+// placeholder is removed when the value is empty, which is the case for the
+// first run of the tests.
+func rawCommandArgs(args []string, o rerunOpts) []string {
+	if !hasRawCommandPlaceholder(args) {
+		var result []string
+		result = append(result, args...)
+		return append(result, o.Args()...)
+	}
+	values := map[string]string{
+		rawCommandRunFlag: o.runFlag,
+		rawCommandRun:     o.run(),
+		rawCommandPkg:     o.pkg,
+	}
+	replacer := strings.NewReplacer(
+		rawCommandRunFlag, o.runFlag,
+		rawCommandRun, o.run(),
+		rawCommandPkg, o.pkg)
+	result := make([]string, 0, len(args))
+	for _, arg := range args {
+		if value, ok := values[arg]; ok && value == "" {
+			continue
+		}
+		result = append(result, replacer.Replace(arg))
+	}
+	return result
+}
+
+func hasRawCommandPlaceholder(args []string) bool {
+	for _, arg := range args {
+		for _, placeholder := range []string{rawCommandRunFlag, rawCommandRun, rawCommandPkg} {
+			if strings.Contains(arg, placeholder) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setRerunEnv sets the environment variables with the test and package to
+// re-run, for a --raw-command.
+func setRerunEnv(o rerunOpts) error {
+	if err := os.Setenv(rerunRunEnv, o.run()); err != nil {
+		return err
+	}
+	return os.Setenv(rerunPackageEnv, o.pkg)
+}
+
+func unsetRerunEnv() {
+	_ = os.Unsetenv(rerunRunEnv)
+	_ = os.Unsetenv(rerunPackageEnv)
+}
+
 func newRerunOptsFromTestCase(tc testjson.TestCase) rerunOpts {
 	return rerunOpts{
 		runFlag: goTestRunFlagForTestCase(tc.Test),
@@ -54,30 +143,71 @@ func rerunFailed(ctx context.Context, opts *options, scanConfig testjson.ScanCon
 	defer cancel()
 	tcFilter := rerunFailsFilter(opts)
 
+	maxAttempts := opts.rerunFailsMaxAttempts
+	if maxAttempts == 0 {
+		// known flakes from --flake-rules, and resource conflicts with
+		// --rerun-conflicts, are re-run once
+		maxAttempts = 1
+	}
+	start := rerunTimeNow()
+	opts.reruns = &jsonsummary.Reruns{Budget: opts.rerunTimeBudget.Seconds()}
+	defer func() {
+		opts.reruns.Elapsed = rerunTimeNow().Sub(start).Seconds()
+	}()
+	if opts.rawCommand {
+		defer unsetRerunEnv()
+	}
 	rec := newFailureRecorderFromExecution(scanConfig.Execution)
-	for attempts := 0; rec.count() > 0 && attempts < opts.rerunFailsMaxAttempts; attempts++ {
+	for attempts := 0; rec.count() > 0 && attempts < maxAttempts; attempts++ {
+		tcs := tcFilter(rec.failures)
+		if rerunBudgetUsed(opts, start) {
+			return rerunBudgetErr(opts, len(tcs))
+		}
 		testjson.PrintSummary(opts.stdout, scanConfig.Execution, testjson.SummarizeNone)
 		opts.stdout.Write([]byte("\n")) // nolint: errcheck
 
+		opts.reruns.Attempts++
 		nextRec := newFailureRecorder(scanConfig.Handler)
-		for _, tc := range tcFilter(rec.failures) {
-			goTestProc, err := startGoTestFn(ctx, "", goTestCmdArgs(opts, newRerunOptsFromTestCase(tc)))
+		for i, tc := range tcs {
+			if i > 0 && rerunBudgetUsed(opts, start) {
+				return rerunBudgetErr(opts, len(tcs)-i)
+			}
+			opts.reruns.Tests++
+			rOpts := newRerunOptsFromTestCase(tc)
+			rOpts.timeoutFlag = opts.packageTimeouts.timeoutArg(tc.Package)
+			rOpts.packageArgs = opts.packageArgs.argsFor(tc.Package)
+			if opts.rawCommand {
+				if err := setRerunEnv(rOpts); err != nil {
+					return err
+				}
+			}
+			goTestProc, err := startGoTestWithEnv(ctx, opts.modules.dir(tc.Package), goTestCmdArgs(opts, rOpts),
+				opts.envGroups.envFor(tc.Package))
 			if err != nil {
 				return err
 			}
+			opts.stuck.watch(goTestProc, cancel)
 
 			cfg := testjson.ScanConfig{
-				RunID:     attempts + 1,
-				Stdout:    goTestProc.stdout,
-				Stderr:    goTestProc.stderr,
-				Handler:   nextRec,
-				Execution: scanConfig.Execution,
-				Stop:      cancel,
+				RunID:                   attempts + 1,
+				Stdout:                  goTestProc.stdout,
+				Stderr:                  goTestProc.stderr,
+				Handler:                 nextRec,
+				Execution:               scanConfig.Execution,
+				Stop:                    cancel,
+				CaptureDiagnostics:      scanConfig.CaptureDiagnostics,
+				StrictJSON:              scanConfig.StrictJSON,
+				StrictOutputAttribution: scanConfig.StrictOutputAttribution,
+				ScrubOutput:             scanConfig.ScrubOutput,
 			}
 			if _, err := testjson.ScanTestOutput(cfg); err != nil {
 				return err
 			}
 			exitErr := goTestProc.cmd.Wait()
+			recordUsage(opts, goTestProc, tc.Package)
+			if err := opts.stuck.abortErr(); err != nil {
+				return err
+			}
 			if exitErr != nil {
 				nextRec.lastErr = exitErr
 			}
@@ -93,6 +223,56 @@ func rerunFailed(ctx context.Context, opts *options, scanConfig testjson.ScanCon
 // startGoTestFn is a shim for testing
 var startGoTestFn = startGoTest
 
+// rerunTimeNow is a shim for testing
+var rerunTimeNow = time.Now
+
+// rerunBudgetUsed returns true when the time spent re-running tests since
+// start is at least the --rerun-time-budget. A go test process that started
+// before the budget was used is not stopped, so the reruns may take longer
+// than the budget by the time of one process.
+func rerunBudgetUsed(opts *options, start time.Time) bool {
+	return opts.rerunTimeBudget > 0 && rerunTimeNow().Sub(start) >= opts.rerunTimeBudget
+}
+
+// rerunBudgetErr records the number of failed tests that were not run again
+// because the --rerun-time-budget was used, and returns the error for the run.
+func rerunBudgetErr(opts *options, notRerun int) error {
+	opts.reruns.NotRerun = notRerun
+	return categoryError{
+		category: exitRerunExhausted,
+		err: fmt.Errorf("--rerun-time-budget of %v was used, %d failed tests were not run again",
+			opts.rerunTimeBudget, notRerun),
+		// the failures were reported by go test, and the budget is printed in
+		// the summary by printRerunSummary.
+		reported: true,
+	}
+}
+
+// printRerunSummary prints the number of attempts, tests, and time spent
+// re-running failed tests with --rerun-fails.
+func printRerunSummary(out io.Writer, reruns *jsonsummary.Reruns) {
+	if reruns == nil || (reruns.Attempts == 0 && reruns.NotRerun == 0) {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Reruns: %s, %s run again in %s\n",
+		pluralize(reruns.Attempts, "attempt"), pluralize(reruns.Tests, "test"), formatSeconds(reruns.Elapsed))
+	if reruns.NotRerun > 0 {
+		fmt.Fprintf(out, "=== Rerun time budget of %s was used, %d failed tests were not run again\n",
+			formatSeconds(reruns.Budget), reruns.NotRerun)
+	}
+}
+
+func pluralize(n int, singular string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %ss", n, singular)
+}
+
+func formatSeconds(s float64) string {
+	return testjson.FormatDurationAsSeconds(time.Duration(s*float64(time.Second)), 2)
+}
+
 func hasErrors(err error, exec *testjson.Execution) error {
 	switch {
 	case len(exec.Errors()) > 0:
@@ -135,11 +315,7 @@ func (r *failureRecorder) count() int {
 }
 
 func goTestRunFlagForTestCase(test testjson.TestName) string {
-	if test.IsSubTest() {
-		root, sub := test.Split()
-		return "-test.run=^" + root + "$/^" + sub + "$"
-	}
-	return "-test.run=^" + test.Name() + "$"
+	return "-test.run=" + repro.RunPattern(test)
 }
 
 func writeRerunFailsReport(opts *options, exec *testjson.Execution) error {
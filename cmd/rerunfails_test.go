@@ -5,9 +5,13 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/internal/jsonsummary"
 	"gotest.tools/gotestsum/testjson"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
@@ -136,6 +140,120 @@ func TestRerunFailed_ReturnsAnErrorWhenTheLastTestIsSuccessful(t *testing.T) {
 	assert.Error(t, err, "run-failed-3")
 }
 
+func TestRerunFailed_TimeBudget(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	origNow := rerunTimeNow
+	rerunTimeNow = func() time.Time { return now }
+	defer func() { rerunTimeNow = origNow }()
+
+	failed := func(name string) string {
+		return `{"Package": "pkg", "Action": "run"}
+{"Package": "pkg", "Test": "` + name + `", "Action": "run"}
+{"Package": "pkg", "Test": "` + name + `", "Action": "fail"}
+{"Package": "pkg", "Action": "fail"}
+`
+	}
+	events := []string{failed("TestOne"), failed("TestTwo")}
+	fn := func(args []string) *proc {
+		// each run of go test takes 6 seconds
+		now = now.Add(6 * time.Second)
+		next := events[0]
+		events = events[1:]
+		return &proc{
+			cmd:    fakeWaiter{result: newExitCode("run-failed", 1)},
+			stdout: strings.NewReader(next),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	stdout := new(bytes.Buffer)
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        3,
+		rerunTimeBudget:              10 * time.Second,
+		stdout:                       stdout,
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithTwoFailures(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(context.Background(), opts, cfg)
+	assert.Error(t, err, "--rerun-time-budget of 10s was used, 2 failed tests were not run again")
+	assert.Equal(t, ExitCodeWithDefault(opts.exitCodes.resolve(err)), 5)
+	assert.DeepEqual(t, opts.reruns, &jsonsummary.Reruns{
+		Attempts: 1,
+		Tests:    2,
+		Elapsed:  12,
+		Budget:   10,
+		NotRerun: 2,
+	})
+
+	out := new(bytes.Buffer)
+	printRerunSummary(out, opts.reruns)
+	expected := `
+=== Reruns: 1 attempt, 2 tests run again in 12.00s
+=== Rerun time budget of 10.00s was used, 2 failed tests were not run again
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRerunFailed_RawCommandEnv(t *testing.T) {
+	jsonPassed := `{"Package": "pkg", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+{"Package": "pkg", "Action": "pass"}
+`
+	type started struct {
+		args []string
+		run  string
+		pkg  string
+	}
+	var runs []started
+	fn := func(args []string) *proc {
+		runs = append(runs, started{
+			args: args,
+			run:  os.Getenv(rerunRunEnv),
+			pkg:  os.Getenv(rerunPackageEnv),
+		})
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(jsonPassed),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	opts := &options{
+		rawCommand:                   true,
+		args:                         []string{"./test-all", "--package={pkg}"},
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        2,
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithTwoFailures(t),
+		Handler:   noopHandler{},
+	}
+	assert.NilError(t, rerunFailed(context.Background(), opts, cfg))
+	expected := []started{
+		{args: []string{"./test-all", "--package=pkg"}, run: "^TestOne$", pkg: "pkg"},
+		{args: []string{"./test-all", "--package=pkg"}, run: "^TestTwo$", pkg: "pkg"},
+	}
+	assert.DeepEqual(t, runs, expected, gocmp.AllowUnexported(started{}))
+	_, ok := os.LookupEnv(rerunRunEnv)
+	assert.Assert(t, !ok, "expected %v to be unset", rerunRunEnv)
+}
+
+func TestPrintRerunSummary_NoReruns(t *testing.T) {
+	out := new(bytes.Buffer)
+	printRerunSummary(out, nil)
+	printRerunSummary(out, &jsonsummary.Reruns{})
+	assert.Equal(t, out.String(), "")
+}
+
 func patchStartGoTestFn(f func(args []string) *proc) func() {
 	orig := startGoTestFn
 	startGoTestFn = func(ctx context.Context, dir string, args []string) (*proc, error) {
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// resultCache stores the TestEvents of packages that passed, keyed on a hash
+// of all the inputs to the package tests. When the inputs have not changed
+// the package does not need to be tested again.
+type resultCache struct {
+	dir string
+	// keys maps a package import path to the hash of its inputs.
+	keys map[string]string
+	// hits is the sorted list of packages whose results were read from the cache.
+	hits []string
+	// events received from go test, indexed by package.
+	events map[string][][]byte
+}
+
+// cacheEntry is the file format of a package in the result cache.
+type cacheEntry struct {
+	Package string
+	Key     string
+	Events  []json.RawMessage
+}
+
+func newResultCache(opts *options) (*resultCache, error) {
+	if err := os.MkdirAll(opts.resultCacheDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create result cache directory: %w", err)
+	}
+	pkgs, err := goListTestPackages(cmdArgPackageList(opts, rerunOpts{}, "./..."), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	cache := &resultCache{
+		dir:    opts.resultCacheDir,
+		events: make(map[string][][]byte),
+	}
+	cache.keys, err = packageInputHashes(pkgs, append([]string{"go version " + goEnv(opts).Version}, opts.args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash package inputs: %w", err)
+	}
+	for _, pkg := range opts.resultCacheInvalidate {
+		if err := os.Remove(cache.path(pkg)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to invalidate result cache for %v: %w", pkg, err)
+		}
+	}
+	return cache, nil
+}
+
+func (c *resultCache) path(pkg string) string {
+	sum := sha256.Sum256([]byte(pkg))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// replay sends the cached TestEvents of every package with a matching key
+// to the handler, and returns the list of packages which must still be tested.
+func (c *resultCache) replay(handler testjson.EventHandler) (*testjson.Execution, []string, error) {
+	var misses []string
+	stdout := new(bytes.Buffer)
+	for _, pkg := range sortedStringKeys(c.keys) {
+		entry, ok := c.read(pkg)
+		if !ok {
+			misses = append(misses, pkg)
+			continue
+		}
+		c.hits = append(c.hits, pkg)
+		for _, event := range entry.Events {
+			stdout.Write(markCachedEvent(pkg, event))
+			stdout.WriteString("\n")
+		}
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: stdout, Handler: handler})
+	return exec, misses, err
+}
+
+func (c *resultCache) read(pkg string) (cacheEntry, bool) {
+	var entry cacheEntry
+	raw, err := ioutil.ReadFile(c.path(pkg))
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		log.Warnf("Ignoring invalid result cache entry for %v: %v", pkg, err)
+		return entry, false
+	}
+	return entry, entry.Package == pkg && entry.Key == c.keys[pkg]
+}
+
+// markCachedEvent replaces the elapsed time on the package pass line with
+// (cached), the same way go test reports a package from the build cache.
+func markCachedEvent(pkg string, raw []byte) []byte {
+	var event testjson.TestEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return raw
+	}
+	prefix := "ok  \t" + pkg + "\t"
+	if !event.PackageEvent() || !strings.HasPrefix(event.Output, prefix) {
+		return raw
+	}
+	fields := strings.SplitN(strings.TrimPrefix(event.Output, prefix), "\t", 2)
+	fields[0] = "(cached)"
+	event.Output = prefix + strings.Join(fields, "\t")
+	if !strings.HasSuffix(event.Output, "\n") {
+		event.Output += "\n"
+	}
+	out, err := json.Marshal(event)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// recorder returns an EventHandler that records all the events received from
+// go test, so that they can be saved to the cache.
+func (c *resultCache) recorder(handler testjson.EventHandler) testjson.EventHandler {
+	return &cacheRecorder{EventHandler: handler, cache: c}
+}
+
+type cacheRecorder struct {
+	testjson.EventHandler
+	cache *resultCache
+}
+
+func (r *cacheRecorder) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if raw := event.Bytes(); len(raw) > 0 {
+		// copy the bytes, because the scanner re-uses the underlying buffer
+		raw = append([]byte(nil), raw...)
+		r.cache.events[event.Package] = append(r.cache.events[event.Package], raw)
+	}
+	return r.EventHandler.Event(event, execution)
+}
+
+// save writes the recorded events of every package that passed to the cache.
+func (c *resultCache) save(exec *testjson.Execution) error {
+	for _, name := range exec.Packages() {
+		key, ok := c.keys[name]
+		events := c.events[name]
+		pkg := exec.Package(name)
+		if !ok || len(events) == 0 || pkg.Result() != testjson.ActionPass || len(pkg.Failed) > 0 {
+			continue
+		}
+		entry := cacheEntry{Package: name, Key: key}
+		for _, event := range events {
+			entry.Events = append(entry.Events, json.RawMessage(event))
+		}
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(c.path(name), raw, 0o644); err != nil {
+			return fmt.Errorf("failed to write result cache for %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *resultCache) isHit(pkg string) bool {
+	i := sort.SearchStrings(c.hits, pkg)
+	return i < len(c.hits) && c.hits[i] == pkg
+}
+
+// junitProperties returns the properties added to the testsuite of a package
+// that was read from the result cache.
+func (c *resultCache) junitProperties(pkg string) []junitxml.JUnitProperty {
+	if c == nil || !c.isHit(pkg) {
+		return nil
+	}
+	return []junitxml.JUnitProperty{{Name: "gotestsum.result-cache", Value: "hit"}}
+}
+
+func (c *resultCache) printSummary(out io.Writer, packageName func(string) string) {
+	if c == nil || len(c.hits) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Result cache: %d packages passed with the same inputs in a previous run\n",
+		len(c.hits))
+	for _, pkg := range c.hits {
+		fmt.Fprintf(out, "=== CACHED: %s\n", packageName(pkg))
+	}
+}
+
+// packageInputHashes returns a hash of the inputs for every package with
+// tests. The inputs are the extra values, the files of every package in the
+// main module that is a dependency of the test binary (Go, assembly, C, C++,
+// header, syso, and embedded files), the files in the testdata directory, and
+// the version of every other module dependency.
+func packageInputHashes(pkgs []listedPackage, extra []string) (map[string]string, error) {
+	byPath := make(map[string]listedPackage, len(pkgs))
+	for _, pkg := range pkgs {
+		byPath[pkg.ImportPath] = pkg
+	}
+
+	fileHashes := make(map[string]string)
+	hashPkg := func(pkg listedPackage) (string, error) {
+		switch {
+		case pkg.Standard:
+			return "", nil
+		case pkg.Module != nil && pkg.Module.Version != "":
+			return pkg.Module.Path + "@" + pkg.Module.Version, nil
+		}
+		if h, ok := fileHashes[pkg.ImportPath]; ok {
+			return h, nil
+		}
+		h := sha256.New()
+		for _, name := range pkgFiles(pkg) {
+			if err := hashFile(h, moduleDir(pkg), filepath.Join(pkg.Dir, name)); err != nil {
+				return "", err
+			}
+		}
+		fileHashes[pkg.ImportPath] = hex.EncodeToString(h.Sum(nil))
+		return fileHashes[pkg.ImportPath], nil
+	}
+
+	result := make(map[string]string)
+	for _, pkg := range pkgs {
+		if !pkg.isTestMain() {
+			continue
+		}
+		name := strings.TrimSuffix(pkg.ImportPath, ".test")
+		h := sha256.New()
+		for _, v := range extra {
+			fmt.Fprintln(h, v)
+		}
+		deps := append([]string{}, pkg.Deps...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			depHash, err := hashPkg(byPath[dep])
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintln(h, dep, depHash)
+		}
+		if err := hashTestdata(h, byPath[name]); err != nil {
+			return nil, err
+		}
+		result[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	return result, nil
+}
+
+func pkgFiles(pkg listedPackage) []string {
+	var files []string
+	for _, group := range [][]string{
+		pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles,
+		pkg.EmbedFiles, pkg.TestEmbedFiles, pkg.XTestEmbedFiles,
+		pkg.SFiles, pkg.CFiles, pkg.CXXFiles, pkg.HFiles, pkg.SysoFiles,
+	} {
+		files = append(files, group...)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// moduleDir returns the root directory of the module of the package, or the
+// package directory if the package is not in a module.
+func moduleDir(pkg listedPackage) string {
+	if pkg.Module != nil && pkg.Module.Dir != "" {
+		return pkg.Module.Dir
+	}
+	return pkg.Dir
+}
+
+// hashFile writes the path of the file, relative to root, and the contents of
+// the file to h. The path is relative so that the hash is the same when the
+// module is in a different directory, like on another CI runner.
+func hashFile(h io.Writer, root string, path string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	fh, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close() // nolint: errcheck
+	fmt.Fprintln(h, filepath.ToSlash(rel))
+	_, err = io.Copy(h, bufio.NewReader(fh))
+	return err
+}
+
+func hashTestdata(h io.Writer, pkg listedPackage) error {
+	if pkg.Dir == "" {
+		return nil
+	}
+	root := filepath.Join(pkg.Dir, "testdata")
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case info.IsDir():
+			return nil
+		}
+		return hashFile(h, moduleDir(pkg), path)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
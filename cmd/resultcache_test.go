@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestMarkCachedEvent(t *testing.T) {
+	raw := `{"Action":"output","Package":"example.com/pkg","Output":"ok  \texample.com/pkg\t0.012s\tcoverage: 10.0% of statements\n"}`
+	out := markCachedEvent("example.com/pkg", []byte(raw))
+	expected := `{"Time":"0001-01-01T00:00:00Z","Action":"output","Package":"example.com/pkg","Test":"","Elapsed":0,` +
+		`"Output":"ok  \texample.com/pkg\t(cached)\tcoverage: 10.0% of statements\n","RunID":0}`
+	assert.Equal(t, string(out), expected)
+
+	raw = `{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"ok  \texample.com/pkg\t0.012s\n"}`
+	assert.Equal(t, string(markCachedEvent("example.com/pkg", []byte(raw))), raw)
+}
+
+func TestPackageInputHashes(t *testing.T) {
+	newModule := func(t *testing.T) *fs.Dir {
+		return fs.NewDir(t, t.Name(),
+			fs.WithDir("a",
+				fs.WithFile("a.go", "package a"),
+				fs.WithFile("a_test.go", "package a"),
+				fs.WithFile("a_amd64.s", "TEXT ·add(SB),$0"),
+				fs.WithDir("testdata", fs.WithFile("input", "data"))),
+			fs.WithDir("b", fs.WithFile("b.go", "package b")))
+	}
+	listPackages := func(dir *fs.Dir) []listedPackage {
+		mod := &listedModule{Path: "example.com", Dir: dir.Path()}
+		return []listedPackage{
+			{
+				ImportPath:  "example.com/a",
+				Dir:         dir.Join("a"),
+				Module:      mod,
+				GoFiles:     []string{"a.go"},
+				TestGoFiles: []string{"a_test.go"},
+				SFiles:      []string{"a_amd64.s"},
+				Deps:        []string{"example.com/b", "fmt"},
+			},
+			{ImportPath: "example.com/b", Dir: dir.Join("b"), Module: mod, GoFiles: []string{"b.go"}},
+			{ImportPath: "fmt", Standard: true},
+			{
+				ImportPath: "example.com/a.test",
+				Name:       "main",
+				Deps:       []string{"example.com/a", "example.com/b", "fmt"},
+			},
+		}
+	}
+	dir := newModule(t)
+	pkgs := listPackages(dir)
+
+	first, err := packageInputHashes(pkgs, []string{"go1.20"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(first), 1)
+
+	t.Run("same inputs", func(t *testing.T) {
+		again, err := packageInputHashes(pkgs, []string{"go1.20"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, again, first)
+	})
+	t.Run("same inputs in another directory", func(t *testing.T) {
+		other, err := packageInputHashes(listPackages(newModule(t)), []string{"go1.20"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, other, first)
+	})
+	t.Run("different extra values", func(t *testing.T) {
+		other, err := packageInputHashes(pkgs, []string{"go1.21"})
+		assert.NilError(t, err)
+		assert.Assert(t, other["example.com/a"] != first["example.com/a"])
+	})
+	t.Run("assembly file changed", func(t *testing.T) {
+		before, err := packageInputHashes(pkgs, []string{"go1.20"})
+		assert.NilError(t, err)
+		writeFile(t, dir.Join("a", "a_amd64.s"), "TEXT ·sub(SB),$0")
+		other, err := packageInputHashes(pkgs, []string{"go1.20"})
+		assert.NilError(t, err)
+		assert.Assert(t, other["example.com/a"] != before["example.com/a"])
+	})
+	t.Run("dependency file changed", func(t *testing.T) {
+		before, err := packageInputHashes(pkgs, []string{"go1.20"})
+		assert.NilError(t, err)
+		writeFile(t, dir.Join("b", "b.go"), "package b // changed")
+		other, err := packageInputHashes(pkgs, []string{"go1.20"})
+		assert.NilError(t, err)
+		assert.Assert(t, other["example.com/a"] != before["example.com/a"])
+	})
+	t.Run("testdata changed", func(t *testing.T) {
+		before, err := packageInputHashes(pkgs, []string{"go1.20"})
+		assert.NilError(t, err)
+		writeFile(t, dir.Join("a", "testdata", "input"), "changed")
+		other, err := packageInputHashes(pkgs, []string{"go1.20"})
+		assert.NilError(t, err)
+		assert.Assert(t, other["example.com/a"] != before["example.com/a"])
+	})
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	assert.NilError(t, ioutil.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestResultCache_SaveAndReplay(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	source := `{"Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOne","Elapsed":0.1}
+{"Action":"output","Package":"example.com/a","Output":"ok  \texample.com/a\t0.123s\n"}
+{"Action":"pass","Package":"example.com/a","Elapsed":0.2}
+{"Action":"run","Package":"example.com/b","Test":"TestTwo"}
+{"Action":"fail","Package":"example.com/b","Test":"TestTwo","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/b","Elapsed":0.2}
+`
+	keys := map[string]string{"example.com/a": "key-a", "example.com/b": "key-b"}
+	cache := &resultCache{dir: dir.Path(), keys: keys, events: map[string][][]byte{}}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  bytes.NewReader([]byte(source)),
+		Handler: cache.recorder(noopHandler{}),
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, cache.save(exec))
+
+	files, err := filepath.Glob(dir.Join("*.json"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 1, "only passing packages are saved")
+
+	next := &resultCache{dir: dir.Path(), keys: keys, events: map[string][][]byte{}}
+	exec, misses, err := next.replay(noopHandler{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, misses, []string{"example.com/b"})
+	assert.DeepEqual(t, next.hits, []string{"example.com/a"})
+	assert.Equal(t, exec.Total(), 1)
+	assert.Equal(t, len(next.junitProperties("example.com/a")), 1)
+	assert.Equal(t, len(next.junitProperties("example.com/b")), 0)
+
+	changed := &resultCache{dir: dir.Path(), keys: map[string]string{"example.com/a": "other"}}
+	_, misses, err = changed.replay(noopHandler{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, misses, []string{"example.com/a"})
+}
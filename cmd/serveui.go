@@ -0,0 +1,391 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/textreport"
+	"gotest.tools/gotestsum/internal/websocket"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// RunServeUI runs the tests in the same way as Run, and serves a web dashboard
+// of the test run while the tests are running.
+func RunServeUI(name string, args []string) error {
+	return runWithFlags(name, args, func(flags *pflag.FlagSet, opts *options) {
+		flags.StringVar(&opts.serveUIAddr, "addr", ":8080",
+			"address for the dashboard HTTP server to listen on")
+		flags.DurationVar(&opts.serveUILinger, "linger", 0,
+			"continue to serve the dashboard for this duration after the tests are done")
+	})
+}
+
+// dashboardBroadcastInterval is the minimum time between updates sent to the
+// dashboard clients.
+const dashboardBroadcastInterval = 250 * time.Millisecond
+
+// dashboard serves the state of the test run over HTTP, and sends updates to
+// every connected client over a WebSocket.
+type dashboard struct {
+	server   *http.Server
+	listener net.Listener
+	linger   time.Duration
+	out      io.Writer
+	// requirementURL is the --requirement-url-template used to link the
+	// requirements of failed tests.
+	requirementURL string
+
+	mu       sync.Mutex
+	state    dashboardState
+	packages map[string]*dashboardPackage
+	dirty    bool
+	clients  map[*websocket.Conn]struct{}
+	// exec is the Execution from the most recent event, which is used to
+	// find the tests that are running.
+	exec *testjson.Execution
+	// sendMu is held while sending a state to clients, so that clients never
+	// receive an older state after a newer one.
+	sendMu sync.Mutex
+	stop   chan struct{}
+	done   sync.WaitGroup
+}
+
+type dashboardState struct {
+	Started  time.Time           `json:"started"`
+	Elapsed  float64             `json:"elapsed"`
+	Done     bool                `json:"done"`
+	Tests    int                 `json:"tests"`
+	Failed   int                 `json:"failed"`
+	Skipped  int                 `json:"skipped"`
+	Running  int                 `json:"running"`
+	Packages []*dashboardPackage `json:"packages"`
+	Failures []dashboardTestCase `json:"failures"`
+	Errors   []string            `json:"errors,omitempty"`
+	// RunningTests are the tests that are running, in the order they started.
+	RunningTests []dashboardTestCase `json:"runningTests,omitempty"`
+
+	// LastEvent is the time the most recent test event was received.
+	LastEvent time.Time `json:"lastEvent"`
+}
+
+type dashboardPackage struct {
+	Name    string  `json:"name"`
+	Result  string  `json:"result"`
+	Elapsed float64 `json:"elapsed"`
+	Tests   int     `json:"tests"`
+	Failed  int     `json:"failed"`
+	Skipped int     `json:"skipped"`
+	Running int     `json:"running"`
+}
+
+type dashboardTestCase struct {
+	Package string  `json:"package"`
+	Test    string  `json:"test"`
+	Elapsed float64 `json:"elapsed"`
+	Output  string  `json:"output"`
+	// Requirements in the name of the test, with their URLs.
+	Requirements []textreport.RequirementLink `json:"requirements,omitempty"`
+}
+
+// startDashboard starts the dashboard server when serveUIAddr is set. When
+// only statusAddr is set the server serves /status and /metrics, without the
+// web page.
+func startDashboard(opts *options) (*dashboard, error) {
+	addr, withUI := opts.serveUIAddr, true
+	if addr == "" {
+		addr, withUI = opts.statusAddr, false
+	}
+	if addr == "" {
+		return nil, nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start dashboard server: %w", err)
+	}
+	d := &dashboard{
+		listener:       listener,
+		linger:         opts.serveUILinger,
+		out:            opts.stderr,
+		requirementURL: opts.requirementURLTemplate,
+		state:          dashboardState{Started: time.Now()},
+		packages:       make(map[string]*dashboardPackage),
+		clients:        make(map[*websocket.Conn]struct{}),
+		stop:           make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	if withUI {
+		mux.HandleFunc("/", d.serveIndex)
+		mux.HandleFunc("/api/state", d.serveState)
+		mux.HandleFunc("/ws", d.serveWebSocket)
+	}
+	mux.HandleFunc("/status", d.serveState)
+	mux.HandleFunc("/metrics", d.serveMetrics)
+	d.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	d.done.Add(2)
+	go func() {
+		defer d.done.Done()
+		if err := d.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Warnf("Dashboard server stopped: %v", err)
+		}
+	}()
+	go d.broadcastLoop()
+	if withUI {
+		fmt.Fprintf(opts.stderr, "Serving the test dashboard at http://%v\n", dashboardHost(listener.Addr()))
+	} else {
+		fmt.Fprintf(opts.stderr, "Serving the test status at http://%[1]v/status and http://%[1]v/metrics\n",
+			dashboardHost(listener.Addr()))
+	}
+	return d, nil
+}
+
+// dashboardHost returns a host:port that can be used in a URL to reach addr.
+func dashboardHost(addr net.Addr) string {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok || !tcpAddr.IP.IsUnspecified() {
+		return addr.String()
+	}
+	return fmt.Sprintf("localhost:%d", tcpAddr.Port)
+}
+
+// update the state of the dashboard from event. It is safe to call on a nil
+// dashboard.
+func (d *dashboard) update(event testjson.TestEvent, exec *testjson.Execution) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state.LastEvent = time.Now()
+	d.exec = exec
+	switch event.Action {
+	case testjson.ActionRun, testjson.ActionPass, testjson.ActionFail, testjson.ActionSkip:
+	default:
+		return
+	}
+	d.dirty = true
+	pkg := d.pkg(event.Package)
+	if event.PackageEvent() {
+		if event.Action.IsTerminal() {
+			pkg.Result = string(event.Action)
+			pkg.Elapsed = event.Elapsed
+		}
+		return
+	}
+
+	switch event.Action {
+	case testjson.ActionRun:
+		pkg.Tests++
+		pkg.Running++
+		d.state.Tests++
+		d.state.Running++
+		return
+	case testjson.ActionFail:
+		pkg.Failed++
+		d.state.Failed++
+		tc := exec.Package(event.Package).LastFailedByName(event.Test)
+		d.state.Failures = append(d.state.Failures, dashboardTestCase{
+			Package:      event.Package,
+			Test:         event.Test,
+			Elapsed:      event.Elapsed,
+			Output:       strings.Join(exec.OutputLines(tc), ""),
+			Requirements: textreport.RequirementLinks(event.Test, d.requirementURL),
+		})
+	case testjson.ActionSkip:
+		pkg.Skipped++
+		d.state.Skipped++
+	}
+	if pkg.Running > 0 {
+		pkg.Running--
+		d.state.Running--
+	}
+}
+
+func (d *dashboard) pkg(name string) *dashboardPackage {
+	pkg, ok := d.packages[name]
+	if !ok {
+		pkg = &dashboardPackage{Name: name, Result: "running"}
+		d.packages[name] = pkg
+		d.state.Packages = append(d.state.Packages, pkg)
+	}
+	return pkg
+}
+
+// finish marks the run as done, and sends the final state to all clients. It
+// is safe to call on a nil dashboard.
+func (d *dashboard) finish(exec *testjson.Execution) {
+	if d == nil || exec == nil {
+		return
+	}
+	d.mu.Lock()
+	for _, name := range exec.Packages() {
+		pkg := d.pkg(name)
+		pkg.Running = 0
+		if result := exec.Package(name).Result(); result != "" {
+			pkg.Result = string(result)
+		}
+	}
+	sort.SliceStable(d.state.Packages, func(i, j int) bool {
+		return d.state.Packages[i].Name < d.state.Packages[j].Name
+	})
+	d.state.Running = 0
+	d.state.RunningTests = nil
+	d.state.Done = true
+	d.state.Errors = exec.Errors()
+	d.dirty = true
+	d.mu.Unlock()
+	d.broadcast()
+}
+
+// close stops the server after waiting for the linger duration. It is safe to
+// call on a nil dashboard.
+func (d *dashboard) close() {
+	if d == nil {
+		return
+	}
+	if d.linger > 0 {
+		fmt.Fprintf(d.out, "Test dashboard will stop in %v\n", d.linger)
+		time.Sleep(d.linger)
+	}
+	close(d.stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = d.server.Shutdown(ctx)
+	d.mu.Lock()
+	for client := range d.clients {
+		_ = client.Close()
+	}
+	d.mu.Unlock()
+	d.done.Wait()
+}
+
+func (d *dashboard) broadcastLoop() {
+	defer d.done.Done()
+	ticker := time.NewTicker(dashboardBroadcastInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.broadcast()
+		}
+	}
+}
+
+// broadcast sends the state to all clients if it has changed since the last
+// broadcast.
+func (d *dashboard) broadcast() {
+	d.sendMu.Lock()
+	defer d.sendMu.Unlock()
+	d.mu.Lock()
+	if !d.dirty {
+		d.mu.Unlock()
+		return
+	}
+	d.dirty = false
+	msg, err := d.marshalState()
+	clients := make([]*websocket.Conn, 0, len(d.clients))
+	for client := range d.clients {
+		clients = append(clients, client)
+	}
+	d.mu.Unlock()
+	if err != nil {
+		log.Warnf("Failed to encode dashboard state: %v", err)
+		return
+	}
+
+	for _, client := range clients {
+		if err := client.WriteText(msg); err != nil {
+			log.Debugf("Failed to send dashboard update: %v", err)
+			d.removeClient(client)
+		}
+	}
+}
+
+// marshalState must be called while holding d.mu.
+func (d *dashboard) marshalState() ([]byte, error) {
+	if !d.state.Done {
+		d.state.Elapsed = time.Since(d.state.Started).Seconds()
+		d.state.RunningTests = dashboardRunningTests(d.exec)
+	}
+	return json.Marshal(d.state)
+}
+
+func dashboardRunningTests(exec *testjson.Execution) []dashboardTestCase {
+	running := exec.Running()
+	result := make([]dashboardTestCase, 0, len(running))
+	for _, tc := range running {
+		result = append(result, dashboardTestCase{
+			Package: tc.Package,
+			Test:    tc.Test.Name(),
+			Elapsed: tc.Elapsed.Seconds(),
+		})
+	}
+	return result
+}
+
+func (d *dashboard) removeClient(client *websocket.Conn) {
+	d.mu.Lock()
+	delete(d.clients, client)
+	d.mu.Unlock()
+	_ = client.Close()
+}
+
+func (d *dashboard) serveState(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	msg, err := d.marshalState()
+	d.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(msg)
+}
+
+func (d *dashboard) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	client, err := websocket.Upgrade(w, r)
+	if err != nil {
+		log.Debugf("Dashboard websocket: %v", err)
+		return
+	}
+
+	d.sendMu.Lock()
+	defer d.sendMu.Unlock()
+	d.mu.Lock()
+	msg, err := d.marshalState()
+	d.clients[client] = struct{}{}
+	d.mu.Unlock()
+	if err == nil {
+		err = client.WriteText(msg)
+	}
+	if err != nil {
+		d.removeClient(client)
+		return
+	}
+	go func() {
+		<-client.Done()
+		d.removeClient(client)
+	}()
+}
+
+func (d *dashboard) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(dashboardHTML))
+}
@@ -0,0 +1,119 @@
+package cmd
+
+// dashboardHTML is the page served by the dashboard. It connects to the /ws
+// endpoint and renders every state it receives.
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>gotestsum</title>
+<style>
+body { font-family: sans-serif; margin: 1.5em; color: #222; }
+h1 { font-size: 1.3em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { text-align: left; padding: 0.2em 0.8em; border-bottom: 1px solid #ddd; }
+td.num { text-align: right; }
+.pass { color: #1a7f37; }
+.fail { color: #cf222e; }
+.skip { color: #9a6700; }
+.running { color: #0969da; }
+pre { background: #f6f8fa; padding: 0.6em; overflow-x: auto; }
+#status { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>gotestsum <span id="status" class="running">connecting</span></h1>
+<p id="totals"></p>
+<h2>Failures</h2>
+<div id="failures"><p>None</p></div>
+<h2>Packages</h2>
+<table>
+<thead><tr><th>Package</th><th>Result</th><th>Tests</th><th>Failed</th><th>Skipped</th><th>Running</th><th>Elapsed</th></tr></thead>
+<tbody id="packages"></tbody>
+</table>
+<script>
+function el(tag, text, cls) {
+  var e = document.createElement(tag);
+  if (text !== undefined) { e.textContent = text; }
+  if (cls) { e.className = cls; }
+  return e;
+}
+
+function seconds(s) {
+  return s.toFixed(2) + "s";
+}
+
+function render(state) {
+  var status = document.getElementById("status");
+  if (state.done) {
+    status.textContent = state.failed > 0 || (state.errors || []).length > 0 ? "failed" : "passed";
+    status.className = state.failed > 0 ? "fail" : "pass";
+  } else {
+    status.textContent = "running";
+    status.className = "running";
+  }
+  document.getElementById("totals").textContent =
+    state.tests + " tests, " + state.failed + " failed, " + state.skipped +
+    " skipped, " + state.running + " running in " + seconds(state.elapsed);
+
+  var tbody = document.getElementById("packages");
+  tbody.textContent = "";
+  (state.packages || []).forEach(function (pkg) {
+    var tr = el("tr");
+    tr.appendChild(el("td", pkg.name));
+    tr.appendChild(el("td", pkg.result, pkg.result));
+    tr.appendChild(el("td", pkg.tests, "num"));
+    tr.appendChild(el("td", pkg.failed, "num"));
+    tr.appendChild(el("td", pkg.skipped, "num"));
+    tr.appendChild(el("td", pkg.running, "num"));
+    tr.appendChild(el("td", pkg.result === "running" ? "" : seconds(pkg.elapsed), "num"));
+    tbody.appendChild(tr);
+  });
+
+  var failures = document.getElementById("failures");
+  failures.textContent = "";
+  var items = (state.errors || []).map(function (e) { return {title: "Error", output: e}; });
+  (state.failures || []).forEach(function (tc) {
+    items.push({
+      title: tc.package + " " + tc.test + " (" + seconds(tc.elapsed) + ")",
+      output: tc.output,
+      requirements: tc.requirements
+    });
+  });
+  if (items.length === 0) {
+    failures.appendChild(el("p", "None"));
+  }
+  items.forEach(function (item) {
+    failures.appendChild(el("h3", item.title, "fail"));
+    var links = (item.requirements || []).filter(function (req) { return req.url; });
+    if (links.length > 0) {
+      var p = el("p", "Requirements: ");
+      links.forEach(function (req, i) {
+        var a = el("a", req.id);
+        a.href = req.url;
+        if (i > 0) { p.appendChild(document.createTextNode(", ")); }
+        p.appendChild(a);
+      });
+      failures.appendChild(p);
+    }
+    failures.appendChild(el("pre", item.output));
+  });
+}
+
+function connect() {
+  var proto = location.protocol === "https:" ? "wss://" : "ws://";
+  var ws = new WebSocket(proto + location.host + "/ws");
+  ws.onmessage = function (msg) { render(JSON.parse(msg.data)); };
+  ws.onclose = function () {
+    var status = document.getElementById("status");
+    if (status.textContent === "running" || status.textContent === "connecting") {
+      status.textContent = "disconnected";
+      status.className = "skip";
+    }
+  };
+}
+connect();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+type dashboardHandler struct {
+	dashboard *dashboard
+}
+
+func (h dashboardHandler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	h.dashboard.update(event, exec)
+	return nil
+}
+
+func (h dashboardHandler) Err(string) error {
+	return nil
+}
+
+func TestDashboard(t *testing.T) {
+	d, err := startDashboard(&options{serveUIAddr: "127.0.0.1:0", stderr: ioutil.Discard})
+	assert.NilError(t, err)
+	defer d.close()
+
+	f, err := os.Open("../testjson/testdata/input/go-test-json.out")
+	assert.NilError(t, err)
+	defer f.Close() // nolint: errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  f,
+		Stderr:  strings.NewReader(""),
+		Handler: dashboardHandler{dashboard: d},
+	})
+	assert.NilError(t, err)
+	d.finish(exec)
+
+	resp, err := http.Get("http://" + d.listener.Addr().String() + "/api/state")
+	assert.NilError(t, err)
+	defer resp.Body.Close() // nolint: errcheck
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	var state dashboardState
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&state))
+	assert.Assert(t, state.Done)
+	assert.Equal(t, state.Tests, exec.Total())
+	var failed int
+	for _, name := range exec.Packages() {
+		failed += len(exec.Package(name).Failed)
+	}
+	assert.Equal(t, state.Failed, failed)
+	assert.Equal(t, state.Skipped, len(exec.Skipped()))
+	assert.Equal(t, state.Running, 0)
+	assert.Equal(t, len(state.Packages), len(exec.Packages()))
+	assert.Equal(t, len(state.Failures), failed)
+	for _, pkg := range state.Packages {
+		assert.Equal(t, pkg.Result, string(exec.Package(pkg.Name).Result()), pkg.Name)
+	}
+
+	index, err := http.Get("http://" + d.listener.Addr().String() + "/")
+	assert.NilError(t, err)
+	defer index.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(index.Body)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(body), `new WebSocket(`))
+}
+
+type dashboardStateHandler struct {
+	dashboard *dashboard
+	states    []dashboardState
+}
+
+func (h *dashboardStateHandler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	h.dashboard.update(event, exec)
+	h.dashboard.mu.Lock()
+	msg, err := h.dashboard.marshalState()
+	h.dashboard.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	var state dashboardState
+	if err := json.Unmarshal(msg, &state); err != nil {
+		return err
+	}
+	h.states = append(h.states, state)
+	return nil
+}
+
+func (h *dashboardStateHandler) Err(string) error {
+	return nil
+}
+
+func TestDashboard_RunningTests(t *testing.T) {
+	d := &dashboard{packages: make(map[string]*dashboardPackage)}
+	handler := &dashboardStateHandler{dashboard: d}
+	events := strings.Join([]string{
+		`{"Time":"2024-01-01T10:00:00Z","Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Time":"2024-01-01T10:00:01Z","Action":"run","Package":"example.com/a","Test":"TestTwo"}`,
+		`{"Time":"2024-01-01T10:00:02Z","Action":"pass","Package":"example.com/a","Test":"TestOne"}`,
+	}, "\n")
+	_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(events),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	var names [][]string
+	for _, state := range handler.states {
+		var running []string
+		for _, tc := range state.RunningTests {
+			running = append(running, tc.Test)
+			assert.Equal(t, tc.Package, "example.com/a")
+			assert.Assert(t, tc.Elapsed > 0)
+		}
+		names = append(names, running)
+	}
+	// the last state is from the event that ends TestTwo when the scan ends
+	expected := [][]string{{"TestOne"}, {"TestOne", "TestTwo"}, {"TestTwo"}, nil}
+	assert.DeepEqual(t, names, expected)
+}
+
+func TestDashboard_NilIsDisabled(t *testing.T) {
+	d, err := startDashboard(&options{})
+	assert.NilError(t, err)
+	assert.Assert(t, d == nil)
+	d.update(testjson.TestEvent{Action: testjson.ActionRun}, nil)
+	d.finish(nil)
+	d.close()
+}
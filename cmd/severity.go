@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// severity is the importance of a test failure. A higher value is more
+// important.
+type severity int
+
+const (
+	severityMinor severity = iota + 1
+	severityMajor
+	severityBlocker
+)
+
+// severities are sorted from the most important.
+var severities = []severity{severityBlocker, severityMajor, severityMinor}
+
+func (s severity) String() string {
+	switch s {
+	case severityMinor:
+		return "minor"
+	case severityMajor:
+		return "major"
+	case severityBlocker:
+		return "blocker"
+	}
+	return ""
+}
+
+func parseSeverity(name string) (severity, error) {
+	for _, s := range severities {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid severity %q, must be one of: blocker, major, minor", name)
+}
+
+// severityValue is a flag.Value for --fail-on-severity.
+type severityValue struct {
+	value severity
+}
+
+func (v *severityValue) Set(raw string) error {
+	s, err := parseSeverity(strings.TrimSpace(raw))
+	if err != nil {
+		return err
+	}
+	v.value = s
+	return nil
+}
+
+func (v *severityValue) String() string {
+	return v.value.String()
+}
+
+func (v *severityValue) Type() string {
+	return "severity"
+}
+
+// severityRule sets the severity of the failures that match.
+type severityRule struct {
+	severity severity
+	failureMatcher
+}
+
+// severityRulesValue is a flag.Value for the --severity-rules file. Each line
+// of the file is a severity and a rule, like:
+//
+//	blocker package=./payments/...
+//	major test=^TestIntegration
+//	minor output=connection refused
+//
+// The severity of a failure is the severity of the first rule that matches.
+// Failures that do not match any rule are major. Blank lines and lines that
+// start with # are ignored.
+type severityRulesValue struct {
+	filename string
+	rules    []severityRule
+}
+
+func (v *severityRulesValue) Set(filename string) error {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read severity rules: %w", err)
+	}
+	var rules []severityRule
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseSeverityRule(line)
+		if err != nil {
+			return fmt.Errorf("%v:%d: %w", filename, lineNum, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read severity rules: %w", err)
+	}
+	v.filename = filename
+	v.rules = rules
+	return nil
+}
+
+func parseSeverityRule(line string) (severityRule, error) {
+	i := strings.IndexAny(line, " \t")
+	if i < 0 {
+		return severityRule{}, fmt.Errorf("expected a severity and a rule, like: major package=./api/...")
+	}
+	s, err := parseSeverity(line[:i])
+	if err != nil {
+		return severityRule{}, err
+	}
+	matcher, err := parseFailureMatcher(strings.TrimSpace(line[i:]))
+	if err != nil {
+		return severityRule{}, err
+	}
+	return severityRule{severity: s, failureMatcher: matcher}, nil
+}
+
+func (v *severityRulesValue) String() string {
+	return v.filename
+}
+
+func (v *severityRulesValue) Type() string {
+	return "filename"
+}
+
+func (v *severityRulesValue) enabled() bool {
+	return len(v.rules) > 0
+}
+
+// forTest returns the severity of the failure of a test with output.
+func (v *severityRulesValue) forTest(tc testjson.TestCase, output string) severity {
+	for _, rule := range v.rules {
+		if rule.match(tc, output) {
+			return rule.severity
+		}
+	}
+	return severityMajor
+}
+
+// forFailure returns the severity of a failed test in exec.
+func (v *severityRulesValue) forFailure(exec *testjson.Execution, tc testjson.TestCase) severity {
+	return v.forTest(tc, failureOutput(exec, tc))
+}
+
+// junitProperties returns the function that adds the severity property to
+// the failed test cases in the junit.xml file, or nil when there are no
+// rules.
+func (v *severityRulesValue) junitProperties(exec *testjson.Execution) func(tc testjson.TestCase) []junitxml.JUnitProperty {
+	if !v.enabled() {
+		return nil
+	}
+	return func(tc testjson.TestCase) []junitxml.JUnitProperty {
+		pkg := exec.Package(tc.Package)
+		if pkg == nil || !isFailed(pkg, tc) {
+			return nil
+		}
+		return []junitxml.JUnitProperty{{Name: "severity", Value: v.forFailure(exec, tc).String()}}
+	}
+}
+
+func isFailed(pkg *testjson.Package, tc testjson.TestCase) bool {
+	if tc.Test == "TestMain" && pkg.TestMainFailed() {
+		return true
+	}
+	for _, failed := range pkg.Failed {
+		if failed.ID == tc.ID && failed.Test == tc.Test {
+			return true
+		}
+	}
+	return false
+}
+
+// severityFailure is a test that failed, with the severity of the failure.
+type severityFailure struct {
+	name     string
+	severity severity
+}
+
+// classify returns the severity of each test that failed in its last run, or
+// nil when there are no rules.
+func (v *severityRulesValue) classify(exec *testjson.Execution) []severityFailure {
+	if !v.enabled() || exec == nil {
+		return nil
+	}
+	var result []severityFailure
+	for _, tc := range failingTestCases(exec) {
+		result = append(result, severityFailure{
+			name:     baselineName(tc),
+			severity: v.forFailure(exec, tc),
+		})
+	}
+	return result
+}
+
+// severityExitErr returns nil when the run failed only because of test
+// failures, and every failure that is not known to fail has a severity lower
+// than failOn. Build errors, timeouts, and panics are never ignored.
+func severityExitErr(
+	exec *testjson.Execution,
+	exitErr error,
+	failures []severityFailure,
+	failOn severity,
+	known func(name string) bool,
+) error {
+	if failOn == 0 || !canIgnoreFailures(exec, exitErr) {
+		return exitErr
+	}
+	for _, f := range failures {
+		if f.severity >= failOn && !known(f.name) {
+			return exitErr
+		}
+	}
+	return nil
+}
+
+// printSummary prints the failures grouped by severity, from the most
+// important.
+func (v *severityRulesValue) printSummary(out io.Writer, failures []severityFailure) {
+	if !v.enabled() {
+		return
+	}
+	counts := make(map[severity]int)
+	for _, f := range failures {
+		counts[f.severity]++
+	}
+	fmt.Fprintf(out, "\n=== Severity: %d blocker, %d major, %d minor\n",
+		counts[severityBlocker], counts[severityMajor], counts[severityMinor])
+	for _, s := range severities {
+		for _, f := range failures {
+			if f.severity == s {
+				fmt.Fprintf(out, "=== %s: %s\n", strings.ToUpper(s.String()), f.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestSeverityRulesValue_Set(t *testing.T) {
+	type testCase struct {
+		name     string
+		content  string
+		expected string
+	}
+	run := func(t *testing.T, tc testCase) {
+		file := fs.NewFile(t, "rules.txt", fs.WithContent(tc.content))
+		var v severityRulesValue
+		err := v.Set(file.Path())
+		if tc.expected == "" {
+			assert.NilError(t, err)
+			return
+		}
+		assert.ErrorContains(t, err, tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name: "valid",
+			content: `# rules
+blocker package=./payments/...
+major	test=^TestIntegration
+
+minor output=connection refused
+`,
+		},
+		{
+			name:     "unknown severity",
+			content:  "critical package=./api",
+			expected: `:1: invalid severity "critical", must be one of: blocker, major, minor`,
+		},
+		{
+			name:     "unknown rule",
+			content:  "\nminor file=api.go",
+			expected: `:2: invalid rule "file=api.go", must be package=, test=, or output=`,
+		},
+		{
+			name:     "missing rule",
+			content:  "minor",
+			expected: ":1: expected a severity and a rule",
+		},
+		{
+			name:     "invalid regexp",
+			content:  "minor test=Test(",
+			expected: `:1: invalid test pattern "Test("`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func scanSeverityRun(t *testing.T) *testjson.Execution {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/payments","Test":"TestCharge"}
+{"Action":"fail","Package":"example.com/payments","Test":"TestCharge"}
+{"Action":"fail","Package":"example.com/payments"}
+{"Action":"run","Package":"example.com/api","Test":"TestIntegrationDB"}
+{"Action":"fail","Package":"example.com/api","Test":"TestIntegrationDB"}
+{"Action":"run","Package":"example.com/api","Test":"TestClient"}
+{"Action":"output","Package":"example.com/api","Test":"TestClient","Output":"dial: connection refused\n"}
+{"Action":"fail","Package":"example.com/api","Test":"TestClient"}
+{"Action":"run","Package":"example.com/api","Test":"TestPass"}
+{"Action":"pass","Package":"example.com/api","Test":"TestPass"}
+{"Action":"fail","Package":"example.com/api"}
+`),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func severityRulesForTest(t *testing.T) severityRulesValue {
+	t.Helper()
+	file := fs.NewFile(t, "rules.txt", fs.WithContent(`
+blocker package=example.com/payments/...
+minor output=connection refused
+minor test=^TestIntegration
+`))
+	var v severityRulesValue
+	assert.NilError(t, v.Set(file.Path()))
+	return v
+}
+
+func TestSeverityRulesValue_Classify(t *testing.T) {
+	v := severityRulesForTest(t)
+	exec := scanSeverityRun(t)
+
+	failures := v.classify(exec)
+	expected := []severityFailure{
+		{name: "example.com/api.TestClient", severity: severityMinor},
+		{name: "example.com/api.TestIntegrationDB", severity: severityMinor},
+		{name: "example.com/payments.TestCharge", severity: severityBlocker},
+	}
+	assert.DeepEqual(t, failures, expected, gocmp.AllowUnexported(severityFailure{}))
+
+	out := new(bytes.Buffer)
+	v.printSummary(out, failures)
+	assert.Equal(t, out.String(), `
+=== Severity: 1 blocker, 0 major, 2 minor
+=== BLOCKER: example.com/payments.TestCharge
+=== MINOR: example.com/api.TestClient
+=== MINOR: example.com/api.TestIntegrationDB
+`)
+
+	var empty severityRulesValue
+	assert.Assert(t, empty.classify(exec) == nil)
+	out.Reset()
+	empty.printSummary(out, nil)
+	assert.Equal(t, out.String(), "")
+}
+
+func TestSeverityExitErr(t *testing.T) {
+	exec := scanSeverityRun(t)
+	failures := []severityFailure{
+		{name: "example.com/api.TestClient", severity: severityMinor},
+		{name: "example.com/payments.TestCharge", severity: severityBlocker},
+	}
+	exitErr := exitError{num: 1}
+	var baseline baselineValue
+
+	assert.Equal(t, severityExitErr(exec, exitErr, failures, 0, baseline.contains), exitErr)
+	assert.Equal(t, severityExitErr(exec, exitErr, failures, severityMajor, baseline.contains), exitErr)
+	assert.NilError(t, severityExitErr(exec, exitErr, failures[:1], severityMajor, baseline.contains))
+	assert.Equal(t, severityExitErr(exec, exitErr, failures[:1], severityMinor, baseline.contains), exitErr)
+	assert.Equal(t, severityExitErr(exec, exitError{num: 2}, failures[:1], severityMajor, baseline.contains),
+		exitError{num: 2})
+
+	baseline.tests = map[string]bool{"example.com/payments.TestCharge": true}
+	assert.NilError(t, severityExitErr(exec, exitErr, failures, severityMajor, baseline.contains))
+}
+
+func TestSeverityRulesValue_JUnitProperties(t *testing.T) {
+	v := severityRulesForTest(t)
+	exec := scanSeverityRun(t)
+
+	props := v.junitProperties(exec)
+	pkg := exec.Package("example.com/payments")
+	assert.DeepEqual(t, props(pkg.Failed[0]), []junitxml.JUnitProperty{
+		{Name: "severity", Value: "blocker"},
+	})
+	passed := exec.Package("example.com/api").Passed[0]
+	assert.Assert(t, props(passed) == nil)
+
+	var empty severityRulesValue
+	assert.Assert(t, empty.junitProperties(exec) == nil)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// signatureExt is added to the name of a report file to get the name of the
+// file with its detached signature.
+const signatureExt = ".sig"
+
+// signingKeyValue is a flag.Value for the --sign-report private key. The key
+// is read when the flag is set, so that a bad key fails the run before any
+// tests are run.
+type signingKeyValue struct {
+	filename string
+	signer   crypto.Signer
+}
+
+func (v *signingKeyValue) Set(filename string) error {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read signing key: %w", err)
+	}
+	signer, err := parseSigningKey(raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse signing key %v: %w", filename, err)
+	}
+	v.filename = filename
+	v.signer = signer
+	return nil
+}
+
+func (v *signingKeyValue) String() string {
+	return v.filename
+}
+
+func (v *signingKeyValue) Type() string {
+	return "filename"
+}
+
+// parseSigningKey returns the private key from a PEM file. The key may be an
+// RSA, ECDSA, or Ed25519 key, in PKCS #8, PKCS #1, or SEC 1 form. Encrypted
+// keys are not supported.
+func parseSigningKey(raw []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, raw = pem.Decode(raw)
+		if block == nil {
+			return nil, fmt.Errorf("no private key found")
+		}
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("unsupported private key type %T", key)
+			}
+			return signer, nil
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "ENCRYPTED PRIVATE KEY":
+			return nil, fmt.Errorf("encrypted private keys are not supported")
+		}
+		// skip other blocks, like EC PARAMETERS or a certificate
+	}
+}
+
+// signReports writes a detached signature for the --jsonfile, --junitfile,
+// and --test-manifest.
+func signReports(opts *options) error {
+	if opts.signReport.signer == nil {
+		return nil
+	}
+	for _, filename := range []string{opts.jsonFile, opts.junitFile, opts.testManifest} {
+		if filename == "" {
+			continue
+		}
+		if err := signReportFile(opts.signReport.signer, filename); err != nil {
+			return fmt.Errorf("failed to sign %v: %w", filename, err)
+		}
+	}
+	return nil
+}
+
+// signReportFile writes the signature of filename to filename.sig. The
+// signature is the raw signature bytes, in the same form as the signature
+// written by 'openssl dgst -sha256 -sign', so that it can be verified with
+// 'openssl dgst -sha256 -verify'. Ed25519 signatures are of the whole file,
+// and can be verified with 'openssl pkeyutl -verify -rawin'.
+func signReportFile(signer crypto.Signer, filename string) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	sig, err := sign(signer, content)
+	if err != nil {
+		return err
+	}
+	log.Debugf("writing signature of %v to %v", filename, filename+signatureExt)
+	return ioutil.WriteFile(filename+signatureExt, sig, 0o644)
+}
+
+func sign(signer crypto.Signer, content []byte) ([]byte, error) {
+	switch signer.(type) {
+	case ed25519.PrivateKey:
+		return signer.Sign(rand.Reader, content, crypto.Hash(0))
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		digest := sha256.Sum256(content)
+		return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", signer)
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestSignReports(t *testing.T) {
+	type testCase struct {
+		name   string
+		key    func(t *testing.T) (crypto.Signer, *pem.Block)
+		verify func(t *testing.T, pub crypto.PublicKey, content, sig []byte)
+	}
+
+	verifyDigest := func(t *testing.T, pub crypto.PublicKey, content, sig []byte) {
+		digest := sha256.Sum256(content)
+		switch pub := pub.(type) {
+		case *rsa.PublicKey:
+			assert.NilError(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig))
+		case *ecdsa.PublicKey:
+			assert.Assert(t, ecdsa.VerifyASN1(pub, digest[:], sig))
+		default:
+			t.Fatalf("unexpected key type %T", pub)
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name: "ed25519 PKCS8",
+			key: func(t *testing.T) (crypto.Signer, *pem.Block) {
+				_, key, err := ed25519.GenerateKey(rand.Reader)
+				assert.NilError(t, err)
+				der, err := x509.MarshalPKCS8PrivateKey(key)
+				assert.NilError(t, err)
+				return key, &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+			},
+			verify: func(t *testing.T, pub crypto.PublicKey, content, sig []byte) {
+				assert.Assert(t, ed25519.Verify(pub.(ed25519.PublicKey), content, sig))
+			},
+		},
+		{
+			name: "ecdsa SEC1",
+			key: func(t *testing.T) (crypto.Signer, *pem.Block) {
+				key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				assert.NilError(t, err)
+				der, err := x509.MarshalECPrivateKey(key)
+				assert.NilError(t, err)
+				return key, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+			},
+			verify: verifyDigest,
+		},
+		{
+			name: "rsa PKCS1",
+			key: func(t *testing.T) (crypto.Signer, *pem.Block) {
+				key, err := rsa.GenerateKey(rand.Reader, 2048)
+				assert.NilError(t, err)
+				der := x509.MarshalPKCS1PrivateKey(key)
+				return key, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}
+			},
+			verify: verifyDigest,
+		},
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		key, block := tc.key(t)
+		dir := fs.NewDir(t, "sign-report",
+			fs.WithFile("key.pem", string(pem.EncodeToMemory(block))),
+			fs.WithFile("go-test.json", `{"Action":"pass"}`+"\n"),
+			fs.WithFile("junit.xml", "<testsuites></testsuites>\n"))
+
+		opts := &options{
+			jsonFile:  dir.Join("go-test.json"),
+			junitFile: dir.Join("junit.xml"),
+		}
+		assert.NilError(t, opts.signReport.Set(dir.Join("key.pem")))
+		assert.NilError(t, signReports(opts))
+
+		for _, filename := range []string{opts.jsonFile, opts.junitFile} {
+			content, err := ioutil.ReadFile(filename)
+			assert.NilError(t, err)
+			sig, err := ioutil.ReadFile(filename + ".sig")
+			assert.NilError(t, err)
+			tc.verify(t, key.Public(), content, sig)
+		}
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestSignReports_NotSet(t *testing.T) {
+	dir := fs.NewDir(t, "sign-report", fs.WithFile("junit.xml", ""))
+	opts := &options{junitFile: dir.Join("junit.xml")}
+	assert.NilError(t, signReports(opts))
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t, fs.WithFile("junit.xml", ""))))
+}
+
+func TestParseSigningKey_Errors(t *testing.T) {
+	_, err := parseSigningKey([]byte("not a key"))
+	assert.Error(t, err, "no private key found")
+
+	encrypted := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("x")})
+	_, err = parseSigningKey(encrypted)
+	assert.Error(t, err, "encrypted private keys are not supported")
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")})
+	_, err = parseSigningKey(cert)
+	assert.Error(t, err, "no private key found")
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// skipCategoryOther is the category of skipped tests with output that does
+// not match any category.
+const skipCategoryOther = "other"
+
+// skipCategoryProperty is the name of the testcase property with the
+// category of a skipped test.
+const skipCategoryProperty = "skip.category"
+
+// skipCategory is a reason a test was skipped. A skipped test is in the
+// category when the output of the test matches the pattern.
+type skipCategory struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// defaultSkipCategories are the reasons that tests are most often skipped,
+// using the messages printed by t.Skip in the standard library and in
+// commonly used test helpers.
+var defaultSkipCategories = []skipCategory{
+	{
+		name:    "short",
+		pattern: regexp.MustCompile(`(?i)short mode|testing\.Short|-short\b`),
+	},
+	{
+		name: "missing-env",
+		pattern: regexp.MustCompile(`(?i)\b(not set|unset|is empty|environment variable|env var)\b|` +
+			`\b[A-Z][A-Z0-9]*_[A-Z0-9_]+\b.*\b(required|missing)\b`),
+	},
+	{
+		name: "platform",
+		pattern: regexp.MustCompile(`(?i)\b(on|for|requires|only) (windows|linux|darwin|macos|freebsd|openbsd|` +
+			`netbsd|plan9|js|wasm|wasip1|arm|arm64|amd64|386)\b|not supported on|unsupported (os|platform)|GOOS|GOARCH`),
+	},
+}
+
+// skipCategoriesValue is a flag.Value for the --skip-category name=regexp
+// categories. It may be set more than once. The categories are checked in
+// the order they were set, before the default categories.
+type skipCategoriesValue struct {
+	raw        []string
+	categories []skipCategory
+}
+
+func (v *skipCategoriesValue) Set(raw string) error {
+	parts := strings.SplitN(raw, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || parts[1] == "" {
+		return fmt.Errorf("invalid skip category %q, must be in the form name=regexp", raw)
+	}
+	pattern, err := regexp.Compile(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid skip category %q: %w", raw, err)
+	}
+	v.raw = append(v.raw, raw)
+	v.categories = append(v.categories, skipCategory{name: strings.TrimSpace(parts[0]), pattern: pattern})
+	return nil
+}
+
+func (v *skipCategoriesValue) String() string {
+	return strings.Join(v.raw, ",")
+}
+
+func (v *skipCategoriesValue) Type() string {
+	return "name=regexp"
+}
+
+// categorize returns the name of the first category that matches the output
+// of a skipped test, or skipCategoryOther.
+func (v *skipCategoriesValue) categorize(output string) string {
+	for _, categories := range [][]skipCategory{v.categories, defaultSkipCategories} {
+		for _, c := range categories {
+			if c.pattern.MatchString(output) {
+				return c.name
+			}
+		}
+	}
+	return skipCategoryOther
+}
+
+// skipCategoryFunc returns the category of a test case in exec, or an empty
+// string when the test case was not skipped.
+func skipCategoryFunc(opts *options, exec *testjson.Execution) func(tc testjson.TestCase) string {
+	return func(tc testjson.TestCase) string {
+		pkg := exec.Package(tc.Package)
+		if pkg == nil || !isSkipped(pkg, tc) {
+			return ""
+		}
+		return opts.skipCategories.categorize(strings.Join(pkg.OutputLines(tc), ""))
+	}
+}
+
+func isSkipped(pkg *testjson.Package, tc testjson.TestCase) bool {
+	for _, skipped := range pkg.Skipped {
+		if skipped.ID == tc.ID && skipped.Test == tc.Test {
+			return true
+		}
+	}
+	return false
+}
+
+// skipCategoryJUnitProperties returns the function that adds the category
+// property to the skipped test cases in the junit.xml file.
+func skipCategoryJUnitProperties(opts *options, exec *testjson.Execution) func(tc testjson.TestCase) []junitxml.JUnitProperty {
+	category := skipCategoryFunc(opts, exec)
+	return func(tc testjson.TestCase) []junitxml.JUnitProperty {
+		if c := category(tc); c != "" {
+			return []junitxml.JUnitProperty{{Name: skipCategoryProperty, Value: c}}
+		}
+		return nil
+	}
+}
+
+type skipCategoryCount struct {
+	name  string
+	count int
+}
+
+// countSkipCategories returns the number of skipped tests in each category,
+// sorted from the largest category.
+func countSkipCategories(opts *options, exec *testjson.Execution) []skipCategoryCount {
+	counts := make(map[string]int)
+	for _, tc := range exec.Skipped() {
+		if opts.hideExamples && tc.Test.IsExample() {
+			continue
+		}
+		pkg := exec.Package(tc.Package)
+		counts[opts.skipCategories.categorize(strings.Join(pkg.OutputLines(tc), ""))]++
+	}
+	result := make([]skipCategoryCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, skipCategoryCount{name: name, count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].count != result[j].count {
+			return result[i].count > result[j].count
+		}
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+// printSkipCategories prints the number of skipped tests in each category,
+// so that it is easy to see how much of the suite is not run, and why.
+func printSkipCategories(out io.Writer, exec *testjson.Execution, opts *options) {
+	if !opts.hideSummary.value.Includes(testjson.SummarizeSkipped) {
+		return
+	}
+	counts := countSkipCategories(opts, exec)
+	if len(counts) == 0 {
+		return
+	}
+	var skipped int
+	parts := make([]string, 0, len(counts))
+	for _, c := range counts {
+		skipped += c.count
+		parts = append(parts, fmt.Sprintf("%d %s", c.count, c.name))
+	}
+	total := exec.Total()
+	if opts.hideExamples {
+		total -= exec.Examples()
+	}
+	fmt.Fprintf(out, "\n=== Skip categories: %d of %d tests skipped (%s): %s\n",
+		skipped, total, formatPercent(skipped, total), strings.Join(parts, ", "))
+}
+
+func formatPercent(n, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.0f%%", float64(n)*100/float64(total))
+}
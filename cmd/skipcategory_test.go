@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestSkipCategoriesValue(t *testing.T) {
+	var v skipCategoriesValue
+	assert.NilError(t, v.Set("docker=(?i)docker"))
+	assert.NilError(t, v.Set("flaky=quarantined"))
+	assert.Equal(t, v.String(), "docker=(?i)docker,flaky=quarantined")
+	assert.Equal(t, v.Type(), "name=regexp")
+
+	assert.ErrorContains(t, v.Set("docker"), "must be in the form name=regexp")
+	assert.ErrorContains(t, v.Set("=docker"), "must be in the form name=regexp")
+	assert.ErrorContains(t, v.Set("docker=("), "invalid skip category")
+}
+
+func TestSkipCategoriesValue_Categorize(t *testing.T) {
+	type testCase struct {
+		output   string
+		expected string
+	}
+	run := func(t *testing.T, tc testCase) {
+		var v skipCategoriesValue
+		assert.NilError(t, v.Set("docker=(?i)docker is not running"))
+		assert.Equal(t, v.categorize(tc.output), tc.expected)
+	}
+
+	testCases := map[string]testCase{
+		"short mode": {
+			output:   "    db_test.go:12: skipping in short mode\n--- SKIP: TestDB (0.00s)\n",
+			expected: "short",
+		},
+		"testing.Short": {
+			output:   "    db_test.go:12: slow test, skipped when testing.Short() is true\n",
+			expected: "short",
+		},
+		"missing environment variable": {
+			output:   "    s3_test.go:20: AWS_ACCESS_KEY_ID not set\n",
+			expected: "missing-env",
+		},
+		"required environment variable": {
+			output:   "    s3_test.go:20: INTEGRATION_DB_URL is required\n",
+			expected: "missing-env",
+		},
+		"platform": {
+			output:   "    signal_test.go:8: not supported on windows\n",
+			expected: "platform",
+		},
+		"GOOS": {
+			output:   "    signal_test.go:8: skipping on GOOS=plan9\n",
+			expected: "platform",
+		},
+		"custom category before the defaults": {
+			output:   "    docker_test.go:8: docker is not running, not set up\n",
+			expected: "docker",
+		},
+		"other": {
+			output:   "    todo_test.go:8: TODO: fix this test\n",
+			expected: "other",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func scanSkippedRun(t *testing.T) *testjson.Execution {
+	t.Helper()
+	events := []string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestDB"}`,
+		`{"Action":"output","Package":"example.com/a","Test":"TestDB","Output":"    db_test.go:12: skipping in short mode\n"}`,
+		`{"Action":"skip","Package":"example.com/a","Test":"TestDB"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestCache"}`,
+		`{"Action":"output","Package":"example.com/a","Test":"TestCache","Output":"    cache_test.go:9: skipping in short mode\n"}`,
+		`{"Action":"skip","Package":"example.com/a","Test":"TestCache"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestS3"}`,
+		`{"Action":"output","Package":"example.com/a","Test":"TestS3","Output":"    s3_test.go:20: AWS_REGION not set\n"}`,
+		`{"Action":"skip","Package":"example.com/a","Test":"TestS3"}`,
+		`{"Action":"pass","Package":"example.com/a"}`,
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(strings.Join(events, "\n")),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestPrintSkipCategories(t *testing.T) {
+	exec := scanSkippedRun(t)
+	_, opts := setupFlags("gotestsum")
+
+	out := new(bytes.Buffer)
+	printSkipCategories(out, exec, opts)
+	expected := `
+=== Skip categories: 3 of 4 tests skipped (75%): 2 short, 1 missing-env
+`
+	assert.Equal(t, out.String(), expected)
+
+	assert.NilError(t, opts.hideSummary.Set("skipped"))
+	out.Reset()
+	printSkipCategories(out, exec, opts)
+	assert.Equal(t, out.String(), "")
+}
+
+func TestSkipCategoryJUnitProperties(t *testing.T) {
+	exec := scanSkippedRun(t)
+	props := skipCategoryJUnitProperties(&options{}, exec)
+	pkg := exec.Package("example.com/a")
+
+	assert.DeepEqual(t, props(pkg.Skipped[2]), []junitxml.JUnitProperty{
+		{Name: "skip.category", Value: "missing-env"},
+	})
+	assert.Assert(t, props(pkg.Passed[0]) == nil)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// serveMetrics serves the state of the test run in the Prometheus text
+// exposition format.
+func (d *dashboard) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	buf := new(bytes.Buffer)
+	d.mu.Lock()
+	if !d.state.Done {
+		d.state.Elapsed = time.Since(d.state.Started).Seconds()
+	}
+	writeMetrics(buf, d.state)
+	d.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+type metric struct {
+	name string
+	kind string
+	help string
+}
+
+var runMetrics = []metric{
+	{name: "gotestsum_tests_started_total", kind: "counter", help: "Number of tests that started."},
+	{name: "gotestsum_tests_failed_total", kind: "counter", help: "Number of tests that failed."},
+	{name: "gotestsum_tests_skipped_total", kind: "counter", help: "Number of tests that were skipped."},
+	{name: "gotestsum_tests_running", kind: "gauge", help: "Number of tests that are running."},
+}
+
+// writeMetrics writes the totals of the run, and the same metrics with a
+// package label for each package, followed by the elapsed time, the time of
+// the last event, and if the run is done.
+func writeMetrics(out io.Writer, state dashboardState) {
+	pkgs := append([]*dashboardPackage{}, state.Packages...)
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Name < pkgs[j].Name
+	})
+
+	totals := []int{state.Tests, state.Failed, state.Skipped, state.Running}
+	for i, m := range runMetrics {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		fmt.Fprintf(out, "%s %d\n", m.name, totals[i])
+		for _, pkg := range pkgs {
+			values := []int{pkg.Tests, pkg.Failed, pkg.Skipped, pkg.Running}
+			fmt.Fprintf(out, "%s{package=\"%s\"} %d\n", m.name, escapeLabelValue(pkg.Name), values[i])
+		}
+	}
+
+	fmt.Fprint(out, "# HELP gotestsum_elapsed_seconds Time since the test run started.\n")
+	fmt.Fprint(out, "# TYPE gotestsum_elapsed_seconds gauge\n")
+	fmt.Fprintf(out, "gotestsum_elapsed_seconds %g\n", state.Elapsed)
+
+	fmt.Fprint(out, "# HELP gotestsum_last_event_timestamp_seconds Time the most recent test event was received.\n")
+	fmt.Fprint(out, "# TYPE gotestsum_last_event_timestamp_seconds gauge\n")
+	var lastEvent float64
+	if !state.LastEvent.IsZero() {
+		lastEvent = float64(state.LastEvent.UnixNano()) / 1e9
+	}
+	fmt.Fprintf(out, "gotestsum_last_event_timestamp_seconds %g\n", lastEvent)
+
+	fmt.Fprint(out, "# HELP gotestsum_done 1 if the test run is done, otherwise 0.\n")
+	fmt.Fprint(out, "# TYPE gotestsum_done gauge\n")
+	var done int
+	if state.Done {
+		done = 1
+	}
+	fmt.Fprintf(out, "gotestsum_done %d\n", done)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
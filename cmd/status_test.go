@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	state := dashboardState{
+		Elapsed:   12.5,
+		Tests:     5,
+		Failed:    1,
+		Skipped:   1,
+		Running:   2,
+		LastEvent: time.Unix(1700000000, 500000000),
+		Packages: []*dashboardPackage{
+			{Name: "example.com/b", Tests: 3, Failed: 1, Running: 2},
+			{Name: `example.com/"a"`, Tests: 2, Skipped: 1},
+		},
+	}
+	out := new(bytes.Buffer)
+	writeMetrics(out, state)
+	expected := `# HELP gotestsum_tests_started_total Number of tests that started.
+# TYPE gotestsum_tests_started_total counter
+gotestsum_tests_started_total 5
+gotestsum_tests_started_total{package="example.com/\"a\""} 2
+gotestsum_tests_started_total{package="example.com/b"} 3
+# HELP gotestsum_tests_failed_total Number of tests that failed.
+# TYPE gotestsum_tests_failed_total counter
+gotestsum_tests_failed_total 1
+gotestsum_tests_failed_total{package="example.com/\"a\""} 0
+gotestsum_tests_failed_total{package="example.com/b"} 1
+# HELP gotestsum_tests_skipped_total Number of tests that were skipped.
+# TYPE gotestsum_tests_skipped_total counter
+gotestsum_tests_skipped_total 1
+gotestsum_tests_skipped_total{package="example.com/\"a\""} 1
+gotestsum_tests_skipped_total{package="example.com/b"} 0
+# HELP gotestsum_tests_running Number of tests that are running.
+# TYPE gotestsum_tests_running gauge
+gotestsum_tests_running 2
+gotestsum_tests_running{package="example.com/\"a\""} 0
+gotestsum_tests_running{package="example.com/b"} 2
+# HELP gotestsum_elapsed_seconds Time since the test run started.
+# TYPE gotestsum_elapsed_seconds gauge
+gotestsum_elapsed_seconds 12.5
+# HELP gotestsum_last_event_timestamp_seconds Time the most recent test event was received.
+# TYPE gotestsum_last_event_timestamp_seconds gauge
+gotestsum_last_event_timestamp_seconds 1.7000000005e+09
+# HELP gotestsum_done 1 if the test run is done, otherwise 0.
+# TYPE gotestsum_done gauge
+gotestsum_done 0
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestStatusServer(t *testing.T) {
+	d, err := startDashboard(&options{statusAddr: "127.0.0.1:0", stderr: ioutil.Discard})
+	assert.NilError(t, err)
+	defer d.close()
+
+	d.update(testjson.TestEvent{Package: "pkg", Test: "TestOne", Action: testjson.ActionRun}, nil)
+	base := "http://" + d.listener.Addr().String()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(base + path)
+		assert.NilError(t, err)
+		defer resp.Body.Close() // nolint: errcheck
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.NilError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("/metrics")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Assert(t, cmp.Contains(body, `gotestsum_tests_running{package="pkg"} 1`))
+
+	code, body = get("/status")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Assert(t, cmp.Contains(body, `"running":1`))
+	assert.Assert(t, !strings.Contains(body, `"lastEvent":"0001-01-01`), body)
+
+	code, _ = get("/")
+	assert.Equal(t, code, http.StatusNotFound)
+}
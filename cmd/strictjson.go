@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func validateStrictJSON(value string) error {
+	switch value {
+	case "", "warn", "fail":
+		return nil
+	}
+	return fmt.Errorf("--strict-json must be warn or fail, not %q", value)
+}
+
+// checkStrictJSON prints a warning for each line of the go test output that
+// was not a valid test2json event. When --strict-json=fail the invalid lines
+// are an error.
+func checkStrictJSON(opts *options, execution *testjson.Execution) error {
+	if opts.strictJSON == "" {
+		return nil
+	}
+	lines := execution.InvalidLines()
+	for _, line := range lines {
+		text := line.Text
+		if opts.scrubOutput != nil {
+			text = opts.scrubOutput(text)
+		}
+		log.Warnf("go test output line %d (byte offset %d) of run %d: %v: %v",
+			line.Line, line.Offset, line.RunID, line.Reason, text)
+	}
+	if opts.strictJSON == "fail" && len(lines) > 0 {
+		return fmt.Errorf("%d lines of the go test output are not valid test2json events", len(lines))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckStrictJSON(t *testing.T) {
+	events := []string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`=== RUN   TestOne`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"pass","Package":"example.com/a"}`,
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:                   strings.NewReader(strings.Join(events, "\n")),
+		StrictJSON:               true,
+		IgnoreNonJSONOutputLines: true,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.InvalidLines()), 1)
+
+	t.Run("not enabled", func(t *testing.T) {
+		assert.NilError(t, checkStrictJSON(&options{}, exec))
+	})
+	t.Run("warn", func(t *testing.T) {
+		assert.NilError(t, checkStrictJSON(&options{strictJSON: "warn"}, exec))
+	})
+	t.Run("fail", func(t *testing.T) {
+		err := checkStrictJSON(&options{strictJSON: "fail"}, exec)
+		assert.ErrorContains(t, err, "1 lines of the go test output are not valid test2json events")
+	})
+}
+
+func TestOptions_Validate_StrictJSON(t *testing.T) {
+	assert.NilError(t, options{strictJSON: "fail"}.Validate())
+	err := options{strictJSON: "error"}.Validate()
+	assert.ErrorContains(t, err, "--strict-json must be warn or fail")
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// stuckAbortGrace is the maximum time to wait for the test binaries to print
+// their stack traces before the run is aborted.
+const stuckAbortGrace = 30 * time.Second
+
+// stuckDetector watches for a test run that has not produced any TestEvents
+// for longer than threshold. When the run is stuck the test binaries are sent
+// a SIGQUIT, which causes them to print the stack of every goroutine and exit.
+// The stack traces are received by gotestsum as output of the package.
+type stuckDetector struct {
+	threshold time.Duration
+	// abort the entire test run after the test binaries that were sent a
+	// SIGQUIT exit.
+	abort bool
+	// lastEvent is the time, in unix nanoseconds, of the most recent event.
+	lastEvent int64
+	// aborted is set to 1 when the run was aborted.
+	aborted int32
+	// pending is the number of signalled test binaries which have not yet
+	// reported a package result.
+	pending int32
+	// mu guards stop, which is set by watch and called by abortRun from
+	// different goroutines.
+	mu   sync.Mutex
+	stop func()
+	once sync.Once
+	// exec is the *testjson.Execution from the most recent event.
+	exec atomic.Value
+	// packageName formats the package of the running tests.
+	packageName func(pkgpath string) string
+}
+
+func newStuckDetector(opts *options) *stuckDetector {
+	if opts.stuckThreshold <= 0 {
+		return nil
+	}
+	return &stuckDetector{
+		threshold:   opts.stuckThreshold,
+		abort:       opts.stuckAbort,
+		packageName: opts.packageName,
+	}
+}
+
+// touch records that an event was received.
+func (d *stuckDetector) touch() {
+	if d == nil {
+		return
+	}
+	atomic.StoreInt64(&d.lastEvent, time.Now().UnixNano())
+}
+
+// received is called by the EventHandler for every event. When the run is
+// being aborted, the run is stopped once every package that was sent a SIGQUIT
+// has reported a result.
+func (d *stuckDetector) received(event testjson.TestEvent, exec *testjson.Execution) {
+	if d == nil {
+		return
+	}
+	d.touch()
+	if exec != nil {
+		d.exec.Store(exec)
+	}
+	if atomic.LoadInt32(&d.aborted) == 0 || !event.PackageEvent() {
+		return
+	}
+	switch event.Action {
+	case testjson.ActionPass, testjson.ActionFail, testjson.ActionSkip:
+		if atomic.AddInt32(&d.pending, -1) <= 0 {
+			d.abortRun()
+		}
+	}
+}
+
+func (d *stuckDetector) abortRun() {
+	d.mu.Lock()
+	stop := d.stop
+	d.mu.Unlock()
+	d.once.Do(stop)
+}
+
+func (d *stuckDetector) execution() *testjson.Execution {
+	exec, _ := d.exec.Load().(*testjson.Execution)
+	return exec
+}
+
+func (d *stuckDetector) idle() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&d.lastEvent)))
+}
+
+// watch the process until it exits. If abort is enabled stop is called to end
+// the run after the process is stuck.
+func (d *stuckDetector) watch(p *proc, stop func()) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.stop = stop
+	d.mu.Unlock()
+	d.touch()
+	go func() {
+		ticker := time.NewTicker(stuckCheckInterval(d.threshold))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+			}
+			idle := d.idle()
+			if idle < d.threshold {
+				continue
+			}
+			log.Warnf("No test events received for %v, sending SIGQUIT to the test binaries "+
+				"to print goroutine stack traces", idle.Round(time.Second))
+			if running := formatRunningTests(d.execution(), time.Now(), d.packageName); running != "" {
+				log.Warnf("Tests that are running: %v", running)
+			}
+			sent, err := signalTestBinaries(p.pid)
+			if err != nil {
+				log.Warnf("Failed to send SIGQUIT to the test binaries: %v", err)
+			}
+			d.touch()
+			if !d.abort {
+				continue
+			}
+			atomic.StoreInt32(&d.pending, int32(sent))
+			atomic.StoreInt32(&d.aborted, 1)
+			if sent == 0 {
+				d.abortRun()
+				return
+			}
+			select {
+			case <-p.done:
+			case <-time.After(stuckAbortGrace):
+				d.abortRun()
+			}
+			return
+		}
+	}()
+}
+
+// abortErr returns an error if the run was aborted because it was stuck.
+func (d *stuckDetector) abortErr() error {
+	if d == nil || atomic.LoadInt32(&d.aborted) == 0 {
+		return nil
+	}
+	return categoryError{
+		category: exitTimeout,
+		err:      fmt.Errorf("test run aborted because no test events were received for %v", d.threshold),
+	}
+}
+
+func stuckCheckInterval(threshold time.Duration) time.Duration {
+	interval := threshold / 10
+	if interval < 100*time.Millisecond {
+		return 100 * time.Millisecond
+	}
+	if interval > 10*time.Second {
+		return 10 * time.Second
+	}
+	return interval
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestStuckDetector_Abort(t *testing.T) {
+	d := &stuckDetector{threshold: 10 * time.Millisecond, abort: true}
+	done := make(chan struct{})
+	defer close(done)
+	stopped := make(chan struct{})
+
+	// pid 0 is never signalled, so the run is stopped immediately
+	d.watch(&proc{done: done}, func() { close(stopped) })
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the stuck run to be stopped")
+	}
+	assert.Error(t, d.abortErr(),
+		"test run aborted because no test events were received for 10ms")
+}
+
+func TestStuckDetector_Received(t *testing.T) {
+	var stopped int
+	d := &stuckDetector{
+		threshold: time.Second,
+		abort:     true,
+		aborted:   1,
+		pending:   2,
+		stop:      func() { stopped++ },
+	}
+	d.received(testjson.TestEvent{Package: "one", Test: "TestHang", Action: testjson.ActionFail}, nil)
+	d.received(testjson.TestEvent{Package: "one", Action: testjson.ActionOutput}, nil)
+	assert.Equal(t, stopped, 0)
+	d.received(testjson.TestEvent{Package: "one", Action: testjson.ActionFail}, nil)
+	assert.Equal(t, stopped, 0)
+	d.received(testjson.TestEvent{Package: "two", Action: testjson.ActionFail}, nil)
+	assert.Equal(t, stopped, 1)
+	d.received(testjson.TestEvent{Package: "three", Action: testjson.ActionPass}, nil)
+	assert.Equal(t, stopped, 1)
+}
+
+func TestStuckDetector_NotStuck(t *testing.T) {
+	d := &stuckDetector{threshold: time.Hour, abort: true}
+	done := make(chan struct{})
+	d.watch(&proc{done: done}, func() { t.Error("unexpected stop") })
+	d.touch()
+	close(done)
+	assert.NilError(t, d.abortErr())
+
+	var nilDetector *stuckDetector
+	nilDetector.touch()
+	assert.NilError(t, nilDetector.abortErr())
+}
+
+func TestStuckCheckInterval(t *testing.T) {
+	assert.Equal(t, stuckCheckInterval(time.Millisecond), 100*time.Millisecond)
+	assert.Equal(t, stuckCheckInterval(5*time.Second), 500*time.Millisecond)
+	assert.Equal(t, stuckCheckInterval(5*time.Minute), 10*time.Second)
+}
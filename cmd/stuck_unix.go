@@ -0,0 +1,87 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// signalTestBinaries sends SIGQUIT to every test binary (a process with a
+// name that ends in .test) that is a descendant of the process with pid, and
+// returns the number of processes that were sent the signal.
+func signalTestBinaries(pid int) (int, error) {
+	if pid <= 0 {
+		return 0, fmt.Errorf("invalid pid %d", pid)
+	}
+	out, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=", "-o", "args=").Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list processes: %w", err)
+	}
+	var sent int
+	for _, child := range testBinaryDescendants(parsePS(out), pid) {
+		if err := syscall.Kill(child, syscall.SIGQUIT); err == nil {
+			sent++
+		}
+	}
+	if sent == 0 {
+		return 0, fmt.Errorf("no test binaries were found")
+	}
+	return sent, nil
+}
+
+type psEntry struct {
+	pid     int
+	ppid    int
+	command string
+}
+
+func parsePS(out []byte) []psEntry {
+	var result []psEntry
+	scan := bufio.NewScanner(bytes.NewReader(out))
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		result = append(result, psEntry{pid: pid, ppid: ppid, command: fields[2]})
+	}
+	return result
+}
+
+func testBinaryDescendants(entries []psEntry, root int) []int {
+	children := make(map[int][]psEntry)
+	for _, entry := range entries {
+		children[entry.ppid] = append(children[entry.ppid], entry)
+	}
+
+	var result []int
+	queue := []int{root}
+	seen := map[int]bool{root: true}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, child := range children[next] {
+			if seen[child.pid] {
+				continue
+			}
+			seen[child.pid] = true
+			queue = append(queue, child.pid)
+			if strings.HasSuffix(child.command, ".test") {
+				result = append(result, child.pid)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTestBinaryDescendants(t *testing.T) {
+	out := `    1     0 /sbin/init
+  100     1 /usr/bin/go test -json ./...
+  101   100 /tmp/go-build123/b001/pkg.test -test.paniconexit0 -test.timeout=10m0s
+  102   100 /usr/lib/go/pkg/tool/linux_amd64/compile -o /tmp/go-build123/b002/_pkg_.a
+  103   101 /tmp/go-build123/b001/child.test -test.run=TestHelper
+  200     1 /tmp/go-build999/b001/other.test
+bad line
+`
+	entries := parsePS([]byte(out))
+	assert.Equal(t, len(entries), 6)
+	assert.DeepEqual(t, testBinaryDescendants(entries, 100), []int{101, 103})
+	assert.Assert(t, len(testBinaryDescendants(entries, 102)) == 0)
+}
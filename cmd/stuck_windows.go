@@ -0,0 +1,7 @@
+package cmd
+
+import "fmt"
+
+func signalTestBinaries(_ int) (int, error) {
+	return 0, fmt.Errorf("sending SIGQUIT is not supported on windows")
+}
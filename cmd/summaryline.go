@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// printSummaryLine prints a single line with the totals of the test run. The
+// format of the line is stable so that it can be parsed by tools that scrape
+// logs. New fields may be added to the end of the line.
+func printSummaryLine(out io.Writer, exec *testjson.Execution) {
+	if exec == nil {
+		return
+	}
+	fmt.Fprintf(out, "GOTESTSUM_RESULT total=%d failed=%d skipped=%d errors=%d elapsed=%.1fs reruns=%d\n",
+		exec.Total(),
+		len(exec.Failed()),
+		len(exec.Skipped()),
+		exec.ErrorCount(),
+		exec.Elapsed().Seconds(),
+		countReruns(exec))
+}
+
+// countReruns returns the number of test cases that were run by --rerun-fails.
+func countReruns(exec *testjson.Execution) int {
+	var count int
+	for _, name := range exec.Packages() {
+		for _, tc := range exec.Package(name).TestCases() {
+			if tc.RunID > 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestPrintSummaryLine(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+{"Package": "pkg", "Test": "TestTwo", "Action": "run"}
+{"Package": "pkg", "Test": "TestTwo", "Action": "skip"}
+{"Package": "pkg", "Test": "TestThree", "Action": "run"}
+{"Package": "pkg", "Test": "TestThree", "Action": "pass"}
+{"Package": "pkg", "Action": "fail"}
+`),
+		Stderr: strings.NewReader("an error\n    more about the error\n"),
+	})
+	assert.NilError(t, err)
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		RunID: 1,
+		Stdout: strings.NewReader(`{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+{"Package": "pkg", "Action": "pass"}
+`),
+		Execution: exec,
+	})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	printSummaryLine(out, exec)
+	assert.Assert(t, strings.HasPrefix(out.String(),
+		"GOTESTSUM_RESULT total=4 failed=1 skipped=1 errors=1 elapsed="), out.String())
+	assert.Assert(t, strings.HasSuffix(out.String(), "s reruns=1\n"), out.String())
+}
+
+func TestPrintSummaryLine_NilExecution(t *testing.T) {
+	out := new(bytes.Buffer)
+	printSummaryLine(out, nil)
+	assert.Equal(t, out.String(), "")
+}
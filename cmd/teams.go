@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/textreport"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// teamsMaxFailures is the number of failed tests listed in the card. A card
+// with a long list is hard to read, and may be larger than Teams accepts.
+const teamsMaxFailures = 10
+
+// teamsPublisher is a publisher that sends an Adaptive Card with the result
+// of the run to a Microsoft Teams incoming webhook.
+type teamsPublisher struct {
+	opts *options
+	exec *testjson.Execution
+}
+
+func (p teamsPublisher) name() string {
+	return "teams"
+}
+
+func (p teamsPublisher) publish(_ []byte, _ int) error {
+	report := textreport.New(p.exec, textreport.Config{
+		Labels:                 p.opts.labels.value(),
+		RequirementURLTemplate: p.opts.requirementURLTemplate,
+	})
+	msg := newTeamsMessage(report, vcs(p.opts), p.opts.labels.labels, ciLinks(os.Getenv))
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return postJSON(p.opts.teamsWebhookURL, raw)
+}
+
+func teamsPublishers(opts *options, exec *testjson.Execution) []publisher {
+	if opts.teamsWebhookURL == "" {
+		return nil
+	}
+	return []publisher{teamsPublisher{opts: opts, exec: exec}}
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+	Actions []teamsAction  `json:"actions,omitempty"`
+}
+
+// teamsElement is a TextBlock or a FactSet.
+type teamsElement struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Color  string      `json:"color,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func newTeamsMessage(report textreport.Report, vcs *vcsInfo, labels []label, links []ciLink) teamsMessage {
+	failures := teamsFailureList(report)
+	title, color := "Tests passed", "Good"
+	switch {
+	case failures != "" || len(report.Errors) > 0:
+		title, color = "Tests failed", "Attention"
+	case len(report.Flaky) > 0:
+		title, color = "Tests passed after re-running flaky tests", "Warning"
+	}
+
+	facts := []teamsFact{
+		{Title: "Total", Value: strconv.Itoa(report.Total)},
+		{Title: "Passed", Value: strconv.Itoa(report.Passed)},
+		{Title: "Failed", Value: strconv.Itoa(report.Failed)},
+		{Title: "Skipped", Value: strconv.Itoa(report.Skipped)},
+	}
+	if len(report.Flaky) > 0 {
+		facts = append(facts, teamsFact{Title: "Flaky", Value: strconv.Itoa(len(report.Flaky))})
+	}
+	if len(report.Errors) > 0 {
+		facts = append(facts, teamsFact{Title: "Errors", Value: strconv.Itoa(len(report.Errors))})
+	}
+	facts = append(facts, teamsFact{Title: "Elapsed", Value: fmt.Sprintf("%.3fs", report.Elapsed.Seconds())})
+	if vcs != nil {
+		facts = append(facts, teamsFact{Title: "Commit", Value: vcs.Commit})
+		if vcs.Branch != "" {
+			facts = append(facts, teamsFact{Title: "Branch", Value: vcs.Branch})
+		}
+	}
+	for _, l := range labels {
+		facts = append(facts, teamsFact{Title: l.key, Value: l.value})
+	}
+
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body: []teamsElement{
+			{Type: "TextBlock", Text: title, Size: "Medium", Weight: "Bolder", Color: color, Wrap: true},
+			{Type: "FactSet", Facts: facts},
+		},
+	}
+	if failures != "" {
+		card.Body = append(card.Body,
+			teamsElement{Type: "TextBlock", Text: "Failed tests", Weight: "Bolder"},
+			teamsElement{Type: "TextBlock", Text: failures, Wrap: true})
+	}
+	for _, link := range links {
+		card.Actions = append(card.Actions, teamsAction{Type: "Action.OpenUrl", Title: link.title, URL: link.url})
+	}
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	}
+}
+
+// teamsFailureList returns a markdown list of the tests that failed, and did
+// not pass when they were re-run.
+func teamsFailureList(report textreport.Report) string {
+	flaky := make(map[string]bool)
+	for _, tc := range report.Flaky {
+		flaky[tc.Package+"."+tc.Name] = true
+	}
+	var lines []string
+	var more int
+	seen := make(map[string]bool)
+	for _, tc := range report.Failures {
+		name := tc.Package + "." + tc.Name
+		if flaky[name] || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if len(lines) == teamsMaxFailures {
+			more++
+			continue
+		}
+		lines = append(lines, "- "+name+teamsRequirementLinks(tc.Requirements))
+	}
+	if more > 0 {
+		lines = append(lines, fmt.Sprintf("- and %d more", more))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// teamsRequirementLinks returns markdown links to the requirements that have
+// a URL from the --requirement-url-template.
+func teamsRequirementLinks(reqs []textreport.RequirementLink) string {
+	var links []string
+	for _, req := range reqs {
+		if req.URL != "" {
+			links = append(links, "["+req.ID+"]("+req.URL+")")
+		}
+	}
+	if len(links) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(links, ", ") + ")"
+}
+
+// ciLink is a link to a page of the CI system that ran the tests.
+type ciLink struct {
+	title string
+	url   string
+}
+
+// ciLinks returns links to the CI job, and the artifacts of the job, from the
+// environment variables set by GitHub Actions, GitLab CI, and Jenkins. The
+// GOTESTSUM_ARTIFACTS_URL environment variable replaces the link to the
+// artifacts.
+func ciLinks(getenv func(string) string) []ciLink {
+	var job, artifacts string
+	switch {
+	case getenv("GITHUB_RUN_ID") != "":
+		job = getenv("GITHUB_SERVER_URL") + "/" + getenv("GITHUB_REPOSITORY") +
+			"/actions/runs/" + getenv("GITHUB_RUN_ID")
+		artifacts = job + "#artifacts"
+	case getenv("CI_JOB_URL") != "":
+		job = getenv("CI_JOB_URL")
+		artifacts = job + "/artifacts/browse"
+	case getenv("BUILD_URL") != "":
+		job = getenv("BUILD_URL")
+		artifacts = strings.TrimSuffix(job, "/") + "/artifact/"
+	}
+	if url := getenv("GOTESTSUM_ARTIFACTS_URL"); url != "" {
+		artifacts = url
+	}
+
+	var links []ciLink
+	if job != "" {
+		links = append(links, ciLink{title: "View CI job", url: job})
+	}
+	if artifacts != "" {
+		links = append(links, ciLink{title: "Artifacts", url: artifacts})
+	}
+	return links
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/internal/textreport"
+	"gotest.tools/v3/assert"
+)
+
+func TestTeamsPublisher(t *testing.T) {
+	patchPublisherRetryDelay(t)
+	patchVCS(t, &vcsInfo{Commit: "abc123", Branch: "main"})
+
+	var msg teamsMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("Content-Type"), "application/json")
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&msg))
+	}))
+	defer srv.Close()
+
+	out := new(bytes.Buffer)
+	opts := &options{stdout: out, stderr: out, teamsWebhookURL: srv.URL}
+	assert.NilError(t, runPublishers(opts, scanFlakyRun(t), nil))
+	assert.Equal(t, out.String(), `
+=== Publish: 1 publishers, 0 failed
+=== PUBLISHED: teams (1 attempt)
+`)
+
+	assert.Equal(t, msg.Type, "message")
+	assert.Equal(t, len(msg.Attachments), 1)
+	assert.Equal(t, msg.Attachments[0].ContentType, "application/vnd.microsoft.card.adaptive")
+	card := msg.Attachments[0].Content
+	assert.Equal(t, card.Type, "AdaptiveCard")
+	assert.Equal(t, card.Body[0].Text, "Tests passed after re-running flaky tests")
+	facts := card.Body[1].Facts
+	assert.Equal(t, facts[5].Title, "Elapsed")
+	facts = append(facts[:5], facts[6:]...)
+	assert.DeepEqual(t, facts, []teamsFact{
+		{Title: "Total", Value: "3"},
+		{Title: "Passed", Value: "2"},
+		{Title: "Failed", Value: "1"},
+		{Title: "Skipped", Value: "0"},
+		{Title: "Flaky", Value: "1"},
+		{Title: "Commit", Value: "abc123"},
+		{Title: "Branch", Value: "main"},
+	})
+}
+
+func TestNewTeamsMessage_Failures(t *testing.T) {
+	report := textreport.Report{Total: 12, Failed: 12, Elapsed: 1500 * time.Millisecond}
+	for i := 0; i < 12; i++ {
+		report.Failures = append(report.Failures, textreport.TestCase{
+			Package: "example.com/pkg",
+			Name:    fmt.Sprintf("TestFails%d", i),
+		})
+	}
+	links := []ciLink{{title: "View CI job", url: "https://ci.example.com/1"}}
+
+	card := newTeamsMessage(report, nil, nil, links).Attachments[0].Content
+	assert.Equal(t, card.Body[0].Text, "Tests failed")
+	assert.Equal(t, card.Body[0].Color, "Attention")
+	assert.Equal(t, len(card.Body), 4)
+	lines := strings.Split(card.Body[3].Text, "\n")
+	assert.Equal(t, len(lines), 11)
+	assert.Equal(t, lines[0], "- example.com/pkg.TestFails0")
+	assert.Equal(t, lines[10], "- and 2 more")
+	assert.DeepEqual(t, card.Actions, []teamsAction{
+		{Type: "Action.OpenUrl", Title: "View CI job", URL: "https://ci.example.com/1"},
+	})
+}
+
+func TestTeamsFailureList_RequirementLinks(t *testing.T) {
+	report := textreport.Report{Failures: []textreport.TestCase{
+		{
+			Package: "example.com/pkg",
+			Name:    "TestLogin[REQ-1,REQ-2]",
+			Requirements: []textreport.RequirementLink{
+				{ID: "REQ-1", URL: "https://jira.example.com/browse/REQ-1"},
+				{ID: "REQ-2", URL: "https://jira.example.com/browse/REQ-2"},
+			},
+		},
+		{
+			Package:      "example.com/pkg",
+			Name:         "TestLogout[REQ-3]",
+			Requirements: []textreport.RequirementLink{{ID: "REQ-3"}},
+		},
+	}}
+	expected := `- example.com/pkg.TestLogin[REQ-1,REQ-2] ([REQ-1](https://jira.example.com/browse/REQ-1), [REQ-2](https://jira.example.com/browse/REQ-2))
+- example.com/pkg.TestLogout[REQ-3]`
+	assert.Equal(t, teamsFailureList(report), expected)
+}
+
+func TestCILinks(t *testing.T) {
+	type testCase struct {
+		name     string
+		env      map[string]string
+		expected []ciLink
+	}
+	run := func(t *testing.T, tc testCase) {
+		getenv := func(key string) string {
+			return tc.env[key]
+		}
+		assert.DeepEqual(t, ciLinks(getenv), tc.expected, cmpCILink)
+	}
+
+	testCases := []testCase{
+		{name: "no CI"},
+		{
+			name: "GitHub Actions",
+			env: map[string]string{
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_REPOSITORY": "org/repo",
+				"GITHUB_RUN_ID":     "42",
+			},
+			expected: []ciLink{
+				{title: "View CI job", url: "https://github.com/org/repo/actions/runs/42"},
+				{title: "Artifacts", url: "https://github.com/org/repo/actions/runs/42#artifacts"},
+			},
+		},
+		{
+			name: "GitLab CI",
+			env:  map[string]string{"CI_JOB_URL": "https://gitlab.com/org/repo/-/jobs/42"},
+			expected: []ciLink{
+				{title: "View CI job", url: "https://gitlab.com/org/repo/-/jobs/42"},
+				{title: "Artifacts", url: "https://gitlab.com/org/repo/-/jobs/42/artifacts/browse"},
+			},
+		},
+		{
+			name: "Jenkins with artifacts URL",
+			env: map[string]string{
+				"BUILD_URL":               "https://jenkins.example.com/job/repo/42/",
+				"GOTESTSUM_ARTIFACTS_URL": "https://storage.example.com/42/",
+			},
+			expected: []ciLink{
+				{title: "View CI job", url: "https://jenkins.example.com/job/repo/42/"},
+				{title: "Artifacts", url: "https://storage.example.com/42/"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+var cmpCILink = gocmp.AllowUnexported(ciLink{})
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/dnephin/pflag"
+	"golang.org/x/term"
+)
+
+// defaultTerminalWidth is the width used by --force-tty when the width of the
+// terminal can not be detected, and COLUMNS is not set.
+const defaultTerminalWidth = 80
+
+// setupTerminal configures the output for the capabilities of stdout. When
+// stdout is not a terminal, formats that move the cursor are disabled, and
+// escape sequences are removed from the output if colors are disabled.
+// --force-tty formats the output as if stdout is a terminal, and enables
+// colors unless --no-color is set. The returned function restores the state
+// of the terminal.
+func setupTerminal(flags *pflag.FlagSet, opts *options) func() {
+	isTTY := term.IsTerminal(int(os.Stdout.Fd()))
+	restore := func() {}
+	if isTTY {
+		restore = enableVirtualTerminal(opts)
+	}
+	configureTerminal(opts, isTTY, flags.Changed("no-color"))
+	return restore
+}
+
+func configureTerminal(opts *options, isTTY bool, noColorSet bool) {
+	switch {
+	case opts.forceTTY:
+		if !noColorSet {
+			opts.noColor = false
+		}
+		if !isTTY && opts.formatOptions.TerminalWidth == 0 {
+			opts.formatOptions.TerminalWidth = terminalWidthFromEnv()
+		}
+	case !isTTY:
+		opts.formatOptions.NoTerminal = true
+		if opts.noColor {
+			opts.stdout = &ansiStripWriter{out: opts.stdout}
+		}
+	}
+}
+
+func terminalWidthFromEnv() int {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width <= 0 {
+		return defaultTerminalWidth
+	}
+	return width
+}
+
+// ansiStripWriter removes ANSI escape sequences from the bytes written to it,
+// including any sequences printed by the tests. A sequence may be split
+// across calls to Write.
+type ansiStripWriter struct {
+	out   io.Writer
+	state ansiState
+	buf   []byte
+}
+
+type ansiState int
+
+const (
+	ansiText ansiState = iota
+	// ansiEscape follows an ESC character.
+	ansiEscape
+	// ansiCSI is a Control Sequence Introducer, like a color or cursor
+	// movement, which ends with a byte in the range 0x40-0x7E.
+	ansiCSI
+	// ansiOSC is an Operating System Command, like a hyperlink or window
+	// title, which ends with BEL or ESC \.
+	ansiOSC
+	ansiOSCEscape
+)
+
+const (
+	asciiESC = 0x1b
+	asciiBEL = 0x07
+)
+
+func (w *ansiStripWriter) Write(p []byte) (int, error) {
+	w.buf = w.buf[:0]
+	for _, c := range p {
+		switch w.state {
+		case ansiText:
+			if c == asciiESC {
+				w.state = ansiEscape
+				continue
+			}
+			w.buf = append(w.buf, c)
+		case ansiEscape:
+			switch c {
+			case '[':
+				w.state = ansiCSI
+			case ']':
+				w.state = ansiOSC
+			default:
+				w.state = ansiText
+			}
+		case ansiCSI:
+			if c >= 0x40 && c <= 0x7e {
+				w.state = ansiText
+			}
+		case ansiOSC:
+			switch c {
+			case asciiBEL:
+				w.state = ansiText
+			case asciiESC:
+				w.state = ansiOSCEscape
+			}
+		case ansiOSCEscape:
+			if c == '\\' {
+				w.state = ansiText
+			} else {
+				w.state = ansiOSC
+			}
+		}
+	}
+	if len(w.buf) == 0 {
+		return len(p), nil
+	}
+	if _, err := w.out.Write(w.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestAnsiStripWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := &ansiStripWriter{out: buf}
+
+	writes := []string{
+		"\x1b[32m✓\x1b[0m  pkg/one (1.2s)\n",
+		"\x1b[1A\x1b[2K",
+		"split \x1b",
+		"[31",
+		"mred\x1b[0m\n",
+		"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x07\n",
+		"\x1b7plain\x1b8\n",
+	}
+	for _, write := range writes {
+		n, err := w.Write([]byte(write))
+		assert.NilError(t, err)
+		assert.Equal(t, n, len(write))
+	}
+	expected := "✓  pkg/one (1.2s)\nsplit red\nlink\nplain\n"
+	assert.Equal(t, buf.String(), expected)
+}
+
+func TestConfigureTerminal(t *testing.T) {
+	t.Run("not a terminal", func(t *testing.T) {
+		opts := &options{stdout: new(bytes.Buffer), noColor: true}
+		configureTerminal(opts, false, false)
+		assert.Assert(t, opts.formatOptions.NoTerminal)
+		_, ok := opts.stdout.(*ansiStripWriter)
+		assert.Assert(t, ok, "expected stdout to strip escape sequences")
+	})
+	t.Run("not a terminal with color", func(t *testing.T) {
+		opts := &options{stdout: new(bytes.Buffer)}
+		configureTerminal(opts, false, true)
+		assert.Assert(t, opts.formatOptions.NoTerminal)
+		_, ok := opts.stdout.(*bytes.Buffer)
+		assert.Assert(t, ok, "expected stdout to be unchanged")
+	})
+	t.Run("terminal", func(t *testing.T) {
+		opts := &options{stdout: new(bytes.Buffer)}
+		configureTerminal(opts, true, false)
+		assert.Assert(t, !opts.formatOptions.NoTerminal)
+		_, ok := opts.stdout.(*bytes.Buffer)
+		assert.Assert(t, ok, "expected stdout to be unchanged")
+	})
+	t.Run("force tty", func(t *testing.T) {
+		defer env.Patch(t, "COLUMNS", "120")()
+		opts := &options{stdout: new(bytes.Buffer), noColor: true, forceTTY: true}
+		configureTerminal(opts, false, false)
+		assert.Assert(t, !opts.formatOptions.NoTerminal)
+		assert.Assert(t, !opts.noColor)
+		assert.Equal(t, opts.formatOptions.TerminalWidth, 120)
+		_, ok := opts.stdout.(*bytes.Buffer)
+		assert.Assert(t, ok, "expected stdout to be unchanged")
+	})
+	t.Run("force tty with no-color", func(t *testing.T) {
+		defer env.Patch(t, "COLUMNS", "")()
+		opts := &options{stdout: new(bytes.Buffer), noColor: true, forceTTY: true}
+		configureTerminal(opts, false, true)
+		assert.Assert(t, opts.noColor)
+		assert.Equal(t, opts.formatOptions.TerminalWidth, defaultTerminalWidth)
+	})
+}
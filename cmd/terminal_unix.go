@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+// enableVirtualTerminal is only necessary on Windows, terminals on other
+// platforms always process escape sequences.
+func enableVirtualTerminal(_ *options) func() {
+	return func() {}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableVirtualTerminal enables the processing of ANSI escape sequences by the
+// Windows console, so that colors and the formats that move the cursor work
+// in cmd.exe and PowerShell. When the console supports it, the output is
+// written to the console directly instead of translating the escape sequences
+// to console API calls. The returned function restores the previous mode.
+func enableVirtualTerminal(opts *options) func() {
+	var restore []func()
+	if reset, ok := enableVirtualTerminalProcessing(os.Stdout); ok {
+		opts.stdout = os.Stdout
+		restore = append(restore, reset)
+	}
+	if reset, ok := enableVirtualTerminalProcessing(os.Stderr); ok {
+		opts.stderr = os.Stderr
+		restore = append(restore, reset)
+	}
+	return func() {
+		for _, fn := range restore {
+			fn()
+		}
+	}
+}
+
+func enableVirtualTerminalProcessing(f *os.File) (func(), bool) {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return nil, false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return func() {}, true
+	}
+	if err := windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+		return nil, false
+	}
+	return func() {
+		_ = windows.SetConsoleMode(handle, mode)
+	}, true
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// digestExt is added to the name of the --test-manifest to get the name of
+// the file with its SHA-256 digest.
+const digestExt = ".sha256"
+
+// testManifest is the --test-manifest file. It lists every test that was run,
+// and the digest of the test binary of its package.
+type testManifest struct {
+	GoVersion string              `json:"go_version"`
+	Tests     []testManifestEntry `json:"tests"`
+}
+
+type testManifestEntry struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	Result  string `json:"result"`
+	// BinarySHA256 is the digest of the test binary that ran the test, which
+	// is only known when the binary was written to the --profile-dir.
+	BinarySHA256 string `json:"binary_sha256,omitempty"`
+	// RebuiltBinarySHA256 is the digest of the test binary of the package
+	// built again with 'go test -c', when BinarySHA256 is not known. It is
+	// empty when the binary could not be built.
+	RebuiltBinarySHA256 string `json:"rebuilt_binary_sha256,omitempty"`
+}
+
+// writeTestManifest writes the --test-manifest, and a file with its SHA-256
+// digest, in the format used by sha256sum.
+func writeTestManifest(opts *options, exec *testjson.Execution) error {
+	if opts.testManifest == "" {
+		return nil
+	}
+	manifest := newTestManifest(opts, exec)
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	raw = append(raw, '\n')
+
+	_ = os.MkdirAll(filepath.Dir(opts.testManifest), 0o755)
+	if err := ioutil.WriteFile(opts.testManifest, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write test manifest: %w", err)
+	}
+	digest := sha256.Sum256(raw)
+	line := hex.EncodeToString(digest[:]) + "  " + filepath.Base(opts.testManifest) + "\n"
+	if err := ioutil.WriteFile(opts.testManifest+digestExt, []byte(line), 0o644); err != nil {
+		return fmt.Errorf("failed to write test manifest digest: %w", err)
+	}
+	return nil
+}
+
+func newTestManifest(opts *options, exec *testjson.Execution) testManifest {
+	manifest := testManifest{
+		GoVersion: goEnv(opts).Version,
+		Tests:     []testManifestEntry{},
+	}
+	for _, pkgname := range exec.Packages() {
+		pkg := exec.Package(pkgname)
+		var cases []testManifestCase
+		add := func(tcs []testjson.TestCase, result string) {
+			for _, tc := range tcs {
+				cases = append(cases, testManifestCase{tc: tc, result: result})
+			}
+		}
+		add(pkg.Failed, "fail")
+		add(pkg.Skipped, "skip")
+		add(pkg.Passed, "pass")
+		if len(cases) == 0 {
+			continue
+		}
+		sort.Slice(cases, func(i, j int) bool {
+			return cases[i].tc.ID < cases[j].tc.ID
+		})
+
+		digest, err := testBinaryDigest(opts, pkgname)
+		if err != nil {
+			log.Warnf("Failed to find the digest of the test binary of %v: %v", pkgname, err)
+		}
+		for _, c := range cases {
+			entry := testManifestEntry{
+				Package: pkgname,
+				Test:    c.tc.Test.Name(),
+				Result:  c.result,
+			}
+			if digest.rebuilt {
+				entry.RebuiltBinarySHA256 = digest.sha256
+			} else {
+				entry.BinarySHA256 = digest.sha256
+			}
+			manifest.Tests = append(manifest.Tests, entry)
+		}
+	}
+	return manifest
+}
+
+type testManifestCase struct {
+	tc     testjson.TestCase
+	result string
+}
+
+// binaryDigest is the SHA-256 digest of a test binary.
+type binaryDigest struct {
+	sha256 string
+	// rebuilt is true when the digest is of a binary built again with
+	// 'go test -c', instead of the binary that ran the tests.
+	rebuilt bool
+}
+
+// testBinaryDigest returns the SHA-256 digest of the test binary of the
+// package. When the binary was written to the --profile-dir the digest is of
+// that file, otherwise the binary is built again with 'go test -c', with the
+// build flags from the go test args and the --package-args of the package, and
+// the environment of its --env-group.
+//
+// testBinaryDigest is a variable so that it can be replaced in tests.
+var testBinaryDigest = func(opts *options, pkg string) (binaryDigest, error) {
+	if profiles, ok := opts.profiles[pkg]; ok && profiles.Binary != "" {
+		digest, err := fileDigest(profiles.Binary)
+		return binaryDigest{sha256: digest}, err
+	}
+	if opts.rawCommand {
+		return binaryDigest{}, fmt.Errorf("the test binary is not known when using --raw-command")
+	}
+
+	dir, err := ioutil.TempDir("", "gotestsum-manifest")
+	if err != nil {
+		return binaryDigest{}, err
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	binary := filepath.Join(dir, "pkg.test")
+	args := append([]string{"test", "-c", "-o", binary}, rebuildFlags(opts, pkg)...)
+	args = append(args, pkg)
+	log.Debugf("exec: go %v", args)
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = os.Stderr
+	if env := opts.envGroups.envFor(pkg); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if err := cmd.Run(); err != nil {
+		return binaryDigest{}, err
+	}
+	digest, err := fileDigest(binary)
+	return binaryDigest{sha256: digest, rebuilt: true}, err
+}
+
+// rebuildFlags returns the flags used to build the test binary of the package
+// again, from the go test args and the --package-args of the package.
+func rebuildFlags(opts *options, pkg string) []string {
+	flags := goBuildFlags(opts.args)
+	return append(flags, goBuildFlags(opts.packageArgs.argsFor(pkg))...)
+}
+
+func fileDigest(path string) (string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close() // nolint: errcheck
+	digest := sha256.New()
+	if _, err := io.Copy(digest, fh); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// goBuildValueFlags are the go test flags, with a value, that change the test
+// binary.
+var goBuildValueFlags = []string{
+	"tags", "covermode", "coverpkg", "gcflags", "ldflags", "asmflags", "mod", "modfile", "overlay",
+}
+
+// goBuildBoolFlags are the go test flags, without a value, that change the
+// test binary.
+var goBuildBoolFlags = []string{"race", "msan", "asan", "cover", "trimpath"}
+
+// goBuildFlags returns the flags in the go test args that change the test
+// binary, so that it can be built again with the same flags. A -coverprofile
+// flag is replaced by -cover, because it also builds the binary with coverage.
+func goBuildFlags(args []string) []string {
+	args = args[:findPkgArgPosition(args)]
+	var result []string
+	if start, _ := argIndex("coverprofile", args); start >= 0 && boolArgIndex("cover", args) < 0 {
+		result = append(result, "-cover")
+	}
+	for _, flag := range goBuildValueFlags {
+		start, end := argIndex(flag, args)
+		switch {
+		case start < 0:
+		case start == end:
+			result = append(result, args[start])
+		case end < len(args):
+			result = append(result, args[start], args[end])
+		}
+	}
+	for _, arg := range args {
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		for _, flag := range goBuildBoolFlags {
+			if name == flag && strings.HasPrefix(arg, "-") {
+				result = append(result, arg)
+			}
+		}
+	}
+	return result
+}
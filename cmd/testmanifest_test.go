@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestWriteTestManifest(t *testing.T) {
+	raw, err := ioutil.ReadFile("../testjson/testdata/input/go-test-json-with-shuffle.out")
+	assert.NilError(t, err)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: bytes.NewReader(raw)})
+	assert.NilError(t, err)
+
+	orig := testBinaryDigest
+	testBinaryDigest = func(opts *options, pkg string) (binaryDigest, error) {
+		digest := sha256.Sum256([]byte(pkg))
+		rebuilt := !strings.HasSuffix(pkg, "/good")
+		return binaryDigest{sha256: hex.EncodeToString(digest[:8]), rebuilt: rebuilt}, nil
+	}
+	t.Cleanup(func() { testBinaryDigest = orig })
+
+	dir := fs.NewDir(t, "test-manifest")
+	opts := &options{
+		testManifest: dir.Join("manifest.json"),
+		goEnv:        &goEnvironment{Version: "go7.7.7"},
+	}
+	assert.NilError(t, writeTestManifest(opts, exec))
+
+	manifest, err := ioutil.ReadFile(opts.testManifest)
+	assert.NilError(t, err)
+	golden.Assert(t, string(manifest), "test-manifest.golden")
+
+	digest, err := ioutil.ReadFile(opts.testManifest + ".sha256")
+	assert.NilError(t, err)
+	sum := sha256.Sum256(manifest)
+	assert.Equal(t, string(digest), hex.EncodeToString(sum[:])+"  manifest.json\n")
+}
+
+func TestGoBuildFlags(t *testing.T) {
+	args := []string{
+		"-count=1", "-tags", "integration", "-race", "-run=TestOne",
+		"-ldflags=-s", "-coverprofile=c.out", "./...", "-args", "-trimpath",
+	}
+	expected := []string{"-cover", "-tags", "integration", "-ldflags=-s", "-race"}
+	assert.DeepEqual(t, goBuildFlags(args), expected)
+
+	args = []string{"-cover", "-coverprofile", "c.out", "./..."}
+	assert.DeepEqual(t, goBuildFlags(args), []string{"-cover"})
+}
+
+func TestRebuildFlags(t *testing.T) {
+	opts := &options{args: []string{"-race", "-run=TestOne"}}
+	assert.NilError(t, opts.packageArgs.Set("./integration/...=-tags=integration -count=2"))
+	opts.packageArgs.byPackage = map[string][]string{
+		"example.com/integration/db": opts.packageArgs.items[0].args,
+	}
+
+	assert.DeepEqual(t, rebuildFlags(opts, "example.com/integration/db"), []string{"-race", "-tags=integration"})
+	assert.DeepEqual(t, rebuildFlags(opts, "example.com/web"), []string{"-race"})
+}
@@ -0,0 +1,367 @@
+package bisect
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	opts.args = flags.Args()
+	opts.stdout = os.Stdout
+	return run(opts)
+}
+
+type options struct {
+	pkg      string
+	failing  string
+	jsonfile string
+	debug    bool
+	args     []string
+
+	// shims for testing
+	stdout io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.pkg, "package", "",
+		"the package that contains the failing test")
+	flags.StringVar(&opts.failing, "failing", "",
+		"name of the top-level test that fails when run after some other tests")
+	flags.StringVar(&opts.jsonfile, "jsonfile", "",
+		"test2json output of a failed run, used to find the order of tests. "+
+			"When not set the package tests are run once to find the order")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [--] [go test flags]
+
+Find the smallest set of tests that cause the --failing test to fail when
+they run before it. The tests that ran before the failing test are split into
+smaller groups, and each group is run with the failing test using 'go test -run',
+until no test can be removed from the set without the failing test passing.
+
+Tests run by 'go test -run' run in source order, so the order of the tests must
+not have been changed with -shuffle.
+
+Any args after the flags are passed to 'go test'.
+
+    %[1]s --package ./store --failing TestDelete -- -tags=integration
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	switch {
+	case opts.pkg == "":
+		return fmt.Errorf("--package is required")
+	case opts.failing == "":
+		return fmt.Errorf("--failing is required")
+	case strings.Contains(opts.failing, "/"):
+		return fmt.Errorf("--failing must be the name of a top-level test, not a subtest")
+	}
+
+	before, err := testsRunBefore(opts)
+	if err != nil {
+		return err
+	}
+	fails := func(tests []string) (bool, error) {
+		return runTests(opts, tests)
+	}
+	result, err := bisect(before, fails)
+	if err != nil {
+		return err
+	}
+	printResult(opts.stdout, opts.failing, result)
+	return nil
+}
+
+// testsRunBefore returns the names of the top-level tests that ran before the
+// failing test, in the order they ran.
+func testsRunBefore(opts *options) ([]string, error) {
+	var exec *testjson.Execution
+	var err error
+	if opts.jsonfile != "" {
+		exec, err = scanFile(opts.jsonfile)
+	} else {
+		exec, err = goTest(opts, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return orderBefore(exec, opts.failing)
+}
+
+func scanFile(path string) (*testjson.Execution, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer fh.Close() // nolint: errcheck
+	return testjson.ScanTestOutput(testjson.ScanConfig{Stdout: fh})
+}
+
+// orderBefore returns the top-level tests that started before the failing test.
+func orderBefore(exec *testjson.Execution, failing string) ([]string, error) {
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if pkg.LastFailedByName(failing).ID == 0 {
+			continue
+		}
+
+		var target testjson.TestCase
+		for _, tc := range pkg.Failed {
+			if tc.Test.Name() == failing {
+				target = tc
+				break
+			}
+		}
+		var result []string
+		seen := map[string]bool{failing: true}
+		for _, tc := range testCasesByID(pkg) {
+			name := tc.Test.Name()
+			if tc.ID >= target.ID || tc.Test.IsSubTest() || seen[name] {
+				continue
+			}
+			seen[name] = true
+			result = append(result, name)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("test %v did not fail", failing)
+}
+
+// testCasesByID returns all the test cases in the package, sorted by the
+// order they started.
+func testCasesByID(pkg *testjson.Package) []testjson.TestCase {
+	tcs := pkg.TestCases()
+	byID := make([]testjson.TestCase, pkg.Total+1)
+	for _, tc := range tcs {
+		if tc.ID < len(byID) {
+			byID[tc.ID] = tc
+		}
+	}
+	result := make([]testjson.TestCase, 0, len(tcs))
+	for _, tc := range byID {
+		if tc.ID != 0 {
+			result = append(result, tc)
+		}
+	}
+	return result
+}
+
+// runTests runs the tests followed by the failing test, and returns true if
+// the failing test failed.
+func runTests(opts *options, tests []string) (bool, error) {
+	names := make([]string, 0, len(tests)+1)
+	for _, name := range tests {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	names = append(names, regexp.QuoteMeta(opts.failing))
+	exec, err := goTest(opts, "-test.run=^("+strings.Join(names, "|")+")$")
+	if err != nil {
+		return false, err
+	}
+	for _, tc := range exec.Failed() {
+		if tc.Test.Name() == opts.failing {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func goTest(opts *options, runFlag string) (*testjson.Execution, error) {
+	args := []string{"test", "-json", "-count=1"}
+	if runFlag != "" {
+		args = append(args, runFlag)
+	}
+	args = append(args, opts.args...)
+	args = append(args, opts.pkg)
+
+	log.Debugf("exec: go %v", args)
+	cmd := exec.Command("go", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = ioutil.Discard
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run go test: %w", err)
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: stdout})
+	if err != nil {
+		return nil, err
+	}
+	// a non-zero exit code is expected when the test fails.
+	_ = cmd.Wait()
+	if len(exec.Errors()) > 0 {
+		return nil, fmt.Errorf("go test failed: %v", strings.Join(exec.Errors(), "\n"))
+	}
+	return exec, nil
+}
+
+type result struct {
+	// tests that must run before the failing test for it to fail. Empty if
+	// the failing test fails when run on its own.
+	tests []string
+	// reproduced is false when the failing test did not fail with all the tests.
+	reproduced bool
+	runs       int
+}
+
+// bisect uses the delta-debugging algorithm to find a minimal set of tests
+// from candidates that cause fails to return true.
+func bisect(candidates []string, fails func([]string) (bool, error)) (result, error) {
+	var res result
+	try := func(tests []string) (bool, error) {
+		res.runs++
+		failed, err := fails(tests)
+		log.Debugf("run %d with %d tests: failed=%v", res.runs, len(tests), failed)
+		return failed, err
+	}
+
+	failed, err := try(nil)
+	switch {
+	case err != nil:
+		return res, err
+	case failed:
+		res.reproduced = true
+		return res, nil
+	}
+
+	failed, err = try(candidates)
+	switch {
+	case err != nil:
+		return res, err
+	case !failed:
+		return res, nil
+	}
+	res.reproduced = true
+
+	current := candidates
+	n := 2
+	for len(current) >= 2 {
+		chunks := split(current, n)
+		found := false
+		for _, chunk := range chunks {
+			failed, err := try(chunk)
+			if err != nil {
+				return res, err
+			}
+			if failed {
+				current, n, found = chunk, 2, true
+				break
+			}
+		}
+		if !found && n > 2 {
+			for i := range chunks {
+				complement := without(chunks, i)
+				failed, err := try(complement)
+				if err != nil {
+					return res, err
+				}
+				if failed {
+					current, n, found = complement, maxInt(n-1, 2), true
+					break
+				}
+			}
+		}
+		if !found {
+			if n >= len(current) {
+				break
+			}
+			n = minInt(n*2, len(current))
+		}
+	}
+	res.tests = current
+	return res, nil
+}
+
+func split(items []string, n int) [][]string {
+	result := make([][]string, 0, n)
+	size := len(items) / n
+	extra := len(items) % n
+	start := 0
+	for i := 0; i < n; i++ {
+		end := start + size
+		if i < extra {
+			end++
+		}
+		result = append(result, items[start:end])
+		start = end
+	}
+	return result
+}
+
+func without(chunks [][]string, skip int) []string {
+	var result []string
+	for i, chunk := range chunks {
+		if i != skip {
+			result = append(result, chunk...)
+		}
+	}
+	return result
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func printResult(out io.Writer, failing string, res result) {
+	switch {
+	case !res.reproduced:
+		fmt.Fprintf(out, "%v did not fail when run after all the other tests (%d runs)\n",
+			failing, res.runs)
+	case len(res.tests) == 0:
+		fmt.Fprintf(out, "%v fails when run on its own, it is not order dependent (%d runs)\n",
+			failing, res.runs)
+	default:
+		fmt.Fprintf(out, "%v fails when run after (%d runs):\n", failing, res.runs)
+		for _, name := range res.tests {
+			fmt.Fprintf(out, "    %v\n", name)
+		}
+	}
+}
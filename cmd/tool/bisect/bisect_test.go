@@ -0,0 +1,105 @@
+package bisect
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestBisect(t *testing.T) {
+	candidates := []string{"TestA", "TestB", "TestC", "TestD", "TestE", "TestF", "TestG"}
+
+	type testCase struct {
+		name     string
+		fails    func(tests []string) bool
+		expected result
+	}
+	run := func(t *testing.T, tc testCase) {
+		actual, err := bisect(candidates, func(tests []string) (bool, error) {
+			return tc.fails(tests), nil
+		})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, actual.tests, tc.expected.tests)
+		assert.Equal(t, actual.reproduced, tc.expected.reproduced)
+	}
+
+	testCases := []testCase{
+		{
+			name:     "one test causes failure",
+			fails:    containsAll("TestE"),
+			expected: result{tests: []string{"TestE"}, reproduced: true},
+		},
+		{
+			name:     "two tests cause failure",
+			fails:    containsAll("TestB", "TestF"),
+			expected: result{tests: []string{"TestB", "TestF"}, reproduced: true},
+		},
+		{
+			name:     "fails on its own",
+			fails:    func([]string) bool { return true },
+			expected: result{reproduced: true},
+		},
+		{
+			name:     "does not fail",
+			fails:    func([]string) bool { return false },
+			expected: result{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func containsAll(names ...string) func([]string) bool {
+	return func(tests []string) bool {
+		set := make(map[string]bool)
+		for _, name := range tests {
+			set[name] = true
+		}
+		for _, name := range names {
+			if !set[name] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestOrderBefore(t *testing.T) {
+	source := `{"Action":"run","Package":"example.com/pkg","Test":"TestFirst"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFirst/sub"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFirst/sub"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFirst"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestSecond"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestSecond"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTarget"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestTarget"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestAfter"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestAfter"}
+{"Action":"fail","Package":"example.com/pkg"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(source)})
+	assert.NilError(t, err)
+
+	actual, err := orderBefore(exec, "TestTarget")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, actual, []string{"TestFirst", "TestSecond"})
+
+	_, err = orderBefore(exec, "TestAfter")
+	assert.Error(t, err, "test TestAfter did not fail")
+}
+
+func TestPrintResult(t *testing.T) {
+	out := new(bytes.Buffer)
+	printResult(out, "TestTarget", result{tests: []string{"TestA", "TestB"}, reproduced: true, runs: 6})
+	expected := `TestTarget fails when run after (6 runs):
+    TestA
+    TestB
+`
+	assert.Equal(t, out.String(), expected)
+}
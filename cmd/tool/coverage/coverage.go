@@ -0,0 +1,156 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	usage := func(name string) string {
+		return fmt.Sprintf(`Usage:
+    %[1]s COMMAND [flags]
+
+Commands:
+    %[1]s diff       compare the coverage of each package in two coverage profiles
+    %[1]s per-test   write a map of the lines covered by each test
+
+Use '%[1]s COMMAND --help' for command specific help.
+`, name)
+	}
+
+	var next string
+	var rest []string
+	if len(args) > 0 {
+		next, rest = args[0], args[1:]
+	}
+	switch next {
+	case "", "help", "?", "-h", "--help":
+		fmt.Println(usage(name))
+		return nil
+	case "diff":
+		return runDiffCommand(name+" "+next, rest)
+	case "per-test":
+		return runPerTestCommand(name+" "+next, rest)
+	default:
+		fmt.Fprintln(os.Stderr, usage(name))
+		return fmt.Errorf("invalid command: %v %v", name, next)
+	}
+}
+
+func runDiffCommand(name string, args []string) error {
+	flags, opts := setupDiffFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		diffUsage(os.Stderr, name, flags)
+		return err
+	}
+	if flags.NArg() != 2 {
+		diffUsage(os.Stderr, name, flags)
+		return fmt.Errorf("expected two coverage profiles, the old and the new")
+	}
+	opts.oldProfile = flags.Arg(0)
+	opts.newProfile = flags.Arg(1)
+	opts.checkDelta = flags.Changed("min-delta")
+	opts.stdout = os.Stdout
+	return runDiff(opts)
+}
+
+type diffOptions struct {
+	oldProfile string
+	newProfile string
+	minDelta   float64
+	checkDelta bool
+	output     string
+	debug      bool
+
+	// shims for testing
+	stdout io.Writer
+}
+
+func setupDiffFlags(name string) (*pflag.FlagSet, *diffOptions) {
+	opts := &diffOptions{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		diffUsage(os.Stdout, name, flags)
+	}
+	flags.Float64Var(&opts.minDelta, "min-delta", 0,
+		"fail when the coverage of a package, or the total, changes by less than this many percentage points")
+	flags.StringVarP(&opts.output, "output", "o", "-",
+		"write the Markdown table to this file, defaults to stdout")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func diffUsage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] OLD NEW
+
+Compare the statement coverage of each package in two coverage profiles
+written by 'go test -coverprofile', and print the change as a Markdown table
+that can be added to a CI job summary.
+
+When --min-delta is set the command fails if the coverage of a package that
+is in both profiles, or the total coverage, dropped by more than the delta.
+A delta of -0.5 allows the coverage to drop by half a percentage point.
+
+    %[1]s main.out pr.out --min-delta -0.5 >> "$GITHUB_STEP_SUMMARY"
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func runDiff(opts *diffOptions) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	old, err := readProfile(opts.oldProfile)
+	if err != nil {
+		return err
+	}
+	updated, err := readProfile(opts.newProfile)
+	if err != nil {
+		return err
+	}
+
+	d := newDiff(old, updated)
+	out, closer, err := outputWriter(opts.stdout, opts.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := d.writeMarkdown(out, opts); err != nil {
+		_ = closer()
+		return fmt.Errorf("failed to write coverage diff: %w", err)
+	}
+	if err := closer(); err != nil {
+		return err
+	}
+	if !opts.checkDelta {
+		return nil
+	}
+	if failed := d.dropped(opts.minDelta); len(failed) > 0 {
+		return fmt.Errorf("coverage dropped by more than %v percentage points: %v",
+			-opts.minDelta, joinNames(failed))
+	}
+	return nil
+}
+
+func outputWriter(stdout io.Writer, v string) (io.Writer, func() error, error) {
+	if v == "" || v == "-" {
+		return stdout, func() error { return nil }, nil
+	}
+	fh, err := os.Create(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fh, fh.Close, nil
+}
@@ -0,0 +1,135 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/coverage"
+)
+
+func readProfile(filename string) (map[string]coverage.Statements, error) {
+	blocks, err := coverage.ReadProfile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coverage profile %v: %w", filename, err)
+	}
+	return coverage.ByPackage(blocks), nil
+}
+
+// packageDiff is the coverage of a package in the old and new profiles. old
+// or new is nil when the package is not in that profile.
+type packageDiff struct {
+	name string
+	old  *coverage.Statements
+	new  *coverage.Statements
+}
+
+// delta returns the change in coverage in percentage points, and false when
+// the package is not in both profiles.
+func (p packageDiff) delta() (float64, bool) {
+	if p.old == nil || p.new == nil {
+		return 0, false
+	}
+	return p.new.Percent() - p.old.Percent(), true
+}
+
+type diff struct {
+	packages []packageDiff
+	total    packageDiff
+}
+
+func newDiff(old, updated map[string]coverage.Statements) diff {
+	names := make(map[string]bool)
+	for name := range old {
+		names[name] = true
+	}
+	for name := range updated {
+		names[name] = true
+	}
+
+	var d diff
+	var oldTotal, newTotal coverage.Statements
+	for name := range names {
+		p := packageDiff{name: name}
+		if s, ok := old[name]; ok {
+			p.old = &s
+			oldTotal = oldTotal.Add(s)
+		}
+		if s, ok := updated[name]; ok {
+			p.new = &s
+			newTotal = newTotal.Add(s)
+		}
+		d.packages = append(d.packages, p)
+	}
+	sort.Slice(d.packages, func(i, j int) bool {
+		return d.packages[i].name < d.packages[j].name
+	})
+	d.total = packageDiff{name: "Total", old: &oldTotal, new: &newTotal}
+	return d
+}
+
+// dropped returns the names of the packages, and "Total", where the coverage
+// changed by less than minDelta.
+func (d diff) dropped(minDelta float64) []string {
+	var names []string
+	for _, p := range append(d.packages, d.total) {
+		if isDropped(p, minDelta) {
+			names = append(names, p.name)
+		}
+	}
+	return names
+}
+
+func isDropped(p packageDiff, minDelta float64) bool {
+	delta, ok := p.delta()
+	// round to the precision that is printed, so that the result matches
+	// the table.
+	return ok && math.Round(delta*10)/10 < minDelta
+}
+
+func (d diff) writeMarkdown(out io.Writer, opts *diffOptions) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "| Package | Old | New | Delta |\n")
+	fmt.Fprintf(w, "| --- | --- | --- | --- |\n")
+	row := func(p packageDiff, name string) {
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n",
+			name, formatPercent(p.old), formatPercent(p.new), formatDelta(p, opts))
+	}
+	for _, p := range d.packages {
+		row(p, "`"+p.name+"`")
+	}
+	row(d.total, "**Total**")
+	return w.Flush()
+}
+
+func formatPercent(s *coverage.Statements) string {
+	if s == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", s.Percent())
+}
+
+func formatDelta(p packageDiff, opts *diffOptions) string {
+	delta, ok := p.delta()
+	switch {
+	case !ok && p.old == nil:
+		return "new"
+	case !ok:
+		return "removed"
+	}
+	text := fmt.Sprintf("%+.1f%%", delta)
+	if text == "-0.0%" {
+		text = "+0.0%"
+	}
+	if opts.checkDelta && isDropped(p, opts.minDelta) {
+		text += " ❌"
+	}
+	return text
+}
+
+func joinNames(names []string) string {
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,78 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+const oldProfile = `mode: set
+example.com/app/store/store.go:10.2,12.16 4 1
+example.com/app/store/store.go:14.2,16.3 4 1
+example.com/app/store/store.go:18.2,20.3 2 0
+example.com/app/api/api.go:5.2,7.3 5 1
+example.com/app/api/api.go:9.2,11.3 5 0
+example.com/app/old/old.go:1.2,2.3 1 1
+`
+
+const newProfile = `mode: set
+example.com/app/store/store.go:10.2,12.16 4 1
+example.com/app/store/store.go:14.2,16.3 4 0
+example.com/app/store/store.go:18.2,20.3 2 0
+example.com/app/api/api.go:5.2,7.3 5 1
+example.com/app/api/api.go:9.2,11.3 5 1
+example.com/app/new/new.go:1.2,2.3 2 0
+`
+
+func TestRunDiff(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("old.out", oldProfile),
+		fs.WithFile("new.out", newProfile))
+	out := new(bytes.Buffer)
+	opts := &diffOptions{
+		oldProfile: dir.Join("old.out"),
+		newProfile: dir.Join("new.out"),
+		minDelta:   -0.5,
+		checkDelta: true,
+		stdout:     out,
+	}
+	err := runDiff(opts)
+	assert.Error(t, err, "coverage dropped by more than 0.5 percentage points: example.com/app/store, Total")
+	golden.Assert(t, out.String(), "diff.golden")
+}
+
+func TestRunDiff_NoGate(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("old.out", oldProfile),
+		fs.WithFile("new.out", newProfile))
+	out := new(bytes.Buffer)
+	opts := &diffOptions{
+		oldProfile: dir.Join("old.out"),
+		newProfile: dir.Join("new.out"),
+		stdout:     out,
+	}
+	assert.NilError(t, runDiff(opts))
+	assert.Assert(t, !bytes.Contains(out.Bytes(), []byte("❌")))
+}
+
+func TestRunDiff_AllowedDrop(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("old.out", oldProfile),
+		fs.WithFile("new.out", newProfile))
+	opts := &diffOptions{
+		oldProfile: dir.Join("old.out"),
+		newProfile: dir.Join("new.out"),
+		minDelta:   -50,
+		checkDelta: true,
+		stdout:     new(bytes.Buffer),
+	}
+	assert.NilError(t, runDiff(opts))
+}
+
+func TestRunDiff_MissingProfile(t *testing.T) {
+	opts := &diffOptions{oldProfile: "missing.out", newProfile: "missing.out"}
+	assert.ErrorContains(t, runDiff(opts), "failed to read coverage profile missing.out")
+}
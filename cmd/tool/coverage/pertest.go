@@ -0,0 +1,303 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/coverage"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+func runPerTestCommand(name string, args []string) error {
+	flags, opts := setupPerTestFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		perTestUsage(os.Stderr, name, flags)
+		return err
+	}
+	opts.args = flags.Args()
+	opts.stdout = os.Stdout
+	return runPerTest(opts)
+}
+
+type perTestOptions struct {
+	packages  []string
+	coverpkg  string
+	batchSize int
+	parallel  int
+	output    string
+	debug     bool
+	args      []string
+
+	// shims for testing
+	stdout       io.Writer
+	listPackages func(patterns []string, args []string) ([]string, error)
+	listTests    func(pkg string, args []string) ([]string, error)
+	goTest       func(args []string) error
+}
+
+func setupPerTestFlags(name string) (*pflag.FlagSet, *perTestOptions) {
+	opts := &perTestOptions{
+		listPackages: listPackages,
+		listTests:    listTests,
+		goTest:       goTest,
+	}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		perTestUsage(os.Stdout, name, flags)
+	}
+	flags.StringSliceVar(&opts.packages, "packages", []string{"./..."},
+		"packages to test")
+	flags.StringVar(&opts.coverpkg, "coverpkg", "",
+		"packages to measure the coverage of, passed to go test -coverpkg. "+
+			"Defaults to the package of each test")
+	flags.IntVar(&opts.batchSize, "batch-size", 1,
+		"number of tests to run with each coverage profile, the coverage of a batch is attributed to every test in the batch")
+	flags.IntVar(&opts.parallel, "parallel", 1,
+		"number of batches to run at the same time")
+	flags.StringVarP(&opts.output, "output", "o", "-",
+		"write the coverage map to this file, defaults to stdout")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func perTestUsage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [--] [go test flags]
+
+Run each top-level test on its own, with a separate coverage profile, and write
+a JSON map of the lines covered by each test. The map can be used as the
+--coverage-map of 'gotestsum tool impact'.
+
+Running every test on its own is slow, so this is best run on a schedule, like
+a nightly CI job. Use --batch-size to run more than one test with each coverage
+profile. The coverage of a batch is attributed to every test in the batch, so
+larger batches are faster, but less precise.
+
+Any args after the flags are passed to 'go test'.
+
+    %[1]s --packages ./... --parallel 4 --output coverage.json -- -tags=integration
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+// testBatch is a group of tests in a package that are run with the same
+// coverage profile.
+type testBatch struct {
+	pkg   string
+	tests []string
+}
+
+func (b testBatch) runPattern() string {
+	names := make([]string, 0, len(b.tests))
+	for _, name := range b.tests {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	return "^(" + strings.Join(names, "|") + ")$"
+}
+
+func runPerTest(opts *perTestOptions) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if opts.batchSize < 1 {
+		return fmt.Errorf("--batch-size must be at least 1")
+	}
+	if opts.parallel < 1 {
+		opts.parallel = 1
+	}
+	pkgs, err := opts.listPackages(opts.packages, opts.args)
+	if err != nil {
+		return err
+	}
+	var batches []testBatch
+	for _, pkg := range pkgs {
+		tests, err := opts.listTests(pkg, opts.args)
+		if err != nil {
+			return err
+		}
+		batches = append(batches, makeBatches(pkg, tests, opts.batchSize)...)
+	}
+
+	dir, err := ioutil.TempDir("", "gotestsum-coverage")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	results := make([][]coverage.TestCoverage, len(batches))
+	errs := make([]error, len(batches))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				profile := filepath.Join(dir, strconv.Itoa(i)+".out")
+				results[i], errs[i] = runBatch(opts, batches[i], profile)
+			}
+		}()
+	}
+	for i := range batches {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	m := &coverage.Map{Tests: []coverage.TestCoverage{}}
+	for i, batch := range batches {
+		if errs[i] != nil {
+			return fmt.Errorf("failed to run %v in %v: %w",
+				strings.Join(batch.tests, ", "), batch.pkg, errs[i])
+		}
+		m.Tests = append(m.Tests, results[i]...)
+	}
+
+	out, closer, err := outputWriter(opts.stdout, opts.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := m.Encode(out); err != nil {
+		_ = closer()
+		return fmt.Errorf("failed to write coverage map: %w", err)
+	}
+	return closer()
+}
+
+func makeBatches(pkg string, tests []string, size int) []testBatch {
+	var batches []testBatch
+	for len(tests) > 0 {
+		n := size
+		if n > len(tests) {
+			n = len(tests)
+		}
+		batches = append(batches, testBatch{pkg: pkg, tests: tests[:n]})
+		tests = tests[n:]
+	}
+	return batches
+}
+
+// runBatch runs the tests in the batch, and returns the coverage of each test.
+// A test that fails still covers the lines it ran, so test failures are only
+// logged.
+func runBatch(opts *perTestOptions, batch testBatch, profile string) ([]coverage.TestCoverage, error) {
+	args := []string{"test", "-count=1", "-run", batch.runPattern(), "-coverprofile", profile}
+	if opts.coverpkg != "" {
+		args = append(args, "-coverpkg", opts.coverpkg)
+	}
+	args = append(args, opts.args...)
+	args = append(args, batch.pkg)
+	if err := opts.goTest(args); err != nil {
+		log.Warnf("tests failed in %v (%v): %v", batch.pkg, strings.Join(batch.tests, ", "), err)
+	}
+
+	blocks, err := coverage.ReadProfile(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coverage profile: %w", err)
+	}
+	files := coverage.CoveredLines(blocks)
+	result := make([]coverage.TestCoverage, 0, len(batch.tests))
+	for _, name := range batch.tests {
+		result = append(result, coverage.TestCoverage{Package: batch.pkg, Test: name, Files: files})
+	}
+	return result, nil
+}
+
+// listPackages returns the import path of the packages that have tests.
+func listPackages(patterns []string, args []string) ([]string, error) {
+	cmdArgs := []string{"list", "-json"}
+	cmdArgs = append(cmdArgs, buildFlags(args)...)
+	cmdArgs = append(cmdArgs, patterns...)
+	log.Debugf("exec: go %v", cmdArgs)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	var pkgs []string
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var pkg struct {
+			ImportPath   string
+			TestGoFiles  []string
+			XTestGoFiles []string
+		}
+		if err := dec.Decode(&pkg); err != nil {
+			return nil, fmt.Errorf("failed to decode go list output: %w", err)
+		}
+		if len(pkg.TestGoFiles)+len(pkg.XTestGoFiles) == 0 {
+			log.Debugf("skipping package %v without tests", pkg.ImportPath)
+			continue
+		}
+		pkgs = append(pkgs, pkg.ImportPath)
+	}
+	return pkgs, nil
+}
+
+// buildFlags returns the go test flags in args that change which files are
+// built, so that the same packages are listed.
+func buildFlags(args []string) []string {
+	var result []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-tags=") || strings.HasPrefix(arg, "--tags=") {
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
+// listTests returns the names of the top-level tests in the package.
+func listTests(pkg string, args []string) ([]string, error) {
+	cmdArgs := []string{"test", "-list", "^Test"}
+	cmdArgs = append(cmdArgs, buildFlags(args)...)
+	cmdArgs = append(cmdArgs, pkg)
+	log.Debugf("exec: go %v", cmdArgs)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tests in %v: %w", pkg, err)
+	}
+	return parseTestList(string(out)), nil
+}
+
+func parseTestList(out string) []string {
+	var names []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "Test") && !strings.ContainsAny(line, " \t") {
+			names = append(names, line)
+		}
+	}
+	return names
+}
+
+func goTest(args []string) error {
+	log.Debugf("exec: go %v", args)
+	cmd := exec.Command("go", args...)
+	cmd.Stdout = ioutil.Discard
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
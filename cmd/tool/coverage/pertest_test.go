@@ -0,0 +1,109 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/internal/coverage"
+	"gotest.tools/v3/assert"
+)
+
+func TestRunPerTest(t *testing.T) {
+	profiles := map[string]string{
+		"^(TestGet)$": `mode: set
+example.com/app/store/store.go:10.2,12.16 4 1
+example.com/app/store/store.go:14.2,16.3 4 0
+`,
+		"^(TestPut)$": `mode: set
+example.com/app/store/store.go:10.2,12.16 4 0
+example.com/app/store/store.go:14.2,16.3 4 1
+`,
+		"^(TestServe)$": `mode: set
+example.com/app/api/api.go:5.2,7.3 5 1
+`,
+	}
+	var mu sync.Mutex
+	var runs [][]string
+	out := new(bytes.Buffer)
+	opts := &perTestOptions{
+		packages:  []string{"./..."},
+		batchSize: 1,
+		parallel:  2,
+		args:      []string{"-tags=integration"},
+		stdout:    out,
+		listPackages: func(patterns []string, args []string) ([]string, error) {
+			assert.DeepEqual(t, patterns, []string{"./..."})
+			return []string{"example.com/app/api", "example.com/app/store"}, nil
+		},
+		listTests: func(pkg string, args []string) ([]string, error) {
+			if pkg == "example.com/app/api" {
+				return []string{"TestServe"}, nil
+			}
+			return []string{"TestGet", "TestPut"}, nil
+		},
+		goTest: func(args []string) error {
+			mu.Lock()
+			runs = append(runs, args)
+			mu.Unlock()
+			run, profile := flagValue(args, "-run"), flagValue(args, "-coverprofile")
+			if err := ioutil.WriteFile(profile, []byte(profiles[run]), 0o644); err != nil {
+				return err
+			}
+			if run == "^(TestPut)$" {
+				return fmt.Errorf("exit status 1")
+			}
+			return nil
+		},
+	}
+	assert.NilError(t, runPerTest(opts))
+	assert.Equal(t, len(runs), 3)
+	for _, args := range runs {
+		assert.Equal(t, args[len(args)-2], "-tags=integration")
+	}
+
+	m, err := coverage.DecodeMap(out)
+	assert.NilError(t, err)
+	expected := &coverage.Map{Tests: []coverage.TestCoverage{
+		{Package: "example.com/app/api", Test: "TestServe", Files: map[string][]coverage.LineRange{
+			"example.com/app/api/api.go": {{Start: 5, End: 7}},
+		}},
+		{Package: "example.com/app/store", Test: "TestGet", Files: map[string][]coverage.LineRange{
+			"example.com/app/store/store.go": {{Start: 10, End: 12}},
+		}},
+		{Package: "example.com/app/store", Test: "TestPut", Files: map[string][]coverage.LineRange{
+			"example.com/app/store/store.go": {{Start: 14, End: 16}},
+		}},
+	}}
+	assert.DeepEqual(t, m, expected)
+}
+
+func flagValue(args []string, name string) string {
+	for i, arg := range args {
+		if arg == name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func TestMakeBatches(t *testing.T) {
+	batches := makeBatches("pkg", []string{"TestA", "TestB", "TestC"}, 2)
+	assert.DeepEqual(t, batches, []testBatch{
+		{pkg: "pkg", tests: []string{"TestA", "TestB"}},
+		{pkg: "pkg", tests: []string{"TestC"}},
+	}, cmpTestBatch)
+	assert.Equal(t, batches[0].runPattern(), "^(TestA|TestB)$")
+}
+
+func TestParseTestList(t *testing.T) {
+	out := "TestOne\nTestTwo\nExampleThree\nok  \texample.com/pkg\t0.003s\n"
+	assert.DeepEqual(t, parseTestList(out), []string{"TestOne", "TestTwo"})
+	assert.Assert(t, strings.Contains(testBatch{tests: []string{"Test.Dot"}}.runPattern(), `Test\.Dot`))
+}
+
+var cmpTestBatch = gocmp.AllowUnexported(testBatch{})
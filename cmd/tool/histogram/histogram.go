@@ -0,0 +1,190 @@
+package histogram
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		opts.jsonfile = flags.Arg(0)
+	default:
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("too many arguments, expected one jsonfile")
+	}
+	opts.stdout = os.Stdout
+	return run(opts)
+}
+
+type options struct {
+	jsonfile string
+	buckets  []time.Duration
+	subtests bool
+	debug    bool
+
+	// shims for testing
+	stdout io.Writer
+}
+
+var defaultBuckets = []time.Duration{
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.DurationSliceVar(&opts.buckets, "buckets", defaultBuckets,
+		"comma separated list of the upper bound of each bucket")
+	flags.BoolVar(&opts.subtests, "subtests", false,
+		"include subtests in the histogram of tests")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [JSONFILE]
+
+Print a histogram of the elapsed time of the tests, and of the packages, in a
+test run. JSONFILE is a file of test2json events, like the file written by
+'gotestsum --jsonfile', and defaults to stdin.
+
+Each bucket counts the values that are less than its upper bound, and at least
+the upper bound of the previous bucket. The last bucket counts all the values
+greater than the largest upper bound.
+
+    %[1]s saved.json --buckets 50ms,500ms,5s
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if len(opts.buckets) == 0 {
+		return fmt.Errorf("at least one bucket is required")
+	}
+	buckets := append([]time.Duration{}, opts.buckets...)
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i] < buckets[j]
+	})
+
+	in, err := jsonfile.Open(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer in.Close() // nolint: errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	if err != nil {
+		return fmt.Errorf("failed to scan jsonfile: %w", err)
+	}
+
+	var tests, pkgs []time.Duration
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		for _, tc := range pkg.TestCases() {
+			if !opts.subtests && tc.Test.IsSubTest() {
+				continue
+			}
+			tests = append(tests, tc.Elapsed)
+		}
+		if pkg.Total > 0 {
+			pkgs = append(pkgs, pkg.Elapsed())
+		}
+	}
+	printHistogram(opts.stdout, "Tests", newHistogram(buckets, tests))
+	fmt.Fprintln(opts.stdout)
+	printHistogram(opts.stdout, "Packages", newHistogram(buckets, pkgs))
+	return nil
+}
+
+type bucket struct {
+	label string
+	count int
+}
+
+// newHistogram returns the count of values in each bucket. bounds must be
+// sorted. The result has one more bucket than bounds, for the values greater
+// than the largest bound.
+func newHistogram(bounds []time.Duration, values []time.Duration) []bucket {
+	result := make([]bucket, len(bounds)+1)
+	for i, bound := range bounds {
+		switch i {
+		case 0:
+			result[i].label = "< " + bound.String()
+		default:
+			result[i].label = bounds[i-1].String() + " - " + bound.String()
+		}
+	}
+	result[len(bounds)].label = ">= " + bounds[len(bounds)-1].String()
+
+	for _, v := range values {
+		i := sort.Search(len(bounds), func(i int) bool {
+			return v < bounds[i]
+		})
+		result[i].count++
+	}
+	return result
+}
+
+// maxBarWidth is the number of characters used for the bar of the largest
+// bucket.
+const maxBarWidth = 50
+
+func printHistogram(out io.Writer, title string, buckets []bucket) {
+	var total, largest, labelWidth int
+	for _, b := range buckets {
+		total += b.count
+		if b.count > largest {
+			largest = b.count
+		}
+		if len(b.label) > labelWidth {
+			labelWidth = len(b.label)
+		}
+	}
+	fmt.Fprintf(out, "%s (%d)\n", title, total)
+	for _, b := range buckets {
+		width := 0
+		if largest > 0 {
+			width = b.count * maxBarWidth / largest
+		}
+		if width == 0 && b.count > 0 {
+			width = 1
+		}
+		fmt.Fprintf(out, "%*s | %-*s %d\n",
+			labelWidth, b.label, maxBarWidth, strings.Repeat("#", width), b.count)
+	}
+}
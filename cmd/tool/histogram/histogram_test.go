@@ -0,0 +1,42 @@
+package histogram
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile: "../../../testjson/testdata/input/go-test-json.out",
+		buckets:  []time.Duration{10 * time.Millisecond, time.Millisecond},
+		stdout:   out,
+	}
+	assert.NilError(t, run(opts))
+	golden.Assert(t, out.String(), "histogram.golden")
+}
+
+func TestNewHistogram(t *testing.T) {
+	bounds := []time.Duration{time.Second, time.Minute}
+	values := []time.Duration{
+		0,
+		500 * time.Millisecond,
+		time.Second,
+		30 * time.Second,
+		time.Minute,
+		time.Hour,
+	}
+	expected := []bucket{
+		{label: "< 1s", count: 2},
+		{label: "1s - 1m0s", count: 2},
+		{label: ">= 1m0s", count: 2},
+	}
+	assert.DeepEqual(t, newHistogram(bounds, values), expected, cmpBucket)
+}
+
+var cmpBucket = gocmp.AllowUnexported(bucket{})
@@ -0,0 +1,432 @@
+package impact
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/coverage"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	if flags.NArg() > 0 {
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	opts.stdout = os.Stdout
+	return run(opts)
+}
+
+type options struct {
+	base        string
+	coverageMap string
+	jsonfile    string
+	output      string
+	debug       bool
+
+	// shims for testing
+	stdout  io.Writer
+	gitDiff func(base string) (string, error)
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{gitDiff: gitDiff}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.base, "base", "",
+		"git ref of the branch the changes will be merged into, like origin/main")
+	flags.StringVar(&opts.coverageMap, "coverage-map", "",
+		"file with the coverage of each test, from a prior run with per-test coverage")
+	flags.StringVar(&opts.jsonfile, "jsonfile", "",
+		"test2json output of the test run for the changes, used to report the result of each test")
+	flags.StringVarP(&opts.output, "output", "o", "-",
+		"write the report to this file, defaults to stdout")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Report the tests that cover the lines changed since --base, and the result of
+those tests in the --jsonfile, as Markdown that can be posted as a comment on
+a pull request.
+
+The changed lines are the lines in the working tree that are different from
+the merge base of --base and HEAD. The --coverage-map is a JSON file with the
+lines covered by each test, from a prior run of the tests with per-test
+coverage. When --jsonfile is not set every test is reported as not run.
+
+    %[1]s --base origin/main --coverage-map coverage.json --jsonfile test.json
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	switch {
+	case opts.base == "":
+		return fmt.Errorf("--base is required")
+	case opts.coverageMap == "":
+		return fmt.Errorf("--coverage-map is required")
+	}
+
+	diff, err := opts.gitDiff(opts.base)
+	if err != nil {
+		return fmt.Errorf("failed to find the lines changed since %v: %w", opts.base, err)
+	}
+	changes, err := parseDiff(diff)
+	if err != nil {
+		return err
+	}
+	cov, err := coverage.ReadMap(opts.coverageMap)
+	if err != nil {
+		return fmt.Errorf("failed to read coverage map: %w", err)
+	}
+	var exec *testjson.Execution
+	if opts.jsonfile != "" {
+		if exec, err = scanFile(opts.jsonfile); err != nil {
+			return err
+		}
+	}
+
+	rpt := newReport(opts.base, changes, cov, exec)
+	out, closer, err := outputWriter(opts.stdout, opts.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := rpt.writeMarkdown(out); err != nil {
+		_ = closer()
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return closer()
+}
+
+func scanFile(path string) (*testjson.Execution, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer fh.Close() // nolint: errcheck
+	return testjson.ScanTestOutput(testjson.ScanConfig{Stdout: fh})
+}
+
+func outputWriter(stdout io.Writer, v string) (io.Writer, func() error, error) {
+	if v == "" || v == "-" {
+		return stdout, func() error { return nil }, nil
+	}
+	fh, err := os.Create(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fh, fh.Close, nil
+}
+
+// gitDiff returns the diff, with no context lines, of the working tree from
+// the merge base of base and HEAD.
+func gitDiff(base string) (string, error) {
+	mergeBase, err := git("merge-base", base, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return git("diff", "--no-color", "--no-ext-diff", "-U0",
+		"--src-prefix=a/", "--dst-prefix=b/", strings.TrimSpace(mergeBase), "--")
+}
+
+func git(args ...string) (string, error) {
+	log.Debugf("exec: git %v", args)
+	cmd := exec.Command("git", args...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %v: %w: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return string(out), nil
+}
+
+// changedLines maps the path of a file, relative to the root of the
+// repository, to the lines that were added or changed in the file.
+type changedLines map[string][]coverage.LineRange
+
+// parseDiff returns the lines added or changed in the non-test Go files of a
+// unified diff. Lines that were only removed are not included, because
+// there is no line in the new file that a test could cover.
+func parseDiff(diff string) (changedLines, error) {
+	changes := make(changedLines)
+	var file string
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+				file = ""
+			}
+		case strings.HasPrefix(line, "@@ ") && file != "":
+			r, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			if r.End >= r.Start {
+				changes[file] = append(changes[file], r)
+			}
+		}
+	}
+	return changes, scanner.Err()
+}
+
+// parseHunkHeader returns the range of lines in the new file from a hunk
+// header, like "@@ -10,2 +12,3 @@ func name()". The range is empty when the
+// hunk only removes lines.
+func parseHunkHeader(line string) (coverage.LineRange, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return coverage.LineRange{}, fmt.Errorf("invalid hunk header in diff: %v", line)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(fields[2], "+"), ",", 2)
+	start, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return coverage.LineRange{}, fmt.Errorf("invalid hunk header in diff: %v", line)
+	}
+	count := 1
+	if len(parts) == 2 {
+		if count, err = strconv.Atoi(parts[1]); err != nil {
+			return coverage.LineRange{}, fmt.Errorf("invalid hunk header in diff: %v", line)
+		}
+	}
+	return coverage.LineRange{Start: start, End: start + count - 1}, nil
+}
+
+type result string
+
+const (
+	resultPassed  result = "passed"
+	resultFailed  result = "failed"
+	resultSkipped result = "skipped"
+	resultNotRun  result = "not run"
+)
+
+func (r result) icon() string {
+	switch r {
+	case resultPassed:
+		return "✅"
+	case resultFailed:
+		return "❌"
+	case resultSkipped:
+		return "⏭️"
+	}
+	return "⚠️"
+}
+
+// impactedTest is a test that covers at least one of the changed lines.
+type impactedTest struct {
+	pkg    string
+	test   string
+	lines  int
+	result result
+}
+
+type report struct {
+	base         string
+	files        int
+	changedLines int
+	tests        []impactedTest
+	// uncovered maps the name of a file to the changed lines that are not
+	// covered by any test.
+	uncovered map[string][]coverage.LineRange
+}
+
+func newReport(base string, changes changedLines, cov *coverage.Map, exec *testjson.Execution) report {
+	rpt := report{base: base, files: len(changes), uncovered: make(map[string][]coverage.LineRange)}
+	covered := make(map[string]map[int]bool)
+	for file, ranges := range changes {
+		covered[file] = make(map[int]bool)
+		for _, r := range ranges {
+			rpt.changedLines += r.End - r.Start + 1
+		}
+	}
+
+	for _, tc := range cov.Tests {
+		var lines int
+		for file, ranges := range changes {
+			seen := make(map[int]bool)
+			for _, r := range ranges {
+				for _, c := range tc.Covers(file) {
+					if !r.Overlaps(c) {
+						continue
+					}
+					for n := maxInt(r.Start, c.Start); n <= minInt(r.End, c.End); n++ {
+						seen[n] = true
+						covered[file][n] = true
+					}
+				}
+			}
+			lines += len(seen)
+		}
+		if lines == 0 {
+			continue
+		}
+		rpt.tests = append(rpt.tests, impactedTest{
+			pkg:    tc.Package,
+			test:   tc.Test,
+			lines:  lines,
+			result: testResult(exec, tc.Package, tc.Test),
+		})
+	}
+	sort.Slice(rpt.tests, func(i, j int) bool {
+		a, b := rpt.tests[i], rpt.tests[j]
+		if a.pkg != b.pkg {
+			return a.pkg < b.pkg
+		}
+		return a.test < b.test
+	})
+
+	for file, ranges := range changes {
+		for _, r := range ranges {
+			for n := r.Start; n <= r.End; n++ {
+				if !covered[file][n] {
+					rpt.uncovered[file] = appendLine(rpt.uncovered[file], n)
+				}
+			}
+		}
+	}
+	return rpt
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// appendLine adds the line to the last range in ranges when it is the next
+// line, otherwise it adds a new range.
+func appendLine(ranges []coverage.LineRange, n int) []coverage.LineRange {
+	if last := len(ranges) - 1; last >= 0 && ranges[last].End == n-1 {
+		ranges[last].End = n
+		return ranges
+	}
+	return append(ranges, coverage.LineRange{Start: n, End: n})
+}
+
+// testResult returns the result of the last run of the test in exec.
+func testResult(exec *testjson.Execution, pkgName string, name string) result {
+	if exec == nil {
+		return resultNotRun
+	}
+	pkg := exec.Package(pkgName)
+	if pkg == nil {
+		return resultNotRun
+	}
+	var last testjson.TestCase
+	res := resultNotRun
+	check := func(cases []testjson.TestCase, r result) {
+		for _, tc := range cases {
+			if tc.Test.Name() == name && tc.ID >= last.ID {
+				last, res = tc, r
+			}
+		}
+	}
+	check(pkg.Passed, resultPassed)
+	check(pkg.Skipped, resultSkipped)
+	check(pkg.Failed, resultFailed)
+	return res
+}
+
+func (r report) writeMarkdown(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "## Test impact\n\n")
+	if r.changedLines == 0 {
+		fmt.Fprintf(w, "No Go source files changed since `%v`.\n", r.base)
+		return w.Flush()
+	}
+	fmt.Fprintf(w, "%d changed %s in %d %s since `%v`, covered by %d %s.\n",
+		r.changedLines, plural(r.changedLines, "line", "lines"),
+		r.files, plural(r.files, "file", "files"),
+		r.base, len(r.tests), plural(len(r.tests), "test", "tests"))
+
+	if len(r.tests) > 0 {
+		fmt.Fprintf(w, "\n| Result | Package | Test | Changed lines covered |\n")
+		fmt.Fprintf(w, "| --- | --- | --- | --- |\n")
+		for _, tc := range r.tests {
+			fmt.Fprintf(w, "| %s %s | `%s` | `%s` | %d |\n",
+				tc.result.icon(), tc.result, tc.pkg, tc.test, tc.lines)
+		}
+		counts := make(map[result]int)
+		for _, tc := range r.tests {
+			counts[tc.result]++
+		}
+		fmt.Fprintf(w, "\n%d passed, %d failed, %d skipped, %d not run.\n",
+			counts[resultPassed], counts[resultFailed], counts[resultSkipped], counts[resultNotRun])
+	}
+
+	if len(r.uncovered) > 0 {
+		fmt.Fprintf(w, "\n### Changed lines not covered by any test\n\n")
+		files := make([]string, 0, len(r.uncovered))
+		for file := range r.uncovered {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			fmt.Fprintf(w, "- `%s`: %s\n", filepath.ToSlash(file), formatRanges(r.uncovered[file]))
+		}
+	}
+	return w.Flush()
+}
+
+func formatRanges(ranges []coverage.LineRange) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r.Start == r.End {
+			parts = append(parts, strconv.Itoa(r.Start))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d-%d", r.Start, r.End))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, singular, many string) string {
+	if n == 1 {
+		return singular
+	}
+	return many
+}
@@ -0,0 +1,144 @@
+package impact
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/coverage"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+const diff = `diff --git a/store/store.go b/store/store.go
+index 1111111..2222222 100644
+--- a/store/store.go
++++ b/store/store.go
+@@ -10,0 +11,3 @@ func Get(key string) string {
++	if key == "" {
++		return ""
++	}
+@@ -40 +43 @@ func Put(key, value string) {
+-	old()
++	updated()
+@@ -60,2 +62,0 @@ func Delete(key string) {
+-	removed()
+-	removed()
+diff --git a/store/store_test.go b/store/store_test.go
+--- a/store/store_test.go
++++ b/store/store_test.go
+@@ -1 +1,2 @@ package store
+diff --git a/docs/README.md b/docs/README.md
+--- a/docs/README.md
++++ b/docs/README.md
+@@ -1 +1 @@
+diff --git a/api/api.go b/api/api.go
+new file mode 100644
+--- /dev/null
++++ b/api/api.go
+@@ -0,0 +1,5 @@
+`
+
+func TestParseDiff(t *testing.T) {
+	changes, err := parseDiff(diff)
+	assert.NilError(t, err)
+	expected := changedLines{
+		"store/store.go": {{Start: 11, End: 13}, {Start: 43, End: 43}},
+		"api/api.go":     {{Start: 1, End: 5}},
+	}
+	assert.DeepEqual(t, changes, expected)
+
+	_, err = parseDiff("+++ b/file.go\n@@ -1 +x @@\n")
+	assert.ErrorContains(t, err, "invalid hunk header in diff")
+}
+
+const coverageMap = `{
+  "tests": [
+    {
+      "package": "example.com/app/store",
+      "test": "TestGet",
+      "files": {"example.com/app/store/store.go": [{"start": 5, "end": 12}]}
+    },
+    {
+      "package": "example.com/app/store",
+      "test": "TestPut",
+      "files": {"example.com/app/store/store.go": [{"start": 40, "end": 45}, {"start": 12, "end": 14}]}
+    },
+    {
+      "package": "example.com/app/store",
+      "test": "TestDelete",
+      "files": {"example.com/app/store/store.go": [{"start": 60, "end": 70}]}
+    },
+    {
+      "package": "example.com/app/api",
+      "test": "TestServe",
+      "files": {"example.com/app/api/api.go": [{"start": 1, "end": 2}]}
+    },
+    {
+      "package": "example.com/app/api",
+      "test": "TestSkipped",
+      "files": {"example.com/app/api/api.go": [{"start": 2, "end": 2}]}
+    }
+  ]
+}
+`
+
+const jsonfile = `{"Action":"run","Package":"example.com/app/store","Test":"TestGet"}
+{"Action":"fail","Package":"example.com/app/store","Test":"TestGet"}
+{"Action":"run","Package":"example.com/app/store","Test":"TestPut"}
+{"Action":"fail","Package":"example.com/app/store","Test":"TestPut"}
+{"Action":"run","Package":"example.com/app/store","Test":"TestPut"}
+{"Action":"pass","Package":"example.com/app/store","Test":"TestPut"}
+{"Action":"run","Package":"example.com/app/api","Test":"TestSkipped"}
+{"Action":"skip","Package":"example.com/app/api","Test":"TestSkipped"}
+{"Action":"pass","Package":"example.com/app/api"}
+{"Action":"fail","Package":"example.com/app/store"}
+`
+
+func TestRun(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("coverage.json", coverageMap),
+		fs.WithFile("test.json", jsonfile))
+	out := new(bytes.Buffer)
+	opts := &options{
+		base:        "origin/main",
+		coverageMap: dir.Join("coverage.json"),
+		jsonfile:    dir.Join("test.json"),
+		output:      "-",
+		stdout:      out,
+		gitDiff: func(base string) (string, error) {
+			assert.Equal(t, base, "origin/main")
+			return diff, nil
+		},
+	}
+	assert.NilError(t, run(opts))
+	golden.Assert(t, out.String(), "impact.golden")
+}
+
+func TestRun_NoChanges(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("coverage.json", coverageMap))
+	out := new(bytes.Buffer)
+	opts := &options{
+		base:        "origin/main",
+		coverageMap: dir.Join("coverage.json"),
+		stdout:      out,
+		gitDiff: func(string) (string, error) {
+			return "", nil
+		},
+	}
+	assert.NilError(t, run(opts))
+	assert.Equal(t, out.String(), "## Test impact\n\nNo Go source files changed since `origin/main`.\n")
+}
+
+func TestRun_MissingFlags(t *testing.T) {
+	assert.ErrorContains(t, run(&options{}), "--base is required")
+	assert.ErrorContains(t, run(&options{base: "main"}), "--coverage-map is required")
+}
+
+func TestAppendLine(t *testing.T) {
+	var ranges []coverage.LineRange
+	for _, n := range []int{3, 4, 5, 8, 10, 11} {
+		ranges = appendLine(ranges, n)
+	}
+	assert.Equal(t, formatRanges(ranges), "3-5, 8, 10-11")
+}
@@ -0,0 +1,166 @@
+package lintmerge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	if opts.lintJSON == "" || opts.junitFile == "" {
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("--lint-json and --junitfile are required")
+	}
+	return run(opts)
+}
+
+type options struct {
+	lintJSON    string
+	jsonfile    string
+	junitFile   string
+	projectName string
+	debug       bool
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.lintJSON, "lint-json", "",
+		"path to the output of 'golangci-lint run --out-format json'")
+	flags.StringVar(&opts.jsonfile, "jsonfile", os.Getenv("GOTESTSUM_JSONFILE"),
+		"path to test2json output, like the file written by 'gotestsum --jsonfile'")
+	flags.StringVar(&opts.junitFile, "junitfile", "",
+		"write a JUnit XML file with the test results and the lint issues")
+	flags.StringVar(&opts.projectName, "junitfile-project-name",
+		os.Getenv("GOTESTSUM_JUNITFILE_PROJECT_NAME"),
+		"name of the project used in the junit.xml file")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Write a JUnit XML file with the issues reported by golangci-lint as failed
+test cases, along with the results of a test run from --jsonfile. Each linter
+is written as a testsuite named lint/LINTER.
+
+    golangci-lint run --out-format json > lint.json
+    %[1]s --lint-json lint.json --jsonfile tests.json --junitfile combined.xml
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	issues, err := readLintIssues(opts.lintJSON)
+	if err != nil {
+		return err
+	}
+	exec, err := readExecution(opts.jsonfile)
+	if err != nil {
+		return err
+	}
+
+	fh, err := os.Create(opts.junitFile)
+	if err != nil {
+		return fmt.Errorf("failed to create junitfile: %w", err)
+	}
+	cfg := junitxml.Config{
+		ProjectName: opts.projectName,
+		LintIssues:  issues,
+	}
+	if err := junitxml.Write(fh, exec, cfg); err != nil {
+		_ = fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+func readExecution(path string) (*testjson.Execution, error) {
+	if path == "" {
+		return testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader("")})
+	}
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer fh.Close() // nolint: errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: fh})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan jsonfile: %w", err)
+	}
+	return exec, nil
+}
+
+// golangciReport is the subset of the golangci-lint JSON output used to
+// create the lint issues.
+type golangciReport struct {
+	Issues []struct {
+		FromLinter string
+		Text       string
+		Severity   string
+		Pos        struct {
+			Filename string
+			Line     int
+			Column   int
+		}
+	}
+}
+
+func readLintIssues(path string) ([]junitxml.LintIssue, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lint-json: %w", err)
+	}
+	defer fh.Close() // nolint: errcheck
+	return parseLintIssues(fh)
+}
+
+func parseLintIssues(in io.Reader) ([]junitxml.LintIssue, error) {
+	var report golangciReport
+	if err := json.NewDecoder(in).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to decode golangci-lint JSON: %w", err)
+	}
+	issues := make([]junitxml.LintIssue, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		issues = append(issues, junitxml.LintIssue{
+			Linter:   issue.FromLinter,
+			Severity: issue.Severity,
+			File:     issue.Pos.Filename,
+			Line:     issue.Pos.Line,
+			Column:   issue.Pos.Column,
+			Message:  issue.Text,
+		})
+	}
+	log.Debugf("read %d lint issues", len(issues))
+	return issues, nil
+}
@@ -0,0 +1,76 @@
+package lintmerge
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const golangciOutput = `{
+  "Issues": [
+    {
+      "FromLinter": "errcheck",
+      "Text": "Error return value of ` + "`fh.Close`" + ` is not checked",
+      "Severity": "",
+      "SourceLines": ["\tfh.Close()"],
+      "Pos": {"Filename": "cmd/main.go", "Offset": 120, "Line": 12, "Column": 10}
+    },
+    {
+      "FromLinter": "staticcheck",
+      "Text": "SA4006: this value of x is never used",
+      "Severity": "warning",
+      "Pos": {"Filename": "internal/a/a.go", "Offset": 0, "Line": 3, "Column": 2}
+    }
+  ],
+  "Report": {"Linters": [{"Name": "errcheck", "Enabled": true}]}
+}`
+
+func TestParseLintIssues(t *testing.T) {
+	issues, err := parseLintIssues(strings.NewReader(golangciOutput))
+	assert.NilError(t, err)
+	expected := []junitxml.LintIssue{
+		{
+			Linter:  "errcheck",
+			File:    "cmd/main.go",
+			Line:    12,
+			Column:  10,
+			Message: "Error return value of `fh.Close` is not checked",
+		},
+		{
+			Linter:   "staticcheck",
+			Severity: "warning",
+			File:     "internal/a/a.go",
+			Line:     3,
+			Column:   2,
+			Message:  "SA4006: this value of x is never used",
+		},
+	}
+	assert.DeepEqual(t, issues, expected)
+}
+
+func TestParseLintIssues_InvalidJSON(t *testing.T) {
+	_, err := parseLintIssues(strings.NewReader("cmd/main.go:12:10: not json"))
+	assert.ErrorContains(t, err, "failed to decode golangci-lint JSON")
+}
+
+func TestRun(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("lint.json", golangciOutput))
+	opts := &options{
+		lintJSON:  dir.Join("lint.json"),
+		jsonfile:  "../../../testjson/testdata/input/go-test-json.out",
+		junitFile: filepath.Join(dir.Path(), "combined.xml"),
+	}
+	assert.NilError(t, run(opts))
+
+	raw, err := ioutil.ReadFile(opts.junitFile)
+	assert.NilError(t, err)
+	out := string(raw)
+	assert.Assert(t, strings.Contains(out, `name="lint/errcheck"`), out)
+	assert.Assert(t, strings.Contains(out, `name="lint/staticcheck"`), out)
+	assert.Assert(t, strings.Contains(out, `name="gotest.tools/gotestsum/testjson/internal/good"`), out)
+}
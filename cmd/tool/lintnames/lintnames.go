@@ -0,0 +1,192 @@
+package lintnames
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	opts.stdout = os.Stdout
+	return run(opts)
+}
+
+type options struct {
+	jsonfile              string
+	requireRequirementTag bool
+	maxLength             int
+	debug                 bool
+
+	// shims for testing
+	stdout io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.jsonfile, "jsonfile", os.Getenv("GOTESTSUM_JSONFILE"),
+		"path to test2json output, defaults to stdin")
+	flags.BoolVar(&opts.requireRequirementTag, "require-requirement-tag", false,
+		"report tests without a requirement in square brackets in their name")
+	flags.IntVar(&opts.maxLength, "max-length", 120,
+		"report tests with a name longer than this number of characters, 0 for no limit")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read a json file and report test names that do not follow the naming
+convention. The json file may be created with 'gotestsum --jsonfile' or
+'go test -json'.
+
+A requirement is the text in square brackets in a test name, like
+TestLogin[REQ-12]. The JUnit XML file uses the requirement as a property of the
+testcase, and removes it from the name of the testcase. The command reports
+tests that would have the same name in the JUnit XML file after the
+requirement is removed, tests with a name that is longer than --max-length,
+and with --require-requirement-tag, tests that do not have a requirement.
+
+The command exits with a non-zero status when any test is reported.
+
+    %[1]s --jsonfile saved.json --require-requirement-tag
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	in, err := jsonfile.Open(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer in.Close() // nolint: errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	if err != nil {
+		return fmt.Errorf("failed to scan jsonfile: %w", err)
+	}
+
+	problems := lintNames(exec, opts)
+	for _, p := range problems {
+		fmt.Fprintf(opts.stdout, "%s.%s: %s\n",
+			testjson.RelativePackagePath(p.pkg), p.test, p.message)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d test names do not follow the naming convention", len(problems))
+	}
+	return nil
+}
+
+type problem struct {
+	pkg     string
+	test    string
+	message string
+}
+
+// lintNames returns the problems with the names of the tests in exec, sorted
+// by package and test name. A test that ran more than once is only checked
+// once.
+func lintNames(exec *testjson.Execution, opts *options) []problem {
+	var problems []problem
+	for _, pkgName := range exec.Packages() {
+		names := uniqueTestNames(exec.Package(pkgName))
+		// stripped maps the name without requirements to the names of the
+		// tests with that name
+		stripped := make(map[string][]string)
+		for _, name := range names {
+			requirements, strippedName := junitxml.SplitRequirements(name)
+			stripped[strippedName] = append(stripped[strippedName], name)
+
+			if opts.requireRequirementTag && len(requirements) == 0 {
+				problems = append(problems, problem{
+					pkg:     pkgName,
+					test:    name,
+					message: "missing requirement tag",
+				})
+			}
+			if opts.maxLength > 0 && len(strippedName) > opts.maxLength {
+				problems = append(problems, problem{
+					pkg:  pkgName,
+					test: name,
+					message: fmt.Sprintf("name is %d characters, longer than the limit of %d",
+						len(strippedName), opts.maxLength),
+				})
+			}
+		}
+
+		for _, name := range names {
+			_, strippedName := junitxml.SplitRequirements(name)
+			others := stripped[strippedName]
+			if len(others) < 2 {
+				continue
+			}
+			problems = append(problems, problem{
+				pkg:  pkgName,
+				test: name,
+				message: fmt.Sprintf("name %q without the requirement is the same as %v",
+					strippedName, otherNames(others, name)),
+			})
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].pkg != problems[j].pkg {
+			return problems[i].pkg < problems[j].pkg
+		}
+		return problems[i].test < problems[j].test
+	})
+	return problems
+}
+
+func uniqueTestNames(pkg *testjson.Package) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, tc := range pkg.TestCases() {
+		name := tc.Test.Name()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func otherNames(names []string, name string) []string {
+	var result []string
+	for _, n := range names {
+		if n != name {
+			result = append(result, n)
+		}
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package lintnames
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestRun(t *testing.T) {
+	events := []string{
+		`{"Action":"run","Package":"example.com/auth","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"pass","Package":"example.com/auth","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"run","Package":"example.com/auth","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"fail","Package":"example.com/auth","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"run","Package":"example.com/auth","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"pass","Package":"example.com/auth","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"run","Package":"example.com/auth","Test":"TestLogout"}`,
+		`{"Action":"skip","Package":"example.com/auth","Test":"TestLogout"}`,
+		`{"Action":"run","Package":"example.com/auth","Test":"TestTokenRefreshAfterExpiry[REQ-3]"}`,
+		`{"Action":"pass","Package":"example.com/auth","Test":"TestTokenRefreshAfterExpiry[REQ-3]"}`,
+		`{"Action":"pass","Package":"example.com/auth"}`,
+		`{"Action":"run","Package":"example.com/store","Test":"TestLogin"}`,
+		`{"Action":"pass","Package":"example.com/store","Test":"TestLogin"}`,
+		`{"Action":"pass","Package":"example.com/store"}`,
+	}
+	jsonfile := fs.NewFile(t, "saved.json", fs.WithContent(strings.Join(events, "\n")))
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile:              jsonfile.Path(),
+		requireRequirementTag: true,
+		maxLength:             20,
+		stdout:                out,
+	}
+	err := run(opts)
+	assert.Error(t, err, "5 test names do not follow the naming convention")
+
+	expected := `example.com/auth.TestLogin[REQ-1]: name "TestLogin" without the requirement is the same as [TestLogin[REQ-2]]
+example.com/auth.TestLogin[REQ-2]: name "TestLogin" without the requirement is the same as [TestLogin[REQ-1]]
+example.com/auth.TestLogout: missing requirement tag
+example.com/auth.TestTokenRefreshAfterExpiry[REQ-3]: name is 27 characters, longer than the limit of 20
+example.com/store.TestLogin: missing requirement tag
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRun_NoProblems(t *testing.T) {
+	events := []string{
+		`{"Action":"run","Package":"example.com/auth","Test":"TestLogin"}`,
+		`{"Action":"pass","Package":"example.com/auth","Test":"TestLogin"}`,
+		`{"Action":"pass","Package":"example.com/auth"}`,
+	}
+	jsonfile := fs.NewFile(t, "saved.json", fs.WithContent(strings.Join(events, "\n")))
+
+	out := new(bytes.Buffer)
+	opts := &options{jsonfile: jsonfile.Path(), maxLength: 120, stdout: out}
+	assert.NilError(t, run(opts))
+	assert.Equal(t, out.String(), "")
+}
@@ -0,0 +1,146 @@
+package mutate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mutant is a single change to the source of a package.
+type mutant struct {
+	pkg  string
+	file string
+	line int
+	col  int
+	// index is the position of the expression in the file, in the order
+	// visited by ast.Inspect.
+	index int
+	from  token.Token
+	to    token.Token
+}
+
+func (m mutant) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", relativePath(m.file), m.line, m.col, m.description())
+}
+
+func (m mutant) description() string {
+	return fmt.Sprintf("replaced %v with %v", m.from, m.to)
+}
+
+// relativePath returns path relative to the working directory, or path if it
+// is not in the working directory.
+func relativePath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// operators maps each binary operator to the operator that replaces it in a
+// mutant, the same mutations made by the expression mutators of go-mutesting.
+var operators = map[token.Token]token.Token{
+	token.EQL:  token.NEQ,
+	token.NEQ:  token.EQL,
+	token.LSS:  token.LEQ,
+	token.LEQ:  token.LSS,
+	token.GTR:  token.GEQ,
+	token.GEQ:  token.GTR,
+	token.ADD:  token.SUB,
+	token.SUB:  token.ADD,
+	token.MUL:  token.QUO,
+	token.QUO:  token.MUL,
+	token.LAND: token.LOR,
+	token.LOR:  token.LAND,
+}
+
+// findMutants returns a mutant for every binary expression in the source of
+// the file that can be mutated.
+func findMutants(pkg, filename string, src []byte) ([]mutant, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var result []mutant
+	index := 0
+	ast.Inspect(file, func(node ast.Node) bool {
+		expr, ok := node.(*ast.BinaryExpr)
+		if !ok {
+			return true
+		}
+		defer func() { index++ }()
+		to, ok := operators[expr.Op]
+		if !ok || isStringConcat(expr) {
+			return true
+		}
+		pos := fset.Position(expr.OpPos)
+		result = append(result, mutant{
+			pkg:   pkg,
+			file:  filename,
+			line:  pos.Line,
+			col:   pos.Column,
+			index: index,
+			from:  expr.Op,
+			to:    to,
+		})
+		return true
+	})
+	return result, nil
+}
+
+// isStringConcat returns true if either operand is a string literal, because
+// replacing + in a string concatenation does not compile.
+func isStringConcat(expr *ast.BinaryExpr) bool {
+	if expr.Op != token.ADD {
+		return false
+	}
+	for _, operand := range []ast.Expr{expr.X, expr.Y} {
+		if lit, ok := operand.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the source of the file with the mutation.
+func (m mutant) apply(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, m.file, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	index := 0
+	var found bool
+	ast.Inspect(file, func(node ast.Node) bool {
+		expr, ok := node.(*ast.BinaryExpr)
+		if !ok || found {
+			return !found
+		}
+		if index == m.index && expr.Op == m.from {
+			expr.Op = m.to
+			found = true
+			return false
+		}
+		index++
+		return true
+	})
+	if !found {
+		return nil, fmt.Errorf("expression for mutant %v not found", m)
+	}
+	buf := new(bytes.Buffer)
+	if err := format.Node(buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
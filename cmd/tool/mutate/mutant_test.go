@@ -0,0 +1,63 @@
+package mutate
+
+import (
+	"go/token"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+const source = `package example
+
+func check(a, b int, ok bool) bool {
+	if a == b && ok {
+		return true
+	}
+	name := "a" + "b"
+	return a+1 < b*2 || len(name) > 3
+}
+`
+
+func TestFindMutants(t *testing.T) {
+	mutants, err := findMutants("example.com/example", "example.go", []byte(source))
+	assert.NilError(t, err)
+
+	type mutation struct {
+		Line     int
+		From, To token.Token
+	}
+	var actual []mutation
+	for _, m := range mutants {
+		actual = append(actual, mutation{Line: m.line, From: m.from, To: m.to})
+	}
+	expected := []mutation{
+		{Line: 4, From: token.LAND, To: token.LOR},
+		{Line: 4, From: token.EQL, To: token.NEQ},
+		{Line: 8, From: token.LOR, To: token.LAND},
+		{Line: 8, From: token.LSS, To: token.LEQ},
+		{Line: 8, From: token.ADD, To: token.SUB},
+		{Line: 8, From: token.MUL, To: token.QUO},
+		{Line: 8, From: token.GTR, To: token.GEQ},
+	}
+	assert.DeepEqual(t, actual, expected)
+}
+
+func TestMutant_Apply(t *testing.T) {
+	mutants, err := findMutants("example.com/example", "example.go", []byte(source))
+	assert.NilError(t, err)
+	assert.Assert(t, len(mutants) > 4)
+
+	out, err := mutants[4].apply([]byte(source))
+	assert.NilError(t, err)
+	expected := `package example
+
+func check(a, b int, ok bool) bool {
+	if a == b && ok {
+		return true
+	}
+	name := "a" + "b"
+	return a-1 < b*2 || len(name) > 3
+}
+`
+	assert.Equal(t, string(out), expected)
+}
@@ -0,0 +1,434 @@
+package mutate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	opts.args = flags.Args()
+	opts.stdout = os.Stdout
+	return run(opts)
+}
+
+type options struct {
+	packages []string
+	parallel int
+	report   string
+	minScore float64
+	debug    bool
+	args     []string
+
+	// shims for testing
+	stdout io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringSliceVar(&opts.packages, "packages", []string{"./..."},
+		"packages to mutate and test")
+	flags.IntVar(&opts.parallel, "parallel", 1,
+		"number of mutants to test at the same time")
+	flags.StringVar(&opts.report, "report", "",
+		"write a JSON report of every mutant to this file")
+	flags.Float64Var(&opts.minScore, "min-score", 0,
+		"exit with an error if the mutation score of any package is lower than this value (0 to 1)")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [--] [go test flags]
+
+Measure how well the tests of each package detect changes to the code. Each
+comparison, arithmetic, and boolean operator in the non-test files of the
+packages is replaced, one at a time, and the tests of the package are run
+with the change. A mutant that does not cause a test to fail survived.
+
+The mutation score of a package is the fraction of mutants that were killed.
+Mutants that do not compile are not included in the score. The source files
+are not modified, the mutants are applied with 'go test -overlay', which
+requires go1.16 or later.
+
+Any args after the flags are passed to 'go test'.
+
+    %[1]s --packages ./store --parallel 4 -- -timeout=1m
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+type status string
+
+const (
+	statusKilled    status = "killed"
+	statusSurvived  status = "survived"
+	statusNotViable status = "not-viable"
+)
+
+type mutantResult struct {
+	mutant mutant
+	status status
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if opts.parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+	pkgs, err := listPackages(opts.packages)
+	if err != nil {
+		return err
+	}
+	var mutants []mutant
+	for _, pkg := range pkgs {
+		exec, err := goTest(opts, "", pkg.ImportPath)
+		if err != nil {
+			return err
+		}
+		if len(exec.Errors()) > 0 || len(exec.Failed()) > 0 {
+			return fmt.Errorf("the tests of %v must pass before they can be mutated", pkg.ImportPath)
+		}
+		pkgMutants, err := packageMutants(pkg)
+		if err != nil {
+			return err
+		}
+		mutants = append(mutants, pkgMutants...)
+	}
+	log.Debugf("found %d mutants in %d packages", len(mutants), len(pkgs))
+
+	tmpDir, err := ioutil.TempDir("", "gotestsum-mutate")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir) // nolint: errcheck
+
+	results, err := testMutants(opts, tmpDir, mutants)
+	if err != nil {
+		return err
+	}
+	scores := scoresByPackage(results)
+	printReport(opts.stdout, scores)
+	if opts.report != "" {
+		if err := writeReport(opts.report, scores); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	for _, score := range scores {
+		if score.tested() > 0 && score.score() < opts.minScore {
+			return fmt.Errorf("mutation score of %v is %.2f, lower than --min-score %.2f",
+				score.pkg, score.score(), opts.minScore)
+		}
+	}
+	return nil
+}
+
+type goPackage struct {
+	ImportPath   string
+	Dir          string
+	GoFiles      []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+}
+
+func listPackages(patterns []string) ([]goPackage, error) {
+	args := append([]string{"list", "-json"}, patterns...)
+	log.Debugf("exec: go %v", args)
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	var pkgs []goPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var pkg goPackage
+		if err := dec.Decode(&pkg); err != nil {
+			return nil, fmt.Errorf("failed to decode go list output: %w", err)
+		}
+		if len(pkg.TestGoFiles)+len(pkg.XTestGoFiles) == 0 {
+			log.Debugf("skipping package %v without tests", pkg.ImportPath)
+			continue
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+func packageMutants(pkg goPackage) ([]mutant, error) {
+	var result []mutant
+	for _, name := range pkg.GoFiles {
+		filename := filepath.Join(pkg.Dir, name)
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		mutants, err := findMutants(pkg.ImportPath, filename, src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %w", filename, err)
+		}
+		result = append(result, mutants...)
+	}
+	return result, nil
+}
+
+// testMutants runs the tests of the package for each mutant, with up to
+// opts.parallel mutants at the same time. The results are in the same order
+// as mutants.
+func testMutants(opts *options, tmpDir string, mutants []mutant) ([]mutantResult, error) {
+	results := make([]mutantResult, len(mutants))
+	errs := make([]error, len(mutants))
+	sem := make(chan struct{}, opts.parallel)
+	var wg sync.WaitGroup
+	for i := range mutants {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			dir := filepath.Join(tmpDir, fmt.Sprintf("%d", i))
+			results[i], errs[i] = testMutant(opts, dir, mutants[i])
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func testMutant(opts *options, dir string, m mutant) (mutantResult, error) {
+	result := mutantResult{mutant: m}
+	overlay, err := writeOverlay(dir, m)
+	if err != nil {
+		return result, err
+	}
+
+	exec, err := goTest(opts, overlay, m.pkg)
+	if err != nil {
+		return result, err
+	}
+	switch {
+	case len(exec.Errors()) > 0:
+		result.status = statusNotViable
+	case len(exec.Failed()) > 0:
+		result.status = statusKilled
+	default:
+		result.status = statusSurvived
+	}
+	log.Debugf("mutant %v: %v", m, result.status)
+	return result, nil
+}
+
+// writeOverlay writes the mutated source file, and a go build -overlay file
+// that replaces the original file with the mutated one. It returns the path
+// to the overlay file.
+func writeOverlay(dir string, m mutant) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	src, err := ioutil.ReadFile(m.file)
+	if err != nil {
+		return "", err
+	}
+	mutated, err := m.apply(src)
+	if err != nil {
+		return "", err
+	}
+	source := filepath.Join(dir, filepath.Base(m.file))
+	if err := ioutil.WriteFile(source, mutated, 0o644); err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(map[string]map[string]string{
+		"Replace": {m.file: source},
+	})
+	if err != nil {
+		return "", err
+	}
+	overlay := filepath.Join(dir, "overlay.json")
+	return overlay, ioutil.WriteFile(overlay, raw, 0o644)
+}
+
+func goTest(opts *options, overlay string, pkg string) (*testjson.Execution, error) {
+	args := []string{"test", "-json", "-count=1", "-failfast"}
+	if overlay != "" {
+		args = append(args, "-overlay="+overlay)
+	}
+	args = append(args, opts.args...)
+	args = append(args, pkg)
+
+	log.Debugf("exec: go %v", args)
+	cmd := exec.Command("go", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run go test: %w", err)
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Handler: discardHandler{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// a non-zero exit code is expected when the mutant is killed.
+	_ = cmd.Wait()
+	return exec, nil
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Event(testjson.TestEvent, *testjson.Execution) error {
+	return nil
+}
+
+func (discardHandler) Err(string) error {
+	return nil
+}
+
+type packageScore struct {
+	pkg       string
+	killed    []mutant
+	survived  []mutant
+	notViable []mutant
+}
+
+func (s packageScore) tested() int {
+	return len(s.killed) + len(s.survived)
+}
+
+// score returns the fraction of viable mutants that were killed.
+func (s packageScore) score() float64 {
+	if s.tested() == 0 {
+		return 0
+	}
+	return float64(len(s.killed)) / float64(s.tested())
+}
+
+func scoresByPackage(results []mutantResult) []packageScore {
+	byPkg := make(map[string]*packageScore)
+	for _, result := range results {
+		score, ok := byPkg[result.mutant.pkg]
+		if !ok {
+			score = &packageScore{pkg: result.mutant.pkg}
+			byPkg[result.mutant.pkg] = score
+		}
+		switch result.status {
+		case statusKilled:
+			score.killed = append(score.killed, result.mutant)
+		case statusSurvived:
+			score.survived = append(score.survived, result.mutant)
+		case statusNotViable:
+			score.notViable = append(score.notViable, result.mutant)
+		}
+	}
+	scores := make([]packageScore, 0, len(byPkg))
+	for _, score := range byPkg {
+		scores = append(scores, *score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].pkg < scores[j].pkg
+	})
+	return scores
+}
+
+func printReport(out io.Writer, scores []packageScore) {
+	for _, score := range scores {
+		fmt.Fprintf(out, "%v: %.2f mutation score (%d killed, %d survived, %d not viable)\n",
+			testjson.RelativePackagePath(score.pkg), score.score(),
+			len(score.killed), len(score.survived), len(score.notViable))
+		for _, m := range score.survived {
+			fmt.Fprintf(out, "    survived: %v\n", m)
+		}
+	}
+}
+
+type reportMutant struct {
+	File        string
+	Line        int
+	Column      int
+	Description string
+	Status      status
+}
+
+type reportPackage struct {
+	Package  string
+	Score    float64
+	Killed   int
+	Survived int
+	Mutants  []reportMutant
+}
+
+func writeReport(path string, scores []packageScore) error {
+	report := make([]reportPackage, 0, len(scores))
+	for _, score := range scores {
+		pkg := reportPackage{
+			Package:  score.pkg,
+			Score:    score.score(),
+			Killed:   len(score.killed),
+			Survived: len(score.survived),
+		}
+		add := func(mutants []mutant, s status) {
+			for _, m := range mutants {
+				pkg.Mutants = append(pkg.Mutants, reportMutant{
+					File:        m.file,
+					Line:        m.line,
+					Column:      m.col,
+					Description: m.description(),
+					Status:      s,
+				})
+			}
+		}
+		add(score.survived, statusSurvived)
+		add(score.killed, statusKilled)
+		add(score.notViable, statusNotViable)
+		report = append(report, pkg)
+	}
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(raw, '\n'), 0o644)
+}
@@ -0,0 +1,59 @@
+package mutate
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestRun(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	out := new(bytes.Buffer)
+	opts := &options{
+		packages: []string{"./testdata/calc"},
+		parallel: 2,
+		report:   filepath.Join(dir.Path(), "report.json"),
+		stdout:   out,
+	}
+	assert.NilError(t, run(opts))
+
+	expected := `cmd/tool/mutate/testdata/calc: 0.50 mutation score (1 killed, 1 survived, 0 not viable)
+    survived: testdata/calc/calc.go:5:7: replaced > with >=
+`
+	assert.Equal(t, out.String(), expected)
+
+	raw, err := ioutil.ReadFile(opts.report)
+	assert.NilError(t, err)
+	var report []reportPackage
+	assert.NilError(t, json.Unmarshal(raw, &report))
+	assert.Equal(t, len(report), 1)
+	assert.Equal(t, report[0].Score, 0.5)
+	assert.Equal(t, len(report[0].Mutants), 2)
+	assert.Equal(t, report[0].Mutants[0].Status, statusSurvived)
+}
+
+func TestRun_MinScore(t *testing.T) {
+	opts := &options{
+		packages: []string{"./testdata/calc"},
+		parallel: 1,
+		minScore: 0.8,
+		stdout:   new(bytes.Buffer),
+	}
+	assert.ErrorContains(t, run(opts), "lower than --min-score 0.80")
+}
+
+func TestPackageScore(t *testing.T) {
+	score := packageScore{
+		killed:    make([]mutant, 3),
+		survived:  make([]mutant, 1),
+		notViable: make([]mutant, 2),
+	}
+	assert.Equal(t, score.tested(), 4)
+	assert.Equal(t, score.score(), 0.75)
+	assert.Equal(t, packageScore{}.score(), 0.0)
+}
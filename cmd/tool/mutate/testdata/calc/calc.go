@@ -0,0 +1,18 @@
+package calc
+
+// Max returns the larger of a and b.
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Sum returns the total of all the values.
+func Sum(values ...int) int {
+	total := 0
+	for _, v := range values {
+		total = total + v
+	}
+	return total
+}
@@ -0,0 +1,15 @@
+package calc
+
+import "testing"
+
+func TestMax(t *testing.T) {
+	if Max(1, 2) != 2 {
+		t.Fatal("expected 2")
+	}
+}
+
+func TestSum(t *testing.T) {
+	if Sum(1, 2, 3) != 6 {
+		t.Fatal("expected 6")
+	}
+}
@@ -0,0 +1,361 @@
+package parallel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	opts.stdout = os.Stdout
+	return run(opts)
+}
+
+type options struct {
+	jsonfile  string
+	threshold time.Duration
+	parallel  int
+	format    string
+	source    bool
+	debug     bool
+
+	// shims for testing
+	stdout     io.Writer
+	loadSource func(pkgs []string) (map[string]map[string]sourceInfo, error)
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{loadSource: loadSource}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.jsonfile, "jsonfile", os.Getenv("GOTESTSUM_JSONFILE"),
+		"path to test2json output, defaults to stdin")
+	flags.DurationVar(&opts.threshold, "threshold", 100*time.Millisecond,
+		"tests with an elapsed time greater than threshold are reported")
+	flags.IntVar(&opts.parallel, "parallel", runtime.GOMAXPROCS(0),
+		"the go test -parallel value used to estimate the time saved")
+	flags.StringVar(&opts.format, "format", "text",
+		"print the report as: text, json, or markdown")
+	flags.BoolVar(&opts.source, "source", false,
+		"read the source of the tests to find calls that prevent t.Parallel, like t.Setenv")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read a json file and report the slow top-level tests that do not call
+t.Parallel, with an estimate of the wall time that would be saved in each
+package if they did. The json file may be created with 'gotestsum --jsonfile'
+or 'go test -json'. A test is parallel when the json file has a pause event for
+the test. When a test ran more than once the median elapsed time is used.
+
+The estimate assumes the tests that are not parallel run one after the other,
+and the parallel tests then run --parallel at a time.
+
+With --source the source of the packages is read, and tests that call
+t.Setenv, t.Chdir, os.Setenv, os.Unsetenv, or os.Chdir are reported with the
+call that prevents them from running in parallel. These tests are not included
+in the estimate. Go build flags, like build tags, may be set using the GOFLAGS
+environment variable.
+
+    go test -json ./... > saved.json
+    %[1]s --jsonfile saved.json --threshold 500ms --format markdown
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	switch opts.format {
+	case "text", "json", "markdown":
+	default:
+		return fmt.Errorf("invalid --format %q, must be one of: text, json, markdown", opts.format)
+	}
+	if opts.parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+
+	in, err := jsonfile.Open(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer in.Close() // nolint: errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	if err != nil {
+		return fmt.Errorf("failed to scan jsonfile: %w", err)
+	}
+
+	var source map[string]map[string]sourceInfo
+	if opts.source {
+		source, err = opts.loadSource(exec.Packages())
+		if err != nil {
+			return err
+		}
+	}
+	rep := newReport(exec, source, opts)
+	switch opts.format {
+	case "json":
+		return writeJSON(opts.stdout, rep)
+	case "markdown":
+		writeMarkdown(opts.stdout, rep)
+	default:
+		writeText(opts.stdout, rep)
+	}
+	return nil
+}
+
+// report is the result of the command. Times are in seconds, to match the
+// JSON summary.
+type report struct {
+	Parallel int             `json:"parallel"`
+	Tests    []reportTest    `json:"tests"`
+	Packages []reportPackage `json:"packages"`
+	// Saved is the sum of the time saved in every package.
+	Saved float64 `json:"saved"`
+}
+
+type reportTest struct {
+	Package string  `json:"package"`
+	Test    string  `json:"test"`
+	Elapsed float64 `json:"elapsed"`
+	// Blockers are the calls in the test that prevent it from being parallel.
+	Blockers []string `json:"blockers,omitempty"`
+}
+
+type reportPackage struct {
+	Package string `json:"package"`
+	// Current is the estimated wall time of the tests in the package.
+	Current float64 `json:"current"`
+	// Estimated is the estimated wall time of the tests in the package when
+	// the reported tests, without blockers, are parallel.
+	Estimated float64 `json:"estimated"`
+	Saved     float64 `json:"saved"`
+}
+
+// topLevelTest is a top-level test, with the median of its elapsed times.
+type topLevelTest struct {
+	name     string
+	elapsed  time.Duration
+	parallel bool
+}
+
+func newReport(exec *testjson.Execution, source map[string]map[string]sourceInfo, opts *options) report {
+	rep := report{Parallel: opts.parallel, Tests: []reportTest{}, Packages: []reportPackage{}}
+	var saved time.Duration
+	for _, pkgname := range exec.Packages() {
+		tests := topLevelTests(exec.Package(pkgname))
+		var sequential, parallel, candidates []time.Duration
+		for _, tc := range tests {
+			if tc.parallel {
+				parallel = append(parallel, tc.elapsed)
+				continue
+			}
+			sequential = append(sequential, tc.elapsed)
+			if tc.elapsed < opts.threshold {
+				continue
+			}
+			info := source[pkgname][tc.name]
+			rep.Tests = append(rep.Tests, reportTest{
+				Package:  pkgname,
+				Test:     tc.name,
+				Elapsed:  tc.elapsed.Seconds(),
+				Blockers: info.blockers,
+			})
+			if len(info.blockers) == 0 {
+				candidates = append(candidates, tc.elapsed)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		current := wallTime(sequential, parallel, opts.parallel)
+		estimated := wallTime(
+			without(sequential, candidates),
+			append(append([]time.Duration{}, parallel...), candidates...),
+			opts.parallel)
+		// a test that is the only test in a package is not faster when it
+		// is parallel.
+		if estimated >= current {
+			continue
+		}
+		rep.Packages = append(rep.Packages, reportPackage{
+			Package:   pkgname,
+			Current:   current.Seconds(),
+			Estimated: estimated.Seconds(),
+			Saved:     (current - estimated).Seconds(),
+		})
+		saved += current - estimated
+	}
+	rep.Saved = saved.Seconds()
+
+	sort.SliceStable(rep.Tests, func(i, j int) bool {
+		return rep.Tests[i].Elapsed > rep.Tests[j].Elapsed
+	})
+	sort.SliceStable(rep.Packages, func(i, j int) bool {
+		return rep.Packages[i].Saved > rep.Packages[j].Saved
+	})
+	return rep
+}
+
+// topLevelTests returns the top-level tests in the package, sorted by name.
+// Example functions are not included, because they can not be parallel.
+func topLevelTests(pkg *testjson.Package) []topLevelTest {
+	times := make(map[string][]time.Duration)
+	parallel := make(map[string]bool)
+	for _, tc := range pkg.TestCases() {
+		if tc.Test.IsSubTest() || tc.Test.IsExample() {
+			continue
+		}
+		name := tc.Test.Name()
+		times[name] = append(times[name], tc.Elapsed)
+		parallel[name] = parallel[name] || tc.Parallel
+	}
+	result := make([]topLevelTest, 0, len(times))
+	for name, elapsed := range times {
+		result = append(result, topLevelTest{name: name, elapsed: median(elapsed), parallel: parallel[name]})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+func median(times []time.Duration) time.Duration {
+	sort.Slice(times, func(i, j int) bool {
+		return times[i] < times[j]
+	})
+	return times[len(times)/2]
+}
+
+// wallTime returns the estimated wall time of a package. The tests that are
+// not parallel run one after the other, and then the parallel tests run n at
+// a time. The parallel tests take at least as long as the slowest parallel
+// test.
+func wallTime(sequential, parallel []time.Duration, n int) time.Duration {
+	var seq, par, slowest time.Duration
+	for _, d := range sequential {
+		seq += d
+	}
+	for _, d := range parallel {
+		par += d
+		if d > slowest {
+			slowest = d
+		}
+	}
+	par /= time.Duration(n)
+	if slowest > par {
+		par = slowest
+	}
+	return seq + par
+}
+
+// without returns the times in all that are not in remove.
+func without(all, remove []time.Duration) []time.Duration {
+	counts := make(map[time.Duration]int)
+	for _, d := range remove {
+		counts[d]++
+	}
+	var result []time.Duration
+	for _, d := range all {
+		if counts[d] > 0 {
+			counts[d]--
+			continue
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+func writeJSON(out io.Writer, rep report) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+func writeText(out io.Writer, rep report) {
+	if len(rep.Tests) == 0 {
+		fmt.Fprintln(out, "No slow tests that are not parallel")
+		return
+	}
+	for _, tc := range rep.Tests {
+		fmt.Fprintf(out, "%s %s %v%s\n", testjson.RelativePackagePath(tc.Package), tc.Test,
+			seconds(tc.Elapsed), formatBlockers(tc.Blockers, " (", ")"))
+	}
+	fmt.Fprintln(out)
+	for _, pkg := range rep.Packages {
+		fmt.Fprintf(out, "%s: %v to %v, saves %v\n", testjson.RelativePackagePath(pkg.Package),
+			seconds(pkg.Current), seconds(pkg.Estimated), seconds(pkg.Saved))
+	}
+	fmt.Fprintf(out, "Estimated time saved with -parallel %d: %v\n", rep.Parallel, seconds(rep.Saved))
+}
+
+func writeMarkdown(out io.Writer, rep report) {
+	fmt.Fprintln(out, "## Slow tests that are not parallel")
+	fmt.Fprintln(out)
+	if len(rep.Tests) == 0 {
+		fmt.Fprintln(out, "None")
+		return
+	}
+	fmt.Fprintln(out, "| Package | Test | Elapsed | Blocked by |")
+	fmt.Fprintln(out, "|---|---|---:|---|")
+	for _, tc := range rep.Tests {
+		fmt.Fprintf(out, "| %s | %s | %v | %s |\n", testjson.RelativePackagePath(tc.Package),
+			tc.Test, seconds(tc.Elapsed), formatBlockers(tc.Blockers, "", ""))
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Estimated time saved with `-parallel %d`: **%v**\n", rep.Parallel, seconds(rep.Saved))
+	if len(rep.Packages) == 0 {
+		return
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "| Package | Current | Estimated | Saved |")
+	fmt.Fprintln(out, "|---|---:|---:|---:|")
+	for _, pkg := range rep.Packages {
+		fmt.Fprintf(out, "| %s | %v | %v | %v |\n", testjson.RelativePackagePath(pkg.Package),
+			seconds(pkg.Current), seconds(pkg.Estimated), seconds(pkg.Saved))
+	}
+}
+
+func formatBlockers(blockers []string, prefix, suffix string) string {
+	if len(blockers) == 0 {
+		return ""
+	}
+	return prefix + "calls " + strings.Join(blockers, ", ") + suffix
+}
+
+// seconds returns a duration from seconds, rounded to milliseconds.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
@@ -0,0 +1,135 @@
+package parallel
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"testing"
+	"time"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const input = `{"Action":"run","Package":"example.com/store","Test":"TestGet"}
+{"Action":"pass","Package":"example.com/store","Test":"TestGet","Elapsed":2}
+{"Action":"run","Package":"example.com/store","Test":"TestPut"}
+{"Action":"pass","Package":"example.com/store","Test":"TestPut","Elapsed":1}
+{"Action":"run","Package":"example.com/store","Test":"TestFast"}
+{"Action":"pass","Package":"example.com/store","Test":"TestFast","Elapsed":0.01}
+{"Action":"run","Package":"example.com/store","Test":"TestList"}
+{"Action":"pause","Package":"example.com/store","Test":"TestList"}
+{"Action":"cont","Package":"example.com/store","Test":"TestList"}
+{"Action":"pass","Package":"example.com/store","Test":"TestList","Elapsed":1}
+{"Action":"run","Package":"example.com/store","Test":"ExampleGet"}
+{"Action":"pass","Package":"example.com/store","Test":"ExampleGet","Elapsed":3}
+{"Action":"pass","Package":"example.com/store","Elapsed":4.1}
+{"Action":"run","Package":"example.com/api","Test":"TestLogin"}
+{"Action":"pass","Package":"example.com/api","Test":"TestLogin","Elapsed":0.5}
+{"Action":"pass","Package":"example.com/api","Elapsed":0.6}
+`
+
+func TestRun_Text(t *testing.T) {
+	dir := fs.NewDir(t, "parallel", fs.WithFile("saved.json", input))
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile:  dir.Join("saved.json"),
+		threshold: 100 * time.Millisecond,
+		parallel:  4,
+		format:    "text",
+		stdout:    out,
+	}
+	assert.NilError(t, run(opts))
+	expected := `example.com/store TestGet 2s
+example.com/store TestPut 1s
+example.com/api TestLogin 500ms
+
+example.com/store: 4.01s to 2.01s, saves 2s
+Estimated time saved with -parallel 4: 2s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRun_SourceBlockers(t *testing.T) {
+	dir := fs.NewDir(t, "parallel", fs.WithFile("saved.json", input))
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile:  dir.Join("saved.json"),
+		threshold: 100 * time.Millisecond,
+		parallel:  4,
+		format:    "json",
+		source:    true,
+		stdout:    out,
+		loadSource: func(pkgs []string) (map[string]map[string]sourceInfo, error) {
+			return map[string]map[string]sourceInfo{
+				"example.com/store": {"TestPut": {blockers: []string{"t.Setenv"}}},
+			}, nil
+		},
+	}
+	assert.NilError(t, run(opts))
+
+	var rep report
+	assert.NilError(t, json.Unmarshal(out.Bytes(), &rep))
+	assert.DeepEqual(t, rep.Tests[1], reportTest{
+		Package:  "example.com/store",
+		Test:     "TestPut",
+		Elapsed:  1,
+		Blockers: []string{"t.Setenv"},
+	})
+	assert.DeepEqual(t, rep.Packages, []reportPackage{
+		{Package: "example.com/store", Current: 4.01, Estimated: 3.01, Saved: 1},
+	}, cmpSeconds)
+}
+
+var cmpSeconds = gocmp.Comparer(func(x, y float64) bool {
+	return seconds(x) == seconds(y)
+})
+
+func TestRun_InvalidFormat(t *testing.T) {
+	err := run(&options{format: "html", parallel: 1})
+	assert.ErrorContains(t, err, `invalid --format "html"`)
+}
+
+func TestWallTime(t *testing.T) {
+	s := time.Second
+	assert.Equal(t, wallTime([]time.Duration{2 * s, s}, nil, 4), 3*s)
+	assert.Equal(t, wallTime(nil, []time.Duration{2 * s, s, s}, 4), 2*s)
+	assert.Equal(t, wallTime([]time.Duration{s}, []time.Duration{s, s, s, s, s, s, s, s}, 4), 3*s)
+}
+
+func TestFileSourceInfo(t *testing.T) {
+	src := `package store
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	t.Setenv("HOME", "/tmp")
+}
+
+func TestSubtestChdir(t *testing.T) {
+	t.Run("sub", func(t *testing.T) {
+		os.Chdir("/tmp")
+	})
+}
+
+func TestFine(t *testing.T) {
+	os.Getenv("HOME")
+}
+
+func helper(t *testing.T) {
+	t.Setenv("A", "b")
+}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "store_test.go", src, 0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, fileSourceInfo(file), map[string]sourceInfo{
+		"TestEnv":          {blockers: []string{"t.Setenv"}},
+		"TestSubtestChdir": {blockers: []string{"os.Chdir"}},
+		"TestFine":         {},
+	}, gocmp.AllowUnexported(sourceInfo{}))
+}
@@ -0,0 +1,127 @@
+package parallel
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// sourceInfo is what the source of a test function shows about whether the
+// test can be parallel.
+type sourceInfo struct {
+	// blockers are the calls that prevent the test from calling t.Parallel,
+	// like t.Setenv, sorted by name.
+	blockers []string
+}
+
+// parallelBlockers are the calls that panic in a parallel test, or change the
+// state of the process for every test.
+var parallelBlockers = map[string]bool{
+	"os.Setenv":   true,
+	"os.Unsetenv": true,
+	"os.Chdir":    true,
+}
+
+// testParallelBlockers are the methods of testing.T that can not be used in a
+// parallel test.
+var testParallelBlockers = map[string]bool{
+	"Setenv": true,
+	"Chdir":  true,
+}
+
+// loadSource reads the test files of the packages, and returns the sourceInfo
+// of each test function, by package and test name.
+func loadSource(pkgNames []string) (map[string]map[string]sourceInfo, error) {
+	cfg := packages.Config{
+		Mode:       packages.NeedName | packages.NeedFiles | packages.NeedSyntax,
+		Tests:      true,
+		BuildFlags: buildFlags(),
+	}
+	pkgs, err := packages.Load(&cfg, pkgNames...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+	result := make(map[string]map[string]sourceInfo)
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("failed to load package %v: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+		// test2json uses the name of the package without the _test suffix for
+		// the tests in an external test package.
+		name := strings.TrimSuffix(pkg.PkgPath, "_test")
+		for _, file := range pkg.Syntax {
+			for test, info := range fileSourceInfo(file) {
+				if result[name] == nil {
+					result[name] = make(map[string]sourceInfo)
+				}
+				result[name][test] = info
+			}
+		}
+		log.Debugf("read the source of %d tests in %v", len(result[name]), name)
+	}
+	return result, nil
+}
+
+// fileSourceInfo returns the sourceInfo of each test function in the file.
+func fileSourceInfo(file *ast.File) map[string]sourceInfo {
+	result := make(map[string]sourceInfo)
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil || fd.Recv != nil || !strings.HasPrefix(fd.Name.Name, "Test") {
+			continue
+		}
+		result[fd.Name.Name] = sourceInfo{blockers: findBlockers(fd)}
+	}
+	return result
+}
+
+// findBlockers returns the calls in the function that prevent it from being
+// parallel. Calls in subtests are included, because a subtest that uses
+// t.Setenv prevents the parent test from being parallel.
+func findBlockers(fd *ast.FuncDecl) []string {
+	found := make(map[string]bool)
+	ast.Inspect(fd.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		name := ident.Name + "." + sel.Sel.Name
+		switch {
+		case parallelBlockers[name]:
+			found[name] = true
+		case ident.Name != "os" && testParallelBlockers[sel.Sel.Name] && len(call.Args) > 0:
+			found["t."+sel.Sel.Name] = true
+		}
+		return true
+	})
+	if len(found) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(found))
+	for name := range found {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func buildFlags() []string {
+	flags := os.Getenv("GOFLAGS")
+	if len(flags) == 0 {
+		return nil
+	}
+	return strings.Split(flags, " ")
+}
@@ -0,0 +1,139 @@
+package repro
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/repro"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	if flags.NArg() < 2 {
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("a jsonfile and a test name are required")
+	}
+	opts.jsonfile = flags.Arg(0)
+	opts.test = flags.Arg(1)
+	opts.args = flags.Args()[2:]
+	if len(opts.args) > 0 && opts.args[0] == "--" {
+		opts.args = opts.args[1:]
+	}
+	opts.stdout = os.Stdout
+	return run(opts)
+}
+
+type options struct {
+	jsonfile string
+	test     string
+	pkg      string
+	debug    bool
+	args     []string
+
+	// shims for testing
+	stdout io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.pkg, "package", "",
+		"only print the command for the test in this package")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] JSONFILE TESTNAME [-- go test flags]
+
+Print a 'go test' command that runs TESTNAME again, with the same -shuffle
+seed, to reproduce a failure. JSONFILE is a file of test2json events, like the
+file written by 'gotestsum --jsonfile'. A command is printed for each package
+where the test failed, or where it ran if it did not fail.
+
+Any args after -- are added to the 'go test' command.
+
+    %[1]s testjson.log TestDelete/missing_key -- -tags=integration
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	fh, err := os.Open(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer fh.Close() // nolint: errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: fh})
+	if err != nil {
+		return fmt.Errorf("failed to scan jsonfile: %w", err)
+	}
+	cmds := commands(exec, opts)
+	if len(cmds) == 0 {
+		return fmt.Errorf("test %v was not found in %v", opts.test, opts.jsonfile)
+	}
+	for _, cmd := range cmds {
+		fmt.Fprintln(opts.stdout, repro.ShellQuote(cmd))
+	}
+	return nil
+}
+
+// commands returns a go test command for each package where the test failed.
+// If the test did not fail in any package, a command is returned for each
+// package where the test ran.
+func commands(exec *testjson.Execution, opts *options) [][]string {
+	byPackage := func(match func(pkg *testjson.Package) bool) [][]string {
+		var result [][]string
+		for _, name := range exec.Packages() {
+			if opts.pkg != "" && name != opts.pkg {
+				continue
+			}
+			pkg := exec.Package(name)
+			if match(pkg) {
+				cmd := repro.Command(name, testjson.TestName(opts.test), pkg.ShuffleSeed(), opts.args)
+				result = append(result, cmd)
+			}
+		}
+		return result
+	}
+
+	failed := byPackage(func(pkg *testjson.Package) bool {
+		return pkg.LastFailedByName(opts.test).ID != 0
+	})
+	if len(failed) > 0 {
+		return failed
+	}
+	return byPackage(func(pkg *testjson.Package) bool {
+		for _, tc := range pkg.TestCases() {
+			if tc.Test.Name() == opts.test {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,45 @@
+package repro
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRun(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile: "../../../testjson/testdata/input/go-test-json-with-shuffle.out",
+		test:     "TestFailed",
+		args:     []string{"-tags=integration"},
+		stdout:   out,
+	}
+	assert.NilError(t, run(opts))
+	expected := "go test -tags=integration -count=1 -run='^TestFailed$' -shuffle=123456 " +
+		"gotest.tools/gotestsum/testjson/internal/withfails\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRun_TestDidNotFail(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile: "../../../testjson/testdata/input/go-test-json-with-shuffle.out",
+		test:     "TestPassed",
+		pkg:      "gotest.tools/gotestsum/testjson/internal/good",
+		stdout:   out,
+	}
+	assert.NilError(t, run(opts))
+	expected := "go test -count=1 -run='^TestPassed$' -shuffle=123456 " +
+		"gotest.tools/gotestsum/testjson/internal/good\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRun_TestNotFound(t *testing.T) {
+	opts := &options{
+		jsonfile: "../../../testjson/testdata/input/go-test-json-with-shuffle.out",
+		test:     "TestDoesNotExist",
+		stdout:   new(bytes.Buffer),
+	}
+	assert.ErrorContains(t, run(opts), "test TestDoesNotExist was not found")
+}
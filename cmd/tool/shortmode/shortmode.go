@@ -0,0 +1,300 @@
+package shortmode
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	opts.args = flags.Args()
+	opts.stdout = os.Stdout
+	return run(opts)
+}
+
+type options struct {
+	shortJSONFile   string
+	fullJSONFile    string
+	minShortPercent float64
+	debug           bool
+	args            []string
+
+	// shims for testing
+	stdout io.Writer
+	goTest func(args []string) (*testjson.Execution, error)
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{goTest: goTest}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.shortJSONFile, "short-jsonfile", "",
+		"test2json output of a run with -short. When not set the tests are run with -short")
+	flags.StringVar(&opts.fullJSONFile, "full-jsonfile", "",
+		"test2json output of a run without -short. When not set the tests are run without -short")
+	flags.Float64Var(&opts.minShortPercent, "min-short-percent", 0,
+		"fail when -short runs less than this percent of the tests that run without -short")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [--] [go test flags]
+
+Compare a run of the tests with -short to a run of the tests without -short,
+and report the tests that only run in one of the modes. A test runs when it
+passes or fails, and does not run when it is skipped, or is missing from the
+run.
+
+The runs are read from --short-jsonfile and --full-jsonfile, which may be
+created with 'gotestsum --jsonfile' or 'go test -json'. When a file is not set
+the tests are run with 'go test', using any args after the flags. The default
+packages are ./...
+
+Tests that only run without -short are expected, for example slow integration
+tests. Tests that only run with -short are usually a mistake. Use
+--min-short-percent to fail when too few tests run with -short.
+
+    %[1]s -- -tags=integration ./...
+    %[1]s --short-jsonfile short.json --full-jsonfile full.json --min-short-percent 80
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	short, err := loadRun(opts, opts.shortJSONFile, true)
+	if err != nil {
+		return err
+	}
+	full, err := loadRun(opts, opts.fullJSONFile, false)
+	if err != nil {
+		return err
+	}
+
+	result := compare(short, full)
+	printResult(opts.stdout, result)
+	if opts.minShortPercent > 0 && result.shortPercent() < opts.minShortPercent {
+		return fmt.Errorf("-short runs %.1f%% of the tests, less than the minimum of %.1f%%",
+			result.shortPercent(), opts.minShortPercent)
+	}
+	return nil
+}
+
+// loadRun reads the jsonfile, or runs the tests when jsonfile is empty.
+func loadRun(opts *options, jsonfile string, short bool) (*testjson.Execution, error) {
+	if jsonfile != "" {
+		fh, err := os.Open(jsonfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jsonfile: %w", err)
+		}
+		defer fh.Close() // nolint: errcheck
+		return testjson.ScanTestOutput(testjson.ScanConfig{Stdout: fh})
+	}
+	args := []string{"test", "-json", "-count=1"}
+	if short {
+		args = append(args, "-short")
+	}
+	args = append(args, opts.args...)
+	if !hasPackageArg(opts.args) {
+		args = append(args, "./...")
+	}
+	return opts.goTest(args)
+}
+
+// hasPackageArg returns true when args include a package. Flags with a value
+// must use the -flag=value form.
+func hasPackageArg(args []string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return true
+		}
+	}
+	return false
+}
+
+func goTest(args []string) (*testjson.Execution, error) {
+	log.Debugf("exec: go %v", args)
+	cmd := exec.Command("go", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = ioutil.Discard
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run go test: %w", err)
+	}
+	execution, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: stdout})
+	if err != nil {
+		return nil, err
+	}
+	// a non-zero exit code is expected when a test fails.
+	_ = cmd.Wait()
+	if len(execution.Errors()) > 0 {
+		return nil, fmt.Errorf("go test failed: %v", strings.Join(execution.Errors(), "\n"))
+	}
+	return execution, nil
+}
+
+// testState is the state of a test in a run.
+type testState string
+
+const (
+	stateRan     testState = "ran"
+	stateSkipped testState = "skipped"
+	stateMissing testState = "not run"
+)
+
+type test struct {
+	pkg  string
+	name string
+	// state is the state of the test in the mode where it did not run.
+	state testState
+}
+
+type result struct {
+	// onlyFull are the tests that only run without -short.
+	onlyFull []test
+	// onlyShort are the tests that only run with -short.
+	onlyShort []test
+	// ranShort is the number of tests that ran with -short, of the ranFull
+	// tests that ran without -short.
+	ranShort int
+	ranFull  int
+}
+
+// shortPercent returns the percent of the tests that ran without -short that
+// also ran with -short.
+func (r result) shortPercent() float64 {
+	if r.ranFull == 0 {
+		return 100
+	}
+	return float64(r.ranShort) * 100 / float64(r.ranFull)
+}
+
+type testKey struct {
+	pkg  string
+	name string
+}
+
+// testStates returns the state of every test in the run. A test that ran
+// more than once is ran if any of its runs passed or failed.
+func testStates(execution *testjson.Execution) map[testKey]testState {
+	states := make(map[testKey]testState)
+	for _, pkgname := range execution.Packages() {
+		pkg := execution.Package(pkgname)
+		for _, tc := range pkg.Skipped {
+			key := testKey{pkg: pkgname, name: tc.Test.Name()}
+			if _, ok := states[key]; !ok {
+				states[key] = stateSkipped
+			}
+		}
+		for _, tcs := range [][]testjson.TestCase{pkg.Passed, pkg.Failed} {
+			for _, tc := range tcs {
+				states[testKey{pkg: pkgname, name: tc.Test.Name()}] = stateRan
+			}
+		}
+	}
+	return states
+}
+
+func compare(short, full *testjson.Execution) result {
+	shortStates := testStates(short)
+	fullStates := testStates(full)
+	state := func(states map[testKey]testState, key testKey) testState {
+		if s, ok := states[key]; ok {
+			return s
+		}
+		return stateMissing
+	}
+
+	var res result
+	for key, s := range fullStates {
+		if s != stateRan {
+			continue
+		}
+		res.ranFull++
+		if other := state(shortStates, key); other != stateRan {
+			res.onlyFull = append(res.onlyFull, test{pkg: key.pkg, name: key.name, state: other})
+			continue
+		}
+		res.ranShort++
+	}
+	for key, s := range shortStates {
+		if s != stateRan {
+			continue
+		}
+		if other := state(fullStates, key); other != stateRan {
+			res.onlyShort = append(res.onlyShort, test{pkg: key.pkg, name: key.name, state: other})
+		}
+	}
+	res.onlyFull = withoutSubtestsOfListed(res.onlyFull)
+	res.onlyShort = withoutSubtestsOfListed(res.onlyShort)
+	return res
+}
+
+// withoutSubtestsOfListed sorts tests by package and name, and removes the
+// subtests of tests that are in the list, because the subtests of a test that
+// did not run also did not run.
+func withoutSubtestsOfListed(tests []test) []test {
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].pkg != tests[j].pkg {
+			return tests[i].pkg < tests[j].pkg
+		}
+		return tests[i].name < tests[j].name
+	})
+	listed := make(map[testKey]bool)
+	result := tests[:0]
+	for _, t := range tests {
+		if i := strings.LastIndex(t.name, "/"); i > 0 && listed[testKey{pkg: t.pkg, name: t.name[:i]}] {
+			listed[testKey{pkg: t.pkg, name: t.name}] = true
+			continue
+		}
+		listed[testKey{pkg: t.pkg, name: t.name}] = true
+		result = append(result, t)
+	}
+	return result
+}
+
+func printResult(out io.Writer, res result) {
+	printTests := func(title string, tests []test) {
+		if len(tests) == 0 {
+			return
+		}
+		fmt.Fprintf(out, "%s (%d):\n", title, len(tests))
+		for _, t := range tests {
+			fmt.Fprintf(out, "  %s.%s (%s)\n", testjson.RelativePackagePath(t.pkg), t.name, t.state)
+		}
+		fmt.Fprintln(out)
+	}
+	printTests("Tests that only run without -short", res.onlyFull)
+	printTests("Tests that only run with -short", res.onlyShort)
+	fmt.Fprintf(out, "-short runs %d of %d tests (%.1f%%)\n", res.ranShort, res.ranFull, res.shortPercent())
+}
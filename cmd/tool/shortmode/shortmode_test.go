@@ -0,0 +1,90 @@
+package shortmode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const shortRun = `{"Action":"run","Package":"example.com/store","Test":"TestGet"}
+{"Action":"pass","Package":"example.com/store","Test":"TestGet"}
+{"Action":"run","Package":"example.com/store","Test":"TestIntegration"}
+{"Action":"output","Package":"example.com/store","Test":"TestIntegration","Output":"skipping in short mode\n"}
+{"Action":"skip","Package":"example.com/store","Test":"TestIntegration"}
+{"Action":"run","Package":"example.com/store","Test":"TestFast"}
+{"Action":"pass","Package":"example.com/store","Test":"TestFast"}
+{"Action":"pass","Package":"example.com/store"}
+`
+
+const fullRun = `{"Action":"run","Package":"example.com/store","Test":"TestGet"}
+{"Action":"pass","Package":"example.com/store","Test":"TestGet"}
+{"Action":"run","Package":"example.com/store","Test":"TestIntegration"}
+{"Action":"run","Package":"example.com/store","Test":"TestIntegration/put"}
+{"Action":"pass","Package":"example.com/store","Test":"TestIntegration/put"}
+{"Action":"pass","Package":"example.com/store","Test":"TestIntegration"}
+{"Action":"run","Package":"example.com/store","Test":"TestFast"}
+{"Action":"skip","Package":"example.com/store","Test":"TestFast"}
+{"Action":"run","Package":"example.com/store","Test":"TestSlow"}
+{"Action":"pass","Package":"example.com/store","Test":"TestSlow"}
+{"Action":"pass","Package":"example.com/store"}
+`
+
+func TestRun_FromJSONFiles(t *testing.T) {
+	dir := fs.NewDir(t, "shortmode",
+		fs.WithFile("short.json", shortRun),
+		fs.WithFile("full.json", fullRun))
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		shortJSONFile: dir.Join("short.json"),
+		fullJSONFile:  dir.Join("full.json"),
+		stdout:        out,
+	}
+	assert.NilError(t, run(opts))
+	expected := `Tests that only run without -short (2):
+  example.com/store.TestIntegration (skipped)
+  example.com/store.TestSlow (not run)
+
+Tests that only run with -short (1):
+  example.com/store.TestFast (skipped)
+
+-short runs 1 of 4 tests (25.0%)
+`
+	assert.Equal(t, out.String(), expected)
+
+	out.Reset()
+	opts.minShortPercent = 50
+	assert.ErrorContains(t, run(opts), "-short runs 25.0% of the tests, less than the minimum of 50.0%")
+}
+
+func TestRun_GoTest(t *testing.T) {
+	var calls [][]string
+	out := new(bytes.Buffer)
+	opts := &options{
+		args:   []string{"-tags=integration"},
+		stdout: out,
+		goTest: func(args []string) (*testjson.Execution, error) {
+			calls = append(calls, args)
+			input := fullRun
+			if strings.Contains(strings.Join(args, " "), "-short") {
+				input = shortRun
+			}
+			return testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+		},
+	}
+	assert.NilError(t, run(opts))
+	assert.DeepEqual(t, calls, [][]string{
+		{"test", "-json", "-count=1", "-short", "-tags=integration", "./..."},
+		{"test", "-json", "-count=1", "-tags=integration", "./..."},
+	})
+	assert.Assert(t, strings.Contains(out.String(), "-short runs 1 of 4 tests"), out.String())
+}
+
+func TestHasPackageArg(t *testing.T) {
+	assert.Assert(t, !hasPackageArg([]string{"-tags=integration", "-race"}))
+	assert.Assert(t, hasPackageArg([]string{"-race", "./store/..."}))
+}
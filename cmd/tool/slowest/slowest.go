@@ -3,12 +3,13 @@ package slowest
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/dnephin/pflag"
 	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/jsonfile"
 	"gotest.tools/gotestsum/internal/log"
 	"gotest.tools/gotestsum/testjson"
 )
@@ -54,7 +55,10 @@ If a TestCase appears more than once in the json file, it will only appear once
 in the output, and the median value of all the elapsed times will be used.
 
 By default this command will print the list of tests slower than threshold to stdout.
-The list will be sorted from slowest to fastest.
+The list will be sorted from slowest to fastest. The time spent in TestMain
+before the first test and after the last test of a package is printed as the
+(setup) and (teardown) of the package, when it is slower than threshold. These
+times are only available from test2json output created by go1.20 or later.
 
 If --skip-stmt is set, instead of printing the list to stdout, the AST for the
 Go source code in the working directory tree will be modified. The value of
@@ -103,7 +107,7 @@ func run(opts *options) error {
 	if opts.debug {
 		log.SetLevel(log.DebugLevel)
 	}
-	in, err := jsonfileReader(opts.jsonfile)
+	in, err := jsonfile.Open(opts.jsonfile)
 	if err != nil {
 		return fmt.Errorf("failed to read jsonfile: %v", err)
 	}
@@ -126,18 +130,13 @@ func run(opts *options) error {
 		}
 		return writeTestSkip(tcs, skipStmt)
 	}
+	tcs = append(tcs, aggregate.SetupTeardown(exec, opts.threshold)...)
+	sort.SliceStable(tcs, func(i, j int) bool {
+		return tcs[i].Elapsed > tcs[j].Elapsed
+	})
 	for _, tc := range tcs {
 		fmt.Printf("%s %s %v\n", tc.Package, tc.Test, tc.Elapsed)
 	}
 
 	return nil
 }
-
-func jsonfileReader(v string) (io.ReadCloser, error) {
-	switch v {
-	case "", "-":
-		return ioutil.NopCloser(os.Stdin), nil
-	default:
-		return os.Open(v)
-	}
-}
@@ -0,0 +1,257 @@
+package timeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		opts.jsonfile = flags.Arg(0)
+	default:
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("too many arguments, expected one jsonfile")
+	}
+	return run(opts)
+}
+
+type options struct {
+	jsonfile string
+	output   string
+	debug    bool
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVarP(&opts.output, "output", "o", "-",
+		"write the trace to this file, defaults to stdout")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [JSONFILE]
+
+Write a trace of the test run in the Chrome trace event format, which can be
+opened with chrome://tracing or https://ui.perfetto.dev. Each package is a
+process in the trace, and each test is a slice on a track of the package.
+Tests that ran at the same time are placed on separate tracks.
+
+JSONFILE is a file of test2json events, like the file written by
+'gotestsum --jsonfile', and defaults to stdin. The test2json events must
+include the time of each event.
+
+    %[1]s saved.json --output trace.json
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	in, err := jsonfile.Open(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer in.Close() // nolint: errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	if err != nil {
+		return fmt.Errorf("failed to scan jsonfile: %w", err)
+	}
+	tr := newTrace(exec)
+	if len(tr.TraceEvents) == 0 {
+		return fmt.Errorf("no tests with event times were found in the jsonfile")
+	}
+
+	out, closer, err := outputWriter(opts.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	enc := json.NewEncoder(out)
+	if err := enc.Encode(tr); err != nil {
+		_ = closer()
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return closer()
+}
+
+func outputWriter(v string) (io.Writer, func() error, error) {
+	if v == "" || v == "-" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	fh, err := os.Create(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fh, fh.Close, nil
+}
+
+// trace is a document in the Chrome trace event format.
+// See https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type trace struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+type traceEvent struct {
+	Name     string `json:"name"`
+	Category string `json:"cat,omitempty"`
+	Phase    string `json:"ph"`
+	// Timestamp and Duration are in microseconds.
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur,omitempty"`
+	PID       int                    `json:"pid"`
+	TID       int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+type slice struct {
+	tc     testjson.TestCase
+	result testjson.Action
+	start  time.Time
+	end    time.Time
+}
+
+func newTrace(exec *testjson.Execution) trace {
+	tr := trace{TraceEvents: []traceEvent{}, DisplayTimeUnit: "ms"}
+	var origin time.Time
+	slicesByPkg := make(map[string][]slice)
+	for _, name := range exec.Packages() {
+		slices := packageSlices(exec.Package(name))
+		if len(slices) == 0 {
+			continue
+		}
+		slicesByPkg[name] = slices
+		if origin.IsZero() || slices[0].start.Before(origin) {
+			origin = slices[0].start
+		}
+	}
+
+	pid := 0
+	for _, name := range exec.Packages() {
+		slices, ok := slicesByPkg[name]
+		if !ok {
+			continue
+		}
+		pid++
+		tr.TraceEvents = append(tr.TraceEvents,
+			traceEvent{
+				Name:  "process_name",
+				Phase: "M",
+				PID:   pid,
+				Args:  map[string]interface{}{"name": name},
+			},
+			traceEvent{
+				Name:  "process_sort_index",
+				Phase: "M",
+				PID:   pid,
+				Args:  map[string]interface{}{"sort_index": pid},
+			})
+
+		for i, track := range assignTracks(slices) {
+			s := slices[i]
+			tr.TraceEvents = append(tr.TraceEvents, traceEvent{
+				Name:      s.tc.Test.Name(),
+				Category:  string(s.result),
+				Phase:     "X",
+				Timestamp: s.start.Sub(origin).Microseconds(),
+				Duration:  s.end.Sub(s.start).Microseconds(),
+				PID:       pid,
+				TID:       track,
+				Args: map[string]interface{}{
+					"package": name,
+					"result":  string(s.result),
+					"elapsed": s.tc.Elapsed.String(),
+				},
+			})
+		}
+	}
+	return tr
+}
+
+// packageSlices returns a slice for each test in the package, sorted by start
+// time. When two tests start at the same time the longer test is first, so
+// that a subtest is after its parent.
+func packageSlices(pkg *testjson.Package) []slice {
+	var slices []slice
+	add := func(tcs []testjson.TestCase, result testjson.Action) {
+		for _, tc := range tcs {
+			start, end := tc.Span()
+			if start.IsZero() {
+				continue
+			}
+			slices = append(slices, slice{tc: tc, result: result, start: start, end: end})
+		}
+	}
+	add(pkg.Passed, testjson.ActionPass)
+	add(pkg.Failed, testjson.ActionFail)
+	add(pkg.Skipped, testjson.ActionSkip)
+	sort.SliceStable(slices, func(i, j int) bool {
+		if slices[i].start.Equal(slices[j].start) {
+			return slices[i].end.After(slices[j].end)
+		}
+		return slices[i].start.Before(slices[j].start)
+	})
+	return slices
+}
+
+// assignTracks returns the track for each slice. Slices on the same track must
+// either not overlap, or be nested inside another slice, because the trace
+// viewer shows a track as a stack of slices. A slice is placed on the first
+// track where it fits.
+func assignTracks(slices []slice) []int {
+	var stacks [][]time.Time
+	result := make([]int, len(slices))
+	for i, s := range slices {
+		placed := false
+		for track := range stacks {
+			stack := stacks[track]
+			for len(stack) > 0 && !stack[len(stack)-1].After(s.start) {
+				stack = stack[:len(stack)-1]
+			}
+			stacks[track] = stack
+			if len(stack) == 0 || !s.end.After(stack[len(stack)-1]) {
+				stacks[track] = append(stack, s.end)
+				result[i] = track + 1
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			stacks = append(stacks, []time.Time{s.end})
+			result[i] = len(stacks)
+		}
+	}
+	return result
+}
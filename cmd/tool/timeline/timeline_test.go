@@ -0,0 +1,59 @@
+package timeline
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+const input = `{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"example.com/a","Test":"TestOne/sub"}
+{"Time":"2023-01-01T00:00:00.5Z","Action":"pass","Package":"example.com/a","Test":"TestOne/sub","Elapsed":0.5}
+{"Time":"2023-01-01T00:00:00.1Z","Action":"run","Package":"example.com/a","Test":"TestTwo"}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"example.com/a","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:01.5Z","Action":"fail","Package":"example.com/a","Test":"TestTwo","Elapsed":1.4}
+{"Time":"2023-01-01T00:00:02Z","Action":"run","Package":"example.com/a","Test":"TestThree"}
+{"Time":"2023-01-01T00:00:02Z","Action":"skip","Package":"example.com/a","Test":"TestThree","Elapsed":0}
+{"Time":"2023-01-01T00:00:02Z","Action":"fail","Package":"example.com/a","Elapsed":2}
+{"Time":"2023-01-01T00:00:01Z","Action":"run","Package":"example.com/b","Test":"TestFour"}
+{"Time":"2023-01-01T00:00:03Z","Action":"pass","Package":"example.com/b","Test":"TestFour","Elapsed":2}
+{"Time":"2023-01-01T00:00:03Z","Action":"pass","Package":"example.com/b","Elapsed":2}
+`
+
+func TestRun(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("saved.json", input))
+	opts := &options{
+		jsonfile: dir.Join("saved.json"),
+		output:   dir.Join("trace.json"),
+	}
+	assert.NilError(t, run(opts))
+
+	raw, err := ioutil.ReadFile(opts.output)
+	assert.NilError(t, err)
+	golden.Assert(t, string(raw), "trace.golden")
+}
+
+func TestRun_NoTimes(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("saved.json",
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`+"\n"))
+	opts := &options{jsonfile: dir.Join("saved.json"), output: dir.Join("trace.json")}
+	assert.ErrorContains(t, run(opts), "no tests with event times")
+}
+
+func TestAssignTracks(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	slices := packageSlices(exec.Package("example.com/a"))
+	var names []string
+	for _, s := range slices {
+		names = append(names, s.tc.Test.Name())
+	}
+	assert.DeepEqual(t, names, []string{"TestOne", "TestOne/sub", "TestTwo", "TestThree"})
+	assert.DeepEqual(t, assignTracks(slices), []int{1, 1, 2, 1})
+}
@@ -0,0 +1,220 @@
+package tune
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/configfile"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		opts.jsonfile = flags.Arg(0)
+	default:
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("expected at most one json file, got %d args", flags.NArg())
+	}
+	opts.stdout = os.Stdout
+	return run(opts)
+}
+
+// Config file keys of the gotestsum flags that set the go test -p and
+// -parallel flags.
+const (
+	configKeyP        = "go-test-p"
+	configKeyParallel = "go-test-parallel"
+)
+
+type options struct {
+	jsonfile   string
+	p          int
+	parallel   int
+	apply      bool
+	configFile string
+	debug      bool
+
+	// shims for testing
+	stdout io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.IntVar(&opts.p, "p", 0,
+		"the go test -p value used for the run. Defaults to the value in the config file, or GOMAXPROCS")
+	flags.IntVar(&opts.parallel, "parallel", 0,
+		"the go test -parallel value used for the run. Defaults to the value in the config file, or GOMAXPROCS")
+	flags.BoolVar(&opts.apply, "apply", false,
+		"write the recommended values to the config file")
+	flags.StringVar(&opts.configFile, "config", ".gotestsum.yaml",
+		"path to the gotestsum config file")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [FILE]
+
+Read the test2json output of a run, from FILE or stdin, and print the
+recommended go test -p and -parallel values for the tests. FILE may be created
+with 'gotestsum --jsonfile' or 'go test -json'.
+
+-p is increased when the run had -p packages running at the same time, and
+more packages waiting to run. -parallel is increased when a package had
+-parallel tests running at the same time, and more parallel tests waiting to
+run. Values are increased by at most a factor of 2, so run the tool again
+after the next run to find the best values.
+
+With --apply the recommended values are written to the config file as
+%[2]s and %[3]s, which are used by gotestsum for the next run.
+
+    gotestsum --jsonfile saved.json
+    %[1]s --apply saved.json
+
+Flags:
+`, name, configKeyP, configKeyParallel)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if opts.p < 0 || opts.parallel < 0 {
+		return fmt.Errorf("--p and --parallel must not be negative")
+	}
+
+	in, err := jsonfile.Open(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer in.Close() // nolint: errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	if err != nil {
+		return fmt.Errorf("failed to scan jsonfile: %w", err)
+	}
+
+	config, err := readConfig(opts.configFile)
+	if err != nil {
+		return err
+	}
+	entries, err := configfile.Parse(config)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %v: %w", opts.configFile, err)
+	}
+	current := currentSettings(opts, entries)
+	tuning := aggregate.Tune(exec, current)
+	printTuning(opts.stdout, tuning)
+
+	if !opts.apply {
+		return nil
+	}
+	if tuning.Recommended == tuning.Current {
+		fmt.Fprintf(opts.stdout, "\nThe current values are recommended, %v was not changed\n", opts.configFile)
+		return nil
+	}
+	config, err = configfile.Set(config, configKeyP, strconv.Itoa(tuning.Recommended.P))
+	if err != nil {
+		return err
+	}
+	config, err = configfile.Set(config, configKeyParallel, strconv.Itoa(tuning.Recommended.Parallel))
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(opts.configFile, config, 0o644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	fmt.Fprintf(opts.stdout, "\nWrote %v: %v, %v\n", opts.configFile,
+		configLine(configKeyP, strconv.Itoa(tuning.Recommended.P)),
+		configLine(configKeyParallel, strconv.Itoa(tuning.Recommended.Parallel)))
+	return nil
+}
+
+// readConfig returns the content of the config file, or nil if the file does
+// not exist.
+func readConfig(filename string) ([]byte, error) {
+	raw, err := ioutil.ReadFile(filename)
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return raw, nil
+}
+
+// currentSettings returns the -p and -parallel values used for the run, from
+// the flags, the config file, or the go test default of GOMAXPROCS.
+func currentSettings(opts *options, entries []configfile.Entry) aggregate.Settings {
+	value := func(flag int, key string) int {
+		if flag > 0 {
+			return flag
+		}
+		if n, err := strconv.Atoi(configValue(entries, key)); err == nil && n > 0 {
+			return n
+		}
+		return runtime.GOMAXPROCS(0)
+	}
+	return aggregate.Settings{
+		P:        value(opts.p, configKeyP),
+		Parallel: value(opts.parallel, configKeyParallel),
+	}
+}
+
+func printTuning(out io.Writer, tuning aggregate.Tuning) {
+	fmt.Fprintf(out, "-p %s\n    %s\n", formatChange(tuning.Current.P, tuning.Recommended.P), tuning.PReason)
+	fmt.Fprintf(out, "-parallel %s\n    %s\n",
+		formatChange(tuning.Current.Parallel, tuning.Recommended.Parallel), tuning.ParallelReason)
+}
+
+func formatChange(current, recommended int) string {
+	if current == recommended {
+		return fmt.Sprintf("%d (no change)", current)
+	}
+	return fmt.Sprintf("%d (was %d)", recommended, current)
+}
+
+// configValue returns the last value of a top-level key in the config file,
+// which is the value used by gotestsum, or an empty string if the key is not
+// in the file.
+func configValue(entries []configfile.Entry, key string) string {
+	var value string
+	for _, entry := range entries {
+		if entry.Name == key && len(entry.Values) > 0 {
+			value = entry.Values[len(entry.Values)-1]
+		}
+	}
+	return value
+}
+
+func configLine(key, value string) string {
+	return key + ": " + value
+}
@@ -0,0 +1,103 @@
+package tune
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+// Four packages run two at a time, and each has a test that takes 1s.
+const input = `{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/a","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/b","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/b","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:01Z","Action":"run","Package":"pkg/c","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/c","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:01Z","Action":"run","Package":"pkg/d","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/d","Test":"TestOne","Elapsed":1}
+`
+
+func TestRun(t *testing.T) {
+	dir := fs.NewDir(t, "tune", fs.WithFile("saved.json", input))
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile:   dir.Join("saved.json"),
+		p:          2,
+		parallel:   4,
+		configFile: dir.Join(".gotestsum.yaml"),
+		stdout:     out,
+	}
+	assert.NilError(t, run(opts))
+	expected := `-p 4 (was 2)
+    the run reached the limit of -p 2 packages at the same time, and the tests of the 4 packages took 4.00s, with 1.00s in the slowest package
+-parallel 4 (no change)
+    no package had more than -parallel 4 parallel tests waiting to run
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRun_Apply(t *testing.T) {
+	config := `# defaults
+format: dots
+go-test-p: 2 # tuned
+packages: ./...
+`
+	dir := fs.NewDir(t, "tune",
+		fs.WithFile("saved.json", input),
+		fs.WithFile(".gotestsum.yaml", config))
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile:   dir.Join("saved.json"),
+		parallel:   4,
+		apply:      true,
+		configFile: dir.Join(".gotestsum.yaml"),
+		stdout:     out,
+	}
+	assert.NilError(t, run(opts))
+	assert.Assert(t, bytes.Contains(out.Bytes(), []byte("-p 4 (was 2)")), out.String())
+
+	raw, err := ioutil.ReadFile(dir.Join(".gotestsum.yaml"))
+	assert.NilError(t, err)
+	expected := `# defaults
+format: dots
+go-test-p: 4
+packages: ./...
+go-test-parallel: 4
+`
+	assert.Equal(t, string(raw), expected)
+}
+
+func TestRun_ApplyNoChange(t *testing.T) {
+	dir := fs.NewDir(t, "tune", fs.WithFile("saved.json", input))
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile:   dir.Join("saved.json"),
+		p:          4,
+		parallel:   4,
+		apply:      true,
+		configFile: dir.Join(".gotestsum.yaml"),
+		stdout:     out,
+	}
+	assert.NilError(t, run(opts))
+	assert.Assert(t, bytes.Contains(out.Bytes(), []byte("was not changed")), out.String())
+	_, err := ioutil.ReadFile(dir.Join(".gotestsum.yaml"))
+	assert.Assert(t, os.IsNotExist(err), err)
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	dir := fs.NewDir(t, "tune",
+		fs.WithFile("saved.json", input),
+		fs.WithFile(".gotestsum.yaml", "packages: [./cmd\n"))
+	opts := &options{
+		jsonfile:   dir.Join("saved.json"),
+		apply:      true,
+		configFile: dir.Join(".gotestsum.yaml"),
+		stdout:     new(bytes.Buffer),
+	}
+	err := run(opts)
+	assert.ErrorContains(t, err, "line 1: missing closing bracket")
+}
@@ -0,0 +1,372 @@
+package zephyr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	opts.stdout = os.Stdout
+	opts.token = os.Getenv("ZEPHYR_TOKEN")
+	return run(opts)
+}
+
+type options struct {
+	jsonfile    string
+	url         string
+	projectKey  string
+	cycleName   string
+	mappingFile string
+	dryRun      bool
+	debug       bool
+
+	token string
+	// shims for testing
+	stdout io.Writer
+	client *http.Client
+	now    func() time.Time
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.jsonfile, "jsonfile", os.Getenv("GOTESTSUM_JSONFILE"),
+		"path to test2json output, defaults to stdin")
+	flags.StringVar(&opts.url, "url",
+		envWithDefault("ZEPHYR_URL", "https://api.zephyrscale.smartbear.com/v2"),
+		"base URL of the Zephyr Scale API")
+	flags.StringVar(&opts.projectKey, "project-key", os.Getenv("ZEPHYR_PROJECT_KEY"),
+		"key of the Jira project of the test cycle")
+	flags.StringVar(&opts.cycleName, "cycle-name", "",
+		"name of the test cycle, defaults to the time of the test run")
+	flags.StringVar(&opts.mappingFile, "mapping-file", "",
+		"JSON file that maps test names to Zephyr Scale test case keys")
+	flags.BoolVar(&opts.dryRun, "dry-run", false,
+		"print the requests instead of sending them")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func envWithDefault(key, defValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defValue
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read a json file and publish the results to a new Zephyr Scale test cycle. The
+json file may be created with 'gotestsum --jsonfile' or 'go test -json'.
+
+The test case key of a test is the requirement in square brackets in the name
+of the test, like TestLogin[PROJ-T12], or the key for the test in the
+--mapping-file. The mapping file is a JSON object where each key is the name of
+a test, optionally prefixed by the relative package path, and each value is a
+test case key:
+
+    {"TestLogin": "PROJ-T12", "./auth.TestLogout": "PROJ-T13"}
+
+A test case with more than one test fails if any of the tests failed. Tests
+without a test case key are not published. The API token is read from the
+ZEPHYR_TOKEN environment variable.
+
+    %[1]s --jsonfile saved.json --project-key PROJ
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if opts.projectKey == "" {
+		return fmt.Errorf("--project-key is required")
+	}
+	if opts.token == "" && !opts.dryRun {
+		return fmt.Errorf("the ZEPHYR_TOKEN environment variable is required")
+	}
+	mapping, err := readMappingFile(opts.mappingFile)
+	if err != nil {
+		return err
+	}
+
+	in, err := jsonfile.Open(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %w", err)
+	}
+	defer in.Close() // nolint: errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	if err != nil {
+		return fmt.Errorf("failed to scan jsonfile: %w", err)
+	}
+
+	results := testCaseResults(exec, mapping)
+	if len(results) == 0 {
+		fmt.Fprintln(opts.stdout, "No tests with a Zephyr Scale test case key")
+		return nil
+	}
+
+	c := newClient(opts)
+	cycle, err := c.createTestCycle(testCycle{
+		ProjectKey:  opts.projectKey,
+		Name:        cycleName(opts),
+		Description: fmt.Sprintf("%d tests published by gotestsum", exec.Total()),
+	})
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		result.ProjectKey = opts.projectKey
+		result.TestCycleKey = cycle.Key
+		if err := c.createTestExecution(result); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(opts.stdout, "Published %d test cases to test cycle %s\n", len(results), cycle.Key)
+	return nil
+}
+
+func cycleName(opts *options) string {
+	if opts.cycleName != "" {
+		return opts.cycleName
+	}
+	now := time.Now
+	if opts.now != nil {
+		now = opts.now
+	}
+	return "gotestsum " + now().UTC().Format("2006-01-02 15:04:05")
+}
+
+// readMappingFile returns the test case keys from the mapping file, or nil
+// when there is no mapping file.
+func readMappingFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping file: %w", err)
+	}
+	mapping := make(map[string]string)
+	if err := json.Unmarshal(raw, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file %v: %w", path, err)
+	}
+	return mapping, nil
+}
+
+const (
+	statusPass        = "Pass"
+	statusFail        = "Fail"
+	statusNotExecuted = "Not Executed"
+)
+
+// testCaseResults returns a test execution for each test case key, sorted by
+// key. When a test ran more than once the result of the last run is used.
+func testCaseResults(exec *testjson.Execution, mapping map[string]string) []testExecution {
+	byKey := make(map[string]*testExecution)
+	for _, pkgName := range exec.Packages() {
+		pkg := exec.Package(pkgName)
+		for _, tc := range lastRuns(pkg) {
+			status := testStatus(pkg, tc)
+			for _, key := range testCaseKeys(tc, mapping) {
+				result, ok := byKey[key]
+				if !ok {
+					result = &testExecution{TestCaseKey: key, StatusName: statusNotExecuted}
+					byKey[key] = result
+				}
+				result.ExecutionTime += tc.Elapsed.Milliseconds()
+				switch {
+				case status == statusFail:
+					result.StatusName = statusFail
+					name := testjson.RelativePackagePath(tc.Package) + "." + tc.Test.Name()
+					result.Comment = strings.TrimPrefix(result.Comment+", "+name, ", ")
+				case status == statusPass && result.StatusName == statusNotExecuted:
+					result.StatusName = statusPass
+				}
+			}
+		}
+	}
+
+	results := make([]testExecution, 0, len(byKey))
+	for _, result := range byKey {
+		if result.Comment != "" {
+			result.Comment = "Failed: " + result.Comment
+		}
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].TestCaseKey < results[j].TestCaseKey
+	})
+	return results
+}
+
+// lastRuns returns the last run of each test in the package.
+func lastRuns(pkg *testjson.Package) []testjson.TestCase {
+	last := make(map[string]testjson.TestCase)
+	for _, tc := range pkg.TestCases() {
+		if prev, ok := last[tc.Test.Name()]; !ok || tc.ID > prev.ID {
+			last[tc.Test.Name()] = tc
+		}
+	}
+	result := make([]testjson.TestCase, 0, len(last))
+	for _, tc := range last {
+		result = append(result, tc)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+func testStatus(pkg *testjson.Package, tc testjson.TestCase) string {
+	for _, failed := range pkg.Failed {
+		if failed.ID == tc.ID {
+			return statusFail
+		}
+	}
+	for _, skipped := range pkg.Skipped {
+		if skipped.ID == tc.ID {
+			return statusNotExecuted
+		}
+	}
+	return statusPass
+}
+
+// testCaseKeys returns the test case keys of a test. The keys in the mapping
+// file are used before the requirements in the name of the test.
+func testCaseKeys(tc testjson.TestCase, mapping map[string]string) []string {
+	name := tc.Test.Name()
+	if key, ok := mapping[testjson.RelativePackagePath(tc.Package)+"."+name]; ok {
+		return []string{key}
+	}
+	if key, ok := mapping[name]; ok {
+		return []string{key}
+	}
+	requirements, _ := junitxml.SplitRequirements(name)
+	return requirements
+}
+
+type testCycle struct {
+	ProjectKey  string `json:"projectKey"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type createdResource struct {
+	ID  int    `json:"id"`
+	Key string `json:"key"`
+}
+
+type testExecution struct {
+	ProjectKey    string `json:"projectKey"`
+	TestCaseKey   string `json:"testCaseKey"`
+	TestCycleKey  string `json:"testCycleKey"`
+	StatusName    string `json:"statusName"`
+	ExecutionTime int64  `json:"executionTime"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+type client struct {
+	opts   *options
+	client *http.Client
+}
+
+func newClient(opts *options) *client {
+	c := &client{opts: opts, client: opts.client}
+	if c.client == nil {
+		c.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return c
+}
+
+func (c *client) createTestCycle(cycle testCycle) (createdResource, error) {
+	var created createdResource
+	if c.opts.dryRun {
+		created.Key = "DRY-RUN"
+	}
+	if err := c.post("/testcycles", cycle, &created); err != nil {
+		return created, fmt.Errorf("failed to create test cycle: %w", err)
+	}
+	return created, nil
+}
+
+func (c *client) createTestExecution(result testExecution) error {
+	if err := c.post("/testexecutions", result, nil); err != nil {
+		return fmt.Errorf("failed to create test execution for %v: %w", result.TestCaseKey, err)
+	}
+	return nil
+}
+
+// post sends body as JSON to the path, and decodes the response into
+// response, which may be nil. In dry run mode the request is printed instead.
+func (c *client) post(path string, body interface{}, response interface{}) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSuffix(c.opts.url, "/") + path
+	if c.opts.dryRun {
+		fmt.Fprintf(c.opts.stdout, "POST %s %s\n", url, raw)
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.opts.token)
+	log.Debugf("POST %s %s", url, raw)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%v: %v", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	if response == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, response)
+}
@@ -0,0 +1,145 @@
+package zephyr
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+var events = []string{
+	`{"Action":"run","Package":"example.com/auth","Test":"TestLogin[PROJ-T1]"}`,
+	`{"Action":"pass","Package":"example.com/auth","Test":"TestLogin[PROJ-T1]","Elapsed":0.25}`,
+	`{"Action":"run","Package":"example.com/auth","Test":"TestLogout"}`,
+	`{"Action":"fail","Package":"example.com/auth","Test":"TestLogout","Elapsed":1}`,
+	`{"Action":"run","Package":"example.com/auth","Test":"TestRefresh[PROJ-T1]"}`,
+	`{"Action":"fail","Package":"example.com/auth","Test":"TestRefresh[PROJ-T1]","Elapsed":0.5}`,
+	`{"Action":"run","Package":"example.com/auth","Test":"TestRefresh[PROJ-T1]"}`,
+	`{"Action":"pass","Package":"example.com/auth","Test":"TestRefresh[PROJ-T1]","Elapsed":0.5}`,
+	`{"Action":"run","Package":"example.com/auth","Test":"TestSkipped[PROJ-T3]"}`,
+	`{"Action":"skip","Package":"example.com/auth","Test":"TestSkipped[PROJ-T3]"}`,
+	`{"Action":"run","Package":"example.com/auth","Test":"TestUnmapped"}`,
+	`{"Action":"pass","Package":"example.com/auth","Test":"TestUnmapped"}`,
+	`{"Action":"fail","Package":"example.com/auth"}`,
+}
+
+func TestRun(t *testing.T) {
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("Authorization"), "Bearer the-token")
+		body := map[string]interface{}{}
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, request{path: r.URL.Path, body: body})
+		if r.URL.Path == "/v2/testcycles" {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":10,"key":"PROJ-R10"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":11}`))
+	}))
+	defer srv.Close()
+
+	dir := fs.NewDir(t, "zephyr",
+		fs.WithFile("saved.json", strings.Join(events, "\n")),
+		fs.WithFile("mapping.json", `{"example.com/auth.TestLogout": "PROJ-T2"}`))
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile:    dir.Join("saved.json"),
+		mappingFile: dir.Join("mapping.json"),
+		url:         srv.URL + "/v2/",
+		projectKey:  "PROJ",
+		token:       "the-token",
+		stdout:      out,
+		now: func() time.Time {
+			return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		},
+	}
+	assert.NilError(t, run(opts))
+	assert.Equal(t, out.String(), "Published 3 test cases to test cycle PROJ-R10\n")
+
+	expected := []request{
+		{path: "/v2/testcycles", body: map[string]interface{}{
+			"projectKey":  "PROJ",
+			"name":        "gotestsum 2024-01-02 03:04:05",
+			"description": "6 tests published by gotestsum",
+		}},
+		{path: "/v2/testexecutions", body: map[string]interface{}{
+			"projectKey":    "PROJ",
+			"testCaseKey":   "PROJ-T1",
+			"testCycleKey":  "PROJ-R10",
+			"statusName":    "Pass",
+			"executionTime": float64(750),
+		}},
+		{path: "/v2/testexecutions", body: map[string]interface{}{
+			"projectKey":    "PROJ",
+			"testCaseKey":   "PROJ-T2",
+			"testCycleKey":  "PROJ-R10",
+			"statusName":    "Fail",
+			"executionTime": float64(1000),
+			"comment":       "Failed: example.com/auth.TestLogout",
+		}},
+		{path: "/v2/testexecutions", body: map[string]interface{}{
+			"projectKey":    "PROJ",
+			"testCaseKey":   "PROJ-T3",
+			"testCycleKey":  "PROJ-R10",
+			"statusName":    "Not Executed",
+			"executionTime": float64(0),
+		}},
+	}
+	assert.DeepEqual(t, requests, expected, cmpRequest)
+}
+
+type request struct {
+	path string
+	body map[string]interface{}
+}
+
+var cmpRequest = gocmp.AllowUnexported(request{})
+
+func TestRun_DryRun(t *testing.T) {
+	jsonfile := fs.NewFile(t, "saved.json", fs.WithContent(strings.Join(events[:2], "\n")))
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile:   jsonfile.Path(),
+		url:        "https://zephyr.example.com/v2",
+		projectKey: "PROJ",
+		cycleName:  "nightly",
+		dryRun:     true,
+		stdout:     out,
+	}
+	assert.NilError(t, run(opts))
+	expected := `POST https://zephyr.example.com/v2/testcycles {"projectKey":"PROJ","name":"nightly","description":"1 tests published by gotestsum"}
+POST https://zephyr.example.com/v2/testexecutions {"projectKey":"PROJ","testCaseKey":"PROJ-T1","testCycleKey":"DRY-RUN","statusName":"Pass","executionTime":250}
+Published 1 test cases to test cycle DRY-RUN
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRun_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"project not found"}`))
+	}))
+	defer srv.Close()
+	jsonfile := fs.NewFile(t, "saved.json", fs.WithContent(strings.Join(events[:2], "\n")))
+
+	opts := &options{jsonfile: jsonfile.Path(), url: srv.URL, token: "x", stdout: new(bytes.Buffer)}
+	assert.Error(t, run(opts), "--project-key is required")
+
+	opts.projectKey = "PROJ"
+	err := run(opts)
+	assert.ErrorContains(t, err, `failed to create test cycle: 400 Bad Request: {"message":"project not found"}`)
+
+	opts.token = ""
+	assert.Error(t, run(opts), "the ZEPHYR_TOKEN environment variable is required")
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// tuneArgs returns the go test flags for the --go-test-p and
+// --go-test-parallel values. A flag in the go test args is used instead of the
+// value from gotestsum, so that a value from the config file can be changed
+// for one run.
+func tuneArgs(opts *options) []string {
+	var result []string
+	if opts.goTestP > 0 && !hasArg(opts.args, "p") {
+		result = append(result, "-p="+strconv.Itoa(opts.goTestP))
+	}
+	if opts.goTestParallel > 0 && !hasArg(opts.args, "parallel", "test.parallel") {
+		result = append(result, "-parallel="+strconv.Itoa(opts.goTestParallel))
+	}
+	return result
+}
+
+func hasArg(args []string, names ...string) bool {
+	for _, name := range names {
+		if start, _ := argIndex(name, args); start >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// argValue returns the value of the flag in args, and true if the flag is in
+// args.
+func argValue(args []string, name string) (string, bool) {
+	start, end := argIndex(name, args)
+	switch {
+	case start < 0:
+		return "", false
+	case start == end:
+		return strings.SplitN(args[start], "=", 2)[1], true
+	case end < len(args):
+		return args[end], true
+	}
+	return "", false
+}
+
+// currentTuneSettings returns the -p and -parallel values used by go test for
+// the run. go test uses GOMAXPROCS when a flag is not set.
+func currentTuneSettings(opts *options) aggregate.Settings {
+	settings := aggregate.Settings{P: runtime.GOMAXPROCS(0), Parallel: runtime.GOMAXPROCS(0)}
+	args := append(tuneArgs(opts), opts.args...)
+	if v, ok := argValue(args, "p"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			settings.P = n
+		}
+	}
+	for _, name := range []string{"parallel", "test.parallel"} {
+		if v, ok := argValue(args, name); ok {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				settings.Parallel = n
+			}
+			break
+		}
+	}
+	return settings
+}
+
+// printTuning prints the recommended -p and -parallel values for the run.
+func printTuning(out io.Writer, exec *testjson.Execution, opts *options) {
+	if !opts.tune || opts.rawCommand {
+		return
+	}
+	tuning := aggregate.Tune(exec, currentTuneSettings(opts))
+	fmt.Fprintf(out, "\n=== Tuning: -p %s, -parallel %s\n",
+		formatTuneChange(tuning.Current.P, tuning.Recommended.P),
+		formatTuneChange(tuning.Current.Parallel, tuning.Recommended.Parallel))
+	fmt.Fprintf(out, "=== -p: %s\n", tuning.PReason)
+	fmt.Fprintf(out, "=== -parallel: %s\n", tuning.ParallelReason)
+}
+
+func formatTuneChange(current, recommended int) string {
+	if current == recommended {
+		return fmt.Sprintf("%d (no change)", current)
+	}
+	return fmt.Sprintf("%d (was %d)", recommended, current)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestTuneArgs(t *testing.T) {
+	type testCase struct {
+		name     string
+		opts     *options
+		expected []string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		assert.DeepEqual(t, tuneArgs(tc.opts), tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name: "not set",
+			opts: &options{},
+		},
+		{
+			name:     "both set",
+			opts:     &options{goTestP: 2, goTestParallel: 8},
+			expected: []string{"-p=2", "-parallel=8"},
+		},
+		{
+			name:     "go test args are used instead",
+			opts:     &options{goTestP: 2, goTestParallel: 8, args: []string{"-p", "4", "-test.parallel=1"}},
+			expected: nil,
+		},
+		{
+			name:     "go test arg for one flag",
+			opts:     &options{goTestP: 2, goTestParallel: 8, args: []string{"-parallel=3"}},
+			expected: []string{"-p=2"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestCurrentTuneSettings(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	assert.DeepEqual(t, currentTuneSettings(&options{}), aggregate.Settings{P: procs, Parallel: procs})
+	assert.DeepEqual(t, currentTuneSettings(&options{goTestP: 3}), aggregate.Settings{P: 3, Parallel: procs})
+
+	opts := &options{goTestP: 3, args: []string{"-p", "5", "--parallel=7", "./..."}}
+	assert.DeepEqual(t, currentTuneSettings(opts), aggregate.Settings{P: 5, Parallel: 7})
+}
+
+func TestGoTestCmdArgs_Tune(t *testing.T) {
+	opts := &options{goTestP: 2, goTestParallel: 8, args: []string{"-count=1", "./pkg"}}
+	assert.DeepEqual(t, goTestCmdArgs(opts, rerunOpts{}),
+		[]string{"go", "test", "-json", "-p=2", "-parallel=8", "-count=1", "./pkg"})
+}
+
+func TestPrintTuning(t *testing.T) {
+	input := `{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/a","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:01Z","Action":"run","Package":"pkg/b","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/b","Test":"TestOne","Elapsed":1}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	printTuning(out, exec, &options{tune: true, goTestP: 1, goTestParallel: 4})
+	expected := `
+=== Tuning: -p 2 (was 1), -parallel 4 (no change)
+=== -p: the run reached the limit of -p 1 packages at the same time, and the tests of the 2 packages took 2.00s, with 1.00s in the slowest package
+=== -parallel: no package had more than -parallel 4 parallel tests waiting to run
+`
+	assert.Equal(t, out.String(), expected)
+
+	out.Reset()
+	printTuning(out, exec, &options{goTestP: 1})
+	assert.Equal(t, out.String(), "")
+}
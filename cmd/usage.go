@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// recordUsage stores the resource usage of a 'go test' process that has
+// exited. pkg is the package tested by the process, or empty if the process
+// tested more than one package.
+func recordUsage(opts *options, p *proc, pkg string) {
+	if p.processState == nil {
+		return
+	}
+	state := p.processState()
+	if state == nil {
+		return
+	}
+	usage := jsonsummary.ResourceUsage{
+		Package: pkg,
+		Wall:    time.Since(p.started).Seconds(),
+		User:    state.UserTime().Seconds(),
+		System:  state.SystemTime().Seconds(),
+		MaxRSS:  maxRSS(state),
+	}
+	opts.resourceUsage = append(opts.resourceUsage, usage)
+
+	if opts.maxRSS.bytes > 0 && usage.MaxRSS > opts.maxRSS.bytes {
+		name := opts.packageName(pkg)
+		if pkg == "" {
+			name = "go test"
+		}
+		log.Warnf("%v used %v of memory, which exceeds --max-rss=%v",
+			name, formatBytes(usage.MaxRSS), opts.maxRSS.String())
+	}
+}
+
+func printResourceUsage(out io.Writer, usage []jsonsummary.ResourceUsage, packageName func(string) string) {
+	if len(usage) == 0 {
+		return
+	}
+	var total jsonsummary.ResourceUsage
+	for _, u := range usage {
+		total.Wall += u.Wall
+		total.User += u.User
+		total.System += u.System
+		if u.MaxRSS > total.MaxRSS {
+			total.MaxRSS = u.MaxRSS
+		}
+	}
+	fmt.Fprintf(out, "\n=== Resource usage: %d go test runs, wall %.3fs, user %.3fs, system %.3fs, max RSS %v\n",
+		len(usage), total.Wall, total.User, total.System, formatBytes(total.MaxRSS))
+	for _, u := range usage {
+		if u.Package == "" {
+			continue
+		}
+		fmt.Fprintf(out, "=== USAGE: %s wall %.3fs, user %.3fs, system %.3fs, max RSS %v\n",
+			packageName(u.Package), u.Wall, u.User, u.System, formatBytes(u.MaxRSS))
+	}
+}
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{suffix: "GiB", size: 1 << 30},
+	{suffix: "MiB", size: 1 << 20},
+	{suffix: "KiB", size: 1 << 10},
+	{suffix: "GB", size: 1000 * 1000 * 1000},
+	{suffix: "MB", size: 1000 * 1000},
+	{suffix: "KB", size: 1000},
+	{suffix: "B", size: 1},
+}
+
+func formatBytes(n int64) string {
+	for _, unit := range byteSizeUnits[:3] {
+		if n >= unit.size {
+			return fmt.Sprintf("%.1f%s", float64(n)/float64(unit.size), unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// byteSizeValue is a flag.Value for a size in bytes, with an optional unit
+// suffix (ex: 512MiB, 2GB).
+type byteSizeValue struct {
+	raw   string
+	bytes int64
+}
+
+func (b *byteSizeValue) String() string {
+	return b.raw
+}
+
+func (b *byteSizeValue) Set(raw string) error {
+	value := strings.TrimSpace(raw)
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(strings.ToUpper(value), strings.ToUpper(unit.suffix)) {
+			value = strings.TrimSpace(value[:len(value)-len(unit.suffix)])
+			multiplier = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q, must be a number with an optional unit (ex: 512MiB)", raw)
+	}
+	b.raw = raw
+	b.bytes = int64(n * float64(multiplier))
+	return nil
+}
+
+func (b *byteSizeValue) Type() string {
+	return "size"
+}
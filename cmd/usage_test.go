@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestByteSizeValue_Set(t *testing.T) {
+	type testCase struct {
+		raw      string
+		expected int64
+	}
+	for _, tc := range []testCase{
+		{raw: "1024", expected: 1024},
+		{raw: "12B", expected: 12},
+		{raw: "2KB", expected: 2000},
+		{raw: "2kib", expected: 2048},
+		{raw: "1.5GiB", expected: 3 << 29},
+		{raw: "512 MB", expected: 512 * 1000 * 1000},
+	} {
+		t.Run(tc.raw, func(t *testing.T) {
+			var value byteSizeValue
+			assert.NilError(t, value.Set(tc.raw))
+			assert.Equal(t, value.bytes, tc.expected)
+			assert.Equal(t, value.String(), tc.raw)
+		})
+	}
+
+	var value byteSizeValue
+	assert.ErrorContains(t, value.Set("lots"), `invalid size "lots"`)
+}
+
+func TestPrintResourceUsage(t *testing.T) {
+	out := new(bytes.Buffer)
+	printResourceUsage(out, []jsonsummary.ResourceUsage{
+		{Wall: 2, User: 3.5, System: 0.5, MaxRSS: 50 << 20},
+		{Package: "example.com/pkg", Wall: 1, User: 1, System: 0.25, MaxRSS: 70 << 20},
+	}, testjson.RelativePackagePath)
+	expected := `
+=== Resource usage: 2 go test runs, wall 3.000s, user 4.500s, system 0.750s, max RSS 70.0MiB
+=== USAGE: example.com/pkg wall 1.000s, user 1.000s, system 0.250s, max RSS 70.0MiB
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSS returns the maximum resident set size, in bytes, of the process
+// or any of its children.
+func maxRSS(state *os.ProcessState) int64 {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	// darwin reports the value in bytes, everything else in kilobytes.
+	if runtime.GOOS == "darwin" {
+		return int64(rusage.Maxrss)
+	}
+	return int64(rusage.Maxrss) * 1024
+}
@@ -0,0 +1,8 @@
+package cmd
+
+import "os"
+
+// maxRSS is not available on windows.
+func maxRSS(_ *os.ProcessState) int64 {
+	return 0
+}
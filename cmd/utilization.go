@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// utilizationChartWidth is the number of columns used for the timeline of
+// each package.
+const utilizationChartWidth = 60
+
+// printUtilizationChart prints a Gantt style chart with a line for each
+// package. Each column in a line is a slice of the wall time of the run, and
+// shows the number of tests in the package that were running during that time.
+func printUtilizationChart(out io.Writer, exec *testjson.Execution, width int, packageName func(string) string) {
+	type row struct {
+		name  string
+		spans []aggregate.Span
+		util  aggregate.Utilization
+	}
+	var rows []row
+	var start, end time.Time
+	var nameWidth int
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		spans := aggregate.Spans(pkg.TestCases())
+		if len(spans) == 0 {
+			continue
+		}
+		r := row{
+			name:  packageName(name),
+			spans: spans,
+			util:  aggregate.PackageUtilization(pkg),
+		}
+		rows = append(rows, r)
+		for _, span := range spans {
+			if start.IsZero() || span.Start.Before(start) {
+				start = span.Start
+			}
+			if span.End.After(end) {
+				end = span.End
+			}
+		}
+		if len(r.name) > nameWidth {
+			nameWidth = len(r.name)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	total := aggregate.ExecutionUtilization(exec)
+	fmt.Fprintf(out, "\n=== Utilization (wall %s, test time %s, idle %s, parallelism %.2f)\n",
+		testjson.FormatDurationAsSeconds(total.Wall, 2),
+		testjson.FormatDurationAsSeconds(total.TestTime, 2),
+		testjson.FormatDurationAsSeconds(total.Idle, 2),
+		total.Parallelism)
+
+	wall := end.Sub(start)
+	for _, r := range rows {
+		fmt.Fprintf(out, "%-*s |%s| %.2f parallel, %s idle\n",
+			nameWidth, r.name,
+			timeline(r.spans, start, wall, width),
+			r.util.Parallelism,
+			testjson.FormatDurationAsSeconds(r.util.Idle, 2))
+	}
+	fmt.Fprintf(out, "%-*s  %-*s%s\n", nameWidth, "", width-len("0s"), "0s",
+		testjson.FormatDurationAsSeconds(wall, 1))
+}
+
+// timeline returns a line of width columns. Each column is the number of spans
+// that overlap the slice of time, a space when no spans overlap, or + when
+// more than 9 overlap.
+func timeline(spans []aggregate.Span, start time.Time, wall time.Duration, width int) string {
+	var buf strings.Builder
+	bucket := wall / time.Duration(width)
+	if bucket <= 0 {
+		bucket = 1
+	}
+	for i := 0; i < width; i++ {
+		from := start.Add(time.Duration(i) * bucket)
+		to := from.Add(bucket)
+		count := 0
+		for _, span := range spans {
+			if span.Start.Before(to) && span.End.After(from) {
+				count++
+			}
+		}
+		switch {
+		case count == 0:
+			buf.WriteByte(' ')
+		case count > 9:
+			buf.WriteByte('+')
+		default:
+			buf.WriteByte(byte('0' + count))
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestPrintUtilizationChart(t *testing.T) {
+	input := `{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"example.com/a","Test":"TestTwo"}
+{"Time":"2023-01-01T00:00:05Z","Action":"pass","Package":"example.com/a","Test":"TestOne","Elapsed":5}
+{"Time":"2023-01-01T00:00:05Z","Action":"pass","Package":"example.com/a","Test":"TestTwo","Elapsed":5}
+{"Time":"2023-01-01T00:00:05Z","Action":"run","Package":"example.com/b","Test":"TestThree"}
+{"Time":"2023-01-01T00:00:10Z","Action":"pass","Package":"example.com/b","Test":"TestThree","Elapsed":5}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	printUtilizationChart(out, exec, 10, testjson.RelativePackagePath)
+	expected := `
+=== Utilization (wall 10.00s, test time 15.00s, idle 0.00s, parallelism 1.50)
+example.com/a |22222     | 2.00 parallel, 0.00s idle
+example.com/b |     11111| 1.00 parallel, 0.00s idle
+               0s      10.0s
+`
+	assert.Equal(t, out.String(), expected)
+}
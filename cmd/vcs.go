@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/textreport"
+)
+
+// vcsInfo is the state of the git working tree that contains the current
+// directory.
+type vcsInfo struct {
+	Commit string
+	// Branch is empty when HEAD is detached.
+	Branch string
+	// Dirty is true if the working tree has changes that are not committed.
+	Dirty bool
+}
+
+// vcs returns the state of the git working tree, or nil when --no-vcs is set,
+// or the current directory is not in a git working tree. The state is looked
+// up once, and stored on opts.
+func vcs(opts *options) *vcsInfo {
+	if opts.noVCS {
+		return nil
+	}
+	if !opts.vcsProbed {
+		opts.vcs = probeVCS()
+		opts.vcsProbed = true
+	}
+	return opts.vcs
+}
+
+// probeVCS is a variable so that it can be replaced in tests.
+var probeVCS = func() *vcsInfo {
+	commit, err := gitOutput("rev-parse", "HEAD")
+	if err != nil {
+		log.Debugf("no vcs metadata: %v", err)
+		return nil
+	}
+	info := &vcsInfo{Commit: strings.TrimSpace(commit)}
+	if branch, err := gitOutput("rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		if branch = strings.TrimSpace(branch); branch != "HEAD" {
+			info.Branch = branch
+		}
+	}
+	status, err := gitOutput("status", "--porcelain")
+	if err != nil {
+		log.Warnf("Failed to lookup the git status of the working tree: %v", err)
+	}
+	info.Dirty = strings.TrimSpace(status) != ""
+	return info
+}
+
+// junitProperties returns the vcs properties for the testsuite of every
+// package.
+func (v *vcsInfo) junitProperties() []junitxml.JUnitProperty {
+	if v == nil {
+		return nil
+	}
+	props := []junitxml.JUnitProperty{{Name: "vcs.commit", Value: v.Commit}}
+	if v.Branch != "" {
+		props = append(props, junitxml.JUnitProperty{Name: "vcs.branch", Value: v.Branch})
+	}
+	return append(props, junitxml.JUnitProperty{Name: "vcs.dirty", Value: strconv.FormatBool(v.Dirty)})
+}
+
+func (v *vcsInfo) summary() *jsonsummary.VCS {
+	if v == nil {
+		return nil
+	}
+	return &jsonsummary.VCS{Commit: v.Commit, Branch: v.Branch, Dirty: v.Dirty}
+}
+
+// report returns the vcs state for the --report-template and the
+// --webhook-template.
+func (v *vcsInfo) report() *textreport.VCS {
+	if v == nil {
+		return nil
+	}
+	return &textreport.VCS{Commit: v.Commit, Branch: v.Branch, Dirty: v.Dirty}
+}
+
+// environ returns the vcs state as environment variables for the
+// --post-run-command.
+func (v *vcsInfo) environ() []string {
+	if v == nil {
+		return nil
+	}
+	return []string{
+		"GOTESTSUM_VCS_COMMIT=" + v.Commit,
+		"GOTESTSUM_VCS_BRANCH=" + v.Branch,
+		"GOTESTSUM_VCS_DIRTY=" + strconv.FormatBool(v.Dirty),
+	}
+}
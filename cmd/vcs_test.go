@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func patchVCS(t *testing.T, info *vcsInfo) {
+	t.Helper()
+	orig := probeVCS
+	probeVCS = func() *vcsInfo {
+		return info
+	}
+	t.Cleanup(func() { probeVCS = orig })
+}
+
+func TestVCS(t *testing.T) {
+	patchVCS(t, &vcsInfo{Commit: "abc123", Branch: "main", Dirty: true})
+
+	opts := &options{}
+	info := vcs(opts)
+	assert.DeepEqual(t, info.junitProperties(), []junitxml.JUnitProperty{
+		{Name: "vcs.commit", Value: "abc123"},
+		{Name: "vcs.branch", Value: "main"},
+		{Name: "vcs.dirty", Value: "true"},
+	})
+	assert.DeepEqual(t, info.summary(), &jsonsummary.VCS{Commit: "abc123", Branch: "main", Dirty: true})
+	assert.DeepEqual(t, info.environ(), []string{
+		"GOTESTSUM_VCS_COMMIT=abc123",
+		"GOTESTSUM_VCS_BRANCH=main",
+		"GOTESTSUM_VCS_DIRTY=true",
+	})
+
+	t.Run("no-vcs", func(t *testing.T) {
+		info := vcs(&options{noVCS: true})
+		assert.Assert(t, info == nil)
+		assert.Assert(t, info.junitProperties() == nil)
+		assert.Assert(t, info.summary() == nil)
+		assert.Assert(t, info.environ() == nil)
+	})
+}
+
+func TestProbeVCS_NotAGitRepository(t *testing.T) {
+	env.Patch(t, "GIT_DIR", t.TempDir())
+	assert.Assert(t, probeVCS() == nil)
+}
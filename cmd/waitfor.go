@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// readinessProbe checks if a dependency of the tests is ready. A tcp probe is
+// ready when a connection to the address is accepted, an http probe when a GET
+// of the URL returns a 2xx or 3xx status, and a cmd probe when the command
+// exits successfully.
+type readinessProbe struct {
+	raw    string
+	scheme string
+	// target is the address of a tcp probe, or the URL of an http probe.
+	target string
+	// args are the command and args of a cmd probe.
+	args []string
+}
+
+// waitForValue is a flag.Value for the list of probes from --wait-for.
+type waitForValue struct {
+	probes []readinessProbe
+}
+
+func (v *waitForValue) Set(raw string) error {
+	probe, err := parseReadinessProbe(strings.TrimSpace(raw))
+	if err != nil {
+		return err
+	}
+	v.probes = append(v.probes, probe)
+	return nil
+}
+
+func (v *waitForValue) String() string {
+	raw := make([]string, 0, len(v.probes))
+	for _, probe := range v.probes {
+		raw = append(raw, probe.raw)
+	}
+	return strings.Join(raw, ",")
+}
+
+func (v *waitForValue) Type() string {
+	return "probe"
+}
+
+func parseReadinessProbe(raw string) (readinessProbe, error) {
+	probe := readinessProbe{raw: raw}
+	if strings.HasPrefix(raw, "cmd:") {
+		args, err := shlex.Split(strings.TrimPrefix(raw, "cmd:"))
+		if err != nil || len(args) == 0 {
+			return probe, fmt.Errorf("invalid probe %q, must be cmd:COMMAND [ARGS]", raw)
+		}
+		probe.scheme, probe.args = "cmd", args
+		return probe, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return probe, fmt.Errorf("invalid probe %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		if _, port, err := net.SplitHostPort(u.Host); err != nil || port == "" {
+			return probe, fmt.Errorf("invalid probe %q, must be tcp://HOST:PORT", raw)
+		}
+		probe.scheme, probe.target = "tcp", u.Host
+	case "http", "https":
+		if u.Host == "" {
+			return probe, fmt.Errorf("invalid probe %q, the URL must have a host", raw)
+		}
+		probe.scheme, probe.target = u.Scheme, raw
+	default:
+		return probe, fmt.Errorf("invalid probe %q, must start with tcp://, http://, https://, or cmd:", raw)
+	}
+	return probe, nil
+}
+
+// probeAttemptTimeout is the most time that one check of a probe may take.
+const probeAttemptTimeout = 5 * time.Second
+
+// check returns nil if the dependency is ready.
+func (p readinessProbe) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, probeAttemptTimeout)
+	defer cancel()
+	switch p.scheme {
+	case "tcp":
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", p.target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case "cmd":
+		out, err := runSetupCommandFn(ctx, p.args)
+		if err != nil {
+			return fmt.Errorf("%w%s", err, formatCommandOutput(out))
+		}
+		return nil
+	default:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("%v", resp.Status)
+		}
+		return nil
+	}
+}
+
+// waitForProbes checks each probe until it is ready, or until the timeout.
+// The result of every probe that was checked is returned, including the probe
+// that was not ready.
+func waitForProbes(ctx context.Context, probes []readinessProbe, timeout time.Duration) ([]jsonsummary.Probe, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	results := make([]jsonsummary.Probe, 0, len(probes))
+	for _, probe := range probes {
+		start := time.Now()
+		result := jsonsummary.Probe{Target: probe.raw}
+		var err error
+		for {
+			result.Attempts++
+			checkErr := probe.check(ctx)
+			if checkErr == nil {
+				err = nil
+				break
+			}
+			// keep the error from the last check that was not stopped by the
+			// timeout, because it explains why the probe was not ready
+			if ctx.Err() == nil || err == nil {
+				err = checkErr
+			}
+			log.Debugf("--wait-for %v is not ready: %v", probe.raw, checkErr)
+			select {
+			case <-ctx.Done():
+			case <-time.After(setupProbeInterval):
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		result.Elapsed = time.Since(start).Seconds()
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			return results, fmt.Errorf("--wait-for %v was not ready after %v: %w", probe.raw, timeout, err)
+		}
+		result.Ready = true
+		results = append(results, result)
+	}
+	return results, nil
+}
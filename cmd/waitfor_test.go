@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+)
+
+func TestWaitForValue(t *testing.T) {
+	var v waitForValue
+	assert.NilError(t, v.Set("tcp://localhost:5432"))
+	assert.NilError(t, v.Set("http://localhost:8080/healthz"))
+	assert.NilError(t, v.Set("cmd:pg_isready -h 'local host'"))
+	expected := []readinessProbe{
+		{raw: "tcp://localhost:5432", scheme: "tcp", target: "localhost:5432"},
+		{raw: "http://localhost:8080/healthz", scheme: "http", target: "http://localhost:8080/healthz"},
+		{raw: "cmd:pg_isready -h 'local host'", scheme: "cmd", args: []string{"pg_isready", "-h", "local host"}},
+	}
+	assert.DeepEqual(t, v.probes, expected, gocmp.AllowUnexported(readinessProbe{}))
+	assert.Equal(t, v.String(), "tcp://localhost:5432,http://localhost:8080/healthz,cmd:pg_isready -h 'local host'")
+
+	assert.ErrorContains(t, v.Set("tcp://localhost"), "must be tcp://HOST:PORT")
+	assert.ErrorContains(t, v.Set("http:///healthz"), "the URL must have a host")
+	assert.ErrorContains(t, v.Set("cmd:"), "must be cmd:COMMAND [ARGS]")
+	assert.ErrorContains(t, v.Set("localhost:5432"), "must start with tcp://, http://, https://, or cmd:")
+}
+
+func TestWaitForProbes(t *testing.T) {
+	defer patchSetupProbeInterval(time.Millisecond)()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close() // nolint: errcheck
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	_, reset := patchRunSetupCommandFn(func(args []string) ([]byte, error) {
+		return nil, nil
+	})
+	defer reset()
+
+	probes := parseProbes(t, "tcp://"+listener.Addr().String(), srv.URL+"/healthz", "cmd:true")
+	results, err := waitForProbes(context.Background(), probes, time.Minute)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 3)
+	for _, result := range results {
+		assert.Assert(t, result.Ready, result.Target)
+		assert.Equal(t, result.Error, "")
+	}
+	assert.Equal(t, results[0].Attempts, 1)
+	assert.Equal(t, results[1].Attempts, 3)
+}
+
+func TestWaitForProbes_NotReady(t *testing.T) {
+	defer patchSetupProbeInterval(time.Millisecond)()
+	_, reset := patchRunSetupCommandFn(func(args []string) ([]byte, error) {
+		return []byte("no response"), errors.New("exit status 2")
+	})
+	defer reset()
+
+	probes := parseProbes(t, "cmd:pg_isready", "tcp://localhost:5432")
+	results, err := waitForProbes(context.Background(), probes, 20*time.Millisecond)
+	assert.Error(t, err, "--wait-for cmd:pg_isready was not ready after 20ms: exit status 2\nno response")
+	assert.Equal(t, len(results), 1, "probes after the one that failed are not checked")
+	assert.Assert(t, !results[0].Ready)
+	assert.Assert(t, results[0].Attempts > 1)
+	assert.Equal(t, results[0].Error, "exit status 2\nno response")
+}
+
+func parseProbes(t *testing.T, raw ...string) []readinessProbe {
+	t.Helper()
+	var v waitForValue
+	for _, r := range raw {
+		assert.NilError(t, v.Set(r))
+	}
+	return v.probes
+}
@@ -7,8 +7,11 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"gotest.tools/gotestsum/internal/filewatcher"
+	"gotest.tools/gotestsum/internal/log"
 	"gotest.tools/gotestsum/testjson"
 )
 
@@ -17,12 +20,48 @@ func runWatcher(opts *options) error {
 	defer cancel()
 
 	w := &watchRuns{opts: *opts}
-	return filewatcher.Watch(ctx, opts.packages, w.run)
+	dirs := opts.packages
+	modules, err := watchModules(opts)
+	if err != nil {
+		return err
+	}
+	if len(modules) > 0 {
+		w.modules = modules
+		dirs = make([]string, 0, len(modules))
+		for _, mod := range modules {
+			dirs = append(dirs, mod.Dir+"/...")
+		}
+	}
+	return filewatcher.Watch(ctx, dirs, w.run)
+}
+
+// watchModules returns the modules to watch when the current directory has a
+// go.work file, or when --all-modules is set. Modules are not used when the
+// --packages flag sets the directories to watch.
+func watchModules(opts *options) (goModules, error) {
+	if len(opts.packages) > 0 {
+		return nil, nil
+	}
+	if _, err := os.Stat("go.work"); err != nil && !opts.allModules {
+		return nil, nil
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	modules, err := findModules(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find modules: %w", err)
+	}
+	return modules, nil
 }
 
 type watchRuns struct {
 	opts     options
 	prevExec *testjson.Execution
+	// modules are the modules of the workspace, when the watched directories
+	// are the directories of the modules.
+	modules goModules
 }
 
 func (w *watchRuns) run(event filewatcher.Event) error {
@@ -44,17 +83,26 @@ func (w *watchRuns) run(event filewatcher.Event) error {
 	}
 
 	var dir string
-	if w.opts.watchChdir {
-		dir, event.PkgPath = event.PkgPath, "./"
-	}
-
 	opts := w.opts // shallow copy opts
 	opts.packages = append([]string{}, opts.packages...)
-	opts.packages = append(opts.packages, event.PkgPath)
+	switch mod, ok := w.modules.forDir(event.PkgPath); {
+	case w.opts.watchChdir:
+		dir, event.PkgPath = event.PkgPath, "./"
+		opts.packages = append(opts.packages, event.PkgPath)
+	case ok && !strings.Contains(event.PkgPath, "..."):
+		pkgs, err := moduleAffectedPackages(mod, event.PkgPath)
+		if err != nil {
+			return err
+		}
+		dir = mod.Dir
+		opts.packages = append(opts.packages, pkgs...)
+	default:
+		opts.packages = append(opts.packages, event.PkgPath)
+	}
 	opts.packages = append(opts.packages, event.Args...)
 
 	var err error
-	if w.prevExec, err = runSingle(&opts, dir); !IsExitCoder(err) {
+	if w.prevExec, err = runSingle(&opts, dir); !isTestRunFailure(err) {
 		return err
 	}
 	return nil
@@ -68,6 +116,9 @@ func runSingle(opts *options, dir string) (*testjson.Execution, error) {
 	defer cancel()
 
 	if err := opts.Validate(); err != nil {
+		return nil, misuseError(err)
+	}
+	if err := setupAttachmentDir(opts); err != nil {
 		return nil, err
 	}
 
@@ -75,6 +126,8 @@ func runSingle(opts *options, dir string) (*testjson.Execution, error) {
 	if err != nil {
 		return nil, err
 	}
+	opts.stuck = newStuckDetector(opts)
+	opts.stuck.watch(goTestProc, cancel)
 
 	handler, err := newEventHandler(opts)
 	if err != nil {
@@ -82,10 +135,14 @@ func runSingle(opts *options, dir string) (*testjson.Execution, error) {
 	}
 	defer handler.Close() // nolint: errcheck
 	cfg := testjson.ScanConfig{
-		Stdout:  goTestProc.stdout,
-		Stderr:  goTestProc.stderr,
-		Handler: handler,
-		Stop:    cancel,
+		Stdout:                  goTestProc.stdout,
+		Stderr:                  goTestProc.stderr,
+		Handler:                 handler,
+		Stop:                    cancel,
+		CaptureDiagnostics:      opts.captureDiagnostics,
+		StrictJSON:              opts.strictJSON != "",
+		StrictOutputAttribution: opts.strictOutputAttribution,
+		ScrubOutput:             newScrubber(opts, os.Environ()),
 	}
 	exec, err := testjson.ScanTestOutput(cfg)
 	if err != nil {
@@ -95,6 +152,31 @@ func runSingle(opts *options, dir string) (*testjson.Execution, error) {
 	return exec, finishRun(opts, exec, err)
 }
 
+// moduleAffectedPackages returns the packages in the module that are in
+// pkgDir, or that import the package in pkgDir. If no packages with tests are
+// affected, the path to pkgDir relative to the module directory is returned,
+// so that go test reports the package has no tests.
+func moduleAffectedPackages(mod goModule, pkgDir string) ([]string, error) {
+	pkgDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := goListTestPackagesInDir(mod.Dir, []string{"./..."}, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages in module %v: %w", mod.Path, err)
+	}
+	affected := packagesAffectedByDirs(pkgs, map[string]bool{pkgDir: true}, false)
+	if len(affected) > 0 {
+		log.Debugf("packages in %v affected by changes in %v: %v", mod.Path, pkgDir, affected)
+		return affected, nil
+	}
+	rel, err := filepath.Rel(mod.Dir, pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	return []string{"./" + filepath.ToSlash(rel)}, nil
+}
+
 func delveInitFile(exec *testjson.Execution) (string, func(), error) {
 	fh, err := ioutil.TempFile("", "gotestsum-delve-init")
 	if err != nil {
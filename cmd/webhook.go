@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gotest.tools/gotestsum/internal/textreport"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// webhookEvent is a point in the test run when the webhook is sent.
+type webhookEvent string
+
+const (
+	webhookOnStart      webhookEvent = "start"
+	webhookOnFailure    webhookEvent = "failure"
+	webhookOnCompletion webhookEvent = "completion"
+	webhookOnFlaky      webhookEvent = "flaky"
+)
+
+var webhookEvents = []webhookEvent{webhookOnStart, webhookOnFailure, webhookOnCompletion, webhookOnFlaky}
+
+// webhookEventsValue is a flag.Value for the comma separated list of events
+// that send the webhook.
+type webhookEventsValue struct {
+	events map[webhookEvent]bool
+}
+
+func (v *webhookEventsValue) Set(raw string) error {
+	items, err := readAsCSV(raw)
+	if err != nil {
+		return err
+	}
+	events := make(map[webhookEvent]bool)
+	for _, item := range items {
+		event := webhookEvent(strings.TrimSpace(item))
+		if !isWebhookEvent(event) {
+			return fmt.Errorf("invalid webhook event %q, must be one of: %v",
+				item, strings.Join(webhookEventNames(), ", "))
+		}
+		events[event] = true
+	}
+	v.events = events
+	return nil
+}
+
+func isWebhookEvent(event webhookEvent) bool {
+	for _, e := range webhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookEventNames() []string {
+	names := make([]string, 0, len(webhookEvents))
+	for _, e := range webhookEvents {
+		names = append(names, string(e))
+	}
+	return names
+}
+
+func (v *webhookEventsValue) String() string {
+	var names []string
+	for event := range v.enabled() {
+		names = append(names, string(event))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (v *webhookEventsValue) Type() string {
+	return "events"
+}
+
+func (v *webhookEventsValue) enabled() map[webhookEvent]bool {
+	if v.events == nil {
+		return map[webhookEvent]bool{webhookOnCompletion: true}
+	}
+	return v.events
+}
+
+// webhookPayload is the data used to execute the --webhook-template.
+type webhookPayload struct {
+	textreport.Report
+	// Event is the name of the webhookEvent that sent the webhook.
+	Event string
+}
+
+// defaultWebhookTemplate is a payload that is accepted by Slack incoming
+// webhooks, with the counts, the requirements and their URLs, and the git
+// commit, as extra fields for other services.
+var defaultWebhookTemplate = template.Must(textreport.Parse("webhook", `{
+  "text": {{ if eq .Event "start" }}{{ json "gotestsum: tests started" }}{{ else -}}
+    {{ json (printf "gotestsum: %s: %d tests, %d failed, %d skipped, %d flaky in %ss" .Event .Total .Failed .Skipped (len .Flaky) (seconds .Elapsed)) }}{{ end }},
+  "event": {{ json .Event }},
+  "total": {{ .Total }},
+  "failed": {{ .Failed }},
+  "skipped": {{ .Skipped }},
+  "flaky": {{ len .Flaky }}
+  {{- if .Requirements }},
+  "requirements": {{ json .Requirements }}
+  {{- end }}
+  {{- if .VCS }},
+  "vcs": {{ json .VCS }}
+  {{- end }}
+}
+`))
+
+// webhookPublisher is a publisher that sends an HTTP POST request to the
+// --webhook-url, with a payload created from the --webhook-template.
+type webhookPublisher struct {
+	opts    *options
+	payload webhookPayload
+}
+
+func (p webhookPublisher) name() string {
+	return "webhook " + p.payload.Event
+}
+
+func (p webhookPublisher) publish(_ []byte, _ int) error {
+	tmpl := p.opts.webhookTemplate.tmpl
+	if tmpl == nil {
+		tmpl = defaultWebhookTemplate
+	}
+	tmpl, err := textreport.WithPackageName(tmpl, p.opts.formatOptions.PackageName)
+	if err != nil {
+		return err
+	}
+	body := new(bytes.Buffer)
+	if err := tmpl.Execute(body, p.payload); err != nil {
+		return fmt.Errorf("failed to execute webhook template: %w", err)
+	}
+	return postJSON(p.opts.webhookURL, body.Bytes())
+}
+
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+func postJSON(url string, body []byte) error {
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%v: %v", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// webhookStart sends the start webhook, when it is enabled. The result is
+// printed with the results of the other publishers at the end of the run.
+func webhookStart(opts *options) {
+	if opts.webhookURL == "" || !opts.webhookOn.enabled()[webhookOnStart] {
+		return
+	}
+	pub := webhookPublisher{opts: opts, payload: webhookPayload{
+		Report: textreport.Report{
+			Environment: runEnvironment(opts),
+			Labels:      opts.labels.value(),
+			VCS:         vcs(opts).report(),
+		},
+		Event: string(webhookOnStart),
+	}}
+	opts.publishResults = append(opts.publishResults, runPublisher(pub, nil, opts.publisherRetries))
+}
+
+// webhookPublishers returns a webhookPublisher for each enabled event that
+// happened in the test run.
+func webhookPublishers(opts *options, exec *testjson.Execution, exitErr error) []publisher {
+	if opts.webhookURL == "" {
+		return nil
+	}
+	report := textreport.New(exec, textreport.Config{
+		Environment:            runEnvironment(opts),
+		Labels:                 opts.labels.value(),
+		RequirementURLTemplate: opts.requirementURLTemplate,
+		VCS:                    vcs(opts).report(),
+	})
+	happened := map[webhookEvent]bool{
+		webhookOnFailure:    exitErr != nil,
+		webhookOnCompletion: true,
+		webhookOnFlaky:      len(report.Flaky) > 0,
+	}
+	var result []publisher
+	for _, event := range webhookEvents {
+		if !happened[event] || !opts.webhookOn.enabled()[event] {
+			continue
+		}
+		result = append(result, webhookPublisher{
+			opts:    opts,
+			payload: webhookPayload{Report: report, Event: string(event)},
+		})
+	}
+	return result
+}
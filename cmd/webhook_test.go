@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/textreport"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestWebhookEventsValue(t *testing.T) {
+	var v webhookEventsValue
+	assert.Equal(t, v.String(), "completion")
+	assert.NilError(t, v.Set("start,flaky"))
+	assert.Equal(t, v.String(), "flaky,start")
+	assert.ErrorContains(t, v.Set("start,done"),
+		`invalid webhook event "done", must be one of: start, failure, completion, flaky`)
+}
+
+func TestWebhookPublishers(t *testing.T) {
+	patchPublisherRetryDelay(t)
+	patchGoEnvironment(t, goEnvironment{Version: "go7.7.7"})
+	patchVCS(t, nil)
+
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("Content-Type"), "application/json")
+		raw, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		bodies = append(bodies, string(raw))
+	}))
+	defer srv.Close()
+
+	tmpl := fs.NewFile(t, "payload.tmpl", fs.WithContent(
+		`{"content": {{ json (printf "%s %d/%d" .Event .Failed .Total) }}, "flaky": [{{ range $i, $t := .Flaky }}{{ if $i }},{{ end }}{{ json $t.Name }}{{ end }}]}`))
+	out := new(bytes.Buffer)
+	opts := &options{stdout: out, stderr: out, webhookURL: srv.URL}
+	assert.NilError(t, opts.webhookTemplate.Set(tmpl.Path()))
+	assert.NilError(t, opts.webhookOn.Set("start,failure,completion,flaky"))
+
+	webhookStart(opts)
+	exec := scanFlakyRun(t)
+	assert.NilError(t, runPublishers(opts, exec, errors.New("exit status 1")))
+
+	expected := []string{
+		`{"content": "start 0/0", "flaky": []}`,
+		`{"content": "failure 1/3", "flaky": ["TestFlaky"]}`,
+		`{"content": "completion 1/3", "flaky": ["TestFlaky"]}`,
+		`{"content": "flaky 1/3", "flaky": ["TestFlaky"]}`,
+	}
+	assert.DeepEqual(t, bodies, expected)
+	assert.Equal(t, out.String(), `
+=== Publish: 4 publishers, 0 failed
+=== PUBLISHED: webhook start (1 attempt)
+=== PUBLISHED: webhook failure (1 attempt)
+=== PUBLISHED: webhook completion (1 attempt)
+=== PUBLISHED: webhook flaky (1 attempt)
+`)
+}
+
+func TestWebhookPublishers_DefaultTemplate(t *testing.T) {
+	patchPublisherRetryDelay(t)
+	patchGoEnvironment(t, goEnvironment{Version: "go7.7.7"})
+	patchVCS(t, nil)
+
+	var requests int
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		raw, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		body = string(raw)
+	}))
+	defer srv.Close()
+
+	out := new(bytes.Buffer)
+	opts := &options{stdout: out, stderr: out, webhookURL: srv.URL, publisherRetries: 1}
+	webhookStart(opts)
+	assert.Equal(t, requests, 0, "start is not enabled by default")
+
+	exec := scanFlakyRun(t)
+	assert.NilError(t, runPublishers(opts, exec, nil))
+	assert.Equal(t, requests, 2)
+	assert.Assert(t, strings.Contains(body, `"event": "completion",`), body)
+	assert.Assert(t, strings.Contains(body, `"flaky": 1`), body)
+	assert.Assert(t, strings.Contains(out.String(), "=== PUBLISHED: webhook completion (2 attempts)"), out.String())
+}
+
+func TestWebhookPublishers_VCS(t *testing.T) {
+	patchPublisherRetryDelay(t)
+	patchGoEnvironment(t, goEnvironment{Version: "go7.7.7"})
+	patchVCS(t, &vcsInfo{Commit: "abc123", Branch: "main"})
+
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		bodies = append(bodies, string(raw))
+	}))
+	defer srv.Close()
+
+	tmpl := fs.NewFile(t, "payload.tmpl", fs.WithContent(
+		`{"event": {{ json .Event }}, "commit": {{ json .VCS.Commit }}, "branch": {{ json .VCS.Branch }}}`))
+	out := new(bytes.Buffer)
+	opts := &options{stdout: out, stderr: out, webhookURL: srv.URL}
+	assert.NilError(t, opts.webhookTemplate.Set(tmpl.Path()))
+	assert.NilError(t, opts.webhookOn.Set("start,completion"))
+
+	webhookStart(opts)
+	assert.NilError(t, runPublishers(opts, scanFlakyRun(t), nil))
+	expected := []string{
+		`{"event": "start", "commit": "abc123", "branch": "main"}`,
+		`{"event": "completion", "commit": "abc123", "branch": "main"}`,
+	}
+	assert.DeepEqual(t, bodies, expected)
+
+	bodies = nil
+	opts = &options{stdout: out, stderr: out, webhookURL: srv.URL}
+	assert.NilError(t, runPublishers(opts, scanFlakyRun(t), nil))
+	assert.Equal(t, len(bodies), 1)
+	assert.Assert(t, strings.Contains(bodies[0], `"vcs": {"commit":"abc123","branch":"main","dirty":false}`), bodies[0])
+}
+
+func scanFlakyRun(t *testing.T) *testjson.Execution {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"pass","Package":"pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"run","Package":"pkg","Test":"TestFlaky"}
+{"Action":"fail","Package":"pkg","Test":"TestFlaky","Elapsed":0.1}
+{"Action":"fail","Package":"pkg","Elapsed":0.2}
+`),
+	})
+	assert.NilError(t, err)
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"pkg","Test":"TestFlaky","Elapsed":0.1}
+{"Action":"pass","Package":"pkg","Elapsed":0.2}
+`),
+		Execution: exec,
+		RunID:     1,
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestDefaultWebhookTemplate_Requirements(t *testing.T) {
+	payload := webhookPayload{Event: "completion", Report: textreport.Report{
+		Requirements: []textreport.Requirement{
+			{ID: "REQ-1", URL: "https://jira.example.com/browse/REQ-1", Failed: 1},
+		},
+	}}
+	out := new(bytes.Buffer)
+	assert.NilError(t, defaultWebhookTemplate.Execute(out, payload))
+
+	var body struct {
+		Requirements []textreport.Requirement `json:"requirements"`
+	}
+	assert.NilError(t, json.Unmarshal(out.Bytes(), &body), out.String())
+	assert.DeepEqual(t, body.Requirements, payload.Requirements)
+}
@@ -0,0 +1,38 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"log"
+	"os"
+	"os/exec"
+)
+
+// toastScript shows a toast notification using the Windows Runtime API from
+// PowerShell. The text is read from environment variables so that it does not
+// need to be quoted. The notification is sent with the application ID of
+// PowerShell, because an application ID must be registered to show a toast.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:GOTESTSUM_NOTIFY_TITLE)) | Out-Null
+$text.Item(1).AppendChild($template.CreateTextNode($env:GOTESTSUM_NOTIFY_SUBTITLE)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+$toast.Tag = 'gotestsum'
+$appID = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($appID).Show($toast)
+`
+
+// notify shows a desktop toast notification.
+func notify(title, subtitle string) error {
+	log.Printf("toast notification %q %q", title, subtitle)
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(),
+		"GOTESTSUM_NOTIFY_TITLE="+title,
+		"GOTESTSUM_NOTIFY_SUBTITLE="+subtitle)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+func main() {
+	total := envInt("TOTAL")
+	skipped := envInt("SKIPPED")
+	failed := envInt("FAILED")
+	errors := envInt("ERRORS")
+
+	emoji := "✅"
+	title := "Passed"
+	switch {
+	case errors > 0:
+		emoji = "⚠️"
+		title = "Errored"
+	case failed > 0:
+		emoji = "❌"
+		title = "Failed"
+	case skipped > 0:
+		title = "Passed with skipped"
+	}
+
+	subtitle := fmt.Sprintf("%d Tests Run", total)
+	if errors > 0 {
+		subtitle += fmt.Sprintf(", %d Errored", errors)
+	}
+	if failed > 0 {
+		subtitle += fmt.Sprintf(", %d Failed", failed)
+	}
+	if skipped > 0 {
+		subtitle += fmt.Sprintf(", %d Skipped", skipped)
+	}
+
+	if err := notify(emoji+" "+title, subtitle); err != nil {
+		log.Fatalf("Failed to exec: %v", err)
+	}
+}
+
+func envInt(name string) int {
+	val := os.Getenv("TESTS_" + name)
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0
+	}
+	return n
+}
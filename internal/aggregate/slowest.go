@@ -32,6 +32,36 @@ func Slowest(exec *testjson.Execution, threshold time.Duration) []testjson.TestC
 	return tests[:end]
 }
 
+// Names of the pseudo test cases returned by SetupTeardown.
+const (
+	SetupTestName    testjson.TestName = "(setup)"
+	TeardownTestName testjson.TestName = "(teardown)"
+)
+
+// SetupTeardown returns a pseudo test case for the time spent in TestMain
+// before the first test (SetupTestName), and after the last test
+// (TeardownTestName), of each package where the time is greater than
+// threshold. The slice is sorted by Elapsed time in descending order.
+func SetupTeardown(exec *testjson.Execution, threshold time.Duration) []testjson.TestCase {
+	if threshold == 0 {
+		return nil
+	}
+	var result []testjson.TestCase
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if d := pkg.Setup(); testjson.IsSlowSetup(d, threshold) {
+			result = append(result, testjson.TestCase{Package: name, Test: SetupTestName, Elapsed: d})
+		}
+		if d := pkg.Teardown(); testjson.IsSlowSetup(d, threshold) {
+			result = append(result, testjson.TestCase{Package: name, Test: TeardownTestName, Elapsed: d})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Elapsed > result[j].Elapsed
+	})
+	return result
+}
+
 // ByElapsed maps all test cases by name, and if there is more than one
 // instance of a TestCase, uses fn to select the elapsed time for the group.
 //
@@ -61,3 +61,24 @@ func TestMedian(t *testing.T) {
 		})
 	}
 }
+
+func TestSetupTeardown(t *testing.T) {
+	input := `{"Time":"2023-01-01T00:00:00Z","Action":"start","Package":"pkg/slow"}
+{"Time":"2023-01-01T00:00:03Z","Action":"run","Package":"pkg/slow","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:04Z","Action":"pass","Package":"pkg/slow","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:06Z","Action":"pass","Package":"pkg/slow","Elapsed":6}
+{"Time":"2023-01-01T00:00:00Z","Action":"start","Package":"pkg/fast"}
+{"Time":"2023-01-01T00:00:00.1Z","Action":"run","Package":"pkg/fast","Test":"TestTwo"}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/fast","Test":"TestTwo","Elapsed":0.9}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/fast","Elapsed":1}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	actual := SetupTeardown(exec, time.Second)
+	expected := []testjson.TestCase{
+		{Package: "pkg/slow", Test: SetupTestName, Elapsed: 3 * time.Second},
+		{Package: "pkg/slow", Test: TeardownTestName, Elapsed: 2 * time.Second},
+	}
+	assert.DeepEqual(t, actual, expected, cmpopts.IgnoreUnexported(testjson.TestCase{}))
+}
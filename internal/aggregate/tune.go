@@ -0,0 +1,152 @@
+package aggregate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Settings are the values of the go test -p and -parallel flags.
+type Settings struct {
+	// P is the number of test binaries that run at the same time.
+	P int
+	// Parallel is the number of parallel tests that run at the same time in a
+	// test binary.
+	Parallel int
+}
+
+// Tuning is a recommendation for the go test -p and -parallel flags, based on
+// the times of the tests in a run.
+type Tuning struct {
+	// Current are the settings used for the run.
+	Current     Settings
+	Recommended Settings
+	// PReason and ParallelReason explain the recommended values.
+	PReason        string
+	ParallelReason string
+}
+
+// maxTuneFactor is the most that Tune increases a setting by in one step,
+// because the tests may use more CPU and memory when more of them run at the
+// same time.
+const maxTuneFactor = 2
+
+// Tune returns the recommended -p and -parallel values for the tests in exec,
+// which were run with the current settings. A setting is only increased when
+// the run was limited by the current value. Settings are never decreased,
+// because a run that did not reach a limit is not faster with a lower limit.
+func Tune(exec *testjson.Execution, current Settings) Tuning {
+	tuning := Tuning{Current: current, Recommended: current}
+	tuning.Recommended.P, tuning.PReason = tuneP(exec, current.P)
+	tuning.Recommended.Parallel, tuning.ParallelReason = tuneParallel(exec, current.Parallel)
+	return tuning
+}
+
+// tuneP recommends a -p value. Packages are limited by -p when -p packages
+// were running at the same time and there were more packages to run. The run
+// can not be faster than the slowest package, so -p is not increased past the
+// total test time of the packages divided by the time of the slowest package.
+func tuneP(exec *testjson.Execution, p int) (int, string) {
+	var spans []Span
+	var total, slowest time.Duration
+	for _, name := range exec.Packages() {
+		span, ok := packageSpan(exec.Package(name))
+		if !ok {
+			continue
+		}
+		spans = append(spans, span)
+		elapsed := span.End.Sub(span.Start)
+		total += elapsed
+		if elapsed > slowest {
+			slowest = elapsed
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].Start.Before(spans[j].Start)
+	})
+
+	peak := maxConcurrent(spans)
+	switch {
+	case len(spans) == 0:
+		return p, "the run has no test times"
+	case len(spans) <= p:
+		return p, fmt.Sprintf("the run has %d packages with tests, not more than -p %d", len(spans), p)
+	case peak < p:
+		return p, fmt.Sprintf("at most %d packages ran at the same time, less than -p %d", peak, p)
+	}
+
+	recommended := int(math.Ceil(float64(total) / float64(slowest)))
+	if limit := p * maxTuneFactor; recommended > limit {
+		recommended = limit
+	}
+	if recommended <= p {
+		return p, fmt.Sprintf("the slowest package took %s, which limits the run more than -p %d",
+			testjson.FormatDurationAsSeconds(slowest, 2), p)
+	}
+	return recommended, fmt.Sprintf("the run reached the limit of -p %d packages at the same time, "+
+		"and the tests of the %d packages took %s, with %s in the slowest package",
+		p, len(spans), testjson.FormatDurationAsSeconds(total, 2),
+		testjson.FormatDurationAsSeconds(slowest, 2))
+}
+
+// packageSpan returns the time from the start of the first test in the
+// package, to the end of the last test.
+func packageSpan(pkg *testjson.Package) (Span, bool) {
+	spans := Spans(pkg.TestCases())
+	if len(spans) == 0 {
+		return Span{}, false
+	}
+	span := spans[0]
+	for _, s := range spans[1:] {
+		if s.End.After(span.End) {
+			span.End = s.End
+		}
+	}
+	return span, true
+}
+
+// tuneParallel recommends a -parallel value. A package is limited by
+// -parallel when -parallel of its parallel tests were running at the same
+// time and the package has more parallel tests. The recommended value is the
+// number of parallel tests in the package with the most parallel tests.
+func tuneParallel(exec *testjson.Execution, parallel int) (int, string) {
+	var limited string
+	var most int
+	for _, name := range exec.Packages() {
+		var spans []Span
+		names := make(map[testjson.TestName]bool)
+		for _, tc := range exec.Package(name).TestCases() {
+			if !tc.Parallel {
+				continue
+			}
+			// a test run more than once, with -count, is only counted once
+			names[tc.Test] = true
+			if start, end := tc.Span(); !start.IsZero() {
+				spans = append(spans, Span{Start: start, End: end})
+			}
+		}
+		if len(names) <= parallel || len(names) <= most {
+			continue
+		}
+		sort.Slice(spans, func(i, j int) bool {
+			return spans[i].Start.Before(spans[j].Start)
+		})
+		if maxConcurrent(spans) >= parallel {
+			limited, most = name, len(names)
+		}
+	}
+	if limited == "" {
+		return parallel, fmt.Sprintf("no package had more than -parallel %d parallel tests waiting to run", parallel)
+	}
+
+	recommended := most
+	if limit := parallel * maxTuneFactor; recommended > limit {
+		recommended = limit
+	}
+	return recommended, fmt.Sprintf("%s reached the limit of -parallel %d tests at the same time, "+
+		"and has %d parallel tests",
+		testjson.RelativePackagePath(limited), parallel, most)
+}
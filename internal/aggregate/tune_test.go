@@ -0,0 +1,122 @@
+package aggregate
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+// Four packages run two at a time, and each has a test that takes 1s. The
+// four parallel tests in pkg/a run two at a time.
+const tuneInput = `{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:00Z","Action":"pause","Package":"pkg/a","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/a","Test":"TestTwo"}
+{"Time":"2023-01-01T00:00:00Z","Action":"pause","Package":"pkg/a","Test":"TestTwo"}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/a","Test":"TestThree"}
+{"Time":"2023-01-01T00:00:00Z","Action":"pause","Package":"pkg/a","Test":"TestThree"}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/a","Test":"TestFour"}
+{"Time":"2023-01-01T00:00:00Z","Action":"pause","Package":"pkg/a","Test":"TestFour"}
+{"Time":"2023-01-01T00:00:00Z","Action":"cont","Package":"pkg/a","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:00Z","Action":"cont","Package":"pkg/a","Test":"TestTwo"}
+{"Time":"2023-01-01T00:00:00.5Z","Action":"pass","Package":"pkg/a","Test":"TestOne","Elapsed":0.5}
+{"Time":"2023-01-01T00:00:00.5Z","Action":"pass","Package":"pkg/a","Test":"TestTwo","Elapsed":0.5}
+{"Time":"2023-01-01T00:00:00.5Z","Action":"cont","Package":"pkg/a","Test":"TestThree"}
+{"Time":"2023-01-01T00:00:00.5Z","Action":"cont","Package":"pkg/a","Test":"TestFour"}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/a","Test":"TestThree","Elapsed":0.5}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/a","Test":"TestFour","Elapsed":0.5}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/a","Elapsed":1}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/b","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/b","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/b","Elapsed":1}
+{"Time":"2023-01-01T00:00:01Z","Action":"run","Package":"pkg/c","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/c","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/c","Elapsed":1}
+{"Time":"2023-01-01T00:00:01Z","Action":"run","Package":"pkg/d","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/d","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/d","Elapsed":1}
+`
+
+func TestTune(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(tuneInput)})
+	assert.NilError(t, err)
+
+	type testCase struct {
+		name     string
+		current  Settings
+		expected Tuning
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		assert.DeepEqual(t, Tune(exec, tc.current), tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name:    "limited by both",
+			current: Settings{P: 2, Parallel: 2},
+			expected: Tuning{
+				Current:     Settings{P: 2, Parallel: 2},
+				Recommended: Settings{P: 4, Parallel: 4},
+				PReason: "the run reached the limit of -p 2 packages at the same time, " +
+					"and the tests of the 4 packages took 4.00s, with 1.00s in the slowest package",
+				ParallelReason: "pkg/a reached the limit of -parallel 2 tests at the same time, " +
+					"and has 4 parallel tests",
+			},
+		},
+		{
+			name:    "increase is limited",
+			current: Settings{P: 1, Parallel: 1},
+			expected: Tuning{
+				Current:     Settings{P: 1, Parallel: 1},
+				Recommended: Settings{P: 2, Parallel: 2},
+				PReason: "the run reached the limit of -p 1 packages at the same time, " +
+					"and the tests of the 4 packages took 4.00s, with 1.00s in the slowest package",
+				ParallelReason: "pkg/a reached the limit of -parallel 1 tests at the same time, " +
+					"and has 4 parallel tests",
+			},
+		},
+		{
+			name:    "not limited",
+			current: Settings{P: 4, Parallel: 8},
+			expected: Tuning{
+				Current:        Settings{P: 4, Parallel: 8},
+				Recommended:    Settings{P: 4, Parallel: 8},
+				PReason:        "the run has 4 packages with tests, not more than -p 4",
+				ParallelReason: "no package had more than -parallel 8 parallel tests waiting to run",
+			},
+		},
+		{
+			name:    "not at the limit",
+			current: Settings{P: 3, Parallel: 3},
+			expected: Tuning{
+				Current:        Settings{P: 3, Parallel: 3},
+				Recommended:    Settings{P: 3, Parallel: 3},
+				PReason:        "at most 2 packages ran at the same time, less than -p 3",
+				ParallelReason: "no package had more than -parallel 3 parallel tests waiting to run",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestTune_SlowestPackage(t *testing.T) {
+	input := `{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/b","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:01Z","Action":"pass","Package":"pkg/b","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:01Z","Action":"run","Package":"pkg/c","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/c","Test":"TestOne","Elapsed":1}
+{"Time":"2023-01-01T00:00:04Z","Action":"pass","Package":"pkg/a","Test":"TestOne","Elapsed":4}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	actual := Tune(exec, Settings{P: 2, Parallel: 4})
+	assert.Equal(t, actual.Recommended, Settings{P: 2, Parallel: 4})
+	assert.Equal(t, actual.PReason, "the slowest package took 4.00s, which limits the run more than -p 2")
+}
@@ -0,0 +1,127 @@
+package aggregate
+
+import (
+	"sort"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Utilization of the time spent running tests. It shows how much of the wall
+// time was spent running tests, and how many tests ran at the same time.
+type Utilization struct {
+	// Wall is the time from the start of the first test to the end of the
+	// last test.
+	Wall time.Duration
+	// TestTime is the sum of the elapsed time of all the top-level tests.
+	TestTime time.Duration
+	// Idle is the part of Wall when no tests were running.
+	Idle time.Duration
+	// Parallelism is the average number of tests running at the same time,
+	// TestTime divided by Wall.
+	Parallelism float64
+	// MaxParallelism is the largest number of tests that were running at the
+	// same time.
+	MaxParallelism int
+}
+
+// Span is the time a top-level test was running.
+type Span struct {
+	Start time.Time
+	End   time.Time
+}
+
+// PackageUtilization returns the Utilization of the tests in the package.
+func PackageUtilization(pkg *testjson.Package) Utilization {
+	return newUtilization(Spans(pkg.TestCases()))
+}
+
+// ExecutionUtilization returns the Utilization of the tests in all the
+// packages. Tests in different packages run at the same time when go test
+// runs more than one test binary (-p).
+func ExecutionUtilization(exec *testjson.Execution) Utilization {
+	var spans []Span
+	for _, name := range exec.Packages() {
+		spans = append(spans, Spans(exec.Package(name).TestCases())...)
+	}
+	return newUtilization(spans)
+}
+
+// Spans returns the span of each top-level test in tcs, sorted by the start
+// time. Subtests are omitted because their time is included in the top-level
+// test. Tests without a time are also omitted.
+func Spans(tcs []testjson.TestCase) []Span {
+	spans := make([]Span, 0, len(tcs))
+	for _, tc := range tcs {
+		if tc.Test.IsSubTest() {
+			continue
+		}
+		start, end := tc.Span()
+		if start.IsZero() {
+			continue
+		}
+		spans = append(spans, Span{Start: start, End: end})
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].Start.Before(spans[j].Start)
+	})
+	return spans
+}
+
+func newUtilization(spans []Span) Utilization {
+	if len(spans) == 0 {
+		return Utilization{}
+	}
+	var u Utilization
+	start, end := spans[0].Start, spans[0].End
+	busyEnd := spans[0].Start
+	var busy time.Duration
+	for _, span := range spans {
+		u.TestTime += span.End.Sub(span.Start)
+		if span.End.After(end) {
+			end = span.End
+		}
+		switch {
+		case span.Start.After(busyEnd):
+			busy += span.End.Sub(span.Start)
+			busyEnd = span.End
+		case span.End.After(busyEnd):
+			busy += span.End.Sub(busyEnd)
+			busyEnd = span.End
+		}
+	}
+	u.Wall = end.Sub(start)
+	u.Idle = u.Wall - busy
+	if u.Wall > 0 {
+		u.Parallelism = float64(u.TestTime) / float64(u.Wall)
+	}
+	u.MaxParallelism = maxConcurrent(spans)
+	return u
+}
+
+// maxConcurrent returns the largest number of spans that overlap at any time.
+func maxConcurrent(spans []Span) int {
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	edges := make([]edge, 0, len(spans)*2)
+	for _, span := range spans {
+		edges = append(edges, edge{at: span.Start, delta: 1}, edge{at: span.End, delta: -1})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at.Equal(edges[j].at) {
+			// end a span before starting the next one at the same time
+			return edges[i].delta < edges[j].delta
+		}
+		return edges[i].at.Before(edges[j].at)
+	})
+	var current, result int
+	for _, e := range edges {
+		current += e.delta
+		if current > result {
+			result = current
+		}
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package aggregate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+// Two parallel tests in pkg/a overlap for 1s, followed by 1s with no tests
+// running, and a test in pkg/b runs at the same time as pkg/a.
+const utilizationInput = `{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/a","Test":"TestTwo"}
+{"Time":"2023-01-01T00:00:00Z","Action":"run","Package":"pkg/b","Test":"TestThree"}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/a","Test":"TestOne","Elapsed":2}
+{"Time":"2023-01-01T00:00:02Z","Action":"pass","Package":"pkg/b","Test":"TestThree","Elapsed":2}
+{"Time":"2023-01-01T00:00:03Z","Action":"pass","Package":"pkg/a","Test":"TestTwo","Elapsed":2}
+{"Time":"2023-01-01T00:00:04Z","Action":"run","Package":"pkg/a","Test":"TestFour"}
+{"Time":"2023-01-01T00:00:05Z","Action":"pass","Package":"pkg/a","Test":"TestFour","Elapsed":1}
+{"Time":"2023-01-01T00:00:05Z","Action":"pass","Package":"pkg/a","Elapsed":5}
+{"Time":"2023-01-01T00:00:05Z","Action":"pass","Package":"pkg/b","Elapsed":2}
+`
+
+func TestPackageUtilization(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(utilizationInput)})
+	assert.NilError(t, err)
+
+	actual := PackageUtilization(exec.Package("pkg/a"))
+	expected := Utilization{
+		Wall:           5 * time.Second,
+		TestTime:       5 * time.Second,
+		Idle:           time.Second,
+		Parallelism:    1,
+		MaxParallelism: 2,
+	}
+	assert.DeepEqual(t, actual, expected)
+}
+
+func TestExecutionUtilization(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(utilizationInput)})
+	assert.NilError(t, err)
+
+	actual := ExecutionUtilization(exec)
+	expected := Utilization{
+		Wall:           5 * time.Second,
+		TestTime:       7 * time.Second,
+		Idle:           time.Second,
+		Parallelism:    1.4,
+		MaxParallelism: 3,
+	}
+	assert.DeepEqual(t, actual, expected)
+}
+
+func TestExecutionUtilization_NoTimes(t *testing.T) {
+	input := `{"Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Action":"pass","Package":"pkg/a","Test":"TestOne","Elapsed":2}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ExecutionUtilization(exec), Utilization{})
+}
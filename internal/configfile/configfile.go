@@ -0,0 +1,292 @@
+/*
+Package configfile reads and writes the .gotestsum.yaml config file, which
+sets the default value of flags.
+*/
+package configfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Entry is a top-level key of the config file, and its values.
+type Entry struct {
+	Name string
+	// Values of the key. The values of a mapping are key=value.
+	Values []string
+	// Line is the line number of the key, starting at 1.
+	Line int
+	// EndLine is the line number of the last line of the values, which is
+	// the same as Line when the values are on the same line as the key.
+	EndLine int
+}
+
+// Parse parses the subset of YAML used by the config file: a mapping of keys
+// to scalar values, to a list of scalar values, or to a mapping of keys to
+// scalars or lists. Lists and mappings may use the block or the flow style.
+// The values of a mapping are returned as key=value.
+func Parse(raw []byte) ([]Entry, error) {
+	var entries []Entry
+	// nestedKey is the key of the mapping value that is being parsed, and
+	// nestedIndent is the indentation of the key.
+	var nestedKey string
+	var nestedIndent int
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(stripConfigComment(scanner.Text()), " \t")
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		isListItem := strings.HasPrefix(trimmed, "- ") || trimmed == "-"
+		switch {
+		case trimmed == "" || trimmed == "---":
+			continue
+		case isListItem:
+			if len(entries) == 0 || indent == 0 || (nestedKey != "" && indent <= nestedIndent) {
+				return nil, fmt.Errorf("line %d: list item without a key", lineNum)
+			}
+			value, err := configValue(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			if nestedKey != "" {
+				value = nestedKey + "=" + value
+			}
+			last := &entries[len(entries)-1]
+			last.Values = append(last.Values, value)
+			last.EndLine = lineNum
+			continue
+		case indent > 0:
+			if len(entries) == 0 {
+				return nil, fmt.Errorf("line %d: indented key without a parent key", lineNum)
+			}
+			last := &entries[len(entries)-1]
+			if (nestedKey == "" && len(last.Values) > 0) || (nestedKey != "" && indent > nestedIndent) {
+				return nil, fmt.Errorf("line %d: nested values are not supported", lineNum)
+			}
+			key, values, err := parseConfigLine(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			nestedKey, nestedIndent = key, indent
+			for _, value := range values {
+				last.Values = append(last.Values, key+"="+value)
+			}
+			last.EndLine = lineNum
+			continue
+		}
+
+		nestedKey = ""
+		key, values, err := parseConfigLine(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		entries = append(entries, Entry{Name: key, Values: values, Line: lineNum, EndLine: lineNum})
+	}
+	return entries, scanner.Err()
+}
+
+// parseConfigLine parses a line with a key and an optional value.
+func parseConfigLine(line string) (string, []string, error) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return "", nil, fmt.Errorf("expected key: value")
+	}
+	key := strings.TrimSpace(parts[0])
+	rest := strings.TrimSpace(parts[1])
+	if rest == "" {
+		return key, nil, nil
+	}
+	values, err := configValues(rest)
+	return key, values, err
+}
+
+// configValues parses a scalar value, or a flow style list or mapping, like
+// [a, b] or {key: [a, b]}.
+func configValues(value string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(value, "["):
+		if !strings.HasSuffix(value, "]") {
+			return nil, fmt.Errorf("missing closing bracket in %v", value)
+		}
+		var values []string
+		for _, item := range splitFlowItems(value[1 : len(value)-1]) {
+			v, err := configValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case strings.HasPrefix(value, "{"):
+		if !strings.HasSuffix(value, "}") {
+			return nil, fmt.Errorf("missing closing brace in %v", value)
+		}
+		var values []string
+		for _, item := range splitFlowItems(value[1 : len(value)-1]) {
+			key, items, err := parseConfigLine(item)
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range items {
+				values = append(values, key+"="+v)
+			}
+		}
+		return values, nil
+	}
+	v, err := configValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return []string{v}, nil
+}
+
+// splitFlowItems splits the items of a flow style list or mapping at the
+// commas that are not in a quoted value, or in a nested list.
+func splitFlowItems(value string) []string {
+	var items []string
+	var quote byte
+	var depth, start int
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+	items = append(items, value[start:])
+
+	result := items[:0]
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// stripConfigComment removes a comment that starts with a # at the start of
+// the line, or after a space, and is not in a quoted value.
+func stripConfigComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func configValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("missing closing quote in %v", value)
+		}
+		return strings.Replace(value[1:len(value)-1], "''", "'", -1), nil
+	}
+	return value, nil
+}
+
+// Set returns the config file with the value of the top-level key replaced by
+// value, or with the key added to the end of the file when it is not set. All
+// the lines of the old value are replaced by a single line, and other lines,
+// including comments, are not changed. An error is returned when the file can
+// not be parsed, or when Parse would not return value from the new file.
+func Set(raw []byte, key, value string) ([]byte, error) {
+	entries, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	if len(raw) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+	}
+	line := key + ": " + quoteValue(value)
+
+	var found bool
+	// the entries are changed from the end of the file, so that the line
+	// numbers of the earlier entries are still correct. Only the first entry
+	// of a key is kept, so that a later entry does not override the value.
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Name != key {
+			continue
+		}
+		found = true
+		var replacement []string
+		if isFirst(entries[:i], key) {
+			replacement = []string{line}
+		}
+		lines = replaceLines(lines, entry.Line-1, entry.EndLine, replacement)
+	}
+	if !found {
+		lines = append(lines, line)
+	}
+	result := []byte(strings.Join(lines, "\n") + "\n")
+
+	entries, err = Parse(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set %v: %w", key, err)
+	}
+	for _, entry := range entries {
+		if entry.Name == key && (len(entry.Values) != 1 || entry.Values[0] != value) {
+			return nil, fmt.Errorf("failed to set %v: the value %q would be read as %q", key, value, entry.Values)
+		}
+	}
+	return result, nil
+}
+
+// replaceLines returns lines with lines[start:end] replaced by replacement.
+func replaceLines(lines []string, start, end int, replacement []string) []string {
+	result := make([]string, 0, len(lines)-(end-start)+len(replacement))
+	result = append(result, lines[:start]...)
+	result = append(result, replacement...)
+	return append(result, lines[end:]...)
+}
+
+func isFirst(entries []Entry, key string) bool {
+	for _, entry := range entries {
+		if entry.Name == key {
+			return false
+		}
+	}
+	return true
+}
+
+var plainValue = regexp.MustCompile(`^[A-Za-z0-9._/+=:@%-]+$`)
+
+// quoteValue returns the value in double quotes, unless it is a plain scalar
+// that is read back unchanged, like a number or a path.
+func quoteValue(value string) string {
+	if plainValue.MatchString(value) {
+		return value
+	}
+	return strconv.Quote(value)
+}
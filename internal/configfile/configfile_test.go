@@ -0,0 +1,164 @@
+package configfile
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParse(t *testing.T) {
+	raw := `---
+# comment
+format: testname # trailing comment
+jsonfile: "out/test #1.json"
+post-run-command: 'notify ''me'''
+packages:
+  - ./cmd/...
+  - ./testjson
+rerun-fails:
+`
+	entries, err := Parse([]byte(raw))
+	assert.NilError(t, err)
+	expected := []Entry{
+		{Name: "format", Values: []string{"testname"}, Line: 3, EndLine: 3},
+		{Name: "jsonfile", Values: []string{"out/test #1.json"}, Line: 4, EndLine: 4},
+		{Name: "post-run-command", Values: []string{"notify 'me'"}, Line: 5, EndLine: 5},
+		{Name: "packages", Values: []string{"./cmd/...", "./testjson"}, Line: 6, EndLine: 8},
+		{Name: "rerun-fails", Line: 9, EndLine: 9},
+	}
+	assert.DeepEqual(t, entries, expected)
+}
+
+func TestParse_Mappings(t *testing.T) {
+	raw := `
+projects:
+  api:
+    - ./services/api/...
+    - ./lib/api
+  web: ./web/...
+packages: [./cmd/..., "./testjson"]
+package-timeout: {./slow/...: 10m, ./other: [1m, 2m]}
+`
+	entries, err := Parse([]byte(raw))
+	assert.NilError(t, err)
+	expected := []Entry{
+		{
+			Name:    "projects",
+			Values:  []string{"api=./services/api/...", "api=./lib/api", "web=./web/..."},
+			Line:    2,
+			EndLine: 6,
+		},
+		{Name: "packages", Values: []string{"./cmd/...", "./testjson"}, Line: 7, EndLine: 7},
+		{
+			Name:    "package-timeout",
+			Values:  []string{"./slow/...=10m", "./other=1m", "./other=2m"},
+			Line:    8,
+			EndLine: 8,
+		},
+	}
+	assert.DeepEqual(t, entries, expected)
+}
+
+func TestParse_Errors(t *testing.T) {
+	var testcases = []struct {
+		raw      string
+		expected string
+	}{
+		{raw: "- ./...\n", expected: "line 1: list item without a key"},
+		{raw: "  format: dots\n", expected: "line 1: indented key without a parent key"},
+		{raw: "# comment\n\n  format: dots\n", expected: "line 3: indented key without a parent key"},
+		{raw: "projects:\n  api:\n    nested: true\n", expected: "line 3: nested values are not supported"},
+		{raw: "packages:\n  - ./cmd\n  api: ./api\n", expected: "line 3: nested values are not supported"},
+		{raw: "projects:\n  api:\n  - ./api\n", expected: "line 3: list item without a key"},
+		{raw: "packages: [./cmd\n", expected: "line 1: missing closing bracket in [./cmd"},
+		{raw: "format testname\n", expected: "line 1: expected key: value"},
+		{raw: "format: 'testname\n", expected: "line 1: missing closing quote in 'testname"},
+	}
+	for _, tc := range testcases {
+		_, err := Parse([]byte(tc.raw))
+		assert.Error(t, err, tc.expected)
+	}
+}
+
+func TestSet(t *testing.T) {
+	type testCase struct {
+		name     string
+		raw      string
+		key      string
+		value    string
+		expected string
+	}
+	run := func(t *testing.T, tc testCase) {
+		actual, err := Set([]byte(tc.raw), tc.key, tc.value)
+		assert.NilError(t, err)
+		assert.Equal(t, string(actual), tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name:     "empty file",
+			key:      "go-test-p",
+			value:    "4",
+			expected: "go-test-p: 4\n",
+		},
+		{
+			name:     "replace a value with a comment",
+			raw:      "# defaults\nformat: dots\ngo-test-p: 2 # tuned\npackages: ./...\n",
+			key:      "go-test-p",
+			value:    "4",
+			expected: "# defaults\nformat: dots\ngo-test-p: 4\npackages: ./...\n",
+		},
+		{
+			name:     "add to the end",
+			raw:      "format: dots",
+			key:      "go-test-p",
+			value:    "4",
+			expected: "format: dots\ngo-test-p: 4\n",
+		},
+		{
+			name:     "replace a block list",
+			raw:      "go-test-p:\n  - 2\n  - 3\nformat: dots\n",
+			key:      "go-test-p",
+			value:    "4",
+			expected: "go-test-p: 4\nformat: dots\n",
+		},
+		{
+			name:     "replace a flow list",
+			raw:      "go-test-p: [2, 3]\nformat: dots\n",
+			key:      "go-test-p",
+			value:    "4",
+			expected: "go-test-p: 4\nformat: dots\n",
+		},
+		{
+			name:     "remove later entries of the key",
+			raw:      "go-test-p: 2\nformat: dots\ngo-test-p: 3\n",
+			key:      "go-test-p",
+			value:    "4",
+			expected: "go-test-p: 4\nformat: dots\n",
+		},
+		{
+			name:     "does not change a nested key",
+			raw:      "projects:\n  go-test-p: ./...\n",
+			key:      "go-test-p",
+			value:    "4",
+			expected: "projects:\n  go-test-p: ./...\ngo-test-p: 4\n",
+		},
+		{
+			name:     "quote a value",
+			raw:      "format: dots\n",
+			key:      "jsonfile",
+			value:    "out/test #1.json",
+			expected: "format: dots\njsonfile: \"out/test #1.json\"\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestSet_Errors(t *testing.T) {
+	_, err := Set([]byte("packages: [./cmd\n"), "go-test-p", "4")
+	assert.Error(t, err, "line 1: missing closing bracket in [./cmd")
+}
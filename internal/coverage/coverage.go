@@ -0,0 +1,234 @@
+/*
+Package coverage reads the coverage profiles written by 'go test
+-coverprofile', and reads and writes the coverage of each test in a test run,
+which is used to find the tests that cover a change.
+*/
+package coverage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Map is the coverage of each test in a test run.
+type Map struct {
+	Tests []TestCoverage `json:"tests"`
+}
+
+// TestCoverage is the coverage of a single test.
+type TestCoverage struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	// Files maps the name of a file, as it appears in a coverage profile (the
+	// import path of the package, and the name of the file), to the lines
+	// covered by the test.
+	Files map[string][]LineRange `json:"files"`
+}
+
+// LineRange is a range of lines in a file. Start and End are inclusive.
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Overlaps returns true if any line in r is also in o.
+func (r LineRange) Overlaps(o LineRange) bool {
+	return r.Start <= o.End && o.Start <= r.End
+}
+
+// Covers returns the ranges covered by the test in the file at path. The path
+// may be relative to the root of the module, like the paths printed by
+// 'git diff', in which case it matches the end of the file name in the
+// profile.
+func (t TestCoverage) Covers(path string) []LineRange {
+	var result []LineRange
+	for name, ranges := range t.Files {
+		if name == path || strings.HasSuffix(name, "/"+path) {
+			result = append(result, ranges...)
+		}
+	}
+	return result
+}
+
+// ReadMap reads a Map from a JSON file.
+func ReadMap(filename string) (*Map, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint: errcheck
+	return DecodeMap(fh)
+}
+
+// DecodeMap decodes a Map from JSON.
+func DecodeMap(r io.Reader) (*Map, error) {
+	m := &Map{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("failed to decode coverage map: %w", err)
+	}
+	return m, nil
+}
+
+// Encode writes the Map as JSON. The tests are sorted by package and name.
+func (m *Map) Encode(w io.Writer) error {
+	sort.Slice(m.Tests, func(i, j int) bool {
+		if m.Tests[i].Package != m.Tests[j].Package {
+			return m.Tests[i].Package < m.Tests[j].Package
+		}
+		return m.Tests[i].Test < m.Tests[j].Test
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Block is a block of statements in a coverage profile written by
+// 'go test -coverprofile'.
+type Block struct {
+	// File is the import path of the package, and the name of the file.
+	File      string
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
+}
+
+// ParseProfile reads the blocks of a coverage profile. Blocks that are in the
+// profile more than once, like in the profile of a run with -coverpkg, are
+// merged into a single block.
+func ParseProfile(r io.Reader) ([]Block, error) {
+	type key struct {
+		file                                 string
+		startLine, startCol, endLine, endCol int
+	}
+	var blocks []Block
+	index := make(map[key]int)
+	scanner := bufio.NewScanner(r)
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || (lineNum == 1 && strings.HasPrefix(line, "mode:")) {
+			continue
+		}
+		b, err := parseBlock(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		k := key{b.File, b.StartLine, b.StartCol, b.EndLine, b.EndCol}
+		if i, ok := index[k]; ok {
+			blocks[i].Count += b.Count
+			continue
+		}
+		index[k] = len(blocks)
+		blocks = append(blocks, b)
+	}
+	return blocks, scanner.Err()
+}
+
+// parseBlock parses a line of a coverage profile, like:
+//
+//	example.com/pkg/file.go:10.2,12.16 2 1
+func parseBlock(line string) (Block, error) {
+	var b Block
+	i := strings.LastIndex(line, ":")
+	if i < 0 {
+		return b, fmt.Errorf("invalid coverage block: %v", line)
+	}
+	b.File = line[:i]
+	_, err := fmt.Sscanf(line[i+1:], "%d.%d,%d.%d %d %d",
+		&b.StartLine, &b.StartCol, &b.EndLine, &b.EndCol, &b.NumStmt, &b.Count)
+	if err != nil {
+		return b, fmt.Errorf("invalid coverage block: %v", line)
+	}
+	return b, nil
+}
+
+// ReadProfile reads the blocks of the coverage profile in filename.
+func ReadProfile(filename string) ([]Block, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint: errcheck
+	return ParseProfile(fh)
+}
+
+// Statements is the number of statements in a package, and the number of
+// those statements that were run by the tests.
+type Statements struct {
+	Total   int
+	Covered int
+}
+
+// Percent returns the percentage of the statements that were covered.
+func (s Statements) Percent() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return 100 * float64(s.Covered) / float64(s.Total)
+}
+
+// Add returns the sum of s and o.
+func (s Statements) Add(o Statements) Statements {
+	return Statements{Total: s.Total + o.Total, Covered: s.Covered + o.Covered}
+}
+
+// ByPackage returns the statements of each package in the blocks. The name of
+// the package is the directory of the file name of the block.
+func ByPackage(blocks []Block) map[string]Statements {
+	result := make(map[string]Statements)
+	for _, b := range blocks {
+		pkg := path.Dir(b.File)
+		s := result[pkg]
+		s.Total += b.NumStmt
+		if b.Count > 0 {
+			s.Covered += b.NumStmt
+		}
+		result[pkg] = s
+	}
+	return result
+}
+
+// CoveredLines returns the lines of each file that are in a block that was
+// run at least once. The ranges of each file are sorted, and ranges that
+// overlap, or are next to each other, are merged.
+func CoveredLines(blocks []Block) map[string][]LineRange {
+	byFile := make(map[string][]LineRange)
+	for _, b := range blocks {
+		if b.Count == 0 {
+			continue
+		}
+		byFile[b.File] = append(byFile[b.File], LineRange{Start: b.StartLine, End: b.EndLine})
+	}
+	for file, ranges := range byFile {
+		byFile[file] = mergeRanges(ranges)
+	}
+	return byFile
+}
+
+func mergeRanges(ranges []LineRange) []LineRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start < ranges[j].Start
+	})
+	result := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &result[len(result)-1]
+		if r.Start <= last.End+1 {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMap_EncodeDecode(t *testing.T) {
+	m := &Map{Tests: []TestCoverage{
+		{Package: "example.com/b", Test: "TestOne", Files: map[string][]LineRange{
+			"example.com/b/b.go": {{Start: 1, End: 3}},
+		}},
+		{Package: "example.com/a", Test: "TestTwo", Files: map[string][]LineRange{
+			"example.com/a/a.go": {{Start: 10, End: 12}},
+		}},
+	}}
+	buf := new(bytes.Buffer)
+	assert.NilError(t, m.Encode(buf))
+
+	actual, err := DecodeMap(buf)
+	assert.NilError(t, err)
+	assert.Equal(t, actual.Tests[0].Package, "example.com/a")
+	assert.DeepEqual(t, actual, m)
+
+	_, err = DecodeMap(bytes.NewBufferString("[]"))
+	assert.ErrorContains(t, err, "failed to decode coverage map")
+}
+
+func TestTestCoverage_Covers(t *testing.T) {
+	tc := TestCoverage{Files: map[string][]LineRange{
+		"example.com/app/store/store.go": {{Start: 5, End: 12}},
+		"example.com/app/other/store.go": {{Start: 1, End: 1}},
+	}}
+	assert.DeepEqual(t, tc.Covers("store/store.go"), []LineRange{{Start: 5, End: 12}})
+	assert.DeepEqual(t, tc.Covers("example.com/app/store/store.go"), []LineRange{{Start: 5, End: 12}})
+	assert.Assert(t, tc.Covers("tore/store.go") == nil)
+
+	assert.Assert(t, LineRange{Start: 5, End: 12}.Overlaps(LineRange{Start: 12, End: 14}))
+	assert.Assert(t, !LineRange{Start: 5, End: 12}.Overlaps(LineRange{Start: 13, End: 14}))
+}
+
+func TestParseProfile(t *testing.T) {
+	profile := `mode: count
+example.com/app/store/store.go:10.2,12.16 4 3
+example.com/app/store/store.go:14.2,16.3 2 0
+example.com/app/api/api.go:5.2,7.3 5 0
+example.com/app/api/api.go:5.2,7.3 5 2
+`
+	blocks, err := ParseProfile(bytes.NewBufferString(profile))
+	assert.NilError(t, err)
+	expected := []Block{
+		{File: "example.com/app/store/store.go", StartLine: 10, StartCol: 2, EndLine: 12, EndCol: 16, NumStmt: 4, Count: 3},
+		{File: "example.com/app/store/store.go", StartLine: 14, StartCol: 2, EndLine: 16, EndCol: 3, NumStmt: 2},
+		{File: "example.com/app/api/api.go", StartLine: 5, StartCol: 2, EndLine: 7, EndCol: 3, NumStmt: 5, Count: 2},
+	}
+	assert.DeepEqual(t, blocks, expected)
+
+	assert.DeepEqual(t, ByPackage(blocks), map[string]Statements{
+		"example.com/app/store": {Total: 6, Covered: 4},
+		"example.com/app/api":   {Total: 5, Covered: 5},
+	})
+	assert.Equal(t, Statements{Total: 6, Covered: 3}.Percent(), 50.0)
+	assert.Equal(t, Statements{}.Percent(), 0.0)
+
+	_, err = ParseProfile(bytes.NewBufferString("mode: set\nexample.com/a.go:1.2 1\n"))
+	assert.ErrorContains(t, err, "line 2: invalid coverage block")
+}
+
+func TestCoveredLines(t *testing.T) {
+	blocks := []Block{
+		{File: "example.com/a/a.go", StartLine: 20, EndLine: 22, Count: 1},
+		{File: "example.com/a/a.go", StartLine: 10, EndLine: 12, Count: 1},
+		{File: "example.com/a/a.go", StartLine: 13, EndLine: 15, Count: 2},
+		{File: "example.com/a/a.go", StartLine: 14, EndLine: 14, Count: 1},
+		{File: "example.com/a/a.go", StartLine: 30, EndLine: 32, Count: 0},
+		{File: "example.com/a/b.go", StartLine: 1, EndLine: 1, Count: 0},
+	}
+	assert.DeepEqual(t, CoveredLines(blocks), map[string][]LineRange{
+		"example.com/a/a.go": {{Start: 10, End: 15}, {Start: 20, End: 22}},
+	})
+}
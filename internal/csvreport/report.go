@@ -0,0 +1,77 @@
+/*
+Package csvreport writes the result of every test in a testjson.Execution as
+a row of a CSV file.
+*/
+package csvreport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Header is the first row of the CSV file.
+var Header = []string{"package", "name", "status", "duration", "attempt", "requirements"}
+
+// Write a CSV document with one row for every test case in exec. The rows are
+// sorted by package, and by the order the tests started. The duration is in
+// seconds, the attempt starts at 1 and increases each time the test is re-run,
+// and the requirements from the name of the test are separated by a semicolon.
+func Write(out io.Writer, exec *testjson.Execution) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(Header); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	for _, name := range exec.Packages() {
+		for _, row := range packageRows(exec.Package(name)) {
+			if err := w.Write(row); err != nil {
+				return fmt.Errorf("failed to write CSV: %w", err)
+			}
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+func packageRows(pkg *testjson.Package) [][]string {
+	type result struct {
+		tc     testjson.TestCase
+		status string
+	}
+	var results []result
+	for _, tc := range pkg.Passed {
+		results = append(results, result{tc: tc, status: "passed"})
+	}
+	for _, tc := range pkg.Failed {
+		results = append(results, result{tc: tc, status: "failed"})
+	}
+	for _, tc := range pkg.Skipped {
+		results = append(results, result{tc: tc, status: "skipped"})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].tc.ID < results[j].tc.ID
+	})
+
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		requirements, _ := junitxml.SplitRequirements(r.tc.Test.Name())
+		rows = append(rows, []string{
+			r.tc.Package,
+			r.tc.Test.Name(),
+			r.status,
+			strconv.FormatFloat(r.tc.Elapsed.Seconds(), 'f', 3, 64),
+			strconv.Itoa(r.tc.RunID + 1),
+			strings.Join(requirements, ";"),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,43 @@
+package csvreport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestWrite(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/one","Test":"TestLogin[REQ-1,REQ-2]"}
+{"Action":"pass","Package":"example.com/one","Test":"TestLogin[REQ-1,REQ-2]","Elapsed":0.25}
+{"Action":"run","Package":"example.com/one","Test":"TestParse"}
+{"Action":"fail","Package":"example.com/one","Test":"TestParse","Elapsed":1.5}
+{"Action":"run","Package":"example.com/one","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/one","Test":"TestSkip"}
+{"Action":"fail","Package":"example.com/one"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	rerun := `{"Action":"run","Package":"example.com/one","Test":"TestParse"}
+{"Action":"pass","Package":"example.com/one","Test":"TestParse","Elapsed":0.5}
+{"Action":"pass","Package":"example.com/one"}
+`
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    strings.NewReader(rerun),
+		Execution: exec,
+		RunID:     1,
+	})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, Write(out, exec))
+	expected := `package,name,status,duration,attempt,requirements
+example.com/one,"TestLogin[REQ-1,REQ-2]",passed,0.250,1,REQ-1;REQ-2
+example.com/one,TestParse,failed,1.500,1,
+example.com/one,TestSkip,skipped,0.000,1,
+example.com/one,TestParse,passed,0.500,2,
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -0,0 +1,237 @@
+/*
+Package cucumber writes a testjson.Execution as a Cucumber JSON report, so
+that the results of BDD style test suites can be shown by tools that read the
+reports created by Cucumber.
+
+Each package is a feature, each root test is a scenario of the feature, and
+each subtest of a root test is a step of the scenario. The underscores in the
+name of a subtest are replaced by spaces in the name of the step.
+*/
+package cucumber
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Feature is a package.
+type Feature struct {
+	URI         string     `json:"uri"`
+	ID          string     `json:"id"`
+	Keyword     string     `json:"keyword"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Line        int        `json:"line"`
+	Elements    []Scenario `json:"elements"`
+}
+
+// Scenario is a root test.
+type Scenario struct {
+	ID          string `json:"id"`
+	Keyword     string `json:"keyword"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Line        int    `json:"line"`
+	Type        string `json:"type"`
+	Tags        []Tag  `json:"tags,omitempty"`
+	Steps       []Step `json:"steps"`
+}
+
+// Tag of a scenario. The requirements in the name of a test, and the flaky
+// status of the test, are added to the scenario as tags.
+type Tag struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+}
+
+// Step is a subtest of a root test, or the root test itself when the test
+// has no subtests.
+type Step struct {
+	Keyword string `json:"keyword"`
+	Name    string `json:"name"`
+	Line    int    `json:"line"`
+	Result  Result `json:"result"`
+}
+
+// Result of a step. Duration is in nanoseconds.
+type Result struct {
+	Status       string `json:"status"`
+	Duration     int64  `json:"duration"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+const (
+	statusPassed  = "passed"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+
+	// tagFlaky is added to a scenario when the test failed, and then passed
+	// when it was re-run.
+	tagFlaky = "@flaky"
+)
+
+// Write a Cucumber JSON report of exec to out. When a test was re-run the
+// scenario has the result of the last run of the test.
+func Write(out io.Writer, exec *testjson.Execution) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(generate(exec)); err != nil {
+		return fmt.Errorf("failed to write Cucumber JSON: %w", err)
+	}
+	return nil
+}
+
+func generate(exec *testjson.Execution) []Feature {
+	features := []Feature{}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if pkg.Total == 0 {
+			continue
+		}
+		feature := Feature{
+			URI:      name,
+			ID:       featureID(name),
+			Keyword:  "Feature",
+			Name:     name,
+			Line:     1,
+			Elements: []Scenario{},
+		}
+		p := newPackageTests(exec, pkg)
+		for i, root := range p.lastRunOfRootTests() {
+			feature.Elements = append(feature.Elements, p.newScenario(feature.ID, root, i+1))
+		}
+		features = append(features, feature)
+	}
+	return features
+}
+
+// packageTests are the test cases of a package, sorted by ID, and the status
+// of each test case.
+type packageTests struct {
+	exec     *testjson.Execution
+	tests    []testjson.TestCase
+	statuses map[int]string
+}
+
+func newPackageTests(exec *testjson.Execution, pkg *testjson.Package) packageTests {
+	p := packageTests{exec: exec, statuses: make(map[int]string)}
+	for _, tc := range pkg.Passed {
+		p.statuses[tc.ID] = statusPassed
+	}
+	for _, tc := range pkg.Failed {
+		p.statuses[tc.ID] = statusFailed
+	}
+	for _, tc := range pkg.Skipped {
+		p.statuses[tc.ID] = statusSkipped
+	}
+	p.tests = pkg.TestCases()
+	sort.SliceStable(p.tests, func(i, j int) bool {
+		return p.tests[i].ID < p.tests[j].ID
+	})
+	return p
+}
+
+type rootTest struct {
+	testjson.TestCase
+	status string
+	flaky  bool
+}
+
+// lastRunOfRootTests returns the last run of each root test in the package,
+// in the order the tests first started.
+func (p packageTests) lastRunOfRootTests() []rootTest {
+	var roots []rootTest
+	index := make(map[string]int)
+	for _, tc := range p.tests {
+		if tc.Test.IsSubTest() {
+			continue
+		}
+		status := p.statuses[tc.ID]
+		i, ok := index[tc.Test.Name()]
+		if !ok {
+			index[tc.Test.Name()] = len(roots)
+			roots = append(roots, rootTest{TestCase: tc, status: status})
+			continue
+		}
+		prev := roots[i]
+		roots[i] = rootTest{
+			TestCase: tc,
+			status:   status,
+			flaky:    status == statusPassed && (prev.flaky || prev.status == statusFailed),
+		}
+	}
+	return roots
+}
+
+func (p packageTests) newScenario(featureID string, root rootTest, line int) Scenario {
+	requirements, name := junitxml.SplitRequirements(root.Test.Name())
+	scenario := Scenario{
+		ID:      featureID + ";" + scenarioID(name),
+		Keyword: "Scenario",
+		Name:    name,
+		Line:    line,
+		Type:    "scenario",
+		Steps:   []Step{},
+	}
+	for _, req := range requirements {
+		scenario.Tags = append(scenario.Tags, Tag{Name: "@" + req, Line: line})
+	}
+	if root.flaky {
+		scenario.Tags = append(scenario.Tags, Tag{Name: tagFlaky, Line: line})
+	}
+
+	var stepFailed bool
+	prefix := root.Test.Name() + "/"
+	for _, tc := range p.tests {
+		if tc.RunID != root.RunID || !strings.HasPrefix(tc.Test.Name(), prefix) {
+			continue
+		}
+		step := p.newStep(tc, stepName(strings.TrimPrefix(tc.Test.Name(), prefix)), line)
+		stepFailed = stepFailed || step.Result.Status == statusFailed
+		scenario.Steps = append(scenario.Steps, step)
+	}
+	// A test without subtests, or a test that failed outside of its subtests,
+	// is a step of its own scenario, so that the scenario has the result of the
+	// test.
+	if len(scenario.Steps) == 0 || (root.status == statusFailed && !stepFailed) {
+		scenario.Steps = append(scenario.Steps, p.newStep(root.TestCase, name, line))
+	}
+	return scenario
+}
+
+func (p packageTests) newStep(tc testjson.TestCase, name string, line int) Step {
+	step := Step{
+		Keyword: "* ",
+		Name:    name,
+		Line:    line,
+		Result: Result{
+			Status:   p.statuses[tc.ID],
+			Duration: tc.Elapsed.Nanoseconds(),
+		},
+	}
+	if step.Result.Status == statusFailed {
+		step.Result.ErrorMessage = strings.Join(p.exec.OutputLines(tc), "")
+	}
+	return step
+}
+
+// stepName returns the name of a subtest as it was passed to t.Run. The
+// testing package replaces the spaces in the name of a subtest with
+// underscores, which makes the names of steps from godog hard to read.
+func stepName(name string) string {
+	return strings.ReplaceAll(name, "_", " ")
+}
+
+func featureID(pkgname string) string {
+	return strings.NewReplacer("/", "-", ".", "-").Replace(strings.ToLower(pkgname))
+}
+
+func scenarioID(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "-", "/", "-").Replace(name))
+}
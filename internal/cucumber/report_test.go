@@ -0,0 +1,80 @@
+package cucumber
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/login","Test":"TestLogin[REQ-1]"}
+{"Action":"run","Package":"example.com/login","Test":"TestLogin[REQ-1]/valid_password"}
+{"Action":"pass","Package":"example.com/login","Test":"TestLogin[REQ-1]/valid_password","Elapsed":0.25}
+{"Action":"run","Package":"example.com/login","Test":"TestLogin[REQ-1]/wrong_password"}
+{"Action":"output","Package":"example.com/login","Test":"TestLogin[REQ-1]/wrong_password","Output":"    login_test.go:20: expected an error\n"}
+{"Action":"fail","Package":"example.com/login","Test":"TestLogin[REQ-1]/wrong_password","Elapsed":0.5}
+{"Action":"fail","Package":"example.com/login","Test":"TestLogin[REQ-1]","Elapsed":0.75}
+{"Action":"run","Package":"example.com/login","Test":"TestLogout"}
+{"Action":"output","Package":"example.com/login","Test":"TestLogout","Output":"    logout_test.go:8: timeout\n"}
+{"Action":"fail","Package":"example.com/login","Test":"TestLogout","Elapsed":1}
+{"Action":"run","Package":"example.com/login","Test":"TestSkipped"}
+{"Action":"skip","Package":"example.com/login","Test":"TestSkipped"}
+{"Action":"fail","Package":"example.com/login","Elapsed":2}
+{"Action":"output","Package":"example.com/empty","Output":"?   \texample.com/empty\t[no test files]\n"}
+{"Action":"skip","Package":"example.com/empty"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	rerun := `{"Action":"run","Package":"example.com/login","Test":"TestLogout"}
+{"Action":"pass","Package":"example.com/login","Test":"TestLogout","Elapsed":0.5}
+{"Action":"pass","Package":"example.com/login"}
+`
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    strings.NewReader(rerun),
+		Execution: exec,
+		RunID:     1,
+	})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, Write(out, exec))
+	golden.Assert(t, out.String(), "cucumber-report.golden")
+
+	var features []Feature
+	assert.NilError(t, json.Unmarshal(out.Bytes(), &features))
+	assert.Equal(t, len(features), 1)
+	scenarios := features[0].Elements
+	assert.Equal(t, len(scenarios), 3)
+	assert.Equal(t, scenarios[0].Name, "TestLogin")
+	assert.Equal(t, len(scenarios[0].Steps), 2)
+	assert.Equal(t, scenarios[0].Steps[1].Result.Status, "failed")
+	assert.DeepEqual(t, scenarios[1].Tags, []Tag{{Name: "@flaky", Line: 2}})
+	assert.Equal(t, scenarios[1].Steps[0].Result.Status, "passed")
+	assert.Equal(t, scenarios[2].Steps[0].Result.Status, "skipped")
+}
+
+func TestWrite_FailedOutsideOfSubTests(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/one","Test":"TestOne"}
+{"Action":"run","Package":"example.com/one","Test":"TestOne/sub"}
+{"Action":"pass","Package":"example.com/one","Test":"TestOne/sub"}
+{"Action":"output","Package":"example.com/one","Test":"TestOne","Output":"    one_test.go:9: cleanup failed\n"}
+{"Action":"fail","Package":"example.com/one","Test":"TestOne","Elapsed":1}
+{"Action":"fail","Package":"example.com/one"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	features := generate(exec)
+	assert.Equal(t, len(features), 1)
+	steps := features[0].Elements[0].Steps
+	assert.Equal(t, len(steps), 2)
+	assert.Equal(t, steps[0].Name, "sub")
+	assert.Equal(t, steps[1].Name, "TestOne")
+	assert.Equal(t, steps[1].Result.Status, "failed")
+	assert.Equal(t, steps[1].Result.ErrorMessage, "    one_test.go:9: cleanup failed\n")
+}
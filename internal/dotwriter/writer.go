@@ -37,6 +37,13 @@ func (w *Writer) Flush() error {
 	return err
 }
 
+// Clear the lines written by the last Flush, so that other output may be
+// written to out in their place.
+func (w *Writer) Clear() {
+	w.clearLines(w.lineCount)
+	w.lineCount = 0
+}
+
 // Write saves buf to a buffer
 func (w *Writer) Write(buf []byte) (int, error) {
 	return w.buf.Write(buf)
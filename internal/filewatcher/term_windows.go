@@ -1,19 +1,28 @@
 package filewatcher
 
-import "context"
+import (
+	"golang.org/x/sys/windows"
+	"gotest.tools/gotestsum/internal/log"
+)
 
-type terminal struct{}
+// enableNonBlockingRead disables line input and echo on the console, so that
+// a key press is read without waiting for enter.
+func enableNonBlockingRead(fd int) (func(), error) {
+	handle := windows.Handle(fd)
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return nil, err
+	}
 
-func newTerminal() *terminal {
-	return nil
-}
-
-func (r *terminal) Monitor(context.Context) {}
+	reset := func() {
+		if err := windows.SetConsoleMode(handle, mode); err != nil {
+			log.Debugf("failed to reset console mode of fd %d: %v", fd, err)
+		}
+	}
 
-func (r *terminal) Events() <-chan Event {
-	return nil
+	raw := mode &^ (windows.ENABLE_LINE_INPUT | windows.ENABLE_ECHO_INPUT)
+	if err := windows.SetConsoleMode(handle, raw); err != nil {
+		return nil, err
+	}
+	return reset, nil
 }
-
-func (r *terminal) Start() {}
-
-func (r *terminal) Reset() {}
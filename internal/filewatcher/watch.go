@@ -126,10 +126,8 @@ func findAllDirs(dirs []string, maxDepth int) []string {
 
 	var output []string // nolint: prealloc
 	for _, dir := range dirs {
-		const recur = "/..."
-		if strings.HasSuffix(dir, recur) {
-			dir = strings.TrimSuffix(dir, recur)
-			output = append(output, findSubDirs(dir, maxDepth)...)
+		if base, ok := trimRecursive(dir); ok {
+			output = append(output, findSubDirs(base, maxDepth)...)
 			continue
 		}
 		output = append(output, dir)
@@ -137,6 +135,17 @@ func findAllDirs(dirs []string, maxDepth int) []string {
 	return output
 }
 
+// trimRecursive removes the /... suffix from a package pattern. On Windows the
+// pattern may also use a backslash, like .\pkg\...
+func trimRecursive(dir string) (string, bool) {
+	for _, recur := range []string{"/...", string(filepath.Separator) + "..."} {
+		if strings.HasSuffix(dir, recur) {
+			return strings.TrimSuffix(dir, recur), true
+		}
+	}
+	return dir, false
+}
+
 func findSubDirs(rootDir string, maxDepth int) []string {
 	var output []string
 	// add root dir depth so that maxDepth is relative to the root dir
@@ -248,7 +257,18 @@ func (h *fsEventHandler) handleEvent(event fsnotify.Event) error {
 		log.Debugf("skipping event received less than %v after the previous", floodThreshold)
 		return nil
 	}
-	return h.runTests(Event{PkgPath: "./" + filepath.Dir(event.Name)})
+	return h.runTests(Event{PkgPath: pkgPathForFile(event.Name)})
+}
+
+// pkgPathForFile returns the path to the package that contains the file.
+// Relative paths start with ./ so that they are not treated as an import path,
+// and use forward slashes so that the path works on Windows as well.
+func pkgPathForFile(name string) string {
+	dir := filepath.Dir(name)
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return "./" + filepath.ToSlash(dir)
 }
 
 func (h *fsEventHandler) runTests(opts Event) error {
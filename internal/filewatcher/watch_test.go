@@ -158,3 +158,24 @@ func TestFindAllDirs_DefaultPath(t *testing.T) {
 	expected := []string{".", "a", "b"}
 	assert.DeepEqual(t, dirs, expected)
 }
+
+func TestPkgPathForFile(t *testing.T) {
+	assert.Equal(t, pkgPathForFile(filepath.Join("pkg", "file_test.go")), "./pkg")
+	assert.Equal(t, pkgPathForFile(filepath.Join("pkg", "sub", "file.go")), "./pkg/sub")
+	dir := fs.NewDir(t, "watch-abs")
+	assert.Equal(t, pkgPathForFile(dir.Join("file.go")), dir.Path())
+}
+
+func TestTrimRecursive(t *testing.T) {
+	dir, ok := trimRecursive("./pkg/...")
+	assert.Assert(t, ok)
+	assert.Equal(t, dir, "./pkg")
+
+	dir, ok = trimRecursive("." + string(filepath.Separator) + "...")
+	assert.Assert(t, ok)
+	assert.Equal(t, dir, ".")
+
+	dir, ok = trimRecursive("./pkg")
+	assert.Assert(t, !ok)
+	assert.Equal(t, dir, "./pkg")
+}
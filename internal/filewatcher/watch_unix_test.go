@@ -48,7 +48,7 @@ func TestWatch(t *testing.T) {
 		fs.Apply(t, dir, fs.WithFile("file.go", ""))
 
 		event := <-chEvents
-		expected := Event{PkgPath: "./" + dir.Path()}
+		expected := Event{PkgPath: dir.Path()}
 		assert.DeepEqual(t, event, expected, cmpEvent)
 
 		t.Run("and rerun", func(t *testing.T) {
@@ -56,7 +56,7 @@ func TestWatch(t *testing.T) {
 			assert.NilError(t, err)
 
 			event := <-chEvents
-			expected := Event{PkgPath: "./" + dir.Path(), useLastPath: true}
+			expected := Event{PkgPath: dir.Path(), useLastPath: true}
 			assert.DeepEqual(t, event, expected, cmpEvent)
 		})
 
@@ -66,7 +66,7 @@ func TestWatch(t *testing.T) {
 
 			event := <-chEvents
 			expected := Event{
-				PkgPath:     "./" + dir.Path(),
+				PkgPath:     dir.Path(),
 				useLastPath: true,
 				Debug:       true,
 			}
@@ -79,7 +79,7 @@ func TestWatch(t *testing.T) {
 
 			event := <-chEvents
 			expected := Event{
-				PkgPath:     "./" + dir.Path(),
+				PkgPath:     dir.Path(),
 				Args:        []string{"-update"},
 				useLastPath: true,
 			}
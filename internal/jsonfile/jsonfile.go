@@ -0,0 +1,21 @@
+/*
+Package jsonfile opens the go test -json output read by the tool commands.
+*/
+package jsonfile
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Open returns a reader of the file name, or of stdin when name is empty
+// or "-". Closing the reader of stdin does not close stdin.
+func Open(name string) (io.ReadCloser, error) {
+	switch name {
+	case "", "-":
+		return ioutil.NopCloser(os.Stdin), nil
+	default:
+		return os.Open(name)
+	}
+}
@@ -0,0 +1,23 @@
+package jsonfile
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestOpen(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("test.json", `{"Action":"pass"}`))
+
+	in, err := Open(dir.Join("test.json"))
+	assert.NilError(t, err)
+	raw, err := ioutil.ReadAll(in)
+	assert.NilError(t, err)
+	assert.NilError(t, in.Close())
+	assert.Equal(t, string(raw), `{"Action":"pass"}`)
+
+	_, err = Open(dir.Join("missing.json"))
+	assert.ErrorContains(t, err, "missing.json")
+}
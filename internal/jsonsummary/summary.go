@@ -0,0 +1,355 @@
+/*Package jsonsummary creates a JSON summary document from a testjson.Execution.
+ */
+package jsonsummary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Summary of a test run.
+type Summary struct {
+	Total int `json:"total"`
+	// Examples is the number of Example functions included in Total.
+	Examples int        `json:"examples,omitempty"`
+	Failed   int        `json:"failed"`
+	Skipped  int        `json:"skipped"`
+	Errors   []string   `json:"errors,omitempty"`
+	Elapsed  float64    `json:"elapsed"`
+	Packages []Package  `json:"packages"`
+	Failures []TestCase `json:"failures,omitempty"`
+	// ResourceUsage of each go test process.
+	ResourceUsage []ResourceUsage `json:"resourceUsage,omitempty"`
+	// Environment contains the settings required to reproduce the test run,
+	// like GOFLAGS and selected environment variables.
+	Environment map[string]string `json:"environment,omitempty"`
+	// Utilization of the time spent running the tests in all packages.
+	Utilization *Utilization `json:"utilization,omitempty"`
+	// Diagnostics are the lines from the stderr of go test that are not
+	// errors, when they were captured with --capture-diagnostics.
+	Diagnostics []string `json:"diagnostics,omitempty"`
+	// VCS is the state of the git working tree the tests were run from.
+	VCS *VCS `json:"vcs,omitempty"`
+	// Labels are the key=value pairs attached to the test run with --label.
+	Labels map[string]string `json:"labels,omitempty"`
+	// UnattributedOutput is the output that go test attributed to a test that
+	// did not print it, usually because a test wrote to os.Stdout directly.
+	UnattributedOutput []UnattributedOutput `json:"unattributedOutput,omitempty"`
+	// Requirements are the requirements in the names of the tests, with
+	// the number of tests of each requirement that passed, failed, or were
+	// skipped.
+	Requirements []Requirement `json:"requirements,omitempty"`
+	// SkipCategories is the number of skipped tests in each category, from
+	// Config.SkipCategory.
+	SkipCategories map[string]int `json:"skipCategories,omitempty"`
+	// Reruns is the cost of re-running failed tests with --rerun-fails.
+	Reruns *Reruns `json:"reruns,omitempty"`
+	// Setup is the time used to start and stop the dependencies of the tests.
+	Setup *Setup `json:"setup,omitempty"`
+}
+
+// Setup is the time used to start the dependencies of the tests, like a
+// docker compose file, and to stop them after the tests. Times are in
+// seconds.
+type Setup struct {
+	Elapsed  float64 `json:"elapsed"`
+	Teardown float64 `json:"teardown"`
+	// Probes are the results of the --wait-for probes.
+	Probes []Probe `json:"probes,omitempty"`
+	// Error is the reason the dependencies were not ready, when the run
+	// stopped before the tests.
+	Error string `json:"error,omitempty"`
+}
+
+// Probe is the result of waiting for a dependency of the tests to be ready.
+type Probe struct {
+	Target   string  `json:"target"`
+	Ready    bool    `json:"ready"`
+	Attempts int     `json:"attempts"`
+	Elapsed  float64 `json:"elapsed"`
+	// Error is the error from the last check, when the probe was not ready.
+	Error string `json:"error,omitempty"`
+}
+
+// Reruns is the number of attempts, tests, and time spent re-running failed
+// tests. Times are in seconds.
+type Reruns struct {
+	// Attempts is the number of times the failed tests were run again.
+	Attempts int `json:"attempts"`
+	// Tests is the number of tests that were run again, counted once for
+	// each attempt.
+	Tests   int     `json:"tests"`
+	Elapsed float64 `json:"elapsed"`
+	// Budget is the time allowed for re-running tests, or 0 when there is no
+	// limit.
+	Budget float64 `json:"budget,omitempty"`
+	// NotRerun is the number of failed tests that were not run again because
+	// the Budget was used.
+	NotRerun int `json:"notRerun,omitempty"`
+}
+
+// Requirement is a requirement from the names of tests, like REQ-12 in
+// TestLogin[REQ-12].
+type Requirement struct {
+	ID      string `json:"id"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Skipped int    `json:"skipped"`
+}
+
+// VCS is the state of a git working tree.
+type VCS struct {
+	Commit string `json:"commit"`
+	Branch string `json:"branch,omitempty"`
+	Dirty  bool   `json:"dirty"`
+}
+
+// UnattributedOutput is a line of output that was moved from the test that
+// go test attributed it to.
+type UnattributedOutput struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	// MovedTo is the test that printed the output, or empty when the output
+	// was moved to the package output.
+	MovedTo string `json:"movedTo,omitempty"`
+	Output  string `json:"output"`
+}
+
+// Package is the summary of a single package.
+type Package struct {
+	Name string `json:"name"`
+	// Module is the path of the module that contains the package, when the
+	// packages of more than one module were tested.
+	Module   string    `json:"module,omitempty"`
+	Result   string    `json:"result"`
+	Elapsed  float64   `json:"elapsed"`
+	Total    int       `json:"total"`
+	Examples int       `json:"examples,omitempty"`
+	Failed   int       `json:"failed"`
+	Skipped  int       `json:"skipped"`
+	Profiles *Profiles `json:"profiles,omitempty"`
+	// Setup and Teardown are the seconds spent in TestMain before the first
+	// test, and after the last test.
+	Setup    float64 `json:"setup,omitempty"`
+	Teardown float64 `json:"teardown,omitempty"`
+	// ShuffleSeed is the seed used by -shuffle to order the tests.
+	ShuffleSeed string `json:"shuffleSeed,omitempty"`
+	// Utilization of the time spent running the tests in the package.
+	Utilization *Utilization `json:"utilization,omitempty"`
+}
+
+// Utilization shows how much of the wall time was spent running tests, and
+// how many tests ran at the same time. Times are in seconds.
+type Utilization struct {
+	// Wall is the time from the start of the first test to the end of the
+	// last test.
+	Wall float64 `json:"wall"`
+	// TestTime is the sum of the elapsed time of the top-level tests.
+	TestTime float64 `json:"testTime"`
+	// Idle is the part of Wall when no tests were running.
+	Idle float64 `json:"idle"`
+	// Parallelism is the average number of tests running at the same time.
+	Parallelism    float64 `json:"parallelism"`
+	MaxParallelism int     `json:"maxParallelism"`
+}
+
+// Profiles are the paths to the profile files written for a package.
+type Profiles struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+	Binary string `json:"binary,omitempty"`
+}
+
+// ResourceUsage of a go test process, including all of its child processes.
+// Elapsed times are in seconds.
+type ResourceUsage struct {
+	// Package tested by the process. Empty when the process tested more than
+	// one package.
+	Package string  `json:"package,omitempty"`
+	Wall    float64 `json:"wall"`
+	User    float64 `json:"user"`
+	System  float64 `json:"system"`
+	// MaxRSS is the maximum resident set size in bytes of the process, or any
+	// of its children. It is 0 when the value is not available.
+	MaxRSS int64 `json:"maxRSS"`
+}
+
+// TestCase is a test that failed.
+type TestCase struct {
+	Package string  `json:"package"`
+	Name    string  `json:"name"`
+	Elapsed float64 `json:"elapsed"`
+	RunID   int     `json:"runID,omitempty"`
+	Output  string  `json:"output,omitempty"`
+	// Attachments are the paths of files attached to the test.
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// Config used to write a JSON summary.
+type Config struct {
+	// Profiles indexed by package name.
+	Profiles map[string]*Profiles
+	// ResourceUsage of each go test process.
+	ResourceUsage []ResourceUsage
+	// Environment of the test run.
+	Environment map[string]string
+	// Attachments returns the paths of files attached to a test case. It may
+	// be nil.
+	Attachments func(tc testjson.TestCase) []string
+	// HideExamples removes Example functions from the counts and failures.
+	HideExamples bool
+	// Module returns the module path of a package. It may be nil.
+	Module func(pkgname string) string
+	// VCS is the state of the git working tree. It may be nil.
+	VCS *VCS
+	// Labels of the test run. It may be nil.
+	Labels map[string]string
+	// SkipCategory returns the category of a skipped test from the output of
+	// the test. It may be nil.
+	SkipCategory func(output string) string
+	// Reruns is the cost of re-running failed tests. It may be nil.
+	Reruns *Reruns
+	// Setup is the time used to start and stop the dependencies. It may be
+	// nil.
+	Setup *Setup
+	// This is used for tests to have a consistent elapsed time
+	customElapsed float64
+}
+
+// Write creates a JSON summary document and writes it to out.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(generate(exec, cfg)); err != nil {
+		return fmt.Errorf("failed to write JSON summary: %w", err)
+	}
+	return nil
+}
+
+func generate(exec *testjson.Execution, cfg Config) Summary {
+	include := func(tc testjson.TestCase) bool {
+		return !cfg.HideExamples || !tc.Test.IsExample()
+	}
+	summary := Summary{
+		Total:    exec.Total(),
+		Examples: exec.Examples(),
+		Failed:   count(exec.Failed(), include),
+		Skipped:  count(exec.Skipped(), include),
+		Errors:   exec.Errors(),
+		Elapsed:  exec.Elapsed().Seconds(),
+		Packages: []Package{},
+	}
+	summary.ResourceUsage = cfg.ResourceUsage
+	summary.Environment = cfg.Environment
+	summary.Labels = cfg.Labels
+	summary.VCS = cfg.VCS
+	summary.Reruns = cfg.Reruns
+	summary.Setup = cfg.Setup
+	summary.Diagnostics = exec.Diagnostics()
+	for _, out := range exec.UnattributedOutput() {
+		summary.UnattributedOutput = append(summary.UnattributedOutput, UnattributedOutput{
+			Package: out.Package,
+			Test:    out.Test,
+			MovedTo: out.AttributedTo,
+			Output:  out.Output,
+		})
+	}
+	for _, r := range junitxml.RequirementResults(exec, cfg.HideExamples) {
+		summary.Requirements = append(summary.Requirements, Requirement{
+			ID:      r.ID,
+			Passed:  r.Passed,
+			Failed:  r.Failed,
+			Skipped: r.Skipped,
+		})
+	}
+	if cfg.SkipCategory != nil {
+		for _, tc := range exec.Skipped() {
+			if !include(tc) {
+				continue
+			}
+			if summary.SkipCategories == nil {
+				summary.SkipCategories = make(map[string]int)
+			}
+			summary.SkipCategories[cfg.SkipCategory(strings.Join(exec.OutputLines(tc), ""))]++
+		}
+	}
+	summary.Utilization = newUtilization(aggregate.ExecutionUtilization(exec))
+	if cfg.customElapsed != 0 {
+		summary.Elapsed = cfg.customElapsed
+	}
+	if cfg.HideExamples {
+		summary.Total -= summary.Examples
+		summary.Examples = 0
+	}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		item := Package{
+			Name:        name,
+			Result:      string(pkg.Result()),
+			Elapsed:     pkg.Elapsed().Seconds(),
+			Total:       pkg.Total,
+			Examples:    pkg.Examples,
+			Failed:      count(pkg.Failed, include),
+			Skipped:     count(pkg.Skipped, include),
+			Profiles:    cfg.Profiles[name],
+			Setup:       pkg.Setup().Seconds(),
+			Teardown:    pkg.Teardown().Seconds(),
+			ShuffleSeed: pkg.ShuffleSeed(),
+			Utilization: newUtilization(aggregate.PackageUtilization(pkg)),
+		}
+		if cfg.Module != nil {
+			item.Module = cfg.Module(name)
+		}
+		if cfg.HideExamples {
+			item.Total -= item.Examples
+			item.Examples = 0
+		}
+		summary.Packages = append(summary.Packages, item)
+	}
+	for _, tc := range exec.Failed() {
+		if !include(tc) {
+			continue
+		}
+		item := TestCase{
+			Package: tc.Package,
+			Name:    tc.Test.Name(),
+			Elapsed: tc.Elapsed.Seconds(),
+			RunID:   tc.RunID,
+			Output:  strings.Join(exec.OutputLines(tc), ""),
+		}
+		if cfg.Attachments != nil {
+			item.Attachments = cfg.Attachments(tc)
+		}
+		summary.Failures = append(summary.Failures, item)
+	}
+	return summary
+}
+
+func newUtilization(u aggregate.Utilization) *Utilization {
+	if u.Wall == 0 {
+		return nil
+	}
+	return &Utilization{
+		Wall:           u.Wall.Seconds(),
+		TestTime:       u.TestTime.Seconds(),
+		Idle:           u.Idle.Seconds(),
+		Parallelism:    u.Parallelism,
+		MaxParallelism: u.MaxParallelism,
+	}
+}
+
+func count(tcs []testjson.TestCase, include func(tc testjson.TestCase) bool) int {
+	total := 0
+	for _, tc := range tcs {
+		if include(tc) {
+			total++
+		}
+	}
+	return total
+}
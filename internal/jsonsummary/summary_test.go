@@ -0,0 +1,168 @@
+package jsonsummary
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t)
+
+	err := Write(out, exec, Config{
+		customElapsed: 2.1,
+		Profiles: map[string]*Profiles{
+			"gotest.tools/gotestsum/testjson/internal/good": {
+				CPU:    "profiles/good.cpu.pprof",
+				Memory: "profiles/good.mem.pprof",
+				Binary: "profiles/good.test",
+			},
+		},
+		Environment: map[string]string{"go.race": "false", "env.TZ": "UTC"},
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "summary.golden")
+}
+
+func TestGenerate_Attachments(t *testing.T) {
+	exec := createExecution(t)
+	summary := generate(exec, Config{
+		Attachments: func(tc testjson.TestCase) []string {
+			if tc.Test.Name() == "TestFailed" {
+				return []string{"/tmp/shot.png"}
+			}
+			return nil
+		},
+	})
+	var found int
+	for _, tc := range summary.Failures {
+		if tc.Name != "TestFailed" {
+			assert.Assert(t, tc.Attachments == nil, tc.Name)
+			continue
+		}
+		found++
+		assert.DeepEqual(t, tc.Attachments, []string{"/tmp/shot.png"})
+	}
+	assert.Assert(t, found > 0)
+}
+
+func createExecution(t *testing.T) *testjson.Execution {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: readTestData(t, "out"),
+		Stderr: readTestData(t, "err"),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func readTestData(t *testing.T, stream string) io.Reader {
+	raw, err := ioutil.ReadFile("../../testjson/testdata/input/go-test-json." + stream)
+	assert.NilError(t, err)
+	return bytes.NewReader(raw)
+}
+
+func TestGenerate_HideExamples(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(examplesInput),
+	})
+	assert.NilError(t, err)
+
+	summary := generate(exec, Config{})
+	assert.Equal(t, summary.Total, 3)
+	assert.Equal(t, summary.Examples, 2)
+	assert.Equal(t, summary.Failed, 1)
+	assert.Equal(t, len(summary.Failures), 1)
+
+	summary = generate(exec, Config{HideExamples: true})
+	assert.Equal(t, summary.Total, 1)
+	assert.Equal(t, summary.Examples, 0)
+	assert.Equal(t, summary.Failed, 0)
+	assert.Equal(t, len(summary.Failures), 0)
+	assert.Equal(t, summary.Packages[0].Total, 1)
+}
+
+func TestGenerate_Diagnostics(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:             strings.NewReader(examplesInput),
+		Stderr:             strings.NewReader("warning: GOPATH set to GOROOT has no effect\n"),
+		CaptureDiagnostics: true,
+	})
+	assert.NilError(t, err)
+
+	summary := generate(exec, Config{})
+	assert.DeepEqual(t, summary.Diagnostics, []string{"warning: GOPATH set to GOROOT has no effect"})
+}
+
+func TestGenerate_UnattributedOutput(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/one","Test":"TestOne"}
+{"Action":"pause","Package":"example.com/one","Test":"TestOne"}
+{"Action":"run","Package":"example.com/one","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/one","Test":"TestOne","Output":"from two\n"}
+{"Action":"pass","Package":"example.com/one","Test":"TestTwo"}
+{"Action":"cont","Package":"example.com/one","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/one","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/one"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	summary := generate(exec, Config{})
+	expected := []UnattributedOutput{
+		{Package: "example.com/one", Test: "TestOne", MovedTo: "TestTwo", Output: "from two\n"},
+	}
+	assert.DeepEqual(t, summary.UnattributedOutput, expected)
+}
+
+func TestGenerate_SkipCategories(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/one","Test":"TestOne"}
+{"Action":"output","Package":"example.com/one","Test":"TestOne","Output":"skipping in short mode\n"}
+{"Action":"skip","Package":"example.com/one","Test":"TestOne"}
+{"Action":"run","Package":"example.com/one","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/one","Test":"TestTwo","Output":"TOKEN not set\n"}
+{"Action":"skip","Package":"example.com/one","Test":"TestTwo"}
+{"Action":"pass","Package":"example.com/one"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	category := func(output string) string {
+		if strings.Contains(output, "short") {
+			return "short"
+		}
+		return "other"
+	}
+	summary := generate(exec, Config{SkipCategory: category})
+	assert.DeepEqual(t, summary.SkipCategories, map[string]int{"short": 1, "other": 1})
+
+	summary = generate(exec, Config{})
+	assert.Assert(t, summary.SkipCategories == nil)
+}
+
+const examplesInput = `{"Action":"run","Package":"example.com/one","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/one","Test":"TestOne"}
+{"Action":"run","Package":"example.com/one","Test":"ExampleOne"}
+{"Action":"pass","Package":"example.com/one","Test":"ExampleOne"}
+{"Action":"run","Package":"example.com/one","Test":"ExampleTwo"}
+{"Action":"output","Package":"example.com/one","Test":"ExampleTwo","Output":"got: 1\n"}
+{"Action":"fail","Package":"example.com/one","Test":"ExampleTwo"}
+{"Action":"fail","Package":"example.com/one"}
+`
+
+func TestGenerate_Reruns(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(examplesInput)})
+	assert.NilError(t, err)
+
+	reruns := &Reruns{Attempts: 2, Tests: 3, Elapsed: 4.5, Budget: 300}
+	summary := generate(exec, Config{Reruns: reruns})
+	assert.DeepEqual(t, summary.Reruns, reruns)
+
+	summary = generate(exec, Config{})
+	assert.Assert(t, summary.Reruns == nil)
+}
@@ -0,0 +1,81 @@
+package junitxml
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// LintIssue is a problem reported by a linter, which is written as a failed
+// test case in the testsuite of the linter.
+type LintIssue struct {
+	Linter   string
+	Severity string
+	File     string
+	Line     int
+	Column   int
+	Message  string
+}
+
+// Location returns the position of the issue in the compiler format.
+func (i LintIssue) Location() string {
+	switch {
+	case i.Line == 0:
+		return i.File
+	case i.Column == 0:
+		return fmt.Sprintf("%s:%d", i.File, i.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", i.File, i.Line, i.Column)
+	}
+}
+
+// lintSuites returns a testsuite for each linter that reported an issue. Each
+// issue is a failed test case with the directory of the file as the classname.
+func lintSuites(issues []LintIssue, cfg Config, timestamp string) []JUnitTestSuite {
+	byLinter := make(map[string][]LintIssue)
+	for _, issue := range issues {
+		byLinter[issue.Linter] = append(byLinter[issue.Linter], issue)
+	}
+	names := make([]string, 0, len(byLinter))
+	for name := range byLinter {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	suites := make([]JUnitTestSuite, 0, len(names))
+	for _, name := range names {
+		issues := byLinter[name]
+		suite := JUnitTestSuite{
+			Name:       "lint/" + name,
+			Tests:      len(issues),
+			Failures:   len(issues),
+			Assertions: intPtr(len(issues)),
+			Time:       formatDurationAsSeconds(0),
+			Timestamp:  timestamp,
+		}
+		for _, issue := range issues {
+			suite.TestCases = append(suite.TestCases, JUnitTestCase{
+				Classname: cfg.FormatTestCaseClassname(path.Dir(filepath.ToSlash(issue.File))),
+				Name:      issue.Location(),
+				Time:      formatDurationAsSeconds(0),
+				File:      issue.File,
+				Line:      issue.Line,
+				Failure: &JUnitFailure{
+					Message:  issue.Message,
+					Type:     lintFailureType(issue),
+					Contents: fmt.Sprintf("%s: %s (%s)", issue.Location(), issue.Message, issue.Linter),
+				},
+			})
+		}
+		suites = append(suites, suite)
+	}
+	return suites
+}
+
+func lintFailureType(issue LintIssue) string {
+	if issue.Severity == "" {
+		return issue.Linter
+	}
+	return issue.Linter + "/" + issue.Severity
+}
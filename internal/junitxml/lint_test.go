@@ -0,0 +1,41 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite_LintIssues(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader("")})
+	assert.NilError(t, err)
+
+	issues := []LintIssue{
+		{Linter: "errcheck", Severity: "error", File: "cmd/main.go", Line: 12, Column: 5,
+			Message: "Error return value of `fh.Close` is not checked"},
+		{Linter: "govet", File: "testjson/execution.go", Line: 40,
+			Message: "unreachable code"},
+		{Linter: "errcheck", File: "main.go", Line: 3, Column: 1,
+			Message: "Error return value is not checked"},
+	}
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{
+		ProjectName:     "test",
+		LintIssues:      issues,
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+		customElapsed:   "2.1",
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "junitxml-report-lint.golden")
+}
+
+func TestLintIssue_Location(t *testing.T) {
+	assert.Equal(t, LintIssue{File: "a.go"}.Location(), "a.go")
+	assert.Equal(t, LintIssue{File: "a.go", Line: 3}.Location(), "a.go:3")
+	assert.Equal(t, LintIssue{File: "a.go", Line: 3, Column: 4}.Location(), "a.go:3:4")
+}
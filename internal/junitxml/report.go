@@ -3,11 +3,16 @@
 package junitxml
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -23,20 +28,36 @@ type JUnitTestSuites struct {
 	Failures int      `xml:"failures,attr"`
 	Errors   int      `xml:"errors,attr"`
 	Time     string   `xml:"time,attr"`
-	Suites   []JUnitTestSuite
+	// Diagnostics are the lines from the stderr of go test that are not
+	// errors, written as a comment.
+	Diagnostics string          `xml:",comment"`
+	Properties  JUnitProperties `xml:"properties,omitempty"`
+	Suites      []JUnitTestSuite
 }
 
 // JUnitTestSuite is a single JUnit test suite which may contain many
 // testcases.
 type JUnitTestSuite struct {
-	XMLName    xml.Name        `xml:"testsuite"`
-	Tests      int             `xml:"tests,attr"`
-	Failures   int             `xml:"failures,attr"`
+	XMLName  xml.Name `xml:"testsuite"`
+	Tests    int      `xml:"tests,attr"`
+	Failures int      `xml:"failures,attr"`
+	Errors   int      `xml:"errors,attr"`
+	Skipped  int      `xml:"skipped,attr"`
+	// Assertions is the number of tests that passed or failed. go test does
+	// not count individual assertions. It is nil for schemas which do not
+	// allow the attribute.
+	Assertions *int            `xml:"assertions,attr,omitempty"`
 	Time       string          `xml:"time,attr"`
 	Name       string          `xml:"name,attr"`
 	Properties JUnitProperties `xml:"properties,omitempty"`
 	TestCases  []JUnitTestCase
-	Timestamp  string `xml:"timestamp,attr"`
+	Timestamp  string `xml:"timestamp,attr,omitempty"`
+	Hostname   string `xml:"hostname,attr,omitempty"`
+	ID         string `xml:"id,attr,omitempty"`
+	Package    string `xml:"package,attr,omitempty"`
+	// SystemOut and SystemErr are only set for schemas which require them.
+	SystemOut *string `xml:"system-out,omitempty"`
+	SystemErr *string `xml:"system-err,omitempty"`
 }
 
 // JUnitTestCase is a single test case with its result.
@@ -45,9 +66,16 @@ type JUnitTestCase struct {
 	Classname   string            `xml:"classname,attr"`
 	Name        string            `xml:"name,attr"`
 	Time        string            `xml:"time,attr"`
+	File        string            `xml:"file,attr,omitempty"`
+	Line        int               `xml:"line,attr,omitempty"`
 	SkipMessage *JUnitSkipMessage `xml:"skipped,omitempty"`
 	Failure     *JUnitFailure     `xml:"failure,omitempty"`
-	Properties  JUnitProperties   `xml:"properties,omitempty"`
+	// Error is set for build errors.
+	Error      *JUnitFailure   `xml:"error,omitempty"`
+	Properties JUnitProperties `xml:"properties,omitempty"`
+	// SystemOut contains the Jenkins attachment references, one per line, for
+	// files attached to the test by the test run.
+	SystemOut string `xml:"system-out,omitempty"`
 }
 
 // JUnitSkipMessage contains the reason why a testcase was skipped.
@@ -80,6 +108,57 @@ type Config struct {
 	FormatTestSuiteName     FormatFunc
 	FormatTestCaseClassname FormatFunc
 	HideEmptyPackages       bool
+	// HideExamples removes the test cases of Example functions.
+	HideExamples bool
+	// Sort is the order of test cases in each testsuite. Defaults to
+	// SortRunOrder.
+	Sort Sort
+	// Schema of the document. Defaults to SchemaJenkins.
+	Schema Schema
+	// Validate the document against Schema before it is written.
+	Validate bool
+	// PackageProperties returns additional properties to add to the testsuite
+	// of a package. It may be nil.
+	PackageProperties func(pkgname string) []JUnitProperty
+	// Properties are added to the testsuites element. Schemas that do not
+	// allow properties on the testsuites element get the properties on every
+	// testsuite instead.
+	Properties []JUnitProperty
+	// GoVersion is the value of the go.version property of every testsuite.
+	// When it is empty the version is looked up by running go version.
+	GoVersion string
+	// Attachments returns the paths of files attached to a test case. It may
+	// be nil.
+	Attachments func(tc testjson.TestCase) []string
+	// TestCaseProperties returns additional properties to add to a test case.
+	// A failure of TestMain is a test case with the name TestMain. It may be
+	// nil.
+	TestCaseProperties func(tc testjson.TestCase) []JUnitProperty
+	// LintIssues are added to the document as failed test cases, with a
+	// testsuite for each linter.
+	LintIssues []LintIssue
+	// DisambiguateNames adds a suffix to the name of test cases that would
+	// otherwise have the same classname and name as a different test. See
+	// Collisions.
+	DisambiguateNames bool
+	// RequirementProperties replace the Requirement property of a testcase
+	// with properties in the format expected by a different tool. When it is
+	// empty the requirements are the Requirement or Requirements property.
+	RequirementProperties []RequirementProperty
+	// RequirementURLTemplate is used to add a RequirementURL property, with
+	// the URL of the requirement, for each requirement of a testcase. See
+	// RequirementURL for the format. It may be empty.
+	RequirementURLTemplate string
+	// Timestamp is the timestamp of every testsuite. When it is zero the
+	// timestamp is the time the run started.
+	Timestamp time.Time
+	// Deterministic removes the values that change every time the same tests
+	// are run, so that the document is the same for every run. The time of
+	// every element is 0, durations are removed from the test output, and the
+	// timestamp is Timestamp, or the Unix epoch when Timestamp is zero.
+	Deterministic bool
+	// nameSuffixes is set by generate when DisambiguateNames is true.
+	nameSuffixes map[testIdentity]string
 	// This is used for tests to have a consistent timestamp
 	customTimestamp string
 	customElapsed   string
@@ -90,7 +169,22 @@ type FormatFunc func(string) string
 
 // Write creates an XML document and writes it to out.
 func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
-	if err := write(out, generate(exec, cfg)); err != nil {
+	cfg = configWithDefaults(cfg)
+	if !cfg.Validate {
+		if err := write(out, generate(exec, cfg)); err != nil {
+			return fmt.Errorf("failed to write JUnit XML: %v", err)
+		}
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := write(buf, generate(exec, cfg)); err != nil {
+		return fmt.Errorf("failed to write JUnit XML: %v", err)
+	}
+	if err := Validate(bytes.NewReader(buf.Bytes()), cfg.Schema); err != nil {
+		return fmt.Errorf("JUnit XML does not conform to the %v schema: %w", cfg.Schema, err)
+	}
+	if _, err := out.Write(buf.Bytes()); err != nil {
 		return fmt.Errorf("failed to write JUnit XML: %v", err)
 	}
 	return nil
@@ -98,41 +192,243 @@ func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
 
 func generate(exec *testjson.Execution, cfg Config) JUnitTestSuites {
 	cfg = configWithDefaults(cfg)
-	version := goVersion()
+	version := cfg.GoVersion
+	if version == "" {
+		version = goVersion()
+	}
 	suites := JUnitTestSuites{
-		Name:     cfg.ProjectName,
-		Tests:    exec.Total(),
-		Failures: len(exec.Failed()),
-		Errors:   len(exec.Errors()),
-		Time:     formatDurationAsSeconds(time.Since(exec.Started())),
+		Name:       cfg.ProjectName,
+		Tests:      exec.Total(),
+		Failures:   len(exec.Failed()),
+		Errors:     len(exec.Errors()),
+		Time:       formatDurationAsSeconds(time.Since(exec.Started())),
+		Properties: JUnitProperties{cfg.Properties},
 	}
 
+	if cfg.HideExamples {
+		suites.Tests -= exec.Examples()
+		suites.Failures -= countExamples(exec.Failed())
+	}
 	if cfg.customElapsed != "" {
 		suites.Time = cfg.customElapsed
 	}
+	suites.Diagnostics = formatDiagnostics(exec.Diagnostics())
+	if cfg.DisambiguateNames {
+		cfg.nameSuffixes = nameSuffixes(Collisions(exec, cfg))
+	}
+	timestamp := suiteTimestamp(exec, cfg)
+	runRequirements := make(map[string]bool)
+	buildErrs := buildErrorsByPackage(exec.BuildErrors())
 	for _, pkgname := range exec.Packages() {
 		pkg := exec.Package(pkgname)
-		if cfg.HideEmptyPackages && pkg.IsEmpty() {
+		if cfg.HideEmptyPackages && pkg.IsEmpty() && len(buildErrs[pkgname]) == 0 {
 			continue
 		}
 		properties := JUnitProperties{packageProperties(version)}
+		if seed := pkg.ShuffleSeed(); seed != "" {
+			properties.Property = append(properties.Property,
+				JUnitProperty{Name: "go.test.shuffle", Value: seed})
+		}
+		if cfg.PackageProperties != nil {
+			properties.Property = append(properties.Property, cfg.PackageProperties(pkgname)...)
+		}
+		if reqs := packageRequirements(pkg, cfg); len(reqs) > 0 {
+			properties.Property = append(properties.Property, requirementsProperty(reqs))
+			for _, req := range reqs {
+				runRequirements[req] = true
+			}
+		}
 		junitpkg := JUnitTestSuite{
 			Name:       cfg.FormatTestSuiteName(pkgname),
 			Tests:      pkg.Total,
 			Time:       formatDurationAsSeconds(pkg.Elapsed()),
 			Properties: properties,
-			TestCases:  packageTestCases(pkg, cfg.FormatTestCaseClassname),
+			TestCases:  packageTestCases(pkgname, pkg, cfg),
 			Failures:   len(pkg.Failed),
-			Timestamp:  cfg.customTimestamp,
+			Errors:     packageErrors(pkg),
+			Skipped:    len(pkg.Skipped),
+			Assertions: intPtr(len(pkg.Passed) + len(pkg.Failed)),
+			Timestamp:  timestamp,
+		}
+		if cfg.HideExamples {
+			hideExampleCounts(&junitpkg, pkg)
 		}
-		if cfg.customTimestamp == "" {
-			junitpkg.Timestamp = exec.Started().Format(time.RFC3339)
+		if errs := buildErrs[pkgname]; len(errs) > 0 {
+			junitpkg.TestCases = append(junitpkg.TestCases, buildErrorTestCases(errs, cfg.FormatTestCaseClassname)...)
+			junitpkg.Tests += len(errs)
+			junitpkg.Errors += len(errs)
+			delete(buildErrs, pkgname)
 		}
 		suites.Suites = append(suites.Suites, junitpkg)
 	}
+	suites.Suites = append(suites.Suites, buildErrorSuites(buildErrs, cfg, version, timestamp)...)
+	suites.Tests += len(exec.BuildErrors())
+	suites.Suites = append(suites.Suites, lintSuites(cfg.LintIssues, cfg, timestamp)...)
+	suites.Tests += len(cfg.LintIssues)
+	suites.Failures += len(cfg.LintIssues)
+	// schemas without testsuites properties would copy the list to every
+	// testsuite, which already has the requirements of its tests.
+	if len(runRequirements) > 0 && supportsProperties(cfg.Schema) {
+		suites.Properties.Property = append(suites.Properties.Property,
+			requirementsProperty(sortedKeys(runRequirements)))
+	}
+	if cfg.Deterministic {
+		removeDurations(&suites)
+	}
+	applySchema(&suites, cfg.Schema)
 	return suites
 }
 
+func suiteTimestamp(exec *testjson.Execution, cfg Config) string {
+	switch {
+	case cfg.customTimestamp != "":
+		return cfg.customTimestamp
+	case !cfg.Timestamp.IsZero():
+		return cfg.Timestamp.UTC().Format(time.RFC3339)
+	case cfg.Deterministic:
+		return time.Unix(0, 0).UTC().Format(time.RFC3339)
+	}
+	return exec.Started().Format(time.RFC3339)
+}
+
+// durationPattern matches the elapsed time printed by go test at the end of a
+// test, like --- FAIL: TestOne (0.12s), or a package, like ok pkg 0.123s.
+var durationPattern = regexp.MustCompile(
+	`(?m)^(\s*--- (?:PASS|FAIL|SKIP): .+ \()\d+\.\d+s\)$|^((?:ok|FAIL)\s+\S+\s+)\d+\.\d+s`)
+
+// removeDurations sets the time of every element to 0, and replaces the
+// durations in the test output with 0, for Config.Deterministic.
+func removeDurations(suites *JUnitTestSuites) {
+	zero := formatDurationAsSeconds(0)
+	replace := func(text string) string {
+		return durationPattern.ReplaceAllStringFunc(text, func(match string) string {
+			groups := durationPattern.FindStringSubmatch(match)
+			if groups[1] != "" {
+				return groups[1] + "0.00s)"
+			}
+			return groups[2] + "0.000s"
+		})
+	}
+	suites.Time = zero
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		suite.Time = zero
+		for j := range suite.TestCases {
+			tc := &suite.TestCases[j]
+			tc.Time = zero
+			if tc.Failure != nil {
+				tc.Failure.Contents = replace(tc.Failure.Contents)
+			}
+			if tc.SkipMessage != nil {
+				tc.SkipMessage.Message = replace(tc.SkipMessage.Message)
+			}
+		}
+	}
+}
+
+// hideExampleCounts removes the examples in pkg from the counts of the suite.
+func hideExampleCounts(suite *JUnitTestSuite, pkg *testjson.Package) {
+	failed := countExamples(pkg.Failed)
+	passed := countExamples(pkg.Passed)
+	suite.Tests -= pkg.Examples
+	suite.Failures -= failed
+	suite.Skipped -= countExamples(pkg.Skipped)
+	*suite.Assertions -= passed + failed
+}
+
+func countExamples(tcs []testjson.TestCase) int {
+	count := 0
+	for _, tc := range tcs {
+		if tc.Test.IsExample() {
+			count++
+		}
+	}
+	return count
+}
+
+// packageErrors returns the number of errors in the package. A package has an
+// error when it failed without any failed tests, for example when TestMain
+// exits non-zero. These failures are reported as a TestMain test case.
+func packageErrors(pkg *testjson.Package) int {
+	if pkg.TestMainFailed() {
+		return 1
+	}
+	return 0
+}
+
+// buildErrorsByPackage groups build errors by package. Errors without a
+// package, like those reported by a static analysis tool, are grouped by the
+// directory of the file.
+func buildErrorsByPackage(errs []testjson.BuildError) map[string][]testjson.BuildError {
+	result := make(map[string][]testjson.BuildError)
+	for _, buildErr := range errs {
+		pkg := buildErr.Package
+		if pkg == "" {
+			pkg = path.Dir(filepath.ToSlash(buildErr.File))
+		}
+		result[pkg] = append(result[pkg], buildErr)
+	}
+	return result
+}
+
+// buildErrorSuites returns a testsuite for each package that failed to build,
+// and has no test events.
+func buildErrorSuites(
+	buildErrs map[string][]testjson.BuildError,
+	cfg Config,
+	version string,
+	timestamp string,
+) []JUnitTestSuite {
+	names := make([]string, 0, len(buildErrs))
+	for name := range buildErrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	suites := make([]JUnitTestSuite, 0, len(names))
+	for _, name := range names {
+		errs := buildErrs[name]
+		properties := JUnitProperties{packageProperties(version)}
+		if cfg.PackageProperties != nil {
+			properties.Property = append(properties.Property, cfg.PackageProperties(name)...)
+		}
+		suites = append(suites, JUnitTestSuite{
+			Name:       cfg.FormatTestSuiteName(name),
+			Tests:      len(errs),
+			Errors:     len(errs),
+			Assertions: intPtr(0),
+			Time:       formatDurationAsSeconds(0),
+			Properties: properties,
+			TestCases:  buildErrorTestCases(errs, cfg.FormatTestCaseClassname),
+			Timestamp:  timestamp,
+		})
+	}
+	return suites
+}
+
+func buildErrorTestCases(errs []testjson.BuildError, formatClassname FormatFunc) []JUnitTestCase {
+	cases := make([]JUnitTestCase, 0, len(errs))
+	for _, buildErr := range errs {
+		cases = append(cases, JUnitTestCase{
+			Classname: formatClassname(buildErr.Package),
+			Name:      buildErr.Location(),
+			Time:      formatDurationAsSeconds(0),
+			File:      buildErr.File,
+			Line:      buildErr.Line,
+			Error: &JUnitFailure{
+				Message:  buildErr.Message,
+				Type:     "BuildError",
+				Contents: buildErr.String(),
+			},
+		})
+	}
+	return cases
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
 func configWithDefaults(cfg Config) Config {
 	noop := func(v string) string {
 		return v
@@ -143,9 +439,29 @@ func configWithDefaults(cfg Config) Config {
 	if cfg.FormatTestCaseClassname == nil {
 		cfg.FormatTestCaseClassname = noop
 	}
+	if cfg.Schema == "" {
+		cfg.Schema = SchemaJenkins
+	}
 	return cfg
 }
 
+// formatDiagnostics returns the text of the comment with the diagnostics, one
+// per line. An XML comment can not contain --, so it is replaced with - -.
+func formatDiagnostics(diagnostics []string) string {
+	if len(diagnostics) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString(" go test diagnostics:\n")
+	for _, line := range diagnostics {
+		for strings.Contains(line, "--") {
+			line = strings.Replace(line, "--", "- -", -1)
+		}
+		buf.WriteString(line + "\n")
+	}
+	return buf.String()
+}
+
 func formatDurationAsSeconds(d time.Duration) string {
 	return fmt.Sprintf("%f", d.Seconds())
 }
@@ -176,44 +492,152 @@ func goVersion() string {
 	return strings.TrimPrefix(strings.TrimSpace(string(out)), "go version ")
 }
 
-func packageTestCases(pkg *testjson.Package, formatClassname FormatFunc) []JUnitTestCase {
-	cases := []JUnitTestCase{}
+// Sort is the order of test cases in a testsuite.
+type Sort string
+
+const (
+	// SortRunOrder sorts test cases in the order they started. It is the
+	// default.
+	SortRunOrder Sort = "runorder"
+	// SortName sorts test cases by name.
+	SortName Sort = "name"
+	// SortOutcome groups test cases by outcome: failed, skipped, then passed.
+	// Within each group test cases are in run order.
+	SortOutcome Sort = "outcome"
+)
+
+// Sorts is the list of all supported sort orders.
+var Sorts = []Sort{SortRunOrder, SortName, SortOutcome}
+
+// ParseSort returns the Sort with name, or an error if there is no sort order
+// with that name.
+func ParseSort(name string) (Sort, error) {
+	names := make([]string, 0, len(Sorts))
+	for _, sort := range Sorts {
+		if string(sort) == name {
+			return sort, nil
+		}
+		names = append(names, string(sort))
+	}
+	return "", fmt.Errorf("invalid sort: %v, must be one of: %v", name, strings.Join(names, ", "))
+}
+
+type outcome int
+
+const (
+	outcomeFailed outcome = iota
+	outcomeSkipped
+	outcomePassed
+)
+
+type sortableTestCase struct {
+	tc      testjson.TestCase
+	outcome outcome
+	junit   JUnitTestCase
+}
+
+func packageTestCases(pkgname string, pkg *testjson.Package, cfg Config) []JUnitTestCase {
+	formatClassname := cfg.FormatTestCaseClassname
+	var cases []sortableTestCase
 
 	if pkg.TestMainFailed() {
-		jtc := newJUnitTestCase(testjson.TestCase{Test: "TestMain"}, formatClassname)
+		tc := testjson.TestCase{Test: "TestMain"}
+		jtc := newJUnitTestCase(tc, formatClassname, cfg.RequirementProperties)
 		jtc.Failure = &JUnitFailure{
 			Message:  "Failed",
 			Contents: pkg.Output(0),
 		}
-		cases = append(cases, jtc)
+		cases = append(cases, sortableTestCase{tc: tc, outcome: outcomeFailed, junit: jtc})
 	}
 
 	for _, tc := range pkg.Failed {
-		jtc := newJUnitTestCase(tc, formatClassname)
+		jtc := newJUnitTestCase(tc, formatClassname, cfg.RequirementProperties)
 		jtc.Failure = &JUnitFailure{
 			Message:  "Failed",
 			Contents: strings.Join(pkg.OutputLines(tc), ""),
 		}
-		cases = append(cases, jtc)
+		cases = append(cases, sortableTestCase{tc: tc, outcome: outcomeFailed, junit: jtc})
 	}
 
 	for _, tc := range pkg.Skipped {
-		jtc := newJUnitTestCase(tc, formatClassname)
+		jtc := newJUnitTestCase(tc, formatClassname, cfg.RequirementProperties)
 		jtc.SkipMessage = &JUnitSkipMessage{
 			Message: strings.Join(pkg.OutputLines(tc), ""),
 		}
-		cases = append(cases, jtc)
+		cases = append(cases, sortableTestCase{tc: tc, outcome: outcomeSkipped, junit: jtc})
 	}
 
 	for _, tc := range pkg.Passed {
-		jtc := newJUnitTestCase(tc, formatClassname)
-		cases = append(cases, jtc)
+		jtc := newJUnitTestCase(tc, formatClassname, cfg.RequirementProperties)
+		cases = append(cases, sortableTestCase{tc: tc, outcome: outcomePassed, junit: jtc})
 	}
-	return cases
+
+	sortTestCases(cases, cfg.Sort)
+	result := make([]JUnitTestCase, 0, len(cases))
+	for _, c := range cases {
+		if cfg.HideExamples && c.tc.Test.IsExample() {
+			continue
+		}
+		if cfg.Attachments != nil {
+			c.junit.SystemOut = attachmentRefs(cfg.Attachments(c.tc))
+		}
+		if cfg.RequirementURLTemplate != "" {
+			reqs, _ := SplitRequirements(c.tc.Test.Name())
+			c.junit.Properties.Property = append(c.junit.Properties.Property,
+				requirementURLProperties(reqs, cfg.RequirementURLTemplate)...)
+		}
+		if cfg.TestCaseProperties != nil {
+			tc := c.tc
+			tc.Package = pkgname
+			c.junit.Properties.Property = append(c.junit.Properties.Property, cfg.TestCaseProperties(tc)...)
+		}
+		c.junit.Name += cfg.nameSuffixes[testIdentity{pkg: c.tc.Package, test: c.tc.Test.Name()}]
+		result = append(result, c.junit)
+	}
+	return result
+}
+
+// attachmentRefs returns the paths formatted using the convention of the
+// Jenkins JUnit attachments plugin, which is also used by GitLab.
+func attachmentRefs(paths []string) string {
+	var buf strings.Builder
+	for _, path := range paths {
+		buf.WriteString("[[ATTACHMENT|" + path + "]]\n")
+	}
+	return buf.String()
+}
+
+// sortTestCases sorts cases by order. Ties are broken by the TestCase.ID, which
+// is the order the tests started, so the order is the same for every run.
+func sortTestCases(cases []sortableTestCase, order Sort) {
+	less := func(i, j int) bool {
+		return cases[i].tc.ID < cases[j].tc.ID
+	}
+	switch order {
+	case SortName:
+		less = func(i, j int) bool {
+			if cases[i].tc.Test != cases[j].tc.Test {
+				return cases[i].tc.Test < cases[j].tc.Test
+			}
+			return cases[i].tc.ID < cases[j].tc.ID
+		}
+	case SortOutcome:
+		less = func(i, j int) bool {
+			if cases[i].outcome != cases[j].outcome {
+				return cases[i].outcome < cases[j].outcome
+			}
+			return cases[i].tc.ID < cases[j].tc.ID
+		}
+	}
+	sort.SliceStable(cases, less)
 }
 
-func newJUnitTestCase(tc testjson.TestCase, formatClassname FormatFunc) JUnitTestCase {
+func newJUnitTestCase(tc testjson.TestCase, formatClassname FormatFunc, mapping []RequirementProperty) JUnitTestCase {
 	props, strippedName := extractRequirementFromName(tc.Test.Name())
+	if len(mapping) > 0 {
+		requirements, _ := SplitRequirements(tc.Test.Name())
+		props = MapRequirements(requirements, mapping)
+	}
 	return JUnitTestCase{
 		Classname:  formatClassname(tc.Package),
 		Name:       strippedName,
@@ -222,6 +646,18 @@ func newJUnitTestCase(tc testjson.TestCase, formatClassname FormatFunc) JUnitTes
 	}
 }
 
+// SplitRequirements returns the requirements from the square brackets in a
+// test name, and the name with the brackets removed. The JUnit XML report uses
+// the requirements as the Requirement property of the testcase, and the
+// stripped name as the name of the testcase.
+func SplitRequirements(name string) (requirements []string, strippedName string) {
+	props, strippedName := extractRequirementFromName(name)
+	for _, prop := range props {
+		requirements = append(requirements, strings.Split(prop.Value, ",")...)
+	}
+	return requirements, strippedName
+}
+
 func extractRequirementFromName(name string) (props []JUnitProperty, strippedName string) {
 
 	// Find the opening and closing square brackets in the name
@@ -255,6 +691,108 @@ func extractRequirementFromName(name string) (props []JUnitProperty, strippedNam
 	return nil, name
 }
 
+// Collision is a classname and name of a testcase that is used by more than
+// one test. Test cases with the same classname and name are reported as the
+// same test by most tools that read JUnit XML.
+type Collision struct {
+	Classname string
+	Name      string
+	// Tests are the package and full name of each test with the classname and
+	// name, sorted by package and name.
+	Tests []CollisionTest
+}
+
+// CollisionTest is one of the tests in a Collision.
+type CollisionTest struct {
+	Package string
+	Test    string
+}
+
+type testIdentity struct {
+	pkg  string
+	test string
+}
+
+// Collisions returns the classname and name of the test cases that are used by
+// more than one test, after the requirements are removed from the names. A
+// test that ran more than once is not a collision. Collisions are sorted by
+// classname and name.
+func Collisions(exec *testjson.Execution, cfg Config) []Collision {
+	cfg = configWithDefaults(cfg)
+	type key struct {
+		classname string
+		name      string
+	}
+	tests := make(map[key]map[testIdentity]bool)
+	for _, pkgname := range exec.Packages() {
+		for _, tc := range exec.Package(pkgname).TestCases() {
+			if cfg.HideExamples && tc.Test.IsExample() {
+				continue
+			}
+			_, name := extractRequirementFromName(tc.Test.Name())
+			k := key{classname: cfg.FormatTestCaseClassname(tc.Package), name: name}
+			if tests[k] == nil {
+				tests[k] = make(map[testIdentity]bool)
+			}
+			tests[k][testIdentity{pkg: tc.Package, test: tc.Test.Name()}] = true
+		}
+	}
+
+	var result []Collision
+	for k, ids := range tests {
+		if len(ids) < 2 {
+			continue
+		}
+		c := Collision{Classname: k.classname, Name: k.name}
+		for id := range ids {
+			c.Tests = append(c.Tests, CollisionTest{Package: id.pkg, Test: id.test})
+		}
+		sort.Slice(c.Tests, func(i, j int) bool {
+			if c.Tests[i].Package != c.Tests[j].Package {
+				return c.Tests[i].Package < c.Tests[j].Package
+			}
+			return c.Tests[i].Test < c.Tests[j].Test
+		})
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Classname != result[j].Classname {
+			return result[i].Classname < result[j].Classname
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// nameSuffixes returns the suffix that makes the name of each test in the
+// collisions unique. The suffix is the full name of the test when the tests
+// are in the same package, the package when the tests have the same name, and
+// both otherwise.
+func nameSuffixes(collisions []Collision) map[testIdentity]string {
+	suffixes := make(map[testIdentity]string)
+	for _, c := range collisions {
+		pkgs := make(map[string]bool)
+		names := make(map[string]bool)
+		for _, tc := range c.Tests {
+			pkgs[tc.Package] = true
+			names[tc.Test] = true
+		}
+		for _, tc := range c.Tests {
+			var suffix string
+			switch {
+			case len(pkgs) == 1:
+				suffix = tc.Test
+			case len(names) == 1:
+				suffix = tc.Package
+			default:
+				suffix = tc.Package + "." + tc.Test
+			}
+			suffixes[testIdentity{pkg: tc.Package, test: tc.Test}] = " (" + suffix + ")"
+		}
+	}
+	return suffixes
+}
+
 // Marshals the JUnitProperties into XML. Returns nil if no properties are set,
 // allowing the omitempty xml tag to function correctly.
 func (prop JUnitProperties) MarshalXML(e *xml.Encoder, start xml.StartElement) (err error) {
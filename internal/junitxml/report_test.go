@@ -2,15 +2,19 @@ package junitxml
 
 import (
 	"bytes"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"runtime"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"gotest.tools/gotestsum/testjson"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/env"
 	"gotest.tools/v3/golden"
 )
@@ -44,6 +48,93 @@ func TestWrite_HideEmptyPackages(t *testing.T) {
 	golden.Assert(t, out.String(), "junitxml-report-skip-empty.golden")
 }
 
+func TestGenerate_ShuffleSeed(t *testing.T) {
+	raw, err := ioutil.ReadFile("../../testjson/testdata/input/go-test-json-with-shuffle.out")
+	assert.NilError(t, err)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: bytes.NewReader(raw)})
+	assert.NilError(t, err)
+
+	env.Patch(t, "GOVERSION", "go7.7.7")
+	suites := generate(exec, Config{})
+	for _, suite := range suites.Suites {
+		if suite.Name != "gotest.tools/gotestsum/testjson/internal/good" {
+			continue
+		}
+		assert.DeepEqual(t, suite.Properties.Property, []JUnitProperty{
+			{Name: "go.version", Value: "go7.7.7"},
+			{Name: "go.test.shuffle", Value: "123456"},
+		})
+		return
+	}
+	t.Fatal("missing testsuite for package good")
+}
+
+func TestGenerate_Deterministic(t *testing.T) {
+	exec := createExecution(t)
+	env.Patch(t, "GOVERSION", "go7.7.7")
+
+	type testCase struct {
+		name      string
+		timestamp time.Time
+		expected  string
+	}
+	run := func(t *testing.T, tc testCase) {
+		suites := generate(exec, Config{Deterministic: true, Timestamp: tc.timestamp})
+		assert.Equal(t, suites.Time, "0.000000")
+		var failures int
+		for _, suite := range suites.Suites {
+			assert.Equal(t, suite.Time, "0.000000")
+			assert.Equal(t, suite.Timestamp, tc.expected)
+			for _, c := range suite.TestCases {
+				assert.Equal(t, c.Time, "0.000000")
+				if c.Failure == nil {
+					continue
+				}
+				failures++
+				assert.Assert(t, !durationPattern.MatchString(
+					strings.NewReplacer("(0.00s)", "", "0.000s", "").Replace(c.Failure.Contents)),
+					c.Failure.Contents)
+			}
+		}
+		assert.Assert(t, failures > 0)
+	}
+
+	testCases := []testCase{
+		{name: "epoch", expected: "1970-01-01T00:00:00Z"},
+		{
+			name:      "timestamp",
+			timestamp: time.Date(2022, 3, 4, 5, 6, 7, 0, time.FixedZone("x", 3600)),
+			expected:  "2022-03-04T04:06:07Z",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestRemoveDurations(t *testing.T) {
+	suites := &JUnitTestSuites{Suites: []JUnitTestSuite{{
+		Time: "1.200000",
+		TestCases: []JUnitTestCase{{
+			Time: "0.500000",
+			Failure: &JUnitFailure{Contents: "    thing_test.go:12: broken\n" +
+				"--- FAIL: TestThing (0.51s)\n" +
+				"    --- FAIL: TestThing/sub (0.12s)\n" +
+				"FAIL\tgotest.tools/thing\t1.234s\n"},
+		}},
+	}}}
+	removeDurations(suites)
+	assert.Equal(t, suites.Suites[0].Time, "0.000000")
+	assert.Equal(t, suites.Suites[0].TestCases[0].Time, "0.000000")
+	expected := "    thing_test.go:12: broken\n" +
+		"--- FAIL: TestThing (0.00s)\n" +
+		"    --- FAIL: TestThing/sub (0.00s)\n" +
+		"FAIL\tgotest.tools/thing\t0.000s\n"
+	assert.Equal(t, suites.Suites[0].TestCases[0].Failure.Contents, expected)
+}
+
 func createExecution(t *testing.T) *testjson.Execution {
 	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
 		Stdout: readTestData(t, "out"),
@@ -70,3 +161,306 @@ func TestGoVersion(t *testing.T) {
 		assert.Equal(t, goVersion(), expected)
 	})
 }
+
+func TestGenerate_SuiteCounts(t *testing.T) {
+	exec := createExecution(t)
+	env.Patch(t, "GOVERSION", "go7.7.7")
+
+	suites := generate(exec, Config{})
+	for _, suite := range suites.Suites {
+		pkg := exec.Package(suite.Name)
+		if pkg == nil {
+			// packages that failed to build
+			assert.Equal(t, suite.Errors, suite.Tests, suite.Name)
+			continue
+		}
+		assert.Equal(t, suite.Skipped, len(pkg.Skipped), suite.Name)
+		assert.Equal(t, *suite.Assertions, len(pkg.Passed)+len(pkg.Failed), suite.Name)
+		expectedErrors := 0
+		if pkg.TestMainFailed() {
+			expectedErrors = 1
+		}
+		assert.Equal(t, suite.Errors, expectedErrors, suite.Name)
+	}
+}
+
+func TestGenerate_Sort(t *testing.T) {
+	exec := createExecution(t)
+	env.Patch(t, "GOVERSION", "go7.7.7")
+
+	names := func(order Sort) []string {
+		suites := generate(exec, Config{Sort: order})
+		for _, suite := range suites.Suites {
+			if suite.Name != "gotest.tools/gotestsum/testjson/internal/withfails" {
+				continue
+			}
+			var result []string
+			for _, tc := range suite.TestCases {
+				result = append(result, tc.Name)
+			}
+			return result
+		}
+		t.Fatal("missing testsuite for package withfails")
+		return nil
+	}
+
+	t.Run("runorder is the default", func(t *testing.T) {
+		assert.DeepEqual(t, names(""), names(SortRunOrder))
+	})
+
+	t.Run("name", func(t *testing.T) {
+		actual := names(SortName)
+		assert.Assert(t, sort.StringsAreSorted(actual), actual)
+	})
+
+	t.Run("outcome", func(t *testing.T) {
+		suites := generate(exec, Config{Sort: SortOutcome})
+		for _, suite := range suites.Suites {
+			var seenSkip, seenPass bool
+			for _, tc := range suite.TestCases {
+				switch {
+				case tc.Failure != nil:
+					assert.Assert(t, !seenSkip && !seenPass, "failure after skip or pass in %v", suite.Name)
+				case tc.SkipMessage != nil:
+					assert.Assert(t, !seenPass, "skip after pass in %v", suite.Name)
+					seenSkip = true
+				default:
+					seenPass = true
+				}
+			}
+		}
+	})
+}
+
+func TestParseSort(t *testing.T) {
+	order, err := ParseSort("name")
+	assert.NilError(t, err)
+	assert.Equal(t, order, SortName)
+
+	_, err = ParseSort("bogus")
+	assert.Error(t, err, "invalid sort: bogus, must be one of: runorder, name, outcome")
+}
+
+func TestGenerate_BuildErrors(t *testing.T) {
+	exec := createExecution(t)
+	env.Patch(t, "GOVERSION", "go7.7.7")
+
+	suites := generate(exec, Config{})
+	last := suites.Suites[len(suites.Suites)-1]
+	assert.Equal(t, last.Tests, len(last.TestCases))
+	assert.Equal(t, last.Name, "gotest.tools/gotestsum/testjson/internal/broken")
+	assert.DeepEqual(t, last.TestCases, []JUnitTestCase{
+		{
+			Classname: "gotest.tools/gotestsum/testjson/internal/broken",
+			Name:      "testjson/internal/broken/broken.go:5:21",
+			Time:      "0.000000",
+			File:      "testjson/internal/broken/broken.go",
+			Line:      5,
+			Error: &JUnitFailure{
+				Message:  "undefined: somepackage",
+				Type:     "BuildError",
+				Contents: "testjson/internal/broken/broken.go:5:21: undefined: somepackage",
+			},
+		},
+	})
+}
+
+func TestGenerate_Attachments(t *testing.T) {
+	exec := createExecution(t)
+	env.Patch(t, "GOVERSION", "go7.7.7")
+
+	cfg := Config{
+		Attachments: func(tc testjson.TestCase) []string {
+			if tc.Test.Name() == "TestPassed" {
+				return []string{"/reports/attachments/TestPassed/log.txt"}
+			}
+			return nil
+		},
+	}
+	suites := generate(exec, cfg)
+	var found int
+	for _, suite := range suites.Suites {
+		for _, tc := range suite.TestCases {
+			if tc.Name != "TestPassed" {
+				assert.Equal(t, tc.SystemOut, "", tc.Name)
+				continue
+			}
+			found++
+			assert.Equal(t, tc.SystemOut, "[[ATTACHMENT|/reports/attachments/TestPassed/log.txt]]\n")
+		}
+	}
+	assert.Assert(t, found > 0)
+}
+
+func TestGenerate_TestCaseProperties(t *testing.T) {
+	exec := createExecution(t)
+	env.Patch(t, "GOVERSION", "go7.7.7")
+
+	cfg := Config{
+		TestCaseProperties: func(tc testjson.TestCase) []JUnitProperty {
+			if tc.Test.Name() == "TestFailed" {
+				return []JUnitProperty{{Name: "severity", Value: tc.Package}}
+			}
+			return nil
+		},
+	}
+	suites := generate(exec, cfg)
+	var found int
+	for _, suite := range suites.Suites {
+		for _, tc := range suite.TestCases {
+			if tc.Name != "TestFailed" {
+				continue
+			}
+			found++
+			assert.DeepEqual(t, tc.Properties.Property, []JUnitProperty{
+				{Name: "severity", Value: suite.Name},
+			})
+		}
+	}
+	assert.Assert(t, found > 0)
+}
+
+func TestGenerate_HideExamples(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/one","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/one","Test":"TestOne"}
+{"Action":"run","Package":"example.com/one","Test":"ExampleOne"}
+{"Action":"pass","Package":"example.com/one","Test":"ExampleOne"}
+{"Action":"run","Package":"example.com/one","Test":"ExampleTwo"}
+{"Action":"fail","Package":"example.com/one","Test":"ExampleTwo"}
+{"Action":"fail","Package":"example.com/one"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	env.Patch(t, "GOVERSION", "go7.7.7")
+
+	suites := generate(exec, Config{})
+	assert.Equal(t, suites.Tests, 3)
+	assert.Equal(t, len(suites.Suites[0].TestCases), 3)
+
+	suites = generate(exec, Config{HideExamples: true})
+	assert.Equal(t, suites.Tests, 1)
+	assert.Equal(t, suites.Failures, 0)
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 1)
+	assert.Equal(t, suite.Failures, 0)
+	assert.Equal(t, *suite.Assertions, 1)
+	assert.Equal(t, len(suite.TestCases), 1)
+	assert.Equal(t, suite.TestCases[0].Name, "TestOne")
+}
+
+func TestSplitRequirements(t *testing.T) {
+	var testcases = []struct {
+		name         string
+		requirements []string
+		stripped     string
+	}{
+		{name: "TestLogin", stripped: "TestLogin"},
+		{name: "TestLogin[REQ-1]", requirements: []string{"REQ-1"}, stripped: "TestLogin"},
+		{name: "TestLogin/[REQ-1,REQ-2]_expired", requirements: []string{"REQ-1", "REQ-2"}, stripped: "TestLogin/_expired"},
+	}
+	for _, tc := range testcases {
+		requirements, stripped := SplitRequirements(tc.name)
+		assert.DeepEqual(t, requirements, tc.requirements)
+		assert.Equal(t, stripped, tc.stripped)
+	}
+}
+
+func collisionExecution(t *testing.T) *testjson.Execution {
+	t.Helper()
+	events := []string{
+		`{"Action":"run","Package":"example.com/a/util","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"pass","Package":"example.com/a/util","Test":"TestLogin[REQ-1]"}`,
+		`{"Action":"run","Package":"example.com/a/util","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"fail","Package":"example.com/a/util","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"run","Package":"example.com/a/util","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"pass","Package":"example.com/a/util","Test":"TestLogin[REQ-2]"}`,
+		`{"Action":"run","Package":"example.com/a/util","Test":"TestParse"}`,
+		`{"Action":"pass","Package":"example.com/a/util","Test":"TestParse"}`,
+		`{"Action":"pass","Package":"example.com/a/util"}`,
+		`{"Action":"run","Package":"example.com/b/util","Test":"TestParse"}`,
+		`{"Action":"pass","Package":"example.com/b/util","Test":"TestParse"}`,
+		`{"Action":"pass","Package":"example.com/b/util"}`,
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(strings.Join(events, "\n")),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestCollisions(t *testing.T) {
+	exec := collisionExecution(t)
+
+	expected := []Collision{
+		{
+			Classname: "example.com/a/util",
+			Name:      "TestLogin",
+			Tests: []CollisionTest{
+				{Package: "example.com/a/util", Test: "TestLogin[REQ-1]"},
+				{Package: "example.com/a/util", Test: "TestLogin[REQ-2]"},
+			},
+		},
+	}
+	assert.DeepEqual(t, Collisions(exec, Config{}), expected)
+
+	short := func(pkg string) string {
+		return pkg[strings.LastIndex(pkg, "/")+1:]
+	}
+	expected = append(expected, Collision{
+		Classname: "util",
+		Name:      "TestParse",
+		Tests: []CollisionTest{
+			{Package: "example.com/a/util", Test: "TestParse"},
+			{Package: "example.com/b/util", Test: "TestParse"},
+		},
+	})
+	expected[0].Classname = "util"
+	assert.DeepEqual(t, Collisions(exec, Config{FormatTestCaseClassname: short}), expected)
+}
+
+func TestGenerate_DisambiguateNames(t *testing.T) {
+	exec := collisionExecution(t)
+	short := func(pkg string) string {
+		return pkg[strings.LastIndex(pkg, "/")+1:]
+	}
+	cfg := Config{FormatTestCaseClassname: short, DisambiguateNames: true, Sort: SortName}
+
+	var names []string
+	for _, suite := range generate(exec, cfg).Suites {
+		for _, tc := range suite.TestCases {
+			names = append(names, tc.Classname+" "+tc.Name)
+		}
+	}
+	expected := []string{
+		"util TestLogin (TestLogin[REQ-1])",
+		"util TestLogin (TestLogin[REQ-2])",
+		"util TestLogin (TestLogin[REQ-2])",
+		"util TestParse (example.com/a/util)",
+		"util TestParse (example.com/b/util)",
+	}
+	assert.DeepEqual(t, names, expected)
+	assert.Equal(t, len(Collisions(exec, cfg)), 2, "collisions are reported before the suffix is added")
+}
+
+func TestWrite_Diagnostics(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"pass","Package":"example.com/a"}` + "\n"),
+		Stderr: strings.NewReader(
+			"go: downloading example.com/dep v1.2.0\nwarning: flag --old-flag is deprecated\n"),
+		CaptureDiagnostics: true,
+	})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, Write(out, exec, Config{}))
+	expected := `<testsuites tests="0" failures="0" errors="0" time=`
+	assert.Assert(t, cmp.Contains(out.String(), expected))
+	expected = `<!-- go test diagnostics:
+go: downloading example.com/dep v1.2.0
+warning: flag - -old-flag is deprecated
+-->`
+	assert.Assert(t, cmp.Contains(out.String(), expected))
+
+	var parsed JUnitTestSuites
+	assert.NilError(t, xml.Unmarshal(out.Bytes(), &parsed))
+}
@@ -0,0 +1,191 @@
+package junitxml
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// requirementsPropertyName is the name of the property of a testsuite with
+// the requirements of all of its test cases, and of the testsuites element
+// with the requirements of every test in the run.
+const requirementsPropertyName = "requirements"
+
+func requirementsProperty(reqs []string) JUnitProperty {
+	return JUnitProperty{Name: requirementsPropertyName, Value: strings.Join(reqs, ",")}
+}
+
+// packageRequirements returns the sorted requirements from the names of all
+// the tests in the package.
+func packageRequirements(pkg *testjson.Package, cfg Config) []string {
+	reqs := make(map[string]bool)
+	for _, tc := range pkg.TestCases() {
+		if cfg.HideExamples && tc.Test.IsExample() {
+			continue
+		}
+		names, _ := SplitRequirements(tc.Test.Name())
+		for _, name := range names {
+			if name != "" {
+				reqs[name] = true
+			}
+		}
+	}
+	return sortedKeys(reqs)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RequirementProperty maps the requirements in the name of a test to a
+// property of the testcase, for tools that expect a different property than
+// Requirement.
+type RequirementProperty struct {
+	// Name of the property.
+	Name string
+	// Format of the value of the property. When the format contains {id} or
+	// {number} a property is added for each requirement, with {id} replaced
+	// by the requirement, and {number} replaced by the digits at the end of
+	// the requirement, like 12 for REQ-12. Otherwise {ids} is replaced by the
+	// requirements separated by commas, and one property is added.
+	Format string
+}
+
+// MapRequirements returns the properties for the requirements of a test,
+// using the mapping. It returns nil when there are no requirements.
+func MapRequirements(requirements []string, mapping []RequirementProperty) []JUnitProperty {
+	if len(requirements) == 0 {
+		return nil
+	}
+	var props []JUnitProperty
+	for _, m := range mapping {
+		if !strings.Contains(m.Format, "{id}") && !strings.Contains(m.Format, "{number}") {
+			value := strings.Replace(m.Format, "{ids}", strings.Join(requirements, ","), -1)
+			props = append(props, JUnitProperty{Name: m.Name, Value: value})
+			continue
+		}
+		for _, req := range requirements {
+			value := strings.Replace(m.Format, "{id}", req, -1)
+			value = strings.Replace(value, "{number}", requirementNumber(req), -1)
+			props = append(props, JUnitProperty{Name: m.Name, Value: value})
+		}
+	}
+	return props
+}
+
+// requirementURLPropertyName is the name of the property of a testcase with
+// the URL of one of its requirements.
+const requirementURLPropertyName = "RequirementURL"
+
+// RequirementURL returns the URL of a requirement from a template, like
+// https://jira.example.com/browse/{id}. {id} is replaced by the requirement,
+// and {number} by the digits at the end of the requirement. It returns an
+// empty string when the template is empty.
+func RequirementURL(template, id string) string {
+	if template == "" || id == "" {
+		return ""
+	}
+	value := strings.Replace(template, "{id}", url.PathEscape(id), -1)
+	return strings.Replace(value, "{number}", url.PathEscape(requirementNumber(id)), -1)
+}
+
+// requirementURLProperties returns a RequirementURL property for each
+// requirement.
+func requirementURLProperties(requirements []string, template string) []JUnitProperty {
+	var props []JUnitProperty
+	for _, req := range requirements {
+		if u := RequirementURL(template, req); u != "" {
+			props = append(props, JUnitProperty{Name: requirementURLPropertyName, Value: u})
+		}
+	}
+	return props
+}
+
+// requirementNumber returns the digits at the end of the requirement, or the
+// requirement when it does not end with a digit.
+func requirementNumber(req string) string {
+	i := len(req)
+	for i > 0 && req[i-1] >= '0' && req[i-1] <= '9' {
+		i--
+	}
+	if i == len(req) {
+		return req
+	}
+	return req[i:]
+}
+
+// RequirementResult is the number of tests of a requirement with each result.
+type RequirementResult struct {
+	ID      string
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// RequirementResults returns the results of the tests of every requirement in
+// the names of the tests in exec, sorted by ID. Each test is counted once,
+// with the result of its last run, so a test that failed and then passed when
+// it was re-run is counted as passed. Example functions are excluded when
+// hideExamples is true.
+func RequirementResults(exec *testjson.Execution, hideExamples bool) []RequirementResult {
+	byID := make(map[string]*RequirementResult)
+	for _, pkgname := range exec.Packages() {
+		pkg := exec.Package(pkgname)
+		last := make(map[string]testjson.TestCase)
+		results := make(map[string]string)
+		record := func(tcs []testjson.TestCase, result string) {
+			for _, tc := range tcs {
+				name := tc.Test.Name()
+				if prev, ok := last[name]; ok && prev.ID > tc.ID {
+					continue
+				}
+				last[name] = tc
+				results[name] = result
+			}
+		}
+		record(pkg.Passed, "pass")
+		record(pkg.Failed, "fail")
+		record(pkg.Skipped, "skip")
+
+		for name, result := range results {
+			if hideExamples && testjson.TestName(name).IsExample() {
+				continue
+			}
+			reqs, _ := SplitRequirements(name)
+			for _, id := range reqs {
+				if id == "" {
+					continue
+				}
+				r, ok := byID[id]
+				if !ok {
+					r = &RequirementResult{ID: id}
+					byID[id] = r
+				}
+				switch result {
+				case "pass":
+					r.Passed++
+				case "fail":
+					r.Failed++
+				case "skip":
+					r.Skipped++
+				}
+			}
+		}
+	}
+
+	result := make([]RequirementResult, 0, len(byID))
+	for _, r := range byID {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
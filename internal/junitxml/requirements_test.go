@@ -0,0 +1,145 @@
+package junitxml
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestMapRequirements(t *testing.T) {
+	mapping := []RequirementProperty{
+		{Name: "polarion-testcase-id", Format: "{id}"},
+		{Name: "codebeamer.tracker", Format: "TRK-{number}"},
+		{Name: "Requirement", Format: "{ids}"},
+	}
+	props := MapRequirements([]string{"REQ-12", "REQ-4"}, mapping)
+	expected := []JUnitProperty{
+		{Name: "polarion-testcase-id", Value: "REQ-12"},
+		{Name: "polarion-testcase-id", Value: "REQ-4"},
+		{Name: "codebeamer.tracker", Value: "TRK-12"},
+		{Name: "codebeamer.tracker", Value: "TRK-4"},
+		{Name: "Requirement", Value: "REQ-12,REQ-4"},
+	}
+	assert.DeepEqual(t, props, expected)
+
+	assert.Assert(t, MapRequirements(nil, mapping) == nil)
+	assert.DeepEqual(t, MapRequirements([]string{"LOGIN"}, []RequirementProperty{{Name: "n", Format: "{number}"}}),
+		[]JUnitProperty{{Name: "n", Value: "LOGIN"}})
+}
+
+func TestNewJUnitTestCase_RequirementProperties(t *testing.T) {
+	tc := testjson.TestCase{Package: "pkg", Test: "TestLogin[REQ-1,REQ-2]"}
+	mapping := []RequirementProperty{{Name: "test_id", Format: "{id}"}}
+
+	jtc := newJUnitTestCase(tc, func(v string) string { return v }, mapping)
+	assert.Equal(t, jtc.Name, "TestLogin")
+	assert.DeepEqual(t, jtc.Properties.Property, []JUnitProperty{
+		{Name: "test_id", Value: "REQ-1"},
+		{Name: "test_id", Value: "REQ-2"},
+	})
+
+	jtc = newJUnitTestCase(tc, func(v string) string { return v }, nil)
+	assert.DeepEqual(t, jtc.Properties.Property, []JUnitProperty{
+		{Name: "Requirements", Value: "REQ-1,REQ-2"},
+	})
+}
+
+func TestGenerate_RequirementsRollup(t *testing.T) {
+	events := []string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogin[REQ-9,REQ-1]"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestLogin[REQ-9,REQ-1]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogout[REQ-4]"}`,
+		`{"Action":"fail","Package":"example.com/a","Test":"TestLogout[REQ-4]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestParse"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestParse"}`,
+		`{"Action":"fail","Package":"example.com/a"}`,
+		`{"Action":"run","Package":"example.com/b","Test":"TestSave[REQ-1]"}`,
+		`{"Action":"skip","Package":"example.com/b","Test":"TestSave[REQ-1]"}`,
+		`{"Action":"pass","Package":"example.com/b"}`,
+		`{"Action":"run","Package":"example.com/c","Test":"TestNone"}`,
+		`{"Action":"pass","Package":"example.com/c","Test":"TestNone"}`,
+		`{"Action":"pass","Package":"example.com/c"}`,
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(strings.Join(events, "\n")),
+	})
+	assert.NilError(t, err)
+
+	suiteRequirements := func(suite JUnitTestSuite) string {
+		for _, prop := range suite.Properties.Property {
+			if prop.Name == "requirements" {
+				return prop.Value
+			}
+		}
+		return ""
+	}
+
+	suites := generate(exec, Config{GoVersion: "go7.7.7"})
+	assert.DeepEqual(t, suites.Properties.Property, []JUnitProperty{
+		{Name: "requirements", Value: "REQ-1,REQ-4,REQ-9"},
+	})
+	assert.Equal(t, len(suites.Suites), 3)
+	assert.Equal(t, suiteRequirements(suites.Suites[0]), "REQ-1,REQ-4,REQ-9")
+	assert.Equal(t, suiteRequirements(suites.Suites[1]), "REQ-1")
+	assert.Equal(t, suiteRequirements(suites.Suites[2]), "")
+
+	suites = generate(exec, Config{GoVersion: "go7.7.7", Schema: SchemaSurefire})
+	assert.Equal(t, len(suites.Properties.Property), 0)
+	assert.Equal(t, suiteRequirements(suites.Suites[1]), "REQ-1")
+}
+
+func TestRequirementResults(t *testing.T) {
+	events := []string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogin[REQ-2,REQ-1]"}`,
+		`{"Action":"fail","Package":"example.com/a","Test":"TestLogin[REQ-2,REQ-1]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogout[REQ-2]"}`,
+		`{"Action":"fail","Package":"example.com/a","Test":"TestLogout[REQ-2]"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"ExampleLogin[REQ-3]"}`,
+		`{"Action":"skip","Package":"example.com/a","Test":"ExampleLogin[REQ-3]"}`,
+		`{"Action":"fail","Package":"example.com/a"}`,
+		// TestLogin passed when it was re-run
+		`{"Action":"run","Package":"example.com/a","Test":"TestLogin[REQ-2,REQ-1]"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestLogin[REQ-2,REQ-1]"}`,
+		`{"Action":"pass","Package":"example.com/a"}`,
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(strings.Join(events, "\n")),
+	})
+	assert.NilError(t, err)
+
+	expected := []RequirementResult{
+		{ID: "REQ-1", Passed: 1},
+		{ID: "REQ-2", Passed: 1, Failed: 1},
+		{ID: "REQ-3", Skipped: 1},
+	}
+	assert.DeepEqual(t, RequirementResults(exec, false), expected)
+	assert.DeepEqual(t, RequirementResults(exec, true), expected[:2])
+}
+
+func TestRequirementURL(t *testing.T) {
+	assert.Equal(t, RequirementURL("https://jira.example.com/browse/{id}", "REQ-12"),
+		"https://jira.example.com/browse/REQ-12")
+	assert.Equal(t, RequirementURL("https://tracker.example.com/item/{number}", "REQ-12"),
+		"https://tracker.example.com/item/12")
+	assert.Equal(t, RequirementURL("https://example.com/{id}", "a b/c"), "https://example.com/a%20b%2Fc")
+	assert.Equal(t, RequirementURL("", "REQ-12"), "")
+}
+
+func TestGenerate_RequirementURL(t *testing.T) {
+	events := `{"Action":"run","Package":"example.com/a","Test":"TestLogin[REQ-1,REQ-2]"}
+{"Action":"pass","Package":"example.com/a","Test":"TestLogin[REQ-1,REQ-2]"}
+{"Action":"pass","Package":"example.com/a"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(events)})
+	assert.NilError(t, err)
+
+	suites := generate(exec, Config{GoVersion: "go7.7.7", RequirementURLTemplate: "https://jira.example.com/browse/{id}"})
+	props := suites.Suites[0].TestCases[0].Properties.Property
+	assert.DeepEqual(t, props, []JUnitProperty{
+		{Name: "Requirements", Value: "REQ-1,REQ-2"},
+		{Name: "RequirementURL", Value: "https://jira.example.com/browse/REQ-1"},
+		{Name: "RequirementURL", Value: "https://jira.example.com/browse/REQ-2"},
+	})
+}
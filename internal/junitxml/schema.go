@@ -0,0 +1,340 @@
+package junitxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Schema identifies the dialect of JUnit XML expected by the consumer of the
+// report. Consumers disagree on which elements and attributes are allowed or
+// required, so the generated document is adjusted to match the schema.
+type Schema string
+
+const (
+	// SchemaJenkins is the dialect accepted by the Jenkins JUnit plugin. It is
+	// the default.
+	SchemaJenkins Schema = "jenkins"
+	// SchemaSurefire is the dialect of the Maven Surefire test report XSD.
+	SchemaSurefire Schema = "surefire"
+	// SchemaAnt is the dialect of the Apache Ant JUnit task XSD.
+	SchemaAnt Schema = "ant"
+	// SchemaGitLab is the dialect accepted by GitLab unit test reports.
+	SchemaGitLab Schema = "gitlab"
+	// SchemaPolarion is the dialect of the Polarion xUnit importer. It is the
+	// same as SchemaJenkins, except the testsuites and testcase elements only
+	// have the properties used by Polarion, which start with polarion-.
+	SchemaPolarion Schema = "polarion"
+)
+
+// Schemas is the list of all supported schemas.
+var Schemas = []Schema{SchemaJenkins, SchemaSurefire, SchemaAnt, SchemaGitLab, SchemaPolarion}
+
+// polarionPropertyPrefix is the prefix of the properties read by the Polarion
+// xUnit importer.
+const polarionPropertyPrefix = "polarion-"
+
+// supportsProperties returns true if the schema allows properties on the
+// testsuites and testcase elements.
+func supportsProperties(schema Schema) bool {
+	return schema == SchemaJenkins || schema == SchemaPolarion
+}
+
+// ParseSchema returns the Schema with name, or an error if there is no schema
+// with that name.
+func ParseSchema(name string) (Schema, error) {
+	for _, schema := range Schemas {
+		if string(schema) == name {
+			return schema, nil
+		}
+	}
+	names := make([]string, 0, len(Schemas))
+	for _, schema := range Schemas {
+		names = append(names, string(schema))
+	}
+	return "", fmt.Errorf("invalid schema: %v, must be one of: %v", name, strings.Join(names, ", "))
+}
+
+// applySchema modifies suites so that the document conforms to schema.
+func applySchema(suites *JUnitTestSuites, schema Schema) {
+	// testsuites properties are only supported by Jenkins and Polarion.
+	var suiteProperties []JUnitProperty
+	if !supportsProperties(schema) {
+		suiteProperties = suites.Properties.Property
+		suites.Properties = JUnitProperties{}
+	}
+	if schema == SchemaPolarion {
+		suites.Properties.Property = polarionProperties(suites.Properties.Property)
+	}
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		suite.Properties.Property = append(suite.Properties.Property, suiteProperties...)
+		switch schema {
+		case SchemaSurefire:
+			suite.Timestamp = ""
+			suite.Assertions = nil
+		case SchemaAnt:
+			suite.Assertions = nil
+			suite.ID = strconv.Itoa(i)
+			suite.Package = suite.Name
+			suite.Hostname = "localhost"
+			suite.SystemOut = new(string)
+			suite.SystemErr = new(string)
+		}
+		for j := range suite.TestCases {
+			tc := &suite.TestCases[j]
+			switch schema {
+			case SchemaAnt, SchemaSurefire:
+				tc.File, tc.Line = "", 0
+			case SchemaGitLab:
+				tc.Line = 0
+			}
+			if schema == SchemaAnt {
+				tc.SystemOut = ""
+			}
+			// test case properties are only supported by Jenkins and Polarion.
+			switch {
+			case schema == SchemaPolarion:
+				tc.Properties.Property = polarionProperties(tc.Properties.Property)
+			case !supportsProperties(schema):
+				tc.Properties = JUnitProperties{}
+			}
+		}
+	}
+}
+
+// polarionProperties returns the properties with the polarion- prefix.
+func polarionProperties(props []JUnitProperty) []JUnitProperty {
+	var result []JUnitProperty
+	for _, prop := range props {
+		if strings.HasPrefix(prop.Name, polarionPropertyPrefix) {
+			result = append(result, prop)
+		}
+	}
+	return result
+}
+
+// elementRule is the set of attributes and child elements allowed by a schema
+// for an element.
+type elementRule struct {
+	required []string
+	optional []string
+	children []string
+	// text is true if the element may contain character data.
+	text bool
+}
+
+type schemaRules struct {
+	roots    []string
+	elements map[string]elementRule
+}
+
+var propertiesRules = map[string]elementRule{
+	"properties": {children: []string{"property"}},
+	"property":   {required: []string{"name", "value"}},
+}
+
+func rulesWith(base map[string]elementRule, elements map[string]elementRule) map[string]elementRule {
+	result := make(map[string]elementRule, len(base)+len(elements))
+	for name, rule := range base {
+		result[name] = rule
+	}
+	for name, rule := range elements {
+		result[name] = rule
+	}
+	return result
+}
+
+var failureRule = elementRule{optional: []string{"message", "type"}, text: true}
+
+// schemaDefinitions are derived from the XSD published by each consumer. Only
+// the elements and attributes that can be generated by this package are
+// included, which means a document that is valid according to these rules is
+// also valid according to the XSD, but not the other way around.
+var schemaDefinitions = map[Schema]schemaRules{
+	SchemaJenkins: {
+		roots: []string{"testsuites", "testsuite"},
+		elements: rulesWith(propertiesRules, map[string]elementRule{
+			"testsuites": {
+				optional: []string{"name", "tests", "failures", "errors", "time", "disabled"},
+				children: []string{"properties", "testsuite"},
+			},
+			"testsuite": {
+				required: []string{"name", "tests"},
+				optional: []string{"failures", "errors", "skipped", "assertions", "time",
+					"timestamp", "hostname", "id", "package", "disabled"},
+				children: []string{"properties", "testcase", "system-out", "system-err"},
+			},
+			"testcase": {
+				required: []string{"name"},
+				optional: []string{"classname", "time", "file", "line", "assertions", "status"},
+				children: []string{"properties", "skipped", "failure", "error", "system-out", "system-err"},
+			},
+			"skipped":    {optional: []string{"message"}, text: true},
+			"failure":    failureRule,
+			"error":      failureRule,
+			"system-out": {text: true},
+			"system-err": {text: true},
+		}),
+	},
+	SchemaSurefire: {
+		roots: []string{"testsuites", "testsuite"},
+		elements: rulesWith(propertiesRules, map[string]elementRule{
+			"testsuites": {
+				optional: []string{"name", "tests", "failures", "errors", "time"},
+				children: []string{"testsuite"},
+			},
+			"testsuite": {
+				required: []string{"name", "tests", "failures", "errors", "skipped", "time"},
+				optional: []string{"group"},
+				children: []string{"properties", "testcase"},
+			},
+			"testcase": {
+				required: []string{"name"},
+				optional: []string{"classname", "time", "group"},
+				children: []string{"skipped", "failure", "error", "system-out", "system-err"},
+			},
+			"skipped":    {optional: []string{"message"}, text: true},
+			"failure":    failureRule,
+			"error":      failureRule,
+			"system-out": {text: true},
+			"system-err": {text: true},
+		}),
+	},
+	SchemaAnt: {
+		roots: []string{"testsuites", "testsuite"},
+		elements: rulesWith(propertiesRules, map[string]elementRule{
+			"testsuites": {
+				optional: []string{"name", "tests", "failures", "errors", "time"},
+				children: []string{"testsuite"},
+			},
+			"testsuite": {
+				required: []string{"name", "timestamp", "hostname", "tests", "failures",
+					"errors", "time", "id", "package"},
+				// skipped is not in the XSD, but is written by Ant since 1.9.
+				optional: []string{"skipped"},
+				children: []string{"properties", "testcase", "system-out", "system-err"},
+			},
+			"testcase": {
+				required: []string{"name", "classname", "time"},
+				children: []string{"skipped", "failure", "error"},
+			},
+			"skipped":    {optional: []string{"message"}, text: true},
+			"failure":    failureRule,
+			"error":      failureRule,
+			"system-out": {text: true},
+			"system-err": {text: true},
+		}),
+	},
+	SchemaGitLab: {
+		roots: []string{"testsuites", "testsuite"},
+		elements: rulesWith(propertiesRules, map[string]elementRule{
+			"testsuites": {
+				optional: []string{"name", "tests", "failures", "errors", "time"},
+				children: []string{"testsuite"},
+			},
+			"testsuite": {
+				optional: []string{"name", "tests", "failures", "errors", "skipped", "assertions",
+					"time", "timestamp"},
+				children: []string{"properties", "testcase", "system-out", "system-err"},
+			},
+			"testcase": {
+				required: []string{"name"},
+				optional: []string{"classname", "time", "file"},
+				children: []string{"skipped", "failure", "error", "system-out", "system-err"},
+			},
+			"skipped":    {optional: []string{"message"}, text: true},
+			"failure":    failureRule,
+			"error":      failureRule,
+			"system-out": {text: true},
+			"system-err": {text: true},
+		}),
+	},
+}
+
+func init() {
+	schemaDefinitions[SchemaPolarion] = schemaDefinitions[SchemaJenkins]
+}
+
+// Validate reads a JUnit XML document from r, and returns an error if the
+// document does not conform to schema.
+func Validate(r io.Reader, schema Schema) error {
+	rules, ok := schemaDefinitions[schema]
+	if !ok {
+		return fmt.Errorf("unknown schema %v", schema)
+	}
+
+	dec := xml.NewDecoder(r)
+	var stack []string
+	var hasRoot bool
+	for {
+		token, err := dec.Token()
+		switch {
+		case err == io.EOF:
+			if !hasRoot {
+				return fmt.Errorf("document has no root element")
+			}
+			return nil
+		case err != nil:
+			return fmt.Errorf("invalid XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			switch {
+			case len(stack) == 0 && hasRoot:
+				return fmt.Errorf("document has more than one root element")
+			case len(stack) == 0 && !contains(rules.roots, name):
+				return fmt.Errorf("element %v is not allowed as the root element", name)
+			case len(stack) > 0:
+				parent := stack[len(stack)-1]
+				if !contains(rules.elements[parent].children, name) {
+					return fmt.Errorf("element %v is not allowed in %v", name, parent)
+				}
+			}
+			hasRoot = true
+			if err := validateAttrs(rules.elements[name], t, strings.Join(append(stack, name), "/")); err != nil {
+				return err
+			}
+			stack = append(stack, name)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) == 0 || len(strings.TrimSpace(string(t))) == 0 {
+				continue
+			}
+			if name := stack[len(stack)-1]; !rules.elements[name].text {
+				return fmt.Errorf("element %v must not contain text", name)
+			}
+		}
+	}
+}
+
+func validateAttrs(rule elementRule, elem xml.StartElement, path string) error {
+	seen := make(map[string]bool, len(elem.Attr))
+	for _, attr := range elem.Attr {
+		name := attr.Name.Local
+		if !contains(rule.required, name) && !contains(rule.optional, name) {
+			return fmt.Errorf("attribute %v is not allowed on %v", name, path)
+		}
+		seen[name] = true
+	}
+	for _, name := range rule.required {
+		if !seen[name] {
+			return fmt.Errorf("attribute %v is required on %v", name, path)
+		}
+	}
+	return nil
+}
+
+func contains(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite_Schemas(t *testing.T) {
+	exec := createExecution(t)
+	env.Patch(t, "GOVERSION", "go7.7.7")
+
+	for _, schema := range Schemas {
+		schema := schema
+		t.Run(string(schema), func(t *testing.T) {
+			out := new(bytes.Buffer)
+			err := Write(out, exec, Config{
+				ProjectName:     "test",
+				Schema:          schema,
+				Validate:        true,
+				customTimestamp: new(time.Time).Format(time.RFC3339),
+				customElapsed:   "2.1",
+			})
+			assert.NilError(t, err)
+			if schema == SchemaJenkins {
+				golden.Assert(t, out.String(), "junitxml-report.golden")
+				return
+			}
+			golden.Assert(t, out.String(), "junitxml-report-"+string(schema)+".golden")
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		name     string
+		schema   Schema
+		doc      string
+		expected string
+	}
+	testCases := []testCase{
+		{
+			name:   "valid",
+			schema: SchemaSurefire,
+			doc: `<testsuites><testsuite name="a" tests="1" failures="0" errors="0" skipped="0" time="1">
+<testcase name="TestA" classname="a" time="1"></testcase></testsuite></testsuites>`,
+		},
+		{
+			name:     "missing required attribute",
+			schema:   SchemaSurefire,
+			doc:      `<testsuites><testsuite name="a" tests="1"></testsuite></testsuites>`,
+			expected: "attribute failures is required on testsuites/testsuite",
+		},
+		{
+			name:     "attribute not allowed",
+			schema:   SchemaSurefire,
+			doc:      `<testsuite name="a" tests="1" failures="0" errors="0" skipped="0" time="1" timestamp="x"/>`,
+			expected: "attribute timestamp is not allowed on testsuite",
+		},
+		{
+			name:     "element not allowed",
+			schema:   SchemaAnt,
+			doc:      `<testsuites><testcase name="a"/></testsuites>`,
+			expected: "element testcase is not allowed in testsuites",
+		},
+		{
+			name:     "text not allowed",
+			schema:   SchemaJenkins,
+			doc:      `<testsuites>text</testsuites>`,
+			expected: "element testsuites must not contain text",
+		},
+		{
+			name:     "root not allowed",
+			schema:   SchemaJenkins,
+			doc:      `<testcase name="a"/>`,
+			expected: "element testcase is not allowed as the root element",
+		},
+		{
+			name:     "empty document",
+			schema:   SchemaJenkins,
+			expected: "document has no root element",
+		},
+		{
+			name:     "unknown schema",
+			schema:   "bogus",
+			expected: "unknown schema bogus",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(strings.NewReader(tc.doc), tc.schema)
+			if tc.expected == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tc.expected)
+		})
+	}
+}
+
+func TestParseSchema(t *testing.T) {
+	schema, err := ParseSchema("ant")
+	assert.NilError(t, err)
+	assert.Equal(t, schema, SchemaAnt)
+
+	_, err = ParseSchema("bogus")
+	assert.Error(t, err, "invalid schema: bogus, must be one of: jenkins, surefire, ant, gitlab, polarion")
+}
+
+func TestApplySchema_Polarion(t *testing.T) {
+	suites := JUnitTestSuites{
+		Properties: JUnitProperties{[]JUnitProperty{
+			{Name: "polarion-project-id", Value: "PROJ"},
+			{Name: "label.env", Value: "staging"},
+		}},
+		Suites: []JUnitTestSuite{{
+			Properties: JUnitProperties{[]JUnitProperty{{Name: "go.version", Value: "go7.7.7"}}},
+			TestCases: []JUnitTestCase{{
+				Name: "TestLogin",
+				Properties: JUnitProperties{[]JUnitProperty{
+					{Name: "polarion-testcase-id", Value: "PROJ-1"},
+					{Name: "Requirement", Value: "PROJ-1"},
+				}},
+			}},
+		}},
+	}
+	applySchema(&suites, SchemaPolarion)
+	assert.DeepEqual(t, suites.Properties.Property, []JUnitProperty{
+		{Name: "polarion-project-id", Value: "PROJ"},
+	})
+	assert.DeepEqual(t, suites.Suites[0].Properties.Property, []JUnitProperty{
+		{Name: "go.version", Value: "go7.7.7"},
+	})
+	assert.DeepEqual(t, suites.Suites[0].TestCases[0].Properties.Property, []JUnitProperty{
+		{Name: "polarion-testcase-id", Value: "PROJ-1"},
+	})
+}
+
+func TestWrite_Properties(t *testing.T) {
+	exec := createExecution(t)
+	env.Patch(t, "GOVERSION", "go7.7.7")
+	props := []JUnitProperty{{Name: "label.env", Value: "staging"}}
+
+	for _, schema := range Schemas {
+		schema := schema
+		t.Run(string(schema), func(t *testing.T) {
+			out := new(bytes.Buffer)
+			err := Write(out, exec, Config{Schema: schema, Validate: true, Properties: props})
+			assert.NilError(t, err)
+
+			prop := `<property name="label.env" value="staging"></property>`
+			if schema == SchemaJenkins {
+				assert.Assert(t, strings.Contains(out.String(), "<testsuites "), out.String())
+				start := strings.Index(out.String(), "<properties>")
+				assert.Assert(t, start < strings.Index(out.String(), "<testsuite "), out.String())
+				assert.Equal(t, strings.Count(out.String(), prop), 1)
+				return
+			}
+			if schema == SchemaPolarion {
+				assert.Equal(t, strings.Count(out.String(), prop), 0)
+				return
+			}
+			assert.Equal(t, strings.Count(out.String(), prop), strings.Count(out.String(), "<testsuite "))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/fatih/color"
 )
@@ -16,8 +17,8 @@ const (
 )
 
 var (
-	level = WarnLevel
-	out   = color.Error
+	level           = WarnLevel
+	out   io.Writer = color.Error
 )
 
 // SetLevel for the global logger.
@@ -25,6 +26,16 @@ func SetLevel(l Level) {
 	level = l
 }
 
+// SetOutput sets the writer used by the global logger.
+func SetOutput(w io.Writer) {
+	out = w
+}
+
+// Output returns the writer used by the global logger.
+func Output() io.Writer {
+	return out
+}
+
 // Warnf prints the message to stderr, with a yellow WARN prefix.
 func Warnf(format string, args ...interface{}) {
 	if level < WarnLevel {
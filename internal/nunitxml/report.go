@@ -0,0 +1,363 @@
+/*Package nunitxml creates an NUnit 3 XML report from a testjson.Execution.
+ */
+package nunitxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// TestRun is the root element of an NUnit 3 document.
+type TestRun struct {
+	XMLName       xml.Name    `xml:"test-run"`
+	ID            string      `xml:"id,attr"`
+	TestCaseCount int         `xml:"testcasecount,attr"`
+	Result        string      `xml:"result,attr"`
+	Total         int         `xml:"total,attr"`
+	Passed        int         `xml:"passed,attr"`
+	Failed        int         `xml:"failed,attr"`
+	Warnings      int         `xml:"warnings,attr"`
+	Inconclusive  int         `xml:"inconclusive,attr"`
+	Skipped       int         `xml:"skipped,attr"`
+	Asserts       int         `xml:"asserts,attr"`
+	EngineVersion string      `xml:"engine-version,attr,omitempty"`
+	StartTime     string      `xml:"start-time,attr"`
+	EndTime       string      `xml:"end-time,attr"`
+	Duration      string      `xml:"duration,attr"`
+	Suites        []TestSuite `xml:"test-suite"`
+}
+
+// TestSuite is a package, with a type of Assembly, or the test fixture of a
+// package, which contains the test cases.
+type TestSuite struct {
+	Type          string      `xml:"type,attr"`
+	ID            string      `xml:"id,attr"`
+	Name          string      `xml:"name,attr"`
+	FullName      string      `xml:"fullname,attr"`
+	ClassName     string      `xml:"classname,attr,omitempty"`
+	RunState      string      `xml:"runstate,attr"`
+	TestCaseCount int         `xml:"testcasecount,attr"`
+	Result        string      `xml:"result,attr"`
+	Total         int         `xml:"total,attr"`
+	Passed        int         `xml:"passed,attr"`
+	Failed        int         `xml:"failed,attr"`
+	Warnings      int         `xml:"warnings,attr"`
+	Inconclusive  int         `xml:"inconclusive,attr"`
+	Skipped       int         `xml:"skipped,attr"`
+	Asserts       int         `xml:"asserts,attr"`
+	Duration      string      `xml:"duration,attr"`
+	Properties    *Properties `xml:"properties,omitempty"`
+	Failure       *Failure    `xml:"failure,omitempty"`
+	Suites        []TestSuite `xml:"test-suite,omitempty"`
+	TestCases     []TestCase  `xml:"test-case,omitempty"`
+}
+
+// TestCase is a single test, or subtest, with its result.
+type TestCase struct {
+	ID          string       `xml:"id,attr"`
+	Name        string       `xml:"name,attr"`
+	FullName    string       `xml:"fullname,attr"`
+	MethodName  string       `xml:"methodname,attr"`
+	ClassName   string       `xml:"classname,attr"`
+	RunState    string       `xml:"runstate,attr"`
+	Result      string       `xml:"result,attr"`
+	Label       string       `xml:"label,attr,omitempty"`
+	StartTime   string       `xml:"start-time,attr,omitempty"`
+	EndTime     string       `xml:"end-time,attr,omitempty"`
+	Duration    string       `xml:"duration,attr"`
+	Asserts     int          `xml:"asserts,attr"`
+	Properties  *Properties  `xml:"properties,omitempty"`
+	Failure     *Failure     `xml:"failure,omitempty"`
+	Reason      *Reason      `xml:"reason,omitempty"`
+	Attachments *Attachments `xml:"attachments,omitempty"`
+}
+
+// Properties of a test suite or test case. The requirements in the name of a
+// test are added to the test case as Category properties.
+type Properties struct {
+	Property []Property `xml:"property"`
+}
+
+// Property is a name and value.
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// Failure of a test case, or of a test suite when TestMain failed.
+type Failure struct {
+	Message *CData `xml:"message"`
+}
+
+// Reason a test case was skipped.
+type Reason struct {
+	Message *CData `xml:"message"`
+}
+
+// CData is text that is written as a CDATA section.
+type CData struct {
+	Text string `xml:",cdata"`
+}
+
+// Attachments of a test case.
+type Attachments struct {
+	Attachment []Attachment `xml:"attachment"`
+}
+
+// Attachment is a file attached to a test case.
+type Attachment struct {
+	FilePath string `xml:"filePath"`
+}
+
+const (
+	resultPassed  = "Passed"
+	resultFailed  = "Failed"
+	resultSkipped = "Skipped"
+
+	timeFormat = "2006-01-02 15:04:05Z"
+)
+
+// Config used to write an NUnit XML document.
+type Config struct {
+	// Version of gotestsum, used as the engine-version of the test-run.
+	Version string
+	// PackageProperties returns additional properties to add to the test
+	// suite of a package. It may be nil.
+	PackageProperties func(pkgname string) []junitxml.JUnitProperty
+	// Attachments returns the paths of files attached to a test case. It may
+	// be nil.
+	Attachments func(tc testjson.TestCase) []string
+	// RequirementProperties add properties for the requirements of a test
+	// case, in addition to the Category of each requirement.
+	RequirementProperties []junitxml.RequirementProperty
+	// This is used for tests to have a consistent start and end time
+	customStarted time.Time
+	customEnded   time.Time
+}
+
+// Write an NUnit 3 XML document of exec to out. The document has an Assembly
+// test suite for each package, with the result of the last run of the package,
+// so a package with tests that failed and then passed when they were re-run has
+// a result of Passed.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return fmt.Errorf("failed to write NUnit XML: %v", err)
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "\t")
+	if err := enc.Encode(generate(exec, cfg)); err != nil {
+		return fmt.Errorf("failed to write NUnit XML: %v", err)
+	}
+	if _, err := io.WriteString(out, "\n"); err != nil {
+		return fmt.Errorf("failed to write NUnit XML: %v", err)
+	}
+	return nil
+}
+
+func generate(exec *testjson.Execution, cfg Config) TestRun {
+	started, ended := cfg.customStarted, cfg.customEnded
+	if started.IsZero() {
+		started = exec.Started()
+		ended = started.Add(exec.Elapsed())
+	}
+	run := TestRun{
+		ID:            "0",
+		Result:        resultPassed,
+		EngineVersion: cfg.Version,
+		StartTime:     started.UTC().Format(timeFormat),
+		EndTime:       ended.UTC().Format(timeFormat),
+		Duration:      formatDurationAsSeconds(ended.Sub(started)),
+	}
+	ids := &idGenerator{}
+	for _, pkgname := range exec.Packages() {
+		pkg := exec.Package(pkgname)
+		if pkg.IsEmpty() {
+			continue
+		}
+		suite := packageSuite(pkg, pkgname, cfg, ids)
+		run.Suites = append(run.Suites, suite)
+		run.TestCaseCount += suite.TestCaseCount
+		run.Total += suite.Total
+		run.Passed += suite.Passed
+		run.Failed += suite.Failed
+		run.Skipped += suite.Skipped
+		if suite.Result == resultFailed {
+			run.Result = resultFailed
+		}
+	}
+	if len(exec.Errors()) > 0 {
+		run.Result = resultFailed
+	}
+	return run
+}
+
+type idGenerator struct {
+	next int
+}
+
+func (g *idGenerator) id() string {
+	g.next++
+	return "0-" + strconv.Itoa(g.next)
+}
+
+// packageSuite returns the Assembly test suite of a package. The test cases
+// of the package are in a TestFixture suite, because tools that read NUnit use
+// the fixture as the class of a test case.
+func packageSuite(pkg *testjson.Package, pkgname string, cfg Config, ids *idGenerator) TestSuite {
+	assembly := TestSuite{
+		Type:     "Assembly",
+		ID:       ids.id(),
+		Name:     pkgname,
+		FullName: pkgname,
+		RunState: "Runnable",
+		Duration: formatDurationAsSeconds(pkg.Elapsed()),
+	}
+	fixture := TestSuite{
+		Type:      "TestFixture",
+		ID:        ids.id(),
+		Name:      path.Base(pkgname),
+		FullName:  pkgname,
+		ClassName: pkgname,
+		RunState:  "Runnable",
+		Duration:  assembly.Duration,
+		TestCases: packageTestCases(pkg, cfg, ids),
+	}
+
+	var props []Property
+	if seed := pkg.ShuffleSeed(); seed != "" {
+		props = append(props, Property{Name: "go.test.shuffle", Value: seed})
+	}
+	if cfg.PackageProperties != nil {
+		for _, prop := range cfg.PackageProperties(pkgname) {
+			props = append(props, Property{Name: prop.Name, Value: prop.Value})
+		}
+	}
+	if len(props) > 0 {
+		assembly.Properties = &Properties{Property: props}
+	}
+
+	fixture.TestCaseCount = len(fixture.TestCases)
+	fixture.Total = fixture.TestCaseCount
+	fixture.Passed = len(pkg.Passed)
+	fixture.Failed = len(pkg.Failed)
+	fixture.Skipped = len(pkg.Skipped)
+	fixture.Result = resultPassed
+	switch {
+	case pkg.Result() == testjson.ActionFail:
+		fixture.Result = resultFailed
+	case fixture.Total > 0 && fixture.Skipped == fixture.Total:
+		fixture.Result = resultSkipped
+	}
+	if pkg.TestMainFailed() {
+		fixture.Failure = &Failure{Message: &CData{Text: pkg.Output(0)}}
+	}
+
+	assembly.Suites = []TestSuite{fixture}
+	assembly.TestCaseCount = fixture.TestCaseCount
+	assembly.Total = fixture.Total
+	assembly.Passed = fixture.Passed
+	assembly.Failed = fixture.Failed
+	assembly.Skipped = fixture.Skipped
+	assembly.Result = fixture.Result
+	if fixture.Failure != nil {
+		assembly.Failure = &Failure{Message: &CData{Text: "TestMain failed"}}
+	}
+	return assembly
+}
+
+func packageTestCases(pkg *testjson.Package, cfg Config, ids *idGenerator) []TestCase {
+	results := make(map[int]string)
+	for _, tc := range pkg.Passed {
+		results[tc.ID] = resultPassed
+	}
+	for _, tc := range pkg.Failed {
+		results[tc.ID] = resultFailed
+	}
+	for _, tc := range pkg.Skipped {
+		results[tc.ID] = resultSkipped
+	}
+
+	tcs := pkg.TestCases()
+	sort.SliceStable(tcs, func(i, j int) bool {
+		return tcs[i].ID < tcs[j].ID
+	})
+	cases := make([]TestCase, 0, len(tcs))
+	for _, tc := range tcs {
+		ntc := newTestCase(tc, results[tc.ID], ids, cfg.RequirementProperties)
+		switch ntc.Result {
+		case resultFailed:
+			ntc.Failure = &Failure{Message: &CData{Text: strings.Join(pkg.OutputLines(tc), "")}}
+		case resultSkipped:
+			ntc.Label = "Ignored"
+			ntc.Reason = &Reason{Message: &CData{Text: strings.Join(pkg.OutputLines(tc), "")}}
+		}
+		if cfg.Attachments != nil {
+			ntc.Attachments = newAttachments(cfg.Attachments(tc))
+		}
+		cases = append(cases, ntc)
+	}
+	return cases
+}
+
+func newTestCase(
+	tc testjson.TestCase,
+	result string,
+	ids *idGenerator,
+	mapping []junitxml.RequirementProperty,
+) TestCase {
+	requirements, name := junitxml.SplitRequirements(tc.Test.Name())
+	root, _ := testjson.TestName(name).Split()
+	ntc := TestCase{
+		ID:         ids.id(),
+		Name:       name,
+		FullName:   tc.Package + "." + name,
+		MethodName: root,
+		ClassName:  tc.Package,
+		RunState:   "Runnable",
+		Result:     result,
+		Duration:   formatDurationAsSeconds(tc.Elapsed),
+	}
+	if start, end := tc.Span(); !start.IsZero() {
+		ntc.StartTime = start.UTC().Format(timeFormat)
+		ntc.EndTime = end.UTC().Format(timeFormat)
+	}
+
+	var props []Property
+	for _, req := range requirements {
+		props = append(props, Property{Name: "Category", Value: req})
+	}
+	for _, prop := range junitxml.MapRequirements(requirements, mapping) {
+		props = append(props, Property{Name: prop.Name, Value: prop.Value})
+	}
+	if tc.RunID > 0 {
+		props = append(props, Property{Name: "Attempt", Value: strconv.Itoa(tc.RunID + 1)})
+	}
+	if len(props) > 0 {
+		ntc.Properties = &Properties{Property: props}
+	}
+	return ntc
+}
+
+func newAttachments(paths []string) *Attachments {
+	if len(paths) == 0 {
+		return nil
+	}
+	attachments := &Attachments{}
+	for _, p := range paths {
+		attachments.Attachment = append(attachments.Attachment, Attachment{FilePath: p})
+	}
+	return attachments
+}
+
+func formatDurationAsSeconds(d time.Duration) string {
+	return fmt.Sprintf("%f", d.Seconds())
+}
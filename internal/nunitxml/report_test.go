@@ -0,0 +1,103 @@
+package nunitxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t)
+
+	started := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	err := Write(out, exec, Config{
+		Version: "7.7.7",
+		PackageProperties: func(pkgname string) []junitxml.JUnitProperty {
+			return []junitxml.JUnitProperty{{Name: "go.version", Value: "go7.7.7"}}
+		},
+		customStarted: started,
+		customEnded:   started.Add(2100 * time.Millisecond),
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "nunitxml-report.golden")
+
+	var run TestRun
+	assert.NilError(t, xml.Unmarshal(out.Bytes(), &run))
+	assert.Equal(t, run.Result, "Failed")
+}
+
+func createExecution(t *testing.T) *testjson.Execution {
+	t.Helper()
+	stdout, err := ioutil.ReadFile("../../testjson/testdata/input/go-test-json.out")
+	assert.NilError(t, err)
+	stderr, err := ioutil.ReadFile("../../testjson/testdata/input/go-test-json.err")
+	assert.NilError(t, err)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: bytes.NewReader(stdout),
+		Stderr: bytes.NewReader(stderr),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestGenerate_CategoriesAndAttempts(t *testing.T) {
+	input := `{"Time":"2024-01-01T10:00:00Z","Action":"run","Package":"example.com/one","Test":"TestLogin[REQ-1,REQ-2]"}
+{"Time":"2024-01-01T10:00:01Z","Action":"pass","Package":"example.com/one","Test":"TestLogin[REQ-1,REQ-2]","Elapsed":1}
+{"Time":"2024-01-01T10:00:01Z","Action":"run","Package":"example.com/one","Test":"TestParse"}
+{"Time":"2024-01-01T10:00:02Z","Action":"output","Package":"example.com/one","Test":"TestParse","Output":"    parse_test.go:9: bad input\n"}
+{"Time":"2024-01-01T10:00:02Z","Action":"fail","Package":"example.com/one","Test":"TestParse","Elapsed":1}
+{"Time":"2024-01-01T10:00:02Z","Action":"fail","Package":"example.com/one","Elapsed":2}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	rerun := `{"Action":"run","Package":"example.com/one","Test":"TestParse"}
+{"Action":"pass","Package":"example.com/one","Test":"TestParse","Elapsed":0.5}
+{"Action":"pass","Package":"example.com/one"}
+`
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    strings.NewReader(rerun),
+		Execution: exec,
+		RunID:     1,
+	})
+	assert.NilError(t, err)
+
+	run := generate(exec, Config{Attachments: func(tc testjson.TestCase) []string {
+		if tc.Test == "TestParse" && tc.RunID == 0 {
+			return []string{"out/parse.log"}
+		}
+		return nil
+	}})
+	assert.Equal(t, run.Total, 3)
+	assert.Equal(t, run.Passed, 2)
+	assert.Equal(t, run.Failed, 1)
+	assert.Equal(t, run.Result, "Passed", "failed test passed when it was re-run")
+
+	cases := run.Suites[0].Suites[0].TestCases
+	assert.Equal(t, len(cases), 3)
+	assert.Equal(t, cases[0].Name, "TestLogin")
+	assert.Equal(t, cases[0].FullName, "example.com/one.TestLogin")
+	assert.DeepEqual(t, cases[0].Properties, &Properties{Property: []Property{
+		{Name: "Category", Value: "REQ-1"},
+		{Name: "Category", Value: "REQ-2"},
+	}})
+	assert.Equal(t, cases[0].StartTime, "2024-01-01 10:00:00Z")
+	assert.Equal(t, cases[0].EndTime, "2024-01-01 10:00:01Z")
+
+	assert.Equal(t, cases[1].Result, "Failed")
+	assert.DeepEqual(t, cases[1].Failure, &Failure{Message: &CData{Text: "    parse_test.go:9: bad input\n"}})
+	assert.DeepEqual(t, cases[1].Attachments, &Attachments{Attachment: []Attachment{{FilePath: "out/parse.log"}}})
+
+	assert.Equal(t, cases[2].Result, "Passed")
+	assert.DeepEqual(t, cases[2].Properties, &Properties{Property: []Property{
+		{Name: "Attempt", Value: "2"},
+	}})
+}
@@ -0,0 +1,68 @@
+/*
+Package repro creates go test commands which run a single test again, with
+the same settings, to reproduce a failure.
+*/
+package repro
+
+import (
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Command returns the go test command that runs the test from pkg. When test
+// is empty all the tests in the package are run. If seed is not empty the
+// tests are shuffled with the same seed. args are additional flags passed to
+// go test before the package.
+func Command(pkg string, test testjson.TestName, seed string, args []string) []string {
+	cmd := []string{"go", "test"}
+	cmd = append(cmd, args...)
+	cmd = append(cmd, "-count=1")
+	if test != "" {
+		cmd = append(cmd, "-run="+RunPattern(test))
+	}
+	if seed != "" {
+		cmd = append(cmd, "-shuffle="+seed)
+	}
+	return append(cmd, pkg)
+}
+
+// RunPattern returns the value of a -run flag that matches only the test.
+func RunPattern(test testjson.TestName) string {
+	if test.IsSubTest() {
+		root, sub := test.Split()
+		return "^" + root + "$/^" + sub + "$"
+	}
+	return "^" + test.Name() + "$"
+}
+
+// ShellQuote returns the args joined with spaces, with any argument that
+// contains special characters quoted so that the command can be copied into
+// a POSIX shell.
+func ShellQuote(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, quote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+func quote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(arg, " \t\n\"'`$\\|&;<>()*?[]{}!#~^") {
+		return arg
+	}
+	// quote only the value of a flag, ex: -run='^TestA$'
+	if strings.HasPrefix(arg, "-") {
+		if i := strings.Index(arg, "="); i > 0 && !strings.ContainsAny(arg[:i], " \t'") {
+			return arg[:i+1] + singleQuote(arg[i+1:])
+		}
+	}
+	return singleQuote(arg)
+}
+
+func singleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
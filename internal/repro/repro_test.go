@@ -0,0 +1,28 @@
+package repro
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCommand(t *testing.T) {
+	cmd := Command("example.com/pkg", "TestOne/sub_case", "1234", []string{"-tags=integration"})
+	expected := []string{
+		"go", "test", "-tags=integration", "-count=1",
+		"-run=^TestOne$/^sub_case$", "-shuffle=1234", "example.com/pkg",
+	}
+	assert.DeepEqual(t, cmd, expected)
+
+	cmd = Command("example.com/pkg", "TestOne", "", nil)
+	assert.DeepEqual(t, cmd, []string{"go", "test", "-count=1", "-run=^TestOne$", "example.com/pkg"})
+
+	cmd = Command("example.com/pkg", "", "", nil)
+	assert.DeepEqual(t, cmd, []string{"go", "test", "-count=1", "example.com/pkg"})
+}
+
+func TestShellQuote(t *testing.T) {
+	args := []string{"go", "test", "-run=^TestOne$", "-ldflags", "-X main.version=1", "it's", ""}
+	assert.Equal(t, ShellQuote(args),
+		`go test -run='^TestOne$' -ldflags '-X main.version=1' 'it'\''s' ''`)
+}
@@ -0,0 +1,225 @@
+/*
+Package sarif creates a SARIF log of the test failures and build errors in a
+testjson.Execution, so that they can be shown in the code view of tools like
+GitHub code scanning.
+*/
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Log is the top level object of a SARIF 2.1.0 document.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is a single run of a tool.
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+// Tool describes the tool that created the results.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is the component of the tool that created the results.
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+	Version        string `json:"version,omitempty"`
+	Rules          []Rule `json:"rules"`
+}
+
+// Rule is the kind of a result.
+type Rule struct {
+	ID               string  `json:"id"`
+	ShortDescription Message `json:"shortDescription"`
+}
+
+// Result is a single test failure or build error.
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+}
+
+// Message is the text of a result or description.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Location of a result in a file.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation is a file, and a region in the file.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is the URI of a file. Relative URIs are relative to the
+// root of the source, identified by URIBaseID.
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Region is the line, and optional column, of a result in a file.
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+const (
+	// RuleTestFailure is the rule of the results for failed tests.
+	RuleTestFailure = "test-failure"
+	// RuleBuildError is the rule of the results for build errors.
+	RuleBuildError = "build-error"
+
+	schemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	srcRoot   = "%SRCROOT%"
+)
+
+// Config used to write a SARIF log.
+type Config struct {
+	// Version of gotestsum.
+	Version string
+	// PackageDir returns the directory of a package, relative to the root of
+	// the source. Defaults to testjson.RelativePackagePath.
+	PackageDir func(pkgname string) string
+	// PackageName formats the package path in the messages of the results.
+	// Defaults to testjson.RelativePackagePath.
+	PackageName func(pkgpath string) string
+}
+
+// Write a SARIF log of the failures and build errors in exec to out.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(generate(exec, cfg)); err != nil {
+		return fmt.Errorf("failed to write SARIF: %w", err)
+	}
+	return nil
+}
+
+func generate(exec *testjson.Execution, cfg Config) Log {
+	if cfg.PackageDir == nil {
+		cfg.PackageDir = testjson.RelativePackagePath
+	}
+	if cfg.PackageName == nil {
+		cfg.PackageName = testjson.RelativePackagePath
+	}
+	run := Run{
+		Tool: Tool{Driver: Driver{
+			Name:           "gotestsum",
+			InformationURI: "https://github.com/gotestyourself/gotestsum",
+			Version:        cfg.Version,
+			Rules: []Rule{
+				{ID: RuleTestFailure, ShortDescription: Message{Text: "Test failed"}},
+				{ID: RuleBuildError, ShortDescription: Message{Text: "Build error"}},
+			},
+		}},
+		Results: []Result{},
+	}
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		if tc.Test == "" {
+			continue
+		}
+		run.Results = append(run.Results, testFailureResult(exec, tc, cfg))
+	}
+	for _, buildErr := range exec.BuildErrors() {
+		run.Results = append(run.Results, buildErrorResult(buildErr, cfg))
+	}
+	return Log{Schema: schemaURI, Version: "2.1.0", Runs: []Run{run}}
+}
+
+func testFailureResult(exec *testjson.Execution, tc testjson.TestCase, cfg Config) Result {
+	name := cfg.PackageName(tc.Package) + "." + tc.Test.Name()
+	result := Result{
+		RuleID:  RuleTestFailure,
+		Level:   "error",
+		Message: Message{Text: name + " failed"},
+	}
+	file, line, msg, ok := failureLocation(exec.OutputLines(tc))
+	if !ok {
+		return result
+	}
+	if msg != "" {
+		result.Message.Text += ": " + msg
+	}
+	uri := path.Join(filepath.ToSlash(cfg.PackageDir(tc.Package)), file)
+	result.Locations = []Location{newLocation(uri, line, 0)}
+	return result
+}
+
+// failurePattern matches the location printed by t.Error and t.Fatal, which
+// is indented by at least one space or tab, ex:
+//
+//	example_test.go:12: expected 3, got 4
+var failurePattern = regexp.MustCompile(`^\s+([^\s:/\\]+\.go):(\d+): ?(.*)$`)
+
+// failureLocation returns the file, line, and message of the first line of
+// output that looks like a message from t.Error or t.Fatal.
+func failureLocation(lines []string) (file string, line int, msg string, ok bool) {
+	for _, output := range lines {
+		match := failurePattern.FindStringSubmatch(strings.TrimRight(output, "\r\n"))
+		if match == nil {
+			continue
+		}
+		line, _ = strconv.Atoi(match[2])
+		return match[1], line, match[3], true
+	}
+	return "", 0, "", false
+}
+
+func buildErrorResult(buildErr testjson.BuildError, cfg Config) Result {
+	result := Result{
+		RuleID:  RuleBuildError,
+		Level:   "error",
+		Message: Message{Text: buildErr.Message},
+	}
+	if buildErr.Package != "" {
+		result.Message.Text = cfg.PackageName(buildErr.Package) + ": " + buildErr.Message
+	}
+	file := filepath.ToSlash(buildErr.File)
+	result.Locations = []Location{newLocation(file, buildErr.Line, buildErr.Column)}
+	return result
+}
+
+func newLocation(file string, line, column int) Location {
+	artifact := ArtifactLocation{URI: strings.TrimPrefix(path.Clean(file), "./"), URIBaseID: srcRoot}
+	if path.IsAbs(file) || filepath.IsAbs(file) {
+		artifact = ArtifactLocation{URI: "file://" + absoluteURIPath(file)}
+	}
+	loc := Location{PhysicalLocation: PhysicalLocation{ArtifactLocation: artifact}}
+	if line > 0 {
+		loc.PhysicalLocation.Region = &Region{StartLine: line, StartColumn: column}
+	}
+	return loc
+}
+
+func absoluteURIPath(file string) string {
+	file = filepath.ToSlash(file)
+	if !strings.HasPrefix(file, "/") {
+		// a windows path with a drive letter
+		file = "/" + file
+	}
+	return file
+}
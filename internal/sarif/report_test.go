@@ -0,0 +1,68 @@
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t)
+
+	err := Write(out, exec, Config{Version: "7.7.7"})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "sarif-report.golden")
+
+	var doc map[string]interface{}
+	assert.NilError(t, json.Unmarshal(out.Bytes(), &doc))
+}
+
+func createExecution(t *testing.T) *testjson.Execution {
+	t.Helper()
+	stdout, err := ioutil.ReadFile("../../testjson/testdata/input/go-test-json.out")
+	assert.NilError(t, err)
+	stderr, err := ioutil.ReadFile("../../testjson/testdata/input/go-test-json.err")
+	assert.NilError(t, err)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: bytes.NewReader(stdout),
+		Stderr: bytes.NewReader(stderr),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestFailureLocation(t *testing.T) {
+	lines := []string{
+		"=== RUN   TestOne\n",
+		"panic: boom\n",
+		"    one_test.go:12: expected 3, got 4\n",
+		"    one_test.go:14: second\n",
+	}
+	file, line, msg, ok := failureLocation(lines)
+	assert.Assert(t, ok)
+	assert.Equal(t, file, "one_test.go")
+	assert.Equal(t, line, 12)
+	assert.Equal(t, msg, "expected 3, got 4")
+
+	_, _, _, ok = failureLocation([]string{"\t/usr/lib/go/src/testing/testing.go:1595 +0x1a\n"})
+	assert.Assert(t, !ok)
+}
+
+func TestNewLocation(t *testing.T) {
+	loc := newLocation("./pkg/one.go", 3, 9)
+	assert.DeepEqual(t, loc, Location{PhysicalLocation: PhysicalLocation{
+		ArtifactLocation: ArtifactLocation{URI: "pkg/one.go", URIBaseID: "%SRCROOT%"},
+		Region:           &Region{StartLine: 3, StartColumn: 9},
+	}})
+
+	loc = newLocation("/src/pkg/one.go", 0, 0)
+	assert.DeepEqual(t, loc, Location{PhysicalLocation: PhysicalLocation{
+		ArtifactLocation: ArtifactLocation{URI: "file:///src/pkg/one.go"},
+	}})
+}
@@ -0,0 +1,238 @@
+/*
+Package structlog finds structured log lines, like the JSON lines written by
+log/slog, zap, or logrus, in the output of a test, and formats them as text
+that is easier to read.
+*/
+package structlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Record is a structured log line.
+type Record struct {
+	// Prefix is the text before the JSON object, like the file and line
+	// added by t.Log.
+	Prefix  string
+	Level   string
+	Message string
+	// Fields are the other keys of the log line, in the order of the line.
+	Fields []Field
+}
+
+// Field is a key and value of a log line. Value is a string without quotes,
+// or the compact JSON of any other value.
+type Field struct {
+	Key   string
+	Value string
+}
+
+var (
+	levelKeys   = []string{"level", "lvl", "severity"}
+	messageKeys = []string{"msg", "message"}
+)
+
+// Parse returns the Record of a line, and true, when the line ends with a
+// JSON object that has a level or a message key.
+func Parse(line string) (Record, bool) {
+	text := strings.TrimRight(line, "\r\n")
+	i := strings.Index(text, "{")
+	if i < 0 || !strings.HasSuffix(text, "}") {
+		return Record{}, false
+	}
+	fields, ok := parseObject(text[i:])
+	if !ok {
+		return Record{}, false
+	}
+
+	r := Record{Prefix: text[:i]}
+	var hasLevel, hasMessage bool
+	for _, f := range fields {
+		switch {
+		case !hasLevel && contains(levelKeys, f.Key):
+			r.Level, hasLevel = f.Value, true
+		case !hasMessage && contains(messageKeys, f.Key):
+			r.Message, hasMessage = f.Value, true
+		default:
+			r.Fields = append(r.Fields, f)
+		}
+	}
+	if !hasLevel && !hasMessage {
+		return Record{}, false
+	}
+	return r, true
+}
+
+func contains(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+// parseObject returns the keys and values of a JSON object, in the order of
+// the object.
+func parseObject(text string) ([]Field, bool) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+	var fields []Field
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, false
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, false
+		}
+		fields = append(fields, Field{Key: key, Value: formatValue(raw)})
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('}') {
+		return nil, false
+	}
+	if _, err := dec.Token(); err == nil {
+		// more text after the object
+		return nil, false
+	}
+	return fields, true
+}
+
+func formatValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// Format returns the record as a line of text, ending with a newline. The
+// level is printed in upper case, with a color for the level, followed by the
+// message, and each field as key=value.
+func (r Record) Format() string {
+	var b strings.Builder
+	b.WriteString(r.Prefix)
+	if r.Level != "" {
+		b.WriteString(colorLevel(r.Level))
+		b.WriteString(" ")
+	}
+	b.WriteString(r.Message)
+	for _, f := range r.Fields {
+		b.WriteString(" ")
+		b.WriteString(f.Key)
+		b.WriteString("=")
+		b.WriteString(quote(f.Value))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func colorLevel(level string) string {
+	name := strings.ToUpper(level)
+	switch {
+	case Rank(level) >= Rank("error"):
+		return color.RedString(name)
+	case Rank(level) >= Rank("warn"):
+		return color.YellowString(name)
+	case Rank(level) >= Rank("info"):
+		return color.CyanString(name)
+	}
+	return color.HiBlackString(name)
+}
+
+// quote returns the value in quotes when it is empty, or has a space, a
+// quote, or an =, so that the fields of the line can be read.
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// FormatLine returns the line formatted by Record.Format when it is a
+// structured log line, otherwise the line is returned unchanged.
+func FormatLine(line string) string {
+	r, ok := Parse(line)
+	if !ok {
+		return line
+	}
+	return r.Format()
+}
+
+// Rank returns the severity of a level, using the values of log/slog, where
+// debug is -4, info is 0, warn is 4, and error is 8. The names used by zap and
+// logrus are also supported, and an offset like the INFO+2 from log/slog. The
+// numeric levels used by pino and bunyan, from 10 for trace to 60 for fatal,
+// are also supported. An unknown level has the rank of info.
+func Rank(level string) int {
+	rank, _ := lookupLevel(level)
+	return rank
+}
+
+// IsLevel returns true when level is the name of a level known by Rank, with
+// an optional offset.
+func IsLevel(level string) bool {
+	_, ok := lookupLevel(level)
+	return ok
+}
+
+var levelRanks = map[string]int{
+	"trace":     -8,
+	"debug":     -4,
+	"dbg":       -4,
+	"info":      0,
+	"inf":       0,
+	"warn":      4,
+	"warning":   4,
+	"wrn":       4,
+	"error":     8,
+	"err":       8,
+	"dpanic":    12,
+	"panic":     12,
+	"fatal":     12,
+	"critical":  12,
+	"alert":     12,
+	"emergency": 12,
+}
+
+// numericLevels are the ranks of the numeric levels used by pino and bunyan,
+// where trace is 10, and each level is 10 more than the previous level.
+var numericLevels = []int{-8, -4, 0, 4, 8, 12}
+
+func lookupLevel(level string) (int, bool) {
+	if n, err := strconv.Atoi(level); err == nil {
+		if n < 10 {
+			return 0, false
+		}
+		i := n/10 - 1
+		if i >= len(numericLevels) {
+			i = len(numericLevels) - 1
+		}
+		return numericLevels[i], true
+	}
+	if i := strings.IndexAny(level, "+-"); i > 0 {
+		if offset, err := strconv.Atoi(level[i:]); err == nil {
+			rank, ok := lookupLevel(level[:i])
+			return rank + offset, ok
+		}
+	}
+	rank, ok := levelRanks[strings.ToLower(level)]
+	return rank, ok
+}
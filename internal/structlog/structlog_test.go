@@ -0,0 +1,118 @@
+package structlog
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"gotest.tools/v3/assert"
+)
+
+func TestParse(t *testing.T) {
+	type testCase struct {
+		line     string
+		expected Record
+		ok       bool
+	}
+	run := func(t *testing.T, tc testCase) {
+		r, ok := Parse(tc.line)
+		assert.Equal(t, ok, tc.ok)
+		assert.DeepEqual(t, r, tc.expected)
+	}
+	testCases := map[string]testCase{
+		"slog": {
+			line: `{"time":"2024-06-01T10:00:00Z","level":"INFO","msg":"request done","status":200,"path":"/api"}` + "\n",
+			expected: Record{
+				Level:   "INFO",
+				Message: "request done",
+				Fields: []Field{
+					{Key: "time", Value: "2024-06-01T10:00:00Z"},
+					{Key: "status", Value: "200"},
+					{Key: "path", Value: "/api"},
+				},
+			},
+			ok: true,
+		},
+		"zap with t.Log prefix": {
+			line: `    logger.go:130: {"level":"error","ts":1717236000.5,"msg":"query failed","error":{"code":"23505"}}` + "\n",
+			expected: Record{
+				Prefix:  "    logger.go:130: ",
+				Level:   "error",
+				Message: "query failed",
+				Fields: []Field{
+					{Key: "ts", Value: "1717236000.5"},
+					{Key: "error", Value: `{"code":"23505"}`},
+				},
+			},
+			ok: true,
+		},
+		"logrus": {
+			line: `{"level":"warning","msg":"retrying","time":"2024-06-01T10:00:00Z"}`,
+			expected: Record{
+				Level:   "warning",
+				Message: "retrying",
+				Fields:  []Field{{Key: "time", Value: "2024-06-01T10:00:00Z"}},
+			},
+			ok: true,
+		},
+		"json without a level or message": {
+			line: `    api_test.go:20: {"id":1,"name":"one"}` + "\n",
+		},
+		"not json": {
+			line: "    api_test.go:20: expected {1} got {2}\n",
+		},
+		"text after the object": {
+			line: `{"level":"info","msg":"one"} {"level":"info"}`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestFormatLine(t *testing.T) {
+	defer patchNoColor(true)()
+
+	line := `    logger.go:130: {"level":"error","msg":"query failed","table":"users","query":"SELECT 1","empty":""}` + "\n"
+	expected := `    logger.go:130: ERROR query failed table=users query="SELECT 1" empty=""` + "\n"
+	assert.Equal(t, FormatLine(line), expected)
+
+	plain := "    api_test.go:20: expected 1, got 2\n"
+	assert.Equal(t, FormatLine(plain), plain)
+
+	assert.Equal(t, FormatLine(`{"msg":"no level"}`+"\n"), "no level\n")
+}
+
+func patchNoColor(value bool) func() {
+	orig := color.NoColor
+	color.NoColor = value
+	return func() {
+		color.NoColor = orig
+	}
+}
+
+func TestRank(t *testing.T) {
+	assert.Equal(t, Rank("DEBUG"), -4)
+	assert.Equal(t, Rank("info"), 0)
+	assert.Equal(t, Rank("INFO+2"), 2)
+	assert.Equal(t, Rank("WARN"), 4)
+	assert.Equal(t, Rank("warning"), 4)
+	assert.Equal(t, Rank("ERROR-1"), 7)
+	assert.Equal(t, Rank("fatal"), 12)
+	assert.Equal(t, Rank("custom"), 0)
+	assert.Equal(t, Rank("30"), 0)
+	assert.Equal(t, Rank("40"), 4)
+	assert.Equal(t, Rank("50"), 8)
+	assert.Equal(t, Rank("60"), 12)
+	assert.Equal(t, Rank("70"), 12)
+}
+
+func TestIsLevel(t *testing.T) {
+	assert.Assert(t, IsLevel("warn"))
+	assert.Assert(t, IsLevel("INFO+2"))
+	assert.Assert(t, !IsLevel("custom"))
+	assert.Assert(t, IsLevel("50"))
+	assert.Assert(t, !IsLevel("3"))
+	assert.Assert(t, !IsLevel(""))
+}
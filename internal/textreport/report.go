@@ -0,0 +1,306 @@
+/*
+Package textreport writes a text report of a testjson.Execution using a
+text/template.
+*/
+package textreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Report is the data used to execute the template.
+type Report struct {
+	Total    int
+	Passed   int
+	Failed   int
+	Skipped  int
+	Elapsed  time.Duration
+	Errors   []string
+	Packages []Package
+	// Failures are the tests that failed, in the same order as the summary.
+	Failures []TestCase
+	Skips    []TestCase
+	// Flaky are the tests that failed, and then passed when they were re-run.
+	// The TestCase is the run that passed.
+	Flaky []TestCase
+	// Environment of the test run, like GOFLAGS and the go version.
+	Environment map[string]string
+	// Labels of the test run, from the --label flag.
+	Labels map[string]string
+	// Requirements are the requirements in the names of the tests, sorted by
+	// ID, with the number of tests of each requirement with each result.
+	Requirements []Requirement
+	// VCS is the git commit of the test run. It is nil when the commit is not
+	// known.
+	VCS *VCS
+}
+
+// VCS is the state of the git working tree of the test run.
+type VCS struct {
+	Commit string `json:"commit"`
+	// Branch is empty when HEAD is detached.
+	Branch string `json:"branch,omitempty"`
+	// Dirty is true if the working tree has changes that are not committed.
+	Dirty bool `json:"dirty"`
+}
+
+// Requirement is a requirement from the names of tests, like REQ-12 in
+// TestLogin[REQ-12].
+type Requirement struct {
+	ID string `json:"id"`
+	// URL of the requirement, from Config.RequirementURLTemplate. It is empty
+	// when there is no template.
+	URL     string `json:"url,omitempty"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Skipped int    `json:"skipped"`
+}
+
+// RequirementLink is a requirement of a test, and its URL.
+type RequirementLink struct {
+	ID  string `json:"id"`
+	URL string `json:"url,omitempty"`
+}
+
+// Package is the result of a single package.
+type Package struct {
+	Name    string
+	Result  string
+	Elapsed time.Duration
+	Total   int
+	Passed  int
+	Failed  int
+	Skipped int
+	// Tests are all the tests in the package, in the order they started.
+	Tests []TestCase
+}
+
+// TestCase is the result of a single test.
+type TestCase struct {
+	Package string
+	Name    string
+	Result  string
+	Elapsed time.Duration
+	RunID   int
+	// Output is only available for tests that failed or were skipped.
+	Output string
+	// Requirements are the requirements in the name of the test.
+	Requirements []RequirementLink
+}
+
+// Config used to write a report.
+type Config struct {
+	// Environment of the test run. It may be nil.
+	Environment map[string]string
+	// Labels of the test run. It may be nil.
+	Labels map[string]string
+	// VCS of the test run. It may be nil.
+	VCS *VCS
+	// RequirementURLTemplate is used to create the URL of each requirement.
+	// See junitxml.RequirementURL for the format. It may be empty.
+	RequirementURLTemplate string
+	// PackageName formats the package path in the packageName function of the
+	// template. Defaults to testjson.RelativePackagePath.
+	PackageName func(pkgpath string) string
+	// This is used for tests to have a consistent elapsed time
+	customElapsed time.Duration
+}
+
+// Funcs are the functions available to the template, in addition to the
+// functions defined by text/template.
+var Funcs = template.FuncMap{
+	"join":        strings.Join,
+	"trimSpace":   strings.TrimSpace,
+	"packageName": testjson.RelativePackagePath,
+	"indent":      indent,
+	"seconds": func(d time.Duration) string {
+		return fmt.Sprintf("%.3f", d.Seconds())
+	},
+	"json": toJSON,
+}
+
+// toJSON returns the value encoded as JSON, so that strings, like the output
+// of a test, can be used in a template of a JSON document.
+func toJSON(v interface{}) (string, error) {
+	raw, err := json.Marshal(v)
+	return string(raw), err
+}
+
+func indent(spaces int, text string) string {
+	prefix := strings.Repeat(" ", spaces)
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" && line != "\n" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// Parse a template, with Funcs.
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(Funcs).Parse(text)
+}
+
+// WithPackageName returns a copy of tmpl where the packageName function formats
+// the package path with fn. When fn is nil tmpl is returned unchanged.
+func WithPackageName(tmpl *template.Template, fn func(pkgpath string) string) (*template.Template, error) {
+	if fn == nil {
+		return tmpl, nil
+	}
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return clone.Funcs(template.FuncMap{"packageName": fn}), nil
+}
+
+// Write executes the template with the Report of exec, and writes the result
+// to out.
+func Write(out io.Writer, tmpl *template.Template, exec *testjson.Execution, cfg Config) error {
+	tmpl, err := WithPackageName(tmpl, cfg.PackageName)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(out, New(exec, cfg)); err != nil {
+		return fmt.Errorf("failed to execute report template: %w", err)
+	}
+	return nil
+}
+
+// New returns the Report of exec. It is used by templates that need more data
+// than the Report, which embed the Report in a struct of their own.
+func New(exec *testjson.Execution, cfg Config) Report {
+	report := Report{
+		Total:       exec.Total(),
+		Failed:      len(exec.Failed()),
+		Skipped:     len(exec.Skipped()),
+		Elapsed:     exec.Elapsed(),
+		Errors:      exec.Errors(),
+		Environment: cfg.Environment,
+		Labels:      cfg.Labels,
+		VCS:         cfg.VCS,
+	}
+	if cfg.customElapsed != 0 {
+		report.Elapsed = cfg.customElapsed
+	}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		item := Package{
+			Name:    name,
+			Result:  string(pkg.Result()),
+			Elapsed: pkg.Elapsed(),
+			Total:   pkg.Total,
+			Passed:  len(pkg.Passed),
+			Failed:  len(pkg.Failed),
+			Skipped: len(pkg.Skipped),
+		}
+		item.Tests = packageTests(exec, pkg, cfg)
+		report.Passed += item.Passed
+		report.Packages = append(report.Packages, item)
+	}
+	for _, tc := range exec.Failed() {
+		if tc.Test == "" {
+			continue
+		}
+		report.Failures = append(report.Failures, newTestCase(exec, tc, testjson.ActionFail, cfg))
+	}
+	for _, tc := range exec.Skipped() {
+		report.Skips = append(report.Skips, newTestCase(exec, tc, testjson.ActionSkip, cfg))
+	}
+	report.Flaky = flakyTests(report.Packages)
+	for _, r := range junitxml.RequirementResults(exec, false) {
+		report.Requirements = append(report.Requirements, Requirement{
+			ID:      r.ID,
+			URL:     junitxml.RequirementURL(cfg.RequirementURLTemplate, r.ID),
+			Passed:  r.Passed,
+			Failed:  r.Failed,
+			Skipped: r.Skipped,
+		})
+	}
+	return report
+}
+
+// flakyTests returns the tests that passed in a run after a run that failed.
+func flakyTests(pkgs []Package) []TestCase {
+	var flaky []TestCase
+	for _, pkg := range pkgs {
+		failed := make(map[string]bool)
+		for _, tc := range pkg.Tests {
+			switch {
+			case tc.Result == string(testjson.ActionFail):
+				failed[tc.Name] = true
+			case tc.Result == string(testjson.ActionPass) && failed[tc.Name]:
+				flaky = append(flaky, tc)
+				delete(failed, tc.Name)
+			}
+		}
+	}
+	return flaky
+}
+
+func newTestCase(exec *testjson.Execution, tc testjson.TestCase, result testjson.Action, cfg Config) TestCase {
+	item := TestCase{
+		Package: tc.Package,
+		Name:    tc.Test.Name(),
+		Result:  string(result),
+		Elapsed: tc.Elapsed,
+		RunID:   tc.RunID,
+	}
+	item.Requirements = RequirementLinks(item.Name, cfg.RequirementURLTemplate)
+	if result != testjson.ActionPass {
+		item.Output = strings.Join(exec.OutputLines(tc), "")
+	}
+	return item
+}
+
+// RequirementLinks returns the requirements in the name of a test, with the
+// URL of each requirement from urlTemplate. It returns nil when the name has no
+// requirements.
+func RequirementLinks(name, urlTemplate string) []RequirementLink {
+	reqs, _ := junitxml.SplitRequirements(name)
+	var links []RequirementLink
+	for _, id := range reqs {
+		if id == "" {
+			continue
+		}
+		links = append(links, RequirementLink{ID: id, URL: junitxml.RequirementURL(urlTemplate, id)})
+	}
+	return links
+}
+
+// packageTests returns all the tests in the package, sorted by the order
+// they started.
+func packageTests(exec *testjson.Execution, pkg *testjson.Package, cfg Config) []TestCase {
+	type result struct {
+		tc     testjson.TestCase
+		action testjson.Action
+	}
+	var results []result
+	for _, tc := range pkg.Passed {
+		results = append(results, result{tc: tc, action: testjson.ActionPass})
+	}
+	for _, tc := range pkg.Failed {
+		results = append(results, result{tc: tc, action: testjson.ActionFail})
+	}
+	for _, tc := range pkg.Skipped {
+		results = append(results, result{tc: tc, action: testjson.ActionSkip})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].tc.ID < results[j].tc.ID
+	})
+	tests := make([]TestCase, 0, len(results))
+	for _, r := range results {
+		tests = append(tests, newTestCase(exec, r.tc, r.action, cfg))
+	}
+	return tests
+}
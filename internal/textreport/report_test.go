@@ -0,0 +1,135 @@
+package textreport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+const input = `{"Action":"run","Package":"example.com/one","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/one","Test":"TestOne","Elapsed":0.25}
+{"Action":"run","Package":"example.com/one","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/one","Test":"TestTwo","Output":"    two_test.go:9: broken\n"}
+{"Action":"fail","Package":"example.com/one","Test":"TestTwo","Elapsed":1.5}
+{"Action":"run","Package":"example.com/one","Test":"TestThree"}
+{"Action":"output","Package":"example.com/one","Test":"TestThree","Output":"    three_test.go:3: not today\n"}
+{"Action":"skip","Package":"example.com/one","Test":"TestThree"}
+{"Action":"fail","Package":"example.com/one","Elapsed":2}
+`
+
+const reportTemplate = `{{ .Total }} tests, {{ .Failed }} failed, {{ .Skipped }} skipped in {{ seconds .Elapsed }}s
+{{ range .Packages }}{{ .Name }} {{ .Result }} {{ .Elapsed }}
+{{ range .Tests }}  {{ .Name }} {{ .Result }} {{ .Elapsed }}
+{{ end }}{{ end }}Failures:
+{{ range .Failures }}{{ packageName .Package }}.{{ .Name }}
+{{ indent 2 .Output }}{{ end }}`
+
+func TestWrite(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	tmpl, err := Parse("report", reportTemplate)
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	err = Write(out, tmpl, exec, Config{customElapsed: 3 * time.Second})
+	assert.NilError(t, err)
+
+	expected := `3 tests, 1 failed, 1 skipped in 3.000s
+example.com/one fail 2s
+  TestOne pass 250ms
+  TestTwo fail 1.5s
+  TestThree skip 0s
+Failures:
+example.com/one.TestTwo
+      two_test.go:9: broken
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestNew_Flaky(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	rerun := `{"Action":"run","Package":"example.com/one","Test":"TestTwo"}
+{"Action":"pass","Package":"example.com/one","Test":"TestTwo","Elapsed":0.5}
+{"Action":"pass","Package":"example.com/one"}
+`
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    strings.NewReader(rerun),
+		Execution: exec,
+		RunID:     1,
+	})
+	assert.NilError(t, err)
+
+	report := New(exec, Config{})
+	assert.DeepEqual(t, report.Flaky, []TestCase{{
+		Package: "example.com/one",
+		Name:    "TestTwo",
+		Result:  "pass",
+		Elapsed: 500 * time.Millisecond,
+		RunID:   1,
+	}})
+}
+
+func TestFuncs_JSON(t *testing.T) {
+	tmpl, err := Parse("payload", `{"text": {{ json .Name }}}`)
+	assert.NilError(t, err)
+	out := new(bytes.Buffer)
+	assert.NilError(t, tmpl.Execute(out, TestCase{Name: "TestOne \"quoted\"\n"}))
+	assert.Equal(t, out.String(), `{"text": "TestOne \"quoted\"\n"}`)
+}
+
+func TestWrite_PackageName(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	tmpl, err := Parse("report", `{{ range .Packages }}{{ packageName .Name }}{{ end }}`)
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	cfg := Config{PackageName: func(pkgpath string) string {
+		return "short:" + pkgpath
+	}}
+	assert.NilError(t, Write(out, tmpl, exec, cfg))
+	assert.Equal(t, out.String(), "short:example.com/one")
+
+	out.Reset()
+	assert.NilError(t, Write(out, tmpl, exec, Config{}))
+	assert.Equal(t, out.String(), "example.com/one")
+}
+
+func TestWrite_TemplateError(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	tmpl, err := Parse("report", "{{ .Missing }}")
+	assert.NilError(t, err)
+
+	err = Write(new(bytes.Buffer), tmpl, exec, Config{})
+	assert.ErrorContains(t, err, "failed to execute report template")
+}
+
+func TestNew_Requirements(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/one","Test":"TestLogin[REQ-1,REQ-2]"}
+{"Action":"fail","Package":"example.com/one","Test":"TestLogin[REQ-1,REQ-2]"}
+{"Action":"run","Package":"example.com/one","Test":"TestLogout[REQ-2]"}
+{"Action":"pass","Package":"example.com/one","Test":"TestLogout[REQ-2]"}
+{"Action":"fail","Package":"example.com/one"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	report := New(exec, Config{RequirementURLTemplate: "https://jira.example.com/browse/{id}"})
+	assert.DeepEqual(t, report.Requirements, []Requirement{
+		{ID: "REQ-1", URL: "https://jira.example.com/browse/REQ-1", Failed: 1},
+		{ID: "REQ-2", URL: "https://jira.example.com/browse/REQ-2", Passed: 1, Failed: 1},
+	})
+	assert.DeepEqual(t, report.Failures[0].Requirements, []RequirementLink{
+		{ID: "REQ-1", URL: "https://jira.example.com/browse/REQ-1"},
+		{ID: "REQ-2", URL: "https://jira.example.com/browse/REQ-2"},
+	})
+
+	report = New(exec, Config{})
+	assert.DeepEqual(t, report.Failures[0].Requirements, []RequirementLink{{ID: "REQ-1"}, {ID: "REQ-2"}})
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// example from RFC 6455 section 1.3
+	assert.Equal(t, acceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+}
+
+func TestUpgrade_WriteText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		_ = conn.WriteText([]byte("short"))
+		_ = conn.WriteText([]byte(strings.Repeat("a", 300)))
+		<-conn.Done()
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	assert.NilError(t, err)
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n"+
+		"Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Version: 13\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusSwitchingProtocols)
+	assert.Equal(t, resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+
+	assert.Equal(t, readTextFrame(t, reader), "short")
+	assert.Equal(t, readTextFrame(t, reader), strings.Repeat("a", 300))
+
+	// masked close frame from the client
+	_, err = conn.Write([]byte{0x88, 0x80, 1, 2, 3, 4})
+	assert.NilError(t, err)
+	header := make([]byte, 2)
+	_, err = io.ReadFull(reader, header)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, header, []byte{0x88, 0})
+}
+
+func readTextFrame(t *testing.T, reader io.Reader) string {
+	t.Helper()
+	header := make([]byte, 2)
+	_, err := io.ReadFull(reader, header)
+	assert.NilError(t, err)
+	assert.Equal(t, header[0], byte(0x81))
+	length := int(header[1])
+	if length == 126 {
+		ext := make([]byte, 2)
+		_, err = io.ReadFull(reader, ext)
+		assert.NilError(t, err)
+		length = int(ext[0])<<8 | int(ext[1])
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(reader, payload)
+	assert.NilError(t, err)
+	return string(payload)
+}
+
+func TestUpgrade_NotAHandshake(t *testing.T) {
+	rec := httptest.NewRecorder()
+	_, err := Upgrade(rec, httptest.NewRequest("GET", "/", nil))
+	assert.ErrorContains(t, err, "not a websocket handshake")
+	assert.Equal(t, rec.Code, http.StatusBadRequest)
+}
@@ -5,8 +5,21 @@ import (
 	"os"
 
 	"gotest.tools/gotestsum/cmd"
+	"gotest.tools/gotestsum/cmd/tool/bisect"
+	"gotest.tools/gotestsum/cmd/tool/coverage"
+	"gotest.tools/gotestsum/cmd/tool/histogram"
+	"gotest.tools/gotestsum/cmd/tool/impact"
+	"gotest.tools/gotestsum/cmd/tool/lintmerge"
+	"gotest.tools/gotestsum/cmd/tool/lintnames"
 	"gotest.tools/gotestsum/cmd/tool/matrix"
+	"gotest.tools/gotestsum/cmd/tool/mutate"
+	"gotest.tools/gotestsum/cmd/tool/parallel"
+	"gotest.tools/gotestsum/cmd/tool/repro"
+	"gotest.tools/gotestsum/cmd/tool/shortmode"
 	"gotest.tools/gotestsum/cmd/tool/slowest"
+	"gotest.tools/gotestsum/cmd/tool/timeline"
+	"gotest.tools/gotestsum/cmd/tool/tune"
+	"gotest.tools/gotestsum/cmd/tool/zephyr"
 	"gotest.tools/gotestsum/internal/log"
 )
 
@@ -16,8 +29,11 @@ func main() {
 	case err == nil:
 		return
 	case cmd.IsExitCoder(err):
-		// go test should already report the error to stderr, exit with
-		// the same status code
+		// errors from go test should already be reported to stderr, exit
+		// with the status code for the error
+		if msg := cmd.ErrorMessage(err); msg != "" {
+			log.Error(msg)
+		}
 		os.Exit(cmd.ExitCodeWithDefault(err))
 	default:
 		log.Error(err.Error())
@@ -33,6 +49,14 @@ func route(args []string) error {
 		return cmd.Run(name, []string{"--help"})
 	case "tool":
 		return toolRun(name+" "+next, rest)
+	case "serve-ui":
+		return cmd.RunServeUI(name+" "+next, rest)
+	case "init":
+		return cmd.RunInit(name+" "+next, rest)
+	case "completion":
+		return cmd.RunCompletion(name+" "+next, rest)
+	case "__complete":
+		return cmd.RunComplete(name, rest)
 	default:
 		return cmd.Run(name, args[1:])
 	}
@@ -56,7 +80,20 @@ func toolRun(name string, args []string) error {
 
 Commands:
     %[1]s slowest      find or skip the slowest tests
+    %[1]s parallel     report the slow tests that do not call t.Parallel
+    %[1]s tune         recommend go test -p and -parallel values from a test run
     %[1]s ci-matrix    use previous test runtime to place packages into optimal buckets
+    %[1]s bisect       find the tests that cause an order dependent test failure
+    %[1]s repro        print a go test command to reproduce a test failure
+    %[1]s lint-merge   write golangci-lint issues and test results to a JUnit XML file
+    %[1]s lint-names   report test names that do not follow the naming convention
+    %[1]s mutate       find changes to the code that are not detected by the tests
+    %[1]s timeline     write a Chrome trace of the tests in a test run
+    %[1]s histogram    print a histogram of the elapsed time of tests and packages
+    %[1]s zephyr       publish the results to a Zephyr Scale test cycle
+    %[1]s impact       report the tests that cover the lines changed in a pull request
+    %[1]s coverage     compare coverage profiles, or find the lines covered by each test
+    %[1]s shortmode-audit  report the tests that only run with, or without, -short
 
 Use '%[1]s COMMAND --help' for command specific help.
 `, name)
@@ -69,8 +106,34 @@ Use '%[1]s COMMAND --help' for command specific help.
 		return nil
 	case "slowest":
 		return slowest.Run(name+" "+next, rest)
+	case "parallel":
+		return parallel.Run(name+" "+next, rest)
+	case "tune":
+		return tune.Run(name+" "+next, rest)
 	case "ci-matrix":
 		return matrix.Run(name+" "+next, rest)
+	case "bisect":
+		return bisect.Run(name+" "+next, rest)
+	case "repro":
+		return repro.Run(name+" "+next, rest)
+	case "lint-merge":
+		return lintmerge.Run(name+" "+next, rest)
+	case "lint-names":
+		return lintnames.Run(name+" "+next, rest)
+	case "mutate":
+		return mutate.Run(name+" "+next, rest)
+	case "timeline":
+		return timeline.Run(name+" "+next, rest)
+	case "histogram":
+		return histogram.Run(name+" "+next, rest)
+	case "zephyr":
+		return zephyr.Run(name+" "+next, rest)
+	case "impact":
+		return impact.Run(name+" "+next, rest)
+	case "coverage":
+		return coverage.Run(name+" "+next, rest)
+	case "shortmode-audit":
+		return shortmode.Run(name+" "+next, rest)
 	default:
 		fmt.Fprintln(os.Stderr, usage(name))
 		return fmt.Errorf("invalid command: %v %v", name, next)
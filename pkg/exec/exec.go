@@ -0,0 +1,206 @@
+/*
+Package exec runs 'go test -json', and prints and writes reports for the test
+run in the same way as the gotestsum command.
+
+Tools which embed gotestsum should use this package instead of running the
+gotestsum binary. The exported API of this package, and of the testjson package,
+follows the same compatibility policy as the command line flags: new fields may
+be added to Options and Result, but existing fields will not be removed or
+change meaning without a major version change.
+*/
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Options used by Run.
+type Options struct {
+	// Dir is the working directory of the 'go test' process. If empty the
+	// current directory is used.
+	Dir string
+	// Env is the environment of the 'go test' process. If nil the environment
+	// of the current process is used.
+	Env []string
+	// Packages to test. If empty the package in Dir is tested.
+	Packages []string
+	// Args are passed to 'go test' before Packages. The -json flag is always
+	// added by Run and should not be included.
+	Args []string
+
+	// Format is the name of the format used to print events to Stdout. See
+	// testjson.NewEventFormatter and testjson.RegisterFormatter. Defaults
+	// to "pkgname".
+	Format        string
+	FormatOptions testjson.FormatOptions
+	// Summary sections to print after the run. Defaults to
+	// testjson.SummarizeAll. Use HideSummary to omit the summary entirely.
+	Summary     testjson.Summary
+	HideSummary bool
+	// Stdout receives the formatted events and the summary. Defaults to
+	// os.Stdout.
+	Stdout io.Writer
+	// Stderr receives stderr from the 'go test' process. Defaults to os.Stderr.
+	Stderr io.Writer
+
+	// Handler, if set, is called with every event after it is formatted, and
+	// every line of stderr.
+	Handler testjson.EventHandler
+
+	// JUnitFile is the path to write a JUnit XML report. Skipped if empty.
+	JUnitFile string
+	// JUnitProjectName sets the name attribute of the testsuites element.
+	JUnitProjectName string
+	// JSONSummaryFile is the path to write a JSON summary. Skipped if empty.
+	JSONSummaryFile string
+}
+
+// Result of a test run.
+type Result struct {
+	// Execution contains all the tests and events from the run.
+	Execution *testjson.Execution
+	// ExitCode of the 'go test' process.
+	ExitCode int
+}
+
+// Run 'go test -json' using opts, print the events and summary, and write any
+// of the reports enabled in opts.
+//
+// An error is returned if 'go test' could not be started, if the output could
+// not be parsed, or if a report could not be written. Test failures are not
+// an error, use Result.ExitCode or Result.Execution to check for failures.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	opts = withDefaults(opts)
+	formatter := testjson.NewEventFormatter(opts.Stdout, opts.Format, opts.FormatOptions)
+	if formatter == nil {
+		return nil, fmt.Errorf("unknown format %v", opts.Format)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := append([]string{"test", "-json"}, opts.Args...)
+	args = append(args, opts.Packages...)
+	cmd := osexec.CommandContext(ctx, "go", args...)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.Env
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run go test: %w", err)
+	}
+
+	cfg := testjson.ScanConfig{
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Handler: &handler{opts: opts, formatter: formatter},
+		Stop:    cancel,
+	}
+	execution, err := testjson.ScanTestOutput(cfg)
+	waitErr := cmd.Wait()
+	if err != nil {
+		return &Result{Execution: execution, ExitCode: -1}, err
+	}
+
+	result := &Result{Execution: execution}
+	var exitErr *osexec.ExitError
+	switch {
+	case errors.As(waitErr, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case waitErr != nil:
+		return result, waitErr
+	}
+
+	if !opts.HideSummary {
+		testjson.PrintSummary(opts.Stdout, execution, opts.Summary)
+	}
+	return result, writeReports(opts, execution)
+}
+
+func withDefaults(opts Options) Options {
+	if opts.Format == "" {
+		opts.Format = "pkgname"
+	}
+	if opts.Summary == 0 {
+		opts.Summary = testjson.SummarizeAll
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	return opts
+}
+
+type handler struct {
+	opts      Options
+	formatter testjson.EventFormatter
+}
+
+func (h *handler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if err := h.formatter.Format(event, execution); err != nil {
+		return fmt.Errorf("failed to format event: %w", err)
+	}
+	if h.opts.Handler != nil {
+		return h.opts.Handler.Event(event, execution)
+	}
+	return nil
+}
+
+func (h *handler) Err(text string) error {
+	_, _ = h.opts.Stderr.Write([]byte(text + "\n"))
+	if h.opts.Handler != nil {
+		return h.opts.Handler.Err(text)
+	}
+	return nil
+}
+
+func writeReports(opts Options, execution *testjson.Execution) error {
+	if opts.JUnitFile != "" {
+		err := writeFile(opts.JUnitFile, "JUnit file", func(out io.Writer) error {
+			return junitxml.Write(out, execution, junitxml.Config{
+				ProjectName:       opts.JUnitProjectName,
+				HideEmptyPackages: opts.FormatOptions.HideEmptyPackages,
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if opts.JSONSummaryFile != "" {
+		return writeFile(opts.JSONSummaryFile, "JSON summary file", func(out io.Writer) error {
+			return jsonsummary.Write(out, execution, jsonsummary.Config{})
+		})
+	}
+	return nil
+}
+
+func writeFile(path string, desc string, write func(out io.Writer) error) error {
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	fh, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %v: %w", desc, err)
+	}
+	if err := write(fh); err != nil {
+		_ = fh.Close()
+		return err
+	}
+	return fh.Close()
+}
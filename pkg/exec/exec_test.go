@@ -0,0 +1,62 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestRun(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	out := new(bytes.Buffer)
+	handler := &countingHandler{}
+	opts := Options{
+		Dir:             "../..",
+		Packages:        []string{"./testjson/internal/good"},
+		Args:            []string{"-tags=stubpkg", "-count=1"},
+		Format:          "testname",
+		Stdout:          out,
+		Stderr:          out,
+		Handler:         handler,
+		JUnitFile:       dir.Join("junit.xml"),
+		JSONSummaryFile: dir.Join("summary.json"),
+	}
+	result, err := Run(context.Background(), opts)
+	assert.NilError(t, err, out.String())
+	assert.Equal(t, result.ExitCode, 0)
+	assert.Assert(t, result.Execution.Total() > 0)
+	assert.Equal(t, len(result.Execution.Failed()), 0)
+	assert.Assert(t, handler.events > 0)
+	assert.Assert(t, cmp.Contains(out.String(), "PASS testjson/internal/good.TestPassed"))
+	assert.Assert(t, cmp.Contains(out.String(), "DONE "))
+
+	for _, name := range []string{"junit.xml", "summary.json"} {
+		_, err := os.Stat(filepath.Join(dir.Path(), name))
+		assert.NilError(t, err)
+	}
+}
+
+func TestRun_UnknownFormat(t *testing.T) {
+	_, err := Run(context.Background(), Options{Format: "not-a-format"})
+	assert.Error(t, err, "unknown format not-a-format")
+}
+
+type countingHandler struct {
+	events int
+}
+
+func (h *countingHandler) Event(testjson.TestEvent, *testjson.Execution) error {
+	h.events++
+	return nil
+}
+
+func (h *countingHandler) Err(string) error {
+	return nil
+}
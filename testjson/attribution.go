@@ -0,0 +1,111 @@
+package testjson
+
+import "strings"
+
+// UnattributedOutput is a line of output that test2json attributed to a test
+// that did not print it. This happens when a test writes to os.Stdout
+// directly, instead of using t.Log, while other tests are running in parallel.
+type UnattributedOutput struct {
+	Package string
+	// Test is the name of the test that test2json attributed the output to.
+	Test string
+	// AttributedTo is the name of the test the output was moved to, or an
+	// empty string when the test that printed the output is not known and the
+	// output was moved to the package output.
+	AttributedTo string
+	Output       string
+	RunID        int
+}
+
+// outputTestID returns the ID of the test case that printed the output of the
+// event, which is 0 when the output is moved to the package output.
+//
+// test2json attributes a line of output to the test named by the most recent
+// === RUN, === PAUSE, or === CONT line. Output from t.Log is always attributed
+// correctly, but output written directly to os.Stdout is attributed to
+// whichever test was framed last. A paused test is blocked in t.Parallel and
+// can not print anything, so output attributed to a paused test is moved to the
+// only test that is running, or to the package output when more than one test
+// is running. When strict is true output is also moved to the package output
+// when the test that printed it is ambiguous because more than one test is
+// running.
+func (p *Package) outputTestID(event TestEvent, strict bool) int {
+	tc := p.running[event.Test]
+	if p.panicked || !isDirectOutput(event.Output) {
+		return tc.ID
+	}
+	paused := p.paused[event.Test]
+	if !paused && !strict {
+		return tc.ID
+	}
+
+	active := p.activeTests()
+	var target TestCase
+	switch {
+	case len(active) == 1:
+		target = active[0]
+	case len(active) == 0 && !paused:
+		target = tc
+	}
+	if target.ID == tc.ID {
+		return tc.ID
+	}
+	p.unattributed = append(p.unattributed, UnattributedOutput{
+		Package:      event.Package,
+		Test:         event.Test,
+		AttributedTo: target.Test.Name(),
+		Output:       event.Output,
+		RunID:        event.RunID,
+	})
+	return target.ID
+}
+
+// activeTests returns the running tests that are not paused, and do not have
+// a running subtest. These are the tests that could have printed output.
+func (p *Package) activeTests() []TestCase {
+	var active []TestCase
+	for name, tc := range p.running {
+		if p.paused[name] || p.hasRunningSubTest(name) {
+			continue
+		}
+		active = append(active, tc)
+	}
+	return active
+}
+
+func (p *Package) hasRunningSubTest(name string) bool {
+	for other := range p.running {
+		if strings.HasPrefix(other, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isDirectOutput returns true if the output does not look like it was printed
+// by the testing package. Output from t.Log, and the test status lines of
+// subtests, are indented.
+func isDirectOutput(output string) bool {
+	switch {
+	case output == "" || output == "\n":
+		return false
+	case strings.HasPrefix(output, " "), strings.HasPrefix(output, "\t"):
+		return false
+	case strings.HasPrefix(output, "=== "), strings.HasPrefix(output, "--- "):
+		return false
+	case output == "PASS\n", output == "FAIL\n":
+		return false
+	}
+	return true
+}
+
+// UnattributedOutput returns the output that test2json attributed to a test
+// that did not print it, sorted by package. The tests in the report are
+// usually tests that write to os.Stdout directly, instead of using t.Log.
+func (e *Execution) UnattributedOutput() []UnattributedOutput {
+	var result []UnattributedOutput
+	for _, name := range sortedKeys(e.packages) {
+		result = append(result, e.packages[name].unattributed...)
+	}
+	return result
+}
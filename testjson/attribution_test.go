@@ -0,0 +1,76 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+var interleavedOutput = []string{
+	`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"=== RUN   TestOne\n"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"=== PAUSE TestOne\n"}`,
+	`{"Action":"pause","Package":"example.com/a","Test":"TestOne"}`,
+	`{"Action":"run","Package":"example.com/a","Test":"TestTwo"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"=== RUN   TestTwo\n"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"=== PAUSE TestTwo\n"}`,
+	`{"Action":"pause","Package":"example.com/a","Test":"TestTwo"}`,
+	`{"Action":"run","Package":"example.com/a","Test":"TestSerial"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestSerial","Output":"=== RUN   TestSerial\n"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"printed by serial\n"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestSerial","Output":"--- FAIL: TestSerial (0.00s)\n"}`,
+	`{"Action":"fail","Package":"example.com/a","Test":"TestSerial"}`,
+	`{"Action":"cont","Package":"example.com/a","Test":"TestOne"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"=== CONT  TestOne\n"}`,
+	`{"Action":"cont","Package":"example.com/a","Test":"TestTwo"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"=== CONT  TestTwo\n"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"printed by one or two\n"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"    two_test.go:10: logged by two\n"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"--- FAIL: TestTwo (0.00s)\n"}`,
+	`{"Action":"fail","Package":"example.com/a","Test":"TestTwo"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"printed by one\n"}`,
+	`{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"--- FAIL: TestOne (0.00s)\n"}`,
+	`{"Action":"fail","Package":"example.com/a","Test":"TestOne"}`,
+	`{"Action":"fail","Package":"example.com/a"}`,
+}
+
+func TestScanTestOutput_OutputAttribution(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(strings.Join(interleavedOutput, "\n")),
+	})
+	assert.NilError(t, err)
+
+	pkg := exec.Package("example.com/a")
+	assert.Equal(t, pkg.Output(3), "=== RUN   TestSerial\nprinted by serial\n--- FAIL: TestSerial (0.00s)\n")
+	assert.Equal(t, pkg.Output(2), "=== RUN   TestTwo\n=== PAUSE TestTwo\n=== CONT  TestTwo\n"+
+		"printed by one or two\n    two_test.go:10: logged by two\n--- FAIL: TestTwo (0.00s)\n")
+	assert.Equal(t, pkg.Output(0), "")
+
+	expected := []UnattributedOutput{
+		{Package: "example.com/a", Test: "TestTwo", AttributedTo: "TestSerial", Output: "printed by serial\n"},
+	}
+	assert.DeepEqual(t, exec.UnattributedOutput(), expected)
+}
+
+func TestScanTestOutput_StrictOutputAttribution(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                  strings.NewReader(strings.Join(interleavedOutput, "\n")),
+		RunID:                   1,
+		StrictOutputAttribution: true,
+	})
+	assert.NilError(t, err)
+
+	pkg := exec.Package("example.com/a")
+	assert.Equal(t, pkg.Output(2), "=== RUN   TestTwo\n=== PAUSE TestTwo\n=== CONT  TestTwo\n"+
+		"    two_test.go:10: logged by two\n--- FAIL: TestTwo (0.00s)\n")
+	assert.Equal(t, pkg.Output(1), "=== RUN   TestOne\n=== PAUSE TestOne\n=== CONT  TestOne\n"+
+		"printed by one\n--- FAIL: TestOne (0.00s)\n")
+	assert.Equal(t, pkg.Output(0), "printed by one or two\n")
+
+	expected := []UnattributedOutput{
+		{Package: "example.com/a", Test: "TestTwo", AttributedTo: "TestSerial", Output: "printed by serial\n", RunID: 1},
+		{Package: "example.com/a", Test: "TestTwo", Output: "printed by one or two\n", RunID: 1},
+	}
+	assert.DeepEqual(t, exec.UnattributedOutput(), expected)
+}
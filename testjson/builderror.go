@@ -0,0 +1,97 @@
+package testjson
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BuildError is an error reported by the compiler, or by go vet, while
+// building a test binary.
+type BuildError struct {
+	// Package is the import path of the package which failed to build. It is
+	// empty if the error was not preceded by a package header.
+	Package string
+	File    string
+	Line    int
+	// Column is 0 when the error did not include a column.
+	Column  int
+	Message string
+}
+
+// Location returns the file, line, and column formatted in the same way as
+// the compiler.
+func (e BuildError) Location() string {
+	loc := e.File + ":" + strconv.Itoa(e.Line)
+	if e.Column > 0 {
+		loc += ":" + strconv.Itoa(e.Column)
+	}
+	return loc
+}
+
+// String returns the error formatted in the same way as the compiler.
+func (e BuildError) String() string {
+	return e.Location() + ": " + e.Message
+}
+
+// buildErrorPattern matches a build error, ex: pkg/a.go:12:3: message. The
+// file may start with a drive letter on Windows, ex: C:\src\pkg\a.go.
+var buildErrorPattern = regexp.MustCompile(`^(?:vet: )?((?:[A-Za-z]:)?[^\s:][^:]*\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// ParseBuildError parses a line of compiler or go vet output. It returns
+// false if the line is not a build error.
+func ParseBuildError(line string) (BuildError, bool) {
+	match := buildErrorPattern.FindStringSubmatch(line)
+	if match == nil {
+		return BuildError{}, false
+	}
+	lineNum, _ := strconv.Atoi(match[2])
+	column, _ := strconv.Atoi(match[3])
+	return BuildError{
+		File:    match[1],
+		Line:    lineNum,
+		Column:  column,
+		Message: match[4],
+	}, true
+}
+
+// buildErrorHeaderPackage returns the import path from a header line printed
+// by go build before the errors for a package, ex:
+//
+//	# example.com/pkg [example.com/pkg.test]
+func buildErrorHeaderPackage(line string) string {
+	fields := strings.Fields(strings.TrimPrefix(line, "# "))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// addBuildError records line as a structured BuildError if it is a build
+// error, or as a continuation of the previous build error if it is indented.
+// Must be called while holding errorsLock.
+func (e *Execution) addBuildError(line string) {
+	if strings.HasPrefix(line, "# ") {
+		e.buildErrorPkg = buildErrorHeaderPackage(line)
+		return
+	}
+	if buildErr, ok := ParseBuildError(line); ok {
+		buildErr.Package = e.buildErrorPkg
+		e.buildErrors = append(e.buildErrors, buildErr)
+		return
+	}
+	if n := len(e.buildErrors); n > 0 && strings.HasPrefix(line, "\t") {
+		e.buildErrors[n-1].Message += "\n" + strings.TrimPrefix(line, "\t")
+		return
+	}
+	// Any other output ends the errors for the current package.
+	e.buildErrorPkg = ""
+}
+
+// BuildErrors returns the errors from stderr which were reported by the
+// compiler or go vet.
+func (e *Execution) BuildErrors() []BuildError {
+	e.errorsLock.RLock()
+	defer e.errorsLock.RUnlock()
+	return e.buildErrors
+}
@@ -0,0 +1,117 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseBuildError(t *testing.T) {
+	type testCase struct {
+		line     string
+		expected BuildError
+		ok       bool
+	}
+	testCases := []testCase{
+		{
+			line: "testjson/internal/broken/broken.go:5:21: undefined: somepackage",
+			expected: BuildError{
+				File: "testjson/internal/broken/broken.go", Line: 5, Column: 21,
+				Message: "undefined: somepackage",
+			},
+			ok: true,
+		},
+		{
+			line:     "./a_test.go:12: missing return",
+			expected: BuildError{File: "./a_test.go", Line: 12, Message: "missing return"},
+			ok:       true,
+		},
+		{
+			line: "vet: ./a_test.go:3:8: fmt.Printf format %d has arg s of wrong type string",
+			expected: BuildError{
+				File: "./a_test.go", Line: 3, Column: 8,
+				Message: "fmt.Printf format %d has arg s of wrong type string",
+			},
+			ok: true,
+		},
+		{
+			line: `C:\src\pkg\a.go:12:3: undefined: somepackage`,
+			expected: BuildError{
+				File: `C:\src\pkg\a.go`, Line: 12, Column: 3,
+				Message: "undefined: somepackage",
+			},
+			ok: true,
+		},
+		{
+			line:     `vet: .\a_test.go:3: unreachable code`,
+			expected: BuildError{File: `.\a_test.go`, Line: 3, Message: "unreachable code"},
+			ok:       true,
+		},
+		{line: "# example.com/pkg"},
+		{line: "FAIL\texample.com/pkg [build failed]"},
+		{line: "panic: runtime error: index out of range"},
+	}
+	for _, tc := range testCases {
+		actual, ok := ParseBuildError(tc.line)
+		assert.Equal(t, ok, tc.ok, tc.line)
+		assert.DeepEqual(t, actual, tc.expected)
+	}
+}
+
+func TestExecution_BuildErrors(t *testing.T) {
+	exec := newExecution()
+	stderr := []string{
+		"# example.com/a",
+		"a/a.go:5:21: undefined: somepackage",
+		"a/a.go:8:2: cannot use x (variable of type int) as string value in return statement",
+		"\thave (int)",
+		"\twant (string)",
+		"# example.com/b [example.com/b.test]",
+		"b/b_test.go:3:8: \"os\" imported and not used",
+		"FAIL\texample.com/b [build failed]",
+		"c/c.go:1:1: expected 'package', found 'EOF'",
+	}
+	for _, line := range stderr {
+		exec.addError(line)
+	}
+
+	expected := []BuildError{
+		{Package: "example.com/a", File: "a/a.go", Line: 5, Column: 21, Message: "undefined: somepackage"},
+		{
+			Package: "example.com/a", File: "a/a.go", Line: 8, Column: 2,
+			Message: "cannot use x (variable of type int) as string value in return statement\nhave (int)\nwant (string)",
+		},
+		{Package: "example.com/b", File: "b/b_test.go", Line: 3, Column: 8, Message: "\"os\" imported and not used"},
+		{File: "c/c.go", Line: 1, Column: 1, Message: "expected 'package', found 'EOF'"},
+	}
+	assert.DeepEqual(t, exec.BuildErrors(), expected)
+	// headers are not included in the errors
+	assert.Equal(t, len(exec.Errors()), len(stderr)-2)
+}
+
+func TestFormatError(t *testing.T) {
+	defer func(orig bool) { color.NoColor = orig }(color.NoColor)
+	color.NoColor = false
+
+	out := formatError("a/a.go:5:21: undefined: somepackage")
+	assert.Assert(t, strings.Contains(out, "a/a.go:5:21"), out)
+	assert.Assert(t, out != "a/a.go:5:21: undefined: somepackage", "expected color")
+	assert.Equal(t, formatError("panic: oops"), "panic: oops")
+}
+
+func TestExecution_RemoveErrors(t *testing.T) {
+	exec := newExecution()
+	exec.addError("# example.com/a")
+	exec.addError("a/a.go:5:21: undefined: somepackage")
+
+	removed := exec.RemoveErrors()
+	assert.DeepEqual(t, removed, []string{"a/a.go:5:21: undefined: somepackage"})
+	assert.Equal(t, len(exec.Errors()), 0)
+	assert.Equal(t, len(exec.BuildErrors()), 0)
+
+	// the package from the previous header is not used for new errors
+	exec.addError("b/b.go:1:1: expected 'package', found 'EOF'")
+	assert.Equal(t, exec.BuildErrors()[0].Package, "")
+}
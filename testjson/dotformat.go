@@ -13,15 +13,17 @@ import (
 	"gotest.tools/gotestsum/internal/log"
 )
 
-func dotsFormatV1(event TestEvent, exec *Execution) string {
-	pkg := exec.Package(event.Package)
-	switch {
-	case event.PackageEvent():
-		return ""
-	case event.Action == ActionRun && pkg.Total == 1:
-		return "[" + RelativePackagePath(event.Package) + "]"
+func dotsFormatV1(opts FormatOptions) func(event TestEvent, exec *Execution) string {
+	return func(event TestEvent, exec *Execution) string {
+		pkg := exec.Package(event.Package)
+		switch {
+		case event.PackageEvent():
+			return ""
+		case event.Action == ActionRun && pkg.Total == 1:
+			return "[" + formatPackageName(opts.PackageName, event.Package) + "]"
+		}
+		return fmtDot(event)
 	}
-	return fmtDot(event)
 }
 
 func fmtDot(event TestEvent) string {
@@ -68,11 +70,23 @@ func (l *dotLine) checkWidth(prefix, terminal int) {
 	}
 }
 
+// minDotsWidth is the width left for the dots after a long package name.
+const minDotsWidth = 10
+
 func newDotFormatter(out io.Writer, opts FormatOptions) EventFormatter {
-	w, _, err := term.GetSize(int(os.Stdout.Fd()))
-	if err != nil || w == 0 {
-		log.Warnf("Failed to detect terminal width for dots format, error: %v", err)
-		return &formatAdapter{format: dotsFormatV1, out: out}
+	// the output of tests is printed between the lines of dots, so the lines
+	// can not be updated.
+	if opts.NoTerminal || opts.Verbosity >= VerbosityOutput {
+		return &formatAdapter{format: dotsFormatV1(opts), out: out}
+	}
+	w := opts.TerminalWidth
+	if w == 0 {
+		var err error
+		w, _, err = term.GetSize(int(os.Stdout.Fd()))
+		if err != nil || w == 0 {
+			log.Warnf("Failed to detect terminal width for dots format, error: %v", err)
+			return &formatAdapter{format: dotsFormatV1(opts), out: out}
+		}
 	}
 	return &dotFormatter{
 		pkgs:      make(map[string]*dotLine),
@@ -106,10 +120,15 @@ func (d *dotFormatter) Format(event TestEvent, exec *Execution) error {
 		if d.opts.HideEmptyPackages && exec.Package(pkg).IsEmpty() {
 			continue
 		}
+		if d.opts.Verbosity <= VerbosityQuiet && isPassingPackage(exec.Package(pkg)) {
+			continue
+		}
 
 		line := d.pkgs[pkg]
-		pkgname := RelativePackagePath(pkg) + " "
 		prefix := fmtDotElapsed(exec.Package(pkg))
+		// a name that is wider than the terminal would wrap, and the lines
+		// could no longer be updated.
+		pkgname := truncateName(formatPackageName(d.opts.PackageName, pkg), d.termWidth-len(prefix)-minDotsWidth, d.opts.Truncate) + " "
 		line.checkWidth(len(prefix+pkgname), d.termWidth)
 		fmt.Fprintf(d.writer, prefix+pkgname+line.builder.String()+"\n")
 	}
@@ -135,3 +135,16 @@ func TestNewDotFormatter(t *testing.T) {
 	skip.If(t, !ok, "no terminal width")
 	assert.Assert(t, d.termWidth != 0)
 }
+
+func TestNewDotFormatter_WithTerminalOptions(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	ef := newDotFormatter(buf, FormatOptions{TerminalWidth: 100})
+	d, ok := ef.(*dotFormatter)
+	assert.Assert(t, ok, "expected dotFormatter, got %T", ef)
+	assert.Equal(t, d.termWidth, 100)
+
+	ef = newDotFormatter(buf, FormatOptions{NoTerminal: true, TerminalWidth: 100})
+	_, ok = ef.(*formatAdapter)
+	assert.Assert(t, ok, "expected formatAdapter, got %T", ef)
+}
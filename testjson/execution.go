@@ -11,6 +11,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/sync/errgroup"
 	"gotest.tools/gotestsum/internal/log"
@@ -29,8 +31,38 @@ const (
 	ActionFail   Action = "fail"
 	ActionOutput Action = "output"
 	ActionSkip   Action = "skip"
+	ActionStart  Action = "start"
+
+	// ActionBuildOutput and ActionBuildFail are emitted by go test -json in
+	// go1.24 and later for the output of a package that failed to build. The
+	// Package of these events is empty, and ImportPath is set instead.
+	ActionBuildOutput Action = "build-output"
+	ActionBuildFail   Action = "build-fail"
 )
 
+// known returns true if the Action is one of the actions emitted by test2json,
+// or an action added with RegisterAction.
+func (a Action) known() bool {
+	if a.builtin() {
+		return true
+	}
+	_, ok := registeredAction(a)
+	return ok
+}
+
+// builtin returns true if the Action is one of the actions handled by
+// Execution.
+func (a Action) builtin() bool {
+	switch a {
+	case ActionRun, ActionPause, ActionCont, ActionPass, ActionBench,
+		ActionFail, ActionOutput, ActionSkip, ActionStart,
+		ActionBuildOutput, ActionBuildFail:
+		return true
+	default:
+		return false
+	}
+}
+
 // IsTerminal returns true if the Action is one of: pass, fail, skip.
 func (a Action) IsTerminal() bool {
 	switch a {
@@ -52,6 +84,13 @@ type TestEvent struct {
 	Elapsed float64
 	// Output of test or benchmark
 	Output string
+	// ImportPath of the package that is being built, which is only set on
+	// build-output and build-fail events. For a test binary it includes the
+	// name of the test variant, ex: example.com/pkg [example.com/pkg.test].
+	ImportPath string `json:",omitempty"`
+	// FailedBuild is the ImportPath of the package that failed to build, set on
+	// the fail event of a package that could not be tested.
+	FailedBuild string `json:",omitempty"`
 	// raw is the raw JSON bytes of the event
 	raw []byte
 	// RunID from the ScanConfig which produced this test event.
@@ -60,7 +99,12 @@ type TestEvent struct {
 
 // PackageEvent returns true if the event is a package start or end event
 func (e TestEvent) PackageEvent() bool {
-	return e.Test == ""
+	return e.Test == "" && !e.buildEvent()
+}
+
+// buildEvent returns true if the event is a build-output or build-fail event.
+func (e TestEvent) buildEvent() bool {
+	return e.Action == ActionBuildOutput || e.Action == ActionBuildFail
 }
 
 // ElapsedFormatted returns Elapsed formatted in the go test format, ex (0.00s).
@@ -75,11 +119,14 @@ func (e TestEvent) Bytes() []byte {
 
 // Package is the set of TestEvents for a single go package
 type Package struct {
-	Total   int
-	running map[string]TestCase
-	Failed  []TestCase
-	Skipped []TestCase
-	Passed  []TestCase
+	Total int
+	// Examples is the number of test cases that are Example functions. The
+	// examples are included in Total.
+	Examples int
+	running  map[string]TestCase
+	Failed   []TestCase
+	Skipped  []TestCase
+	Passed   []TestCase
 
 	// elapsed time reported by the pass or fail event for the package.
 	elapsed time.Duration
@@ -106,14 +153,38 @@ type Package struct {
 	// github.com/golang/go/issues/45508. This field may be removed in the future
 	// if the issue is fixed in Go.
 	panicked bool
+	// timedOut is true if the package test binary panicked because the
+	// -timeout was exceeded.
+	timedOut bool
+	// buildFailed is true if the fail event of the package reported that the
+	// package failed to build. The build errors are reported by
+	// Execution.Errors, so the package is not a TestMain failure.
+	buildFailed bool
 	// shuffleSeed is the seed used to shuffle the tests. The value is set when
 	// tests are run with -shuffle
 	shuffleSeed string
 
+	// started is the time of the start event for the package, which is
+	// emitted by go1.20 and later when the test binary starts.
+	started time.Time
+	// firstTestRun is the time the first test started.
+	firstTestRun time.Time
+	// lastTestEnd is the time the last top-level test ended.
+	lastTestEnd time.Time
+	// ended is the time of the pass or fail event for the package.
+	ended time.Time
+
 	// testTimeoutPanicInTest stores the name of a test that received the panic
 	// output caused by a test timeout. This is necessary to work around a race
 	// condition in test2json. See https://github.com/golang/go/issues/57305.
 	testTimeoutPanicInTest string
+
+	// paused is the set of running tests that are paused by t.Parallel, and
+	// are waiting to continue.
+	paused map[string]bool
+	// unattributed is the output that test2json attributed to a test that
+	// did not print it. See outputTestID.
+	unattributed []UnattributedOutput
 }
 
 // Result returns if the package passed, failed, or was skipped because there
@@ -128,6 +199,38 @@ func (p *Package) Elapsed() time.Duration {
 	return p.elapsed
 }
 
+// ShuffleSeed returns the seed used to shuffle the order of the tests, or an
+// empty string if the tests were not run with -shuffle.
+func (p *Package) ShuffleSeed() string {
+	return strings.TrimPrefix(p.shuffleSeed, "-test.shuffle ")
+}
+
+// Setup returns the time spent in TestMain, or init functions, before the
+// first test started. It returns 0 when the time is not known, because the
+// events were created by a version of go before go1.20.
+func (p *Package) Setup() time.Duration {
+	return durationBetween(p.started, p.firstTestRun)
+}
+
+// Teardown returns the time spent in TestMain after the last test ended. It
+// returns 0 when the time is not known.
+func (p *Package) Teardown() time.Duration {
+	return durationBetween(p.lastTestEnd, p.ended)
+}
+
+// IsSlowSetup returns true when d, the Setup or Teardown time of a package, is
+// greater than threshold. A threshold of zero is never slow.
+func IsSlowSetup(d, threshold time.Duration) bool {
+	return threshold > 0 && d > threshold
+}
+
+func durationBetween(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
 // TestCases returns all the test cases.
 func (p *Package) TestCases() []TestCase {
 	tc := append([]TestCase{}, p.Passed...)
@@ -188,6 +291,9 @@ func (p *Package) addOutput(id int, output string) {
 	if strings.HasPrefix(output, "panic: ") {
 		p.panicked = true
 	}
+	if strings.HasPrefix(output, "panic: test timed out") {
+		p.timedOut = true
+	}
 	// TODO: limit size of buffered test output
 	p.output[id] = append(p.output[id], output)
 }
@@ -212,6 +318,21 @@ func (n TestName) Name() string {
 	return string(n)
 }
 
+// IsExample returns true if the name is the name of an Example function, using
+// the same rules as go test.
+func (n TestName) IsExample() bool {
+	root, _ := n.Split()
+	if !strings.HasPrefix(root, "Example") {
+		return false
+	}
+	rest := root[len("Example"):]
+	if rest == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return !unicode.IsLower(r)
+}
+
 func (p *Package) removeOutput(id int) {
 	delete(p.output, id)
 
@@ -233,13 +354,15 @@ func tcIDSet(skipped []TestCase) map[int]struct{} {
 
 // TestMainFailed returns true if the package failed, but there were no tests.
 // This may occur if the package init() or TestMain exited non-zero.
+// A package that failed to build is not a TestMain failure, the build errors
+// are reported by Execution.Errors.
 func (p *Package) TestMainFailed() bool {
-	return p.action == ActionFail && len(p.Failed) == 0
+	return p.action == ActionFail && len(p.Failed) == 0 && !p.buildFailed
 }
 
 // IsEmpty returns true if this package contains no tests.
 func (p *Package) IsEmpty() bool {
-	return p.Total == 0 && !p.TestMainFailed()
+	return p.Total == 0 && !p.TestMainFailed() && !p.buildFailed
 }
 
 const neverFinished time.Duration = -1
@@ -315,6 +438,24 @@ type TestCase struct {
 	hasSubTestFailed bool
 	// Time when the test was run.
 	Time time.Time
+	// Parallel is true when the test called t.Parallel, and was paused until
+	// the tests that are not parallel were done.
+	Parallel bool
+	// end is the time of the event that ended the test.
+	end time.Time
+}
+
+// Span returns the time when the test was running. The time a parallel test
+// spent paused, waiting for other tests, is not included. When the events
+// have no time, both start and end are zero.
+func (tc TestCase) Span() (start, end time.Time) {
+	if tc.end.IsZero() {
+		if tc.Time.IsZero() {
+			return time.Time{}, time.Time{}
+		}
+		return tc.Time, tc.Time.Add(tc.Elapsed)
+	}
+	return tc.end.Add(-tc.Elapsed), tc.end
 }
 
 func newPackage() *Package {
@@ -322,20 +463,38 @@ func newPackage() *Package {
 		output:   make(map[int][]string),
 		running:  make(map[string]TestCase),
 		subTests: make(map[int][]int),
+		paused:   make(map[string]bool),
 	}
 }
 
 // Execution of one or more test packages
 type Execution struct {
-	started    time.Time
-	packages   map[string]*Package
-	errorsLock sync.RWMutex
-	errors     []string
-	done       bool
-	lastRunID  int
+	started time.Time
+	// packagesLock is held while adding packages, and while starting or
+	// ending a test, so that Running can be called from any goroutine.
+	packagesLock sync.RWMutex
+	packages     map[string]*Package
+	errorsLock   sync.RWMutex
+	errors       []string
+	// buildErrors are parsed from errors.
+	buildErrors []BuildError
+	// diagnostics are the lines from stderr that are not errors, saved when
+	// ScanConfig.CaptureDiagnostics is set.
+	diagnostics []string
+	// invalidLines are the lines from stdout that are not valid events, saved
+	// when ScanConfig.StrictJSON is set.
+	invalidLines []InvalidLine
+	// buildErrorPkg is the package of the most recent build error header.
+	buildErrorPkg string
+	done          bool
+	lastRunID     int
+	// strictOutputAttribution is set from the ScanConfig of the current scan.
+	strictOutputAttribution bool
 }
 
 func (e *Execution) add(event TestEvent) {
+	e.packagesLock.Lock()
+	defer e.packagesLock.Unlock()
 	pkg, ok := e.packages[event.Package]
 	if !ok {
 		pkg = newPackage()
@@ -345,23 +504,29 @@ func (e *Execution) add(event TestEvent) {
 		pkg.addEvent(event)
 		return
 	}
-	pkg.addTestEvent(event)
+	pkg.addTestEvent(event, e.strictOutputAttribution)
 }
 
 func (p *Package) addEvent(event TestEvent) {
 	switch event.Action {
+	case ActionStart:
+		if p.started.IsZero() {
+			p.started = event.Time
+		}
 	case ActionPass, ActionFail:
 		p.action = event.Action
 		p.elapsed = elapsedDuration(event.Elapsed)
+		p.ended = event.Time
+		p.buildFailed = event.FailedBuild != ""
 	case ActionOutput:
 		if isCoverageOutput(event.Output) {
-			p.coverage = strings.TrimRight(event.Output, "\n")
+			p.coverage = strings.TrimRight(event.Output, "\r\n")
 		}
 		if strings.Contains(event.Output, "\t(cached)") {
 			p.cached = true
 		}
 		if isShuffleSeedOutput(event.Output) {
-			p.shuffleSeed = strings.TrimRight(event.Output, "\n")
+			p.shuffleSeed = strings.TrimRight(event.Output, "\r\n")
 		}
 		p.addOutput(0, event.Output)
 	}
@@ -371,6 +536,9 @@ func (p *Package) newTestCaseFromEvent(event TestEvent) TestCase {
 	// Incremental total before using it as the ID, because ID 0 is used for
 	// the package output
 	p.Total++
+	if TestName(event.Test).IsExample() {
+		p.Examples++
+	}
 	return TestCase{
 		Package: event.Package,
 		Test:    TestName(event.Test),
@@ -380,8 +548,11 @@ func (p *Package) newTestCaseFromEvent(event TestEvent) TestCase {
 	}
 }
 
-func (p *Package) addTestEvent(event TestEvent) {
+func (p *Package) addTestEvent(event TestEvent, strictOutputAttribution bool) {
 	if event.Action == ActionRun {
+		if p.firstTestRun.IsZero() {
+			p.firstTestRun = event.Time
+		}
 		tc := p.newTestCaseFromEvent(event)
 		p.running[event.Test] = tc
 
@@ -411,16 +582,29 @@ func (p *Package) addTestEvent(event TestEvent) {
 			return
 		}
 
-		tc := p.running[event.Test]
-		p.addOutput(tc.ID, event.Output)
+		p.addOutput(p.outputTestID(event, strictOutputAttribution), event.Output)
 		return
-	case ActionPause, ActionCont:
+	case ActionPause:
+		if p.paused == nil {
+			p.paused = make(map[string]bool)
+		}
+		p.paused[event.Test] = true
+		tc.Parallel = true
+		p.running[event.Test] = tc
+		return
+	case ActionCont:
+		delete(p.paused, event.Test)
 		return
 	}
 
 	// the event.Action must be one of the three "test end" events
 	delete(p.running, event.Test)
+	delete(p.paused, event.Test)
 	tc.Elapsed = elapsedDuration(event.Elapsed)
+	tc.end = event.Time
+	if !tc.Test.IsSubTest() && event.Time.After(p.lastTestEnd) {
+		p.lastTestEnd = event.Time
+	}
 
 	switch event.Action {
 	case ActionFail:
@@ -495,6 +679,43 @@ func (e *Execution) Elapsed() time.Duration {
 	return timeNow().Sub(e.started)
 }
 
+// Running returns the tests that have started and have not ended, sorted by
+// the time they started, the package, and the name of the test. The Elapsed
+// time of each TestCase is the time since the test started, which is zero when
+// the events do not include a time. Running may be called while the Execution
+// is being populated by ScanTestOutput, from any goroutine.
+func (e *Execution) Running() []TestCase {
+	if e == nil {
+		return nil
+	}
+	e.packagesLock.RLock()
+	var running []TestCase
+	for _, pkg := range e.packages {
+		for _, tc := range pkg.running {
+			running = append(running, tc)
+		}
+	}
+	e.packagesLock.RUnlock()
+
+	now := timeNow()
+	for i, tc := range running {
+		if !tc.Time.IsZero() {
+			running[i].Elapsed = now.Sub(tc.Time)
+		}
+	}
+	sort.Slice(running, func(i, j int) bool {
+		ti, tj := running[i], running[j]
+		switch {
+		case !ti.Time.Equal(tj.Time):
+			return ti.Time.Before(tj.Time)
+		case ti.Package != tj.Package:
+			return ti.Package < tj.Package
+		}
+		return ti.Test < tj.Test
+	})
+	return running
+}
+
 // Failed returns a list of all the failed test cases.
 func (e *Execution) Failed() []TestCase {
 	if e == nil {
@@ -554,14 +775,70 @@ func (e *Execution) Total() int {
 	return total
 }
 
+// Examples returns a count of all the test cases that are Example functions.
+func (e *Execution) Examples() int {
+	total := 0
+	for _, pkg := range e.packages {
+		total += pkg.Examples
+	}
+	return total
+}
+
 func (e *Execution) addError(err string) {
+	e.errorsLock.Lock()
+	defer e.errorsLock.Unlock()
+	e.addBuildError(err)
 	// Build errors start with a header
 	if strings.HasPrefix(err, "# ") {
 		return
 	}
-	e.errorsLock.Lock()
 	e.errors = append(e.errors, err)
-	e.errorsLock.Unlock()
+}
+
+func (e *Execution) addDiagnostic(line string) {
+	e.errorsLock.Lock()
+	defer e.errorsLock.Unlock()
+	e.diagnostics = append(e.diagnostics, line)
+}
+
+// Diagnostics returns the lines from the stderr of go test that are not
+// errors, like warnings from the toolchain, and the modules downloaded by the
+// go command. Diagnostics are only saved when ScanConfig.CaptureDiagnostics is
+// set.
+func (e *Execution) Diagnostics() []string {
+	e.errorsLock.RLock()
+	defer e.errorsLock.RUnlock()
+	return e.diagnostics
+}
+
+// InvalidLine is a line of the test2json output that is not a valid
+// TestEvent.
+type InvalidLine struct {
+	// RunID from the ScanConfig which read the line.
+	RunID int
+	// Line is the line number, starting at 1, in the Stdout of the ScanConfig.
+	Line int
+	// Offset is the number of bytes in the Stdout of the ScanConfig before the
+	// start of the line.
+	Offset int64
+	// Text of the line.
+	Text string
+	// Reason the line is not a valid TestEvent.
+	Reason string
+}
+
+func (e *Execution) addInvalidLine(line InvalidLine) {
+	e.errorsLock.Lock()
+	defer e.errorsLock.Unlock()
+	e.invalidLines = append(e.invalidLines, line)
+}
+
+// InvalidLines returns the lines of the test2json output that were not valid
+// TestEvents. Invalid lines are only saved when ScanConfig.StrictJSON is set.
+func (e *Execution) InvalidLines() []InvalidLine {
+	e.errorsLock.RLock()
+	defer e.errorsLock.RUnlock()
+	return e.invalidLines
 }
 
 // Errors returns a list of all the errors.
@@ -571,6 +848,20 @@ func (e *Execution) Errors() []string {
 	return e.errors
 }
 
+// RemoveErrors removes all the errors, and the build errors parsed from them,
+// and returns the removed errors. It is used before running packages again,
+// so that errors from the previous run are not reported when the packages
+// succeed.
+func (e *Execution) RemoveErrors() []string {
+	e.errorsLock.Lock()
+	defer e.errorsLock.Unlock()
+	removed := e.errors
+	e.errors = nil
+	e.buildErrors = nil
+	e.buildErrorPkg = ""
+	return removed
+}
+
 // HasPanic returns true if at least one package had output that looked like a
 // panic.
 func (e *Execution) HasPanic() bool {
@@ -582,7 +873,20 @@ func (e *Execution) HasPanic() bool {
 	return false
 }
 
+// HasTimeout returns true if at least one package test binary panicked because
+// the go test -timeout was exceeded.
+func (e *Execution) HasTimeout() bool {
+	for _, pkg := range e.packages {
+		if pkg.timedOut {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *Execution) end() []TestEvent {
+	e.packagesLock.Lock()
+	defer e.packagesLock.Unlock()
 	e.done = true
 	var result []TestEvent // nolint: prealloc
 	for _, pkg := range e.packages {
@@ -624,6 +928,27 @@ type ScanConfig struct {
 	// IgnoreNonJSONOutputLines causes ScanTestOutput to ignore non-JSON lines received from
 	// the Stdout reader. Instead of causing an error, the lines will be sent to Handler.Err.
 	IgnoreNonJSONOutputLines bool
+	// CaptureDiagnostics saves the lines from the Stderr reader that are not
+	// errors, like toolchain warnings, so that they are returned by
+	// Execution.Diagnostics. The lines are always sent to Handler.Err.
+	CaptureDiagnostics bool
+	// StrictJSON saves the lines from the Stdout reader that are not valid
+	// TestEvents, including JSON objects without a known Action, so that they
+	// are returned by Execution.InvalidLines. The lines are still handled the
+	// same way as when StrictJSON is not set.
+	StrictJSON bool
+	// StrictOutputAttribution moves output that was printed directly to
+	// os.Stdout, not with t.Log, to the package output when more than one test
+	// is running, because test2json may have attributed the output to the
+	// wrong test. By default only the output attributed to a paused test is
+	// moved. The moved output is returned by Execution.UnattributedOutput.
+	StrictOutputAttribution bool
+	// ScrubOutput is called with the Output of every TestEvent, every line
+	// from the Stderr reader, and every line from the Stdout reader that is
+	// not a TestEvent, before they are added to the Execution or sent
+	// to the Handler. It may return the text with secrets removed. The raw
+	// bytes of an event are also changed when the output is changed.
+	ScrubOutput func(text string) string
 }
 
 // EventHandler is called by ScanTestOutput for each event and write to stderr.
@@ -659,6 +984,7 @@ func ScanTestOutput(config ScanConfig) (*Execution, error) {
 	}
 	execution.done = false
 	execution.lastRunID = config.RunID
+	execution.strictOutputAttribution = config.StrictOutputAttribution
 
 	var group errgroup.Group
 	group.Go(func() error {
@@ -687,25 +1013,50 @@ func stopOnError(stop func(), err error) error {
 
 func readStdout(config ScanConfig, execution *Execution) error {
 	scanner := bufio.NewScanner(config.Stdout)
+	var lineNum int
+	var offset int64
 	for scanner.Scan() {
-		raw := scanner.Bytes()
+		lineNum++
+		lineOffset := offset
+		offset += int64(len(scanner.Bytes())) + 1
+		raw := dropCR(scanner.Bytes())
 		event, err := parseEvent(raw)
+		if config.StrictJSON {
+			if reason := invalidEventReason(event, err); reason != "" {
+				execution.addInvalidLine(InvalidLine{
+					RunID:  config.RunID,
+					Line:   lineNum,
+					Offset: lineOffset,
+					Text:   scrubLine(config, string(raw)),
+					Reason: reason,
+				})
+			}
+		}
 		switch {
 		case err == errBadEvent:
 			// nolint: errcheck
-			config.Handler.Err(errBadEvent.Error() + ": " + scanner.Text())
+			config.Handler.Err(errBadEvent.Error() + ": " + scrubLine(config, string(raw)))
 			continue
 		case err != nil:
+			line := scrubLine(config, string(raw))
 			if config.IgnoreNonJSONOutputLines {
 				// nolint: errcheck
-				config.Handler.Err(string(raw))
+				config.Handler.Err(line)
 				continue
 			}
-			return fmt.Errorf("failed to parse test output: %s: %w", string(raw), err)
+			return fmt.Errorf("failed to parse test output: %s: %w", line, err)
 		}
 
 		event.RunID = config.RunID
-		execution.add(event)
+		if config.ScrubOutput != nil && event.Output != "" {
+			scrubEvent(&event, config.ScrubOutput)
+		}
+		event, ok := adaptEvent(event)
+		if ok {
+			if err := execution.addEventOrBuildOutput(config, event); err != nil {
+				return err
+			}
+		}
 		if err := config.Handler.Event(event, execution); err != nil {
 			return err
 		}
@@ -716,17 +1067,36 @@ func readStdout(config ScanConfig, execution *Execution) error {
 	return nil
 }
 
+// addEventOrBuildOutput adds the event to the execution. The output of a
+// build-output event is handled the same way as a line of build errors from
+// the stderr of go test, which is where older versions of go print it.
+func (e *Execution) addEventOrBuildOutput(config ScanConfig, event TestEvent) error {
+	switch event.Action {
+	case ActionBuildOutput:
+		line := strings.TrimSuffix(event.Output, "\n")
+		if err := config.Handler.Err(line); err != nil {
+			return fmt.Errorf("failed to handle build output: %v", err)
+		}
+		e.addError(line)
+		return nil
+	case ActionBuildFail:
+		return nil
+	}
+	e.add(event)
+	return nil
+}
+
 func readStderr(config ScanConfig, execution *Execution) error {
 	scanner := bufio.NewScanner(config.Stderr)
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := scrubLine(config, string(dropCR(scanner.Bytes())))
 		if err := config.Handler.Err(line); err != nil {
 			return fmt.Errorf("failed to handle stderr: %v", err)
 		}
-		if isGoModuleOutput(line) {
-			continue
-		}
-		if strings.HasPrefix(line, "warning:") {
+		if isGoModuleOutput(line) || strings.HasPrefix(line, "warning:") {
+			if config.CaptureDiagnostics {
+				execution.addDiagnostic(line)
+			}
 			continue
 		}
 		execution.addError(line)
@@ -737,6 +1107,46 @@ func readStderr(config ScanConfig, execution *Execution) error {
 	return nil
 }
 
+// scrubLine returns the line with the secrets removed by config.ScrubOutput.
+func scrubLine(config ScanConfig, line string) string {
+	if config.ScrubOutput == nil {
+		return line
+	}
+	return config.ScrubOutput(line)
+}
+
+// scrubEvent replaces the Output of the event with the scrubbed output. The
+// raw bytes of the event are encoded again, so that the secret is not written
+// to the --jsonfile.
+func scrubEvent(event *TestEvent, scrub func(string) string) {
+	output := scrub(event.Output)
+	if output == event.Output {
+		return
+	}
+	event.Output = output
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(event.raw, &fields); err != nil {
+		event.raw = nil
+		return
+	}
+	raw, err := json.Marshal(output)
+	if err != nil {
+		event.raw = nil
+		return
+	}
+	fields["Output"] = raw
+	if event.raw, err = json.Marshal(fields); err != nil {
+		event.raw = nil
+	}
+}
+
+// dropCR removes the carriage return from a line that ended with \r\n, which
+// is common in output that was written on Windows.
+func dropCR(line []byte) []byte {
+	return bytes.TrimSuffix(line, []byte("\r"))
+}
+
 func isGoModuleOutput(scannerText string) bool {
 	prefixes := []string{
 		"go: copying",
@@ -769,6 +1179,22 @@ func parseEvent(raw []byte) (TestEvent, error) {
 
 var errBadEvent = errors.New("bad output from test2json")
 
+// invalidEventReason returns the reason the event is not a valid TestEvent,
+// or an empty string if the event is valid.
+func invalidEventReason(event TestEvent, err error) string {
+	switch {
+	case err == errBadEvent:
+		return err.Error()
+	case err != nil:
+		return "not a JSON object: " + err.Error()
+	case event.Action == "":
+		return "missing Action"
+	case !event.Action.known():
+		return fmt.Sprintf("unknown Action %q", event.Action)
+	}
+	return ""
+}
+
 type noopHandler struct{}
 
 func (s noopHandler) Event(TestEvent, *Execution) error {
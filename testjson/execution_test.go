@@ -3,6 +3,8 @@ package testjson
 import (
 	"bytes"
 	"fmt"
+	"path"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,6 +46,14 @@ func TestScanTestOutput_MinimalConfig(t *testing.T) {
 	assert.Equal(t, exec.Total(), 59)
 }
 
+func TestPackage_ShuffleSeed(t *testing.T) {
+	in := bytes.NewReader(golden.Get(t, "input/go-test-json-with-shuffle.out"))
+	exec, err := ScanTestOutput(ScanConfig{Stdout: in})
+	assert.NilError(t, err)
+	assert.Equal(t, exec.Package("gotest.tools/gotestsum/testjson/internal/good").ShuffleSeed(), "123456")
+	assert.Equal(t, exec.Package("gotest.tools/gotestsum/testjson/internal/badmain").ShuffleSeed(), "")
+}
+
 func TestScanTestOutput_CallsStopOnError(t *testing.T) {
 	var called bool
 	stop := func() {
@@ -217,6 +227,39 @@ func TestScanOutput_WithNonJSONLines(t *testing.T) {
 	}
 }
 
+func TestScanTestOutput_WithCRLF(t *testing.T) {
+	stdout := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"output","Package":"example.com/a","Output":"coverage: 50.0% of statements\r\n"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"pass","Package":"example.com/a"}`,
+		"not json",
+		"",
+	}, "\r\n")
+	stderr := "b.go:3:1: syntax error\r\nb.go:4:1: syntax error\r\n"
+
+	handler := &captureHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                   strings.NewReader(stdout),
+		Stderr:                   strings.NewReader(""),
+		Handler:                  handler,
+		IgnoreNonJSONOutputLines: true,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, exec.Total(), 1)
+	assert.DeepEqual(t, handler.errs, []string{"not json"})
+	pkg := exec.Package("example.com/a")
+	assert.Equal(t, pkg.Result(), ActionPass)
+	assert.Equal(t, pkg.coverage, "coverage: 50.0% of statements")
+
+	exec, err = ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(""),
+		Stderr: strings.NewReader(stderr),
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, exec.Errors(), []string{"b.go:3:1: syntax error", "b.go:4:1: syntax error"})
+}
+
 type captureHandler struct {
 	events []TestEvent
 	errs   []string
@@ -231,3 +274,308 @@ func (s *captureHandler) Err(text string) error {
 	s.errs = append(s.errs, text)
 	return fmt.Errorf(text)
 }
+
+func TestExecution_HasTimeout(t *testing.T) {
+	exec := newExecution()
+	exec.add(TestEvent{Package: "one", Test: "TestSlow", Action: ActionRun})
+	exec.add(TestEvent{Package: "one", Test: "TestSlow", Action: ActionOutput, Output: "panic: boom\n"})
+	assert.Assert(t, !exec.HasTimeout())
+
+	exec.add(TestEvent{
+		Package: "two",
+		Test:    "TestSlow",
+		Action:  ActionOutput,
+		Output:  "panic: test timed out after 1s\n",
+	})
+	assert.Assert(t, exec.HasTimeout())
+}
+
+func TestTestName_IsExample(t *testing.T) {
+	assert.Assert(t, TestName("Example").IsExample())
+	assert.Assert(t, TestName("ExampleExecution").IsExample())
+	assert.Assert(t, TestName("ExampleExecution_Total").IsExample())
+	assert.Assert(t, TestName("Example_suffix").IsExample())
+	assert.Assert(t, !TestName("Examples").IsExample())
+	assert.Assert(t, !TestName("TestExample").IsExample())
+	assert.Assert(t, !TestName("TestExample/ExampleSub").IsExample())
+}
+
+func TestExecution_Examples(t *testing.T) {
+	exec := newExecution()
+	exec.add(TestEvent{Package: "one", Test: "TestOne", Action: ActionRun})
+	exec.add(TestEvent{Package: "one", Test: "TestOne", Action: ActionPass})
+	exec.add(TestEvent{Package: "one", Test: "ExampleOne", Action: ActionRun})
+	exec.add(TestEvent{Package: "one", Test: "ExampleOne", Action: ActionPass})
+	exec.add(TestEvent{Package: "two", Test: "ExampleTwo", Action: ActionRun})
+	exec.add(TestEvent{Package: "two", Test: "ExampleTwo", Action: ActionFail})
+
+	assert.Equal(t, exec.Total(), 3)
+	assert.Equal(t, exec.Examples(), 2)
+	assert.Equal(t, exec.Package("one").Examples, 1)
+}
+
+func TestPackage_SetupTeardown(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time {
+		return start.Add(d)
+	}
+	exec := newExecution()
+	exec.add(TestEvent{Package: "one", Action: ActionStart, Time: at(0)})
+	exec.add(TestEvent{Package: "one", Test: "TestA", Action: ActionRun, Time: at(2 * time.Second)})
+	exec.add(TestEvent{Package: "one", Test: "TestA/sub", Action: ActionRun, Time: at(2 * time.Second)})
+	exec.add(TestEvent{Package: "one", Test: "TestA/sub", Action: ActionPass, Time: at(3 * time.Second)})
+	exec.add(TestEvent{Package: "one", Test: "TestA", Action: ActionPass, Time: at(3 * time.Second)})
+	exec.add(TestEvent{Package: "one", Action: ActionPass, Time: at(5 * time.Second)})
+
+	pkg := exec.Package("one")
+	assert.Equal(t, pkg.Setup(), 2*time.Second)
+	assert.Equal(t, pkg.Teardown(), 2*time.Second)
+
+	t.Run("without a start event", func(t *testing.T) {
+		exec := newExecution()
+		exec.add(TestEvent{Package: "one", Test: "TestA", Action: ActionRun, Time: at(0)})
+		exec.add(TestEvent{Package: "one", Test: "TestA", Action: ActionPass, Time: at(time.Second)})
+		exec.add(TestEvent{Package: "one", Action: ActionPass, Time: at(time.Second)})
+		assert.Equal(t, exec.Package("one").Setup(), time.Duration(0))
+	})
+}
+
+func TestIsSlowSetup(t *testing.T) {
+	assert.Assert(t, IsSlowSetup(2*time.Second, time.Second))
+	assert.Assert(t, !IsSlowSetup(time.Second, time.Second))
+	assert.Assert(t, !IsSlowSetup(time.Second, 0))
+}
+
+func TestExecution_Running(t *testing.T) {
+	patchTimeNow(t)
+	now := timeNow()
+	exec := newExecution()
+	assert.Equal(t, len(exec.Running()), 0)
+
+	events := []TestEvent{
+		{Package: "example.com/b", Test: "TestSlow", Action: ActionRun, Time: now.Add(-time.Minute)},
+		{Package: "example.com/a", Test: "TestSlow", Action: ActionRun, Time: now.Add(-time.Minute)},
+		{Package: "example.com/a", Test: "TestSlow/sub", Action: ActionRun, Time: now.Add(-time.Second)},
+		{Package: "example.com/a", Test: "TestDone", Action: ActionRun, Time: now.Add(-time.Minute)},
+		{Package: "example.com/a", Test: "TestDone", Action: ActionPass, Time: now},
+		{Package: "example.com/c", Test: "TestNoTime", Action: ActionRun},
+	}
+	for _, event := range events {
+		exec.add(event)
+	}
+
+	running := exec.Running()
+	var names []string
+	for _, tc := range running {
+		names = append(names, path.Join(tc.Package, tc.Test.Name()))
+	}
+	expected := []string{
+		"example.com/c/TestNoTime",
+		"example.com/a/TestSlow",
+		"example.com/b/TestSlow",
+		"example.com/a/TestSlow/sub",
+	}
+	assert.DeepEqual(t, names, expected)
+	assert.Equal(t, running[0].Elapsed, time.Duration(0))
+	assert.Equal(t, running[1].Elapsed, time.Minute)
+	assert.Equal(t, running[3].Elapsed, time.Second)
+}
+
+func TestScanTestOutput_CaptureDiagnostics(t *testing.T) {
+	stderr := strings.Join([]string{
+		"go: downloading example.com/dep v1.2.0",
+		"warning: GOPATH set to GOROOT has no effect",
+		"a.go:3:1: syntax error",
+		"",
+	}, "\n")
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:             strings.NewReader(""),
+		Stderr:             strings.NewReader(stderr),
+		CaptureDiagnostics: true,
+	})
+	assert.NilError(t, err)
+	expected := []string{
+		"go: downloading example.com/dep v1.2.0",
+		"warning: GOPATH set to GOROOT has no effect",
+	}
+	assert.DeepEqual(t, exec.Diagnostics(), expected)
+	assert.DeepEqual(t, exec.Errors(), []string{"a.go:3:1: syntax error"})
+
+	exec, err = ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(""),
+		Stderr: strings.NewReader(stderr),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.Diagnostics()), 0)
+}
+
+func TestScanTestOutput_StrictJSON(t *testing.T) {
+	stdout := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`hello from a test`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Package":"example.com/a"}`,
+		`{"Action":"finish","Package":"example.com/a"}`,
+		`{"Action":"pass","Package":"example.com/a"}`,
+		"",
+	}, "\n")
+	handler := &captureHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		RunID:                    2,
+		Stdout:                   strings.NewReader(stdout),
+		Handler:                  handler,
+		StrictJSON:               true,
+		IgnoreNonJSONOutputLines: true,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, exec.Total(), 1)
+	assert.Equal(t, exec.Package("example.com/a").Result(), ActionPass)
+	assert.DeepEqual(t, handler.errs, []string{"hello from a test"})
+
+	expected := []InvalidLine{
+		{
+			RunID:  2,
+			Line:   2,
+			Offset: 60,
+			Text:   "hello from a test",
+			Reason: "not a JSON object: invalid character 'h' looking for beginning of value",
+		},
+		{RunID: 2, Line: 4, Offset: 139, Text: `{"Package":"example.com/a"}`, Reason: "missing Action"},
+		{
+			RunID:  2,
+			Line:   5,
+			Offset: 167,
+			Text:   `{"Action":"finish","Package":"example.com/a"}`,
+			Reason: `unknown Action "finish"`,
+		},
+	}
+	assert.DeepEqual(t, exec.InvalidLines(), expected)
+}
+
+func TestScanTestOutput_StrictJSON_NonJSONLineIsAnError(t *testing.T) {
+	stdout := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`hello from a test`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestOne"}`,
+		"",
+	}, "\n")
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(stdout), StrictJSON: true})
+	assert.ErrorContains(t, err, "failed to parse test output: hello from a test")
+	assert.Equal(t, len(exec.InvalidLines()), 1)
+}
+
+func TestScanTestOutput_BuildOutputEvents(t *testing.T) {
+	stdout := strings.Join([]string{
+		`{"ImportPath":"example.com/a [example.com/a.test]","Action":"build-output","Output":"# example.com/a [example.com/a.test]\n"}`,
+		`{"ImportPath":"example.com/a [example.com/a.test]","Action":"build-output","Output":"./a_test.go:5:21: undefined: somepackage\n"}`,
+		`{"ImportPath":"example.com/a [example.com/a.test]","Action":"build-fail"}`,
+		`{"Action":"start","Package":"example.com/a"}`,
+		`{"Action":"output","Package":"example.com/a","Output":"FAIL\texample.com/a [build failed]\n"}`,
+		`{"Action":"fail","Package":"example.com/a","FailedBuild":"example.com/a [example.com/a.test]"}`,
+		"",
+	}, "\n")
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(stdout), StrictJSON: true})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, exec.Packages(), []string{"example.com/a"})
+	assert.DeepEqual(t, exec.Errors(), []string{"./a_test.go:5:21: undefined: somepackage"})
+	assert.Equal(t, len(exec.BuildErrors()), 1)
+	assert.Equal(t, exec.BuildErrors()[0].Package, "example.com/a")
+	assert.Equal(t, len(exec.Failed()), 0)
+	assert.Equal(t, len(exec.InvalidLines()), 0)
+
+	pkg := exec.Package("example.com/a")
+	assert.Equal(t, pkg.Result(), ActionFail)
+	assert.Assert(t, !pkg.TestMainFailed())
+	assert.Assert(t, !pkg.IsEmpty())
+}
+
+type recordingHandler struct {
+	events []TestEvent
+	errs   []string
+}
+
+func (h *recordingHandler) Event(event TestEvent, _ *Execution) error {
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingHandler) Err(text string) error {
+	h.errs = append(h.errs, text)
+	return nil
+}
+
+func TestScanTestOutput_ScrubOutput(t *testing.T) {
+	stdout := `{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"token: s3cret\n"}
+{"Action":"fail","Package":"pkg","Test":"TestOne"}
+{"Action":"fail","Package":"pkg"}
+`
+	handler := &recordingHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(stdout),
+		Stderr:  strings.NewReader("panic: s3cret\n"),
+		Handler: handler,
+		ScrubOutput: func(text string) string {
+			return strings.Replace(text, "s3cret", "[REDACTED]", -1)
+		},
+	})
+	assert.NilError(t, err)
+
+	tc := exec.Package("pkg").Failed[0]
+	assert.DeepEqual(t, exec.Package("pkg").OutputLines(tc), []string{"token: [REDACTED]\n"})
+	assert.DeepEqual(t, exec.Errors(), []string{"panic: [REDACTED]"})
+	assert.DeepEqual(t, handler.errs, []string{"panic: [REDACTED]"})
+
+	event := handler.events[1]
+	assert.Equal(t, event.Output, "token: [REDACTED]\n")
+	assert.Assert(t, !bytes.Contains(event.Bytes(), []byte("s3cret")), string(event.Bytes()))
+	assert.Assert(t, bytes.Contains(event.Bytes(), []byte(`"Test":"TestOne"`)), string(event.Bytes()))
+	assert.Equal(t, string(handler.events[0].Bytes()), `{"Action":"run","Package":"pkg","Test":"TestOne"}`)
+}
+
+func TestScanTestOutput_ScrubOutput_NonJSONLines(t *testing.T) {
+	stdout := `{"Action":"run","Package":"pkg","Test":"TestOne"}
+token: s3cret
+{"Action":"pass","Package":"pkg","Test":"TestOne"}
+{"Action":"pass","Package":"pkg"}
+`
+	scrub := func(text string) string {
+		return strings.Replace(text, "s3cret", "[REDACTED]", -1)
+	}
+	handler := &recordingHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                   strings.NewReader(stdout),
+		Handler:                  handler,
+		IgnoreNonJSONOutputLines: true,
+		StrictJSON:               true,
+		ScrubOutput:              scrub,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, handler.errs, []string{"token: [REDACTED]"})
+	assert.Equal(t, len(exec.InvalidLines()), 1)
+	assert.Equal(t, exec.InvalidLines()[0].Text, "token: [REDACTED]")
+
+	_, err = ScanTestOutput(ScanConfig{Stdout: strings.NewReader(stdout), ScrubOutput: scrub})
+	assert.ErrorContains(t, err, "token: [REDACTED]")
+	assert.Assert(t, !strings.Contains(err.Error(), "s3cret"), err.Error())
+}
+
+func TestTestCase_Parallel(t *testing.T) {
+	input := `{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"pause","Package":"pkg","Test":"TestOne"}
+{"Action":"run","Package":"pkg","Test":"TestTwo"}
+{"Action":"pass","Package":"pkg","Test":"TestTwo"}
+{"Action":"cont","Package":"pkg","Test":"TestOne"}
+{"Action":"pass","Package":"pkg","Test":"TestOne"}
+{"Action":"pass","Package":"pkg"}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	passed := exec.Package("pkg").Passed
+	assert.Equal(t, len(passed), 2)
+	assert.Equal(t, passed[0].Test, TestName("TestTwo"))
+	assert.Assert(t, !passed[0].Parallel)
+	assert.Equal(t, passed[1].Test, TestName("TestOne"))
+	assert.Assert(t, passed[1].Parallel)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 
 	"github.com/fatih/color"
 )
@@ -41,16 +42,29 @@ func standardQuietFormat(event TestEvent, _ *Execution) string {
 	return event.Output
 }
 
-func testNameFormat(event TestEvent, exec *Execution) string {
+func testNameFormat(opts FormatOptions) func(event TestEvent, exec *Execution) string {
+	if !opts.Tree {
+		return func(event TestEvent, exec *Execution) string {
+			return testNameLine(opts, event, exec)
+		}
+	}
+	tree := newTestTree()
+	return func(event TestEvent, exec *Execution) string {
+		text := testNameLine(opts, event, exec)
+		if event.PackageEvent() {
+			return text
+		}
+		return tree.format(event, text)
+	}
+}
+
+func testNameLine(opts FormatOptions, event TestEvent, exec *Execution) string {
 	result := colorEvent(event)(strings.ToUpper(string(event.Action)))
 	formatTest := func() string {
-		pkgPath := RelativePackagePath(event.Package)
-
-		return fmt.Sprintf("%s %s%s %s\n",
-			result,
-			joinPkgToTestName(pkgPath, event.Test),
-			formatRunID(event.RunID),
-			event.ElapsedFormatted())
+		if opts.Tree {
+			return treeTestLine(opts, event)
+		}
+		return testLine(opts, event)
 	}
 
 	switch {
@@ -67,7 +81,7 @@ func testNameFormat(event TestEvent, exec *Execution) string {
 		}
 
 		event.Elapsed = 0 // hide elapsed for now, for backwards compat
-		return result + " " + packageLine(event, exec.Package(event.Package))
+		return packageLine(opts, result+" ", event, exec.Package(event.Package))
 
 	case event.Action == ActionFail:
 		pkg := exec.Package(event.Package)
@@ -152,7 +166,7 @@ func shortFormatPackageEvent(opts FormatOptions, event TestEvent, exec *Executio
 	}
 
 	fmtEvent := func(action string) string {
-		return action + "  " + packageLine(event, exec.Package(event.Package))
+		return packageLine(opts, action+"  ", event, exec.Package(event.Package))
 	}
 	withColor := colorEvent(event)
 	switch event.Action {
@@ -175,10 +189,13 @@ func shortFormatPackageEvent(opts FormatOptions, event TestEvent, exec *Executio
 	return ""
 }
 
-func packageLine(event TestEvent, pkg *Package) string {
-	var buf strings.Builder
-	buf.WriteString(RelativePackagePath(event.Package))
+// packageLine returns the line for a package, which starts with prefix.
+func packageLine(opts FormatOptions, prefix string, event TestEvent, pkg *Package) string {
+	if opts.CoverageColumn {
+		prefix += coverageColumn(pkg) + " "
+	}
 
+	var buf strings.Builder
 	switch {
 	case pkg.cached:
 		buf.WriteString(" (cached)")
@@ -187,15 +204,17 @@ func packageLine(event TestEvent, pkg *Package) string {
 		buf.WriteString(fmt.Sprintf(" (%s)", d))
 	}
 
-	if pkg.coverage != "" {
+	if pkg.coverage != "" && !opts.CoverageColumn {
 		buf.WriteString(" (" + pkg.coverage + ")")
 	}
 
 	if event.Action == ActionFail && pkg.shuffleSeed != "" {
 		buf.WriteString(" (" + pkg.shuffleSeed + ")")
 	}
-	buf.WriteString("\n")
-	return buf.String()
+	if opts.Align {
+		return alignLine(opts, prefix, formatPackageName(opts.PackageName, event.Package), strings.TrimPrefix(buf.String(), " "))
+	}
+	return prefix + formatPackageName(opts.PackageName, event.Package) + buf.String() + "\n"
 }
 
 func pkgNameWithFailuresFormat(opts FormatOptions) func(event TestEvent, exec *Execution) string {
@@ -233,10 +252,79 @@ type EventFormatter interface {
 type FormatOptions struct {
 	HideEmptyPackages    bool
 	UseHiVisibilityIcons bool
+	// NoTerminal is true when the output is not written to a terminal. Formats
+	// that move the cursor to update previous lines print the same events
+	// using a format that only appends lines.
+	NoTerminal bool
+	// TerminalWidth is the width used by formats that wrap lines. When it is 0
+	// the width of the terminal connected to stdout is used.
+	TerminalWidth int
+	// Verbosity changes how much of the run is printed by the built-in
+	// formats. Formatters added with RegisterFormatter may use it to do the
+	// same.
+	Verbosity Verbosity
+	// Timestamps prints the time of the event at the start of each line.
+	Timestamps bool
+	// CoverageColumn prints the coverage of a package in a column before the
+	// name of the package, instead of after it.
+	CoverageColumn bool
+	// Tree prints the subtests of a test below the test, indented by the
+	// depth of the subtest.
+	Tree bool
+	// WallClock prints the time since the start of the run on the right side
+	// of the line printed when a test or package ends.
+	WallClock bool
+	// Align prints the elapsed time of tests and packages in a column on the
+	// right side of the terminal, and truncates names that do not fit.
+	Align bool
+	// Truncate is the part of a long name that is replaced by an ellipsis
+	// when the name does not fit in the terminal. Defaults to TruncateMiddle.
+	Truncate Truncate
+	// PackageName formats the package path printed by the formats. Defaults
+	// to RelativePackagePath.
+	PackageName func(pkgpath string) string
 }
 
-// NewEventFormatter returns a formatter for printing events.
+// NewEventFormatter returns a formatter for printing events. The format may
+// include modifiers, like testname+tree, see ParseFormat. NewEventFormatter
+// returns nil if the format is not valid.
 func NewEventFormatter(out io.Writer, format string, formatOpts FormatOptions) EventFormatter {
+	format, formatOpts, err := ParseFormat(format, formatOpts)
+	if err != nil {
+		return nil
+	}
+	if !isBuiltinFormat(format) {
+		formattersLock.RLock()
+		defer formattersLock.RUnlock()
+		return formatters[format](out, formatOpts)
+	}
+
+	if formatOpts.Align || formatOpts.WallClock {
+		formatOpts.TerminalWidth = terminalWidth(formatOpts)
+	}
+	var timestamps *timestampWriter
+	if formatOpts.Timestamps {
+		timestamps = &timestampWriter{out: out}
+		out = timestamps
+	}
+	var wallClock *wallClockFormatter
+	if formatOpts.WallClock {
+		wallClock = newWallClockFormatter(out, formatOpts)
+		out = wallClock.buf
+	}
+	formatter := newBuiltinFormatter(out, format, formatOpts)
+	formatter = withVerbosity(out, formatter, format, formatOpts)
+	if wallClock != nil {
+		wallClock.formatter = formatter
+		formatter = wallClock
+	}
+	if timestamps != nil {
+		return &timestampFormatter{formatter: formatter, writer: timestamps}
+	}
+	return formatter
+}
+
+func newBuiltinFormatter(out io.Writer, format string, formatOpts FormatOptions) EventFormatter {
 	switch format {
 	case "debug":
 		return &formatAdapter{out, debugFormat}
@@ -245,18 +333,56 @@ func NewEventFormatter(out io.Writer, format string, formatOpts FormatOptions) E
 	case "standard-quiet":
 		return &formatAdapter{out, standardQuietFormat}
 	case "dots", "dots-v1":
-		return &formatAdapter{out, dotsFormatV1}
+		return &formatAdapter{out, dotsFormatV1(formatOpts)}
 	case "dots-v2":
 		return newDotFormatter(out, formatOpts)
 	case "testname", "short-verbose":
-		return &formatAdapter{out, testNameFormat}
+		return &formatAdapter{out, testNameFormat(formatOpts)}
 	case "pkgname", "short":
 		return &formatAdapter{out, pkgNameFormat(formatOpts)}
 	case "pkgname-and-test-fails", "short-with-failures":
 		return &formatAdapter{out, pkgNameWithFailuresFormat(formatOpts)}
-	default:
-		return nil
 	}
+	return nil
+}
+
+// FormatterFactory creates an EventFormatter that writes to out.
+type FormatterFactory func(out io.Writer, opts FormatOptions) EventFormatter
+
+var (
+	formattersLock sync.RWMutex
+	formatters     = make(map[string]FormatterFactory)
+)
+
+// RegisterFormatter adds a formatter that is returned by NewEventFormatter
+// when it is called with name. An error is returned if name is already used
+// by a built-in formatter, or by another registered formatter.
+func RegisterFormatter(name string, factory FormatterFactory) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("formatter name must not be empty")
+	case factory == nil:
+		return fmt.Errorf("formatter %v must not be nil", name)
+	}
+	formattersLock.Lock()
+	defer formattersLock.Unlock()
+	if _, ok := formatters[name]; ok || isBuiltinFormat(name) {
+		return fmt.Errorf("formatter %v is already registered", name)
+	}
+	formatters[name] = factory
+	return nil
+}
+
+// builtinFormats are the names of the formats created by NewEventFormatter
+// without a registered FormatterFactory.
+var builtinFormats = []string{
+	"debug", "standard-verbose", "standard-quiet", "dots", "dots-v1",
+	"dots-v2", "testname", "short-verbose", "pkgname", "short",
+	"pkgname-and-test-fails", "short-with-failures",
+}
+
+func isBuiltinFormat(name string) bool {
+	return contains(builtinFormats, name)
 }
 
 type formatAdapter struct {
@@ -2,6 +2,7 @@ package testjson
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
 	"gotest.tools/v3/assert"
@@ -101,12 +102,12 @@ func TestFormats_DefaultGoTestJson(t *testing.T) {
 	testCases := []testCase{
 		{
 			name:        "testname",
-			format:      testNameFormat,
+			format:      testNameFormat(FormatOptions{}),
 			expectedOut: "format/testname.out",
 		},
 		{
 			name:        "dots-v1",
-			format:      dotsFormatV1,
+			format:      dotsFormatV1(FormatOptions{}),
 			expectedOut: "format/dots-v1.out",
 		},
 		{
@@ -168,7 +169,7 @@ func TestFormats_Coverage(t *testing.T) {
 	testCases := []testCase{
 		{
 			name:        "testname",
-			format:      testNameFormat,
+			format:      testNameFormat(FormatOptions{}),
 			expectedOut: "format/testname-coverage.out",
 		},
 		{
@@ -219,7 +220,7 @@ func TestFormats_Shuffle(t *testing.T) {
 	testCases := []testCase{
 		{
 			name:        "testname",
-			format:      testNameFormat,
+			format:      testNameFormat(FormatOptions{}),
 			expectedOut: "format/testname-shuffle.out",
 		},
 		{
@@ -245,3 +246,28 @@ func TestFormats_Shuffle(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterFormatter(t *testing.T) {
+	factory := func(out io.Writer, _ FormatOptions) EventFormatter {
+		return &formatAdapter{out, func(event TestEvent, _ *Execution) string {
+			return string(event.Action) + "\n"
+		}}
+	}
+	t.Cleanup(func() {
+		formattersLock.Lock()
+		delete(formatters, "test-actions")
+		formattersLock.Unlock()
+	})
+	assert.NilError(t, RegisterFormatter("test-actions", factory))
+
+	out := new(bytes.Buffer)
+	formatter := NewEventFormatter(out, "test-actions", FormatOptions{})
+	assert.Assert(t, formatter != nil)
+	assert.NilError(t, formatter.Format(TestEvent{Action: ActionPass}, nil))
+	assert.Equal(t, out.String(), "pass\n")
+
+	err := RegisterFormatter("test-actions", factory)
+	assert.Error(t, err, "formatter test-actions is already registered")
+	err = RegisterFormatter("dots", factory)
+	assert.Error(t, err, "formatter dots is already registered")
+}
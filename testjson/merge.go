@@ -0,0 +1,203 @@
+package testjson
+
+import "time"
+
+// MergeOptions are options used by Execution.MergeWithOptions.
+type MergeOptions struct {
+	// ClockOffset is added to the time of every event in the other Execution.
+	// Use it when the other Execution was recorded on a machine with a clock
+	// that differs from the clock of the machine that recorded the Execution,
+	// so that the start and end times of packages and tests can be compared.
+	ClockOffset time.Duration
+}
+
+// Merge the packages, test cases, output, errors, and diagnostics from other
+// into e, so that e contains the results of both executions. It is the same
+// as MergeWithOptions with the zero value of MergeOptions.
+func (e *Execution) Merge(other *Execution) {
+	e.MergeWithOptions(other, MergeOptions{})
+}
+
+// MergeWithOptions merges the packages, test cases, output, and errors from
+// other into e. other is not modified, and may be discarded after the merge.
+//
+// A test that ran in both executions is kept as a test that ran more than
+// once. The RunID of the test cases from other are moved after the runs of e,
+// so that the test cases from other are reported like the test cases from a
+// re-run of the test, and a test that failed in e and passed in other is
+// reported as flaky. Tests that only ran in other keep their RunID.
+//
+// When a package is in both executions the package failed if either failed,
+// the elapsed time is the longest of the two, and the coverage and shuffle
+// seed from e are used when they are set. The merged execution started at the
+// earliest start time of the two, after the ClockOffset is applied to other.
+func (e *Execution) MergeWithOptions(other *Execution, opts MergeOptions) {
+	if other == nil {
+		return
+	}
+	if e.packages == nil {
+		e.packages = make(map[string]*Package)
+	}
+	runOffset := e.lastRunID + 1
+	lastRunID := e.lastRunID
+	if other.lastRunID > lastRunID {
+		lastRunID = other.lastRunID
+	}
+
+	e.packagesLock.Lock()
+	for _, name := range sortedKeys(other.packages) {
+		pkg, ok := e.packages[name]
+		if !ok {
+			pkg = newPackage()
+			e.packages[name] = pkg
+		}
+		if maxRunID := pkg.merge(other.packages[name], opts.ClockOffset, runOffset); maxRunID > lastRunID {
+			lastRunID = maxRunID
+		}
+	}
+	e.packagesLock.Unlock()
+
+	otherStarted := shiftTime(other.started, opts.ClockOffset)
+	if e.started.IsZero() || (!otherStarted.IsZero() && otherStarted.Before(e.started)) {
+		e.started = otherStarted
+	}
+	e.lastRunID = lastRunID
+	e.done = e.done && other.done
+
+	other.errorsLock.RLock()
+	errors := append([]string{}, other.errors...)
+	buildErrors := append([]BuildError{}, other.buildErrors...)
+	diagnostics := append([]string{}, other.diagnostics...)
+	invalidLines := append([]InvalidLine{}, other.invalidLines...)
+	other.errorsLock.RUnlock()
+
+	e.errorsLock.Lock()
+	e.errors = append(e.errors, errors...)
+	e.buildErrors = append(e.buildErrors, buildErrors...)
+	e.diagnostics = append(e.diagnostics, diagnostics...)
+	e.invalidLines = append(e.invalidLines, invalidLines...)
+	e.errorsLock.Unlock()
+}
+
+// merge the test cases and output of other into p. The IDs of the test cases
+// from other are moved after the IDs in p, and the test cases of tests that are
+// also in p have their RunID increased by runOffset. merge returns the largest
+// RunID of the test cases from other.
+func (p *Package) merge(other *Package, offset time.Duration, runOffset int) int {
+	if p.output == nil {
+		p.output = make(map[int][]string)
+	}
+	if p.running == nil {
+		p.running = make(map[string]TestCase)
+	}
+	if p.subTests == nil {
+		p.subTests = make(map[int][]int)
+	}
+	names := p.testNames()
+	isNew := p.Total == 0 && p.action == ""
+	idOffset := p.Total
+	var maxRunID int
+	convert := func(tc TestCase) TestCase {
+		tc.ID += idOffset
+		tc.Time = shiftTime(tc.Time, offset)
+		tc.end = shiftTime(tc.end, offset)
+		if names[tc.Test.Name()] {
+			tc.RunID += runOffset
+		}
+		if tc.RunID > maxRunID {
+			maxRunID = tc.RunID
+		}
+		return tc
+	}
+
+	for _, tc := range other.Failed {
+		p.Failed = append(p.Failed, convert(tc))
+	}
+	for _, tc := range other.Skipped {
+		p.Skipped = append(p.Skipped, convert(tc))
+	}
+	for _, tc := range other.Passed {
+		p.Passed = append(p.Passed, convert(tc))
+	}
+	for name, tc := range other.running {
+		if _, ok := p.running[name]; !ok {
+			p.running[name] = convert(tc)
+		}
+	}
+	for id, lines := range other.output {
+		if id != 0 {
+			id += idOffset
+		}
+		p.output[id] = append(p.output[id], lines...)
+	}
+	for rootID, subIDs := range other.subTests {
+		for _, subID := range subIDs {
+			p.subTests[rootID+idOffset] = append(p.subTests[rootID+idOffset], subID+idOffset)
+		}
+	}
+	p.unattributed = append(p.unattributed, other.unattributed...)
+	p.Total += other.Total
+	p.Examples += other.Examples
+
+	switch {
+	case p.action == ActionFail || other.action == ActionFail:
+		p.action = ActionFail
+	case p.action == "":
+		p.action = other.action
+	}
+	if other.elapsed > p.elapsed {
+		p.elapsed = other.elapsed
+	}
+	if p.coverage == "" {
+		p.coverage = other.coverage
+	}
+	if p.shuffleSeed == "" {
+		p.shuffleSeed = other.shuffleSeed
+	}
+	if p.testTimeoutPanicInTest == "" {
+		p.testTimeoutPanicInTest = other.testTimeoutPanicInTest
+	}
+	p.cached = other.cached && (isNew || p.cached)
+	p.panicked = p.panicked || other.panicked
+	p.timedOut = p.timedOut || other.timedOut
+	p.buildFailed = p.buildFailed || other.buildFailed
+
+	p.started = earliestTime(p.started, shiftTime(other.started, offset))
+	p.firstTestRun = earliestTime(p.firstTestRun, shiftTime(other.firstTestRun, offset))
+	p.lastTestEnd = latestTime(p.lastTestEnd, shiftTime(other.lastTestEnd, offset))
+	p.ended = latestTime(p.ended, shiftTime(other.ended, offset))
+	return maxRunID
+}
+
+// testNames returns the names of all the tests in the package.
+func (p *Package) testNames() map[string]bool {
+	names := make(map[string]bool)
+	for _, tc := range p.TestCases() {
+		names[tc.Test.Name()] = true
+	}
+	for name := range p.running {
+		names[name] = true
+	}
+	return names
+}
+
+func shiftTime(t time.Time, offset time.Duration) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.Add(offset)
+}
+
+func earliestTime(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+func latestTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
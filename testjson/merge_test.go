@@ -0,0 +1,93 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func scanLines(t *testing.T, stderr string, lines ...string) *Execution {
+	t.Helper()
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(strings.Join(lines, "\n") + "\n"),
+		Stderr: strings.NewReader(stderr),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestExecution_Merge(t *testing.T) {
+	first := scanLines(t, "",
+		`{"Time":"2024-01-01T10:00:00Z","Action":"start","Package":"example.com/a"}`,
+		`{"Time":"2024-01-01T10:00:01Z","Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Time":"2024-01-01T10:00:01Z","Action":"output","Package":"example.com/a","Test":"TestOne","Output":"first failure\n"}`,
+		`{"Time":"2024-01-01T10:00:02Z","Action":"fail","Package":"example.com/a","Test":"TestOne","Elapsed":1}`,
+		`{"Time":"2024-01-01T10:00:03Z","Action":"fail","Package":"example.com/a","Elapsed":3}`,
+	)
+	second := scanLines(t, "b.go:1:1: syntax error\n",
+		`{"Time":"2024-01-01T09:59:00Z","Action":"start","Package":"example.com/a"}`,
+		`{"Time":"2024-01-01T09:59:01Z","Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Time":"2024-01-01T09:59:02Z","Action":"pass","Package":"example.com/a","Test":"TestOne","Elapsed":1}`,
+		`{"Time":"2024-01-01T09:59:02Z","Action":"run","Package":"example.com/a","Test":"TestTwo"}`,
+		`{"Time":"2024-01-01T09:59:03Z","Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"second failure\n"}`,
+		`{"Time":"2024-01-01T09:59:04Z","Action":"fail","Package":"example.com/a","Test":"TestTwo","Elapsed":2}`,
+		`{"Time":"2024-01-01T09:59:05Z","Action":"fail","Package":"example.com/a","Elapsed":5}`,
+		`{"Time":"2024-01-01T09:59:05Z","Action":"run","Package":"example.com/b","Test":"TestThree"}`,
+		`{"Time":"2024-01-01T09:59:06Z","Action":"pass","Package":"example.com/b","Test":"TestThree","Elapsed":1}`,
+		`{"Time":"2024-01-01T09:59:06Z","Action":"pass","Package":"example.com/b","Elapsed":1}`,
+	)
+
+	first.MergeWithOptions(second, MergeOptions{ClockOffset: time.Minute})
+
+	assert.Equal(t, first.Total(), 4)
+	assert.DeepEqual(t, first.Packages(), []string{"example.com/a", "example.com/b"})
+	assert.DeepEqual(t, first.Errors(), []string{"b.go:1:1: syntax error"})
+	assert.Equal(t, first.lastRunID, 1)
+
+	pkg := first.Package("example.com/a")
+	assert.Equal(t, pkg.Result(), ActionFail)
+	assert.Equal(t, pkg.Elapsed(), 5*time.Second)
+	assert.Equal(t, pkg.started, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	assert.Equal(t, pkg.ended, time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC))
+
+	assert.Equal(t, len(pkg.Failed), 2)
+	assert.Equal(t, pkg.Failed[0].Test.Name(), "TestOne")
+	assert.Equal(t, pkg.Failed[0].RunID, 0)
+	assert.Equal(t, pkg.Failed[1].Test.Name(), "TestTwo")
+	assert.Equal(t, pkg.Failed[1].RunID, 0, "test only ran in other")
+	assert.Equal(t, pkg.Failed[1].Time, time.Date(2024, 1, 1, 10, 0, 2, 0, time.UTC))
+	assert.Equal(t, pkg.Output(pkg.Failed[0].ID), "first failure\n")
+	assert.Equal(t, pkg.Output(pkg.Failed[1].ID), "second failure\n")
+
+	assert.Equal(t, len(pkg.Passed), 1)
+	assert.Equal(t, pkg.Passed[0].Test.Name(), "TestOne")
+	assert.Equal(t, pkg.Passed[0].RunID, 1, "duplicate test moved to a later run")
+
+	ids := map[int]bool{}
+	for _, tc := range pkg.TestCases() {
+		assert.Assert(t, !ids[tc.ID], "duplicate ID %d", tc.ID)
+		ids[tc.ID] = true
+	}
+	assert.Equal(t, first.Package("example.com/b").Result(), ActionPass)
+}
+
+func TestExecution_Merge_Empty(t *testing.T) {
+	exec := newExecution()
+	other := scanLines(t, "",
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"output","Package":"example.com/a","Output":"coverage: 50.0% of statements\n"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"pass","Package":"example.com/a"}`,
+	)
+	exec.Merge(other)
+	exec.Merge(nil)
+
+	assert.Equal(t, exec.Total(), 1)
+	pkg := exec.Package("example.com/a")
+	assert.Equal(t, pkg.Result(), ActionPass)
+	assert.Equal(t, pkg.coverage, "coverage: 50.0% of statements")
+	assert.Equal(t, pkg.Passed[0].RunID, 0)
+	assert.Equal(t, other.Total(), 1, "other is not modified")
+}
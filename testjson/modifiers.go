@@ -0,0 +1,264 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// formatModifier is a change to a format that may be added to the name of any
+// format that supports it, like pkgname+hivis+timestamps.
+type formatModifier struct {
+	apply func(opts *FormatOptions)
+	// formats are the built-in formats that support the modifier. When formats
+	// is empty every format supports the modifier.
+	formats []string
+}
+
+var pkgnameFormats = []string{"pkgname", "short", "pkgname-and-test-fails", "short-with-failures"}
+
+// nameFormats are the built-in formats that print a line with the name of
+// each package, or each test and package.
+var nameFormats = append([]string{"testname", "short-verbose"}, pkgnameFormats...)
+
+// lineFormats are the built-in formats that print one or more lines for each
+// event that is printed.
+var lineFormats = []string{
+	"pkgname", "short", "pkgname-and-test-fails", "short-with-failures",
+	"testname", "short-verbose", "standard-quiet", "standard-verbose", "debug",
+}
+
+var formatModifiers = map[string]formatModifier{
+	"hivis": {
+		apply:   func(opts *FormatOptions) { opts.UseHiVisibilityIcons = true },
+		formats: pkgnameFormats,
+	},
+	"hide-empty": {
+		apply:   func(opts *FormatOptions) { opts.HideEmptyPackages = true },
+		formats: append([]string{"dots-v2"}, pkgnameFormats...),
+	},
+	"timestamps": {
+		apply:   func(opts *FormatOptions) { opts.Timestamps = true },
+		formats: lineFormats,
+	},
+	"coverage": {
+		apply:   func(opts *FormatOptions) { opts.CoverageColumn = true },
+		formats: nameFormats,
+	},
+	"wall-clock": {
+		apply:   func(opts *FormatOptions) { opts.WallClock = true },
+		formats: lineFormats,
+	},
+	"align": {
+		apply:   func(opts *FormatOptions) { opts.Align = true },
+		formats: nameFormats,
+	},
+	"tree": {
+		apply:   func(opts *FormatOptions) { opts.Tree = true },
+		formats: []string{"testname", "short-verbose"},
+	},
+}
+
+// FormatModifiers returns the names of the modifiers that may be added to the
+// name of a format, sorted by name.
+func FormatModifiers() []string {
+	names := make([]string, 0, len(formatModifiers))
+	for name := range formatModifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SupportedFormatModifiers returns the names of the modifiers that may be
+// added to the name of format, sorted by name. Every modifier may be added to
+// a registered format.
+func SupportedFormatModifiers(format string) []string {
+	var names []string
+	for _, name := range FormatModifiers() {
+		modifier := formatModifiers[name]
+		if !isBuiltinFormat(format) || len(modifier.formats) == 0 || contains(modifier.formats, format) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Formats returns the names of the built-in and registered formats, sorted by
+// name.
+func Formats() []string {
+	names := append([]string(nil), builtinFormats...)
+	formattersLock.RLock()
+	for name := range formatters {
+		names = append(names, name)
+	}
+	formattersLock.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// ParseFormat returns the name of the format, and the options changed by the
+// modifiers, from a format with modifiers separated by +, like
+// testname+tree+timestamps. An error is returned if the format is not a
+// built-in or registered format, or a modifier is not supported by the format.
+func ParseFormat(value string, opts FormatOptions) (string, FormatOptions, error) {
+	parts := strings.Split(value, "+")
+	name := parts[0]
+	if !isBuiltinFormat(name) && !isRegisteredFormat(name) {
+		return name, opts, fmt.Errorf("unknown format %s", name)
+	}
+	for _, mod := range parts[1:] {
+		modifier, ok := formatModifiers[mod]
+		switch {
+		case !ok:
+			return name, opts, fmt.Errorf("unknown format modifier %q in %s, must be one of: %s",
+				mod, value, strings.Join(FormatModifiers(), ", "))
+		case isBuiltinFormat(name) && len(modifier.formats) > 0 && !contains(modifier.formats, name):
+			return name, opts, fmt.Errorf("format modifier %q can not be used with the %s format", mod, name)
+		}
+		modifier.apply(&opts)
+	}
+	return name, opts, nil
+}
+
+func isRegisteredFormat(name string) bool {
+	formattersLock.RLock()
+	defer formattersLock.RUnlock()
+	_, ok := formatters[name]
+	return ok
+}
+
+func contains(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+// timestampFormatter prints the time of the event at the start of each line
+// printed by the formatter.
+type timestampFormatter struct {
+	formatter EventFormatter
+	writer    *timestampWriter
+}
+
+func (f *timestampFormatter) Format(event TestEvent, exec *Execution) error {
+	f.writer.time = event.Time
+	return f.formatter.Format(event, exec)
+}
+
+// timestampWriter writes the time at the start of each line.
+type timestampWriter struct {
+	out io.Writer
+	// time is the time of the event that is being formatted.
+	time time.Time
+	// partial is true when the last write did not end with a newline.
+	partial bool
+}
+
+const timestampLayout = "15:04:05.000"
+
+func (w *timestampWriter) Write(p []byte) (int, error) {
+	var buf strings.Builder
+	for _, line := range strings.SplitAfter(string(p), "\n") {
+		if line == "" {
+			continue
+		}
+		if !w.partial {
+			buf.WriteString(w.timestamp() + " ")
+		}
+		buf.WriteString(line)
+		w.partial = !strings.HasSuffix(line, "\n")
+	}
+	if _, err := io.WriteString(w.out, buf.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *timestampWriter) timestamp() string {
+	if w.time.IsZero() {
+		return strings.Repeat(" ", len(timestampLayout))
+	}
+	return w.time.Format(timestampLayout)
+}
+
+var coveragePercent = regexp.MustCompile(`coverage: ([0-9.]+%)`)
+
+// coverageColumn returns the coverage of the package as a column with a fixed
+// width, so that the package names that follow it are aligned.
+func coverageColumn(pkg *Package) string {
+	var percent string
+	if m := coveragePercent.FindStringSubmatch(pkg.coverage); m != nil {
+		percent = m[1]
+	}
+	return fmt.Sprintf("%6s", percent)
+}
+
+// testTree prints the subtests of a test as a tree below the test, when the
+// test ends. go test prints a subtest when it ends, which is before the test
+// that started it, so the lines of the subtests are kept until the test ends.
+type testTree struct {
+	// started is the order that the tests started, used to print the tests
+	// in the tree in the same order.
+	started map[string]int
+	// pending are the lines of the subtests of each top-level test.
+	pending map[string][]treeLine
+}
+
+type treeLine struct {
+	order int
+	text  string
+}
+
+func newTestTree() *testTree {
+	return &testTree{started: make(map[string]int), pending: make(map[string][]treeLine)}
+}
+
+// format returns the lines printed for the event, with the text of the line
+// for the test.
+func (t *testTree) format(event TestEvent, text string) string {
+	key := event.Package + "." + event.Test
+	switch {
+	case event.Action == ActionRun:
+		t.started[key] = len(t.started)
+		return ""
+	case TestName(event.Test).IsSubTest():
+		if text != "" {
+			root, _ := TestName(event.Test).Split()
+			rootKey := event.Package + "." + root
+			t.pending[rootKey] = append(t.pending[rootKey], treeLine{order: t.started[key], text: text})
+		}
+		return ""
+	case !event.Action.IsTerminal():
+		return text
+	}
+
+	lines := t.pending[key]
+	delete(t.pending, key)
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].order < lines[j].order
+	})
+	var buf strings.Builder
+	buf.WriteString(text)
+	for _, line := range lines {
+		buf.WriteString(line.text)
+	}
+	return buf.String()
+}
+
+// treeTestLine returns the line of a test in a tree. A subtest is printed
+// with the last part of its name, indented by the depth of the subtest.
+func treeTestLine(opts FormatOptions, event TestEvent) string {
+	name := TestName(event.Test)
+	if !name.IsSubTest() {
+		return testLine(opts, event)
+	}
+	parts := strings.Split(name.Name(), "/")
+	return testLineWithName(opts, event, strings.Repeat("  ", len(parts)-1), parts[len(parts)-1])
+}
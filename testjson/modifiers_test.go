@@ -0,0 +1,175 @@
+package testjson
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestParseFormat(t *testing.T) {
+	type testCase struct {
+		name         string
+		value        string
+		expectedName string
+		expectedOpts FormatOptions
+		expectedErr  string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		name, opts, err := ParseFormat(tc.value, FormatOptions{})
+		if tc.expectedErr != "" {
+			assert.Error(t, err, tc.expectedErr)
+			return
+		}
+		assert.NilError(t, err)
+		assert.Equal(t, name, tc.expectedName)
+		assert.DeepEqual(t, opts, tc.expectedOpts)
+	}
+
+	testCases := []testCase{
+		{
+			name:         "no modifiers",
+			value:        "testname",
+			expectedName: "testname",
+		},
+		{
+			name:         "many modifiers",
+			value:        "testname+tree+timestamps+coverage",
+			expectedName: "testname",
+			expectedOpts: FormatOptions{Tree: true, Timestamps: true, CoverageColumn: true},
+		},
+		{
+			name:         "hivis",
+			value:        "pkgname+hivis+hide-empty",
+			expectedName: "pkgname",
+			expectedOpts: FormatOptions{UseHiVisibilityIcons: true, HideEmptyPackages: true},
+		},
+		{
+			name:        "unknown format",
+			value:       "not-a-format+tree",
+			expectedErr: "unknown format not-a-format",
+		},
+		{
+			name:  "unknown modifier",
+			value: "pkgname+sparkles",
+			expectedErr: `unknown format modifier "sparkles" in pkgname+sparkles, ` +
+				`must be one of: align, coverage, hide-empty, hivis, timestamps, tree, wall-clock`,
+		},
+		{
+			name:        "modifier not supported by format",
+			value:       "dots+timestamps",
+			expectedErr: `format modifier "timestamps" can not be used with the dots format`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestSupportedFormatModifiers(t *testing.T) {
+	assert.DeepEqual(t, SupportedFormatModifiers("dots"), []string(nil))
+	assert.DeepEqual(t, SupportedFormatModifiers("dots-v2"), []string{"hide-empty"})
+	assert.DeepEqual(t, SupportedFormatModifiers("standard-quiet"), []string{"timestamps", "wall-clock"})
+	assert.DeepEqual(t, SupportedFormatModifiers("registered"), FormatModifiers())
+}
+
+func TestFormats(t *testing.T) {
+	assert.NilError(t, RegisterFormatter("test-formats", func(io.Writer, FormatOptions) EventFormatter {
+		return nil
+	}))
+	t.Cleanup(func() {
+		formattersLock.Lock()
+		delete(formatters, "test-formats")
+		formattersLock.Unlock()
+	})
+
+	formats := Formats()
+	assert.Assert(t, contains(formats, "short-with-failures"))
+	assert.Assert(t, contains(formats, "test-formats"))
+	assert.Equal(t, len(formats), len(builtinFormats)+1)
+}
+
+func TestNewEventFormatter_Modifiers(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+
+	type testCase struct {
+		format      string
+		input       string
+		expectedOut string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		patchPkgPathPrefix(t, "gotest.tools")
+		out := new(bytes.Buffer)
+		formatter := NewEventFormatter(out, tc.format, FormatOptions{NoTerminal: true})
+		assert.Assert(t, formatter != nil)
+		shim := newFakeHandler(formatter, tc.input)
+		_, err := ScanTestOutput(shim.Config(t))
+		assert.NilError(t, err)
+
+		golden.Assert(t, out.String(), tc.expectedOut)
+	}
+
+	testCases := []testCase{
+		{
+			format:      "pkgname+timestamps",
+			input:       "input/go-test-json",
+			expectedOut: "format/modifiers/pkgname-timestamps.out",
+		},
+		{
+			format:      "testname+tree",
+			input:       "input/go-test-json",
+			expectedOut: "format/modifiers/testname-tree.out",
+		},
+		{
+			format:      "pkgname+coverage",
+			input:       "input/go-test-json-with-cover",
+			expectedOut: "format/modifiers/pkgname-coverage.out",
+		},
+		{
+			format:      "testname+wall-clock",
+			input:       "input/go-test-json",
+			expectedOut: "format/modifiers/testname-wall-clock.out",
+		},
+		{
+			format:      "pkgname+hivis+wall-clock+timestamps",
+			input:       "input/go-test-json",
+			expectedOut: "format/modifiers/pkgname-hivis-wall-clock-timestamps.out",
+		},
+		{
+			format:      "testname+align+tree",
+			input:       "input/go-test-json",
+			expectedOut: "format/modifiers/testname-align-tree.out",
+		},
+		{
+			format:      "pkgname+align+wall-clock",
+			input:       "input/go-test-json-with-cover",
+			expectedOut: "format/modifiers/pkgname-align-wall-clock.out",
+		},
+		{
+			format:      "testname+coverage+timestamps",
+			input:       "input/go-test-json-with-cover",
+			expectedOut: "format/modifiers/testname-coverage-timestamps.out",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestFormatWallClock(t *testing.T) {
+	assert.Equal(t, formatWallClock(0), "0:00.0")
+	assert.Equal(t, formatWallClock(1234*time.Millisecond), "0:01.2")
+	assert.Equal(t, formatWallClock(12*time.Minute+3*time.Second), "12:03.0")
+	assert.Equal(t, formatWallClock(time.Hour+2*time.Minute+59960*time.Millisecond), "1:03:00.0")
+}
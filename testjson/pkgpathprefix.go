@@ -24,6 +24,15 @@ func RelativePackagePath(pkgpath string) string {
 	return strings.TrimPrefix(pkgpath, pkgPathPrefix+"/")
 }
 
+// formatPackageName returns the name of the package printed by the formatters
+// and the summary, formatted by fn, or the RelativePackagePath when fn is nil.
+func formatPackageName(fn func(pkgpath string) string, pkgpath string) string {
+	if fn == nil {
+		return RelativePackagePath(pkgpath)
+	}
+	return fn(pkgpath)
+}
+
 func getPkgPathPrefix() string {
 	cwd, _ := os.Getwd()
 	if isGoModuleEnabled() {
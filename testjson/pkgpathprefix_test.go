@@ -17,6 +17,26 @@ func TestRelativePackagePath(t *testing.T) {
 	assert.Equal(t, relPath, ".")
 }
 
+func TestFormatPackageName(t *testing.T) {
+	prefix := "gotest.tools/gotestsum/testjson"
+	patchPkgPathPrefix(t, prefix)
+	assert.Equal(t, formatPackageName(nil, prefix+"/extra/relpath"), "extra/relpath")
+
+	custom := func(pkgpath string) string {
+		return "custom:" + pkgpath
+	}
+	assert.Equal(t, formatPackageName(custom, prefix+"/extra"), "custom:"+prefix+"/extra")
+}
+
+func TestPackageLine_PackageName(t *testing.T) {
+	opts := FormatOptions{PackageName: func(pkgpath string) string {
+		return "custom:" + pkgpath
+	}}
+	event := TestEvent{Action: ActionPass, Package: "example.com/pkg"}
+	line := packageLine(opts, "ok  ", event, newPackage())
+	assert.Equal(t, line, "ok  custom:example.com/pkg\n")
+}
+
 func TestGetPkgPathPrefix(t *testing.T) {
 	t.Run("with go path", func(t *testing.T) {
 		skip.If(t, isGoModuleEnabled())
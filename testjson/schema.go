@@ -0,0 +1,74 @@
+package testjson
+
+import "sync"
+
+// EventAdapter changes a TestEvent read by ScanTestOutput before the event is
+// added to the Execution, and sent to the EventHandler. Adapters are used to
+// read events from versions of go that emit events this package does not know
+// about, or that changed the meaning of an existing event. An adapter returns
+// false to ignore the event, which is not added to the Execution, but is still
+// sent to the EventHandler.
+type EventAdapter func(event TestEvent) (TestEvent, bool)
+
+// schema is the registry of actions and adapters used to read events from
+// versions of test2json that are newer, or older, than this package.
+var schema = struct {
+	lock     sync.RWMutex
+	actions  map[Action]Action
+	adapters []EventAdapter
+}{actions: make(map[Action]Action)}
+
+// RegisterAction registers an Action that is not known to this package, and
+// the known Action that events with the new Action are handled as. Use an
+// empty handleAs to ignore the events with the new Action. Registered actions
+// are not reported as invalid by ScanConfig.StrictJSON.
+//
+// Events with an Action that is neither known nor registered are not added to
+// the Execution, but are still sent to the EventHandler, so that a new version
+// of go does not break the scan.
+func RegisterAction(action Action, handleAs Action) {
+	schema.lock.Lock()
+	defer schema.lock.Unlock()
+	schema.actions[action] = handleAs
+}
+
+// RegisterEventAdapter adds an EventAdapter that is called for every event
+// read by ScanTestOutput. Adapters are called in the order they were
+// registered, before the Action of the event is mapped by RegisterAction.
+func RegisterEventAdapter(adapter EventAdapter) {
+	schema.lock.Lock()
+	defer schema.lock.Unlock()
+	schema.adapters = append(schema.adapters, adapter)
+}
+
+func registeredAction(action Action) (Action, bool) {
+	schema.lock.RLock()
+	defer schema.lock.RUnlock()
+	handleAs, ok := schema.actions[action]
+	return handleAs, ok
+}
+
+// adaptEvent applies the registered adapters and actions to the event. The
+// second return value is false if the event should not be added to the
+// Execution.
+func adaptEvent(event TestEvent) (TestEvent, bool) {
+	schema.lock.RLock()
+	adapters := schema.adapters
+	schema.lock.RUnlock()
+
+	for _, adapter := range adapters {
+		var ok bool
+		if event, ok = adapter(event); !ok {
+			return event, false
+		}
+	}
+	if event.Action.builtin() {
+		return event, true
+	}
+	handleAs, ok := registeredAction(event.Action)
+	if !ok || handleAs == "" {
+		return event, false
+	}
+	event.Action = handleAs
+	return event, event.Action.builtin()
+}
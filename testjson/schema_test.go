@@ -0,0 +1,93 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func patchSchema(t *testing.T) {
+	t.Helper()
+	schema.lock.Lock()
+	actions, adapters := schema.actions, schema.adapters
+	schema.actions = make(map[Action]Action)
+	schema.adapters = nil
+	schema.lock.Unlock()
+	t.Cleanup(func() {
+		schema.lock.Lock()
+		schema.actions, schema.adapters = actions, adapters
+		schema.lock.Unlock()
+	})
+}
+
+func TestScanTestOutput_UnknownAction(t *testing.T) {
+	patchSchema(t)
+	stdout := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne","NewField":true}`,
+		`{"Action":"attr","Package":"example.com/a","Test":"TestOne","Key":"owner"}`,
+		`{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"failed\n"}`,
+		`{"Action":"fail","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"attr","Package":"example.com/a"}`,
+		`{"Action":"fail","Package":"example.com/a"}`,
+		"",
+	}, "\n")
+	handler := &captureHandler{}
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(stdout), Handler: handler})
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(handler.events), 6, "unknown actions are sent to the handler")
+	assert.Equal(t, exec.Total(), 1)
+	failed := exec.Failed()
+	assert.Equal(t, len(failed), 1)
+	assert.Equal(t, exec.Package("example.com/a").Output(failed[0].ID), "failed\n")
+}
+
+func TestRegisterAction(t *testing.T) {
+	patchSchema(t)
+	RegisterAction("finish", ActionPass)
+	RegisterAction("attr", "")
+
+	stdout := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"attr","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"finish","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"finish","Package":"example.com/a"}`,
+		"",
+	}, "\n")
+	handler := &captureHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:     strings.NewReader(stdout),
+		Handler:    handler,
+		StrictJSON: true,
+	})
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(exec.InvalidLines()), 0)
+	assert.Equal(t, len(exec.Package("example.com/a").Passed), 1)
+	assert.Equal(t, exec.Package("example.com/a").Result(), ActionPass)
+	assert.Equal(t, handler.events[2].Action, ActionPass)
+}
+
+func TestRegisterEventAdapter(t *testing.T) {
+	patchSchema(t)
+	RegisterEventAdapter(func(event TestEvent) (TestEvent, bool) {
+		return event, event.Test != "TestIgnored"
+	})
+	RegisterEventAdapter(func(event TestEvent) (TestEvent, bool) {
+		event.Package = strings.TrimSuffix(event.Package, "_test")
+		return event, true
+	})
+
+	stdout := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a_test","Test":"TestOne"}`,
+		`{"Action":"pass","Package":"example.com/a_test","Test":"TestOne"}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestIgnored"}`,
+		`{"Action":"pass","Package":"example.com/a"}`,
+		"",
+	}, "\n")
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(stdout)})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, exec.Packages(), []string{"example.com/a"})
+	assert.Equal(t, exec.Total(), 1)
+}
@@ -3,6 +3,7 @@ package testjson
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 	"unicode"
@@ -21,23 +22,29 @@ const (
 	SummarizeFailed
 	SummarizeErrors
 	SummarizeOutput
-	SummarizeAll = SummarizeSkipped | SummarizeFailed | SummarizeErrors | SummarizeOutput
+	// SummarizeRequirements is the list of requirements in the names of
+	// tests. It is printed by the gotestsum command, not by PrintSummary.
+	SummarizeRequirements
+	SummarizeAll = SummarizeSkipped | SummarizeFailed | SummarizeErrors | SummarizeOutput |
+		SummarizeRequirements
 )
 
 var summaryValues = map[Summary]string{
-	SummarizeSkipped: "skipped",
-	SummarizeFailed:  "failed",
-	SummarizeErrors:  "errors",
-	SummarizeOutput:  "output",
+	SummarizeSkipped:      "skipped",
+	SummarizeFailed:       "failed",
+	SummarizeErrors:       "errors",
+	SummarizeOutput:       "output",
+	SummarizeRequirements: "requirements",
 }
 
 var summaryFromValue = map[string]Summary{
-	"none":    SummarizeNone,
-	"skipped": SummarizeSkipped,
-	"failed":  SummarizeFailed,
-	"errors":  SummarizeErrors,
-	"output":  SummarizeOutput,
-	"all":     SummarizeAll,
+	"none":         SummarizeNone,
+	"skipped":      SummarizeSkipped,
+	"failed":       SummarizeFailed,
+	"errors":       SummarizeErrors,
+	"output":       SummarizeOutput,
+	"requirements": SummarizeRequirements,
+	"all":          SummarizeAll,
 }
 
 func (s Summary) String() string {
@@ -68,22 +75,69 @@ func NewSummary(value string) (Summary, bool) {
 // PrintSummary of a test Execution. Prints a section for each summary type
 // followed by a DONE line to out.
 func PrintSummary(out io.Writer, execution *Execution, opts Summary) {
+	PrintSummaryWithOptions(out, execution, opts, SummaryOptions{})
+}
+
+// SummaryOptions are additional options used to print the summary.
+type SummaryOptions struct {
+	// ReproCommand returns a command that runs the failed test again. The
+	// command is printed after the output of each failed test. When
+	// ReproCommand is nil, or returns an empty string, no command is printed.
+	ReproCommand func(tc TestCase) string
+	// Attachments returns the paths of files attached to a test case. The
+	// paths are printed after the output of each failed test. It may be nil.
+	Attachments func(tc TestCase) []string
+	// SlowSetupThreshold is the time spent in TestMain before the first test,
+	// or after the last test, that must be exceeded for the time to be printed
+	// in the summary as the setup or teardown of the package. Zero disables
+	// the section.
+	SlowSetupThreshold time.Duration
+	// Project returns the name of the project that contains the package, or
+	// an empty string if the package is not part of a project. When Project
+	// is set, the summary includes the test counts of each project.
+	Project func(pkg string) string
+	// FormatLine returns the text printed for a line of the output of a failed
+	// test. It may return an empty string to omit the line. When FormatLine is
+	// nil the lines are printed unchanged.
+	FormatLine func(line string) string
+	// PackageName formats the package path printed in the summary. Defaults
+	// to RelativePackagePath.
+	PackageName func(pkgpath string) string
+}
+
+// PrintSummaryWithOptions is the same as PrintSummary with additional options.
+func PrintSummaryWithOptions(out io.Writer, execution *Execution, opts Summary, summaryOpts SummaryOptions) {
 	execSummary := newExecSummary(execution, opts)
 	if opts.Includes(SummarizeSkipped) {
-		writeTestCaseSummary(out, execSummary, formatSkipped())
+		conf := formatSkipped()
+		conf.packageName = summaryOpts.PackageName
+		writeTestCaseSummary(out, execSummary, conf)
 	}
 	if opts.Includes(SummarizeFailed) {
-		writeTestCaseSummary(out, execSummary, formatFailed())
+		conf := formatFailed()
+		conf.footer = summaryOpts.ReproCommand
+		conf.attachments = summaryOpts.Attachments
+		conf.formatLine = summaryOpts.FormatLine
+		conf.packageName = summaryOpts.PackageName
+		writeTestCaseSummary(out, execSummary, conf)
+	}
+
+	if summaryOpts.SlowSetupThreshold > 0 {
+		writeSetupSummary(out, execution, summaryOpts)
 	}
 
 	errors := execution.Errors()
 	if opts.Includes(SummarizeErrors) {
 		writeErrorSummary(out, errors)
 	}
+	if summaryOpts.Project != nil {
+		writeProjectSummary(out, execution, summaryOpts.Project)
+	}
 
-	fmt.Fprintf(out, "\n%s %d tests%s%s%s in %s\n",
+	fmt.Fprintf(out, "\n%s %d tests%s%s%s%s in %s\n",
 		formatExecStatus(execution),
-		execution.Total(),
+		execution.Total()-execution.Examples(),
+		formatTestCount(execution.Examples(), "example", "s"),
 		formatTestCount(len(execution.Skipped()), "skipped", ""),
 		formatTestCount(len(execution.Failed()), "failure", "s"),
 		formatTestCount(countErrors(errors), "error", "s"),
@@ -125,13 +179,30 @@ func writeErrorSummary(out io.Writer, errors []string) {
 		fmt.Fprintln(out, color.MagentaString("\n=== Errors"))
 	}
 	for _, err := range errors {
-		fmt.Fprintln(out, err)
+		fmt.Fprintln(out, formatError(err))
 	}
 }
 
+// formatError highlights the location and message of build errors in the same
+// style as the compiler. Other errors are returned unmodified.
+func formatError(line string) string {
+	buildErr, ok := ParseBuildError(line)
+	if !ok {
+		return line
+	}
+	return color.New(color.Bold).Sprint(buildErr.Location()) + ": " + color.RedString(buildErr.Message)
+}
+
 // countErrors in stderr lines. Build errors may include multiple lines where
 // subsequent lines are indented.
 // FIXME: Panics will include multiple lines, and are still overcounted.
+// ErrorCount returns the number of errors printed in the summary. Indented
+// lines of Errors, like the lines of a stack trace, are part of the error
+// before them, and are not counted.
+func (e *Execution) ErrorCount() int {
+	return countErrors(e.Errors())
+}
+
 func countErrors(errors []string) int {
 	var count int
 	for _, line := range errors {
@@ -164,6 +235,98 @@ func newExecSummary(execution *Execution, opts Summary) executionSummary {
 	return &noOutputSummary{Execution: execution}
 }
 
+type projectCounts struct {
+	packages int
+	total    int
+	skipped  int
+	failed   int
+}
+
+// otherProject is the name printed for the packages that are not part of a
+// project.
+const otherProject = "(other)"
+
+// writeProjectSummary prints the number of packages and tests in each project.
+// Packages that are not part of a project are counted in otherProject.
+func writeProjectSummary(out io.Writer, execution *Execution, project func(pkg string) string) {
+	counts := make(map[string]*projectCounts)
+	var names []string
+	for _, name := range execution.Packages() {
+		projectName := project(name)
+		if projectName == "" {
+			projectName = otherProject
+		}
+		c, ok := counts[projectName]
+		if !ok {
+			c = &projectCounts{}
+			counts[projectName] = c
+			names = append(names, projectName)
+		}
+		pkg := execution.Package(name)
+		c.packages++
+		c.total += pkg.Total - pkg.Examples
+		c.skipped += len(pkg.Skipped)
+		c.failed += len(pkg.Failed)
+		if pkg.TestMainFailed() {
+			c.failed++
+		}
+	}
+	if len(names) == 0 || (len(names) == 1 && names[0] == otherProject) {
+		return
+	}
+	sort.Slice(names, func(i, j int) bool {
+		switch {
+		case names[i] == otherProject:
+			return false
+		case names[j] == otherProject:
+			return true
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Fprintln(out, color.CyanString("\n=== Projects"))
+	for _, name := range names {
+		c := counts[name]
+		fmt.Fprintf(out, "=== %s: %s %d tests%s%s in %d package%s\n",
+			color.CyanString("PROJECT"), name, c.total,
+			formatTestCount(c.skipped, "skipped", ""),
+			formatTestCount(c.failed, "failure", "s"),
+			c.packages, pluralSuffix(c.packages))
+	}
+}
+
+func pluralSuffix(count int) string {
+	if count == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// writeSetupSummary prints the setup and teardown time of each package where
+// the time is greater than the SlowSetupThreshold.
+func writeSetupSummary(out io.Writer, execution *Execution, opts SummaryOptions) {
+	threshold := opts.SlowSetupThreshold
+	var lines []string
+	for _, name := range execution.Packages() {
+		pkg := execution.Package(name)
+		if d := pkg.Setup(); IsSlowSetup(d, threshold) {
+			lines = append(lines, fmt.Sprintf("=== %s: %s (%s)\n",
+				color.CyanString("SETUP"), formatPackageName(opts.PackageName, name), FormatDurationAsSeconds(d, 2)))
+		}
+		if d := pkg.Teardown(); IsSlowSetup(d, threshold) {
+			lines = append(lines, fmt.Sprintf("=== %s: %s (%s)\n",
+				color.CyanString("TEARDOWN"), formatPackageName(opts.PackageName, name), FormatDurationAsSeconds(d, 2)))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintln(out, color.CyanString("\n=== Slow setup and teardown"))
+	for _, line := range lines {
+		fmt.Fprint(out, line)
+	}
+}
+
 func writeTestCaseSummary(out io.Writer, execution executionSummary, conf testCaseFormatConfig) {
 	testCases := conf.getter(execution)
 	if len(testCases) == 0 {
@@ -173,7 +336,7 @@ func writeTestCaseSummary(out io.Writer, execution executionSummary, conf testCa
 	for idx, tc := range testCases {
 		fmt.Fprintf(out, "=== %s: %s %s%s (%s)\n",
 			conf.prefix,
-			RelativePackagePath(tc.Package),
+			formatPackageName(conf.packageName, tc.Package),
 			tc.Test,
 			formatRunID(tc.RunID),
 			FormatDurationAsSeconds(tc.Elapsed, 2))
@@ -181,8 +344,21 @@ func writeTestCaseSummary(out io.Writer, execution executionSummary, conf testCa
 			if isFramingLine(line) || conf.filter(tc.Test.Name(), line) {
 				continue
 			}
+			if conf.formatLine != nil {
+				line = conf.formatLine(line)
+			}
 			fmt.Fprint(out, line)
 		}
+		if conf.footer != nil {
+			if cmd := conf.footer(tc); cmd != "" {
+				fmt.Fprintf(out, "=== %s: %s\n", color.CyanString("REPRO"), cmd)
+			}
+		}
+		if conf.attachments != nil {
+			for _, path := range conf.attachments(tc) {
+				fmt.Fprintf(out, "=== %s: %s\n", color.CyanString("ATTACHMENT"), path)
+			}
+		}
 		if _, isNoOutput := execution.(*noOutputSummary); !isNoOutput && idx+1 != len(testCases) {
 			fmt.Fprintln(out)
 		}
@@ -194,6 +370,17 @@ type testCaseFormatConfig struct {
 	prefix string
 	filter func(testName string, line string) bool
 	getter func(executionSummary) []TestCase
+	// footer returns text printed after the output of the test case. It may
+	// be nil.
+	footer func(tc TestCase) string
+	// attachments returns the files attached to the test case, which are
+	// printed after the footer. It may be nil.
+	attachments func(tc TestCase) []string
+	// formatLine changes a line of the output before it is printed. It may
+	// be nil.
+	formatLine func(line string) string
+	// packageName formats the package path of the test case. It may be nil.
+	packageName func(pkgpath string) string
 }
 
 func formatFailed() testCaseFormatConfig {
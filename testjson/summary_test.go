@@ -9,6 +9,7 @@ import (
 
 	"gotest.tools/gotestsum/internal/text"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/golden"
 )
 
@@ -26,7 +27,7 @@ func TestSummary_String(t *testing.T) {
 		{
 			name:     "all",
 			summary:  SummarizeAll,
-			expected: "skipped,failed,errors,output",
+			expected: "skipped,failed,errors,output,requirements",
 		},
 		{
 			name:     "one value",
@@ -68,6 +69,104 @@ func TestPrintSummary_NoFailures(t *testing.T) {
 	assert.Equal(t, out.String(), expected)
 }
 
+func TestPrintSummary_SlowSetup(t *testing.T) {
+	patchTimeNow(t)
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	exec := &Execution{
+		started: start,
+		done:    true,
+		packages: map[string]*Package{
+			"example.com/slow": {
+				Total:        1,
+				started:      start,
+				firstTestRun: start.Add(3 * time.Second),
+				lastTestEnd:  start.Add(4 * time.Second),
+				ended:        start.Add(4500 * time.Millisecond),
+			},
+		},
+	}
+	timeNow = func() time.Time {
+		return start.Add(5 * time.Second)
+	}
+	out := new(bytes.Buffer)
+	PrintSummaryWithOptions(out, exec, SummarizeAll, SummaryOptions{SlowSetupThreshold: time.Second})
+
+	expected := `
+=== Slow setup and teardown
+=== SETUP: example.com/slow (3.00s)
+
+DONE 1 tests in 5.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummary_Projects(t *testing.T) {
+	patchTimeNow(t)
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	exec := &Execution{
+		started: start,
+		done:    true,
+		packages: map[string]*Package{
+			"example.com/api/users": {
+				Total:   4,
+				Skipped: []TestCase{{Test: "TestSkip"}},
+				Failed:  []TestCase{{Test: "TestFail"}},
+			},
+			"example.com/api/orders": {Total: 2},
+			"example.com/web":        {Total: 3},
+			"example.com/tools":      {Total: 1},
+		},
+	}
+	timeNow = func() time.Time {
+		return start.Add(2 * time.Second)
+	}
+	project := func(pkg string) string {
+		switch {
+		case strings.HasPrefix(pkg, "example.com/api/"):
+			return "api"
+		case pkg == "example.com/web":
+			return "web"
+		}
+		return ""
+	}
+	out := new(bytes.Buffer)
+	PrintSummaryWithOptions(out, exec, SummarizeNone, SummaryOptions{Project: project})
+
+	expected := `
+=== Projects
+=== PROJECT: api 6 tests, 1 skipped, 1 failure in 2 packages
+=== PROJECT: web 3 tests in 1 package
+=== PROJECT: (other) 1 tests in 1 package
+
+DONE 10 tests, 1 skipped, 1 failure in 2.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummary_WithExamples(t *testing.T) {
+	patchTimeNow(t)
+
+	out := new(bytes.Buffer)
+	start := time.Now()
+	exec := &Execution{
+		started: start,
+		done:    true,
+		packages: map[string]*Package{
+			"foo":   {Total: 12, Examples: 2},
+			"other": {Total: 1, Examples: 1},
+		},
+	}
+	timeNow = func() time.Time {
+		return start.Add(34123111 * time.Microsecond)
+	}
+	PrintSummary(out, exec, SummarizeAll)
+
+	expected := "\nDONE 10 tests, 3 examples in 34.123s\n"
+	assert.Equal(t, out.String(), expected)
+}
+
 func TestPrintSummary_WithFailures(t *testing.T) {
 	patchPkgPathPrefix(t, "example.com")
 	patchTimeNow(t)
@@ -336,3 +435,66 @@ func TestSummary_TestTimeoutPanicRace(t *testing.T) {
 	actual := text.ProcessLines(t, out, text.OpRemoveSummaryLineElapsedTime)
 	golden.Assert(t, actual, "summary/test-timeout-panic-race")
 }
+
+func TestPrintSummaryWithOptions_ReproCommand(t *testing.T) {
+	patchTimeNow(t)
+	exec, err := ScanTestOutput(scanConfigFromGolden("input/go-test-json.out")(t))
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	PrintSummaryWithOptions(buf, exec, SummarizeFailed, SummaryOptions{
+		ReproCommand: func(tc TestCase) string {
+			if tc.Test.IsSubTest() {
+				return ""
+			}
+			return "go test -run=^" + tc.Test.Name() + "$ " + tc.Package
+		},
+	})
+	out := buf.String()
+	assert.Assert(t, cmp.Contains(out,
+		"REPRO: go test -run=^TestFailed$ gotest.tools/gotestsum/testjson/internal/withfails\n"))
+	assert.Assert(t, !strings.Contains(out, "REPRO: go test -run=^TestNestedWithFailure/"))
+}
+
+func TestPrintSummaryWithOptions_Attachments(t *testing.T) {
+	patchTimeNow(t)
+	exec, err := ScanTestOutput(scanConfigFromGolden("input/go-test-json.out")(t))
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	PrintSummaryWithOptions(buf, exec, SummarizeFailed, SummaryOptions{
+		Attachments: func(tc TestCase) []string {
+			if tc.Test.Name() != "TestFailed" {
+				return nil
+			}
+			return []string{"/tmp/shot.png", "/tmp/log.txt"}
+		},
+	})
+	out := buf.String()
+	assert.Assert(t, cmp.Contains(out, "=== ATTACHMENT: /tmp/shot.png\n=== ATTACHMENT: /tmp/log.txt\n"))
+	assert.Equal(t, strings.Count(out, "ATTACHMENT:"), 2)
+}
+
+func TestPrintSummaryWithOptions_FormatLine(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"    one_test.go:10: debug line\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"    one_test.go:11: it failed\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestOne"}
+{"Action":"fail","Package":"example.com/a"}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	PrintSummaryWithOptions(buf, exec, SummarizeFailed|SummarizeOutput, SummaryOptions{
+		FormatLine: func(line string) string {
+			if strings.Contains(line, "debug") {
+				return ""
+			}
+			return strings.ToUpper(line)
+		},
+	})
+	out := buf.String()
+	assert.Assert(t, !strings.Contains(out, "debug line"), out)
+	assert.Assert(t, cmp.Contains(out, "    ONE_TEST.GO:11: IT FAILED\n"))
+}
@@ -0,0 +1,168 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Verbosity changes how much of a run is printed by a format.
+type Verbosity int
+
+const (
+	// VerbosityQuiet hides passing tests and packages that passed or have no
+	// tests.
+	VerbosityQuiet Verbosity = -1
+	// VerbosityDefault prints what the format prints.
+	VerbosityDefault Verbosity = 0
+	// VerbosityTests prints a line for each test that passed or failed, in
+	// addition to the package lines of the format.
+	VerbosityTests Verbosity = 1
+	// VerbosityOutput prints all of the output of the tests as it is
+	// received.
+	VerbosityOutput Verbosity = 2
+)
+
+// formatVerbosity returns the verbosity of a built-in format, which is the
+// level that prints the format without any changes. standard is true for the
+// formats that print the output of go test.
+func formatVerbosity(format string) (level Verbosity, standard bool) {
+	switch format {
+	case "standard-quiet":
+		return VerbosityDefault, true
+	case "standard-verbose":
+		return VerbosityOutput, true
+	case "debug":
+		return VerbosityOutput, false
+	case "dots", "dots-v1", "dots-v2", "testname", "short-verbose":
+		return VerbosityTests, false
+	}
+	return VerbosityDefault, false
+}
+
+// verbosityFormatter changes the events printed by a built-in format to
+// match the verbosity.
+type verbosityFormatter struct {
+	out       io.Writer
+	formatter EventFormatter
+	verbosity Verbosity
+	// level is the verbosity of the format, from formatVerbosity.
+	level    Verbosity
+	standard bool
+	opts     FormatOptions
+}
+
+func withVerbosity(out io.Writer, formatter EventFormatter, format string, opts FormatOptions) EventFormatter {
+	verbosity := opts.Verbosity
+	if verbosity == VerbosityDefault {
+		return formatter
+	}
+	level, standard := formatVerbosity(format)
+	return &verbosityFormatter{
+		out:       out,
+		formatter: formatter,
+		verbosity: verbosity,
+		level:     level,
+		standard:  standard,
+		opts:      opts,
+	}
+}
+
+func (f *verbosityFormatter) Format(event TestEvent, exec *Execution) error {
+	switch {
+	case f.verbosity <= VerbosityQuiet:
+		if isPassingEvent(event) {
+			return nil
+		}
+		return f.formatter.Format(event, exec)
+	case event.PackageEvent() || f.verbosity <= f.level:
+		return f.formatter.Format(event, exec)
+	case f.standard:
+		return f.write(standardTestOutput(event, f.verbosity))
+	case f.verbosity >= VerbosityOutput:
+		// the output is printed by the formatter when a test fails, so test
+		// events are not sent to the formatter.
+		return f.write(testOutput(event) + testLine(f.opts, event))
+	}
+	if err := f.formatter.Format(event, exec); err != nil {
+		return err
+	}
+	return f.write(testLine(f.opts, event))
+}
+
+func (f *verbosityFormatter) write(v string) error {
+	if v == "" {
+		return nil
+	}
+	_, err := f.out.Write([]byte(v))
+	return err
+}
+
+// isPassingEvent returns true for the events of tests that passed, and of
+// packages that passed or have no tests.
+func isPassingEvent(event TestEvent) bool {
+	switch {
+	case !event.PackageEvent():
+		return event.Action == ActionPass
+	case event.Action == ActionOutput:
+		return strings.HasPrefix(event.Output, "ok  \t"+event.Package) ||
+			strings.HasPrefix(event.Output, "?   \t"+event.Package)
+	}
+	return event.Action == ActionPass || event.Action == ActionSkip
+}
+
+// isPassingPackage returns true when the package passed, or has no tests.
+func isPassingPackage(pkg *Package) bool {
+	return pkg.Result() == ActionPass || pkg.Result() == ActionSkip
+}
+
+// testLine returns the line printed for a test by the testname format.
+func testLine(opts FormatOptions, event TestEvent) string {
+	return testLineWithName(opts, event, "", joinPkgToTestName(formatPackageName(opts.PackageName, event.Package), event.Test))
+}
+
+// testLineWithName returns the line printed for a test, with the name of the
+// test, indented by indent.
+func testLineWithName(opts FormatOptions, event TestEvent, indent string, name string) string {
+	if event.Action != ActionPass && event.Action != ActionFail {
+		return ""
+	}
+	prefix := indent + colorEvent(event)(strings.ToUpper(string(event.Action))) + " "
+	if opts.Align {
+		right := strings.TrimPrefix(formatRunID(event.RunID)+" "+event.ElapsedFormatted(), " ")
+		return alignLine(opts, prefix, name, right)
+	}
+	return fmt.Sprintf("%s%s%s %s\n", prefix, name, formatRunID(event.RunID), event.ElapsedFormatted())
+}
+
+// testOutput returns the output of a test, without the lines printed by go
+// test to show that a test started or ended. Those are printed by testLine.
+func testOutput(event TestEvent) string {
+	if event.Action != ActionOutput || isFramingLine(event.Output) ||
+		strings.HasPrefix(event.Output, "=== NAME") || isResultLine(event.Output) {
+		return ""
+	}
+	return event.Output
+}
+
+// standardTestOutput returns the output of a test printed by the go test
+// formats. The result lines, like go test -v, are printed for VerbosityTests,
+// and all the output for VerbosityOutput.
+func standardTestOutput(event TestEvent, verbosity Verbosity) string {
+	switch {
+	case event.Action != ActionOutput:
+		return ""
+	case verbosity >= VerbosityOutput || isResultLine(event.Output):
+		return event.Output
+	}
+	return ""
+}
+
+// isResultLine returns true for the lines printed by go test -v when a test
+// ends, like --- PASS: TestName (0.00s).
+func isResultLine(line string) bool {
+	line = strings.TrimLeft(line, " ")
+	return strings.HasPrefix(line, "--- PASS: ") ||
+		strings.HasPrefix(line, "--- FAIL: ") ||
+		strings.HasPrefix(line, "--- SKIP: ")
+}
@@ -0,0 +1,85 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestNewEventFormatter_Verbosity(t *testing.T) {
+	type testCase struct {
+		format      string
+		verbosity   Verbosity
+		expectedOut string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		out := new(bytes.Buffer)
+		formatter := NewEventFormatter(out, tc.format, FormatOptions{NoTerminal: true, Verbosity: tc.verbosity})
+		shim := newFakeHandler(formatter, "input/go-test-json")
+		_, err := ScanTestOutput(shim.Config(t))
+		assert.NilError(t, err)
+
+		golden.Assert(t, out.String(), tc.expectedOut)
+	}
+
+	testCases := []testCase{
+		{
+			format:      "pkgname",
+			verbosity:   VerbosityQuiet,
+			expectedOut: "format/verbosity/pkgname-quiet.out",
+		},
+		{
+			format:      "pkgname",
+			verbosity:   VerbosityTests,
+			expectedOut: "format/verbosity/pkgname-tests.out",
+		},
+		{
+			format:      "pkgname",
+			verbosity:   VerbosityOutput,
+			expectedOut: "format/verbosity/pkgname-output.out",
+		},
+		{
+			format:      "testname",
+			verbosity:   VerbosityQuiet,
+			expectedOut: "format/verbosity/testname-quiet.out",
+		},
+		{
+			format:      "testname",
+			verbosity:   VerbosityTests,
+			expectedOut: "format/testname.out",
+		},
+		{
+			format:      "testname",
+			verbosity:   VerbosityOutput,
+			expectedOut: "format/verbosity/testname-output.out",
+		},
+		{
+			format:      "dots-v1",
+			verbosity:   VerbosityQuiet,
+			expectedOut: "format/verbosity/dots-v1-quiet.out",
+		},
+		{
+			format:      "standard-quiet",
+			verbosity:   VerbosityQuiet,
+			expectedOut: "format/verbosity/standard-quiet-quiet.out",
+		},
+		{
+			format:      "standard-quiet",
+			verbosity:   VerbosityTests,
+			expectedOut: "format/verbosity/standard-quiet-tests.out",
+		},
+		{
+			format:      "standard-quiet",
+			verbosity:   VerbosityOutput,
+			expectedOut: "format/verbosity/standard-quiet-output.out",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expectedOut, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package testjson
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// wallClockFormatter prints the wall-clock time since the start of the run,
+// aligned to the right side of the terminal, at the end of the line printed
+// when a test or package ends.
+type wallClockFormatter struct {
+	out       io.Writer
+	formatter EventFormatter
+	// buf receives the output of formatter for one event.
+	buf   *bytes.Buffer
+	start time.Time
+	width int
+}
+
+func newWallClockFormatter(out io.Writer, opts FormatOptions) *wallClockFormatter {
+	width := terminalWidth(opts)
+	if opts.Timestamps {
+		width -= len(timestampLayout) + 1
+	}
+	return &wallClockFormatter{out: out, buf: new(bytes.Buffer), width: width}
+}
+
+func (f *wallClockFormatter) Format(event TestEvent, exec *Execution) error {
+	if f.start.IsZero() {
+		f.start = event.Time
+	}
+	err := f.formatter.Format(event, exec)
+	text := f.buf.String()
+	f.buf.Reset()
+	if event.Action.IsTerminal() && !event.Time.IsZero() && strings.HasSuffix(text, "\n") {
+		text = f.withColumn(text, event.Time.Sub(f.start))
+	}
+	if _, werr := io.WriteString(f.out, text); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
+
+// withColumn adds the elapsed time to the end of the last line of text.
+func (f *wallClockFormatter) withColumn(text string, elapsed time.Duration) string {
+	text = strings.TrimSuffix(text, "\n")
+	last := text
+	if i := strings.LastIndex(text, "\n"); i >= 0 {
+		last = text[i+1:]
+	}
+	column := formatWallClock(elapsed)
+	pad := f.width - displayWidth(last) - len(column)
+	if pad < 1 {
+		pad = 1
+	}
+	return text + strings.Repeat(" ", pad) + color.HiBlackString(column) + "\n"
+}
+
+// formatWallClock formats elapsed like a stopwatch, as minutes and seconds,
+// or hours, minutes, and seconds when elapsed is an hour or more.
+func formatWallClock(elapsed time.Duration) string {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	elapsed = elapsed.Round(100 * time.Millisecond)
+	h := int(elapsed / time.Hour)
+	m := int(elapsed % time.Hour / time.Minute)
+	s := float64(elapsed%time.Minute) / float64(time.Second)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%04.1f", h, m, s)
+	}
+	return fmt.Sprintf("%d:%04.1f", m, s)
+}
@@ -0,0 +1,134 @@
+package testjson
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// defaultTerminalWidth is the width used by formats that align columns when
+// the output is not a terminal, and the width is not set.
+const defaultTerminalWidth = 80
+
+// terminalWidth returns the width used to align columns to the right side of
+// the terminal.
+func terminalWidth(opts FormatOptions) int {
+	if opts.TerminalWidth > 0 {
+		return opts.TerminalWidth
+	}
+	if !opts.NoTerminal {
+		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+			return w
+		}
+	}
+	return defaultTerminalWidth
+}
+
+var ansiSequence = regexp.MustCompile("\x1b\\[[0-9;?]*[ -/]*[@-~]")
+
+// displayWidth returns the number of columns used to print s in a terminal.
+// ANSI escape sequences, like colors, do not use any columns, and wide
+// characters, like the hivis icons, use two columns.
+func displayWidth(s string) int {
+	var width int
+	for _, r := range ansiSequence.ReplaceAllString(s, "") {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r == 0x23f3, r == 0x2705, r == 0x274c, r == 0x2796,
+		r >= 0x2e80 && r <= 0xa4cf,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff:
+		return 2
+	}
+	return 1
+}
+
+// Truncate is the part of a long name that is replaced by an ellipsis.
+type Truncate string
+
+const (
+	// TruncateMiddle keeps the start and the end of the name.
+	TruncateMiddle Truncate = "middle"
+	// TruncateStart keeps the end of the name.
+	TruncateStart Truncate = "start"
+	// TruncateEnd keeps the start of the name.
+	TruncateEnd Truncate = "end"
+	// TruncateNone prints the whole name, even when it does not fit.
+	TruncateNone Truncate = "none"
+)
+
+const ellipsis = "…"
+
+// truncateName returns name, with part of it replaced by an ellipsis when
+// it is wider than width.
+func truncateName(name string, width int, mode Truncate) string {
+	runes := []rune(name)
+	if mode == TruncateNone || len(runes) <= width {
+		return name
+	}
+	if width < 2 {
+		return ellipsis
+	}
+	keep := width - 1
+	switch mode {
+	case TruncateStart:
+		return ellipsis + string(runes[len(runes)-keep:])
+	case TruncateEnd:
+		return string(runes[:keep]) + ellipsis
+	}
+	// the end of a package path, or a subtest name, is often the part that
+	// identifies it, so the end gets the extra rune.
+	left := keep / 2
+	return string(runes[:left]) + ellipsis + string(runes[len(runes)-(keep-left):])
+}
+
+// wallClockWidth is the width reserved at the end of the line for the
+// wall-clock column, which fits runs of up to 100 minutes.
+const wallClockWidth = len("00:00.0") + 1
+
+// lineWidth returns the width available to a line printed by a format,
+// without the columns added by the timestamps and wall-clock modifiers.
+func lineWidth(opts FormatOptions) int {
+	width := terminalWidth(opts)
+	if opts.Timestamps {
+		width -= len(timestampLayout) + 1
+	}
+	if opts.WallClock {
+		width -= wallClockWidth
+	}
+	return width
+}
+
+// alignLine returns a line that starts with prefix and name, and ends with
+// right aligned to the right side of the line. The name is truncated when
+// the line does not fit.
+func alignLine(opts FormatOptions, prefix, name, right string) string {
+	avail := lineWidth(opts) - displayWidth(prefix)
+	if right != "" {
+		avail -= displayWidth(right) + 1
+	}
+	name = truncateName(name, avail, opts.Truncate)
+	if right == "" {
+		return prefix + name + "\n"
+	}
+	pad := avail - displayWidth(name) + 1
+	if pad < 1 {
+		pad = 1
+	}
+	return prefix + name + strings.Repeat(" ", pad) + right + "\n"
+}
@@ -0,0 +1,37 @@
+package testjson
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	assert.Equal(t, displayWidth("pkg (1ms)"), 9)
+	assert.Equal(t, displayWidth("\x1b[32m✓\x1b[0m  pkg"), 6)
+	assert.Equal(t, displayWidth("✅  pkg"), 7)
+}
+
+func TestTruncateName(t *testing.T) {
+	name := "example.com/project/internal/service"
+	assert.Equal(t, truncateName(name, 40, TruncateMiddle), name)
+	assert.Equal(t, truncateName(name, 20, TruncateMiddle), "example.c…al/service")
+	assert.Equal(t, truncateName(name, 20, ""), "example.c…al/service")
+	assert.Equal(t, truncateName(name, 20, TruncateStart), "…ct/internal/service")
+	assert.Equal(t, truncateName(name, 20, TruncateEnd), "example.com/project…")
+	assert.Equal(t, truncateName(name, 20, TruncateNone), name)
+	assert.Equal(t, truncateName(name, 1, TruncateMiddle), "…")
+}
+
+func TestAlignLine(t *testing.T) {
+	opts := FormatOptions{TerminalWidth: 30}
+	assert.Equal(t, alignLine(opts, "PASS ", "pkg.TestOne", "(0.10s)"),
+		"PASS pkg.TestOne       (0.10s)\n")
+	assert.Equal(t, alignLine(opts, "PASS ", "pkg.TestWithAVeryLongName/sub", "(0.10s)"),
+		"PASS pkg.Test…Name/sub (0.10s)\n")
+	assert.Equal(t, alignLine(opts, "PASS ", "pkg", ""), "PASS pkg\n")
+
+	opts.WallClock = true
+	assert.Equal(t, alignLine(opts, "PASS ", "pkg.TestOne", "(0.10s)"),
+		"PASS pkg.…tOne (0.10s)\n")
+}